@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// clusterWatch tracks the availability of the target cluster across daemon iterations, so the
+// daemon stops restarting kubelet against a cluster that is stopped or gone
+type clusterWatch struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+
+	// Replaced in tests
+	collect   func(ctx context.Context) (*spec.ManagedClusterSpec, error)
+	systemctl func(action, unit string) error
+	now       func() time.Time
+
+	state            string    // Last known spec.ClusterState*, empty until the first successful check
+	unavailableSince time.Time // When the cluster was first seen stopped or deleted
+	deniedSince      time.Time // When reading the cluster was first forbidden, which may mean it was deleted
+}
+
+// newClusterWatch creates a watch for the configured target cluster
+func newClusterWatch(cfg *config.Config, logger *logrus.Logger) *clusterWatch {
+	return &clusterWatch{
+		cfg:    cfg,
+		logger: logger,
+		collect: func(ctx context.Context) (*spec.ManagedClusterSpec, error) {
+			return spec.NewManagedClusterSpecCollector(cfg, logger).Collect(ctx)
+		},
+		systemctl: func(action, unit string) error {
+			return utils.RunSystemCommand("systemctl", action, unit)
+		},
+		now: time.Now,
+	}
+}
+
+// available reports whether the node should keep kubelet running and re-bootstrap on failures
+func (w *clusterWatch) available() bool {
+	return w.state != spec.ClusterStateStopped && w.state != spec.ClusterStateDeleted
+}
+
+// check collects the managed cluster spec and updates the cluster state. It returns true when the
+// cluster loss policy asks for the node to be unbootstrapped.
+func (w *clusterWatch) check(ctx context.Context) bool {
+	collected, err := w.collect(ctx)
+	switch {
+	case errors.Is(err, spec.ErrClusterNotFound):
+		w.deniedSince = time.Time{}
+		w.observe(spec.ClusterStateDeleted)
+	case errors.Is(err, spec.ErrClusterAccessDenied):
+		w.observeDenied(err)
+	case err != nil:
+		// Transient failures (network, throttling, credentials) say nothing about the cluster
+		w.logger.Warnf("Failed to collect managed cluster spec: %v", err)
+		return false
+	default:
+		w.deniedSince = time.Time{}
+		w.observe(collected.ClusterState())
+	}
+	return w.shouldUnbootstrap()
+}
+
+// observeDenied handles a forbidden read of the cluster. The cluster may be deleted, which also removes
+// the role assignments scoped to it, or the node may just have lost its role. It counts as deleted only
+// once it persists for the grace period, measured from the first denial.
+func (w *clusterWatch) observeDenied(err error) {
+	if w.deniedSince.IsZero() {
+		w.deniedSince = w.now()
+		w.logger.Warnf("Target cluster %s may have been deleted: %v. It is considered deleted if access stays denied for %v",
+			w.cfg.GetTargetClusterID(), err, w.cfg.Agent.ClusterLoss.GracePeriod)
+	}
+	if w.state == spec.ClusterStateDeleted || w.now().Sub(w.deniedSince) < w.cfg.Agent.ClusterLoss.GracePeriod {
+		return
+	}
+	if w.unavailableSince.IsZero() {
+		w.unavailableSince = w.deniedSince
+	}
+	w.observe(spec.ClusterStateDeleted)
+}
+
+// observe records a new cluster state, pausing kubelet when the cluster goes away and resuming it when it returns
+func (w *clusterWatch) observe(state string) {
+	previous := w.state
+	w.state = state
+	if state == previous {
+		return
+	}
+
+	if state == spec.ClusterStateAvailable {
+		if previous != "" {
+			w.logger.Infof("Target cluster %s is available again, resuming kubelet", w.cfg.GetTargetClusterID())
+			if err := w.systemctl("start", "kubelet"); err != nil {
+				w.logger.Warnf("Failed to start kubelet: %v", err)
+			}
+		}
+		w.unavailableSince = time.Time{}
+		return
+	}
+
+	if w.unavailableSince.IsZero() {
+		w.unavailableSince = w.now()
+	}
+	w.logger.Errorf("Target cluster %s is %s: stopping kubelet and pausing auto-bootstrap until it is available again",
+		w.cfg.GetTargetClusterID(), state)
+	if err := w.systemctl("stop", "kubelet"); err != nil {
+		w.logger.Warnf("Failed to stop kubelet: %v", err)
+	}
+	if state == spec.ClusterStateDeleted && w.cfg.Agent.ClusterLoss.Action == config.ClusterLossActionUnbootstrap {
+		w.logger.Warnf("Node will be unbootstrapped if the cluster stays deleted for %v (agent.clusterLoss)", w.cfg.Agent.ClusterLoss.GracePeriod)
+	}
+}
+
+// shouldUnbootstrap applies the cluster loss policy: only a cluster that stays deleted for the grace period
+// triggers it, never a stopped one, since stopped clusters keep their nodes when started again
+func (w *clusterWatch) shouldUnbootstrap() bool {
+	return w.state == spec.ClusterStateDeleted &&
+		w.cfg.Agent.ClusterLoss.Action == config.ClusterLossActionUnbootstrap &&
+		w.now().Sub(w.unavailableSince) >= w.cfg.Agent.ClusterLoss.GracePeriod
+}
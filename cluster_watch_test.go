@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+)
+
+func TestClusterWatchCheck(t *testing.T) {
+	var (
+		running  = &spec.ManagedClusterSpec{PowerState: "Running", ProvisioningState: "Succeeded"}
+		stopped  = &spec.ManagedClusterSpec{PowerState: "Stopped", ProvisioningState: "Succeeded"}
+		notFound = fmt.Errorf("%w: cluster", spec.ErrClusterNotFound)
+		denied   = fmt.Errorf("%w: cluster: AuthorizationFailed", spec.ErrClusterAccessDenied)
+		outage   = errors.New("failed to get AKS managed cluster via SDK: 503 Service Unavailable")
+	)
+
+	// step is one daemon iteration: minutes after the first check, the collect result, and the expected outcome
+	type step struct {
+		minute       int
+		spec         *spec.ManagedClusterSpec
+		err          error
+		state        string
+		unbootstrap  bool
+		wantCommands []string
+	}
+	tests := []struct {
+		name   string
+		action string
+		steps  []step
+	}{
+		{
+			name:   "404 unbootstraps after the grace period",
+			action: config.ClusterLossActionUnbootstrap,
+			steps: []step{
+				{minute: 0, spec: running, state: spec.ClusterStateAvailable},
+				{minute: 1, err: notFound, state: spec.ClusterStateDeleted, wantCommands: []string{"stop kubelet"}},
+				{minute: 30, err: notFound, state: spec.ClusterStateDeleted},
+				{minute: 61, err: notFound, state: spec.ClusterStateDeleted, unbootstrap: true},
+			},
+		},
+		{
+			name:   "404 keeps the node without the unbootstrap action",
+			action: config.ClusterLossActionNone,
+			steps: []step{
+				{minute: 0, err: notFound, state: spec.ClusterStateDeleted, wantCommands: []string{"stop kubelet"}},
+				{minute: 120, err: notFound, state: spec.ClusterStateDeleted},
+			},
+		},
+		{
+			name:   "403 counts as deleted once it persists for the grace period",
+			action: config.ClusterLossActionUnbootstrap,
+			steps: []step{
+				{minute: 0, spec: running, state: spec.ClusterStateAvailable},
+				{minute: 1, err: denied, state: spec.ClusterStateAvailable},
+				{minute: 30, err: denied, state: spec.ClusterStateAvailable},
+				{minute: 61, err: denied, state: spec.ClusterStateDeleted, unbootstrap: true, wantCommands: []string{"stop kubelet"}},
+			},
+		},
+		{
+			name:   "403 that clears resets the grace period",
+			action: config.ClusterLossActionUnbootstrap,
+			steps: []step{
+				{minute: 0, err: denied, state: ""},
+				{minute: 30, spec: running, state: spec.ClusterStateAvailable},
+				{minute: 45, err: denied, state: spec.ClusterStateAvailable},
+				{minute: 70, err: denied, state: spec.ClusterStateAvailable},
+				{minute: 106, err: denied, state: spec.ClusterStateDeleted, unbootstrap: true, wantCommands: []string{"stop kubelet"}},
+			},
+		},
+		{
+			name:   "transient 5xx changes nothing",
+			action: config.ClusterLossActionUnbootstrap,
+			steps: []step{
+				{minute: 0, spec: running, state: spec.ClusterStateAvailable},
+				{minute: 1, err: outage, state: spec.ClusterStateAvailable},
+				{minute: 120, err: outage, state: spec.ClusterStateAvailable},
+			},
+		},
+		{
+			name:   "transient 5xx does not reset a deleted cluster",
+			action: config.ClusterLossActionUnbootstrap,
+			steps: []step{
+				{minute: 0, err: notFound, state: spec.ClusterStateDeleted, wantCommands: []string{"stop kubelet"}},
+				{minute: 30, err: outage, state: spec.ClusterStateDeleted},
+				{minute: 60, err: notFound, state: spec.ClusterStateDeleted, unbootstrap: true},
+			},
+		},
+		{
+			name:   "stopped cluster pauses kubelet and never unbootstraps",
+			action: config.ClusterLossActionUnbootstrap,
+			steps: []step{
+				{minute: 0, spec: running, state: spec.ClusterStateAvailable},
+				{minute: 1, spec: stopped, state: spec.ClusterStateStopped, wantCommands: []string{"stop kubelet"}},
+				{minute: 120, spec: stopped, state: spec.ClusterStateStopped},
+				{minute: 121, spec: running, state: spec.ClusterStateAvailable, wantCommands: []string{"start kubelet"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Agent.ClusterLoss.Action = tt.action
+			cfg.Agent.ClusterLoss.GracePeriod = time.Hour
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+
+			start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			var current step
+			var commands []string
+			w := newClusterWatch(cfg, logger)
+			w.collect = func(context.Context) (*spec.ManagedClusterSpec, error) { return current.spec, current.err }
+			w.systemctl = func(action, unit string) error {
+				commands = append(commands, action+" "+unit)
+				return nil
+			}
+			w.now = func() time.Time { return start.Add(time.Duration(current.minute) * time.Minute) }
+
+			for _, current = range tt.steps {
+				commands = nil
+				unbootstrap := w.check(context.Background())
+				if w.state != current.state || unbootstrap != current.unbootstrap {
+					t.Errorf("minute %d: state = %q, unbootstrap = %v, want %q, %v", current.minute, w.state, unbootstrap, current.state, current.unbootstrap)
+				}
+				if !slices.Equal(commands, current.wantCommands) {
+					t.Errorf("minute %d: systemctl calls = %v, want %v", current.minute, commands, current.wantCommands)
+				}
+			}
+		})
+	}
+}
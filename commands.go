@@ -3,21 +3,35 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"go.goms.io/aks/AKSFlexNode/pkg/bootstrapper"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/arc"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/containerd"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/crio"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/preflight"
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/diagnostics"
 	"go.goms.io/aks/AKSFlexNode/pkg/logger"
-	"go.goms.io/aks/AKSFlexNode/pkg/spec"
 	"go.goms.io/aks/AKSFlexNode/pkg/status"
+	"go.goms.io/aks/AKSFlexNode/pkg/tokenbroker"
+	"go.goms.io/aks/AKSFlexNode/pkg/wizard"
 )
 
+// defaultConfigPath is where init writes the configuration file without --config
+const defaultConfigPath = "/etc/aks-flex-node/config.json"
+
 // Version information variables (set at build time)
 var (
 	Version   = "dev"
@@ -39,16 +53,210 @@ func NewAgentCommand() *cobra.Command {
 	return cmd
 }
 
+// NewBootstrapCommand creates a new bootstrap command
+func NewBootstrapCommand() *cobra.Command {
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Bootstrap this machine as an AKS node",
+		Long:  "Run all bootstrap steps once and exit, without starting the monitoring daemon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBootstrap(cmd.Context(), timeout)
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Abort bootstrap after this long, leaving it to be resumed by the next run (0 for no limit)")
+
+	return cmd
+}
+
 // NewUnbootstrapCommand creates a new unbootstrap command
 func NewUnbootstrapCommand() *cobra.Command {
+	var (
+		deleteArcResource bool
+		yes               bool
+		drainTimeout      time.Duration
+		force             bool
+	)
 	cmd := &cobra.Command{
 		Use:   "unbootstrap",
 		Short: "Remove AKS node configuration and Arc connection",
 		Long:  "Clean up and remove all AKS node components and Arc registration from this machine",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runUnbootstrap(cmd.Context())
+			return runUnbootstrap(cmd.Context(), bootstrapper.UnbootstrapOptions{
+				DeleteArcResource: deleteArcResource,
+				DrainTimeout:      drainTimeout,
+				Force:             force,
+			}, yes)
+		},
+	}
+	cmd.Flags().BoolVar(&deleteArcResource, "delete-arc-resource", false, "Also delete the Arc machine resource from Azure instead of leaving it disconnected")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation before deleting the Arc machine resource")
+	cmd.Flags().DurationVar(&drainTimeout, "drain-timeout", 5*time.Minute, "How long to wait for pods to be evicted before services are stopped, 0 skips the drain")
+	cmd.Flags().BoolVar(&force, "force", false, "Delete the pods left after --drain-timeout without honoring PodDisruptionBudgets instead of stopping unbootstrap")
+
+	return cmd
+}
+
+// NewReconcileCommand creates a new reconcile command
+func NewReconcileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Repair drift from the configured node state",
+		Long:  "Verify every bootstrap step and re-run bootstrap only if some component drifted from the configuration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReconcile(cmd.Context())
+		},
+	}
+
+	return cmd
+}
+
+// NewStatusCommand creates a new status command
+func NewStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show node status",
+		Long:  "Collect component versions, service health and Arc connectivity along with the last bootstrap outcome",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(cmd.Context())
+		},
+	}
+
+	return cmd
+}
+
+// NewVerifyCommand creates a new verify command
+func NewVerifyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that the node matches the configuration",
+		Long:  "Check every bootstrap step without changing the host; exits non-zero if any step is not in its desired state",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerify(cmd.Context())
+		},
+	}
+
+	return cmd
+}
+
+// NewUpgradeCommand creates a new upgrade command
+func NewUpgradeCommand() *cobra.Command {
+	var (
+		kubernetesVersion string
+		timeout           time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade node components to the configured versions",
+		Long:  "Replace runc, containerd and Kubernetes binaries whose installed version differs from the configuration and restart node services",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUpgrade(cmd.Context(), kubernetesVersion, timeout)
+		},
+	}
+	cmd.Flags().StringVar(&kubernetesVersion, "kubernetes-version", "", "Kubernetes version to upgrade to (overrides kubernetes.version from the config)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Abort the upgrade after this long, leaving it to be resumed by the next run (0 for no limit)")
+
+	return cmd
+}
+
+// NewPlanCommand creates a new plan command
+func NewPlanCommand() *cobra.Command {
+	var script bool
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "List the Azure writes bootstrap would perform",
+		Long:  "List the role assignments and other Azure Resource Manager writes bootstrap would perform, without changing anything. With --script, print them as Azure CLI commands for a privileged operator",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPlan(cmd.Context(), script)
+		},
+	}
+	cmd.Flags().BoolVar(&script, "script", false, "Print missing writes as an Azure CLI script instead of a report")
+
+	return cmd
+}
+
+// NewPreflightCommand creates a new preflight command
+func NewPreflightCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Run host and network checks without bootstrapping",
+		Long:  "Run the checks bootstrap starts with and report warnings and failures without changing the host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPreflight(cmd.Context())
+		},
+	}
+
+	return cmd
+}
+
+// NewInitCommand creates a new init command
+func NewInitCommand() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:         "init",
+		Short:       "Create a configuration file interactively",
+		Long:        "Ask for the subscription, target cluster and authentication, with defaults from the instance metadata service and the Azure CLI login, check the answers against Azure and write the configuration file given by --config (default " + defaultConfigPath + ")",
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInit(cmd.Context(), force)
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing configuration file")
+
+	return cmd
+}
+
+// NewConfigCommand creates the config command group
+func NewConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect agent configuration",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:         "validate",
+		Short:       "Validate a configuration file",
+		Long:        "Check the configuration file and its drop-in layers against the config schema, then load and validate it without touching the host",
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigValidate()
+		},
+	}
+	cmd.AddCommand(validateCmd)
+
+	schemaCmd := &cobra.Command{
+		Use:         "schema",
+		Short:       "Print the JSON Schema of the configuration file",
+		Long:        "Print the JSON Schema of the configuration file, for editors and CI checks of config files",
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigSchema()
+		},
+	}
+	cmd.AddCommand(schemaCmd)
+
+	var encryptKey string
+	encryptCmd := &cobra.Command{
+		Use:         "encrypt",
+		Short:       "Encrypt a secret for the configuration file",
+		Long:        "Read a secret from stdin and print it as an @encrypted config value, encrypted with the TPM of this machine or a data key stored in Key Vault",
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigEncrypt(cmd.Context(), cmd.InOrStdin(), encryptKey)
 		},
 	}
+	encryptCmd.Flags().StringVar(&encryptKey, "key", config.EncryptionKeyTPM, "tpm, or the Key Vault secret holding the base64-encoded AES-256 data key, https://<vault>.vault.azure.net/secrets/<name>[/<version>]")
+	cmd.AddCommand(encryptCmd)
+
+	provenanceCmd := &cobra.Command{
+		Use:   "provenance",
+		Short: "Show which config layer set each value",
+		Long:  "List the base, site and host config files that were merged and the file that set each configuration value",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigProvenance()
+		},
+	}
+	cmd.AddCommand(provenanceCmd)
 
 	return cmd
 }
@@ -56,13 +264,30 @@ func NewUnbootstrapCommand() *cobra.Command {
 // NewVersionCommand creates a new version command
 func NewVersionCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "version",
-		Short: "Show version information",
-		Long:  "Display version, build commit, and build time information",
-		Run: func(cmd *cobra.Command, args []string) {
-			runVersion()
+		Use:         "version",
+		Short:       "Show version information",
+		Long:        "Display version, build commit, and build time information",
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVersion()
+		},
+	}
+
+	return cmd
+}
+
+// NewDiagnosticsCommand creates a new diagnostics command
+func NewDiagnosticsCommand() *cobra.Command {
+	var bundlePath string
+	cmd := &cobra.Command{
+		Use:   "diagnostics",
+		Short: "Collect a diagnostics bundle",
+		Long:  "Collect agent logs, node status, daemon journals and recent containerd/kubelet core dumps into a tarball",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiagnostics(cmd.Context(), bundlePath)
 		},
 	}
+	cmd.Flags().StringVarP(&bundlePath, "file", "f", "", "Path of the bundle to write (defaults to a timestamped file in the temp directory)")
 
 	return cmd
 }
@@ -71,12 +296,22 @@ func NewVersionCommand() *cobra.Command {
 func runAgent(ctx context.Context) error {
 	logger := logger.GetLoggerFromContext(ctx)
 
-	cfg, err := config.LoadConfig(configPath)
-	if err != nil {
-		return fmt.Errorf("failed to load config from %s: %w", configPath, err)
+	// The persistent pre-run loaded the config
+	cfg := config.GetConfig()
+
+	// Start the token broker before bootstrap so kubelet's first credential requests are served from its cache
+	if cfg.Agent.TokenBroker.Enabled {
+		if err := startTokenBroker(ctx, cfg, logger); err != nil {
+			return err
+		}
+	}
+
+	// ACR refresh tokens obtained with the node identity expire after a few hours
+	if cfg.RegistryAuthUsesNodeIdentity() {
+		go refreshRegistryAuth(ctx, cfg, logger)
 	}
 
-	bootstrapExecutor := bootstrapper.New(cfg, logger)
+	bootstrapExecutor := bootstrapper.New(cfg, logger, Version)
 	result, err := bootstrapExecutor.Bootstrap(ctx)
 	if err != nil {
 		return err
@@ -92,31 +327,474 @@ func runAgent(ctx context.Context) error {
 	return runDaemonLoop(ctx, cfg)
 }
 
+// startTokenBroker runs the kubelet token broker in the background for the lifetime of ctx
+func startTokenBroker(ctx context.Context, cfg *config.Config, logger *logrus.Logger) error {
+	broker, err := tokenbroker.New(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create token broker: %w", err)
+	}
+
+	go func() {
+		if err := broker.Run(ctx); err != nil {
+			// kubelet token scripts fall back to fetching tokens directly, so keep the agent running
+			logger.Errorf("Token broker stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// refreshRegistryAuth renews the container runtime's ACR refresh tokens every hour for the lifetime of ctx. Bootstrap writes
+// the first ones.
+func refreshRegistryAuth(ctx context.Context, cfg *config.Config, logger *logrus.Logger) {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh := containerd.RefreshRegistryAuth
+			if cfg.UsesCRIO() {
+				refresh = crio.RefreshRegistryAuth
+			}
+			if err := refresh(ctx, cfg, logger); err != nil {
+				logger.Errorf("Failed to refresh registry credentials: %v", err)
+			}
+		}
+	}
+}
+
+// runBootstrap executes the bootstrap process once and reports the result
+func runBootstrap(ctx context.Context, timeout time.Duration) error {
+	logger := logger.GetLoggerFromContext(ctx)
+	ctx, cancel, err := withRunTimeout(ctx, timeout)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	bootstrapExecutor := bootstrapper.New(config.GetConfig(), logger, Version)
+	result, err := bootstrapExecutor.Bootstrap(ctx)
+	return reportExecutionResult(result, err, "bootstrap", logger)
+}
+
+// withRunTimeout bounds a run by --timeout. The run is cancelled with a cause naming the flag, so the abort
+// reports why it stopped.
+func withRunTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc, error) {
+	if timeout < 0 {
+		return nil, nil, &usageError{fmt.Errorf("--timeout must not be negative")}
+	}
+	if timeout == 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("--timeout of %s exceeded", timeout))
+	return ctx, cancel, nil
+}
+
 // runUnbootstrap executes the unbootstrap process
-func runUnbootstrap(ctx context.Context) error {
+func runUnbootstrap(ctx context.Context, opts bootstrapper.UnbootstrapOptions, yes bool) error {
+	logger := logger.GetLoggerFromContext(ctx)
+	cfg := config.GetConfig()
+
+	if opts.DrainTimeout < 0 {
+		return &usageError{fmt.Errorf("--drain-timeout must not be negative")}
+	}
+	if opts.DeleteArcResource {
+		if !cfg.IsARCEnabled() {
+			return &usageError{fmt.Errorf("--delete-arc-resource requires azure.arc.enabled")}
+		}
+		if !yes && !confirm(os.Stdin, fmt.Sprintf("Delete Arc machine resource %s in resource group %s from Azure? This cannot be undone [y/N]: ",
+			cfg.GetArcMachineName(), cfg.GetArcResourceGroup())) {
+			return &usageError{fmt.Errorf("deletion of the Arc machine resource was not confirmed, rerun with --yes to skip the prompt")}
+		}
+	}
+
+	bootstrapExecutor := bootstrapper.New(cfg, logger, Version)
+	result, err := bootstrapExecutor.Unbootstrap(ctx, opts)
+
+	// Unbootstrap is more lenient with failures
+	return reportExecutionResult(result, err, "unbootstrap", logger)
+}
+
+// runReconcile re-runs bootstrap only when verification finds drift
+func runReconcile(ctx context.Context) error {
+	logger := logger.GetLoggerFromContext(ctx)
+
+	bootstrapExecutor := bootstrapper.New(config.GetConfig(), logger, Version)
+	verification := bootstrapExecutor.Verify(ctx)
+	if verification.Success {
+		logger.Info("Node matches the configuration, nothing to reconcile")
+		return printResult(verification, func(w io.Writer) {
+			_, _ = fmt.Fprintln(w, "Node matches the configuration, nothing to reconcile")
+		})
+	}
+
+	for _, step := range verification.Steps {
+		if !step.Completed {
+			logger.Infof("Step %s drifted from the configuration", step.StepName)
+		}
+	}
+	result, err := bootstrapExecutor.Bootstrap(ctx)
+	return reportExecutionResult(result, err, "reconcile", logger)
+}
+
+// runUpgrade upgrades node components to the configured (or overridden) versions
+func runUpgrade(ctx context.Context, kubernetesVersion string, timeout time.Duration) error {
+	logger := logger.GetLoggerFromContext(ctx)
+	ctx, cancel, err := withRunTimeout(ctx, timeout)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	cfg := config.GetConfig()
+	if kubernetesVersion != "" {
+		logger.Infof("Upgrading Kubernetes from configured version %s to %s", cfg.Kubernetes.Version, kubernetesVersion)
+		cfg.Kubernetes.Version = strings.TrimPrefix(kubernetesVersion, "v")
+	}
+
+	bootstrapExecutor := bootstrapper.New(cfg, logger, Version)
+	result, err := bootstrapExecutor.Upgrade(ctx)
+	return reportExecutionResult(result, err, "upgrade", logger)
+}
+
+// runPlan prints the Azure writes bootstrap would perform
+func runPlan(ctx context.Context, script bool) error {
+	logger := logger.GetLoggerFromContext(ctx)
+
+	planner := arc.NewPlanner(logger)
+	writes, err := planner.Plan(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to plan Azure writes: %w", err)
+	}
+	if script {
+		fmt.Print(planner.Script(writes))
+		return nil
+	}
+
+	return printResult(writes, func(w io.Writer) {
+		if len(writes) == 0 {
+			_, _ = fmt.Fprintln(w, "Bootstrap performs no Azure writes with this configuration")
+			return
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "OPERATION\tROLE\tPRINCIPAL\tSCOPE\tEXISTS")
+		for _, write := range writes {
+			principal := write.PrincipalID
+			if principal == "" && write.Role != "" {
+				principal = "<arc machine identity>"
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\n", write.Operation, write.Role, principal, write.Scope, write.Exists)
+		}
+		_ = tw.Flush()
+	})
+}
+
+// runPreflight runs the preflight checks and fails if any check failed
+func runPreflight(ctx context.Context) error {
+	logger := logger.GetLoggerFromContext(ctx)
+
+	results := preflight.NewChecker(logger).Run(ctx)
+	if err := printResult(results, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "CHECK\tSTATUS\tMESSAGE")
+		for _, result := range results {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Name, result.Status, result.Message)
+			for _, ep := range result.Endpoints {
+				status := "reachable"
+				if !ep.Reachable {
+					status = "unreachable: " + ep.Error
+				}
+				_, _ = fmt.Fprintf(tw, "  %s\t\t%s %s\n", ep.Name, ep.URL, status)
+			}
+		}
+		_ = tw.Flush()
+	}); err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, result := range results {
+		if result.Status == preflight.StatusFail {
+			failed = append(failed, result.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %v", preflight.ErrChecksFailed, failed)
+	}
+	return nil
+}
+
+// statusReport is the output of the status command
+type statusReport struct {
+	Node          *status.NodeStatus       `json:"node"`
+	LastBootstrap *bootstrapper.Checkpoint `json:"lastBootstrap,omitempty"`
+}
+
+// runStatus collects and prints the current node status
+func runStatus(ctx context.Context) error {
+	logger := logger.GetLoggerFromContext(ctx)
+	cfg := config.GetConfig()
+
+	nodeStatus, err := status.NewCollector(cfg, logger, Version).CollectStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to collect node status: %w", err)
+	}
+	report := statusReport{Node: nodeStatus}
+	if checkpoint, err := bootstrapper.New(cfg, logger, Version).LastCheckpoint("bootstrap"); err == nil {
+		report.LastBootstrap = checkpoint
+	} else {
+		logger.Debugf("No bootstrap checkpoint available: %v", err)
+	}
+
+	return printResult(report, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Kubelet:	%s (running: %t, ready: %s)\n", nodeStatus.KubeletVersion, nodeStatus.KubeletRunning, nodeStatus.KubeletReady)
+		_, _ = fmt.Fprintf(tw, "Containerd:	%s (running: %t)\n", nodeStatus.ContainerdVersion, nodeStatus.ContainerdRunning)
+		_, _ = fmt.Fprintf(tw, "Runc:	%s\n", nodeStatus.RuncVersion)
+		_, _ = fmt.Fprintf(tw, "Arc:	registered: %t, connected: %t\n", nodeStatus.ArcStatus.Registered, nodeStatus.ArcStatus.Connected)
+		if report.LastBootstrap != nil && report.LastBootstrap.Result != nil {
+			_, _ = fmt.Fprintf(tw, "Last bootstrap:	%s (success: %t)\n",
+				report.LastBootstrap.CompletedAt.Format(time.RFC3339), report.LastBootstrap.Result.Success)
+		}
+		_, _ = fmt.Fprintf(tw, "Agent version:	%s\n", nodeStatus.AgentVersion)
+		_ = tw.Flush()
+	})
+}
+
+// runVerify checks every bootstrap step without changing the host
+func runVerify(ctx context.Context) error {
 	logger := logger.GetLoggerFromContext(ctx)
 
-	cfg, err := config.LoadConfig(configPath)
+	result := bootstrapper.New(config.GetConfig(), logger, Version).Verify(ctx)
+	if err := printResult(result, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "STEP	STATE")
+		for _, step := range result.Steps {
+			state := "ok"
+			if !step.Completed {
+				state = "drifted"
+			}
+			_, _ = fmt.Fprintf(tw, "%s	%s\n", step.StepName, state)
+		}
+		_ = tw.Flush()
+	}); err != nil {
+		return err
+	}
+
+	if !result.Success {
+		return fmt.Errorf("node does not match the configuration")
+	}
+	return nil
+}
+
+// initResult is the output of the init command
+type initResult struct {
+	Config  string `json:"config"`
+	Cluster string `json:"cluster"`
+	Auth    string `json:"auth"`
+}
+
+// runInit asks for the settings of a new configuration file and writes it
+func runInit(ctx context.Context, force bool) error {
+	if len(configOverlays) > 0 {
+		return &usageError{fmt.Errorf("init writes a single configuration file, pass --config once")}
+	}
+	if len(configOverrides) > 0 {
+		return &usageError{fmt.Errorf("init does not take --set, edit the written file instead")}
+	}
+	if config.IsRemote(configPath) {
+		return &usageError{fmt.Errorf("init writes a local configuration file, not a URL")}
+	}
+	path := configPath
+	if path == "" {
+		path = defaultConfigPath
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		return &usageError{fmt.Errorf("%s already exists, pass --force to overwrite it", path)}
+	}
+
+	// Questions go to stderr so stdout only carries the result; only warnings are logged between them
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.WarnLevel)
+	w := wizard.New(logger, os.Stdin, os.Stderr)
+	answers, err := w.Run(ctx)
+	if err != nil {
+		return &usageError{err}
+	}
+	if err := w.Check(ctx, answers); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+		if !w.Confirm("Write the configuration file anyway?") {
+			return fmt.Errorf("configuration file not written: %w", err)
+		}
+	}
+	if err := wizard.Write(path, answers); err != nil {
+		return err
+	}
+
+	result := initResult{Config: path, Cluster: answers.ClusterResourceID, Auth: answers.Auth}
+	return printResult(result, func(w io.Writer) {
+		_, _ = fmt.Fprintf(w, "Wrote %s\n", path)
+		_, _ = fmt.Fprintf(w, "Check the host with:  aks-flex-node preflight --config %s\n", path)
+		_, _ = fmt.Fprintf(w, "Join the cluster with: aks-flex-node bootstrap --config %s\n", path)
+	})
+}
+
+// configValidationResult is the output of the config validate command
+type configValidationResult struct {
+	Config    string               `json:"config"`
+	Overlays  []string             `json:"overlays,omitempty"`
+	Overrides []string             `json:"overrides,omitempty"` // The --set flags
+	Valid     bool                 `json:"valid"`
+	Error     string               `json:"error,omitempty"`
+	Errors    []config.SchemaError `json:"errors,omitempty"` // Schema violations with their file, line and field
+}
+
+// runConfigValidate checks the configuration file against the schema, then loads and validates it
+func runConfigValidate() error {
+	if configPath == "" {
+		return &usageError{fmt.Errorf("config path is required for validate command")}
+	}
+
+	var overlays []string
+	for _, overlay := range configOverlays {
+		overlays = append(overlays, config.LayerName(overlay))
+	}
+	result := configValidationResult{Config: config.LayerName(configPath), Overlays: overlays, Overrides: configSets, Valid: true}
+	schemaErrors, loadErr := config.CheckSchema(configPath, configOverlays...)
+	if loadErr == nil && len(schemaErrors) > 0 {
+		// Loading would fail on the same mistakes with less precise errors, or silently ignore unknown fields
+		result.Errors = schemaErrors
+		loadErr = fmt.Errorf("%d schema errors", len(schemaErrors))
+	}
+	if loadErr == nil {
+		_, loadErr = config.LoadConfigWithOverrides(configPath, configOverlays, configOverrides)
+	}
+	if loadErr != nil {
+		result.Valid = false
+		result.Error = loadErr.Error()
+	}
+
+	// Name every file when overlays are merged onto the base
+	name := strings.Join(append([]string{result.Config}, overlays...), " + ")
+	if err := printResult(result, func(w io.Writer) {
+		switch {
+		case result.Valid:
+			_, _ = fmt.Fprintf(w, "%s is valid\n", name)
+		case len(result.Errors) > 0:
+			_, _ = fmt.Fprintf(w, "%s is invalid:\n", name)
+			for _, schemaErr := range result.Errors {
+				_, _ = fmt.Fprintf(w, "  %s\n", schemaErr.Error())
+			}
+		default:
+			_, _ = fmt.Fprintf(w, "%s is invalid: %s\n", name, result.Error)
+		}
+	}); err != nil {
+		return err
+	}
+
+	if loadErr != nil {
+		return &usageError{fmt.Errorf("invalid config %s: %w", name, loadErr)}
+	}
+	return nil
+}
+
+// runConfigSchema prints the JSON Schema of the config file. The schema is JSON in either output format.
+func runConfigSchema() error {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config.Schema())
+}
+
+// configEncryptResult is the output of the config encrypt command
+type configEncryptResult struct {
+	Value string `json:"value"`
+}
+
+// runConfigEncrypt reads a secret from in and prints its @encrypted config value
+func runConfigEncrypt(ctx context.Context, in io.Reader, key string) error {
+	data, err := io.ReadAll(in)
 	if err != nil {
-		return fmt.Errorf("failed to load config from %s: %w", configPath, err)
+		return fmt.Errorf("failed to read the secret from stdin: %w", err)
 	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return &usageError{fmt.Errorf("no secret given on stdin")}
+	}
+	value, err := config.EncryptValue(ctx, key, secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt the secret: %w", err)
+	}
+	return printResult(configEncryptResult{Value: value}, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, value)
+	})
+}
 
-	bootstrapExecutor := bootstrapper.New(cfg, logger)
-	result, err := bootstrapExecutor.Unbootstrap(ctx)
+// configProvenance is the output of the config provenance command
+type configProvenance struct {
+	Layers []string          `json:"layers"`
+	Values map[string]string `json:"values"`
+}
+
+// runConfigProvenance prints the merged config layers and the layer that set each value
+func runConfigProvenance() error {
+	cfg := config.GetConfig()
+	result := configProvenance{Layers: cfg.Layers(), Values: cfg.Provenance()}
+
+	return printResult(result, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "Layers (lowest precedence first):")
+		for _, layer := range result.Layers {
+			_, _ = fmt.Fprintf(w, "  %s\n", layer)
+		}
+		_, _ = fmt.Fprintln(w)
+
+		fields := make([]string, 0, len(result.Values))
+		for field := range result.Values {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "FIELD\tSOURCE")
+		for _, field := range fields {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\n", field, result.Values[field])
+		}
+		_ = tw.Flush()
+	})
+}
+
+// runDiagnostics collects a diagnostics bundle and prints its location
+func runDiagnostics(ctx context.Context, bundlePath string) error {
+	logger := logger.GetLoggerFromContext(ctx)
+
+	collector := diagnostics.NewCollector(config.GetConfig(), logger)
+	path, err := collector.Collect(ctx, bundlePath)
 	if err != nil {
 		return err
 	}
 
-	// Handle and log the result (unbootstrap is more lenient with failures)
-	return handleExecutionResult(result, "unbootstrap", logger)
+	return printResult(map[string]string{"bundle": path}, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, path)
+	})
+}
+
+// versionInfo is the output of the version command
+type versionInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildTime string `json:"buildTime"`
 }
 
 // runVersion displays version information
-func runVersion() {
-	fmt.Printf("AKS Flex Node Agent\n")
-	fmt.Printf("Version: %s\n", Version)
-	fmt.Printf("Git Commit: %s\n", GitCommit)
-	fmt.Printf("Build Time: %s\n", BuildTime)
+func runVersion() error {
+	info := versionInfo{Version: Version, GitCommit: GitCommit, BuildTime: BuildTime}
+	return printResult(info, func(w io.Writer) {
+		_, _ = fmt.Fprintf(w, "AKS Flex Node Agent\n")
+		_, _ = fmt.Fprintf(w, "Version: %s\n", info.Version)
+		_, _ = fmt.Fprintf(w, "Git Commit: %s\n", info.GitCommit)
+		_, _ = fmt.Fprintf(w, "Build Time: %s\n", info.BuildTime)
+	})
 }
 
 // runDaemonLoop runs the periodic status collection and bootstrap monitoring daemon
@@ -139,24 +817,35 @@ func runDaemonLoop(ctx context.Context, cfg *config.Config) error {
 		}
 	}
 
-	logger.Info("Starting periodic status collection daemon (status: 1 minutes, bootstrap check: 2 minute)")
+	logger.Info("Starting periodic status collection daemon (status: 1 minutes, bootstrap check: 2 minute, cluster check: 10 minutes)")
 
 	// Create tickers for different intervals
 	statusTicker := time.NewTicker(1 * time.Minute)
 	bootstrapTicker := time.NewTicker(2 * time.Minute)
-	specTicker := time.NewTicker(30 * time.Minute)
+	specTicker := time.NewTicker(10 * time.Minute)
 	defer statusTicker.Stop()
 	defer bootstrapTicker.Stop()
 	defer specTicker.Stop()
 
-	// Collect status immediately on start
-	if err := collectAndWriteStatus(ctx, cfg, statusFilePath); err != nil {
-		logger.Errorf("Failed to collect initial status: %v", err)
+	// Re-fetch a config given as a URL, so changes made centrally reach the node
+	var configRefresh <-chan time.Time
+	if len(cfg.RemoteSources()) > 0 {
+		logger.Infof("Refreshing the remote config every %v", cfg.Agent.ConfigRefresh)
+		configTicker := time.NewTicker(cfg.Agent.ConfigRefresh)
+		defer configTicker.Stop()
+		configRefresh = configTicker.C
 	}
+	configPending := false
 
-	// Collect managed cluster spec once on daemon startup.
-	if err := collectAndWriteManagedClusterSpec(ctx, cfg); err != nil {
-		logger.Warnf("Failed to collect initial managed cluster spec: %v", err)
+	// Collect managed cluster spec once on daemon startup; it also tells whether the cluster still exists
+	watch := newClusterWatch(cfg, logger)
+	if watch.check(ctx) {
+		return unbootstrapLostCluster(ctx, cfg)
+	}
+
+	// Collect status immediately on start
+	if err := collectAndWriteStatus(ctx, cfg, statusFilePath, watch.state); err != nil {
+		logger.Errorf("Failed to collect initial status: %v", err)
 	}
 
 	// Run the periodic collection and monitoring loop
@@ -167,13 +856,17 @@ func runDaemonLoop(ctx context.Context, cfg *config.Config) error {
 			return ctx.Err()
 		case <-statusTicker.C:
 			logger.Infof("Starting periodic status collection at %s...", time.Now().Format("2006-01-02 15:04:05"))
-			if err := collectAndWriteStatus(ctx, cfg, statusFilePath); err != nil {
+			if err := collectAndWriteStatus(ctx, cfg, statusFilePath, watch.state); err != nil {
 				logger.Errorf("Failed to collect status at %s: %v", time.Now().Format("2006-01-02 15:04:05"), err)
 				// Continue running even if status collection fails
 			} else {
 				logger.Infof("Status collection completed successfully at %s", time.Now().Format("2006-01-02 15:04:05"))
 			}
 		case <-bootstrapTicker.C:
+			if !watch.available() {
+				logger.Infof("Skipping bootstrap health check: target cluster is %s", watch.state)
+				continue
+			}
 			logger.Infof("Starting bootstrap health check at %s...", time.Now().Format("2006-01-02 15:04:05"))
 			if err := checkAndBootstrap(ctx, cfg); err != nil {
 				logger.Errorf("Auto-bootstrap check failed at %s: %v", time.Now().Format("2006-01-02 15:04:05"), err)
@@ -183,20 +876,95 @@ func runDaemonLoop(ctx context.Context, cfg *config.Config) error {
 			}
 		case <-specTicker.C:
 			logger.Infof("Starting periodic managed cluster spec collection at %s...", time.Now().Format("2006-01-02 15:04:05"))
-			if err := collectAndWriteManagedClusterSpec(ctx, cfg); err != nil {
-				logger.Warnf("Failed to collect managed cluster spec at %s: %v", time.Now().Format("2006-01-02 15:04:05"), err)
-			} else {
-				logger.Infof("Managed cluster spec collection completed at %s", time.Now().Format("2006-01-02 15:04:05"))
+			if watch.check(ctx) {
+				return unbootstrapLostCluster(ctx, cfg)
+			}
+		case <-configRefresh:
+			if refreshed := refreshRemoteConfig(ctx); refreshed != nil {
+				cfg, watch.cfg = refreshed, refreshed
+				configPending = true
 			}
+			// A change seen while the cluster is unavailable is applied once it is back
+			if configPending && watch.available() {
+				configPending = false
+				reconcileConfig(ctx, cfg)
+			}
+		}
+	}
+}
+
+// refreshRemoteConfig reloads the configuration and returns it if one of its remote sources changed, or
+// nil to keep the current one. The token broker and registry credential refresh keep the settings they
+// started with until the agent restarts.
+func refreshRemoteConfig(ctx context.Context) *config.Config {
+	logger := logger.GetLoggerFromContext(ctx)
+	refreshed, err := config.LoadConfigWithOverrides(configPath, configOverlays, configOverrides)
+	if err != nil {
+		logger.Errorf("Failed to refresh config, keeping the current one: %v", err)
+		return nil
+	}
+	changed := false
+	for _, source := range refreshed.RemoteSources() {
+		if source.Err != nil {
+			logger.Warnf("Config source %s: %v", source.URL, source.Err)
+		}
+		if source.Changed {
+			logger.Infof("Config source %s changed", source.URL)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return refreshed
+}
+
+// reconcileConfig bootstraps the node again if it drifted from a refreshed configuration
+func reconcileConfig(ctx context.Context, cfg *config.Config) {
+	logger := logger.GetLoggerFromContext(ctx)
+	bootstrapExecutor := bootstrapper.New(cfg, logger, Version)
+	verification := bootstrapExecutor.Verify(ctx)
+	if verification.Success {
+		logger.Info("Node matches the refreshed configuration")
+		return
+	}
+	for _, step := range verification.Steps {
+		if !step.Completed {
+			logger.Infof("Step %s drifted from the refreshed configuration", step.StepName)
 		}
 	}
+	result, err := bootstrapExecutor.Bootstrap(ctx)
+	if err == nil {
+		err = handleExecutionResult(result, "config refresh", logger)
+	}
+	if err != nil {
+		// Remove the status file so the next bootstrap check retries
+		removeStatusFile(ctx)
+		logger.Errorf("Failed to apply the refreshed configuration: %v", err)
+		return
+	}
+	logger.Info("Refreshed configuration applied")
 }
 
-func collectAndWriteManagedClusterSpec(ctx context.Context, cfg *config.Config) error {
+// unbootstrapLostCluster removes the node from the machine after its cluster stayed deleted for the
+// agent.clusterLoss grace period, then ends the daemon since there is nothing left to manage
+func unbootstrapLostCluster(ctx context.Context, cfg *config.Config) error {
 	logger := logger.GetLoggerFromContext(ctx)
-	collector := spec.NewManagedClusterSpecCollector(cfg, logger)
-	_, err := collector.Collect(ctx)
-	return err
+	logger.Warnf("Target cluster %s stayed deleted for %v, unbootstrapping the node (agent.clusterLoss.action=unbootstrap)",
+		cfg.GetTargetClusterID(), cfg.Agent.ClusterLoss.GracePeriod)
+
+	// The machine resource is kept so the operator decides whether to delete or reconnect it. There is no API
+	// server left to drain the node through.
+	result, err := bootstrapper.New(cfg, logger, Version).Unbootstrap(ctx, bootstrapper.UnbootstrapOptions{})
+	if err != nil {
+		return fmt.Errorf("unbootstrap after cluster deletion failed: %w", err)
+	}
+	// Failed cleanup steps are only logged, since restarting the agent would not bring the cluster back
+	if err := handleExecutionResult(result, "unbootstrap", logger); err != nil && !errors.Is(err, bootstrapper.ErrPartial) {
+		return err
+	}
+	logger.Info("Node unbootstrapped after cluster deletion, agent exiting")
+	return nil
 }
 
 // checkAndBootstrap checks if the node needs re-bootstrapping and performs it if necessary
@@ -214,7 +982,7 @@ func checkAndBootstrap(ctx context.Context, cfg *config.Config) error {
 	logger.Info("Node requires re-bootstrapping, initiating auto-bootstrap...")
 
 	// Perform bootstrap
-	bootstrapExecutor := bootstrapper.New(cfg, logger)
+	bootstrapExecutor := bootstrapper.New(cfg, logger, Version)
 	result, err := bootstrapExecutor.Bootstrap(ctx)
 	if err != nil {
 		// Bootstrap failed - remove status file so next check will detect the problem
@@ -244,7 +1012,7 @@ func removeStatusFile(ctx context.Context) {
 }
 
 // collectAndWriteStatus collects current node status and writes it to the status file
-func collectAndWriteStatus(ctx context.Context, cfg *config.Config, statusFilePath string) error {
+func collectAndWriteStatus(ctx context.Context, cfg *config.Config, statusFilePath, clusterState string) error {
 	logger := logger.GetLoggerFromContext(ctx)
 
 	// Create status collector
@@ -255,6 +1023,7 @@ func collectAndWriteStatus(ctx context.Context, cfg *config.Config, statusFilePa
 	if err != nil {
 		return fmt.Errorf("failed to collect node status: %w", err)
 	}
+	nodeStatus.ClusterState = clusterState
 
 	// Write status to JSON file
 	statusData, err := json.MarshalIndent(nodeStatus, "", "  ")
@@ -276,6 +1045,21 @@ func collectAndWriteStatus(ctx context.Context, cfg *config.Config, statusFilePa
 	return nil
 }
 
+// reportExecutionResult prints the result of a one-shot operation and converts it into the command error
+func reportExecutionResult(result *bootstrapper.ExecutionResult, execErr error, operation string, logger *logrus.Logger) error {
+	if result != nil {
+		if err := printResult(result, func(w io.Writer) {
+			writeExecutionResult(w, operation, result)
+		}); err != nil {
+			return err
+		}
+	}
+	if execErr != nil {
+		return execErr
+	}
+	return handleExecutionResult(result, operation, logger)
+}
+
 // handleExecutionResult processes and logs execution results
 func handleExecutionResult(result *bootstrapper.ExecutionResult, operation string, logger *logrus.Logger) error {
 	if result == nil {
@@ -289,10 +1073,10 @@ func handleExecutionResult(result *bootstrapper.ExecutionResult, operation strin
 	}
 
 	if operation == "unbootstrap" {
-		// For unbootstrap, log warnings but don't fail completely
+		// Unbootstrap ran every cleanup step, but reports the ones that failed with a distinct exit code
 		logger.Warnf("%s completed with some failures: %s (duration: %v)",
 			operation, result.Error, result.Duration)
-		return nil
+		return fmt.Errorf("%s %w: %s", operation, bootstrapper.ErrPartial, result.Error)
 	}
 
 	// For bootstrap, return error on failure
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/auth"
+	"go.goms.io/aks/AKSFlexNode/pkg/bootstrapper"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: exitSuccess},
+		{name: "usage", err: &usageError{errors.New("unknown flag")}, want: exitUsage},
+		{name: "wrapped usage", err: fmt.Errorf("config: %w", &usageError{errors.New("invalid")}), want: exitUsage},
+		{name: "aborted", err: fmt.Errorf("bootstrap: %w", bootstrapper.ErrAborted), want: exitAborted},
+		{name: "authentication", err: fmt.Errorf("token: %w", auth.ErrAuthentication), want: exitAuth},
+		{name: "unauthorized", err: &azcore.ResponseError{StatusCode: http.StatusUnauthorized}, want: exitAuth},
+		{name: "forbidden", err: &azcore.ResponseError{StatusCode: http.StatusForbidden}, want: exitRBAC},
+		{name: "download", err: &utilio.DownloadError{URL: "https://example.com", StatusCode: 503}, want: exitNetwork},
+		{name: "validation", err: fmt.Errorf("step: %w", bootstrapper.ErrValidation), want: exitValidation},
+		{name: "partial", err: fmt.Errorf("unbootstrap: %w", bootstrapper.ErrPartial), want: exitPartial},
+		{name: "other failure", err: errors.New("kubelet is not running"), want: exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// runCommand runs the root command with args and returns its exit code and what it printed to stdout
+func runCommand(t *testing.T, args ...string) (int, []byte) {
+	t.Helper()
+	var out bytes.Buffer
+	original := stdout
+	t.Cleanup(func() { stdout = original })
+	stdout = &out
+
+	cmd := newRootCommand()
+	cmd.SetArgs(args)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	err := cmd.ExecuteContext(context.Background())
+	return exitCode(err), out.Bytes()
+}
+
+// writeTestConfig writes a valid config logging to a temp directory and returns its path
+func writeTestConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := fmt.Sprintf(`{
+		"azure": {
+			"subscriptionId": "12345678-1234-1234-1234-123456789012",
+			"tenantId": "12345678-1234-1234-1234-123456789012",
+			"cloud": "AzurePublicCloud",
+			"bootstrapToken": {"token": "abcdef.0123456789abcdef"},
+			"targetCluster": {
+				"resourceId": "/subscriptions/12345678-1234-1234-1234-123456789012/resourceGroups/test-rg/providers/Microsoft.ContainerService/managedClusters/test-cluster",
+				"location": "eastus"
+			}
+		},
+		"agent": {"logLevel": "info", "logDir": %q},
+		"node": {
+			"kubelet": {
+				"serverURL": "https://test-cluster-abc123.hcp.eastus.azmk8s.io:443",
+				"caCertData": "LS0tLS1CRUdJTi1DRVJUSUZJQ0FURS0tLS0tCk1JSUREekNDQWZlZ0F3SUJBZ0lSQU1kbzBZa0R"
+			}
+		}
+	}`, filepath.Join(dir, "logs"))
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommandExitCodes(t *testing.T) {
+	configFile := writeTestConfig(t)
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "version", args: []string{"version"}, want: exitSuccess},
+		{name: "invalid output format", args: []string{"version", "--output", "yaml"}, want: exitUsage},
+		{name: "unknown flag", args: []string{"status", "--no-such-flag"}, want: exitUsage},
+		{name: "missing config", args: []string{"status"}, want: exitUsage},
+		{name: "missing config file", args: []string{"status", "--config", filepath.Join(t.TempDir(), "missing.json")}, want: exitUsage},
+		{name: "invalid set override", args: []string{"status", "--config", configFile, "--set", "node.labels"}, want: exitUsage},
+		{name: "diagnostics bundle in a missing directory", args: []string{"diagnostics", "--config", configFile, "--file", filepath.Join(t.TempDir(), "missing", "bundle.tar.gz")}, want: exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := runCommand(t, tt.args...); got != tt.want {
+				t.Errorf("exit code of %v = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionJSONOutput(t *testing.T) {
+	code, out := runCommand(t, "version", "-o", "json")
+	if code != exitSuccess {
+		t.Fatalf("exit code = %d, want %d", code, exitSuccess)
+	}
+	var info versionInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		t.Fatalf("output %q is not JSON: %v", out, err)
+	}
+	if want := (versionInfo{Version: Version, GitCommit: GitCommit, BuildTime: BuildTime}); info != want {
+		t.Errorf("version output = %+v, want %+v", info, want)
+	}
+}
+
+func TestDiagnosticsFileFlag(t *testing.T) {
+	configFile := writeTestConfig(t)
+	for _, flag := range []string{"-f", "--file"} {
+		t.Run(flag, func(t *testing.T) {
+			bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+			code, out := runCommand(t, "diagnostics", "--config", configFile, "--output", "json", flag, bundle)
+			if code != exitSuccess {
+				t.Fatalf("exit code = %d, want %d", code, exitSuccess)
+			}
+			var result map[string]string
+			if err := json.Unmarshal(out, &result); err != nil {
+				t.Fatalf("output %q is not JSON: %v", out, err)
+			}
+			if result["bundle"] != bundle {
+				t.Errorf("bundle = %q, want %q", result["bundle"], bundle)
+			}
+			if _, err := os.Stat(bundle); err != nil {
+				t.Errorf("bundle not written: %v", err)
+			}
+		})
+	}
+}
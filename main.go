@@ -11,59 +11,147 @@ import (
 
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
 	"go.goms.io/aks/AKSFlexNode/pkg/logger"
+	"go.goms.io/aks/AKSFlexNode/pkg/progress"
+	"go.goms.io/aks/AKSFlexNode/pkg/tui"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
 )
 
 var (
-	configPath string
+	// configPaths are the --config files; configPath is the first and configOverlays are merged onto it in order
+	configPaths    []string
+	configPath     string
+	configOverlays []string
+	// configSets are the --set path=value flags, parsed into configOverrides
+	configSets      []string
+	configOverrides []config.Override
+
+	// progressFD and progressSocket select where progress events are written
+	progressFD     int
+	progressSocket string
+	// progressReporter writes progress events of bootstrap, upgrade and unbootstrap, if requested
+	progressReporter *progress.Reporter
+
+	// tuiEnabled shows runs in progressView, the terminal UI
+	tuiEnabled   bool
+	progressView *tui.View
 )
 
+// skipConfigAnnotation marks commands that run without loading the config in the persistent pre-run
+const skipConfigAnnotation = "aks-flex-node/skip-config"
+
 func main() {
+	// Set up context with signal handling
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Execute command with context
+	err := newRootCommand().ExecuteContext(ctx)
+	if progressView != nil {
+		progressView.Stop()
+	}
+	_ = progressReporter.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Command execution failed: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// newRootCommand builds the aks-flex-node command with its global flags and subcommands
+func newRootCommand() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "aks-flex-node",
 		Short: "AKS Flex Node Agent",
 		Long:  "Azure Kubernetes Service Flex Node Agent for edge computing scenarios",
+		// Errors are reported once by main; usage is only useful for invalid flags
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		_ = cmd.Usage()
+		return &usageError{err}
+	})
 
 	// Add global flags for configuration
-	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to configuration JSON file (required)")
+	rootCmd.PersistentFlags().StringArrayVar(&configPaths, "config", nil, "Path or https URL of the configuration file, JSON, YAML or TOML by extension (required). Repeat to merge overlay files onto it in order, the last one winning")
+	rootCmd.PersistentFlags().StringArrayVar(&configSets, "set", nil, "Override a config field as path=value, e.g. node.labels.zone=b (repeatable, applied after all config files)")
 	// Don't mark as required globally - we'll check in PersistentPreRunE for commands that need it
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "Output format for command results: text or json")
+	rootCmd.PersistentFlags().IntVar(&progressFD, "progress-fd", 0, "Write progress events as JSON lines to this inherited file descriptor (3 or higher)")
+	rootCmd.PersistentFlags().StringVar(&progressSocket, "progress-socket", "", "Write progress events as JSON lines to the unix socket listening at this path")
+	rootCmd.PersistentFlags().BoolVar(&tuiEnabled, "tui", false, "Show bootstrap, upgrade and unbootstrap runs in a terminal UI with the step list and a scrollable log")
 
 	// Add commands
 	rootCmd.AddCommand(NewAgentCommand())
+	rootCmd.AddCommand(NewBootstrapCommand())
 	rootCmd.AddCommand(NewUnbootstrapCommand())
+	rootCmd.AddCommand(NewReconcileCommand())
+	rootCmd.AddCommand(NewStatusCommand())
+	rootCmd.AddCommand(NewVerifyCommand())
+	rootCmd.AddCommand(NewUpgradeCommand())
+	rootCmd.AddCommand(NewPlanCommand())
+	rootCmd.AddCommand(NewPreflightCommand())
+	rootCmd.AddCommand(NewDiagnosticsCommand())
+	rootCmd.AddCommand(NewInitCommand())
+	rootCmd.AddCommand(NewConfigCommand())
 	rootCmd.AddCommand(NewVersionCommand())
 
-	// Set up context with signal handling
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer cancel()
-
 	// Set up persistent pre-run to initialize config and logger
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
-		// Skip config loading for version command
-		if cmd.Name() == "version" {
+		if err := validateOutputFormat(); err != nil {
+			return err
+		}
+		if len(configPaths) > 0 {
+			configPath, configOverlays = configPaths[0], configPaths[1:]
+		}
+		configOverrides = nil
+		for _, set := range configSets {
+			override, err := config.ParseOverride(set)
+			if err != nil {
+				return &usageError{err}
+			}
+			configOverrides = append(configOverrides, override)
+		}
+
+		// Skip config loading for commands that do not need it (version) or load it themselves (config validate)
+		if cmd.Annotations[skipConfigAnnotation] == "true" {
 			return nil
 		}
 
 		// For other commands, config is required
 		if configPath == "" {
-			return fmt.Errorf("config path is required for %s command", cmd.Name())
+			return &usageError{fmt.Errorf("config path is required for %s command", cmd.Name())}
 		}
 
 		// Load config if specified
-		cfg, err := config.LoadConfig(configPath)
+		cfg, err := config.LoadConfigWithOverrides(configPath, configOverlays, configOverrides)
 		if err != nil {
-			return fmt.Errorf("failed to load config from %s: %w", configPath, err)
+			return &usageError{fmt.Errorf("failed to load config from %s: %w", config.LayerName(configPath), err)}
+		}
+
+		utilio.SetDownloadTimeout(cfg.GetTimeouts().Download)
+		utilio.SetDownloadRetry(cfg.GetRetries().Download.Backoff())
+
+		// Keep stdout free for the JSON result; logs go to stderr and the log file
+		if outputFormat == outputJSON {
+			logger.SetConsoleOutput(os.Stderr)
 		}
 
 		// Setup logger and update context
 		ctx := logger.SetupLogger(cmd.Context(), cfg.Agent.LogLevel, cfg.Agent.LogDir)
-		cmd.SetContext(ctx)
+		for _, source := range cfg.RemoteSources() {
+			if source.Err != nil {
+				logger.GetLoggerFromContext(ctx).Warnf("Config source %s: %v", source.URL, source.Err)
+			}
+		}
+		if progressReporter, err = openProgress(); err != nil {
+			return err
+		}
+		if progressView, err = openView(logger.GetLoggerFromContext(ctx)); err != nil {
+			return err
+		}
+		cmd.SetContext(progress.WithReporter(ctx, progressReporter))
 		return nil
 	}
 
-	// Execute command with context
-	if err := rootCmd.ExecuteContext(ctx); err != nil {
-		fmt.Fprintf(os.Stderr, "Command execution failed: %v\n", err)
-		os.Exit(1)
-	}
+	return rootCmd
 }
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/bootstrapper"
+	"go.goms.io/aks/AKSFlexNode/pkg/progress"
+	"go.goms.io/aks/AKSFlexNode/pkg/tui"
+)
+
+// Output formats accepted by the global --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// Process exit codes shared by all commands
+const (
+	exitSuccess = 0 // Command completed and the node is in the requested state
+	exitFailure = 1 // Command ran but the operation failed or the node is not in the desired state
+	exitUsage   = 2 // Invalid flags, arguments or configuration; nothing was changed on the host
+	exitAborted = 3 // The operation was stopped by --timeout or a signal; running it again resumes it
+
+	exitAuth       = 4 // No Azure token could be obtained, or Azure rejected it
+	exitRBAC       = 5 // Azure denied an operation to the authenticated identity
+	exitNetwork    = 6 // A connection or download failed
+	exitValidation = 7 // A preflight check or the precondition of a step failed
+	exitPartial    = 8 // Unbootstrap finished, but some cleanup steps failed
+)
+
+// failureExitCodes maps the failure classes of the bootstrapper to exit codes
+var failureExitCodes = map[string]int{
+	bootstrapper.FailureAborted:    exitAborted,
+	bootstrapper.FailureAuth:       exitAuth,
+	bootstrapper.FailureRBAC:       exitRBAC,
+	bootstrapper.FailureNetwork:    exitNetwork,
+	bootstrapper.FailureValidation: exitValidation,
+	bootstrapper.FailurePartial:    exitPartial,
+}
+
+// outputFormat selects how command results are printed
+var outputFormat string
+
+// stdout receives command results; tests capture it
+var stdout io.Writer = os.Stdout
+
+// usageError marks errors caused by invalid input rather than a failed operation
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// exitCode maps a command error to the process exit code
+func exitCode(err error) int {
+	if err == nil {
+		return exitSuccess
+	}
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+	if code, ok := failureExitCodes[bootstrapper.ClassifyFailure(err)]; ok {
+		return code
+	}
+	return exitFailure
+}
+
+// validateOutputFormat checks the global --output flag
+func validateOutputFormat() error {
+	if outputFormat != outputText && outputFormat != outputJSON {
+		return &usageError{fmt.Errorf("invalid --output %q, valid formats are: text, json", outputFormat)}
+	}
+	return nil
+}
+
+// openProgress opens the progress reporter selected by --progress-fd or --progress-socket, or returns nil
+func openProgress() (*progress.Reporter, error) {
+	switch {
+	case progressFD != 0 && progressSocket != "":
+		return nil, &usageError{fmt.Errorf("--progress-fd and --progress-socket are mutually exclusive")}
+	case progressFD != 0:
+		reporter, err := progress.OpenFD(progressFD)
+		if err != nil {
+			return nil, &usageError{err}
+		}
+		return reporter, nil
+	case progressSocket != "":
+		reporter, err := progress.Dial(progressSocket)
+		if err != nil {
+			return nil, &usageError{err}
+		}
+		return reporter, nil
+	default:
+		return nil, nil
+	}
+}
+
+// openView creates the terminal UI requested by --tui, or returns nil. The view listens to the progress
+// reporter, which is created for it when no progress output was requested.
+func openView(logger *logrus.Logger) (*tui.View, error) {
+	if !tuiEnabled {
+		return nil, nil
+	}
+	if outputFormat == outputJSON {
+		return nil, &usageError{fmt.Errorf("--tui cannot be combined with --output json")}
+	}
+	if progressReporter == nil {
+		progressReporter = progress.New()
+	}
+	view, err := tui.New(logger, progressReporter)
+	if err != nil {
+		return nil, &usageError{err}
+	}
+	return view, nil
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from in. Anything but "y" or "yes",
+// including a closed or non-interactive input, counts as no.
+func confirm(in io.Reader, prompt string) bool {
+	_, _ = fmt.Fprint(os.Stderr, prompt)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// printResult writes v to stdout as indented JSON, or through the text renderer otherwise
+func printResult(v any, text func(w io.Writer)) error {
+	if outputFormat == outputJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	text(stdout)
+	return nil
+}
+
+// writeExecutionResult renders a bootstrap, upgrade or unbootstrap result as a step table
+func writeExecutionResult(w io.Writer, operation string, result *bootstrapper.ExecutionResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "STEP\tRESULT\tDURATION\tERROR")
+	for _, step := range result.StepResults {
+		outcome := "done"
+		switch {
+		case !step.Success:
+			outcome = "failed"
+		case step.Skipped:
+			outcome = "unchanged"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", step.StepName, outcome, step.Duration.Round(time.Millisecond), step.Error)
+	}
+	_ = tw.Flush()
+
+	if result.Success {
+		_, _ = fmt.Fprintf(w, "\n%s succeeded in %s\n", operation, result.Duration.Round(time.Millisecond))
+	} else {
+		_, _ = fmt.Fprintf(w, "\n%s failed after %s: %s\n", operation, result.Duration.Round(time.Millisecond), result.Error)
+		if result.Aborted {
+			_, _ = fmt.Fprintf(w, "Run %s again to resume at step %s\n", operation, result.ResumeFrom)
+		}
+		for _, step := range result.StepResults {
+			if !step.Success && step.LogFile != "" {
+				_, _ = fmt.Fprintf(w, "Log of step %s: %s\n", step.StepName, step.LogFile)
+			}
+		}
+	}
+}
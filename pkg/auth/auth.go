@@ -2,9 +2,11 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
@@ -12,6 +14,37 @@ import (
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
 )
 
+// ErrAuthentication marks failures to obtain an Azure token: the identity is unavailable on the host, its
+// secret or federated token is invalid, or the Azure CLI is not logged in
+var ErrAuthentication = errors.New("authentication failed")
+
+// authError marks err with ErrAuthentication without changing its message
+type authError struct {
+	err error
+}
+
+func (e *authError) Error() string {
+	return e.err.Error()
+}
+
+func (e *authError) Unwrap() []error {
+	return []error{e.err, ErrAuthentication}
+}
+
+// credential marks the token failures of the wrapped credential with ErrAuthentication, including those
+// returned through Azure SDK clients
+type credential struct {
+	azcore.TokenCredential
+}
+
+func (c *credential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	token, err := c.TokenCredential.GetToken(ctx, options)
+	if err != nil && ctx.Err() == nil {
+		return token, &authError{err}
+	}
+	return token, err
+}
+
 // AuthProvider is a simple factory for Azure credentials
 type AuthProvider struct{}
 
@@ -26,34 +59,73 @@ func (a *AuthProvider) ArcCredential() (azcore.TokenCredential, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Arc credential: %w", err)
 	}
-	return cred, nil
+	return &credential{cred}, nil
 }
 
-// UserCredential returns credential based on config (service principal, MSI, or CLI fallback)
+// UserCredential returns credential based on config (service principal, federated identity, MSI, or CLI fallback)
 func (a *AuthProvider) UserCredential(cfg *config.Config) (azcore.TokenCredential, error) {
 	if cfg.IsSPConfigured() {
 		return a.serviceCredential(cfg)
 	}
+	if cfg.IsFederatedIdentityConfigured() {
+		return a.federatedCredential(cfg)
+	}
 	if cfg.IsMIConfigured() {
 		return a.msiCredential(cfg)
 	}
 	return a.cliCredential()
 }
 
-// msiCredential creates managed identity credential for VM MSI with optional ClientID
-func (a *AuthProvider) msiCredential(cfg *config.Config) (azcore.TokenCredential, error) {
-	options := &azidentity.ManagedIdentityCredentialOptions{}
+// NodeCredential returns the credential of the machine's own identity: the Arc managed identity, or the
+// configured service principal, federated identity or managed identity. Unlike UserCredential, it never falls
+// back to the Azure CLI, since it is used unattended.
+func (a *AuthProvider) NodeCredential(cfg *config.Config) (azcore.TokenCredential, error) {
+	switch {
+	case cfg.IsARCEnabled():
+		return a.ArcCredential()
+	case cfg.IsSPConfigured(), cfg.IsFederatedIdentityConfigured(), cfg.IsMIConfigured():
+		return a.UserCredential(cfg)
+	}
+	return nil, fmt.Errorf("no node identity is configured; use Arc, managed identity, service principal or federated identity")
+}
 
-	// If ClientID is specified, use it to select a specific managed identity
-	if cfg.Azure.ManagedIdentity != nil && cfg.Azure.ManagedIdentity.ClientID != "" {
-		options.ID = azidentity.ClientID(cfg.Azure.ManagedIdentity.ClientID)
+// ClusterCredential returns the credential used for calls against the target AKS cluster and its subscription.
+// For a cluster in another tenant, service principal and federated identity tokens are requested from the
+// cluster tenant, which requires the app registration to be multi-tenant and provisioned there.
+func (a *AuthProvider) ClusterCredential(cfg *config.Config) (azcore.TokenCredential, error) {
+	if !cfg.IsCrossTenant() {
+		return a.UserCredential(cfg)
 	}
+	clusterTenantID := cfg.Azure.TargetCluster.TenantID
+	switch {
+	case cfg.IsSPConfigured():
+		sp := cfg.Azure.ServicePrincipal
+		cred, err := azidentity.NewClientSecretCredential(clusterTenantID, sp.ClientID, sp.ClientSecret, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create service principal credential for cluster tenant %s: %w", clusterTenantID, err)
+		}
+		return &credential{cred}, nil
+	case cfg.IsFederatedIdentityConfigured():
+		return a.clientAssertionCredential(clusterTenantID, cfg.Azure.FederatedIdentity)
+	default:
+		cred, err := azidentity.NewAzureCLICredential(&azidentity.AzureCLICredentialOptions{TenantID: clusterTenantID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CLI credential for cluster tenant %s: %w", clusterTenantID, err)
+		}
+		return &credential{cred}, nil
+	}
+}
+
+// msiCredential creates managed identity credential for VM MSI with optional ClientID or resource ID
+func (a *AuthProvider) msiCredential(cfg *config.Config) (azcore.TokenCredential, error) {
+	// If ClientID or ResourceID is specified, use it to select a specific managed identity
+	options := &azidentity.ManagedIdentityCredentialOptions{ID: cfg.GetManagedIdentityID()}
 
 	cred, err := azidentity.NewManagedIdentityCredential(options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create managed identity credential: %w", err)
 	}
-	return cred, nil
+	return &credential{cred}, nil
 }
 
 // serviceCredential creates service principal credential from config
@@ -67,7 +139,30 @@ func (a *AuthProvider) serviceCredential(cfg *config.Config) (azcore.TokenCreden
 	if err != nil {
 		return nil, fmt.Errorf("failed to create service principal credential: %w", err)
 	}
-	return cred, nil
+	return &credential{cred}, nil
+}
+
+// federatedCredential creates a client assertion credential that exchanges an OIDC token for an Azure AD token
+func (a *AuthProvider) federatedCredential(cfg *config.Config) (azcore.TokenCredential, error) {
+	return a.clientAssertionCredential(cfg.Azure.FederatedIdentity.TenantID, cfg.Azure.FederatedIdentity)
+}
+
+// clientAssertionCredential creates a client assertion credential for the given tenant.
+// The token file is read on every request so an external issuer can rotate it in place.
+func (a *AuthProvider) clientAssertionCredential(tenantID string, federated *config.FederatedIdentityConfig) (azcore.TokenCredential, error) {
+	getAssertion := func(context.Context) (string, error) {
+		token, err := os.ReadFile(federated.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read federated token file %s: %w", federated.TokenFile, err)
+		}
+		return strings.TrimSpace(string(token)), nil
+	}
+
+	cred, err := azidentity.NewClientAssertionCredential(tenantID, federated.ClientID, getAssertion, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create federated identity credential: %w", err)
+	}
+	return &credential{cred}, nil
 }
 
 // cliCredential creates Azure CLI credential
@@ -76,7 +171,7 @@ func (a *AuthProvider) cliCredential() (azcore.TokenCredential, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CLI credential: %w", err)
 	}
-	return cred, nil
+	return &credential{cred}, nil
 }
 
 // GetAccessToken retrieves access token for given credential with default ARM scope
@@ -103,13 +198,13 @@ func (a *AuthProvider) CheckCLIAuthStatus(ctx context.Context) error {
 	// Try to get account information - this will fail if not logged in or token expired
 	cmd := exec.CommandContext(ctx, "az", "account", "show", "--output", "json")
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("azure CLI authentication check failed: %w", err)
+		return &authError{fmt.Errorf("azure CLI authentication check failed: %w", err)}
 	}
 
 	// Try to get an access token to verify it's not expired
 	cmd = exec.CommandContext(ctx, "az", "account", "get-access-token", "--output", "json")
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("azure CLI token validation failed: %w", err)
+		return &authError{fmt.Errorf("azure CLI token validation failed: %w", err)}
 	}
 
 	return nil
@@ -130,7 +225,7 @@ func (a *AuthProvider) InteractiveAzLogin(ctx context.Context, tenantID string)
 
 	// Run the interactive login command
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("interactive Azure CLI login failed: %w", err)
+		return &authError{fmt.Errorf("interactive Azure CLI login failed: %w", err)}
 	}
 
 	return nil
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/msi/armmsi"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// UserAssignedIdentity holds the IDs of a user-assigned managed identity
+type UserAssignedIdentity struct {
+	ClientID    string
+	PrincipalID string
+}
+
+// ResolveUserAssignedIdentity looks up the client ID and principal ID of the user-assigned identity
+// configured by resource ID. It authenticates as that identity, so the identity needs read access
+// to its own resource (e.g. Managed Identity Operator or Reader on it).
+func (a *AuthProvider) ResolveUserAssignedIdentity(ctx context.Context, cfg *config.Config) (*UserAssignedIdentity, error) {
+	if cfg.Azure.ManagedIdentity == nil || cfg.Azure.ManagedIdentity.ResourceID == "" {
+		return nil, fmt.Errorf("no managed identity resource ID configured")
+	}
+	id, err := arm.ParseResourceID(cfg.Azure.ManagedIdentity.ResourceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid managed identity resource ID: %w", err)
+	}
+
+	cred, err := a.msiCredential(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := armmsi.NewUserAssignedIdentitiesClient(id.SubscriptionID, cred, cfg.GetARMClientOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user-assigned identities client: %w", err)
+	}
+	resp, err := client.Get(ctx, id.ResourceGroupName, id.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user-assigned identity %s: %w", id.Name, err)
+	}
+	if resp.Properties == nil || resp.Properties.ClientID == nil {
+		return nil, fmt.Errorf("user-assigned identity %s has no client ID", id.Name)
+	}
+	return &UserAssignedIdentity{
+		ClientID:    to.String(resp.Properties.ClientID),
+		PrincipalID: to.String(resp.Properties.PrincipalID),
+	}, nil
+}
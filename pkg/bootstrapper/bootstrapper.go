@@ -2,65 +2,244 @@ package bootstrapper
 
 import (
 	"context"
+	"runtime"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"go.goms.io/aks/AKSFlexNode/pkg/components/arc"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/ca_certificates"
 	"go.goms.io/aks/AKSFlexNode/pkg/components/cni"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/confidential"
 	"go.goms.io/aks/AKSFlexNode/pkg/components/containerd"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/crio"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/crun"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/custom_steps"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/drain"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/gvisor"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/image_prepull"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/installed_files"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kata"
 	"go.goms.io/aks/AKSFlexNode/pkg/components/kube_binaries"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kube_proxy"
 	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/mig"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/node_local_dns"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/node_metadata"
 	"go.goms.io/aks/AKSFlexNode/pkg/components/npd"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/plugins"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/preflight"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/rdma"
 	"go.goms.io/aks/AKSFlexNode/pkg/components/runc"
 	"go.goms.io/aks/AKSFlexNode/pkg/components/services"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/sriov"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/stargz"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/storage"
 	"go.goms.io/aks/AKSFlexNode/pkg/components/system_configuration"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/windows"
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
 )
 
+// hostOS is the operating system of the node, which picks the step lists. Tests set it to build the lists
+// of another one.
+var hostOS = runtime.GOOS
+
 // Bootstrapper executes bootstrap steps sequentially
 type Bootstrapper struct {
 	*BaseExecutor
+	version string // Version of the agent, recorded on the node
 }
 
-// New creates a new bootstrapper
-func New(cfg *config.Config, logger *logrus.Logger) *Bootstrapper {
+// New creates a new bootstrapper for the agent of the given version
+func New(cfg *config.Config, logger *logrus.Logger, agentVersion string) *Bootstrapper {
 	return &Bootstrapper{
 		BaseExecutor: NewBaseExecutor(cfg, logger),
+		version:      agentVersion,
 	}
 }
 
 // Bootstrap executes all bootstrap steps sequentially
 func (b *Bootstrapper) Bootstrap(ctx context.Context) (*ExecutionResult, error) {
-	// Define the bootstrap steps in order - using modules directly
+	defer installed_files.StartRecording(b.config)()
+	result, err := b.ExecuteSteps(ctx, b.bootstrapSteps(), "bootstrap")
+	b.notifyWebhook(ctx, result)
+	return result, err
+}
+
+// bootstrapSteps returns the bootstrap steps in execution order, with the configured plugins and custom steps
+// in place. Custom steps go in last, so they can also run after a plugin.
+func (b *Bootstrapper) bootstrapSteps() []Executor {
+	if hostOS == "windows" {
+		return b.windowsBootstrapSteps()
+	}
+	steps := []Executor{
+		preflight.NewChecker(b.logger),                      // Check host and network before changing anything
+		system_configuration.NewSnapshotter(b.logger),       // Record the host state unbootstrap restores (before anything changes it)
+		ca_certificates.NewInstaller(b.logger),              // Trust extra CAs before anything downloads through a TLS-intercepting proxy
+		arc.NewInstaller(b.logger),                          // Setup Arc
+		services.NewUnInstaller(b.logger),                   // Stop kubelet before setup
+		system_configuration.NewInstaller(b.logger),         // Configure system (early)
+		storage.NewInstaller(b.logger),                      // Mount the data disk for containerd and kubelet state (before either writes it)
+		runc.NewInstaller(b.logger),                         // Install runc
+		crun.NewInstaller(b.logger),                         // Install crun when runtime.ociRuntime selects it (before containerd points at it)
+		stargz.NewInstaller(b.logger),                       // Run the stargz snapshotter when selected (before containerd uses it)
+		containerd.NewInstaller(b.logger),                   // Install containerd
+		crio.NewInstaller(b.logger),                         // Install CRI-O instead when runtime.containerRuntime selects it
+		kube_binaries.NewInstaller(b.logger),                // Install k8s binaries
+		cni.NewInstaller(b.logger),                          // Setup CNI (after container runtime)
+		sriov.NewInstaller(b.logger),                        // Prepare SR-IOV devices and hugepages (after CNI directories, before kubelet)
+		rdma.NewInstaller(b.logger),                         // Prepare RDMA devices and check their links (before services restart the runtime)
+		mig.NewInstaller(b.logger),                          // Partition GPUs into MIG devices (before kubelet, so the device plugin finds them)
+		node_local_dns.NewInstaller(b.logger),               // Set up the node-local DNS cache kubelet points pods at (before kubelet)
+		kubelet.NewInstaller(b.logger),                      // Configure kubelet service with Arc MSI auth
+		gvisor.NewInstaller(b.logger),                       // Install gVisor and its RuntimeClass when enabled (after kubelet kubeconfig)
+		kata.NewInstaller(b.logger),                         // Install Kata Containers and its RuntimeClass when enabled (after kubelet kubeconfig)
+		confidential.NewInstaller(b.logger),                 // Prepare SEV-SNP or TDX and the kata-cc RuntimeClass when enabled (after Kata)
+		kube_proxy.NewInstaller(b.logger),                   // Run kube-proxy when the cluster does not schedule it (after kubelet kubeconfig)
+		npd.NewInstaller(b.logger),                          // Install Node Problem Detector
+		image_prepull.NewInstaller(b.logger),                // Pre-pull images so pods do not all pull at once when the node is Ready (before kubelet starts)
+		services.NewInstaller(b.logger),                     // Start services
+		system_configuration.NewHugepagesVerifier(b.logger), // Check kubelet reports the configured hugepages (after kubelet starts)
+		kubelet.NewServingCertificateVerifier(b.logger),     // Wait for kubelet's serving certificate from the cluster CA (after kubelet starts)
+		node_metadata.NewInstaller(b.logger, b.version),     // Annotate the node with how it was provisioned (after it registers)
+	}
+	for _, plugin := range b.config.Plugins {
+		steps = insertAfter(steps, plugin.After, plugins.NewInstaller(b.logger, plugin), b.logger)
+	}
+	for _, step := range b.config.CustomSteps {
+		steps = insertAfter(steps, step.After, custom_steps.NewInstaller(b.logger, step), b.logger)
+	}
+	return steps
+}
+
+// windowsBootstrapSteps returns the bootstrap steps of a Windows node, with the configured plugins in place.
+// The preflight checks read /proc and run ip, iptables, ufw and firewall-cmd, the Arc installer runs the Linux
+// azcmagent script, and custom steps install packages and systemd units, so none of them run on Windows.
+// Validation rejects Arc on Windows, so the node is never left without the identity it was configured for.
+func (b *Bootstrapper) windowsBootstrapSteps() []Executor {
+	steps := []Executor{
+		windows.NewServicesUnInstaller(b.logger),   // Stop kubelet before setup
+		windows.NewSystemConfigurator(b.logger),    // Enable the Containers feature, Defender exclusions and firewall rules (early)
+		windows.NewContainerdInstaller(b.logger),   // Install containerd and register its service
+		windows.NewKubeBinariesInstaller(b.logger), // Install kubelet, kube-proxy and kubectl
+		windows.NewCNIInstaller(b.logger),          // Set up win-bridge on the HNS network (after containerd)
+		windows.NewKubeletInstaller(b.logger),      // Register the kubelet service
+		windows.NewKubeProxyInstaller(b.logger),    // Register the kube-proxy service when enabled (after kubelet kubeconfig)
+		windows.NewServicesInstaller(b.logger),     // Start services
+	}
+	for _, plugin := range b.config.Plugins {
+		steps = insertAfter(steps, plugin.After, plugins.NewInstaller(b.logger, plugin), b.logger)
+	}
+	return steps
+}
+
+// Upgrade moves the node's runtime and Kubernetes binaries to the versions in the configuration.
+// Installers detect version mismatches in IsCompleted, so only outdated components are replaced.
+func (b *Bootstrapper) Upgrade(ctx context.Context) (*ExecutionResult, error) {
+	if hostOS == "windows" {
+		steps := []Executor{
+			windows.NewServicesUnInstaller(b.logger),   // Stop kube-proxy, kubelet and containerd while binaries are replaced
+			windows.NewContainerdInstaller(b.logger),   // Upgrade containerd
+			windows.NewKubeBinariesInstaller(b.logger), // Upgrade k8s binaries
+			windows.NewKubeletInstaller(b.logger),      // Refresh kubelet configuration for the new version
+			windows.NewKubeProxyInstaller(b.logger),    // Refresh the kube-proxy service
+			windows.NewServicesInstaller(b.logger),     // Start services
+		}
+		defer installed_files.StartRecording(b.config)()
+		return b.ExecuteSteps(ctx, steps, "upgrade")
+	}
 	steps := []Executor{
-		arc.NewInstaller(b.logger),                  // Setup Arc
-		services.NewUnInstaller(b.logger),           // Stop kubelet before setup
-		system_configuration.NewInstaller(b.logger), // Configure system (early)
-		runc.NewInstaller(b.logger),                 // Install runc
-		containerd.NewInstaller(b.logger),           // Install containerd
-		kube_binaries.NewInstaller(b.logger),        // Install k8s binaries
-		cni.NewInstaller(b.logger),                  // Setup CNI (after container runtime)
-		kubelet.NewInstaller(b.logger),              // Configure kubelet service with Arc MSI auth
-		npd.NewInstaller(b.logger),                  // Install Node Problem Detector
-		services.NewInstaller(b.logger),             // Start services
+		services.NewUnInstaller(b.logger),               // Stop kubelet and containerd while binaries are replaced
+		runc.NewInstaller(b.logger),                     // Upgrade runc
+		crun.NewInstaller(b.logger),                     // Upgrade crun when selected
+		stargz.NewInstaller(b.logger),                   // Upgrade the stargz snapshotter when selected
+		containerd.NewInstaller(b.logger),               // Upgrade containerd
+		crio.NewInstaller(b.logger),                     // Upgrade CRI-O when selected
+		gvisor.NewInstaller(b.logger),                   // Upgrade gVisor when enabled
+		kata.NewInstaller(b.logger),                     // Upgrade Kata Containers when enabled
+		kube_binaries.NewInstaller(b.logger),            // Upgrade k8s binaries
+		kubelet.NewInstaller(b.logger),                  // Refresh kubelet configuration for the new version
+		services.NewInstaller(b.logger),                 // Start services
+		node_metadata.NewInstaller(b.logger, b.version), // Record the new component versions on the node
 	}
 
-	return b.ExecuteSteps(ctx, steps, "bootstrap")
+	defer installed_files.StartRecording(b.config)()
+	return b.ExecuteSteps(ctx, steps, "upgrade")
+}
+
+// Verify checks whether every bootstrap installer reports its desired state without changing the node
+func (b *Bootstrapper) Verify(ctx context.Context) *VerifyResult {
+	result := &VerifyResult{Success: true}
+	for _, step := range b.bootstrapSteps() {
+		// Only installers describe desired state; uninstallers in the bootstrap list are transient actions
+		if _, ok := step.(StepExecutor); !ok {
+			continue
+		}
+		completed := step.IsCompleted(ctx)
+		result.Steps = append(result.Steps, StepVerification{StepName: step.GetName(), Completed: completed})
+		if !completed {
+			result.Success = false
+		}
+	}
+	return result
+}
+
+// UnbootstrapOptions controls what unbootstrap removes beyond the node itself
+type UnbootstrapOptions struct {
+	DeleteArcResource bool          // Delete the Arc machine resource in Azure instead of only disconnecting the agent locally
+	DrainTimeout      time.Duration // How long evictions may take before the drain fails, zero skips the drain
+	Force             bool          // Delete the pods left after the drain timeout without honoring PodDisruptionBudgets
 }
 
 // Unbootstrap executes all cleanup steps sequentially (in reverse order of bootstrap)
-func (b *Bootstrapper) Unbootstrap(ctx context.Context) (*ExecutionResult, error) {
+func (b *Bootstrapper) Unbootstrap(ctx context.Context, opts UnbootstrapOptions) (*ExecutionResult, error) {
+	if hostOS == "windows" {
+		return b.ExecuteSteps(ctx, b.windowsUnbootstrapSteps(), "unbootstrap")
+	}
 	steps := []Executor{
-		services.NewUnInstaller(b.logger),             // Stop services first
-		npd.NewUnInstaller(b.logger),                  // Uninstall Node Problem Detector
-		kubelet.NewUnInstaller(b.logger),              // Clean kubelet configuration
-		cni.NewUnInstaller(b.logger),                  // Clean CNI configs
-		kube_binaries.NewUnInstaller(b.logger),        // Uninstall k8s binaries
-		containerd.NewUnInstaller(b.logger),           // Uninstall containerd binary
-		runc.NewUnInstaller(b.logger),                 // Uninstall runc binary
-		system_configuration.NewUnInstaller(b.logger), // Clean system settings
-		arc.NewUnInstaller(b.logger),                  // Uninstall Arc (after cleanup)
+		drain.NewUnInstaller(b.logger, opts.DrainTimeout, opts.Force), // Move workloads off the node while kubelet still runs
+		services.NewUnInstaller(b.logger),                             // Stop services first
+		storage.NewUnInstaller(b.logger),                              // Unmount the data disk (before kubelet and containerd remove their directories)
+		npd.NewUnInstaller(b.logger),                                  // Uninstall Node Problem Detector
+		kube_proxy.NewUnInstaller(b.logger),                           // Stop kube-proxy
+		kubelet.NewUnInstaller(b.logger),                              // Clean kubelet configuration
+		node_local_dns.NewUnInstaller(b.logger),                       // Remove the node-local DNS cache, its interface and rules
+		mig.NewUnInstaller(b.logger),                                  // Destroy the MIG devices and turn MIG mode off
+		rdma.NewUnInstaller(b.logger),                                 // Remove the RDMA modules, udev rules and memlock drop-ins
+		sriov.NewUnInstaller(b.logger),                                // Remove SR-IOV virtual functions and kernel arguments
+		cni.NewUnInstaller(b.logger),                                  // Clean CNI configs
+		kube_binaries.NewUnInstaller(b.logger),                        // Uninstall k8s binaries
+		gvisor.NewUnInstaller(b.logger),                               // Remove gVisor binaries
+		confidential.NewUnInstaller(b.logger),                         // Remove the confidential computing module settings
+		kata.NewUnInstaller(b.logger),                                 // Remove the Kata Containers release
+		containerd.NewUnInstaller(b.logger),                           // Uninstall containerd binary
+		crio.NewUnInstaller(b.logger),                                 // Uninstall CRI-O
+		stargz.NewUnInstaller(b.logger),                               // Remove the stargz snapshotter and its cached layers
+		crun.NewUnInstaller(b.logger),                                 // Remove crun
+		runc.NewUnInstaller(b.logger),                                 // Uninstall runc binary
+		system_configuration.NewUnInstaller(b.logger),                 // Clean system settings
+		system_configuration.NewSnapshotRestorer(b.logger),            // Put back the sysctl values, swap devices and modules found before bootstrap
+		installed_files.NewUnInstaller(b.logger),                      // Remove what bootstrap recorded and the uninstallers left behind
+		arc.NewUnInstaller(b.logger, opts.DeleteArcResource),          // Uninstall Arc (after cleanup)
+	}
+	// Plugins are removed right after the drain while the node still runs, the last configured first
+	for _, plugin := range b.config.Plugins {
+		steps = insertAfter(steps, "NodeDrained", plugins.NewUnInstaller(b.logger, plugin), b.logger)
 	}
 
 	return b.ExecuteSteps(ctx, steps, "unbootstrap")
 }
+
+// windowsUnbootstrapSteps returns the cleanup steps of a Windows node. The node is not drained, since the
+// drain runs kubectl with the Linux kubelet kubeconfig; plugins are removed first, the last configured first.
+func (b *Bootstrapper) windowsUnbootstrapSteps() []Executor {
+	steps := []Executor{
+		windows.NewServicesUnInstaller(b.logger), // Stop services first
+		windows.NewUnInstaller(b.logger),         // Delete the services, HNS network, firewall rules and node directories
+		installed_files.NewUnInstaller(b.logger), // Remove what bootstrap recorded and the uninstallers left behind
+	}
+	for _, plugin := range b.config.Plugins {
+		steps = append([]Executor{plugins.NewUnInstaller(b.logger, plugin)}, steps...)
+	}
+	return steps
+}
@@ -0,0 +1,64 @@
+package bootstrapper
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// stepNames returns the names of steps in order
+func stepNames(steps []Executor) []string {
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.GetName())
+	}
+	return names
+}
+
+func TestWindowsStepLists(t *testing.T) {
+	origOS := hostOS
+	t.Cleanup(func() { hostOS = origOS })
+	hostOS = "windows"
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := &config.Config{
+		Plugins: []config.PluginConfig{
+			{Name: "Inventory", After: "WindowsKubeletInstaller"},
+			{Name: "Monitoring"},
+		},
+		CustomSteps: []config.CustomStepConfig{{Name: "LinuxOnly"}},
+	}
+	b := New(cfg, logger, "v1.0.0")
+
+	wantBootstrap := []string{
+		"WindowsServicesDisabled",
+		"WindowsSystemConfigured",
+		"WindowsContainerdInstaller",
+		"WindowsKubeBinariesInstaller",
+		"WindowsCNIInstaller",
+		"WindowsKubeletInstaller",
+		"Inventory",
+		"WindowsKubeProxyInstaller",
+		"WindowsServicesEnabled",
+		"Monitoring",
+	}
+	if got := stepNames(b.bootstrapSteps()); !reflect.DeepEqual(got, wantBootstrap) {
+		t.Errorf("bootstrapSteps() = %v, want %v", got, wantBootstrap)
+	}
+
+	wantUnbootstrap := []string{
+		"MonitoringUnInstaller",
+		"InventoryUnInstaller",
+		"WindowsServicesDisabled",
+		"WindowsNodeRemoved",
+		"InstalledFilesRemoved",
+	}
+	if got := stepNames(b.windowsUnbootstrapSteps()); !reflect.DeepEqual(got, wantUnbootstrap) {
+		t.Errorf("windowsUnbootstrapSteps() = %v, want %v", got, wantUnbootstrap)
+	}
+}
@@ -0,0 +1,92 @@
+package bootstrapper
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/state"
+)
+
+const (
+	// historyKey holds the audit trail of recent bootstrap and unbootstrap runs
+	historyKey = "history"
+	// maxHistoryEntries bounds the audit trail kept in the state store
+	maxHistoryEntries = 20
+)
+
+// Checkpoint records the outcome of a bootstrap, upgrade or unbootstrap run in the state store
+type Checkpoint struct {
+	Operation   string           `json:"operation"`
+	CompletedAt time.Time        `json:"completedAt"`
+	Result      *ExecutionResult `json:"result"`
+}
+
+// lastRunKey returns the state key holding the most recent checkpoint for an operation
+func lastRunKey(operation string) string {
+	return "last-" + operation
+}
+
+// recordResult persists the run outcome as the latest checkpoint and appends it to the audit history.
+// State persistence is best effort and never changes the outcome of the run.
+func (be *BaseExecutor) recordResult(operation string, result *ExecutionResult) {
+	store, err := state.Open(be.config, be.logger)
+	if err != nil {
+		be.logger.Warnf("Failed to open state store, %s checkpoint not recorded: %v", operation, err)
+		return
+	}
+	defer func() { _ = store.Close() }()
+
+	checkpoint := Checkpoint{
+		Operation:   operation,
+		CompletedAt: time.Now().UTC(),
+		Result:      result,
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		be.logger.Warnf("Failed to encode %s checkpoint: %v", operation, err)
+		return
+	}
+	if err := store.Put(lastRunKey(operation), data); err != nil {
+		be.logger.Warnf("Failed to record %s checkpoint: %v", operation, err)
+		return
+	}
+
+	var history []Checkpoint
+	if existing, err := store.Get(historyKey); err == nil {
+		if err := json.Unmarshal(existing, &history); err != nil {
+			be.logger.Warnf("Discarding unreadable audit history: %v", err)
+			history = nil
+		}
+	} else if !errors.Is(err, state.ErrNotFound) {
+		be.logger.Warnf("Failed to read audit history: %v", err)
+	}
+	history = append(history, checkpoint)
+	if len(history) > maxHistoryEntries {
+		history = history[len(history)-maxHistoryEntries:]
+	}
+	if data, err := json.Marshal(history); err == nil {
+		if err := store.Put(historyKey, data); err != nil {
+			be.logger.Warnf("Failed to record audit history: %v", err)
+		}
+	}
+}
+
+// LastCheckpoint returns the most recent recorded checkpoint for an operation ("bootstrap", "upgrade" or "unbootstrap")
+func (be *BaseExecutor) LastCheckpoint(operation string) (*Checkpoint, error) {
+	store, err := state.Open(be.config, be.logger)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = store.Close() }()
+
+	data, err := store.Get(lastRunKey(operation))
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
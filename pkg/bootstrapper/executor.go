@@ -8,6 +8,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/progress"
 )
 
 // executor is a common base interface for all executors
@@ -32,6 +33,19 @@ type StepExecutor interface {
 	Validate(ctx context.Context) error
 }
 
+// CleanupGate is implemented by unbootstrap steps whose failure stops the cleanup, because the remaining steps
+// would disrupt what the step failed to protect
+type CleanupGate interface {
+	Executor
+
+	// StopsCleanupOnFailure reports whether unbootstrap stops when the step fails
+	StopsCleanupOnFailure() bool
+}
+
+// ErrAborted is returned when a run stops because its context was cancelled, by the --timeout deadline or a
+// signal. Running the operation again skips the completed steps and resumes where it stopped.
+var ErrAborted = errors.New("aborted")
+
 // ExecutionResult represents the result of bootstrap or unbootstrap process
 type ExecutionResult struct {
 	Success     bool          `json:"success"`
@@ -39,14 +53,36 @@ type ExecutionResult struct {
 	Duration    time.Duration `json:"duration"`
 	StepResults []StepResult  `json:"step_results"`
 	Error       string        `json:"error,omitempty"`
+	Aborted     bool          `json:"aborted,omitempty"`     // The run was cancelled before it finished
+	ResumeFrom  string        `json:"resume_from,omitempty"` // Step the next run of an aborted operation starts at
+
+	FailureClass string `json:"failure_class,omitempty"` // Class of the failure, one of the Failure constants
 }
 
 // StepResult represents the result of a single step
 type StepResult struct {
 	StepName string        `json:"step_name"`
 	Success  bool          `json:"success"`
+	Skipped  bool          `json:"skipped,omitempty"` // Step was already completed and did not run
 	Duration time.Duration `json:"duration"`
 	Error    string        `json:"error,omitempty"`
+	LogFile  string        `json:"log_file,omitempty"` // Log output of this step alone, also included in the combined log
+
+	FailureClass string `json:"failure_class,omitempty"` // Class of the failure, one of the Failure constants
+
+	err error // Error of a failed step, kept so the run error wraps it
+}
+
+// VerifyResult reports whether the node matches the desired state of each bootstrap step
+type VerifyResult struct {
+	Success bool               `json:"success"`
+	Steps   []StepVerification `json:"steps"`
+}
+
+// StepVerification is the verification outcome of a single step
+type StepVerification struct {
+	StepName  string `json:"step_name"`
+	Completed bool   `json:"completed"`
 }
 
 // BaseExecutor provides common functionality for bootstrap and unbootstrap operations
@@ -67,28 +103,61 @@ func NewBaseExecutor(cfg *config.Config, logger *logrus.Logger) *BaseExecutor {
 func (be *BaseExecutor) ExecuteSteps(ctx context.Context, steps []Executor, stepType string) (*ExecutionResult, error) {
 	be.logger.Infof("Starting AKS node %s", stepType)
 
+	if err := injector.load(be.config); err != nil {
+		return nil, err
+	}
+	if injector.active() {
+		be.logger.Warn("Fault injection is active: configured steps will fail on purpose")
+	}
+
 	startTime := time.Now()
 	result := &ExecutionResult{
 		StepResults: make([]StepResult, 0),
 	}
+	// Persist the final outcome for both the fail-fast and the completed paths
+	defer be.recordResult(stepType, result)
 
-	// Execute each step
+	reporter := progress.FromContext(ctx)
+	stepNames := make([]string, 0, len(steps))
 	for _, step := range steps {
+		stepNames = append(stepNames, step.GetName())
+	}
+	reporter.Report(progress.Event{Operation: stepType, Event: progress.EventRunStarted, Steps: stepNames, Total: len(steps)})
+	defer reportRunFinished(reporter, stepType, result, len(steps))
+
+	// Execute each step
+	for i, step := range steps {
+		if ctx.Err() != nil {
+			return be.abort(ctx, result, step.GetName(), stepType, startTime)
+		}
+		reporter.Report(progress.Event{
+			Operation: stepType, Event: progress.EventStepStarted, Step: step.GetName(),
+			Index: i + 1, Total: len(steps), Percent: progress.Percent(i, len(steps)),
+		})
+		logFile, stopCapture := be.captureStepLogs(stepType, i+1, step.GetName())
 		stepResult := be.executeStep(ctx, step, stepType)
+		stopCapture()
+		stepResult.LogFile = logFile
 		result.StepResults = append(result.StepResults, stepResult)
+		reportStepFinished(reporter, stepType, stepResult, i+1, len(steps))
 
 		if !stepResult.Success {
-			if stepType == "bootstrap" {
-				// Bootstrap fails fast on first error
+			if ctx.Err() != nil {
+				// The step failed because the run was cancelled under it
+				return be.abort(ctx, result, stepResult.StepName, stepType, startTime)
+			}
+			if stepType != "unbootstrap" || stopsCleanup(step) {
+				// Bootstrap and upgrade fail fast on first error, and so does unbootstrap at a cleanup gate
 				result.Success = false
 				result.Error = stepResult.Error
+				result.FailureClass = stepResult.FailureClass
 				result.Duration = time.Since(startTime)
 				result.StepCount = len(result.StepResults)
 
-				be.logger.Errorf("Bootstrap failed at step %s: %s (completedSteps: %d, totalSteps: %d)",
-					stepResult.StepName, stepResult.Error, len(result.StepResults), len(steps))
+				be.logger.Errorf("AKS node %s failed at step %s: %s (completedSteps: %d, totalSteps: %d)",
+					stepType, stepResult.StepName, stepResult.Error, len(result.StepResults), len(steps))
 
-				return result, fmt.Errorf("bootstrap failed at step %s: %w", stepResult.StepName, errors.New(stepResult.Error))
+				return result, fmt.Errorf("%s failed at step %s: %w", stepType, stepResult.StepName, stepResult.err)
 			}
 			// Unbootstrap continues even if some steps fail for best effort cleanup
 			be.logger.Warnf("Cleanup step %s failed: %s (continuing with remaining steps)",
@@ -110,11 +179,61 @@ func (be *BaseExecutor) ExecuteSteps(ctx context.Context, steps []Executor, step
 			stepType, result.Duration, successfulSteps, len(steps))
 		result.Error = fmt.Sprintf("completed with %d failed steps out of %d total steps",
 			len(steps)-successfulSteps, len(steps))
+		result.FailureClass = FailurePartial
 	}
 
 	return result, nil
 }
 
+// abort ends a cancelled run at the given step. Steps release what they hold through their context and deferred
+// cleanup; the result recorded as the checkpoint names the step the next run resumes at.
+func (be *BaseExecutor) abort(ctx context.Context, result *ExecutionResult, stepName, stepType string, startTime time.Time) (*ExecutionResult, error) {
+	cause := context.Cause(ctx)
+	result.Success = false
+	result.Aborted = true
+	result.ResumeFrom = stepName
+	result.FailureClass = FailureAborted
+	result.Error = fmt.Sprintf("aborted at step %s: %v", stepName, cause)
+	result.Duration = time.Since(startTime)
+	result.StepCount = len(result.StepResults)
+
+	be.logger.Errorf("AKS node %s aborted at step %s: %v (completedSteps: %d); run %s again to resume",
+		stepType, stepName, cause, be.countSuccessfulSteps(result.StepResults), stepType)
+	return result, fmt.Errorf("%s %w at step %s: %w", stepType, ErrAborted, stepName, cause)
+}
+
+// reportStepFinished reports the outcome of a step to the progress reporter
+func reportStepFinished(reporter *progress.Reporter, stepType string, stepResult StepResult, index, total int) {
+	outcome := progress.ResultDone
+	switch {
+	case !stepResult.Success:
+		outcome = progress.ResultFailed
+	case stepResult.Skipped:
+		outcome = progress.ResultSkipped
+	}
+	reporter.Report(progress.Event{
+		Operation: stepType, Event: progress.EventStepFinished, Step: stepResult.StepName,
+		Index: index, Total: total, Percent: progress.Percent(index, total),
+		Result: outcome, Duration: stepResult.Duration.Seconds(), Error: stepResult.Error,
+	})
+}
+
+// reportRunFinished reports the outcome of the run to the progress reporter
+func reportRunFinished(reporter *progress.Reporter, stepType string, result *ExecutionResult, total int) {
+	success := result.Success
+	reporter.Report(progress.Event{
+		Operation: stepType, Event: progress.EventRunFinished,
+		Total: total, Percent: progress.Percent(len(result.StepResults), total),
+		Success: &success, Aborted: result.Aborted, Duration: result.Duration.Seconds(), Error: result.Error,
+	})
+}
+
+// stopsCleanup reports whether a failure of the unbootstrap step stops the cleanup
+func stopsCleanup(step Executor) bool {
+	gate, ok := step.(CleanupGate)
+	return ok && gate.StopsCleanupOnFailure()
+}
+
 // executeStep executes a single step and returns the result
 func (be *BaseExecutor) executeStep(ctx context.Context, step Executor, stepType string) StepResult {
 	stepName := step.GetName()
@@ -125,29 +244,60 @@ func (be *BaseExecutor) executeStep(ctx context.Context, step Executor, stepType
 	// Check if step is already completed
 	if step.IsCompleted(ctx) {
 		be.logger.Infof("%s step: %s already completed", stepType, stepName)
-		return be.createStepResult(stepName, startTime, true, "")
+		stepResult := be.createStepResult(stepName, startTime, true, "")
+		stepResult.Skipped = true
+		return stepResult
 	}
 
 	var err error
-	if bootstrapStep, ok := step.(StepExecutor); ok && stepType == "bootstrap" {
+	if bootstrapStep, ok := step.(StepExecutor); ok && stepType != "unbootstrap" {
 		// Validate preconditions for bootstrap steps
 		if validationErr := bootstrapStep.Validate(ctx); validationErr != nil {
 			be.logger.Errorf("%s step %s validation failed with error: %s", stepType, stepName, validationErr)
-			return be.createStepResult(stepName, startTime, false, fmt.Sprintf("validation failed: %v", validationErr))
+			return be.createFailedStepResult(stepName, startTime, fmt.Errorf("%w: %w", ErrValidation, validationErr))
 		}
 	}
 
-	// Execute the step
-	err = step.Execute(ctx)
-	if err != nil {
+	// Fail the step on purpose when a fault is injected for it
+	if faultErr := injector.inject(ctx, stepName); faultErr != nil {
+		be.logger.Warnf("%s step: %s failed by fault injection: %s", stepType, stepName, faultErr)
+		return be.createFailedStepResult(stepName, startTime, faultErr)
+	}
+
+	if err = be.runStep(ctx, step, stepType); err != nil {
 		be.logger.Errorf("%s step: %s failed with error: %s with duration %s", stepType, stepName, err, time.Since(startTime))
-		return be.createStepResult(stepName, startTime, false, err.Error())
+		return be.createFailedStepResult(stepName, startTime, err)
 	}
 
 	be.logger.Infof("%s step: %s completed successfully with duration %s", stepType, stepName, time.Since(startTime))
 	return be.createStepResult(stepName, startTime, true, "")
 }
 
+// runStep runs the configured pre hooks, the step and its post hooks, and runs them again after a failure as
+// long as the retry policy of the step allows
+func (be *BaseExecutor) runStep(ctx context.Context, step Executor, stepType string) error {
+	stepName := step.GetName()
+	backoff := be.config.StepRetry(stepName).Backoff()
+	var err error
+	for attempt := range max(backoff.Attempts, 1) {
+		if attempt > 0 {
+			be.logger.Warnf("%s step %s failed: %s, retrying (attempt %d/%d)", stepType, stepName, err, attempt+1, backoff.Attempts)
+			if waitErr := backoff.Wait(ctx, attempt); waitErr != nil {
+				return err
+			}
+		}
+		if err = be.runHooks(ctx, stepType, stepName, config.HookPre); err == nil {
+			if err = step.Execute(ctx); err == nil {
+				err = be.runHooks(ctx, stepType, stepName, config.HookPost)
+			}
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
 // createStepResult creates a StepResult with consistent formatting
 func (be *BaseExecutor) createStepResult(stepName string, startTime time.Time, success bool, errorMsg string) StepResult {
 	return StepResult{
@@ -158,6 +308,14 @@ func (be *BaseExecutor) createStepResult(stepName string, startTime time.Time, s
 	}
 }
 
+// createFailedStepResult creates the StepResult of a failed step with the class of its error
+func (be *BaseExecutor) createFailedStepResult(stepName string, startTime time.Time, err error) StepResult {
+	stepResult := be.createStepResult(stepName, startTime, false, err.Error())
+	stepResult.FailureClass = ClassifyFailure(err)
+	stepResult.err = err
+	return stepResult
+}
+
 // countSuccessfulSteps counts the number of successful steps
 func (be *BaseExecutor) countSuccessfulSteps(stepResults []StepResult) int {
 	count := 0
@@ -0,0 +1,153 @@
+package bootstrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/progress"
+)
+
+// flakyStep fails its first failures runs
+type flakyStep struct {
+	failures int
+	runs     int
+}
+
+func (s *flakyStep) Execute(ctx context.Context) error {
+	s.runs++
+	if s.runs <= s.failures {
+		return errors.New("transient failure")
+	}
+	return nil
+}
+func (s *flakyStep) IsCompleted(ctx context.Context) bool { return false }
+func (s *flakyStep) GetName() string                      { return "FlakyInstaller" }
+
+// funcStep runs a function as its step
+type funcStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (s *funcStep) Execute(ctx context.Context) error    { return s.run(ctx) }
+func (s *funcStep) IsCompleted(ctx context.Context) bool { return false }
+func (s *funcStep) GetName() string                      { return s.name }
+
+func TestExecuteStepsAbort(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := &config.Config{Agent: config.AgentConfig{
+		LogDir:     t.TempDir(),
+		StateStore: config.StateStoreConfig{Type: config.StateStoreTypeFile, Path: t.TempDir()},
+	}}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = reader.Close() }()
+	reporter, err := progress.OpenFD(int(writer.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cause := errors.New("--timeout of 1s exceeded")
+	ctx, cancel := context.WithCancelCause(progress.WithReporter(t.Context(), reporter))
+	defer cancel(nil)
+
+	ranLast := false
+	steps := []Executor{
+		&funcStep{name: "FirstInstaller", run: func(ctx context.Context) error { return nil }},
+		&funcStep{name: "SlowInstaller", run: func(ctx context.Context) error {
+			cancel(cause)
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		&funcStep{name: "LastInstaller", run: func(ctx context.Context) error { ranLast = true; return nil }},
+	}
+	be := NewBaseExecutor(cfg, logger)
+	result, err := be.ExecuteSteps(ctx, steps, "bootstrap")
+
+	if !errors.Is(err, ErrAborted) || !errors.Is(err, cause) {
+		t.Fatalf("ExecuteSteps() error = %v, want ErrAborted with the cancel cause", err)
+	}
+	if ranLast {
+		t.Error("a step ran after the run was cancelled")
+	}
+	if !result.Aborted || result.ResumeFrom != "SlowInstaller" || result.StepCount != 2 {
+		t.Errorf("result = %+v, want aborted at SlowInstaller after 2 steps", result)
+	}
+	_ = reporter.Close()
+	var events []string
+	decoder := json.NewDecoder(reader)
+	for {
+		var event progress.Event
+		if decoder.Decode(&event) != nil {
+			break
+		}
+		events = append(events, event.Event+" "+event.Step+" "+event.Result)
+	}
+	wantEvents := []string{
+		"run_started  ", "step_started FirstInstaller ", "step_finished FirstInstaller done",
+		"step_started SlowInstaller ", "step_finished SlowInstaller failed", "run_finished  ",
+	}
+	if !slices.Equal(events, wantEvents) {
+		t.Errorf("progress events = %q, want %q", events, wantEvents)
+	}
+
+	checkpoint, err := be.LastCheckpoint("bootstrap")
+	if err != nil {
+		t.Fatalf("LastCheckpoint() error = %v", err)
+	}
+	if checkpoint.Result.ResumeFrom != "SlowInstaller" {
+		t.Errorf("checkpoint resumes from %q, want SlowInstaller", checkpoint.Result.ResumeFrom)
+	}
+}
+
+func TestRunStepRetries(t *testing.T) {
+	fast := config.RetryPolicy{Attempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	tests := []struct {
+		name     string
+		retries  []config.StepRetryConfig
+		failures int
+		wantRuns int
+		wantErr  bool
+	}{
+		{name: "no policy runs once", failures: 1, wantRuns: 1, wantErr: true},
+		{name: "step policy", retries: []config.StepRetryConfig{{Step: "FlakyInstaller", Policy: fast}}, failures: 2, wantRuns: 3},
+		{name: "policy of every step", retries: []config.StepRetryConfig{{Step: config.AllSteps, Policy: fast}}, failures: 1, wantRuns: 2},
+		{name: "attempts run out", retries: []config.StepRetryConfig{{Step: "FlakyInstaller", Policy: fast}}, failures: 5, wantRuns: 3, wantErr: true},
+		{
+			name: "step policy wins over every step",
+			retries: []config.StepRetryConfig{
+				{Step: config.AllSteps, Policy: fast},
+				{Step: "FlakyInstaller", Policy: config.RetryPolicy{Attempts: 1}},
+			},
+			failures: 1, wantRuns: 1, wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			cfg := &config.Config{Agent: config.AgentConfig{Retries: config.RetriesConfig{Steps: tt.retries}}}
+			step := &flakyStep{failures: tt.failures}
+
+			err := NewBaseExecutor(cfg, logger).runStep(t.Context(), step, "bootstrap")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runStep() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if step.runs != tt.wantRuns {
+				t.Errorf("runs = %d, want %d", step.runs, tt.wantRuns)
+			}
+		})
+	}
+}
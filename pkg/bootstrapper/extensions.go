@@ -0,0 +1,21 @@
+package bootstrapper
+
+import (
+	"slices"
+
+	"github.com/sirupsen/logrus"
+)
+
+// insertAfter inserts step after the step named after. Without after, or when no step has that name, the step
+// is appended, with a warning for the unknown name.
+func insertAfter(steps []Executor, after string, step Executor, logger *logrus.Logger) []Executor {
+	if after == "" {
+		return append(steps, step)
+	}
+	i := slices.IndexFunc(steps, func(existing Executor) bool { return existing.GetName() == after })
+	if i < 0 {
+		logger.Warnf("Step %s is configured after unknown step %s, running it last", step.GetName(), after)
+		return append(steps, step)
+	}
+	return slices.Insert(steps, i+1, step)
+}
@@ -0,0 +1,35 @@
+package bootstrapper
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// namedStep is a step that only has a name
+type namedStep string
+
+func (s namedStep) Execute(ctx context.Context) error    { return nil }
+func (s namedStep) IsCompleted(ctx context.Context) bool { return false }
+func (s namedStep) GetName() string                      { return string(s) }
+
+func TestInsertAfter(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	steps := []Executor{namedStep("A"), namedStep("B"), namedStep("C")}
+
+	steps = insertAfter(steps, "A", namedStep("X"), logger)
+	steps = insertAfter(steps, "", namedStep("Y"), logger)
+	steps = insertAfter(steps, "Missing", namedStep("Z"), logger)
+
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.GetName())
+	}
+	if want := []string{"A", "X", "B", "C", "Y", "Z"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("insertAfter() = %v, want %v", names, want)
+	}
+}
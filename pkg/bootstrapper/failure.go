@@ -0,0 +1,71 @@
+package bootstrapper
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/auth"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/preflight"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Failure classes of a failed run, recorded in the result and mapped to exit codes so automation can branch on
+// them instead of matching error text
+const (
+	FailureAborted    = "aborted"    // Cancelled by --timeout or a signal
+	FailureAuth       = "auth"       // No Azure token could be obtained, or Azure rejected it
+	FailureRBAC       = "rbac"       // Azure denied an operation to the authenticated identity
+	FailureNetwork    = "network"    // A connection or download failed
+	FailureValidation = "validation" // A preflight check or the precondition of a step failed
+	FailurePartial    = "partial"    // Unbootstrap finished, but some cleanup steps failed
+)
+
+// ErrValidation is returned when the Validate precondition of a step fails; the step did not change the host
+var ErrValidation = errors.New("validation failed")
+
+// ErrPartial is returned when a best effort unbootstrap finished with failed steps
+var ErrPartial = errors.New("partially completed")
+
+// ClassifyFailure returns the failure class of an error, or "" for failures outside the known classes
+func ClassifyFailure(err error) string {
+	var respErr *azcore.ResponseError
+	var authFailedErr *azidentity.AuthenticationFailedError
+	var authRequiredErr *azidentity.AuthenticationRequiredError
+	var downloadErr *utilio.DownloadError
+	var netErr net.Error
+	var urlErr *url.Error
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrAborted):
+		return FailureAborted
+	case errors.Is(err, ErrPartial):
+		return FailurePartial
+	case errors.Is(err, ErrValidation), errors.Is(err, preflight.ErrChecksFailed):
+		return FailureValidation
+	case errors.Is(err, auth.ErrAuthentication), errors.As(err, &authFailedErr), errors.As(err, &authRequiredErr):
+		return FailureAuth
+	case errors.As(err, &respErr) && respErr.StatusCode == http.StatusUnauthorized:
+		return FailureAuth
+	case errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden:
+		return FailureRBAC
+	case errors.As(err, &downloadErr), errors.As(err, &urlErr), errors.As(err, &netErr) && !isErrno(netErr):
+		return FailureNetwork
+	default:
+		return ""
+	}
+}
+
+// isErrno reports whether err is a bare system call error. syscall.Errno implements net.Error, so errors of
+// local files would otherwise count as network failures; connection errors wrap theirs in a net.OpError.
+func isErrno(err error) bool {
+	_, ok := err.(syscall.Errno)
+	return ok
+}
@@ -0,0 +1,117 @@
+package bootstrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/auth"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/preflight"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// validatedStep is a step whose precondition fails
+type validatedStep struct {
+	funcStep
+	validate error
+}
+
+func (s *validatedStep) Validate(ctx context.Context) error { return s.validate }
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"unclassified", errors.New("exit status 1"), ""},
+		{"aborted", fmt.Errorf("bootstrap %w at step X: %w", ErrAborted, context.DeadlineExceeded), FailureAborted},
+		{"partial", fmt.Errorf("unbootstrap %w: 2 failed", ErrPartial), FailurePartial},
+		{"step validation", fmt.Errorf("%w: missing kernel module", ErrValidation), FailureValidation},
+		{"preflight", fmt.Errorf("%w: [dns]", preflight.ErrChecksFailed), FailureValidation},
+		{"token", fmt.Errorf("failed to get access token: %w", auth.ErrAuthentication), FailureAuth},
+		{"credential rejected", fmt.Errorf("wrapped: %w", &azidentity.AuthenticationFailedError{}), FailureAuth},
+		{"unauthorized", fmt.Errorf("wrapped: %w", &azcore.ResponseError{StatusCode: http.StatusUnauthorized}), FailureAuth},
+		{"forbidden", fmt.Errorf("insufficient permissions: %w", &azcore.ResponseError{StatusCode: http.StatusForbidden}), FailureRBAC},
+		{"not found", &azcore.ResponseError{StatusCode: http.StatusNotFound}, ""},
+		{"download status", fmt.Errorf("failed to download runc: %w", &utilio.DownloadError{URL: "https://example.com", StatusCode: 502}), FailureNetwork},
+		{"connection", fmt.Errorf("failed to perform HTTP request: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), FailureNetwork},
+		{"dns", &net.DNSError{Err: "no such host", Name: "example.com"}, FailureNetwork},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, FailureNetwork},
+		{"missing file", fmt.Errorf("failed to create bundle: %w", &fs.PathError{Op: "open", Path: "/tmp/missing/bundle", Err: syscall.ENOENT}), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyFailure(tt.err); got != tt.want {
+				t.Errorf("ClassifyFailure(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteStepsFailureClass(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := &config.Config{Agent: config.AgentConfig{
+		LogDir:     t.TempDir(),
+		StateStore: config.StateStoreConfig{Type: config.StateStoreTypeFile, Path: t.TempDir()},
+		Retries:    config.RetriesConfig{Steps: []config.StepRetryConfig{{Step: config.AllSteps, Policy: config.RetryPolicy{Attempts: 1}}}},
+	}}
+	be := NewBaseExecutor(cfg, logger)
+	forbidden := &azcore.ResponseError{StatusCode: http.StatusForbidden}
+
+	t.Run("bootstrap keeps the step error", func(t *testing.T) {
+		steps := []Executor{&funcStep{name: "ArcInstaller", run: func(ctx context.Context) error {
+			return fmt.Errorf("insufficient permissions to assign roles: %w", forbidden)
+		}}}
+		result, err := be.ExecuteSteps(t.Context(), steps, "bootstrap")
+		var respErr *azcore.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Fatalf("ExecuteSteps() error = %v, want it to wrap the Azure response error", err)
+		}
+		if result.FailureClass != FailureRBAC || result.StepResults[0].FailureClass != FailureRBAC {
+			t.Errorf("failure class = %q, step %q, want %q", result.FailureClass, result.StepResults[0].FailureClass, FailureRBAC)
+		}
+	})
+
+	t.Run("failed precondition", func(t *testing.T) {
+		steps := []Executor{&validatedStep{
+			funcStep: funcStep{name: "KubeletInstaller", run: func(ctx context.Context) error { return nil }},
+			validate: errors.New("kubelet binary missing"),
+		}}
+		result, err := be.ExecuteSteps(t.Context(), steps, "bootstrap")
+		if !errors.Is(err, ErrValidation) || result.FailureClass != FailureValidation {
+			t.Errorf("ExecuteSteps() error = %v, class %q, want a validation failure", err, result.FailureClass)
+		}
+		if result.Error != "validation failed: kubelet binary missing" {
+			t.Errorf("result error = %q", result.Error)
+		}
+	})
+
+	t.Run("unbootstrap completes partially", func(t *testing.T) {
+		ranLast := false
+		steps := []Executor{
+			&funcStep{name: "KubeletUnInstaller", run: func(ctx context.Context) error { return errors.New("busy") }},
+			&funcStep{name: "RuncUnInstaller", run: func(ctx context.Context) error { ranLast = true; return nil }},
+		}
+		result, err := be.ExecuteSteps(t.Context(), steps, "unbootstrap")
+		if err != nil {
+			t.Fatalf("ExecuteSteps() error = %v, want best effort cleanup", err)
+		}
+		if !ranLast || result.Success || result.FailureClass != FailurePartial {
+			t.Errorf("result = %+v, want a partial unbootstrap that ran every step", result)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package bootstrapper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+const (
+	// faultsEnvVar injects faults without editing the config file, e.g.
+	// AKS_NODE_CONTROLLER_FAULTS="ContainerdInstaller=transient:2,KubeletInstaller=timeout:45s,CNISetup=fail"
+	faultsEnvVar = "AKS_NODE_CONTROLLER_FAULTS"
+
+	// Supported fault modes
+	faultModeFail      = "fail"
+	faultModeTimeout   = "timeout"
+	faultModeTransient = "transient"
+
+	defaultFaultDelay = 30 * time.Second
+)
+
+// InjectedFaultError is returned by steps failed through fault injection
+type InjectedFaultError struct {
+	Step      string
+	Mode      string
+	Transient bool
+}
+
+func (e *InjectedFaultError) Error() string {
+	return fmt.Sprintf("injected %s fault in step %s", e.Mode, e.Step)
+}
+
+// faultInjector decides whether a step execution should fail. Transient fault counters are
+// kept for the process lifetime so that daemon re-bootstrap attempts eventually succeed.
+type faultInjector struct {
+	mu       sync.Mutex
+	faults   map[string]config.FaultConfig
+	injected map[string]int
+}
+
+// injector is shared by all executors so transient counters survive across runs in the same process
+var injector = &faultInjector{injected: map[string]int{}}
+
+// load refreshes the fault rules from configuration and the environment (environment wins)
+func (f *faultInjector) load(cfg *config.Config) error {
+	faults := map[string]config.FaultConfig{}
+	if cfg != nil {
+		for _, fault := range cfg.Agent.Faults {
+			faults[fault.Step] = fault
+		}
+	}
+	envFaults, err := parseFaultSpec(os.Getenv(faultsEnvVar))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", faultsEnvVar, err)
+	}
+	for _, fault := range envFaults {
+		faults[fault.Step] = fault
+	}
+	for step, fault := range faults {
+		if err := validateFault(fault); err != nil {
+			return fmt.Errorf("invalid fault for step %s: %w", step, err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = faults
+	return nil
+}
+
+// active reports whether any faults are configured
+func (f *faultInjector) active() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.faults) > 0
+}
+
+// inject returns an error if a fault is configured for the step, blocking first for timeout faults
+func (f *faultInjector) inject(ctx context.Context, step string) error {
+	f.mu.Lock()
+	fault, ok := f.faults[step]
+	if ok && fault.Mode == faultModeTransient {
+		count := max(fault.Count, 1)
+		if f.injected[step] >= count {
+			ok = false
+		} else {
+			f.injected[step]++
+		}
+	}
+	f.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	faultErr := &InjectedFaultError{Step: step, Mode: fault.Mode, Transient: fault.Mode == faultModeTransient}
+	if fault.Mode == faultModeTimeout {
+		delay := fault.Delay
+		if delay == 0 {
+			delay = defaultFaultDelay
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", faultErr, ctx.Err())
+		case <-time.After(delay):
+			return fmt.Errorf("%w: %w", faultErr, context.DeadlineExceeded)
+		}
+	}
+	return faultErr
+}
+
+// parseFaultSpec parses a comma-separated list of step=mode[:arg] entries.
+// The argument is a failure count for transient faults and a duration for timeout faults.
+func parseFaultSpec(spec string) ([]config.FaultConfig, error) {
+	var faults []config.FaultConfig
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		step, rule, ok := strings.Cut(entry, "=")
+		if !ok || step == "" {
+			return nil, fmt.Errorf("entry %q must have the form step=mode[:arg]", entry)
+		}
+		mode, arg, hasArg := strings.Cut(rule, ":")
+		fault := config.FaultConfig{Step: step, Mode: mode}
+		if hasArg {
+			switch mode {
+			case faultModeTransient:
+				count, err := strconv.Atoi(arg)
+				if err != nil {
+					return nil, fmt.Errorf("entry %q: invalid transient count: %w", entry, err)
+				}
+				fault.Count = count
+			case faultModeTimeout:
+				delay, err := time.ParseDuration(arg)
+				if err != nil {
+					return nil, fmt.Errorf("entry %q: invalid timeout delay: %w", entry, err)
+				}
+				fault.Delay = delay
+			default:
+				return nil, fmt.Errorf("entry %q: mode %s takes no argument", entry, mode)
+			}
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
+
+// validateFault checks that a fault rule is well formed
+func validateFault(fault config.FaultConfig) error {
+	switch fault.Mode {
+	case faultModeFail, faultModeTimeout, faultModeTransient:
+	default:
+		return fmt.Errorf("unknown mode %q (valid: fail, timeout, transient)", fault.Mode)
+	}
+	if fault.Count < 0 || fault.Delay < 0 {
+		return fmt.Errorf("count and delay must not be negative")
+	}
+	return nil
+}
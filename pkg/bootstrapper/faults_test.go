@@ -0,0 +1,102 @@
+package bootstrapper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestParseFaultSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []config.FaultConfig
+		wantErr bool
+	}{
+		{
+			name: "empty spec",
+			spec: "",
+			want: nil,
+		},
+		{
+			name: "all modes",
+			spec: "CNISetup=fail, ContainerdInstaller=transient:2,KubeletInstaller=timeout:45s",
+			want: []config.FaultConfig{
+				{Step: "CNISetup", Mode: "fail"},
+				{Step: "ContainerdInstaller", Mode: "transient", Count: 2},
+				{Step: "KubeletInstaller", Mode: "timeout", Delay: 45 * time.Second},
+			},
+		},
+		{
+			name:    "missing mode separator",
+			spec:    "CNISetup",
+			wantErr: true,
+		},
+		{
+			name:    "invalid transient count",
+			spec:    "CNISetup=transient:many",
+			wantErr: true,
+		},
+		{
+			name:    "argument on fail mode",
+			spec:    "CNISetup=fail:3",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFaultSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFaultSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFaultSpec() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFaultInjectorTransientFaultsClear(t *testing.T) {
+	f := &faultInjector{injected: map[string]int{}}
+	cfg := &config.Config{Agent: config.AgentConfig{Faults: []config.FaultConfig{
+		{Step: "CNISetup", Mode: "transient", Count: 2},
+	}}}
+	if err := f.load(cfg); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		err := f.inject(context.Background(), "CNISetup")
+		var faultErr *InjectedFaultError
+		if !errors.As(err, &faultErr) || !faultErr.Transient {
+			t.Fatalf("attempt %d: expected transient fault, got %v", attempt, err)
+		}
+	}
+	if err := f.inject(context.Background(), "CNISetup"); err != nil {
+		t.Errorf("expected step to succeed after transient faults, got %v", err)
+	}
+	if err := f.inject(context.Background(), "KubeletInstaller"); err != nil {
+		t.Errorf("expected no fault for unconfigured step, got %v", err)
+	}
+}
+
+func TestFaultInjectorTimeoutHonorsContext(t *testing.T) {
+	f := &faultInjector{injected: map[string]int{}}
+	cfg := &config.Config{Agent: config.AgentConfig{Faults: []config.FaultConfig{
+		{Step: "ArcInstall", Mode: "timeout", Delay: time.Hour},
+	}}}
+	if err := f.load(cfg); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := f.inject(ctx, "ArcInstall"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
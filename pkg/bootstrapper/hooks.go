@@ -0,0 +1,68 @@
+package bootstrapper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// runHooks runs the hooks configured at when of a step in order. Their output goes to the step log. A failed
+// hook stops the remaining hooks and fails the step unless its onFailure is ignore.
+func (be *BaseExecutor) runHooks(ctx context.Context, stepType, stepName, when string) error {
+	for i, hook := range be.config.StepHooks(stepName, when) {
+		be.logger.Infof("Running %s hook %d of %s step %s", when, i+1, stepType, stepName)
+		output, err := runHook(ctx, hook, hookEnv(stepType, stepName, when))
+		for line := range strings.Lines(output) {
+			be.logger.Infof("[%s hook] %s", when, strings.TrimRight(line, "\n"))
+		}
+		if err == nil {
+			continue
+		}
+		if hook.OnFailure == config.HookOnFailureIgnore {
+			be.logger.Warnf("%s hook %d of step %s failed, ignoring: %v", when, i+1, stepName, err)
+			continue
+		}
+		return fmt.Errorf("%s hook %d failed: %w", when, i+1, err)
+	}
+	return nil
+}
+
+// runHook runs a hook with its timeout and returns its combined output
+func runHook(ctx context.Context, hook config.HookConfig, env []string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, hook.Timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if hook.Script != "" {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", hook.Script) // #nosec G204 -- hooks are configured by the node operator
+	} else {
+		cmd = exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...) // #nosec G204 -- hooks are configured by the node operator
+	}
+	cmd.Env = append(os.Environ(), env...)
+	// Stop waiting for the output of processes the hook left behind once it was killed
+	cmd.WaitDelay = time.Second
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", hook.Timeout)
+	}
+	return output.String(), err
+}
+
+// hookEnv tells a hook which step it runs for
+func hookEnv(stepType, stepName, when string) []string {
+	return []string{
+		"AKS_FLEX_NODE_OPERATION=" + stepType,
+		"AKS_FLEX_NODE_STEP=" + stepName,
+		"AKS_FLEX_NODE_HOOK=" + when,
+	}
+}
@@ -0,0 +1,86 @@
+package bootstrapper
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestRunHooks(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "hooks")
+	hook := func(when, script, onFailure string) config.HookConfig {
+		return config.HookConfig{Step: "ContainerdInstaller", When: when, Script: script, Timeout: 5 * time.Second, OnFailure: onFailure}
+	}
+	tests := []struct {
+		name    string
+		hooks   []config.HookConfig
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "runs the hooks of the step in order",
+			hooks: []config.HookConfig{
+				hook(config.HookPre, "echo first-$AKS_FLEX_NODE_HOOK >> "+marker, config.HookOnFailureFail),
+				hook(config.HookPost, "echo post >> "+marker, config.HookOnFailureFail),
+				{Step: "KubeletInstaller", When: config.HookPre, Command: []string{"false"}, Timeout: time.Second, OnFailure: config.HookOnFailureFail},
+				hook(config.HookPre, "echo second-$AKS_FLEX_NODE_STEP >> "+marker, config.HookOnFailureFail),
+			},
+			want: "first-pre\nsecond-ContainerdInstaller\n",
+		},
+		{
+			name: "ignored failure",
+			hooks: []config.HookConfig{
+				hook(config.HookPre, "exit 3", config.HookOnFailureIgnore),
+				hook(config.HookPre, "echo ran >> "+marker, config.HookOnFailureFail),
+			},
+			want: "ran\n",
+		},
+		{
+			name: "failure stops the remaining hooks",
+			hooks: []config.HookConfig{
+				hook(config.HookPre, "exit 3", config.HookOnFailureFail),
+				hook(config.HookPre, "echo ran >> "+marker, config.HookOnFailureFail),
+			},
+			wantErr: true,
+		},
+		{
+			name: "timeout",
+			hooks: []config.HookConfig{
+				{Step: "ContainerdInstaller", When: config.HookPre, Script: "sleep 5", Timeout: 100 * time.Millisecond, OnFailure: config.HookOnFailureFail},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Remove(marker)
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			be := NewBaseExecutor(&config.Config{Hooks: tt.hooks}, logger)
+
+			err := be.runHooks(t.Context(), "bootstrap", "ContainerdInstaller", config.HookPre)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runHooks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			data, _ := os.ReadFile(marker)
+			if got := string(data); got != tt.want {
+				t.Errorf("hooks wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunHookTimeout(t *testing.T) {
+	hook := config.HookConfig{Script: "sleep 5", Timeout: 100 * time.Millisecond}
+	if _, err := runHook(t.Context(), hook, nil); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("runHook() error = %v, want a timeout", err)
+	}
+}
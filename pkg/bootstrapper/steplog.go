@@ -0,0 +1,88 @@
+package bootstrapper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stepLogDir is the directory under the agent log directory holding one log file per step
+const stepLogDir = "steps"
+
+// stepLogHook copies every log entry emitted while a step runs into the step's own log file,
+// in addition to the combined stream the logger already writes
+type stepLogHook struct {
+	mu        sync.Mutex
+	file      *os.File
+	formatter logrus.Formatter
+}
+
+func (h *stepLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *stepLogHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.file.Write(line)
+	return err
+}
+
+// stepLogPath returns the log file of a step, e.g. /var/log/aks-flex-node/steps/bootstrap-03-ContainerdInstaller.log.
+// The step index keeps the files in execution order and apart when a step runs more than once.
+func stepLogPath(logDir, stepType string, index int, stepName string) string {
+	return filepath.Join(logDir, stepLogDir, fmt.Sprintf("%s-%02d-%s.log", stepType, index, stepName))
+}
+
+// captureStepLogs starts copying the logger output into the step's log file and returns the file path
+// and a function that stops the capture. Capturing is best effort: when the file cannot be created the
+// step still runs and only logs to the combined stream.
+func (be *BaseExecutor) captureStepLogs(stepType string, index int, stepName string) (string, func()) {
+	if be.config == nil || be.config.Agent.LogDir == "" {
+		return "", func() {}
+	}
+
+	path := stepLogPath(be.config.Agent.LogDir, stepType, index, stepName)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		be.logger.Warnf("Failed to create step log directory, %s logs only go to the combined log: %v", stepName, err)
+		return "", func() {}
+	}
+	// Each run replaces the previous log of the step so the file only describes the latest attempt
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		be.logger.Warnf("Failed to create step log file, %s logs only go to the combined log: %v", stepName, err)
+		return "", func() {}
+	}
+
+	hook := &stepLogHook{
+		file: file,
+		formatter: &logrus.TextFormatter{
+			FullTimestamp:   true,
+			TimestampFormat: "2006-01-02 15:04:05",
+			DisableColors:   true,
+		},
+	}
+	previous := be.logger.ReplaceHooks(copyHooks(be.logger.Hooks))
+	be.logger.AddHook(hook)
+
+	return path, func() {
+		be.logger.ReplaceHooks(previous)
+		_ = file.Close()
+	}
+}
+
+// copyHooks returns a copy of the logger hooks so adding a hook does not modify the original set
+func copyHooks(hooks logrus.LevelHooks) logrus.LevelHooks {
+	copied := make(logrus.LevelHooks, len(hooks))
+	for level, levelHooks := range hooks {
+		copied[level] = append([]logrus.Hook(nil), levelHooks...)
+	}
+	return copied
+}
@@ -0,0 +1,45 @@
+package bootstrapper
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestCaptureStepLogs(t *testing.T) {
+	logDir := t.TempDir()
+	cfg := &config.Config{Agent: config.AgentConfig{LogDir: logDir}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	be := NewBaseExecutor(cfg, logger)
+
+	logger.Info("before step")
+	path, stop := be.captureStepLogs("bootstrap", 2, "KubeletInstaller")
+	logger.Info("inside step")
+	stop()
+	logger.Info("after step")
+
+	if want := filepath.Join(logDir, "steps", "bootstrap-02-KubeletInstaller.log"); path != want {
+		t.Fatalf("captureStepLogs() path = %q, want %q", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read step log: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "inside step") {
+		t.Errorf("step log is missing the step's entry: %q", content)
+	}
+	if strings.Contains(content, "before step") || strings.Contains(content, "after step") {
+		t.Errorf("step log contains entries from outside the step: %q", content)
+	}
+	if len(logger.Hooks) != 0 {
+		t.Errorf("step log hook still attached after stop: %v", logger.Hooks)
+	}
+}
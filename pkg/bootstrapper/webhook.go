@@ -0,0 +1,62 @@
+package bootstrapper
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/webhook"
+)
+
+// notifyWebhook reports the bootstrap result to the configured webhook, if any. Delivery failures are
+// logged and never change the bootstrap outcome.
+func (b *Bootstrapper) notifyWebhook(ctx context.Context, result *ExecutionResult) {
+	notifier := webhook.New(b.config.Agent.Webhook, b.logger)
+	if notifier == nil || result == nil {
+		return
+	}
+
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		b.logger.Warnf("Failed to get node name: %v", err)
+	}
+	payload := webhook.Payload{
+		Event:           webhook.EventBootstrapCompleted,
+		Timestamp:       time.Now().UTC(),
+		NodeName:        nodeName,
+		ClusterID:       b.config.GetTargetClusterID(),
+		Success:         result.Success,
+		Error:           result.Error,
+		DurationSeconds: result.Duration.Seconds(),
+	}
+	if result.Success {
+		payload.ProviderID = b.providerID(nodeName)
+	}
+	for _, step := range result.StepResults {
+		payload.Steps = append(payload.Steps, webhook.StepSummary{
+			Name:            step.StepName,
+			Success:         step.Success,
+			Skipped:         step.Skipped,
+			DurationSeconds: step.Duration.Seconds(),
+			Error:           step.Error,
+		})
+	}
+
+	if err := notifier.Send(ctx, payload); err != nil {
+		b.logger.Warnf("Failed to notify bootstrap webhook: %v", err)
+	}
+}
+
+// providerID reads the node's spec.providerID from the cluster, returning an empty string when unavailable
+func (b *Bootstrapper) providerID(nodeName string) string {
+	output, err := utils.RunCommandWithOutput("kubectl", "--kubeconfig", kubelet.KubeletKubeconfigPath,
+		"get", "node", nodeName, "-o", "jsonpath={.spec.providerID}")
+	if err != nil {
+		b.logger.Debugf("Failed to read providerID of node %s: %v", nodeName, err)
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
@@ -0,0 +1,141 @@
+package arc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// arcAgentVersion returns the azcmagent version bootstrap must install, or an empty string for the latest
+func (ab *base) arcAgentVersion() string {
+	if ab.config.Azure.Arc == nil {
+		return ""
+	}
+	return ab.config.Azure.Arc.AgentVersion
+}
+
+// arcInstallerPath returns the local azcmagent installer, or an empty string to download it from Microsoft
+func (ab *base) arcInstallerPath() string {
+	if ab.config.Azure.Arc == nil {
+		return ""
+	}
+	return ab.config.Azure.Arc.InstallerPath
+}
+
+// installedArcAgentVersion returns the version of the installed azcmagent, parsed from
+// "azcmagent version 1.45.02800.709"
+func installedArcAgentVersion() (string, error) {
+	output, err := utils.RunCommandWithOutput("azcmagent", "version")
+	if err != nil {
+		return "", fmt.Errorf("failed to get azcmagent version: %w, output: %s", err, output)
+	}
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("azcmagent version returned no output")
+	}
+	return fields[len(fields)-1], nil
+}
+
+// needsArcAgentInstall reports whether azcmagent is missing or differs from the pinned version
+func (i *Installer) needsArcAgentInstall() bool {
+	if !isArcAgentInstalled() {
+		return true
+	}
+	version := i.arcAgentVersion()
+	if version == "" {
+		return false
+	}
+	installed, err := installedArcAgentVersion()
+	if err != nil {
+		i.logger.Warnf("Reinstalling Azure Arc agent: %v", err)
+		return true
+	}
+	if installed != version {
+		i.logger.Infof("Azure Arc agent %s is installed but %s is pinned, reinstalling", installed, version)
+		return true
+	}
+	return false
+}
+
+// installArcAgentFromPath installs azcmagent from a local .deb or .rpm package, or runs a local copy of the
+// install script. Nothing is downloaded for packages, so this works without internet access.
+func (i *Installer) installArcAgentFromPath(path string) error {
+	if !utils.FileExists(path) {
+		return fmt.Errorf("azure.arc.installerPath %s does not exist", path)
+	}
+
+	if ext := filepath.Ext(path); ext == ".deb" || ext == ".rpm" {
+		i.logger.Infof("Installing Azure Arc agent package %s", path)
+		if err := utilhost.InstallPackageFile(path); err != nil {
+			return fmt.Errorf("failed to install Azure Arc agent package %s: %w", path, err)
+		}
+		return nil
+	}
+
+	i.logger.Infof("Running Azure Arc agent installation script %s", path)
+	return i.runArcInstallScript(path)
+}
+
+// runArcInstallScript runs the Arc agent installation script. With a proxy, the script downloads the
+// agent package through it.
+func (i *Installer) runArcInstallScript(path string) error {
+	if err := utils.RunSystemCommand("bash", installScriptArgs(path, i.arcProxyURL())...); err != nil {
+		return fmt.Errorf("failed to install Azure Arc agent: %w", err)
+	}
+	return nil
+}
+
+// installScriptArgs returns the bash arguments running the Arc agent installation script at path. The script
+// takes the proxy as a flag, since it also configures the package repository it adds to use it.
+func installScriptArgs(path, proxyURL string) []string {
+	args := []string{path}
+	if proxyURL != "" {
+		args = append(args, "--proxy", proxyURL)
+	}
+	return args
+}
+
+// pinArcAgentVersion replaces the agent the install script installed with the pinned version, taken from
+// the Microsoft package repository the script configured
+func (i *Installer) pinArcAgentVersion(ctx context.Context, version string) error {
+	i.logger.Infof("Installing pinned Azure Arc agent version %s", version)
+	manager, err := utilhost.PackageManager()
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "apt-get", "install", "-y", "--allow-downgrades", "azcmagent="+version)
+	if manager != utilhost.PackageManagerApt {
+		// dnf and yum downgrade when given an older version
+		cmd = exec.CommandContext(ctx, manager, "install", "-y", "azcmagent-"+version) // #nosec G204 -- the version comes from the config
+	}
+	cmd.Env = append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
+	if proxyURL := i.arcProxyURL(); proxyURL != "" {
+		cmd.Env = append(cmd.Env, proxyEnv(proxyURL)...)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to install azcmagent %s: %w, output: %s", version, err, string(output))
+	}
+	return nil
+}
+
+// verifyArcAgentVersion checks that the installed agent is the pinned version
+func (i *Installer) verifyArcAgentVersion() error {
+	version := i.arcAgentVersion()
+	if version == "" {
+		return nil
+	}
+	installed, err := installedArcAgentVersion()
+	if err != nil {
+		return err
+	}
+	if installed != version {
+		return fmt.Errorf("installed azcmagent version %s does not match azure.arc.agentVersion %s", installed, version)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package arc
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v3"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v5"
@@ -29,6 +30,8 @@ type base struct {
 	hybridComputeMachineClient *armhybridcompute.MachinesClient
 	mcClient                   *armcontainerservice.ManagedClustersClient
 	roleAssignmentsClient      roleAssignmentsClient
+	roleDefinitionsClient      roleDefinitionsClient
+	resolvedCustomRoleID       string // ID of an existing custom role definition found by name
 }
 
 // newbase creates a new Arc base instance which will be shared by Installer and Uninstaller
@@ -49,28 +52,36 @@ func (ab *base) setUpClients(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get authentication credential: %w", err)
 	}
+	clientOptions := config.GetConfig().GetARMClientOptions()
 
 	// Create hybrid compute machines client
-	hybridComputeMachineClient, err := armhybridcompute.NewMachinesClient(config.GetConfig().GetSubscriptionID(), cred, nil)
+	hybridComputeMachineClient, err := armhybridcompute.NewMachinesClient(config.GetConfig().GetSubscriptionID(), cred, clientOptions)
 	if err != nil {
 		return fmt.Errorf("failed to create hybrid compute client: %w", err)
 	}
 
 	// Create managed clusters client
-	mcClient, err := armcontainerservice.NewManagedClustersClient(config.GetConfig().GetSubscriptionID(), cred, nil)
+	mcClient, err := armcontainerservice.NewManagedClustersClient(config.GetConfig().GetSubscriptionID(), cred, clientOptions)
 	if err != nil {
 		return fmt.Errorf("failed to create managed clusters client: %w", err)
 	}
 
 	// Create role assignments client
-	azureClient, err := armauthorization.NewRoleAssignmentsClient(config.GetConfig().GetSubscriptionID(), cred, nil)
+	azureClient, err := armauthorization.NewRoleAssignmentsClient(config.GetConfig().GetSubscriptionID(), cred, clientOptions)
 	if err != nil {
 		return fmt.Errorf("failed to create role assignments client: %w", err)
 	}
 
+	// Create role definitions client (custom least-privilege role)
+	roleDefinitionsClient, err := armauthorization.NewRoleDefinitionsClient(cred, clientOptions)
+	if err != nil {
+		return fmt.Errorf("failed to create role definitions client: %w", err)
+	}
+
 	ab.hybridComputeMachineClient = hybridComputeMachineClient
 	ab.mcClient = mcClient
 	ab.roleAssignmentsClient = &azureRoleAssignmentsClient{client: azureClient}
+	ab.roleDefinitionsClient = roleDefinitionsClient
 	return nil
 }
 
@@ -123,22 +134,39 @@ func (ab *base) checkRequiredPermissions(ctx context.Context, principalID string
 }
 
 func (ab *base) getRoleAssignments() []roleAssignment {
+	scope := ab.config.GetArcRoleScope()
+	if ab.isCustomRoleEnabled() {
+		return []roleAssignment{
+			{ab.config.Azure.Arc.CustomRole.Name, scope, ab.customRoleID()},
+		}
+	}
 	return []roleAssignment{
-		{"Reader (Target Cluster)", ab.config.GetTargetClusterID(), roleDefinitionIDs["Reader"]},
-		{"Azure Kubernetes Service RBAC Cluster Admin", ab.config.GetTargetClusterID(), roleDefinitionIDs["Azure Kubernetes Service RBAC Cluster Admin"]},
-		{"Azure Kubernetes Service Cluster Admin Role", ab.config.GetTargetClusterID(), roleDefinitionIDs["Azure Kubernetes Service Cluster Admin Role"]},
+		{"Reader", scope, roleDefinitionIDs["Reader"]},
+		{"Azure Kubernetes Service RBAC Cluster Admin", scope, roleDefinitionIDs["Azure Kubernetes Service RBAC Cluster Admin"]},
+		{"Azure Kubernetes Service Cluster User Role", scope, roleDefinitionIDs["Azure Kubernetes Service Cluster User Role"]},
 	}
 }
 
-// checkRoleAssignment checks if a principal has a specific role assignment on a scope
+// fullRoleDefinitionID builds the full resource ID of a role definition in the subscription of the
+// target cluster, where all role assignments are scoped
+func (ab *base) fullRoleDefinitionID(roleDefinitionID string) string {
+	subscriptionID := ab.config.GetTargetClusterSubscriptionID()
+	if subscriptionID == "" {
+		subscriptionID = ab.config.Azure.SubscriptionID
+	}
+	return fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Authorization/roleDefinitions/%s",
+		subscriptionID, roleDefinitionID)
+}
+
+// checkRoleAssignment checks if a principal has a specific role assignment on a scope, either directly
+// or inherited from a parent scope such as the resource group or subscription
 func (ab *base) checkRoleAssignment(ctx context.Context, principalID, roleDefinitionID, scope string) (bool, error) {
-	// Build the full role definition ID
-	fullRoleDefinitionID := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Authorization/roleDefinitions/%s",
-		ab.config.Azure.SubscriptionID, roleDefinitionID)
+	fullRoleDefinitionID := ab.fullRoleDefinitionID(roleDefinitionID)
 
-	// List role assignments for the scope
+	// Only list the assignments of this principal rather than every assignment on the scope
+	filter := fmt.Sprintf("principalId eq '%s'", principalID)
 	pager := ab.roleAssignmentsClient.NewListForScopePager(scope, &armauthorization.RoleAssignmentsClientListForScopeOptions{
-		Filter: nil, // We'll filter programmatically
+		Filter: &filter,
 	})
 
 	for pager.More() {
@@ -152,8 +180,9 @@ func (ab *base) checkRoleAssignment(ctx context.Context, principalID, roleDefini
 			if assignment.Properties != nil &&
 				assignment.Properties.PrincipalID != nil &&
 				assignment.Properties.RoleDefinitionID != nil &&
-				*assignment.Properties.PrincipalID == principalID &&
-				*assignment.Properties.RoleDefinitionID == fullRoleDefinitionID {
+				strings.EqualFold(*assignment.Properties.PrincipalID, principalID) &&
+				strings.EqualFold(*assignment.Properties.RoleDefinitionID, fullRoleDefinitionID) &&
+				scopeCovers(assignment.Properties.Scope, scope) {
 				return true, nil
 			}
 		}
@@ -162,12 +191,27 @@ func (ab *base) checkRoleAssignment(ctx context.Context, principalID, roleDefini
 	return false, nil
 }
 
+// scopeCovers checks if an assignment made at assignmentScope applies to scope. Listing a scope also
+// returns assignments on its children, which do not grant access to the scope itself.
+func scopeCovers(assignmentScope *string, scope string) bool {
+	if assignmentScope == nil {
+		return true
+	}
+	parent := strings.ToLower(strings.TrimSuffix(*assignmentScope, "/"))
+	child := strings.ToLower(strings.TrimSuffix(scope, "/"))
+	return parent == "" || parent == child || strings.HasPrefix(child, parent+"/")
+}
+
 // ensureAuthentication ensures the appropriate authentication (SP or CLI) method is set up
 func (ab *base) ensureAuthentication(ctx context.Context) error {
 	if ab.config.IsSPConfigured() {
 		ab.logger.Info("🔐 Using service principal authentication")
 		return nil
 	}
+	if ab.config.IsFederatedIdentityConfigured() {
+		ab.logger.Info("🔐 Using federated identity authentication")
+		return nil
+	}
 
 	ab.logger.Info("🔐 Checking Azure CLI authentication status...")
 	tenantID := ab.config.GetTenantID()
@@ -6,16 +6,19 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v3"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v5"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/hybridcompute/armhybridcompute"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
 	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
 )
 
 // Installer handles Azure Arc installation operations
@@ -42,8 +45,8 @@ func (i *Installer) Validate(ctx context.Context) error {
 		return fmt.Errorf("arc bootstrap setup failed at authentication: %w", err)
 	}
 	// Ensure Arc agent is installed
-	if !isArcAgentInstalled() {
-		i.logger.Info("Azure Arc agent not found, installing...")
+	if i.needsArcAgentInstall() {
+		i.logger.Info("Azure Arc agent not found or not the pinned version, installing...")
 		if err := i.installArcAgentBinary(ctx); err != nil {
 			return fmt.Errorf("failed to install Azure Arc agent binary: %w", err)
 		}
@@ -55,11 +58,39 @@ func (i *Installer) Validate(ctx context.Context) error {
 func (i *Installer) installArcAgentBinary(ctx context.Context) error {
 	i.logger.Info("Installing Azure Arc agent binary...")
 
-	// Clean up any existing package state to avoid conflicts
-	if err := utils.RunSystemCommand("dpkg", "--purge", "azcmagent"); err != nil {
-		i.logger.Debug("No existing azcmagent package to remove")
+	// Clean up any leftover package state to avoid conflicts. An installed agent is upgraded or downgraded
+	// in place instead, since purging it would drop the machine's Arc connection.
+	if !isArcAgentInstalled() {
+		if err := utilhost.RemovePackage("azcmagent"); err != nil {
+			i.logger.Debug("No existing azcmagent package to remove")
+		}
 	}
 
+	if installerPath := i.arcInstallerPath(); installerPath != "" {
+		if err := i.installArcAgentFromPath(installerPath); err != nil {
+			return err
+		}
+	} else if err := i.downloadAndInstallArcAgent(ctx); err != nil {
+		return err
+	}
+
+	if err := i.verifyArcAgentVersion(); err != nil {
+		return err
+	}
+
+	// Setup Arc-specific permissions (add service user to himds group)
+	if err := i.setupArcPermissions(); err != nil {
+		i.logger.Warnf("Failed to setup Arc permissions: %v", err)
+		// Don't fail the installation for permission issues
+	}
+
+	i.logger.Info("Azure Arc agent binary installed successfully")
+	return nil
+}
+
+// downloadAndInstallArcAgent downloads the installation script from Microsoft and runs it, then
+// installs the pinned agent version when one is configured
+func (i *Installer) downloadAndInstallArcAgent(ctx context.Context) error {
 	// Create temporary directory for installation script
 	tempDir, err := os.MkdirTemp("", "arc-install-*")
 	if err != nil {
@@ -84,19 +115,15 @@ func (i *Installer) installArcAgentBinary(ctx context.Context) error {
 		return fmt.Errorf("failed to make installation script executable: %w", err)
 	}
 
-	// Execute installation script
 	i.logger.Info("Running Azure Arc agent installation script...")
-	if err := utils.RunSystemCommand("bash", installScriptPath); err != nil {
-		return fmt.Errorf("failed to install Azure Arc agent: %w", err)
+	if err := i.runArcInstallScript(installScriptPath); err != nil {
+		return err
 	}
 
-	// Setup Arc-specific permissions (add service user to himds group)
-	if err := i.setupArcPermissions(); err != nil {
-		i.logger.Warnf("Failed to setup Arc permissions: %v", err)
-		// Don't fail the installation for permission issues
+	// The script always installs the latest agent
+	if version := i.arcAgentVersion(); version != "" {
+		return i.pinArcAgentVersion(ctx, version)
 	}
-
-	i.logger.Info("Azure Arc agent binary installed successfully")
 	return nil
 }
 
@@ -116,20 +143,12 @@ func (i *Installer) setupArcPermissions() error {
 
 // downloadArcInstallScript downloads the Arc installation script using curl or wget
 func (i *Installer) downloadArcInstallScript(ctx context.Context, destPath string) error {
-	// Try curl first
-	if _, err := exec.LookPath("curl"); err == nil {
-		cmd := exec.CommandContext(ctx, "curl", "-L", "-o", destPath, arcInstallScriptURL)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("curl download failed: %w", err)
+	for _, tool := range []string{"curl", "wget"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			continue
 		}
-		return nil
-	}
-
-	// Try wget as fallback
-	if _, err := exec.LookPath("wget"); err == nil {
-		cmd := exec.CommandContext(ctx, "wget", "-O", destPath, arcInstallScriptURL)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("wget download failed: %w", err)
+		if err := downloadScriptCommand(ctx, tool, destPath, i.arcProxyURL()).Run(); err != nil {
+			return fmt.Errorf("%s download failed: %w", tool, err)
 		}
 		return nil
 	}
@@ -137,6 +156,20 @@ func (i *Installer) downloadArcInstallScript(ctx context.Context, destPath strin
 	return fmt.Errorf("neither curl nor wget is available for downloading Arc installation script")
 }
 
+// downloadScriptCommand returns the curl or wget command downloading the Arc installation script to destPath.
+// The proxy goes in the environment rather than on the command line, where any local user can read its
+// credentials.
+func downloadScriptCommand(ctx context.Context, tool, destPath, proxyURL string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "wget", "-O", destPath, arcInstallScriptURL)
+	if tool == "curl" {
+		cmd = exec.CommandContext(ctx, "curl", "-L", "-o", destPath, arcInstallScriptURL)
+	}
+	if proxyURL != "" {
+		cmd.Env = append(os.Environ(), proxyEnv(proxyURL)...)
+	}
+	return cmd
+}
+
 // GetName returns the step name
 func (i *Installer) GetName() string {
 	return "ArcInstall"
@@ -170,7 +203,7 @@ func (i *Installer) Execute(ctx context.Context) error {
 	}
 
 	// Step 4: Assign RBAC roles to managed identity
-	time.Sleep(10 * time.Second) // brief pause to ensure identity is ready
+	time.Sleep(i.config.GetTimeouts().IdentityPropagation) // brief pause to ensure identity is ready
 	i.logger.Info("Step 4: Assigning RBAC roles to managed identity")
 	if err := i.assignRBACRoles(ctx, arcMachine); err != nil {
 		i.logger.Errorf("Failed to assign RBAC roles: %v", err)
@@ -254,33 +287,47 @@ func (i *Installer) registerArcMachine(ctx context.Context) (*armhybridcompute.M
 }
 
 func (i *Installer) validateManagedCluster(ctx context.Context) error {
-	i.logger.Info("Validating target AKS Managed Cluster requirements for Azure RBAC authentication")
+	i.logger.Info("Validating target AKS Managed Cluster requirements")
 
 	cluster, err := i.getAKSCluster(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get AKS cluster info: %w", err)
 	}
 
-	// Check if Azure RBAC is enabled
-	if cluster.Properties == nil ||
-		cluster.Properties.AADProfile == nil ||
-		cluster.Properties.AADProfile.EnableAzureRBAC == nil ||
-		!*cluster.Properties.AADProfile.EnableAzureRBAC {
-		return fmt.Errorf("target AKS cluster '%s' must have Azure RBAC enabled for node authentication", to.String(cluster.Name))
+	// kubelet authenticates with the Arc identity only through Azure RBAC; a bootstrap token joins without it
+	switch {
+	case isAzureRBACEnabled(cluster):
+		i.logger.Infof("Target AKS cluster '%s' has Azure RBAC enabled", to.String(cluster.Name))
+	case i.config.IsBootstrapTokenConfigured():
+		i.logger.Infof("Target AKS cluster '%s' does not have Azure RBAC enabled, kubelet joins with the bootstrap token", to.String(cluster.Name))
+	default:
+		return fmt.Errorf("target AKS cluster '%s' must have Azure RBAC enabled for node authentication; "+
+			"configure azure.bootstrapToken to join a cluster without it", to.String(cluster.Name))
 	}
 
-	i.logger.Infof("Target AKS cluster '%s' has Azure RBAC enabled", to.String(cluster.Name))
+	// The node resource group name derived from the cluster name is only a default; use the actual one
+	if nodeResourceGroup := to.String(cluster.Properties.NodeResourceGroup); nodeResourceGroup != "" {
+		i.config.Azure.TargetCluster.NodeResourceGroup = nodeResourceGroup
+	}
 	return nil
 }
 
+// isAzureRBACEnabled reports whether the cluster authorizes Entra ID identities through Azure RBAC
+func isAzureRBACEnabled(cluster *armcontainerservice.ManagedCluster) bool {
+	return cluster.Properties != nil &&
+		cluster.Properties.AADProfile != nil &&
+		to.Bool(cluster.Properties.AADProfile.EnableAzureRBAC)
+}
+
 func (i *Installer) waitForArcRegistration(ctx context.Context) (*armhybridcompute.Machine, error) {
 	const (
-		maxRetries   = 10
 		initialDelay = 5 * time.Second
 		maxDelay     = 30 * time.Second
 	)
+	maxWaitTime := i.config.GetTimeouts().ArcRegistration
+	deadline := time.Now().Add(maxWaitTime)
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	for attempt := 0; ; attempt++ {
 		machine, err := i.getArcMachine(ctx)
 		if err == nil &&
 			machine != nil &&
@@ -288,10 +335,13 @@ func (i *Installer) waitForArcRegistration(ctx context.Context) (*armhybridcompu
 			machine.Identity.PrincipalID != nil {
 			return machine, nil // Success!
 		}
-		i.logger.Infof("Arc machine not yet registered (attempt %d/%d): %s", attempt+1, maxRetries, err)
+		i.logger.Infof("Arc machine not yet registered (attempt %d): %s", attempt+1, err)
 
-		delay := min(initialDelay*time.Duration(1<<attempt), maxDelay)
-		i.logger.Infof("Registration attempt %d/%d, waiting %v...", attempt+1, maxRetries, delay)
+		delay := min(initialDelay*time.Duration(1<<min(attempt, 8)), maxDelay, time.Until(deadline))
+		if delay <= 0 {
+			break
+		}
+		i.logger.Infof("Registration attempt %d, waiting %v...", attempt+1, delay)
 
 		select {
 		case <-time.After(delay):
@@ -301,37 +351,19 @@ func (i *Installer) waitForArcRegistration(ctx context.Context) (*armhybridcompu
 		}
 	}
 
-	return nil, fmt.Errorf("arc registration timed out after %d attempts", maxRetries)
+	return nil, fmt.Errorf("arc registration timed out after %v", maxWaitTime)
 }
 
 // runArcAgentConnect connects the machine to Azure Arc using the Arc agent
 func (i *Installer) runArcAgentConnect(ctx context.Context) error {
 	i.logger.Info("Connecting machine to Azure Arc using azcmagent")
 
-	// Get Arc configuration details
-	arcLocation := i.config.GetArcLocation()
-	arcMachineName := i.config.GetArcMachineName()
-	arcResourceGroup := i.config.GetArcResourceGroup()
-	subscriptionID := i.config.GetSubscriptionID()
-	tenantID := i.config.GetTenantID()
-
-	// Build azcmagent connect command
-	args := []string{
-		"connect",
-		"--resource-group", arcResourceGroup,
-		"--tenant-id", tenantID,
-		"--location", arcLocation,
-		"--subscription-id", subscriptionID,
-		"--resource-name", arcMachineName,
+	// azcmagent connect reads the proxy from the agent configuration, so it must be set first
+	if err := i.configureArcProxy(); err != nil {
+		return err
 	}
 
-	// Add Arc tags if any
-	tags := i.config.GetArcTags()
-	tagArgs := []string{}
-	for key, value := range tags {
-		tagArgs = append(tagArgs, "--tags", fmt.Sprintf("%s=%s", key, value))
-	}
-	args = append(args, tagArgs...)
+	args := i.connectArgs()
 
 	// Add authentication parameters
 	// For CLI authentication, we need to preserve the user's environment
@@ -348,6 +380,35 @@ func (i *Installer) runArcAgentConnect(ctx context.Context) error {
 	return nil
 }
 
+// connectArgs returns the azcmagent connect arguments identifying the Arc machine, without authentication
+func (i *Installer) connectArgs() []string {
+	args := []string{
+		"connect",
+		"--resource-group", i.config.GetArcResourceGroup(),
+		"--tenant-id", i.config.GetTenantID(),
+		"--location", i.config.GetArcLocation(),
+		"--subscription-id", i.config.GetSubscriptionID(),
+		"--resource-name", i.config.GetArcMachineName(),
+	}
+
+	// Add Arc tags if any, as one comma-separated list in a stable order
+	tags := i.config.GetArcTags()
+	if len(tags) > 0 {
+		tagPairs := make([]string, 0, len(tags))
+		for key, value := range tags {
+			tagPairs = append(tagPairs, fmt.Sprintf("%s=%s", key, value))
+		}
+		sort.Strings(tagPairs)
+		args = append(args, "--tags", strings.Join(tagPairs, ","))
+	}
+
+	// Onboard through the private endpoints of an Arc Private Link Scope instead of public endpoints
+	if scope := i.config.Azure.Arc.PrivateLinkScope; scope != "" {
+		args = append(args, "--private-link-scope", scope)
+	}
+	return args
+}
+
 // assignRBACRoles assigns required RBAC roles to the Arc machine's managed identity
 func (i *Installer) assignRBACRoles(ctx context.Context, arcMachine *armhybridcompute.Machine) error {
 	managedIdentityID := getArcMachineIdentityID(arcMachine)
@@ -355,7 +416,20 @@ func (i *Installer) assignRBACRoles(ctx context.Context, arcMachine *armhybridco
 		return fmt.Errorf("managed identity ID not found on Arc machine")
 	}
 
+	// The custom role must exist before it can be assigned
+	if i.isCustomRoleEnabled() {
+		if err := i.ensureCustomRole(ctx); err != nil {
+			return err
+		}
+	}
+
+	// In no-write mode an operator pre-creates the assignments, so only wait for them to show up
+	if i.config.Azure.NoWrite {
+		return i.waitForPreAuthorizedRoles(ctx, managedIdentityID)
+	}
+
 	// Track assignment results
+	i.initRoleManifest()
 	requiredRoles := i.getRoleAssignments()
 	var assignmentErrors []error
 	for idx, role := range requiredRoles {
@@ -388,30 +462,51 @@ func (i *Installer) assignRBACRoles(ctx context.Context, arcMachine *armhybridco
 	return nil
 }
 
-// assignRole creates a role assignment for the given principal, role, and scope
+// waitForPreAuthorizedRoles prints the operator commands for missing role assignments and waits for them to be created
+func (i *Installer) waitForPreAuthorizedRoles(ctx context.Context, managedIdentityID string) error {
+	if hasPermissions, err := i.checkRequiredPermissions(ctx, managedIdentityID); err == nil && hasPermissions {
+		i.logger.Info("✅ Pre-authorized role assignments found, no role assignments created (noWrite)")
+		return nil
+	}
+
+	planner := &Planner{base: i.base}
+	writes, err := planner.Plan(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to compute required role assignments: %w", err)
+	}
+	i.logger.Warnf("⚠️  azure.noWrite is set and role assignments are missing. Ask an operator to run:\n%s", planner.Script(writes))
+
+	if err := i.waitForPermissions(ctx, managedIdentityID); err != nil {
+		return fmt.Errorf("pre-authorized role assignments were not created (see 'aks-flex-node plan --script'): %w", err)
+	}
+	return nil
+}
+
+// assignRole creates a role assignment for the given principal, role, and scope unless it already exists
 // Implements retry logic with exponential backoff to handle Azure AD replication delays
 func (i *Installer) assignRole(
 	ctx context.Context, principalID, roleDefinitionID, scope, roleName string,
 ) error {
-	// Build the full role definition ID
-	fullRoleDefinitionID := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Authorization/roleDefinitions/%s",
-		i.config.Azure.SubscriptionID, roleDefinitionID)
+	fullRoleDefinitionID := i.fullRoleDefinitionID(roleDefinitionID)
 
-	const (
-		maxRetries   = 5
-		initialDelay = 5 * time.Second
-		maxDelay     = 30 * time.Second
-	)
+	// Look for an existing assignment first so re-runs do not spend ARM write quota
+	exists, err := i.checkRoleAssignment(ctx, principalID, roleDefinitionID, scope)
+	if err != nil {
+		i.logger.Warnf("⚠️  Could not list existing role assignments, creating '%s' anyway: %v", roleName, err)
+	} else if exists {
+		i.logger.Infof("ℹ️  Role assignment '%s' already exists on %s", roleName, scope)
+		return nil
+	}
+
+	backoff := i.config.GetRetries().RoleAssignment.Backoff()
+	maxRetries := backoff.Attempts
 
 	var lastErr error
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
-			delay := min(initialDelay*time.Duration(1<<(attempt-1)), maxDelay)
-			i.logger.Infof("⏳ Retrying role assignment after %v (attempt %d/%d)...", delay, attempt+1, maxRetries)
-			select {
-			case <-time.After(delay):
-			case <-ctx.Done():
-				return ctx.Err()
+			i.logger.Infof("⏳ Retrying role assignment (attempt %d/%d)...", attempt+1, maxRetries)
+			if err := backoff.Wait(ctx, attempt); err != nil {
+				return err
 			}
 		}
 
@@ -438,8 +533,9 @@ func (i *Installer) assignRole(
 			if strings.Contains(errStr, "403") || strings.Contains(errStr, "Forbidden") {
 				return fmt.Errorf("insufficient permissions to assign roles - ensure the user/service principal has Owner or User Access Administrator role on the target cluster: %w", err)
 			}
+			// Another node may have created the same assignment since it was listed
 			if strings.Contains(errStr, "RoleAssignmentExists") {
-				i.logger.Info("ℹ️  Role assignment already exists (detected from error)")
+				i.logger.Info("ℹ️  Role assignment already exists (created concurrently)")
 				return nil
 			}
 
@@ -472,10 +568,21 @@ func (i *Installer) assignRole(
 
 		// Success
 		i.logger.Debugf("✅ Role assignment created successfully")
+		i.recordRoleAssignment(createdRoleAssignment{
+			Name:             roleAssignmentName,
+			Scope:            scope,
+			RoleName:         roleName,
+			RoleDefinitionID: fullRoleDefinitionID,
+			PrincipalID:      principalID,
+			CreatedAt:        time.Now().UTC(),
+		})
 		return nil
 	}
 
 	// Max retries exhausted
+	// A principal that never replicates usually means the cluster lives in a different Azure AD tenant
+	i.logger.Errorf("💡 If the target cluster is in a different Azure AD tenant than this machine, Arc identities cannot be granted roles on it: " +
+		"set azure.targetCluster.tenantId and use a multi-tenant service principal or federated identity provisioned in the cluster tenant")
 	return fmt.Errorf("failed to assign role after %d attempts due to Azure AD replication delay - arc managed identity not found: %w", maxRetries, lastErr)
 }
 
@@ -484,7 +591,7 @@ func (i *Installer) waitForPermissions(ctx context.Context, managedIdentityID st
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
-	maxWaitTime := 10 * time.Minute // Maximum wait time
+	maxWaitTime := i.config.GetTimeouts().PermissionPropagation
 	timeout := time.After(maxWaitTime)
 
 	for {
@@ -16,12 +16,15 @@ import (
 // UnInstaller handles Azure Arc cleanup operations
 type UnInstaller struct {
 	*base
+	deleteMachine bool // Delete the Arc machine resource in Azure, not just the local agent state
 }
 
-// NewUnInstaller creates a new Arc UnInstaller
-func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+// NewUnInstaller creates a new Arc UnInstaller. The Arc machine resource is only deleted from Azure
+// when deleteMachine is set; otherwise it stays in Azure as disconnected.
+func NewUnInstaller(logger *logrus.Logger, deleteMachine bool) *UnInstaller {
 	return &UnInstaller{
-		base: newBase(logger),
+		base:          newBase(logger),
+		deleteMachine: deleteMachine,
 	}
 }
 
@@ -57,24 +60,7 @@ func (u *UnInstaller) Execute(ctx context.Context) error {
 		u.logger.Warnf("Failed to get Arc machine (continuing cleanup): %v", err)
 	}
 
-	var failedOperations []string
-	// Step 2: Remove RBAC role assignments first (while authentication still works)
-	u.logger.Info("Step 2: Removing RBAC role assignments")
-	if err := u.removeRBACRoles(ctx, arcMachine); err != nil {
-		u.logger.Warnf("Failed to remove RBAC roles (continuing cleanup): %v", err)
-		failedOperations = append(failedOperations, "RBAC role removal")
-	} else {
-		u.logger.Info("Successfully removed RBAC role assignments")
-	}
-
-	// Step 3: Unregister Arc machine resource from Azure
-	u.logger.Info("Step 3: Unregistering Arc machine from Azure")
-	if err := u.unregisterArcMachine(ctx); err != nil {
-		u.logger.Warnf("Failed to unregister Arc machine (continuing cleanup): %v", err)
-		failedOperations = append(failedOperations, "Arc machine unregistration")
-	} else {
-		u.logger.Info("Successfully unregistered Arc machine from Azure")
-	}
+	failedOperations := u.removeAzureResources(ctx, arcMachine)
 
 	// Step 4: Disconnect Arc machine
 	// It's for local cleanup only: Removes Arc agent state from the local machine
@@ -107,6 +93,43 @@ func (u *UnInstaller) Execute(ctx context.Context) error {
 	return nil
 }
 
+// removeAzureResources removes the role assignments of the Arc machine and, with deleteMachine, the machine
+// resource itself, and returns the operations that failed
+func (u *UnInstaller) removeAzureResources(ctx context.Context, arcMachine *armhybridcompute.Machine) []string {
+	if u.config.Azure.NoWrite {
+		// Role assignments and the machine resource are owned by the operator who pre-authorized them
+		u.logger.Infof("azure.noWrite is set, leaving role assignments and Arc machine resource %s for the operator to delete",
+			u.config.GetArcMachineName())
+		return nil
+	}
+
+	var failedOperations []string
+
+	// Step 2: Remove RBAC role assignments first (while authentication still works)
+	u.logger.Info("Step 2: Removing RBAC role assignments")
+	if err := u.removeRBACRoles(ctx, arcMachine); err != nil {
+		u.logger.Warnf("Failed to remove RBAC roles (continuing cleanup): %v", err)
+		failedOperations = append(failedOperations, "RBAC role removal")
+	} else {
+		u.logger.Info("Successfully removed RBAC role assignments")
+	}
+
+	// Step 3: Unregister Arc machine resource from Azure
+	if u.deleteMachine {
+		u.logger.Info("Step 3: Unregistering Arc machine from Azure")
+		if err := u.unregisterArcMachine(ctx); err != nil {
+			u.logger.Warnf("Failed to unregister Arc machine (continuing cleanup): %v", err)
+			failedOperations = append(failedOperations, "Arc machine unregistration")
+		} else {
+			u.logger.Info("Successfully unregistered Arc machine from Azure")
+		}
+	} else {
+		u.logger.Infof("Step 3: Keeping Arc machine resource %s in Azure as disconnected (delete it with --delete-arc-resource)",
+			u.config.GetArcMachineName())
+	}
+	return failedOperations
+}
+
 // unregisterArcMachine removes the Arc machine registration from Azure
 func (u *UnInstaller) unregisterArcMachine(ctx context.Context) error {
 	u.logger.Info("Unregistering Arc machine from Azure")
@@ -137,8 +160,22 @@ func (u *UnInstaller) removeRBACRoles(ctx context.Context, arcMachine *armhybrid
 
 	u.logger.Infof("Removing role assignments for managed identity: %s", managedIdentityID)
 
-	// Define the scopes where we assigned roles
-	// Remove each role assignment
+	// Delete exactly the assignments this agent created, including ones on scopes no longer in the config
+	recorded, found, err := u.loadRoleManifest()
+	if err != nil {
+		u.logger.Warnf("Failed to read role assignment manifest, falling back to configured roles: %v", err)
+	}
+	if found {
+		return u.removeRecordedRoleAssignments(ctx, recorded)
+	}
+
+	// Resolve a custom role created outside the agent so its assignment is matched by the right ID
+	if u.isCustomRoleEnabled() {
+		if _, err := u.findCustomRole(ctx); err != nil {
+			u.logger.Warnf("Failed to look up custom role definition: %v", err)
+		}
+	}
+
 	var removalErrors []string
 	rolesToRemove := u.getRoleAssignments()
 	for _, role := range rolesToRemove {
@@ -159,6 +196,37 @@ func (u *UnInstaller) removeRBACRoles(ctx context.Context, arcMachine *armhybrid
 	return nil
 }
 
+// removeRecordedRoleAssignments deletes the role assignments listed in the manifest. Deleted assignments
+// are dropped from the manifest, failed ones are kept so a later unbootstrap can retry them.
+func (u *UnInstaller) removeRecordedRoleAssignments(ctx context.Context, recorded []createdRoleAssignment) error {
+	var (
+		remaining     []createdRoleAssignment
+		removalErrors []string
+	)
+	for _, assignment := range recorded {
+		u.logger.Infof("Removing role assignment: %s on scope %s", assignment.RoleName, assignment.Scope)
+		if _, err := u.roleAssignmentsClient.Delete(ctx, assignment.Scope, assignment.Name, nil); err != nil &&
+			!strings.Contains(err.Error(), "RoleAssignmentNotFound") && !strings.Contains(err.Error(), "NotFound") {
+			u.logger.Warnf("Failed to remove role assignment %s on scope %s: %v", assignment.RoleName, assignment.Scope, err)
+			removalErrors = append(removalErrors, fmt.Sprintf("%s: %v", assignment.RoleName, err))
+			remaining = append(remaining, assignment)
+			continue
+		}
+		u.logger.Infof("Successfully removed role assignment: %s on scope %s", assignment.RoleName, assignment.Scope)
+	}
+
+	if err := u.saveRoleManifest(remaining); err != nil {
+		u.logger.Warnf("Failed to update role assignment manifest: %v", err)
+	}
+
+	if len(removalErrors) > 0 {
+		return fmt.Errorf("failed to remove some role assignments: %s", strings.Join(removalErrors, "; "))
+	}
+
+	u.logger.Infof("All %d recorded RBAC role assignments removed successfully", len(recorded))
+	return nil
+}
+
 // disconnectArcMachine disconnects the machine using azcmagent
 func (u *UnInstaller) disconnectArcMachine(ctx context.Context) error {
 	u.logger.Info("Disconnecting Arc machine")
@@ -175,9 +243,7 @@ func (u *UnInstaller) disconnectArcMachine(ctx context.Context) error {
 
 // removeRoleAssignment removes role assignment for a specific principal, role, and scope
 func (u *UnInstaller) removeRoleAssignment(ctx context.Context, principalID, roleDefinitionID, scope, roleName string) error {
-	// Build the full role definition ID
-	fullRoleDefinitionID := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Authorization/roleDefinitions/%s",
-		u.config.Azure.SubscriptionID, roleDefinitionID)
+	fullRoleDefinitionID := u.fullRoleDefinitionID(roleDefinitionID)
 
 	// List role assignment for the scope
 	pager := u.roleAssignmentsClient.NewListForScopePager(scope, &armauthorization.RoleAssignmentsClientListForScopeOptions{
@@ -0,0 +1,105 @@
+package arc
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/hybridcompute/armhybridcompute"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/hybridcompute/armhybridcompute/fake"
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// newTestUnInstaller returns an uninstaller whose Arc machine deletions are recorded into deleted
+func newTestUnInstaller(t *testing.T, deleteMachine, noWrite bool, deleteStatus int, deleted *[]string) *UnInstaller {
+	t.Helper()
+	server := &fake.MachinesServer{
+		Delete: func(ctx context.Context, resourceGroupName string, machineName string, options *armhybridcompute.MachinesClientDeleteOptions) (resp azfake.Responder[armhybridcompute.MachinesClientDeleteResponse], errResp azfake.ErrorResponder) {
+			*deleted = append(*deleted, resourceGroupName+"/"+machineName)
+			if deleteStatus != http.StatusOK {
+				errResp.SetResponseError(deleteStatus, "InternalServerError")
+				return resp, errResp
+			}
+			resp.SetResponse(http.StatusOK, armhybridcompute.MachinesClientDeleteResponse{}, nil)
+			return resp, errResp
+		},
+	}
+	client, err := armhybridcompute.NewMachinesClient("test-sub-id", &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: fake.NewMachinesServerTransport(server)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return &UnInstaller{
+		base: &base{
+			config: &config.Config{Azure: config.AzureConfig{
+				SubscriptionID: "test-sub-id",
+				NoWrite:        noWrite,
+				Arc:            &config.ArcConfig{Enabled: true, MachineName: "edge-node-1", ResourceGroup: "arc-rg"},
+			}},
+			logger:                     logger,
+			hybridComputeMachineClient: client,
+			roleAssignmentsClient:      &mockRoleAssignmentsClient{},
+		},
+		deleteMachine: deleteMachine,
+	}
+}
+
+func TestRemoveAzureResources(t *testing.T) {
+	tests := []struct {
+		name          string
+		deleteMachine bool
+		noWrite       bool
+		deleteStatus  int
+		wantDeleted   []string
+		wantFailed    []string
+	}{
+		{
+			name:         "machine kept without --delete-arc-resource",
+			deleteStatus: http.StatusOK,
+		},
+		{
+			name:          "machine deleted with --delete-arc-resource",
+			deleteMachine: true,
+			deleteStatus:  http.StatusOK,
+			wantDeleted:   []string{"arc-rg/edge-node-1"},
+		},
+		{
+			name:          "failed deletion is reported",
+			deleteMachine: true,
+			deleteStatus:  http.StatusInternalServerError,
+			wantDeleted:   []string{"arc-rg/edge-node-1"},
+			wantFailed:    []string{"Arc machine unregistration"},
+		},
+		{
+			name:          "no-write leaves the machine to the operator",
+			deleteMachine: true,
+			noWrite:       true,
+			deleteStatus:  http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted []string
+			uninstaller := newTestUnInstaller(t, tt.deleteMachine, tt.noWrite, tt.deleteStatus, &deleted)
+
+			// Without a machine identity there are no role assignments to remove
+			failed := uninstaller.removeAzureResources(context.Background(), nil)
+			if !slices.Equal(deleted, tt.wantDeleted) {
+				t.Errorf("deleted machines = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if !slices.Equal(failed, tt.wantFailed) {
+				t.Errorf("failed operations = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+}
@@ -12,7 +12,44 @@ var (
 		"Network Contributor": "4d97b98b-1d4f-4787-a291-c67834d212e7",
 		"Contributor":         "b24988ac-6180-42a0-ab88-20f7382dd24c",
 		"Azure Kubernetes Service RBAC Cluster Admin": "b1ff04bb-8a4e-4dc4-8eb5-8693973ce19b",
-		"Azure Kubernetes Service Cluster Admin Role": "0ab0b1a8-8aac-4efd-b8c2-3ee1fb270be8",
+		"Azure Kubernetes Service Cluster User Role":  "4abbcc35-e782-43d8-92c5-2d3f1bd2253f",
+	}
+
+	// customRoleActions are the control plane operations the node needs on the target cluster:
+	// reading the cluster and fetching its user kubeconfig (server URL and CA) during kubelet setup.
+	// The user kubeconfig carries no credentials of its own, unlike the admin kubeconfig, which holds a
+	// cluster-admin client certificate on clusters with local accounts.
+	customRoleActions = []string{
+		"Microsoft.ContainerService/managedClusters/read",
+		"Microsoft.ContainerService/managedClusters/listClusterUserCredential/action",
+	}
+
+	// customRoleDataActions are the Kubernetes API permissions kubelet needs when authenticating
+	// with the Arc identity through Azure RBAC, following what the node authorizer grants kubelets.
+	// Azure RBAC cannot scope a grant to the node's own Node object or to the pods bound to it and the
+	// secrets and configmaps they reference, so these apply cluster-wide. Pods are limited to the verbs
+	// kubelet uses, which leaves out exec, attach and port-forward.
+	customRoleDataActions = []string{
+		"Microsoft.ContainerService/managedClusters/nodes/read",
+		"Microsoft.ContainerService/managedClusters/nodes/write",
+		"Microsoft.ContainerService/managedClusters/pods/read",
+		"Microsoft.ContainerService/managedClusters/pods/write",
+		"Microsoft.ContainerService/managedClusters/pods/delete",
+		"Microsoft.ContainerService/managedClusters/events/write",
+		"Microsoft.ContainerService/managedClusters/events.k8s.io/events/write",
+		"Microsoft.ContainerService/managedClusters/coordination.k8s.io/leases/*",
+		"Microsoft.ContainerService/managedClusters/certificates.k8s.io/certificatesigningrequests/read",
+		"Microsoft.ContainerService/managedClusters/certificates.k8s.io/certificatesigningrequests/write",
+		"Microsoft.ContainerService/managedClusters/configmaps/read",
+		"Microsoft.ContainerService/managedClusters/secrets/read",
+		"Microsoft.ContainerService/managedClusters/services/read",
+		"Microsoft.ContainerService/managedClusters/endpoints/read",
+		"Microsoft.ContainerService/managedClusters/discovery.k8s.io/endpointslices/read",
+		"Microsoft.ContainerService/managedClusters/persistentvolumes/read",
+		"Microsoft.ContainerService/managedClusters/persistentvolumeclaims/read",
+		"Microsoft.ContainerService/managedClusters/serviceaccounts/token/action",
+		"Microsoft.ContainerService/managedClusters/storage.k8s.io/*/read",
+		"Microsoft.ContainerService/managedClusters/node.k8s.io/runtimeclasses/read",
 	}
 
 	// Arc services that may be present (not all are guaranteed to exist on every installation)
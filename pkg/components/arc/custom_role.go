@@ -0,0 +1,174 @@
+package arc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v3"
+	"github.com/google/uuid"
+)
+
+// isCustomRoleEnabled checks if the least-privilege custom role replaces the built-in roles
+func (ab *base) isCustomRoleEnabled() bool {
+	return ab.config.Azure.Arc != nil && ab.config.Azure.Arc.CustomRole.Enabled
+}
+
+// customRoleScope returns the scope the custom role is defined at and assignable to: the cluster subscription
+func (ab *base) customRoleScope() string {
+	subscriptionID := ab.config.GetTargetClusterSubscriptionID()
+	if subscriptionID == "" {
+		subscriptionID = ab.config.Azure.SubscriptionID
+	}
+	return "/subscriptions/" + subscriptionID
+}
+
+// customRoleID returns the role definition ID of the custom role. A definition found by name
+// (e.g. created by an operator) takes precedence; otherwise a stable ID derived from the role name
+// and scope is used so that every node reuses the same definition.
+func (ab *base) customRoleID() string {
+	if ab.resolvedCustomRoleID != "" {
+		return ab.resolvedCustomRoleID
+	}
+	key := strings.ToLower(ab.customRoleScope() + "/" + ab.config.Azure.Arc.CustomRole.Name)
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String()
+}
+
+// customRoleDefinition builds the desired custom role definition
+func (ab *base) customRoleDefinition() armauthorization.RoleDefinition {
+	name := ab.config.Azure.Arc.CustomRole.Name
+	scope := ab.customRoleScope()
+	roleType := "CustomRole"
+	description := "Least-privilege permissions for AKS Flex Node machines joining clusters with their Arc identity"
+	return armauthorization.RoleDefinition{
+		Properties: &armauthorization.RoleDefinitionProperties{
+			RoleName:         &name,
+			Description:      &description,
+			RoleType:         &roleType,
+			AssignableScopes: []*string{&scope},
+			Permissions: []*armauthorization.Permission{{
+				Actions:     stringPtrs(customRoleActions),
+				DataActions: stringPtrs(customRoleDataActions),
+			}},
+		},
+	}
+}
+
+// findCustomRole returns the existing custom role definition, looking it up by ID and then by name.
+// It returns nil without error when no definition exists.
+func (ab *base) findCustomRole(ctx context.Context) (*armauthorization.RoleDefinition, error) {
+	scope := ab.customRoleScope()
+	resp, err := ab.roleDefinitionsClient.Get(ctx, scope, ab.customRoleID(), nil)
+	if err == nil {
+		return &resp.RoleDefinition, nil
+	}
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusNotFound {
+		return nil, fmt.Errorf("failed to get custom role definition: %w", err)
+	}
+
+	// Role names are unique per tenant, so a definition created elsewhere (e.g. by an operator) is found by name
+	filter := fmt.Sprintf("roleName eq '%s'", ab.config.Azure.Arc.CustomRole.Name)
+	pager := ab.roleDefinitionsClient.NewListPager(scope, &armauthorization.RoleDefinitionsClientListOptions{Filter: &filter})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list role definitions: %w", err)
+		}
+		for _, definition := range page.Value {
+			if definition != nil && definition.Name != nil {
+				ab.resolvedCustomRoleID = path.Base(*definition.Name)
+				return definition, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ensureCustomRole creates the custom role definition, or reuses the existing one when it already grants
+// the required permissions. In no-write mode a missing or outdated definition is an error.
+func (ab *base) ensureCustomRole(ctx context.Context) error {
+	name := ab.config.Azure.Arc.CustomRole.Name
+	existing, err := ab.findCustomRole(ctx)
+	if err != nil {
+		return err
+	}
+	if existing != nil && grantsCustomRolePermissions(existing) {
+		ab.logger.Infof("✅ Reusing custom role definition '%s' (%s)", name, ab.customRoleID())
+		return nil
+	}
+
+	if ab.config.Azure.NoWrite {
+		return fmt.Errorf("custom role '%s' is missing or lacks required permissions and azure.noWrite is set (see 'aks-flex-node plan --script')", name)
+	}
+
+	ab.logger.Infof("📋 Creating custom role definition '%s' on %s", name, ab.customRoleScope())
+	if _, err := ab.roleDefinitionsClient.CreateOrUpdate(ctx, ab.customRoleScope(), ab.customRoleID(), ab.customRoleDefinition(), nil); err != nil {
+		return fmt.Errorf("failed to create custom role definition '%s': %w", name, err)
+	}
+	return nil
+}
+
+// grantsCustomRolePermissions checks if a role definition includes every required action and data action
+func grantsCustomRolePermissions(definition *armauthorization.RoleDefinition) bool {
+	if definition.Properties == nil {
+		return false
+	}
+	actions := map[string]bool{}
+	dataActions := map[string]bool{}
+	for _, permission := range definition.Properties.Permissions {
+		if permission == nil {
+			continue
+		}
+		for _, action := range permission.Actions {
+			if action != nil {
+				actions[strings.ToLower(*action)] = true
+			}
+		}
+		for _, action := range permission.DataActions {
+			if action != nil {
+				dataActions[strings.ToLower(*action)] = true
+			}
+		}
+	}
+	for _, action := range customRoleActions {
+		if !actions[strings.ToLower(action)] {
+			return false
+		}
+	}
+	for _, action := range customRoleDataActions {
+		if !dataActions[strings.ToLower(action)] {
+			return false
+		}
+	}
+	return true
+}
+
+// customRoleScript renders the custom role definition as an az role definition create command
+func (ab *base) customRoleScript() string {
+	quote := func(values []string) string {
+		return `"` + strings.Join(values, `", "`) + `"`
+	}
+	return fmt.Sprintf(`az role definition create --role-definition '{
+  "Name": "%s",
+  "IsCustom": true,
+  "Description": "Least-privilege permissions for AKS Flex Node machines",
+  "Actions": [%s],
+  "DataActions": [%s],
+  "AssignableScopes": ["%s"]
+}'
+`, ab.config.Azure.Arc.CustomRole.Name, quote(customRoleActions), quote(customRoleDataActions), ab.customRoleScope())
+}
+
+// stringPtrs converts a string slice into the pointer slice used by the Azure SDK models
+func stringPtrs(values []string) []*string {
+	ptrs := make([]*string, 0, len(values))
+	for i := range values {
+		ptrs = append(ptrs, &values[i])
+	}
+	return ptrs
+}
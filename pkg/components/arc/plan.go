@@ -0,0 +1,160 @@
+package arc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/hybridcompute/armhybridcompute"
+	"github.com/sirupsen/logrus"
+)
+
+// Azure Resource Manager operations performed by the Arc steps
+const (
+	OperationMachineWrite        = "Microsoft.HybridCompute/machines/write"
+	OperationRoleAssignmentWrite = "Microsoft.Authorization/roleAssignments/write"
+	OperationRoleDefinitionWrite = "Microsoft.Authorization/roleDefinitions/write"
+)
+
+// PlannedWrite describes an Azure Resource Manager write that Arc bootstrap would perform
+type PlannedWrite struct {
+	Operation        string `json:"operation"`                  // ARM operation, e.g. Microsoft.Authorization/roleAssignments/write
+	Scope            string `json:"scope"`                      // Resource ID the write targets
+	PrincipalID      string `json:"principalId,omitempty"`      // Principal receiving the role, empty until the machine is registered
+	Role             string `json:"role,omitempty"`             // Role name for role assignments and definitions
+	RoleDefinitionID string `json:"roleDefinitionId,omitempty"` // Full role definition ID for role assignments and definitions
+	Exists           bool   `json:"exists"`                     // Already in place, so bootstrap will not write it
+	Description      string `json:"description"`
+}
+
+// Planner computes the Azure writes of Arc bootstrap without changing anything
+type Planner struct {
+	*base
+}
+
+// NewPlanner creates a new Arc write planner
+func NewPlanner(logger *logrus.Logger) *Planner {
+	return &Planner{
+		base: newBase(logger),
+	}
+}
+
+// Plan lists the Azure writes Arc bootstrap would perform for the current configuration.
+// When Azure is reachable, the machine identity and existing role assignments are looked up so only
+// missing writes remain; otherwise every write is listed with an unknown principal.
+func (p *Planner) Plan(ctx context.Context) ([]PlannedWrite, error) {
+	if !p.config.IsARCEnabled() {
+		return nil, nil
+	}
+
+	online := true
+	if err := p.setUpClients(ctx); err != nil {
+		p.logger.Warnf("Cannot reach Azure, listing all writes without checking existing state: %v", err)
+		online = false
+	}
+
+	var machine *armhybridcompute.Machine
+	if online {
+		if registered, err := p.getArcMachine(ctx); err == nil {
+			machine = registered
+		}
+	}
+	return p.plannedWrites(ctx, online, machine), nil
+}
+
+// plannedWrites lists the Azure writes for the registered machine, nil before registration. Online, the
+// existing custom role and role assignments are looked up so the writes already in place are marked.
+func (p *Planner) plannedWrites(ctx context.Context, online bool, machine *armhybridcompute.Machine) []PlannedWrite {
+	machineID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.HybridCompute/machines/%s",
+		p.config.GetSubscriptionID(), p.config.GetArcResourceGroup(), p.config.GetArcMachineName())
+	machineWrite := PlannedWrite{
+		Operation:   OperationMachineWrite,
+		Scope:       machineID,
+		Exists:      machine != nil,
+		Description: "Register the machine with Azure Arc (performed by azcmagent connect, requires Azure Connected Machine Onboarding on the resource group)",
+	}
+	principalID := getArcMachineIdentityID(machine)
+	writes := []PlannedWrite{machineWrite}
+
+	if p.isCustomRoleEnabled() {
+		roleWrite := PlannedWrite{
+			Operation:   OperationRoleDefinitionWrite,
+			Scope:       p.customRoleScope(),
+			Role:        p.config.Azure.Arc.CustomRole.Name,
+			Description: "Create the least-privilege custom role assigned to the Arc machine identity",
+		}
+		if online {
+			if existing, err := p.findCustomRole(ctx); err != nil {
+				p.logger.Warnf("Failed to check existing custom role: %v", err)
+			} else {
+				roleWrite.Exists = existing != nil && grantsCustomRolePermissions(existing)
+			}
+		}
+		roleWrite.RoleDefinitionID = p.fullRoleDefinitionID(p.customRoleID())
+		writes = append(writes, roleWrite)
+	}
+
+	for _, role := range p.getRoleAssignments() {
+		write := PlannedWrite{
+			Operation:        OperationRoleAssignmentWrite,
+			Scope:            role.scope,
+			PrincipalID:      principalID,
+			Role:             role.roleName,
+			RoleDefinitionID: p.fullRoleDefinitionID(role.roleID),
+			Description:      fmt.Sprintf("Grant the Arc machine identity %s", role.roleName),
+		}
+		if online && principalID != "" {
+			exists, err := p.checkRoleAssignment(ctx, principalID, role.roleID, role.scope)
+			if err != nil {
+				p.logger.Warnf("Failed to check existing %s assignment: %v", role.roleName, err)
+			}
+			write.Exists = exists
+		}
+		writes = append(writes, write)
+	}
+	return writes
+}
+
+// Script renders the missing writes as an Azure CLI script a privileged operator can run separately.
+// Arc registration is left to azcmagent on the machine, so the script resolves the principal from
+// the registered machine when it is not known yet.
+func (p *Planner) Script(writes []PlannedWrite) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/bash\n")
+	sb.WriteString("# Role assignments required by aks-flex-node. Run as a user allowed to assign roles on the scopes below.\n")
+	sb.WriteString("set -euo pipefail\n\n")
+
+	principalResolved := false
+	for _, write := range writes {
+		if write.Exists {
+			continue
+		}
+		switch write.Operation {
+		case OperationMachineWrite:
+			sb.WriteString(fmt.Sprintf("# %s\n# Run 'aks-flex-node bootstrap' on the machine first so the Arc identity exists.\n\n", write.Description))
+		case OperationRoleDefinitionWrite:
+			sb.WriteString(fmt.Sprintf("# %s\n", write.Description))
+			sb.WriteString(p.customRoleScript())
+			sb.WriteString("\n")
+		case OperationRoleAssignmentWrite:
+			principal := write.PrincipalID
+			if principal == "" {
+				if !principalResolved {
+					sb.WriteString(fmt.Sprintf("PRINCIPAL_ID=$(az connectedmachine show --subscription %s --resource-group %s --name %s --query identity.principalId -o tsv)\n\n",
+						p.config.GetSubscriptionID(), p.config.GetArcResourceGroup(), p.config.GetArcMachineName()))
+					principalResolved = true
+				}
+				principal = "$PRINCIPAL_ID"
+			}
+			sb.WriteString(fmt.Sprintf("# %s\n", write.Description))
+			// A custom role created by the script gets a new ID, so it is referenced by name
+			role := write.RoleDefinitionID
+			if p.isCustomRoleEnabled() {
+				role = write.Role
+			}
+			sb.WriteString(fmt.Sprintf("az role assignment create --assignee-object-id \"%s\" --assignee-principal-type ServicePrincipal \\\n  --role \"%s\" --scope \"%s\"\n\n",
+				principal, role, write.Scope))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,161 @@
+package arc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v3"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/hybridcompute/armhybridcompute"
+	"github.com/sirupsen/logrus"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+const planClusterID = "/subscriptions/cluster-sub/resourceGroups/cluster-rg/providers/Microsoft.ContainerService/managedClusters/cluster"
+
+func newTestPlanner(arc *config.ArcConfig) *Planner {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cfg := &config.Config{
+		Azure: config.AzureConfig{
+			SubscriptionID: "machine-sub",
+			Arc:            arc,
+			TargetCluster: &config.TargetClusterConfig{
+				ResourceID:     planClusterID,
+				SubscriptionID: "cluster-sub",
+				ResourceGroup:  "cluster-rg",
+			},
+		},
+	}
+	return &Planner{base: &base{config: cfg, logger: logger}}
+}
+
+func TestPlanWithoutArc(t *testing.T) {
+	planner := newTestPlanner(nil)
+	writes, err := planner.Plan(context.Background())
+	if err != nil || writes != nil {
+		t.Errorf("Plan() = %v, %v, want no writes without Arc", writes, err)
+	}
+}
+
+func TestPlannedWritesOffline(t *testing.T) {
+	planner := newTestPlanner(&config.ArcConfig{Enabled: true, MachineName: "node-1", ResourceGroup: "arc-rg"})
+	writes := planner.plannedWrites(context.Background(), false, nil)
+
+	if len(writes) != 4 {
+		t.Fatalf("plannedWrites() returned %d writes, want the machine and three role assignments: %+v", len(writes), writes)
+	}
+	machine := writes[0]
+	if machine.Operation != OperationMachineWrite || machine.Exists ||
+		machine.Scope != "/subscriptions/machine-sub/resourceGroups/arc-rg/providers/Microsoft.HybridCompute/machines/node-1" {
+		t.Errorf("machine write = %+v", machine)
+	}
+	for _, write := range writes[1:] {
+		if write.Operation != OperationRoleAssignmentWrite || write.Scope != planClusterID || write.PrincipalID != "" || write.Exists {
+			t.Errorf("role assignment write = %+v, want an unchecked assignment on the cluster", write)
+		}
+		if want := "/subscriptions/cluster-sub/providers/Microsoft.Authorization/roleDefinitions/" + roleDefinitionIDs[write.Role]; write.RoleDefinitionID != want {
+			t.Errorf("%s role definition = %s, want %s", write.Role, write.RoleDefinitionID, want)
+		}
+	}
+}
+
+func TestPlannedWritesMarksExistingAssignments(t *testing.T) {
+	planner := newTestPlanner(&config.ArcConfig{Enabled: true, MachineName: "node-1", RoleScope: config.RoleScopeNodeResourceGroup})
+	planner.config.Azure.TargetCluster.NodeResourceGroup = "MC_cluster"
+	nodeResourceGroup := "/subscriptions/cluster-sub/resourceGroups/MC_cluster"
+
+	principalID := "machine-principal"
+	readerID := planner.fullRoleDefinitionID(roleDefinitionIDs["Reader"])
+	subscription := "/subscriptions/cluster-sub"
+	planner.roleAssignmentsClient = &mockRoleAssignmentsClient{
+		existing: []*armauthorization.RoleAssignment{{
+			Properties: &armauthorization.RoleAssignmentProperties{
+				PrincipalID:      &principalID,
+				RoleDefinitionID: &readerID,
+				Scope:            &subscription,
+			},
+		}},
+	}
+	machine := &armhybridcompute.Machine{Identity: &armhybridcompute.Identity{PrincipalID: &principalID}}
+
+	writes := planner.plannedWrites(context.Background(), true, machine)
+	if !writes[0].Exists {
+		t.Error("machine write not marked as existing for a registered machine")
+	}
+	for _, write := range writes[1:] {
+		if write.PrincipalID != principalID || write.Scope != nodeResourceGroup {
+			t.Errorf("role assignment write = %+v, want the machine principal on the node resource group", write)
+		}
+		if want := write.Role == "Reader"; write.Exists != want {
+			t.Errorf("%s exists = %v, want %v", write.Role, write.Exists, want)
+		}
+	}
+}
+
+func TestPlannedWritesCustomRole(t *testing.T) {
+	planner := newTestPlanner(&config.ArcConfig{
+		Enabled:     true,
+		MachineName: "node-1",
+		CustomRole:  config.CustomRoleConfig{Enabled: true, Name: "AKS Flex Node"},
+	})
+	writes := planner.plannedWrites(context.Background(), false, nil)
+
+	if len(writes) != 3 {
+		t.Fatalf("plannedWrites() returned %d writes, want the machine, the role definition and one assignment: %+v", len(writes), writes)
+	}
+	definition, assignment := writes[1], writes[2]
+	if definition.Operation != OperationRoleDefinitionWrite || definition.Scope != "/subscriptions/cluster-sub" || definition.Role != "AKS Flex Node" {
+		t.Errorf("role definition write = %+v", definition)
+	}
+	if assignment.Role != "AKS Flex Node" || assignment.RoleDefinitionID != definition.RoleDefinitionID {
+		t.Errorf("role assignment write = %+v, want the custom role %s", assignment, definition.RoleDefinitionID)
+	}
+}
+
+func TestPlanScript(t *testing.T) {
+	planner := newTestPlanner(&config.ArcConfig{Enabled: true, MachineName: "node-1"})
+	writes := planner.plannedWrites(context.Background(), false, nil)
+	writes[1].Exists = true
+
+	script := planner.Script(writes)
+	if !strings.HasPrefix(script, "#!/bin/bash\n") {
+		t.Errorf("script does not start with a shebang:\n%s", script)
+	}
+	if strings.Count(script, "PRINCIPAL_ID=$(az connectedmachine show") != 1 {
+		t.Errorf("script does not resolve the principal exactly once:\n%s", script)
+	}
+	if strings.Contains(script, `--role "`+writes[1].RoleDefinitionID+`"`) {
+		t.Errorf("script assigns %s, which already exists:\n%s", writes[1].Role, script)
+	}
+	for _, write := range writes[2:] {
+		if !strings.Contains(script, `--assignee-object-id "$PRINCIPAL_ID"`) || !strings.Contains(script, `--role "`+write.RoleDefinitionID+`"`) {
+			t.Errorf("script does not assign %s:\n%s", write.Role, script)
+		}
+	}
+
+	// A known principal is used directly
+	for i := range writes {
+		writes[i].PrincipalID = "machine-principal"
+	}
+	if script := planner.Script(writes); strings.Contains(script, "PRINCIPAL_ID") || !strings.Contains(script, `--assignee-object-id "machine-principal"`) {
+		t.Errorf("script does not use the known principal:\n%s", script)
+	}
+}
+
+func TestPlanScriptCustomRole(t *testing.T) {
+	planner := newTestPlanner(&config.ArcConfig{
+		Enabled:     true,
+		MachineName: "node-1",
+		CustomRole:  config.CustomRoleConfig{Enabled: true, Name: "AKS Flex Node"},
+	})
+	script := planner.Script(planner.plannedWrites(context.Background(), false, nil))
+
+	if !strings.Contains(script, "az role definition create") || !strings.Contains(script, `"AssignableScopes": ["/subscriptions/cluster-sub"]`) {
+		t.Errorf("script does not create the custom role:\n%s", script)
+	}
+	// The role definition created by the script gets a new ID, so the assignment names the role
+	if !strings.Contains(script, `--role "AKS Flex Node"`) {
+		t.Errorf("script does not assign the custom role by name:\n%s", script)
+	}
+}
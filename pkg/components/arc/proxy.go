@@ -0,0 +1,60 @@
+package arc
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// arcProxyURL returns the proxy the Arc agent must use, or an empty string for direct access.
+// azure.arc.proxy.url wins over the node-wide network.proxy, of which Arc only uses the HTTPS proxy
+// since all its endpoints are HTTPS.
+func (ab *base) arcProxyURL() string {
+	if ab.config.Azure.Arc != nil && ab.config.Azure.Arc.Proxy.URL != "" {
+		return ab.config.Azure.Arc.Proxy.URL
+	}
+	return cmp.Or(ab.config.Network.Proxy.HTTPSProxy, ab.config.Network.Proxy.HTTPProxy)
+}
+
+// redactedProxyURL returns the proxy URL with the password of its userinfo masked, for logs
+func redactedProxyURL(proxyURL string) string {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Redacted()
+}
+
+// proxyEnv returns the environment variables making curl, wget and package managers use the proxy
+func proxyEnv(proxyURL string) []string {
+	return []string{"http_proxy=" + proxyURL, "https_proxy=" + proxyURL}
+}
+
+// configureArcProxy stores the proxy in the Arc agent configuration. azcmagent connect and the
+// Arc services (himds, guest configuration, extensions) all read it from there.
+func (i *Installer) configureArcProxy() error {
+	proxyURL := i.arcProxyURL()
+	if proxyURL == "" {
+		return nil
+	}
+
+	i.logger.Infof("Configuring Azure Arc agent to use proxy %s", redactedProxyURL(proxyURL))
+	if output, err := utils.RunCommandWithOutput("azcmagent", "config", "set", "proxy.url", proxyURL); err != nil {
+		return fmt.Errorf("failed to set Arc agent proxy: %w, output: %s", err, output)
+	}
+
+	bypass := i.config.Azure.Arc.Proxy.Bypass
+	if len(bypass) == 0 {
+		if output, err := utils.RunCommandWithOutput("azcmagent", "config", "clear", "proxy.bypass"); err != nil {
+			i.logger.Debugf("Failed to clear Arc agent proxy bypass: %v, output: %s", err, output)
+		}
+		return nil
+	}
+	if output, err := utils.RunCommandWithOutput("azcmagent", "config", "set", "proxy.bypass", strings.Join(bypass, ",")); err != nil {
+		return fmt.Errorf("failed to set Arc agent proxy bypass: %w, output: %s", err, output)
+	}
+	return nil
+}
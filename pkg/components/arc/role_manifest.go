@@ -0,0 +1,92 @@
+package arc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/state"
+)
+
+// roleManifestKey is the state key listing the role assignments created by the agent
+const roleManifestKey = "arc-role-assignments"
+
+// createdRoleAssignment is a role assignment the agent created for the Arc machine identity.
+// Only these are deleted on unbootstrap, so assignments made by operators are left alone.
+type createdRoleAssignment struct {
+	Name             string    `json:"name"`             // Role assignment name (GUID)
+	Scope            string    `json:"scope"`            // Scope the assignment was created on
+	RoleName         string    `json:"roleName"`         // Display name of the role
+	RoleDefinitionID string    `json:"roleDefinitionId"` // Full role definition ID
+	PrincipalID      string    `json:"principalId"`      // Arc machine identity
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+// loadRoleManifest returns the recorded role assignments. A missing manifest (e.g. a node bootstrapped by
+// an older agent) returns found=false so callers can fall back to matching the configured roles.
+func (ab *base) loadRoleManifest() (assignments []createdRoleAssignment, found bool, err error) {
+	store, err := state.Open(ab.config, ab.logger)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = store.Close() }()
+
+	data, err := store.Get(roleManifestKey)
+	if errors.Is(err, state.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := json.Unmarshal(data, &assignments); err != nil {
+		return nil, false, fmt.Errorf("failed to decode role assignment manifest: %w", err)
+	}
+	return assignments, true, nil
+}
+
+// saveRoleManifest replaces the recorded role assignments
+func (ab *base) saveRoleManifest(assignments []createdRoleAssignment) error {
+	store, err := state.Open(ab.config, ab.logger)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	data, err := json.Marshal(assignments)
+	if err != nil {
+		return fmt.Errorf("failed to encode role assignment manifest: %w", err)
+	}
+	return store.Put(roleManifestKey, data)
+}
+
+// initRoleManifest starts an empty manifest on nodes that have none, so that assignments which already
+// existed before bootstrap are not mistaken for the agent's own on unbootstrap
+func (ab *base) initRoleManifest() {
+	_, found, err := ab.loadRoleManifest()
+	if err != nil || found {
+		return
+	}
+	if err := ab.saveRoleManifest([]createdRoleAssignment{}); err != nil {
+		ab.logger.Warnf("Failed to create role assignment manifest: %v", err)
+	}
+}
+
+// recordRoleAssignment adds a newly created role assignment to the manifest.
+// Recording is best effort: a failure only means unbootstrap falls back to matching the configured roles.
+func (ab *base) recordRoleAssignment(assignment createdRoleAssignment) {
+	assignments, _, err := ab.loadRoleManifest()
+	if err != nil {
+		ab.logger.Warnf("Failed to read role assignment manifest, %s on %s not recorded: %v", assignment.RoleName, assignment.Scope, err)
+		return
+	}
+	for _, existing := range assignments {
+		if strings.EqualFold(existing.Name, assignment.Name) && strings.EqualFold(existing.Scope, assignment.Scope) {
+			return
+		}
+	}
+	if err := ab.saveRoleManifest(append(assignments, assignment)); err != nil {
+		ab.logger.Warnf("Failed to record role assignment %s on %s: %v", assignment.RoleName, assignment.Scope, err)
+	}
+}
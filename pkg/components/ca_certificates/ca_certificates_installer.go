@@ -0,0 +1,150 @@
+package ca_certificates
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer adds network.caCertificates to the OS trust store, which containerd (registry pulls),
+// kubelet and its credential scripts read
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new CA certificates Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "CACertificatesInstaller"
+}
+
+// Validate checks that the configured certificates can be read and parsed
+func (i *Installer) Validate(ctx context.Context) error {
+	_, err := i.config.GetCACertificates()
+	return err
+}
+
+// Execute installs the configured certificates into the OS trust store and removes ones no longer configured
+func (i *Installer) Execute(ctx context.Context) error {
+	certificates, err := i.config.GetCACertificates()
+	if err != nil {
+		return err
+	}
+	store, err := detectTrustStore()
+	if err != nil {
+		if len(certificates) == 0 {
+			return nil
+		}
+		return err
+	}
+
+	if err := removeAnchors(store); err != nil {
+		return err
+	}
+	if err := utilio.MkdirAll(store.anchorDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", store.anchorDir, err)
+	}
+	for index, certificate := range certificates {
+		if err := utilio.WriteFile(anchorPath(store, index), certificate, 0o644); err != nil {
+			return fmt.Errorf("failed to install CA certificate: %w", err)
+		}
+	}
+	if err := updateTrustStore(store); err != nil {
+		return err
+	}
+	i.logger.Infof("Installed %d CA certificates into the OS trust store", len(certificates))
+
+	restartServices(i.logger)
+	return nil
+}
+
+// IsCompleted reports whether the OS trust store holds exactly the configured certificates
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	certificates, err := i.config.GetCACertificates()
+	if err != nil {
+		return false
+	}
+	store, err := detectTrustStore()
+	if err != nil {
+		return len(certificates) == 0
+	}
+
+	installed, err := filepath.Glob(filepath.Join(store.anchorDir, anchorFilePrefix+"*"+anchorFileSuffix))
+	if err != nil || len(installed) != len(certificates) {
+		return false
+	}
+	for index, certificate := range certificates {
+		content, err := os.ReadFile(anchorPath(store, index))
+		if err != nil || !bytes.Equal(content, certificate) {
+			return false
+		}
+	}
+	return true
+}
+
+// detectTrustStore returns the trust store layout of the running distribution
+func detectTrustStore() (trustStore, error) {
+	for _, store := range []trustStore{debianTrustStore, rhelTrustStore} {
+		if _, err := exec.LookPath(store.update); err == nil {
+			return store, nil
+		}
+	}
+	return trustStore{}, fmt.Errorf("neither update-ca-certificates nor update-ca-trust is available to install CA certificates")
+}
+
+// anchorPath returns the file of the index-th configured certificate
+func anchorPath(store trustStore, index int) string {
+	return filepath.Join(store.anchorDir, fmt.Sprintf("%s%d%s", anchorFilePrefix, index, anchorFileSuffix))
+}
+
+// removeAnchors removes every certificate file installed by the agent
+func removeAnchors(store trustStore) error {
+	installed, err := filepath.Glob(filepath.Join(store.anchorDir, anchorFilePrefix+"*"+anchorFileSuffix))
+	if err != nil {
+		return fmt.Errorf("failed to list installed CA certificates: %w", err)
+	}
+	for _, path := range installed {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove CA certificate %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// updateTrustStore rebuilds the OS certificate bundle from the anchor directory
+func updateTrustStore(store trustStore) error {
+	if output, err := utils.RunCommandWithOutput(store.update); err != nil {
+		return fmt.Errorf("failed to run %s: %w, output: %s", store.update, err, output)
+	}
+	return nil
+}
+
+// restartServices restarts running services so they load the new trust store
+func restartServices(logger *logrus.Logger) {
+	for _, service := range restartedServices {
+		if !utils.IsServiceActive(service) {
+			continue
+		}
+		logger.Infof("Restarting %s to load the updated trust store", service)
+		if err := utils.RestartService(service); err != nil {
+			logger.Warnf("Failed to restart %s: %v", service, err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package ca_certificates
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// testCACertificate returns a self-signed CA certificate in PEM form
+func testCACertificate(t *testing.T, name string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// fakeTrustStores points the anchor directories at temporary directories and PATH at a directory holding
+// stand-ins for the given update commands, which leave a <command>.ran file behind when run. It returns the
+// directory of the stand-ins.
+func fakeTrustStores(t *testing.T, commands ...string) string {
+	t.Helper()
+	origDebian, origRHEL := debianTrustStore, rhelTrustStore
+	t.Cleanup(func() { debianTrustStore, rhelTrustStore = origDebian, origRHEL })
+	root := t.TempDir()
+	debianTrustStore.anchorDir = filepath.Join(root, "usr", "local", "share", "ca-certificates")
+	rhelTrustStore.anchorDir = filepath.Join(root, "etc", "pki", "ca-trust", "source", "anchors")
+
+	bin := filepath.Join(root, "bin")
+	if err := os.MkdirAll(bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range commands {
+		if err := os.WriteFile(filepath.Join(bin, command), []byte("#!/bin/sh\n: > \"$0.ran\"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+	return bin
+}
+
+func newTestInstaller(certificates ...string) *Installer {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cfg := &config.Config{}
+	cfg.Network.CACertificates = certificates
+	return &Installer{config: cfg, logger: logger}
+}
+
+func TestDetectTrustStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string
+		want     string
+		wantErr  bool
+	}{
+		{name: "Debian", commands: []string{"update-ca-certificates"}, want: "update-ca-certificates"},
+		{name: "RHEL", commands: []string{"update-ca-trust"}, want: "update-ca-trust"},
+		{name: "both prefer Debian", commands: []string{"update-ca-trust", "update-ca-certificates"}, want: "update-ca-certificates"},
+		{name: "neither", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeTrustStores(t, tt.commands...)
+			store, err := detectTrustStore()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectTrustStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if store.update != tt.want {
+				t.Errorf("detectTrustStore() = %+v, want %s", store, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallerUsesDistributionTrustStore(t *testing.T) {
+	certificate := testCACertificate(t, "proxy")
+	tests := []struct {
+		name    string
+		command string
+		store   func() trustStore
+	}{
+		{name: "Debian", command: "update-ca-certificates", store: func() trustStore { return debianTrustStore }},
+		{name: "RHEL", command: "update-ca-trust", store: func() trustStore { return rhelTrustStore }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := fakeTrustStores(t, tt.command)
+			installer := newTestInstaller(certificate)
+			if err := installer.Execute(context.Background()); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			content, err := os.ReadFile(anchorPath(tt.store(), 0))
+			if err != nil || string(content) != certificate {
+				t.Errorf("anchor = %q, %v, want the configured certificate", content, err)
+			}
+			if _, err := os.Stat(filepath.Join(bin, tt.command+".ran")); err != nil {
+				t.Errorf("%s did not run: %v", tt.command, err)
+			}
+			if !installer.IsCompleted(context.Background()) {
+				t.Error("IsCompleted() = false after Execute()")
+			}
+		})
+	}
+}
+
+func TestInstallerIsCompletedDetectsDrift(t *testing.T) {
+	first, second := testCACertificate(t, "first"), testCACertificate(t, "second")
+	fakeTrustStores(t, "update-ca-trust")
+	installer := newTestInstaller(first, second)
+	if err := installer.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// Certificates the agent did not install are left alone
+	unrelated := filepath.Join(rhelTrustStore.anchorDir, "corporate.crt")
+	if err := os.WriteFile(unrelated, []byte(first), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !installer.IsCompleted(context.Background()) {
+		t.Fatal("IsCompleted() = false with the configured certificates installed")
+	}
+
+	if err := os.WriteFile(anchorPath(rhelTrustStore, 1), []byte(first), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if installer.IsCompleted(context.Background()) {
+		t.Error("IsCompleted() = true with a modified certificate")
+	}
+
+	if err := installer.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := os.WriteFile(anchorPath(rhelTrustStore, 2), []byte(second), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if installer.IsCompleted(context.Background()) {
+		t.Error("IsCompleted() = true with a certificate that is no longer configured")
+	}
+
+	installer.config.Network.CACertificates = []string{second}
+	if err := installer.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	installed, _ := filepath.Glob(filepath.Join(rhelTrustStore.anchorDir, anchorFilePrefix+"*"))
+	if len(installed) != 1 || !installer.IsCompleted(context.Background()) {
+		t.Errorf("installed = %v, want only the remaining configured certificate", installed)
+	}
+
+	uninstaller := NewUnInstaller(installer.logger)
+	if err := uninstaller.Execute(context.Background()); err != nil {
+		t.Fatalf("UnInstaller.Execute() error = %v", err)
+	}
+	if !uninstaller.IsCompleted(context.Background()) {
+		t.Error("UnInstaller.IsCompleted() = false after Execute()")
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("unbootstrap removed a certificate the agent did not install: %v", err)
+	}
+}
+
+func TestInstallerWithoutTrustStore(t *testing.T) {
+	fakeTrustStores(t)
+	if installer := newTestInstaller(); !installer.IsCompleted(context.Background()) || installer.Execute(context.Background()) != nil {
+		t.Error("a node without a trust store and without certificates is not left alone")
+	}
+
+	installer := newTestInstaller(testCACertificate(t, "proxy"))
+	if installer.IsCompleted(context.Background()) {
+		t.Error("IsCompleted() = true with certificates configured and no trust store")
+	}
+	if err := installer.Execute(context.Background()); err == nil {
+		t.Error("Execute() succeeded with certificates configured and no trust store")
+	}
+}
@@ -0,0 +1,52 @@
+package ca_certificates
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// UnInstaller removes the CA certificates installed by the agent from the OS trust store
+type UnInstaller struct {
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new CA certificates UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "CACertificatesUnInstaller"
+}
+
+// Execute removes the installed certificates and rebuilds the OS certificate bundle
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	store, err := detectTrustStore()
+	if err != nil {
+		u.logger.Debugf("No trust store to clean up: %v", err)
+		return nil
+	}
+	if err := removeAnchors(store); err != nil {
+		return err
+	}
+	if err := updateTrustStore(store); err != nil {
+		return err
+	}
+	u.logger.Info("Removed agent CA certificates from the OS trust store")
+	return nil
+}
+
+// IsCompleted reports whether no agent CA certificates are left in the trust store
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	store, err := detectTrustStore()
+	if err != nil {
+		return true
+	}
+	installed, err := filepath.Glob(filepath.Join(store.anchorDir, anchorFilePrefix+"*"+anchorFileSuffix))
+	return err == nil && len(installed) == 0
+}
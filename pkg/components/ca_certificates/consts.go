@@ -0,0 +1,23 @@
+package ca_certificates
+
+// trustStore describes where a distribution keeps extra trusted CAs and how it rebuilds its bundle
+type trustStore struct {
+	anchorDir string
+	update    string
+}
+
+var (
+	// Debian and Ubuntu
+	debianTrustStore = trustStore{anchorDir: "/usr/local/share/ca-certificates", update: "update-ca-certificates"}
+	// RHEL, Rocky, Alma and Fedora
+	rhelTrustStore = trustStore{anchorDir: "/etc/pki/ca-trust/source/anchors", update: "update-ca-trust"}
+)
+
+const (
+	// anchorFilePrefix names the certificate files installed by the agent, e.g. aks-flex-node-0.crt
+	anchorFilePrefix = "aks-flex-node-"
+	anchorFileSuffix = ".crt"
+)
+
+// restartedServices read the trust store only at startup, so they are restarted when it changes
+var restartedServices = []string{"containerd", "crio", "kubelet"}
@@ -0,0 +1,100 @@
+package cni
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// prepareCalico readies the node for calico-node instead of writing the bridge configuration. calico-node
+// owns 10-calico.conflist and its kubeconfig in the CNI configuration directory and rewrites them when it
+// starts, so bootstrap leaves that directory alone apart from removing the bridge.
+func (i *Installer) prepareCalico(ctx context.Context) error {
+	if err := utils.RunCleanupCommand(filepath.Join(DefaultCNIConfDir, bridgeConfigFile)); err != nil {
+		return fmt.Errorf("failed to remove the bridge configuration: %w", err)
+	}
+	if err := i.loadCalicoModules(); err != nil {
+		return err
+	}
+	if err := i.configureNetworkManagerForCalico(); err != nil {
+		return err
+	}
+	return InstallPluginsFromImage(ctx, i.config, i.logger, i.config.GetImage(config.ImageCalicoCNI), DefaultCNIBinDir, calicoPlugins, calicoImageFile)
+}
+
+// loadCalicoModules loads the kernel modules Felix programs the dataplane with, now and on every boot
+func (i *Installer) loadCalicoModules() error {
+	modules := i.calicoModules()
+	for _, module := range modules {
+		if err := utilhost.LoadKernelModule(module); err != nil {
+			if module == wireGuardModule {
+				return fmt.Errorf("failed to load the wireguard kernel module required by cni.calico.wireGuard: %w", err)
+			}
+			return fmt.Errorf("failed to load kernel module %s required by Calico: %w", module, err)
+		}
+	}
+	if err := utilio.WriteFile(calicoModulesPath, []byte(strings.Join(modules, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", calicoModulesPath, err)
+	}
+	return nil
+}
+
+// calicoModules returns the kernel modules to load, with wireguard when Calico encrypts traffic
+func (i *Installer) calicoModules() []string {
+	modules := append([]string{}, calicoKernelModules...)
+	if i.config.CNI.Calico.WireGuard {
+		modules = append(modules, wireGuardModule)
+	}
+	return modules
+}
+
+// configureNetworkManagerForCalico keeps NetworkManager away from the interfaces Calico creates. Otherwise it
+// takes over cali* veths and tunnel devices and removes the routes Felix adds to them.
+func (i *Installer) configureNetworkManagerForCalico() error {
+	if !utils.DirectoryExists(networkManagerConfDir) {
+		return nil
+	}
+	if err := utilio.WriteFile(calicoNetworkManagerConfPath, []byte(calicoNetworkManagerConf), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", calicoNetworkManagerConfPath, err)
+	}
+	if utils.IsServiceActive(networkManagerService) {
+		if err := utils.RunSystemCommand("systemctl", "reload", networkManagerService); err != nil {
+			i.logger.Warnf("Failed to reload NetworkManager: %v", err)
+		}
+	}
+	return nil
+}
+
+// isCalicoReady reports whether the node is prepared for calico-node with the pinned image
+func (i *Installer) isCalicoReady() bool {
+	if utils.FileExists(filepath.Join(DefaultCNIConfDir, bridgeConfigFile)) {
+		i.logger.Debug("Bridge configuration is still present with cni.provider calico")
+		return false
+	}
+	if !utils.FileHasContent(calicoModulesPath, strings.Join(i.calicoModules(), "\n")+"\n") {
+		i.logger.Debug("Calico kernel modules are not configured")
+		return false
+	}
+	if utils.DirectoryExists(networkManagerConfDir) && !utils.FileHasContent(calicoNetworkManagerConfPath, calicoNetworkManagerConf) {
+		i.logger.Debug("NetworkManager is not configured to ignore Calico interfaces")
+		return false
+	}
+	// calico-node's conflist chains the portmap and bandwidth plugins from the standard plugin archive
+	for _, plugin := range calicoChainedPlugins {
+		if !utils.FileExistsAndValid(filepath.Join(DefaultCNIBinDir, plugin)) {
+			i.logger.Debugf("CNI plugin not found: %s", plugin)
+			return false
+		}
+	}
+	if image := i.config.GetImage(config.ImageCalicoCNI); !PluginsInstalledFrom(image, calicoPlugins, calicoImageFile) {
+		i.logger.Debugf("Calico CNI plugins from %s are not installed", image)
+		return false
+	}
+	return true
+}
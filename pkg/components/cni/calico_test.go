@@ -0,0 +1,25 @@
+package cni
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestCalicoModules(t *testing.T) {
+	installer := &Installer{config: &config.Config{}, logger: logrus.New()}
+	if modules := installer.calicoModules(); slices.Contains(modules, wireGuardModule) || !slices.Contains(modules, "ip_set") {
+		t.Errorf("calicoModules() = %v, want ip_set without wireguard", modules)
+	}
+
+	installer.config.CNI.Calico.WireGuard = true
+	if modules := installer.calicoModules(); !slices.Contains(modules, wireGuardModule) {
+		t.Errorf("calicoModules() = %v, want wireguard with cni.calico.wireGuard", modules)
+	}
+	if slices.Contains(calicoKernelModules, wireGuardModule) {
+		t.Error("calicoModules() modified calicoKernelModules")
+	}
+}
@@ -0,0 +1,105 @@
+package cni
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// validateCiliumKernel checks that the running kernel is recent enough for the Cilium release and built with
+// the eBPF options the agent needs. A kernel configuration that cannot be read is only logged, since some
+// distributions ship neither /boot/config-<release> nor /proc/config.gz.
+func (i *Installer) validateCiliumKernel() error {
+	release, err := utilhost.KernelRelease()
+	if err != nil {
+		return err
+	}
+	major, minor := ciliumMinKernel(i.config.CNI.Cilium.Version)
+	ok, err := utilhost.KernelVersionAtLeast(release, major, minor)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("kernel %s is too old for Cilium %s, which needs %d.%d or newer", release, i.config.CNI.Cilium.Version, major, minor)
+	}
+
+	options, err := utilhost.KernelConfig(release)
+	if err != nil {
+		i.logger.Warnf("Skipping the Cilium kernel option check: %v", err)
+		return nil
+	}
+	if missing := utilhost.MissingKernelOptions(options, ciliumKernelOptions); len(missing) > 0 {
+		return fmt.Errorf("kernel %s is built without options Cilium needs: %s", release, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ciliumMinKernel returns the oldest kernel a Cilium release supports: 5.10 from Cilium 1.18, 5.4 before
+func ciliumMinKernel(version string) (int, int) {
+	var major, minor int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(version, "v"), "%d.%d", &major, &minor); err == nil &&
+		(major > 1 || (major == 1 && minor >= 18)) {
+		return 5, 10
+	}
+	return 5, 4
+}
+
+// prepareCilium readies the node for the Cilium agent instead of writing the bridge configuration. The agent
+// writes its own configuration to the CNI configuration directory once it runs, and the node reports NotReady
+// until then, so pods are never started on the bridge and stranded when Cilium takes over.
+func (i *Installer) prepareCilium(ctx context.Context) error {
+	if err := utils.RunCleanupCommand(filepath.Join(DefaultCNIConfDir, bridgeConfigFile)); err != nil {
+		return fmt.Errorf("failed to remove the bridge configuration: %w", err)
+	}
+	if err := i.mountBPFFilesystem(); err != nil {
+		return err
+	}
+	return i.installCiliumCNI(ctx)
+}
+
+// mountBPFFilesystem mounts the BPF filesystem now and on every boot, so eBPF maps pinned by the agent survive
+// agent restarts
+func (i *Installer) mountBPFFilesystem() error {
+	if err := utilio.WriteFile(bpfMountUnitPath, []byte(bpfMountUnit), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", bpfMountUnitPath, err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := utils.EnableAndStartService(bpfMountUnitName); err != nil {
+		return fmt.Errorf("failed to mount the BPF filesystem at %s: %w", bpfMountPoint, err)
+	}
+	i.logger.Infof("BPF filesystem mounted at %s", bpfMountPoint)
+	return nil
+}
+
+// installCiliumCNI installs the cilium-cni plugin from the pinned agent image. The agent installs the plugin
+// itself when it starts, but a plugin of the same release must already be in place for pods the container
+// runtime recreates before the agent is up after a node restart. Pulling the image here also spares the agent
+// the pull on first start.
+func (i *Installer) installCiliumCNI(ctx context.Context) error {
+	return InstallPluginsFromImage(ctx, i.config, i.logger, i.config.GetImage(config.ImageCilium), DefaultCNIBinDir, []string{ciliumPlugin}, ciliumImageFile)
+}
+
+// isCiliumReady reports whether the node is prepared for the Cilium agent with the pinned image
+func (i *Installer) isCiliumReady() bool {
+	if utils.FileExists(filepath.Join(DefaultCNIConfDir, bridgeConfigFile)) {
+		i.logger.Debug("Bridge configuration is still present with cni.provider cilium")
+		return false
+	}
+	if !utils.IsServiceActive(bpfMountUnitName) {
+		i.logger.Debugf("BPF filesystem is not mounted at %s", bpfMountPoint)
+		return false
+	}
+	if image := i.config.GetImage(config.ImageCilium); !PluginsInstalledFrom(image, []string{ciliumPlugin}, ciliumImageFile) {
+		i.logger.Debugf("cilium-cni from %s is not installed", image)
+		return false
+	}
+	return true
+}
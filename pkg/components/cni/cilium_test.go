@@ -0,0 +1,25 @@
+package cni
+
+import "testing"
+
+func TestCiliumMinKernel(t *testing.T) {
+	tests := []struct {
+		version   string
+		wantMajor int
+		wantMinor int
+	}{
+		{version: "1.16.6", wantMajor: 5, wantMinor: 4},
+		{version: "v1.17.2", wantMajor: 5, wantMinor: 4},
+		{version: "1.18.0", wantMajor: 5, wantMinor: 10},
+		{version: "2.0.0", wantMajor: 5, wantMinor: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			major, minor := ciliumMinKernel(tt.version)
+			if major != tt.wantMajor || minor != tt.wantMinor {
+				t.Errorf("ciliumMinKernel(%q) = %d.%d, want %d.%d", tt.version, major, minor, tt.wantMajor, tt.wantMinor)
+			}
+		})
+	}
+}
@@ -2,12 +2,18 @@ package cni
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
@@ -39,6 +45,33 @@ func (i *Installer) Validate(ctx context.Context) error {
 	if cniVersion == "" {
 		return fmt.Errorf("CNI version cannot be empty")
 	}
+	if i.config.CNI.Provider == config.CNIProviderCilium {
+		if i.config.CNI.Multus.Enabled {
+			i.logger.Warn("Cilium moves other CNI configurations aside unless its cni.exclusive setting is false; turn it off for Multus")
+		}
+		return i.validateCiliumKernel()
+	}
+	if i.config.CNI.ConfigTemplateFile != "" {
+		// Catch syntax errors and unknown variables before anything is changed
+		text, err := os.ReadFile(i.config.CNI.ConfigTemplateFile)
+		if err != nil {
+			return fmt.Errorf("failed to read cni.configTemplateFile: %w", err)
+		}
+		sample := &conflistTemplateData{
+			CNIVersion: defaultCNISpecVersion,
+			NodeName:   "node",
+			NodeIP:     "192.0.2.10",
+			NodeIPs:    []string{"192.0.2.10"},
+			PodCIDR:    bridgeIPv4Subnet.subnet,
+			PodCIDRs:   []string{bridgeIPv4Subnet.subnet},
+			Gateway:    bridgeIPv4Subnet.gateway,
+			Gateways:   []string{bridgeIPv4Subnet.gateway},
+			MTU:        1500,
+		}
+		if _, err := renderConflist(string(text), sample); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -57,19 +90,73 @@ func (i *Installer) Execute(ctx context.Context) error {
 	i.logger.Info("Step 2: Installing CNI plugins")
 	if err := i.installCNIPlugins(ctx); err != nil {
 		i.logger.Errorf("CNI plugins installation failed: %v", err)
-		return fmt.Errorf("failed to install CNI plugins version %s: %w", defaultCNIVersion, err)
+		return fmt.Errorf("failed to install CNI plugins version %s: %w", getCNIVersion(i.config), err)
 	}
 	i.logger.Info("CNI plugins installed successfully")
 
+	// Set up the pod network of cni.provider
+	if err := i.setupPodNetwork(ctx); err != nil {
+		return err
+	}
+
+	// Put Multus in front of the pod network, or take it out
+	if i.config.CNI.Multus.Enabled {
+		i.logger.Info("Step 4: Installing Multus")
+		if err := i.installMultus(ctx); err != nil {
+			return fmt.Errorf("failed to install Multus: %w", err)
+		}
+	} else {
+		i.removeMultus()
+	}
+
+	i.logger.Info("CNI setup completed successfully")
+	return nil
+}
+
+// setupPodNetwork writes the bridge configuration, or prepares the node for the CNI of cni.provider
+func (i *Installer) setupPodNetwork(ctx context.Context) error {
+	switch i.config.CNI.Provider {
+	case config.CNIProviderCilium:
+		i.logger.Info("Step 3: Preparing the node for the Cilium agent")
+		if err := i.prepareCilium(ctx); err != nil {
+			return fmt.Errorf("failed to prepare the node for Cilium: %w", err)
+		}
+		i.logger.Info("The node becomes Ready once the Cilium agent runs on it")
+		return nil
+	case config.CNIProviderCalico:
+		i.logger.Info("Step 3: Preparing the node for calico-node")
+		if err := i.prepareCalico(ctx); err != nil {
+			return fmt.Errorf("failed to prepare the node for Calico: %w", err)
+		}
+		i.logger.Info("The node becomes Ready once calico-node runs on it")
+		return nil
+	case config.CNIProviderFlannel:
+		i.logger.Info("Step 3: Configuring flannel")
+		if err := i.prepareFlannel(ctx); err != nil {
+			return fmt.Errorf("failed to configure flannel: %w", err)
+		}
+		return nil
+	}
+
+	if i.config.CNI.ConfigTemplateFile != "" {
+		i.logger.Info("Step 3: Rendering the CNI configuration template")
+		if err := i.createTemplatedConfig(ctx); err != nil {
+			return fmt.Errorf("failed to create the CNI configuration: %w", err)
+		}
+		return nil
+	}
+
 	// Create bridge configuration for edge node
 	i.logger.Info("Step 3: Creating bridge configuration")
-	if err := i.createBridgeConfig(); err != nil {
+	subnets, err := i.bridgeSubnets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to determine the pod ranges: %w", err)
+	}
+	if err := i.createBridgeConfig(subnets, i.podMTU(ctx)); err != nil {
 		i.logger.Errorf("Bridge configuration creation failed: %v", err)
 		return fmt.Errorf("failed to create bridge config: %w", err)
 	}
 	i.logger.Info("Bridge configuration created successfully")
-
-	i.logger.Info("CNI setup completed successfully")
 	return nil
 }
 
@@ -91,29 +178,83 @@ func (i *Installer) IsCompleted(ctx context.Context) bool {
 			return false
 		}
 	}
+	if installedPluginsVersion() != getCNIVersion(i.config) {
+		i.logger.Debugf("CNI plugins %s are not installed", getCNIVersion(i.config))
+		return false
+	}
+
+	// Validate Step 3: provider prerequisites or bridge configuration
+	if !i.isPodNetworkReady(ctx) {
+		return false
+	}
+
+	// Validate Step 4: Multus
+	if !i.isMultusReady() {
+		return false
+	}
+
+	i.logger.Debug("CNI setup validation passed - all components properly configured")
+	return true
+}
 
-	// Validate Step 3: Bridge configuration
+// isPodNetworkReady reports whether the bridge configuration, or the prerequisites of cni.provider, are in place
+func (i *Installer) isPodNetworkReady(ctx context.Context) bool {
+	switch i.config.CNI.Provider {
+	case config.CNIProviderCilium:
+		return i.isCiliumReady()
+	case config.CNIProviderCalico:
+		return i.isCalicoReady()
+	case config.CNIProviderFlannel:
+		return i.isFlannelReady(ctx)
+	}
+	if i.config.CNI.ConfigTemplateFile != "" {
+		return i.isTemplatedConfigReady(ctx)
+	}
 	configPath := filepath.Join(DefaultCNIConfDir, bridgeConfigFile)
 	if !utils.FileExistsAndValid(configPath) {
 		i.logger.Debug("Bridge configuration file not found")
 		return false
 	}
-
-	i.logger.Debug("CNI setup validation passed - all components properly configured")
+	if mtu := i.config.Network.MTU; mtu > 0 && bridgeConfigMTU(configPath) != mtu {
+		i.logger.Debugf("Bridge configuration MTU differs from network.mtu %d", mtu)
+		return false
+	}
+	subnets, err := i.bridgeSubnets(ctx)
+	if err != nil {
+		i.logger.Debugf("Failed to determine the pod ranges: %v", err)
+		return false
+	}
+	var want []string
+	for _, subnet := range subnets {
+		want = append(want, subnet.subnet)
+	}
+	if got := bridgeConfigSubnets(configPath); !slices.Equal(got, want) {
+		i.logger.Debugf("Bridge configuration pod ranges %v differ from %v", got, want)
+		return false
+	}
 	return true
 }
 
+// managesCNIConfig reports whether bootstrap writes the CNI configuration, rather than the agent of cni.provider
+func (i *Installer) managesCNIConfig() bool {
+	switch i.config.CNI.Provider {
+	case "", config.CNIProviderBridge, config.CNIProviderFlannel:
+		return true
+	}
+	return false
+}
+
 func (i *Installer) prepareCNIDirectories() error {
 	for _, dir := range cniDirs {
 		if !utils.DirectoryExists(dir) {
 			// Create directory if it doesn't exist
-			if err := utils.RunSystemCommand("mkdir", "-p", dir); err != nil {
+			if err := utilio.MkdirAll(dir, 0o755); err != nil {
 				return fmt.Errorf("failed to create CNI directory %s: %w", dir, err)
 			}
 		}
 
-		// Only clean configuration, not binaries
-		if dir == DefaultCNIConfDir {
+		// Only clean configuration, not binaries. Cilium and Calico agents own the configuration they write.
+		if dir == DefaultCNIConfDir && i.managesCNIConfig() {
 			i.logger.Debugf("Cleaning existing CNI configurations in: %s", dir)
 			if err := utils.RunSystemCommand("rm", "-rf", dir+"/*"); err != nil {
 				return fmt.Errorf("failed to clean CNI configuration directory: %w", err)
@@ -134,8 +275,9 @@ func (i *Installer) prepareCNIDirectories() error {
 
 // installCNIPlugins downloads and installs CNI plugins (matching reference script)
 func (i *Installer) installCNIPlugins(ctx context.Context) error {
-	if canSkipCNIPluginInstallation() {
-		logrus.Info("CNI plugins are already installed and valid, skipping installation")
+	cniVersion := getCNIVersion(i.config)
+	if canSkipCNIPluginInstallation(cniVersion) {
+		logrus.Infof("CNI plugins %s are already installed and valid, skipping installation", cniVersion)
 		return nil
 	}
 
@@ -157,11 +299,19 @@ func (i *Installer) installCNIPlugins(ctx context.Context) error {
 		}
 	}
 
+	if err := utilio.WriteFile(pluginsVersionFile, []byte(cniVersion+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to record the CNI plugins version: %w", err)
+	}
+
 	logrus.Info("CNI plugins installed successfully")
 	return nil
 }
 
-func canSkipCNIPluginInstallation() bool {
+// canSkipCNIPluginInstallation reports whether the plugins of release version are installed
+func canSkipCNIPluginInstallation(version string) bool {
+	if installedPluginsVersion() != version {
+		return false
+	}
 	for _, plugin := range requiredCNIPlugins {
 		pluginPath := filepath.Join(DefaultCNIBinDir, plugin)
 		if !utils.FileExistsAndValid(pluginPath) {
@@ -182,21 +332,31 @@ func (i *Installer) constructCNIDownloadURL() (string, string) {
 
 func getCNIVersion(cfg *config.Config) string {
 	if cfg.CNI.Version != "" {
-		return cfg.CNI.Version
+		return strings.TrimPrefix(cfg.CNI.Version, "v")
 	}
 	return defaultCNIVersion
 }
 
+// installedPluginsVersion returns the release of the installed standard plugins, recorded when they were installed
+func installedPluginsVersion() string {
+	data, err := os.ReadFile(pluginsVersionFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // CreateBridgeConfig creates bridge CNI configuration for edge nodes (compatible with BYO Cilium)
-// Uses 99-bridge.conf filename to ensure CNI solutions like Cilium can override with higher priority configs
-func (i *Installer) createBridgeConfig() error {
+// Uses 99-bridge.conf filename to ensure CNI solutions like Cilium can override with higher priority configs.
+// A non-zero mtu is set on the bridge and pod interfaces, which containerd creates from this configuration.
+func (i *Installer) createBridgeConfig(subnets []bridgeSubnet, mtu int) error {
 	configPath := filepath.Join(DefaultCNIConfDir, bridgeConfigFile)
 
 	// Load br_netfilter kernel module which is required for bridge networking
 	// This enables these sysctl settings:
 	// - net.bridge.bridge-nf-call-iptables = 1
 	// - net.bridge.bridge-nf-call-ip6tables = 1
-	if err := utils.RunSystemCommand("modprobe", "br_netfilter"); err != nil {
+	if err := utilhost.LoadKernelModule("br_netfilter"); err != nil {
 		logrus.Warnf("Failed to load br_netfilter module: %v", err)
 	}
 
@@ -205,35 +365,144 @@ func (i *Installer) createBridgeConfig() error {
 		logrus.Warnf("Failed to remove existing config file: %v", err)
 	}
 
-	bridgeConfig := fmt.Sprintf(`{
+	if err := utilio.WriteFile(configPath, []byte(bridgeConfig(subnets, mtu)), 0644); err != nil {
+		return err
+	}
+
+	logrus.Info("Bridge CNI configuration created")
+	return nil
+}
+
+// bridgeConfig renders the bridge configuration assigning pod addresses from subnets
+func bridgeConfig(subnets []bridgeSubnet, mtu int) string {
+	var ranges, routes []string
+	for _, family := range subnets {
+		ranges = append(ranges, fmt.Sprintf(`
+            [
+                {
+                    "subnet": "%s",
+                    "gateway": "%s"
+                }
+            ]`, family.subnet, family.gateway))
+		routes = append(routes, fmt.Sprintf(`
+            {
+                "dst": "%s"
+            }`, family.defaultRoute))
+	}
+
+	return fmt.Sprintf(`{
     "cniVersion": "%s",
     "name": "bridge",
     "type": "bridge",
     "bridge": "cni0",
     "isGateway": true,
-    "ipMasq": true,
+    "ipMasq": true,%s
     "ipam": {
         "type": "host-local",
-        "ranges": [
-            [
-                {
-                    "subnet": "10.244.0.0/16",
-                    "gateway": "10.244.0.1"
-                }
-            ]
+        "ranges": [%s
         ],
-        "routes": [
-            {
-                "dst": "0.0.0.0/0"
-            }
+        "routes": [%s
         ]
     }
-}`, defaultCNISpecVersion)
+}`, defaultCNISpecVersion, mtuSetting(mtu), strings.Join(ranges, ","), strings.Join(routes, ","))
+}
 
-	if err := utilio.WriteFile(configPath, []byte(bridgeConfig), 0644); err != nil {
-		return err
+// bridgeSubnets returns the pod address ranges of the node. Dual-stack nodes get an address of each family,
+// primary first, since the first one is the pod IP.
+func (i *Installer) bridgeSubnets(ctx context.Context) ([]bridgeSubnet, error) {
+	if !i.config.IsDualStack() {
+		return []bridgeSubnet{bridgeIPv4Subnet}, nil
+	}
+	cidr, err := i.podIPv6CIDR(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ipv6Subnet, err := newIPv6BridgeSubnet(cidr)
+	if err != nil {
+		return nil, err
 	}
+	if i.config.IsIPv6Primary() {
+		return []bridgeSubnet{ipv6Subnet, bridgeIPv4Subnet}, nil
+	}
+	return []bridgeSubnet{bridgeIPv4Subnet, ipv6Subnet}, nil
+}
 
-	logrus.Info("Bridge CNI configuration created")
-	return nil
+// podIPv6CIDR returns network.podIPv6CIDR, or the IPv6 pod CIDR of the target cluster, so pods get IPv6
+// addresses the cluster routes
+func (i *Installer) podIPv6CIDR(ctx context.Context) (string, error) {
+	if cidr := i.config.Network.PodIPv6CIDR; cidr != "" {
+		return cidr, nil
+	}
+	if i.config.Azure.TargetCluster == nil {
+		return "", fmt.Errorf("network.podIPv6CIDR is not set and no target cluster is configured")
+	}
+	clusterSpec, err := spec.NewManagedClusterSpecCollector(i.config, i.logger).Collect(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, cidr := range clusterSpec.PodCIDRs {
+		if ip, _, err := net.ParseCIDR(cidr); err == nil && ip.To4() == nil {
+			return cidr, nil
+		}
+	}
+	return "", fmt.Errorf("the cluster reports no IPv6 pod CIDR; set network.podIPv6CIDR")
+}
+
+// newIPv6BridgeSubnet returns the bridge range of an IPv6 pod CIDR, with the first address as the gateway
+func newIPv6BridgeSubnet(cidr string) (bridgeSubnet, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil || network.IP.To4() != nil {
+		return bridgeSubnet{}, fmt.Errorf("invalid IPv6 pod CIDR %q", cidr)
+	}
+	gateway := slices.Clone(network.IP)
+	gateway[len(gateway)-1]++
+	return bridgeSubnet{subnet: network.String(), gateway: gateway.String(), defaultRoute: "::/0"}, nil
+}
+
+// mtuSetting returns the bridge configuration line setting mtu, or nothing to keep the plugin default
+func mtuSetting(mtu int) string {
+	if mtu <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n    \"mtu\": %d,", mtu)
+}
+
+// bridgeConfigMTU returns the MTU set in a bridge configuration file, or 0 when it sets none
+func bridgeConfigMTU(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	var conf struct {
+		MTU int `json:"mtu"`
+	}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return 0
+	}
+	return conf.MTU
+}
+
+// bridgeConfigSubnets returns the pod ranges a bridge configuration file assigns addresses from, in order
+func bridgeConfigSubnets(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var conf struct {
+		IPAM struct {
+			Ranges [][]struct {
+				Subnet string `json:"subnet"`
+			} `json:"ranges"`
+		} `json:"ipam"`
+	}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return nil
+	}
+	var subnets []string
+	for _, set := range conf.IPAM.Ranges {
+		for _, r := range set {
+			subnets = append(subnets, r.Subnet)
+		}
+	}
+	return subnets
 }
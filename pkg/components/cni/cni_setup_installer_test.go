@@ -0,0 +1,81 @@
+package cni
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestBridgeSubnets(t *testing.T) {
+	tests := []struct {
+		name    string
+		network config.NetworkConfig
+		want    []string
+		wantGW  []string
+		wantErr bool
+	}{
+		{name: "single-stack", want: []string{"10.244.0.0/16"}, wantGW: []string{"10.244.0.1"}},
+		{
+			name:    "dual-stack",
+			network: config.NetworkConfig{IPFamilies: []string{"IPv4", "IPv6"}, PodIPv6CIDR: "fd12:3456:789a::/64"},
+			want:    []string{"10.244.0.0/16", "fd12:3456:789a::/64"},
+			wantGW:  []string{"10.244.0.1", "fd12:3456:789a::1"},
+		},
+		{
+			name:    "IPv6 primary with host bits",
+			network: config.NetworkConfig{IPFamilies: []string{"IPv6", "IPv4"}, PodIPv6CIDR: "fd12:3456:789a::5/64"},
+			want:    []string{"fd12:3456:789a::/64", "10.244.0.0/16"},
+			wantGW:  []string{"fd12:3456:789a::1", "10.244.0.1"},
+		},
+		{name: "no IPv6 range or cluster", network: config.NetworkConfig{IPFamilies: []string{"IPv4", "IPv6"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &Installer{config: &config.Config{Network: tt.network}, logger: logrus.New()}
+			subnets, err := i.bridgeSubnets(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bridgeSubnets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got, gotGW []string
+			for _, subnet := range subnets {
+				got = append(got, subnet.subnet)
+				gotGW = append(gotGW, subnet.gateway)
+			}
+			if !slices.Equal(got, tt.want) || !slices.Equal(gotGW, tt.wantGW) {
+				t.Errorf("bridgeSubnets() = %v via %v, want %v via %v", got, gotGW, tt.want, tt.wantGW)
+			}
+		})
+	}
+}
+
+func TestBridgeConfigSubnets(t *testing.T) {
+	ipv6, err := newIPv6BridgeSubnet("fd12:3456:789a::/64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), bridgeConfigFile)
+	if err := os.WriteFile(path, []byte(bridgeConfig([]bridgeSubnet{bridgeIPv4Subnet, ipv6}, 1400)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bridgeConfigSubnets(path), []string{"10.244.0.0/16", "fd12:3456:789a::/64"}; !slices.Equal(got, want) {
+		t.Errorf("bridgeConfigSubnets() = %v, want %v", got, want)
+	}
+	if got := bridgeConfigMTU(path); got != 1400 {
+		t.Errorf("bridgeConfigMTU() = %d, want 1400", got)
+	}
+
+	// A subnet mentioned outside the IPAM ranges does not count
+	if err := os.WriteFile(path, []byte(`{"type": "bridge", "comment": "\"subnet\": \"fd12:3456:789a::/64\""}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := bridgeConfigSubnets(path); len(got) != 0 {
+		t.Errorf("bridgeConfigSubnets() = %v, want none", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/sirupsen/logrus"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/installed_files"
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils"
 )
@@ -27,27 +28,82 @@ func (u *UnInstaller) Execute(ctx context.Context) error {
 	u.logger.Info("Cleaning up CNI configuration")
 
 	// Remove CNI configuration directories
-	if dirErrors := utils.RemoveDirectories(cniDirs, u.logger); len(dirErrors) > 0 {
+	if dirErrors := utils.RemoveDirectories(installed_files.CreatedPaths(cniDirs...), u.logger); len(dirErrors) > 0 {
 		for _, err := range dirErrors {
 			u.logger.Warnf("Directory removal error: %v", err)
 		}
 	}
 
+	if utils.FileExists(bpfMountUnitPath) {
+		u.removeBPFMount()
+	}
+	for _, err := range utils.RemoveFiles([]string{calicoModulesPath, calicoNetworkManagerConfPath}, u.logger) {
+		u.logger.Warnf("Failed to remove Calico file: %v", err)
+	}
+	if utils.FileExists(flanneldServicePath) || utils.FileExists(flanneldBinaryPath) {
+		u.removeFlannel()
+	}
+
 	u.logger.Info("CNI configuration cleanup completed")
 	return nil
 }
 
 // IsCompleted checks if CNI configuration directories have been removed
 func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
-	for _, dir := range cniDirs {
+	for _, dir := range installed_files.CreatedPaths(cniDirs...) {
 		if utils.DirectoryExists(dir) {
 			return false
 		}
 	}
 
+	for _, path := range []string{bpfMountUnitPath, calicoModulesPath, calicoNetworkManagerConfPath, flanneldServicePath, flanneldBinaryPath} {
+		if utils.FileExists(path) {
+			return false
+		}
+	}
 	return true
 }
 
+// removeBPFMount unmounts the BPF filesystem mounted for Cilium and removes its unit. Failures are logged, so
+// cleanup continues.
+func (u *UnInstaller) removeBPFMount() {
+	if err := utils.StopService(bpfMountUnitName); err != nil {
+		u.logger.Debugf("Failed to unmount the BPF filesystem: %v", err)
+	}
+	if err := utils.DisableService(bpfMountUnitName); err != nil {
+		u.logger.Debugf("Failed to disable %s: %v", bpfMountUnitName, err)
+	}
+	if err := utils.RunCleanupCommand(bpfMountUnitPath); err != nil {
+		u.logger.Warnf("Failed to remove %s: %v", bpfMountUnitPath, err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		u.logger.Warnf("Failed to reload systemd: %v", err)
+	}
+}
+
+// removeFlannel stops flanneld, deletes its VXLAN device and removes its files. Failures are logged, so cleanup
+// continues.
+func (u *UnInstaller) removeFlannel() {
+	if err := utils.StopService(flanneldServiceName); err != nil {
+		u.logger.Debugf("Failed to stop flanneld: %v", err)
+	}
+	if err := utils.DisableService(flanneldServiceName); err != nil {
+		u.logger.Debugf("Failed to disable flanneld: %v", err)
+	}
+	if err := utils.RunSystemCommand("ip", "link", "delete", flannelVXLANDevice); err != nil {
+		u.logger.Debugf("Failed to delete %s: %v (may not exist)", flannelVXLANDevice, err)
+	}
+	for _, err := range utils.RemoveFiles([]string{flanneldServicePath, flanneldBinaryPath}, u.logger) {
+		u.logger.Warnf("Failed to remove flannel file: %v", err)
+	}
+	for _, err := range utils.RemoveDirectories(append(installed_files.CreatedPaths(flannelNetConfDir), flannelRunDir), u.logger) {
+		u.logger.Warnf("Failed to remove flannel directory: %v", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		u.logger.Warnf("Failed to reload systemd: %v", err)
+	}
+}
+
 // GetName returns the cleanup step name
 func (u *UnInstaller) GetName() string {
 	return "CNICleanup"
@@ -12,6 +12,10 @@ const (
 	// Using 99-bridge.conf (high number) ensures other CNI solutions like Cilium
 	// can override this temporary bridge with lower-numbered configs (e.g., 05-cilium.conf)
 	bridgeConfigFile = "99-bridge.conf"
+	// Configuration rendered from cni.configTemplateFile in place of the bridge, with the same priority
+	templatedConfigFile = "99-custom.conflist"
+	// Release of the standard plugins installed from the plugin archive
+	pluginsVersionFile = DefaultCNILibDir + "/plugins-version"
 
 	// Required CNI plugins
 	bridgePlugin    = "bridge"
@@ -21,6 +25,50 @@ const (
 	bandwidthPlugin = "bandwidth"
 	tuningPlugin    = "tuning"
 
+	// Cilium agent prerequisites. The agent writes 05-cilium.conflist itself and installs the plugin from its
+	// image, where it lives under /opt/cni/bin as well.
+	ciliumPlugin     = "cilium-cni"
+	ciliumImageFile  = DefaultCNILibDir + "/cilium-image"
+	bpfMountPoint    = "/sys/fs/bpf"
+	bpfMountUnitName = "sys-fs-bpf.mount"
+	bpfMountUnitPath = "/etc/systemd/system/sys-fs-bpf.mount"
+
+	// Calico prerequisites. calico-node writes 10-calico.conflist and calico-kubeconfig itself.
+	calicoImageFile              = DefaultCNILibDir + "/calico-image"
+	calicoModulesPath            = "/etc/modules-load.d/calico.conf"
+	wireGuardModule              = "wireguard"
+	networkManagerService        = "NetworkManager"
+	networkManagerConfDir        = "/etc/NetworkManager/conf.d"
+	calicoNetworkManagerConfPath = "/etc/NetworkManager/conf.d/calico.conf"
+
+	// flannel files. flanneld writes the node's lease to /run/flannel/subnet.env.
+	flannelPlugin       = "flannel"
+	flannelConfigFile   = "10-flannel.conflist"
+	flannelVersionFile  = DefaultCNILibDir + "/flannel-version"
+	flannelNetConfDir   = "/etc/kube-flannel"
+	flannelNetConfPath  = "/etc/kube-flannel/net-conf.json"
+	flannelRunDir       = "/run/flannel"
+	flanneldBinaryPath  = "/usr/local/bin/flanneld"
+	flanneldServiceName = "flanneld"
+	flanneldServicePath = "/etc/systemd/system/flanneld.service"
+	flannelVXLANDevice  = "flannel.1"
+
+	// Configuration files written by the Cilium and Calico agents
+	ciliumConfigFile = "05-cilium.conflist"
+	calicoConfigFile = "10-calico.conflist"
+
+	// Multus files. 00-multus.conf sorts first, so the container runtime picks it over the primary CNI.
+	multusPlugin      = "multus"
+	multusConfigFile  = "00-multus.conf"
+	multusVersionFile = DefaultCNILibDir + "/multus-version"
+	multusCacheDir    = DefaultCNILibDir + "/multus"
+
+	// containerd runs the pods, and ctr pulls images into the namespace the CRI plugin uses
+	containerdService      = "containerd"
+	containerdK8sNamespace = "k8s.io"
+	// containerdHostsDir holds the registry mirrors, which ctr only follows when pointed at them
+	containerdHostsDir = "/etc/containerd/certs.d"
+
 	// CNI version
 	defaultCNIVersion = "1.5.1"
 
@@ -28,6 +76,74 @@ const (
 	defaultCNISpecVersion = "0.3.1"
 )
 
+// bridgeSubnet is the pod address range of one IP family on the bridge
+type bridgeSubnet struct {
+	subnet       string
+	gateway      string
+	defaultRoute string
+}
+
+// bridgeIPv4Subnet is the IPv4 pod range of the bridge. The IPv6 range of dual-stack nodes comes from the
+// cluster, see podIPv6CIDR.
+var bridgeIPv4Subnet = bridgeSubnet{subnet: "10.244.0.0/16", gateway: "10.244.0.1", defaultRoute: "0.0.0.0/0"}
+
+// bpfMountUnit mounts the BPF filesystem on boot, named after its mount point as systemd requires
+var bpfMountUnit = `[Unit]
+Description=BPF filesystem for Cilium
+Documentation=https://docs.cilium.io/en/stable/operations/system_requirements/#mounted-ebpf-filesystem
+DefaultDependencies=no
+Before=local-fs.target umount.target
+After=swap.target
+
+[Mount]
+What=bpffs
+Where=` + bpfMountPoint + `
+Type=bpf
+Options=rw,nosuid,nodev,noexec,relatime,mode=700
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// ciliumKernelOptions are the kernel build options the Cilium agent needs for its eBPF datapath
+var ciliumKernelOptions = []string{
+	"CONFIG_BPF",
+	"CONFIG_BPF_SYSCALL",
+	"CONFIG_BPF_JIT",
+	"CONFIG_NET_CLS_BPF",
+	"CONFIG_NET_CLS_ACT",
+	"CONFIG_NET_SCH_INGRESS",
+	"CONFIG_CGROUP_BPF",
+	"CONFIG_CRYPTO_SHA1",
+	"CONFIG_CRYPTO_USER_API_HASH",
+	"CONFIG_PERF_EVENTS",
+}
+
+// calicoPlugins are the plugins the Calico CNI image provides
+var calicoPlugins = []string{"calico", "calico-ipam"}
+
+// calicoChainedPlugins are the standard plugins calico-node's conflist chains after the calico plugin
+var calicoChainedPlugins = []string{portmapPlugin, bandwidthPlugin}
+
+// calicoKernelModules are the kernel modules Felix needs for its IP sets, iptables rules and IPIP and VXLAN
+// overlays
+var calicoKernelModules = []string{
+	"ip_set",
+	"ip_set_hash_ip",
+	"ip_set_hash_net",
+	"xt_set",
+	"xt_mark",
+	"xt_multiport",
+	"xt_rpfilter",
+	"ipip",
+	"vxlan",
+}
+
+// calicoNetworkManagerConf keeps NetworkManager from managing the interfaces Calico creates
+var calicoNetworkManagerConf = `[keyfile]
+unmanaged-devices=interface-name:cali*;interface-name:tunl*;interface-name:vxlan.calico;interface-name:vxlan-v6.calico;interface-name:wireguard.cali;interface-name:wg-v6.cali
+`
+
 var cniDirs = []string{
 	DefaultCNIBinDir,
 	DefaultCNIConfDir,
@@ -40,6 +156,36 @@ var requiredCNIPlugins = []string{
 	loopbackPlugin,
 }
 
+// flannelConflist hands pods to the bridge plugin with the subnet flanneld leased for the node
+var flannelConflist = `{
+    "name": "cbr0",
+    "cniVersion": "` + defaultCNISpecVersion + `",
+    "plugins": [
+        {
+            "type": "flannel",
+            "delegate": {
+                "hairpinMode": true,
+                "isDefaultGateway": true
+            }
+        },
+        {
+            "type": "portmap",
+            "capabilities": {
+                "portMappings": true
+            }
+        }
+    ]
+}
+`
+
+var (
+	flanneldDownloadURL      = "https://github.com/flannel-io/flannel/releases/download/v%s/flanneld-%s"
+	flannelPluginDownloadURL = "https://github.com/flannel-io/cni-plugin/releases/download/v%s/flannel-%s"
+)
+
+// multusDownloadURL is the Multus release archive, holding the thin plugin
+var multusDownloadURL = "https://github.com/k8snetworkplumbingwg/multus-cni/releases/download/v%s/multus-cni_%s_linux_%s.tar.gz"
+
 var (
 	cniFileName    = "cni-plugins-linux-%s-v%s.tgz"
 	cniDownLoadURL = "https://github.com/containernetworking/plugins/releases/download/v%s/" + cniFileName
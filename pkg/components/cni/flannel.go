@@ -0,0 +1,253 @@
+package cni
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// prepareFlannel runs flanneld on the node and writes the flannel configuration in place of the bridge.
+// flanneld leases the node's pod subnet from the cluster and writes it to /run/flannel/subnet.env, which the
+// flannel plugin hands to the bridge plugin for each pod.
+func (i *Installer) prepareFlannel(ctx context.Context) error {
+	if err := utils.RunCleanupCommand(filepath.Join(DefaultCNIConfDir, bridgeConfigFile)); err != nil {
+		return fmt.Errorf("failed to remove the bridge configuration: %w", err)
+	}
+	podCIDRs, err := i.flannelPodCIDRs(ctx)
+	if err != nil {
+		return err
+	}
+	unit, err := i.flanneldUnit(ctx)
+	if err != nil {
+		return err
+	}
+	if err := i.installFlannelBinaries(ctx); err != nil {
+		return err
+	}
+
+	netConf, err := flannelNetConf(podCIDRs, i.config.CNI.Flannel.Backend)
+	if err != nil {
+		return err
+	}
+	if err := utilio.MkdirAll(flannelNetConfDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", flannelNetConfDir, err)
+	}
+	if err := utilio.WriteFile(flannelNetConfPath, []byte(netConf), 0o644); err != nil {
+		return fmt.Errorf("failed to write the flannel network configuration: %w", err)
+	}
+	if err := utilio.WriteFile(filepath.Join(DefaultCNIConfDir, flannelConfigFile), []byte(flannelConflist), 0o644); err != nil {
+		return fmt.Errorf("failed to write the flannel CNI configuration: %w", err)
+	}
+
+	if err := utilio.WriteFile(flanneldServicePath, []byte(unit), 0o644); err != nil {
+		return fmt.Errorf("failed to write flanneld service file: %w", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := utils.EnableAndStartService(flanneldServiceName); err != nil {
+		return fmt.Errorf("failed to start flanneld: %w", err)
+	}
+	// Pick up configuration changes when the service was already running
+	if err := utils.RestartService(flanneldServiceName); err != nil {
+		return fmt.Errorf("failed to restart flanneld: %w", err)
+	}
+	i.logger.Infof("flanneld started with the %s backend for pod CIDR %s", i.config.CNI.Flannel.Backend, strings.Join(podCIDRs, ","))
+	return nil
+}
+
+// flannelPodCIDRs returns cni.flannel.podCIDR, or the pod CIDRs of the target cluster
+func (i *Installer) flannelPodCIDRs(ctx context.Context) ([]string, error) {
+	if podCIDR := i.config.CNI.Flannel.PodCIDR; podCIDR != "" {
+		var cidrs []string
+		for _, cidr := range strings.Split(podCIDR, ",") {
+			cidrs = append(cidrs, strings.TrimSpace(cidr))
+		}
+		return cidrs, nil
+	}
+	if i.config.Azure.TargetCluster == nil {
+		return nil, fmt.Errorf("cni.flannel.podCIDR is not set and no target cluster is configured")
+	}
+	clusterSpec, err := spec.NewManagedClusterSpecCollector(i.config, i.logger).Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusterSpec.PodCIDRs) == 0 {
+		return nil, fmt.Errorf("the cluster reports no pod CIDR; set cni.flannel.podCIDR")
+	}
+	return clusterSpec.PodCIDRs, nil
+}
+
+// installFlannelBinaries downloads flanneld and the flannel CNI plugin of the configured releases
+func (i *Installer) installFlannelBinaries(ctx context.Context) error {
+	flannel := &i.config.CNI.Flannel
+	if installedFlannelVersions() == flannelVersions(flannel.Version, flannel.PluginVersion) &&
+		utils.FileExistsAndValid(flanneldBinaryPath) && utils.FileExistsAndValid(filepath.Join(DefaultCNIBinDir, flannelPlugin)) {
+		i.logger.Infof("flanneld %s and flannel plugin %s are already installed", flannel.Version, flannel.PluginVersion)
+		return nil
+	}
+
+	arch := utilhost.GetArch()
+	downloads := map[string]string{
+		flanneldBinaryPath: fmt.Sprintf(flanneldDownloadURL, strings.TrimPrefix(flannel.Version, "v"), arch),
+		filepath.Join(DefaultCNIBinDir, flannelPlugin): fmt.Sprintf(flannelPluginDownloadURL, strings.TrimPrefix(flannel.PluginVersion, "v"), arch),
+	}
+	for path, url := range downloads {
+		i.logger.Infof("Downloading %s from %s", filepath.Base(path), url)
+		if err := utilio.DownloadToLocalFile(ctx, url, path, 0o755); err != nil {
+			return fmt.Errorf("failed to download %s: %w", url, err)
+		}
+	}
+	if err := utilio.WriteFile(flannelVersionFile, []byte(flannelVersions(flannel.Version, flannel.PluginVersion)), 0o644); err != nil {
+		return fmt.Errorf("failed to record the flannel versions: %w", err)
+	}
+	return nil
+}
+
+// flanneldUnit renders the flanneld service. flanneld authenticates with the kubelet kubeconfig, so it acts as
+// the node: it reads the pod CIDR allocated to its Node and records its VTEP address in annotations on it.
+func (i *Installer) flanneldUnit(ctx context.Context) (string, error) {
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		return "", fmt.Errorf("failed to get node name for flanneld: %w", err)
+	}
+	env := []string{"NODE_NAME=" + nodeName}
+	if i.config.IsProxyConfigured() {
+		var extraHosts []string
+		if host, err := i.apiServerHost(ctx); err == nil {
+			extraHosts = append(extraHosts, host)
+		}
+		env = append(env, i.config.GetProxyEnvironment(extraHosts...)...)
+	}
+
+	args := []string{
+		"--kube-subnet-mgr",
+		"--kubeconfig-file=" + kubelet.KubeletKubeconfigPath,
+		"--net-config-path=" + flannelNetConfPath,
+		"--ip-masq",
+	}
+	nodeIPs, err := i.config.GetNodeIPs()
+	if err != nil {
+		return "", err
+	}
+	if len(nodeIPs) > 0 {
+		// Carry overlay traffic on the node IP rather than the default route's interface
+		args = append(args, "--iface="+nodeIPs[0].IP.String())
+	}
+
+	var environment strings.Builder
+	for _, variable := range env {
+		fmt.Fprintf(&environment, "Environment=\"%s\"\n", variable)
+	}
+	return fmt.Sprintf(`[Unit]
+Description=flannel pod network
+Documentation=https://github.com/flannel-io/flannel
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+%sExecStart=%s %s
+Restart=always
+RestartSec=5s
+
+[Install]
+WantedBy=multi-user.target
+`, environment.String(), flanneldBinaryPath, strings.Join(args, " ")), nil
+}
+
+// flannelNetwork is the network configuration flanneld reads from --net-config-path
+type flannelNetwork struct {
+	Network     string         `json:"Network,omitempty"`
+	EnableIPv4  *bool          `json:"EnableIPv4,omitempty"`
+	EnableIPv6  bool           `json:"EnableIPv6,omitempty"`
+	IPv6Network string         `json:"IPv6Network,omitempty"`
+	Backend     flannelBackend `json:"Backend"`
+}
+
+type flannelBackend struct {
+	Type string `json:"Type"`
+}
+
+// flannelNetConf renders the flanneld network configuration for the pod CIDRs, one per IP family
+func flannelNetConf(podCIDRs []string, backend string) (string, error) {
+	network := flannelNetwork{Backend: flannelBackend{Type: backend}}
+	for _, cidr := range podCIDRs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return "", fmt.Errorf("invalid pod CIDR %s: %w", cidr, err)
+		}
+		if ip.To4() != nil {
+			network.Network = cidr
+		} else {
+			network.EnableIPv6 = true
+			network.IPv6Network = cidr
+		}
+	}
+	if network.Network == "" {
+		disabled := false
+		network.EnableIPv4 = &disabled
+	}
+	data, err := json.MarshalIndent(network, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// isFlannelReady reports whether flanneld runs with the current configuration and the flannel configuration
+// replaces the bridge
+func (i *Installer) isFlannelReady(ctx context.Context) bool {
+	if utils.FileExists(filepath.Join(DefaultCNIConfDir, bridgeConfigFile)) {
+		i.logger.Debug("Bridge configuration is still present with cni.provider flannel")
+		return false
+	}
+	flannel := &i.config.CNI.Flannel
+	if installedFlannelVersions() != flannelVersions(flannel.Version, flannel.PluginVersion) {
+		i.logger.Debugf("flanneld %s and flannel plugin %s are not installed", flannel.Version, flannel.PluginVersion)
+		return false
+	}
+	podCIDRs, err := i.flannelPodCIDRs(ctx)
+	if err != nil {
+		i.logger.Debugf("Failed to determine the flannel pod CIDR: %v", err)
+		return false
+	}
+	netConf, err := flannelNetConf(podCIDRs, flannel.Backend)
+	if err != nil || !utils.FileHasContent(flannelNetConfPath, netConf) {
+		i.logger.Debug("flannel network configuration is out of date")
+		return false
+	}
+	if !utils.FileHasContent(filepath.Join(DefaultCNIConfDir, flannelConfigFile), flannelConflist) {
+		i.logger.Debug("flannel CNI configuration is missing")
+		return false
+	}
+	unit, err := i.flanneldUnit(ctx)
+	if err != nil || !utils.FileHasContent(flanneldServicePath, unit) {
+		i.logger.Debug("flanneld service is out of date")
+		return false
+	}
+	return utils.IsServiceActive(flanneldServiceName)
+}
+
+// flannelVersions is the record of the installed flanneld and flannel plugin releases
+func flannelVersions(version, pluginVersion string) string {
+	return fmt.Sprintf("flanneld %s\nflannel %s\n", version, pluginVersion)
+}
+
+// installedFlannelVersions returns the record written when the flannel binaries were installed
+func installedFlannelVersions() string {
+	data, err := os.ReadFile(flannelVersionFile)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,77 @@
+package cni
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFlannelNetConf(t *testing.T) {
+	tests := []struct {
+		name     string
+		podCIDRs []string
+		backend  string
+		want     flannelNetwork
+		wantErr  bool
+	}{
+		{
+			name:     "ipv4 vxlan",
+			podCIDRs: []string{"10.244.0.0/16"},
+			backend:  "vxlan",
+			want:     flannelNetwork{Network: "10.244.0.0/16", Backend: flannelBackend{Type: "vxlan"}},
+		},
+		{
+			name:     "dual-stack host-gw",
+			podCIDRs: []string{"10.244.0.0/16", "fd00:10:244::/56"},
+			backend:  "host-gw",
+			want:     flannelNetwork{Network: "10.244.0.0/16", EnableIPv6: true, IPv6Network: "fd00:10:244::/56", Backend: flannelBackend{Type: "host-gw"}},
+		},
+		{
+			name:     "invalid CIDR",
+			podCIDRs: []string{"10.244.0.0"},
+			backend:  "vxlan",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			netConf, err := flannelNetConf(tt.podCIDRs, tt.backend)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("flannelNetConf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got flannelNetwork
+			if err := json.Unmarshal([]byte(netConf), &got); err != nil {
+				t.Fatalf("flannelNetConf() = %s, not JSON: %v", netConf, err)
+			}
+			if got != tt.want {
+				t.Errorf("flannelNetConf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	netConf, err := flannelNetConf([]string{"fd00:10:244::/56"}, "vxlan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ipv6Only flannelNetwork
+	if err := json.Unmarshal([]byte(netConf), &ipv6Only); err != nil || ipv6Only.EnableIPv4 == nil || *ipv6Only.EnableIPv4 {
+		t.Errorf("flannelNetConf() = %s, want IPv4 disabled for an IPv6-only cluster", netConf)
+	}
+}
+
+func TestFlannelConflist(t *testing.T) {
+	var conflist struct {
+		Plugins []struct {
+			Type string `json:"type"`
+		} `json:"plugins"`
+	}
+	if err := json.Unmarshal([]byte(flannelConflist), &conflist); err != nil {
+		t.Fatalf("flannelConflist is not JSON: %v", err)
+	}
+	if len(conflist.Plugins) != 2 || conflist.Plugins[0].Type != flannelPlugin || conflist.Plugins[1].Type != portmapPlugin {
+		t.Errorf("flannelConflist plugins = %+v, want flannel then portmap", conflist.Plugins)
+	}
+}
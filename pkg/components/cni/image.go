@@ -0,0 +1,96 @@
+package cni
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// InstallPluginsFromImage copies CNI plugins from dir of a container image into the plugin directory and
+// records the image in recordPath, so a later bootstrap with the same image skips the pull
+func InstallPluginsFromImage(ctx context.Context, cfg *config.Config, logger *logrus.Logger, image, dir string, plugins []string, recordPath string) error {
+	if PluginsInstalledFrom(image, plugins, recordPath) {
+		logger.Infof("%s from %s already installed", strings.Join(plugins, ", "), image)
+		return nil
+	}
+
+	// containerd is configured by now but only started by the services step
+	if !utils.IsServiceActive(containerdService) {
+		if err := utils.EnableAndStartService(containerdService); err != nil {
+			return fmt.Errorf("failed to start containerd to pull %s: %w", image, err)
+		}
+	}
+	// Unpack with the snapshotter containerd runs containers with, the only one some filesystems support
+	snapshotter := "--snapshotter=" + cfg.Containerd.Snapshotter
+	if output, err := ctr(ctx, cfg, "images", "pull", snapshotter, "--hosts-dir", containerdHostsDir, image); err != nil {
+		return fmt.Errorf("failed to pull %s: %w, output: %s", image, err, output)
+	}
+
+	mountDir, err := os.MkdirTemp("", "cni-image-")
+	if err != nil {
+		return fmt.Errorf("failed to create a mount point for %s: %w", image, err)
+	}
+	defer func() { _ = os.Remove(mountDir) }()
+	if output, err := ctr(ctx, cfg, "images", "mount", snapshotter, image, mountDir); err != nil {
+		return fmt.Errorf("failed to mount %s: %w, output: %s", image, err, output)
+	}
+	defer func() {
+		// Unmount even when the run was cancelled, so no mount is left behind
+		if output, err := ctr(context.WithoutCancel(ctx), cfg, "images", "unmount", snapshotter, "--rm", mountDir); err != nil {
+			logger.Warnf("Failed to unmount %s: %v, output: %s", mountDir, err, output)
+		}
+	}()
+
+	for _, plugin := range plugins {
+		if err := installPluginFrom(filepath.Join(mountDir, dir, plugin), plugin); err != nil {
+			return fmt.Errorf("failed to install %s from %s: %w", plugin, image, err)
+		}
+	}
+	if err := utilio.WriteFile(recordPath, []byte(image+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to record the image of %s: %w", strings.Join(plugins, ", "), err)
+	}
+	logger.Infof("Installed %s from %s", strings.Join(plugins, ", "), image)
+	return nil
+}
+
+// installPluginFrom copies the plugin binary at source into the plugin directory
+func installPluginFrom(source, plugin string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	return utilio.InstallFile(filepath.Join(DefaultCNIBinDir, plugin), file, 0o755)
+}
+
+// PluginsInstalledFrom reports whether the plugins are installed and recordPath names image as their source
+func PluginsInstalledFrom(image string, plugins []string, recordPath string) bool {
+	data, err := os.ReadFile(recordPath)
+	if err != nil || strings.TrimSpace(string(data)) != image {
+		return false
+	}
+	for _, plugin := range plugins {
+		if !utils.FileExistsAndValid(filepath.Join(DefaultCNIBinDir, plugin)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ctr runs a ctr command in the Kubernetes namespace of containerd. ctr fetches images itself rather than
+// through containerd, so it gets the network.proxy settings.
+func ctr(ctx context.Context, cfg *config.Config, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "ctr", append([]string{"--namespace", containerdK8sNamespace}, args...)...) // #nosec - arguments come from the configuration
+	cmd.Env = append(os.Environ(), cfg.GetProxyEnvironment()...)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
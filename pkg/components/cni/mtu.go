@@ -0,0 +1,62 @@
+package cni
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// podMTU returns the MTU of pod interfaces: network.mtu when set, otherwise the path MTU to the API server.
+// WireGuard, IPsec and ExpressRoute links carry less than 1500 bytes, and pods left at 1500 lose large packets
+// silently when ICMP "fragmentation needed" messages do not make it back. It returns 0 when the MTU cannot be
+// determined, which leaves the bridge plugin default.
+func (i *Installer) podMTU(ctx context.Context) int {
+	if i.config.Network.MTU > 0 {
+		return i.config.Network.MTU
+	}
+
+	host, err := i.apiServerHost(ctx)
+	if err != nil {
+		i.logger.Warnf("Skipping MTU detection: %v. Set network.mtu if the path to the cluster carries less than 1500 bytes", err)
+		return 0
+	}
+	var source net.IP
+	nodeIPs, err := i.config.GetNodeIPs()
+	if err != nil {
+		i.logger.Warnf("Skipping MTU detection: %v", err)
+		return 0
+	}
+	if len(nodeIPs) > 0 {
+		source = nodeIPs[0].IP
+	}
+	mtu, err := utilhost.PathMTU(ctx, host, source)
+	if err != nil {
+		i.logger.Warnf("Skipping MTU detection: %v. Set network.mtu if the path to the cluster carries less than 1500 bytes", err)
+		return 0
+	}
+	i.logger.Infof("Detected path MTU %d to API server %s", mtu, host)
+	return mtu
+}
+
+// apiServerHost returns the host of node.kubelet.serverURL, or of the target cluster's API server in Azure
+func (i *Installer) apiServerHost(ctx context.Context) (string, error) {
+	if serverURL := i.config.Node.Kubelet.ServerURL; serverURL != "" {
+		u, err := url.Parse(serverURL)
+		if err != nil || u.Hostname() == "" {
+			return "", fmt.Errorf("invalid node.kubelet.serverURL %s", serverURL)
+		}
+		return u.Hostname(), nil
+	}
+	if i.config.Azure.TargetCluster == nil {
+		return "", fmt.Errorf("node.kubelet.serverURL is not set and no target cluster is configured")
+	}
+	clusterSpec, err := spec.NewManagedClusterSpecCollector(i.config, i.logger).Collect(ctx)
+	if err != nil {
+		return "", err
+	}
+	return clusterSpec.APIServerFQDN(), nil
+}
@@ -0,0 +1,209 @@
+package cni
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// installMultus installs the Multus thin plugin and writes its configuration ahead of the primary CNI
+// configuration. containerd uses the first configuration in the directory, so every pod goes through Multus,
+// which delegates the pod network to the primary CNI and attaches the networks listed in the pod's
+// k8s.v1.cni.cncf.io/networks annotation.
+func (i *Installer) installMultus(ctx context.Context) error {
+	if err := i.installMultusBinary(ctx); err != nil {
+		return err
+	}
+	primary := primaryCNIConfigFile(&i.config.CNI)
+	if err := utilio.WriteFile(filepath.Join(DefaultCNIConfDir, multusConfigFile), []byte(multusConfig(primary)), 0o644); err != nil {
+		return fmt.Errorf("failed to write the Multus configuration: %w", err)
+	}
+
+	err := checkPrimaryCNI(DefaultCNIConfDir, DefaultCNIBinDir, primary)
+	if os.IsNotExist(err) && !i.managesCNIConfig() {
+		// Multus waits for the file, its readiness indicator, before it sets up pods
+		i.logger.Infof("Multus delegates to %s once the %s agent writes it", primary, i.config.CNI.Provider)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("the primary CNI behind Multus would not work: %w", err)
+	}
+	i.logger.Infof("Multus installed, delegating the pod network to %s", primary)
+	return nil
+}
+
+// installMultusBinary downloads the Multus thin plugin of the configured release
+func (i *Installer) installMultusBinary(ctx context.Context) error {
+	version := strings.TrimPrefix(i.config.CNI.Multus.Version, "v")
+	pluginPath := filepath.Join(DefaultCNIBinDir, multusPlugin)
+	if installedMultusVersion() == version && utils.FileExistsAndValid(pluginPath) {
+		i.logger.Infof("Multus %s is already installed", version)
+		return nil
+	}
+
+	url := fmt.Sprintf(multusDownloadURL, version, version, utilhost.GetArch())
+	i.logger.Infof("Downloading Multus from %s", url)
+	installed := false
+	for tarFile, err := range utilio.DecompressTarGzFromRemote(ctx, url) {
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", url, err)
+		}
+		if filepath.Base(tarFile.Name) != multusPlugin {
+			continue
+		}
+		if err := utilio.InstallFile(pluginPath, tarFile.Body, 0o755); err != nil {
+			return fmt.Errorf("failed to install %s: %w", pluginPath, err)
+		}
+		installed = true
+	}
+	if !installed {
+		return fmt.Errorf("%s does not contain the %s plugin", url, multusPlugin)
+	}
+	if err := utilio.WriteFile(multusVersionFile, []byte(version+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to record the Multus version: %w", err)
+	}
+	return nil
+}
+
+// removeMultus removes the Multus configuration so pods go to the primary CNI directly again
+func (i *Installer) removeMultus() {
+	if err := utils.RunCleanupCommand(filepath.Join(DefaultCNIConfDir, multusConfigFile)); err != nil {
+		i.logger.Warnf("Failed to remove the Multus configuration: %v", err)
+	}
+}
+
+// isMultusReady reports whether Multus matches cni.multus and still fronts a working primary CNI
+func (i *Installer) isMultusReady() bool {
+	configPath := filepath.Join(DefaultCNIConfDir, multusConfigFile)
+	if !i.config.CNI.Multus.Enabled {
+		return !utils.FileExists(configPath)
+	}
+	if installedMultusVersion() != strings.TrimPrefix(i.config.CNI.Multus.Version, "v") {
+		i.logger.Debugf("Multus %s is not installed", i.config.CNI.Multus.Version)
+		return false
+	}
+	primary := primaryCNIConfigFile(&i.config.CNI)
+	if !utils.FileHasContent(configPath, multusConfig(primary)) {
+		// Cilium moves other configurations aside unless its cni.exclusive setting is off
+		i.logger.Debug("Multus configuration is missing or out of date")
+		return false
+	}
+	if err := checkPrimaryCNI(DefaultCNIConfDir, DefaultCNIBinDir, primary); err != nil && !(os.IsNotExist(err) && !i.managesCNIConfig()) {
+		i.logger.Debugf("Primary CNI behind Multus is not functional: %v", err)
+		return false
+	}
+	return true
+}
+
+// primaryCNIConfigFile returns the configuration file of the pod network cni.provider sets up
+func primaryCNIConfigFile(cfg *config.CNIConfig) string {
+	switch cfg.Provider {
+	case config.CNIProviderCilium:
+		return ciliumConfigFile
+	case config.CNIProviderCalico:
+		return calicoConfigFile
+	case config.CNIProviderFlannel:
+		return flannelConfigFile
+	}
+	if cfg.ConfigTemplateFile != "" {
+		return templatedConfigFile
+	}
+	return bridgeConfigFile
+}
+
+// multusConfig renders the Multus thin plugin configuration delegating the pod network to primary. Multus
+// reads the NetworkAttachmentDefinitions of secondary networks with the kubelet kubeconfig.
+func multusConfig(primary string) string {
+	primaryPath := filepath.Join(DefaultCNIConfDir, primary)
+	return fmt.Sprintf(`{
+    "cniVersion": "%s",
+    "name": "multus-cni-network",
+    "type": "%s",
+    "kubeconfig": "%s",
+    "confDir": "%s",
+    "cniDir": "%s",
+    "binDir": "%s",
+    "clusterNetwork": "%s",
+    "readinessindicatorfile": "%s"
+}
+`, defaultCNISpecVersion, multusPlugin, kubelet.KubeletKubeconfigPath, DefaultCNIConfDir, multusCacheDir, DefaultCNIBinDir, primaryPath, primaryPath)
+}
+
+// checkPrimaryCNI checks that the Multus configuration is the one the container runtime picks and that the
+// primary configuration can be delegated to: it parses and every plugin it chains is installed. It returns an
+// error satisfying os.IsNotExist when the primary configuration does not exist yet.
+func checkPrimaryCNI(confDir, binDir, primary string) error {
+	data, err := os.ReadFile(filepath.Join(confDir, primary))
+	if err != nil {
+		return err
+	}
+	if _, err := configPluginTypes(primary, data, binDir); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(confDir)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", confDir, err)
+	}
+	var configs []string
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".conf", ".conflist", ".json":
+			configs = append(configs, entry.Name())
+		}
+	}
+	sort.Strings(configs)
+	if len(configs) > 0 && configs[0] != multusConfigFile {
+		return fmt.Errorf("%s sorts before %s, so pods would bypass Multus", configs[0], multusConfigFile)
+	}
+	return nil
+}
+
+// configPluginTypes returns the plugins a CNI configuration or configuration list named name runs, checking
+// that it parses and that every plugin is installed in binDir
+func configPluginTypes(name string, data []byte, binDir string) ([]string, error) {
+	var conf struct {
+		Type    string `json:"type"`
+		Plugins []struct {
+			Type string `json:"type"`
+		} `json:"plugins"`
+	}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return nil, fmt.Errorf("%s is not valid JSON: %w", name, err)
+	}
+	types := []string{conf.Type}
+	if conf.Type == "" {
+		types = nil
+		for _, plugin := range conf.Plugins {
+			types = append(types, plugin.Type)
+		}
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("%s names no plugin", name)
+	}
+	for _, plugin := range types {
+		if !utils.FileExistsAndValid(filepath.Join(binDir, plugin)) {
+			return nil, fmt.Errorf("plugin %s of %s is not installed in %s", plugin, name, binDir)
+		}
+	}
+	return types, nil
+}
+
+// installedMultusVersion returns the release of the installed Multus plugin
+func installedMultusVersion() string {
+	data, err := os.ReadFile(multusVersionFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
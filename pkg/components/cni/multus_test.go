@@ -0,0 +1,94 @@
+package cni
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMultusConfig(t *testing.T) {
+	var conf map[string]any
+	if err := json.Unmarshal([]byte(multusConfig(ciliumConfigFile)), &conf); err != nil {
+		t.Fatalf("multusConfig() is not JSON: %v", err)
+	}
+	want := filepath.Join(DefaultCNIConfDir, ciliumConfigFile)
+	if conf["type"] != multusPlugin || conf["clusterNetwork"] != want || conf["readinessindicatorfile"] != want {
+		t.Errorf("multusConfig() = %v, want multus delegating to %s", conf, want)
+	}
+}
+
+func TestCheckPrimaryCNI(t *testing.T) {
+	writeFile := func(t *testing.T, path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		confs     map[string]string
+		plugins   []string
+		wantErr   bool
+		notExists bool
+	}{
+		{
+			name:    "bridge behind multus",
+			confs:   map[string]string{multusConfigFile: "{}", bridgeConfigFile: `{"type": "bridge"}`},
+			plugins: []string{"bridge"},
+		},
+		{
+			name:    "conflist behind multus",
+			confs:   map[string]string{multusConfigFile: "{}", flannelConfigFile: flannelConflist},
+			plugins: []string{"flannel", "portmap"},
+		},
+		{
+			name:    "chained plugin missing",
+			confs:   map[string]string{multusConfigFile: "{}", flannelConfigFile: flannelConflist},
+			plugins: []string{"flannel"},
+			wantErr: true,
+		},
+		{
+			name:    "another configuration sorts first",
+			confs:   map[string]string{"00-aaa.conf": "{}", multusConfigFile: "{}", bridgeConfigFile: `{"type": "bridge"}`},
+			plugins: []string{"bridge"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid primary configuration",
+			confs:   map[string]string{multusConfigFile: "{}", bridgeConfigFile: "{"},
+			wantErr: true,
+		},
+		{
+			name:      "primary configuration not written yet",
+			confs:     map[string]string{multusConfigFile: "{}"},
+			wantErr:   true,
+			notExists: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confDir, binDir := t.TempDir(), t.TempDir()
+			for name, content := range tt.confs {
+				writeFile(t, filepath.Join(confDir, name), content)
+			}
+			for _, plugin := range tt.plugins {
+				writeFile(t, filepath.Join(binDir, plugin), "#!/bin/sh\n")
+			}
+
+			primary := bridgeConfigFile
+			if _, ok := tt.confs[flannelConfigFile]; ok {
+				primary = flannelConfigFile
+			}
+			err := checkPrimaryCNI(confDir, binDir, primary)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkPrimaryCNI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if os.IsNotExist(err) != tt.notExists {
+				t.Errorf("checkPrimaryCNI() error = %v, want os.IsNotExist %v", err, tt.notExists)
+			}
+		})
+	}
+}
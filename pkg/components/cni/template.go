@@ -0,0 +1,135 @@
+package cni
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// conflistTemplateData holds the variables a cni.configTemplateFile template can use
+type conflistTemplateData struct {
+	CNIVersion string   // CNI specification version of the built-in configuration
+	NodeName   string   // Name the node registers with
+	NodeIP     string   // Primary node IP
+	NodeIPs    []string // Node IPs, one per IP family, primary first
+	PodCIDR    string   // Pod address range of the primary IP family
+	PodCIDRs   []string // Pod address ranges, one per IP family, primary first
+	Gateway    string   // Gateway of PodCIDR
+	Gateways   []string // Gateways of PodCIDRs
+	MTU        int      // Pod MTU, 0 when it could not be determined
+}
+
+// createTemplatedConfig renders cni.configTemplateFile into the CNI configuration in place of the bridge, for
+// clusters chaining plugins such as bandwidth, portmap or tuning that the built-in configuration does not
+func (i *Installer) createTemplatedConfig(ctx context.Context) error {
+	conflist, err := i.renderConfigTemplate(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := configPluginTypes(templatedConfigFile, []byte(conflist), DefaultCNIBinDir); err != nil {
+		return err
+	}
+	if err := utilhost.LoadKernelModule("br_netfilter"); err != nil {
+		i.logger.Warnf("Failed to load br_netfilter module: %v", err)
+	}
+	if err := utilio.WriteFile(filepath.Join(DefaultCNIConfDir, templatedConfigFile), []byte(conflist), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", templatedConfigFile, err)
+	}
+	i.logger.Infof("CNI configuration rendered from %s", i.config.CNI.ConfigTemplateFile)
+	return nil
+}
+
+// isTemplatedConfigReady reports whether the CNI configuration matches the current rendering of the template
+func (i *Installer) isTemplatedConfigReady(ctx context.Context) bool {
+	conflist, err := i.renderConfigTemplate(ctx)
+	if err != nil {
+		i.logger.Debugf("Failed to render the CNI configuration template: %v", err)
+		return false
+	}
+	if !utils.FileHasContent(filepath.Join(DefaultCNIConfDir, templatedConfigFile), conflist) {
+		i.logger.Debug("CNI configuration differs from the rendered template")
+		return false
+	}
+	return true
+}
+
+// renderConfigTemplate renders cni.configTemplateFile with the node's variables
+func (i *Installer) renderConfigTemplate(ctx context.Context) (string, error) {
+	text, err := os.ReadFile(i.config.CNI.ConfigTemplateFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cni.configTemplateFile: %w", err)
+	}
+	data, err := i.conflistTemplateData(ctx)
+	if err != nil {
+		return "", err
+	}
+	return renderConflist(string(text), data)
+}
+
+// conflistTemplateData collects the template variables. The pod ranges are those of the built-in bridge
+// configuration.
+func (i *Installer) conflistTemplateData(ctx context.Context) (*conflistTemplateData, error) {
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		return nil, err
+	}
+	data := &conflistTemplateData{
+		CNIVersion: defaultCNISpecVersion,
+		NodeName:   nodeName,
+		MTU:        i.podMTU(ctx),
+	}
+
+	nodeIPs, err := i.config.GetNodeIPs()
+	if err != nil {
+		return nil, err
+	}
+	if len(nodeIPs) == 0 {
+		address, err := utilhost.SelectNodeIP(utilhost.NodeIPSelector{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the node IP: %w", err)
+		}
+		nodeIPs = append(nodeIPs, address)
+	}
+	for _, address := range nodeIPs {
+		data.NodeIPs = append(data.NodeIPs, address.IP.String())
+	}
+	data.NodeIP = data.NodeIPs[0]
+
+	subnets, err := i.bridgeSubnets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, family := range subnets {
+		data.PodCIDRs = append(data.PodCIDRs, family.subnet)
+		data.Gateways = append(data.Gateways, family.gateway)
+	}
+	data.PodCIDR, data.Gateway = data.PodCIDRs[0], data.Gateways[0]
+	return data, nil
+}
+
+// renderConflist executes a conflist template. Besides the template builtins, the json function renders a value
+// as JSON, so lists such as .PodCIDRs can be used directly.
+func renderConflist(text string, data *conflistTemplateData) (string, error) {
+	tmpl, err := template.New("conflist").Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			out, err := json.Marshal(v)
+			return string(out), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid cni.configTemplateFile: %w", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render cni.configTemplateFile: %w", err)
+	}
+	return out.String(), nil
+}
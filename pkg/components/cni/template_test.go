@@ -0,0 +1,93 @@
+package cni
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderConflist(t *testing.T) {
+	data := &conflistTemplateData{
+		CNIVersion: "0.3.1",
+		NodeName:   "edge-1",
+		NodeIP:     "192.168.1.10",
+		NodeIPs:    []string{"192.168.1.10", "fd00::10"},
+		PodCIDR:    "10.244.0.0/16",
+		PodCIDRs:   []string{"10.244.0.0/16", "fd00:10:244::/64"},
+		Gateway:    "10.244.0.1",
+		Gateways:   []string{"10.244.0.1", "fd00:10:244::1"},
+		MTU:        1400,
+	}
+	text := `{
+    "cniVersion": "{{.CNIVersion}}",
+    "name": "chained",
+    "plugins": [
+        {
+            "type": "bridge",
+            "bridge": "cni0",
+            "mtu": {{.MTU}},
+            "ipam": {
+                "type": "host-local",
+                "ranges": [{{range $i, $cidr := .PodCIDRs}}{{if $i}}, {{end}}[{"subnet": "{{$cidr}}"}]{{end}}]
+            }
+        },
+        {"type": "tuning", "sysctl": {"net.ipv4.conf.all.arp_ignore": "1"}},
+        {"type": "bandwidth", "capabilities": {"bandwidth": true}, "nodeIPs": {{json .NodeIPs}}}
+    ]
+}`
+	out, err := renderConflist(text, data)
+	if err != nil {
+		t.Fatalf("renderConflist() error = %v", err)
+	}
+	var conf struct {
+		Plugins []map[string]any `json:"plugins"`
+	}
+	if err := json.Unmarshal([]byte(out), &conf); err != nil {
+		t.Fatalf("renderConflist() is not JSON: %v\n%s", err, out)
+	}
+	if len(conf.Plugins) != 3 || conf.Plugins[0]["mtu"] != float64(1400) {
+		t.Errorf("renderConflist() = %s", out)
+	}
+	if !strings.Contains(out, `[{"subnet": "10.244.0.0/16"}], [{"subnet": "fd00:10:244::/64"}]`) || !strings.Contains(out, `["192.168.1.10","fd00::10"]`) {
+		t.Errorf("renderConflist() did not render the lists:\n%s", out)
+	}
+
+	if _, err := renderConflist(`{"name": "{{.ClusterName}}"}`, data); err == nil {
+		t.Error("renderConflist() succeeded with an unknown variable")
+	}
+	if _, err := renderConflist(`{"name": "{{.NodeName}"}`, data); err == nil {
+		t.Error("renderConflist() succeeded with a syntax error")
+	}
+}
+
+func TestConfigPluginTypes(t *testing.T) {
+	binDir := t.TempDir()
+	for _, plugin := range []string{"bridge", "tuning"} {
+		if err := os.WriteFile(filepath.Join(binDir, plugin), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		conf    string
+		want    int
+		wantErr bool
+	}{
+		{name: "single plugin", conf: `{"type": "bridge"}`, want: 1},
+		{name: "chain", conf: `{"plugins": [{"type": "bridge"}, {"type": "tuning"}]}`, want: 2},
+		{name: "plugin not installed", conf: `{"plugins": [{"type": "bridge"}, {"type": "bandwidth"}]}`, wantErr: true},
+		{name: "no plugin", conf: `{"plugins": []}`, wantErr: true},
+		{name: "invalid JSON", conf: `{"plugins": [`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			types, err := configPluginTypes(templatedConfigFile, []byte(tt.conf), binDir)
+			if (err != nil) != tt.wantErr || len(types) != tt.want {
+				t.Errorf("configPluginTypes() = %v, %v, want %d plugins, wantErr %v", types, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
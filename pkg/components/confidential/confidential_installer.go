@@ -0,0 +1,157 @@
+package confidential
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer prepares the node for confidential containers. On a SEV-SNP or TDX host, it turns the technology on
+// in KVM and checks that the Kata release can launch confidential VMs, which containerd runs with the kata-cc
+// runtime handler. On a machine that is itself a confidential VM, it loads the attestation driver instead. The
+// node labels are set from the configuration, where the technologies are detected.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new confidential computing Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "ConfidentialInstaller"
+}
+
+// Validate checks that the machine offers SEV-SNP or TDX, as host or as guest
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.config.Runtime.Confidential.Enabled {
+		return nil
+	}
+	if i.config.Runtime.Confidential.Technology == "" && i.guestTechnology() == "" {
+		return fmt.Errorf("runtime.confidential requires a CPU with AMD SEV-SNP or Intel TDX, or a confidential VM; none was detected")
+	}
+	return nil
+}
+
+// Execute prepares the host or guest, or removes the preparation when runtime.confidential is turned off
+func (i *Installer) Execute(ctx context.Context) error {
+	confidential := &i.config.Runtime.Confidential
+	if !confidential.Enabled {
+		if isConfidentialConfigured() {
+			i.logger.Info("runtime.confidential.enabled is off, removing the confidential computing settings")
+			removeConfidentialConfig(i.logger)
+		}
+		return nil
+	}
+
+	if confidential.Technology != "" {
+		if err := i.prepareHost(confidential.Technology); err != nil {
+			return err
+		}
+		if confidential.RuntimeClass {
+			// Best effort: the node's credentials may not be allowed to write cluster-wide objects
+			err := utils.ApplyRuntimeClass(ctx, kubelet.KubeletKubeconfigPath, runtimeClassName, config.KataCCRuntimeHandler, config.KataCCRuntimeLabel)
+			if err != nil {
+				i.logger.Warnf("Failed to create the %s RuntimeClass, create it with cluster admin credentials: %v", runtimeClassName, err)
+			}
+		}
+		i.logger.Infof("Confidential pods run in %s Kata VMs", confidential.Technology)
+	}
+	if guest := i.guestTechnology(); guest != "" {
+		if err := i.prepareGuest(guest); err != nil {
+			return err
+		}
+		i.logger.Infof("The node is a %s confidential VM", guest)
+	}
+	return nil
+}
+
+// IsCompleted reports whether the host and guest preparation is in place, or removed when turned off
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	confidential := &i.config.Runtime.Confidential
+	if !confidential.Enabled {
+		return !isConfidentialConfigured()
+	}
+	if technology := confidential.Technology; technology != "" {
+		if !utils.FileHasContent(modprobeConfigFile, modprobeConfig(technology)) || !utilhost.IsConfidentialHostEnabled(technology) {
+			return false
+		}
+	}
+	if guest := i.guestTechnology(); guest != "" {
+		if !utils.FileHasContent(modulesLoadFile, guestModules[guest]+"\n") || !utilhost.HasConfidentialGuestDevice(guest) {
+			return false
+		}
+	}
+	return true
+}
+
+// prepareHost turns the technology on in KVM, now and at boot, and checks the Kata release supports it
+func (i *Installer) prepareHost(technology string) error {
+	if err := utilio.WriteFile(modprobeConfigFile, []byte(modprobeConfig(technology)), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", modprobeConfigFile, err)
+	}
+	module := hostModules[technology]
+	if !utilhost.IsConfidentialHostEnabled(technology) {
+		// Reloading KVM fails while VMs run; the modprobe option then applies at the next boot
+		i.logger.Infof("Reloading %s with %s", module.name, module.option)
+		if err := utils.RunSystemCommand("modprobe", "--remove", module.name); err != nil {
+			i.logger.Warnf("Failed to unload %s: %v", module.name, err)
+		}
+		if err := utils.RunSystemCommand("modprobe", module.name); err != nil {
+			return fmt.Errorf("failed to load %s: %w", module.name, err)
+		}
+	}
+	if !utilhost.IsConfidentialHostEnabled(technology) {
+		return fmt.Errorf("%s does not support %s; enable it in the firmware, boot a kernel with host support and reboot", module.name, technology)
+	}
+
+	kataConfig := filepath.Join(kataConfigDir, fmt.Sprintf("configuration-qemu-%s.toml", technology))
+	if !utils.FileExists(kataConfig) {
+		return fmt.Errorf("the Kata Containers release has no %s configuration at %s; use runtime.kata.version 3.13.0 or later", technology, kataConfig)
+	}
+	return nil
+}
+
+// prepareGuest loads the attestation driver of a confidential VM guest, now and at boot, so attestation clients
+// on the node reach its device
+func (i *Installer) prepareGuest(technology string) error {
+	module := guestModules[technology]
+	if err := utilio.WriteFile(modulesLoadFile, []byte(module+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", modulesLoadFile, err)
+	}
+	if !utilhost.HasConfidentialGuestDevice(technology) {
+		if err := utilhost.LoadKernelModule(module); err != nil {
+			return fmt.Errorf("failed to load the %s attestation driver: %w", module, err)
+		}
+	}
+	return nil
+}
+
+// guestTechnology returns the technology protecting the node itself, if it is a confidential VM
+func (i *Installer) guestTechnology() string {
+	flags, err := utilhost.CPUFlags()
+	if err != nil {
+		return ""
+	}
+	return utilhost.ConfidentialGuestTechnology(flags)
+}
+
+// modprobeConfig renders the modprobe option turning a technology on in KVM
+func modprobeConfig(technology string) string {
+	module := hostModules[technology]
+	return fmt.Sprintf("options %s %s\n", module.name, module.option)
+}
@@ -0,0 +1,19 @@
+package confidential
+
+import (
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestModprobeConfig(t *testing.T) {
+	tests := map[string]string{
+		config.ConfidentialSNP: "options kvm_amd sev_snp=1\n",
+		config.ConfidentialTDX: "options kvm_intel tdx=1\n",
+	}
+	for technology, want := range tests {
+		if got := modprobeConfig(technology); got != want {
+			t.Errorf("modprobeConfig(%q) = %q, want %q", technology, got, want)
+		}
+	}
+}
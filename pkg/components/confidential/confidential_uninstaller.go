@@ -0,0 +1,57 @@
+package confidential
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller removes the module settings of confidential computing. They apply until the next reboot; the
+// RuntimeClass is cluster-wide and stays for the other nodes.
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new confidential computing UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "ConfidentialUnInstaller"
+}
+
+// Execute removes the module settings
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isConfidentialConfigured() {
+		return nil
+	}
+	removeConfidentialConfig(u.logger)
+	u.logger.Info("Confidential computing settings removed")
+	return nil
+}
+
+// IsCompleted reports whether no module settings are left
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isConfidentialConfigured()
+}
+
+// isConfidentialConfigured reports whether any module setting is present
+func isConfidentialConfigured() bool {
+	return utils.FileExists(modprobeConfigFile) || utils.FileExists(modulesLoadFile)
+}
+
+// removeConfidentialConfig removes the module settings. Failures are logged, so cleanup continues.
+func removeConfidentialConfig(logger *logrus.Logger) {
+	for _, err := range utils.RemoveFiles([]string{modprobeConfigFile, modulesLoadFile}, logger) {
+		logger.Warnf("Failed to remove confidential computing setting: %v", err)
+	}
+}
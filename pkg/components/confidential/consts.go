@@ -0,0 +1,35 @@
+package confidential
+
+import "go.goms.io/aks/AKSFlexNode/pkg/config"
+
+const (
+	// modprobeConfigFile turns on confidential VMs in KVM across reboots
+	modprobeConfigFile = "/etc/modprobe.d/aks-flex-node-confidential.conf"
+
+	// modulesLoadFile loads the attestation driver of a confidential VM guest at boot
+	modulesLoadFile = "/etc/modules-load.d/aks-flex-node-confidential.conf"
+
+	// kataConfigDir holds the configuration files of the Kata release
+	kataConfigDir = "/opt/kata/share/defaults/kata-containers"
+
+	// runtimeClassName is the RuntimeClass confidential pods set
+	runtimeClassName = "kata-cc"
+)
+
+// kvmModule is the KVM module of a confidential computing host and its parameter turning the technology on
+type kvmModule struct {
+	name   string
+	option string
+}
+
+// hostModules are the KVM modules launching confidential VMs, by technology
+var hostModules = map[string]kvmModule{
+	config.ConfidentialSNP: {name: "kvm_amd", option: "sev_snp=1"},
+	config.ConfidentialTDX: {name: "kvm_intel", option: "tdx=1"},
+}
+
+// guestModules are the drivers creating the attestation device of a confidential VM guest, by technology
+var guestModules = map[string]string{
+	config.ConfidentialSNP: "sev-guest",
+	config.ConfidentialTDX: "tdx_guest",
+}
@@ -0,0 +1,122 @@
+package containerd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// mergedConfigHeader starts a config.toml with fragments merged into it, followed by the digest of the fragments
+const mergedConfigHeader = "# Merged with containerd configuration fragments "
+
+// configFragment is a parsed TOML fragment merged into config.toml, named after where it came from
+type configFragment struct {
+	name    string
+	content string
+	values  map[string]any
+}
+
+// configFragments returns the fragments to merge into config.toml: the .toml files of the drop-in directory in
+// lexical order, then containerd.configPatches. Neither is written by bootstrap, so customizations survive
+// reinstalls and upgrades.
+func (i *Installer) configFragments() ([]configFragment, error) {
+	paths, err := filepath.Glob(filepath.Join(containerdConfDropInDir, "*.toml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", containerdConfDropInDir, err)
+	}
+	var fragments []configFragment
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		fragment, err := parseConfigFragment(path, string(data))
+		if err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, fragment)
+	}
+	for idx, patch := range i.config.Containerd.ConfigPatches {
+		fragment, err := parseConfigFragment(fmt.Sprintf("containerd.configPatches[%d]", idx), patch)
+		if err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, fragment)
+	}
+	return fragments, nil
+}
+
+// parseConfigFragment parses a TOML fragment of config.toml
+func parseConfigFragment(name, content string) (configFragment, error) {
+	values := map[string]any{}
+	if err := toml.Unmarshal([]byte(content), &values); err != nil {
+		return configFragment{}, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return configFragment{name: name, content: content, values: values}, nil
+}
+
+// mergeConfigFragments deep-merges the fragments into the generated config.toml in order. Unlike containerd's
+// own imports, which replace a whole plugin section, tables are merged key by key, so a fragment only needs the
+// settings it changes. Other values, arrays included, are replaced. Without fragments, base is returned as is.
+func mergeConfigFragments(base string, fragments []configFragment) (string, error) {
+	if len(fragments) == 0 {
+		return base, nil
+	}
+	merged := map[string]any{}
+	if err := toml.Unmarshal([]byte(base), &merged); err != nil {
+		return "", fmt.Errorf("failed to parse the generated containerd configuration: %w", err)
+	}
+	for _, fragment := range fragments {
+		mergeTables(merged, fragment.values)
+	}
+	content, err := toml.Marshal(merged)
+	if err != nil {
+		return "", fmt.Errorf("failed to render the merged containerd configuration: %w", err)
+	}
+	return mergedConfigHeader + fragmentsDigest(fragments) + "\n" + string(content), nil
+}
+
+// fragmentsDigest identifies the names and contents of the fragments, so changing or removing one is detected
+func fragmentsDigest(fragments []configFragment) string {
+	hash := sha256.New()
+	for _, fragment := range fragments {
+		fmt.Fprintf(hash, "%s\x00%s\x00", fragment.name, fragment.content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// mergeTables merges src into dst, recursing into tables present in both
+func mergeTables(dst, src map[string]any) {
+	for key, value := range src {
+		srcTable, srcIsTable := value.(map[string]any)
+		dstTable, dstIsTable := dst[key].(map[string]any)
+		if srcIsTable && dstIsTable {
+			mergeTables(dstTable, srcTable)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// areConfigFragmentsApplied reports whether config.toml was merged with the current drop-in files and
+// containerd.configPatches, or with none when there are none
+func (i *Installer) areConfigFragmentsApplied() bool {
+	fragments, err := i.configFragments()
+	if err != nil {
+		i.logger.Debugf("Failed to read the containerd configuration fragments: %v", err)
+		return false
+	}
+	data, err := os.ReadFile(containerdConfigFile)
+	if err != nil {
+		return false
+	}
+	if len(fragments) == 0 {
+		return !strings.HasPrefix(string(data), mergedConfigHeader)
+	}
+	return strings.HasPrefix(string(data), mergedConfigHeader+fragmentsDigest(fragments)+"\n")
+}
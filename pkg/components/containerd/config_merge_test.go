@@ -0,0 +1,99 @@
+package containerd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const testBaseConfig = `version = 2
+oom_score = 0
+[plugins."io.containerd.grpc.v1.cri"]
+	sandbox_image = "mcr.microsoft.com/oss/kubernetes/pause:3.6"
+	[plugins."io.containerd.grpc.v1.cri".containerd]
+		default_runtime_name = "runc"
+		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc.options]
+			BinaryName = "/usr/bin/runc"
+			SystemdCgroup = true
+	[plugins."io.containerd.grpc.v1.cri".registry.headers]
+		X-Meta-Source-Client = ["azure/aks"]
+`
+
+func TestMergeConfigFragments(t *testing.T) {
+	var fragments []configFragment
+	for _, source := range []struct{ name, content string }{
+		{"/etc/containerd/conf.d/10-debug.toml", "[debug]\naddress = \"/run/containerd/debug.sock\"\nlevel = \"debug\"\n"},
+		{"containerd.configPatches[0]", `[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc.options]
+SystemdCgroup = false
+[plugins."io.containerd.grpc.v1.cri".registry.headers]
+X-Meta-Source-Client = ["contoso"]
+`},
+	} {
+		fragment, err := parseConfigFragment(source.name, source.content)
+		if err != nil {
+			t.Fatalf("parseConfigFragment(%s) error = %v", source.name, err)
+		}
+		fragments = append(fragments, fragment)
+	}
+
+	merged, err := mergeConfigFragments(testBaseConfig, fragments)
+	if err != nil {
+		t.Fatalf("mergeConfigFragments() error = %v", err)
+	}
+	if !strings.HasPrefix(merged, mergedConfigHeader+fragmentsDigest(fragments)+"\n") {
+		t.Errorf("merged configuration does not start with the fragments digest:\n%s", merged)
+	}
+
+	var got struct {
+		Version int `toml:"version"`
+		Debug   struct {
+			Address string `toml:"address"`
+		} `toml:"debug"`
+		Plugins struct {
+			CRI struct {
+				SandboxImage string `toml:"sandbox_image"`
+				Containerd   struct {
+					DefaultRuntimeName string `toml:"default_runtime_name"`
+					Runtimes           struct {
+						Runc struct {
+							Options struct {
+								BinaryName    string `toml:"BinaryName"`
+								SystemdCgroup bool   `toml:"SystemdCgroup"`
+							} `toml:"options"`
+						} `toml:"runc"`
+					} `toml:"runtimes"`
+				} `toml:"containerd"`
+				Registry struct {
+					Headers map[string][]string `toml:"headers"`
+				} `toml:"registry"`
+			} `toml:"io.containerd.grpc.v1.cri"`
+		} `toml:"plugins"`
+	}
+	if err := toml.Unmarshal([]byte(merged), &got); err != nil {
+		t.Fatalf("merged configuration is not valid TOML: %v\n%s", err, merged)
+	}
+	cri := got.Plugins.CRI
+	switch {
+	case got.Version != 2:
+		t.Errorf("version = %d, want 2", got.Version)
+	case got.Debug.Address != "/run/containerd/debug.sock":
+		t.Errorf("debug.address = %q, want the drop-in value", got.Debug.Address)
+	case cri.SandboxImage == "" || cri.Containerd.DefaultRuntimeName != "runc" || cri.Containerd.Runtimes.Runc.Options.BinaryName != "/usr/bin/runc":
+		t.Errorf("generated settings were lost in the merge:\n%s", merged)
+	case cri.Containerd.Runtimes.Runc.Options.SystemdCgroup:
+		t.Error("SystemdCgroup was not overridden by the patch")
+	case len(cri.Registry.Headers["X-Meta-Source-Client"]) != 1 || cri.Registry.Headers["X-Meta-Source-Client"][0] != "contoso":
+		t.Errorf("arrays should be replaced, got %v", cri.Registry.Headers["X-Meta-Source-Client"])
+	}
+}
+
+func TestMergeConfigFragmentsWithoutFragments(t *testing.T) {
+	merged, err := mergeConfigFragments(testBaseConfig, nil)
+	if err != nil {
+		t.Fatalf("mergeConfigFragments() error = %v", err)
+	}
+	if merged != testBaseConfig {
+		t.Errorf("configuration changed without fragments:\n%s", merged)
+	}
+}
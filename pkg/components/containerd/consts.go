@@ -11,7 +11,21 @@ const (
 	defaultContainerdConfigDir = "/etc/containerd"
 	containerdConfigFile       = "/etc/containerd/config.toml"
 	containerdServiceFile      = "/etc/systemd/system/containerd.service"
+	containerdServiceDropInDir = "/etc/systemd/system/containerd.service.d"
+	containerdProxyDropIn      = "/etc/systemd/system/containerd.service.d/10-proxy.conf"
 	containerdDataDir          = "/var/lib/containerd"
+	containerdSocketPath       = "/run/containerd/containerd.sock"
+	containerdHostsDir         = "/etc/containerd/certs.d"
+	containerdConfDropInDir    = "/etc/containerd/conf.d"
+	zfsSnapshotterDir          = "/var/lib/containerd/io.containerd.snapshotter.v1.zfs"
+	stargzSnapshotterService   = "stargz-snapshotter"
+	stargzSnapshotterSocket    = "/run/containerd-stargz-grpc/containerd-stargz-grpc.sock"
+	runscConfigFile            = "/etc/containerd/runsc.toml"
+	runcBinaryPath             = "/usr/bin/runc"
+	crunBinaryPath             = "/usr/bin/crun"
+	kataConfigDir              = "/opt/kata/share/defaults/kata-containers"
+	nriPluginBaseDir           = "/opt/nri"
+	nriConfigBaseDir           = "/etc/nri"
 )
 
 var containerdDirs = []string{
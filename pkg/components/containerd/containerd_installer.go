@@ -3,6 +3,7 @@ package containerd
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -29,8 +30,18 @@ func NewInstaller(logger *logrus.Logger) *Installer {
 	}
 }
 
-// Execute downloads and installs the containerd container runtime with required plugins
+// Execute downloads and installs the containerd container runtime with required plugins, or removes containerd
+// when CRI-O runs the pods
 func (i *Installer) Execute(ctx context.Context) error {
+	if i.config.UsesCRIO() {
+		uninstaller := NewUnInstaller(i.logger)
+		if uninstaller.IsCompleted(ctx) {
+			return nil
+		}
+		i.logger.Info("runtime.containerRuntime is crio, removing containerd")
+		return uninstaller.Execute(ctx)
+	}
+
 	i.logger.Info("Step 1: Preparing containerd directories")
 	if err := i.prepareContainerdDirectories(); err != nil {
 		return fmt.Errorf("failed to prepare containerd directories: %w", err)
@@ -45,7 +56,7 @@ func (i *Installer) Execute(ctx context.Context) error {
 
 	// Configure containerd service and configuration files
 	i.logger.Info("Step 3: Configuring containerd")
-	if err := i.configure(); err != nil {
+	if err := i.configure(ctx); err != nil {
 		return fmt.Errorf("containerd configuration failed: %w", err)
 	}
 	i.logger.Info("containerd configured successfully")
@@ -58,7 +69,7 @@ func (i *Installer) prepareContainerdDirectories() error {
 	for _, dir := range containerdDirs {
 		// Create directory if it doesn't exist
 		if !utils.DirectoryExists(dir) {
-			if err := utils.RunSystemCommand("mkdir", "-p", dir); err != nil {
+			if err := utilio.MkdirAll(dir, 0o755); err != nil {
 				return fmt.Errorf("failed to create containerd directory %s: %w", dir, err)
 			}
 		}
@@ -99,6 +110,7 @@ func (i *Installer) installContainerd(ctx context.Context) error {
 		return fmt.Errorf("failed to construct containerd download URL: %w", err)
 	}
 
+	var installed []string
 	for tarFile, err := range utilio.DecompressTarGzFromRemote(ctx, containerdURL) {
 		if err != nil {
 			return err
@@ -116,9 +128,11 @@ func (i *Installer) installContainerd(ctx context.Context) error {
 		if err := utilio.InstallFile(targetFilePath, tarFile.Body, 0755); err != nil {
 			return fmt.Errorf("failed to write file %q: %w", targetFilePath, err)
 		}
+		installed = append(installed, targetFilePath)
 	}
 
-	return nil
+	// Give the binaries the container runtime label on SELinux hosts, so containers get container_t
+	return utilhost.RestoreSELinuxContexts(installed...)
 }
 
 func (i *Installer) canSkipContainerdInstallation() bool {
@@ -184,14 +198,40 @@ func (i *Installer) cleanupExistingInstallation() error {
 }
 
 // configure configures containerd service and systemd unit file
-func (i *Installer) configure() error {
+func (i *Installer) configure(ctx context.Context) error {
 	// Create containerd systemd service
 	if err := i.createContainerdServiceFile(); err != nil {
 		return err
 	}
 
+	// Pull images through the node proxy
+	if err := i.configureProxy(); err != nil {
+		return err
+	}
+
+	// Restrict the containerd socket to root and the socket group
+	socketGID, err := i.configureSocketAccess()
+	if err != nil {
+		return err
+	}
+
+	// Create the directories of the NRI plugins containerd starts
+	if err := i.prepareNRIDirectories(); err != nil {
+		return err
+	}
+
+	// Configure the sandbox runtimes
+	if err := i.writeRuntimeConfigs(); err != nil {
+		return err
+	}
+
 	// Create containerd configuration
-	if err := i.createContainerdConfigFile(); err != nil {
+	if err := i.createContainerdConfigFile(ctx, socketGID); err != nil {
+		return err
+	}
+
+	// Pull through the registry mirrors
+	if err := i.configureMirrors(); err != nil {
 		return err
 	}
 
@@ -237,38 +277,128 @@ WantedBy=multi-user.target`
 	return nil
 }
 
+// configureProxy installs a drop-in passing network.proxy to containerd, or removes it when no proxy is configured
+func (i *Installer) configureProxy() error {
+	env := i.config.GetProxyEnvironment()
+	if len(env) == 0 {
+		if err := utils.RunCleanupCommand(containerdProxyDropIn); err != nil {
+			return fmt.Errorf("failed to remove containerd proxy drop-in: %w", err)
+		}
+		return nil
+	}
+	if err := utilio.MkdirAll(containerdServiceDropInDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", containerdServiceDropInDir, err)
+	}
+	if err := utilio.WriteFile(containerdProxyDropIn, []byte(utils.SystemdEnvironmentDropIn(env)), 0o644); err != nil {
+		return fmt.Errorf("failed to write containerd proxy drop-in: %w", err)
+	}
+	i.logger.Info("Configured containerd to use the proxy from network.proxy")
+	return nil
+}
+
+// isProxyConfigured reports whether the containerd proxy drop-in matches network.proxy
+func (i *Installer) isProxyConfigured() bool {
+	env := i.config.GetProxyEnvironment()
+	content, err := os.ReadFile(containerdProxyDropIn)
+	if len(env) == 0 {
+		return os.IsNotExist(err)
+	}
+	return err == nil && string(content) == utils.SystemdEnvironmentDropIn(env)
+}
+
+// configureSocketAccess creates the containerd socket group, adds the configured users to it and returns its GID
+func (i *Installer) configureSocketAccess() (int, error) {
+	access := i.config.System.Sockets.Containerd
+	gid, err := utilhost.EnsureGroup(access.Group)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set up containerd socket group: %w", err)
+	}
+	for _, user := range access.Users {
+		if err := utilhost.EnsureGroupMember(user, access.Group); err != nil {
+			return 0, fmt.Errorf("failed to grant containerd socket access: %w", err)
+		}
+		i.logger.Infof("Granted user %s access to the containerd socket", user)
+	}
+	return gid, nil
+}
+
+// grpcConfig renders the grpc section of the containerd configuration. containerd creates its socket with
+// mode 0660, owned by root and the given group.
+func grpcConfig(socketGID int) string {
+	return fmt.Sprintf(`[grpc]
+	address = "%s"
+	uid = 0
+	gid = %d`, containerdSocketPath, socketGID)
+}
+
 // createContainerdConfigFile creates the containerd configuration file
-func (i *Installer) createContainerdConfigFile() error {
+func (i *Installer) createContainerdConfigFile(ctx context.Context, socketGID int) error {
 	containerdConfig := fmt.Sprintf(`version = 2
 oom_score = 0
+%s
 [plugins."io.containerd.grpc.v1.cri"]
-	sandbox_image = "%s"
+	sandbox_image = "%s"%s
 	[plugins."io.containerd.grpc.v1.cri".containerd]
 		default_runtime_name = "runc"
-		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+%s		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
 			runtime_type = "io.containerd.runc.v2"
 		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc.options]
-			BinaryName = "/usr/bin/runc"
+			BinaryName = "%s"
 			SystemdCgroup = true
 		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.untrusted]
 			runtime_type = "io.containerd.runc.v2"
 		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.untrusted.options]
-			BinaryName = "/usr/bin/runc"
-	[plugins."io.containerd.grpc.v1.cri".cni]
+			BinaryName = "%s"
+%s	[plugins."io.containerd.grpc.v1.cri".cni]
 		bin_dir = "%s"
 		conf_dir = "%s"
 	[plugins."io.containerd.grpc.v1.cri".registry]
-		config_path = "/etc/containerd/certs.d"
+		config_path = "%s"
 	[plugins."io.containerd.grpc.v1.cri".registry.headers]
 		X-Meta-Source-Client = ["azure/aks"]
 [metrics]
-	address = "%s"`,
-		i.getPauseImage(),
+	address = "%s"%s%s%s`,
+		grpcConfig(socketGID),
+		i.config.GetImage(config.ImagePause),
+		selinuxCRIConfig(utilhost.SELinuxMode()),
+		snapshotterCRIConfig(i.config.Containerd.Snapshotter)+gcCRIConfig(&i.config.Containerd),
+		ociRuntimeBinary(&i.config.Runtime),
+		ociRuntimeBinary(&i.config.Runtime),
+		runtimeHandlersConfig(i.config),
 		cni.DefaultCNIBinDir,
 		cni.DefaultCNIConfDir,
-		i.getMetricsAddress())
+		containerdHostsDir,
+		i.getMetricsAddress(),
+		snapshotterPluginConfig(i.config.Containerd.Snapshotter),
+		gcPluginConfig(&i.config.Containerd.GC),
+		nriPluginConfig(&i.config.Containerd.NRI))
+
+	// Pull private images with the configured credentials, in a file only root can read
+	perm := os.FileMode(0644)
+	if len(i.config.Containerd.RegistryAuth) > 0 {
+		credentials, err := RegistryCredentials(ctx, i.config)
+		if err != nil {
+			return err
+		}
+		auth, err := registryAuthTOML(credentials)
+		if err != nil {
+			return err
+		}
+		containerdConfig += "\n" + auth
+		perm = 0600
+	}
 
-	if err := utilio.WriteFile(containerdConfigFile, []byte(containerdConfig), 0644); err != nil {
+	// Apply the user's customizations on top
+	fragments, err := i.configFragments()
+	if err != nil {
+		return err
+	}
+	containerdConfig, err = mergeConfigFragments(containerdConfig, fragments)
+	if err != nil {
+		return err
+	}
+
+	if err := utilio.WriteFile(containerdConfigFile, []byte(containerdConfig), perm); err != nil {
 		return err
 	}
 
@@ -277,7 +407,10 @@ oom_score = 0
 
 // Validate validates preconditions before execution
 func (i *Installer) Validate(ctx context.Context) error {
-	return nil
+	if i.config.UsesCRIO() {
+		return nil
+	}
+	return i.validateSnapshotter()
 }
 
 // GetName returns the step name
@@ -287,6 +420,10 @@ func (i *Installer) GetName() string {
 
 // IsCompleted checks if containerd and required plugins are installed
 func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if i.config.UsesCRIO() {
+		return NewUnInstaller(i.logger).IsCompleted(ctx)
+	}
+
 	// Check if containerd binaries are installed and functional
 	if !i.canSkipContainerdInstallation() {
 		return false
@@ -302,6 +439,46 @@ func (i *Installer) IsCompleted(ctx context.Context) bool {
 		return false
 	}
 
+	// Check if the proxy drop-in is up to date
+	if !i.isProxyConfigured() {
+		return false
+	}
+
+	// Check if the registry mirrors are up to date
+	if !i.areMirrorsConfigured() {
+		return false
+	}
+
+	// Check if the registry credentials are up to date
+	if !i.isRegistryAuthConfigured() {
+		return false
+	}
+
+	// Check if the configured snapshotter is selected
+	if !i.isSnapshotterConfigured() {
+		return false
+	}
+
+	// Check if the garbage collection settings are up to date
+	if !i.isGCConfigured() {
+		return false
+	}
+
+	// Check if NRI is set up as configured
+	if !i.isNRIConfigured() {
+		return false
+	}
+
+	// Check if the sandbox runtimes are registered
+	if !i.areRuntimeHandlersConfigured() {
+		return false
+	}
+
+	// Check if the configuration fragments are merged
+	if !i.areConfigFragmentsApplied() {
+		return false
+	}
+
 	// Verify systemd can parse the service file
 	if err := utils.RunSystemCommand("systemctl", "check", "containerd"); err != nil {
 		i.logger.Debugf("containerd service file is invalid: %v", err)
@@ -319,14 +496,6 @@ func (i *Installer) getContainerdVersion() string {
 	return "1.7.20"
 }
 
-func (i *Installer) getPauseImage() string {
-	if i.config.Containerd.PauseImage != "" {
-		return i.config.Containerd.PauseImage
-	}
-	// Default pause image
-	return "mcr.microsoft.com/oss/kubernetes/pause:3.6"
-}
-
 func (i *Installer) getMetricsAddress() string {
 	if i.config.Containerd.MetricsAddress != "" {
 		return i.config.Containerd.MetricsAddress
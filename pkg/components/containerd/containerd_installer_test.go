@@ -0,0 +1,30 @@
+package containerd
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+func TestGRPCConfig(t *testing.T) {
+	var got struct {
+		GRPC struct {
+			Address string `toml:"address"`
+			UID     *int   `toml:"uid"`
+			GID     *int   `toml:"gid"`
+		} `toml:"grpc"`
+	}
+	content := grpcConfig(996)
+	if err := toml.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("grpc section is not valid TOML: %v\n%s", err, content)
+	}
+	if got.GRPC.Address != containerdSocketPath {
+		t.Errorf("address = %q, want %q", got.GRPC.Address, containerdSocketPath)
+	}
+	if got.GRPC.UID == nil || *got.GRPC.UID != 0 {
+		t.Errorf("uid = %v, want the socket owned by root", got.GRPC.UID)
+	}
+	if got.GRPC.GID == nil || *got.GRPC.GID != 996 {
+		t.Errorf("gid = %v, want the socket group 996", got.GRPC.GID)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/sirupsen/logrus"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/installed_files"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils"
 )
 
@@ -118,6 +119,7 @@ func (u *UnInstaller) removeSystemdServices() error {
 
 	serviceFiles := []string{
 		containerdServiceFile,
+		containerdProxyDropIn,
 	}
 
 	if fileErrors := utils.RemoveFiles(serviceFiles, u.logger); len(fileErrors) > 0 {
@@ -139,10 +141,9 @@ func (u *UnInstaller) removeSystemdServices() error {
 func (u *UnInstaller) cleanupContainerdFiles() error {
 	u.logger.Info("Cleaning up containerd configuration and data files")
 
-	containerdDirectories := []string{
-		containerdDataDir,
-		defaultContainerdConfigDir,
-	}
+	// containerd creates its data directory itself; the others are kept if they existed before bootstrap
+	containerdDirectories := append([]string{containerdDataDir},
+		installed_files.CreatedPaths(defaultContainerdConfigDir, nriPluginBaseDir, nriConfigBaseDir)...)
 
 	// Remove directories recursively
 	if dirErrors := utils.RemoveDirectories(containerdDirectories, u.logger); len(dirErrors) > 0 {
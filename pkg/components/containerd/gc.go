@@ -0,0 +1,83 @@
+package containerd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// containerdGCSettings are the settings of containerd's garbage collection scheduler, as read back from config.toml
+type containerdGCSettings struct {
+	PauseThreshold    float64 `toml:"pause_threshold,omitempty"`
+	DeletionThreshold int     `toml:"deletion_threshold,omitempty"`
+	MutationThreshold int     `toml:"mutation_threshold,omitempty"`
+	ScheduleDelay     string  `toml:"schedule_delay,omitempty"`
+	StartupDelay      string  `toml:"startup_delay,omitempty"`
+}
+
+// gcCRIConfig returns the settings of the CRI containerd section that reduce the disk images take
+func gcCRIConfig(cfg *config.ContainerdConfig) string {
+	if cfg.DiscardUnpackedLayers {
+		return "\t\tdiscard_unpacked_layers = true\n"
+	}
+	return ""
+}
+
+// gcPluginConfig returns the garbage collection scheduler section holding the configured containerd.gc settings,
+// or nothing when containerd's defaults are kept
+func gcPluginConfig(cfg *config.ContainerdGCConfig) string {
+	var sb strings.Builder
+	if cfg.PauseThreshold != 0 {
+		fmt.Fprintf(&sb, "\tpause_threshold = %g\n", cfg.PauseThreshold)
+	}
+	if cfg.DeletionThreshold != 0 {
+		fmt.Fprintf(&sb, "\tdeletion_threshold = %d\n", cfg.DeletionThreshold)
+	}
+	if cfg.MutationThreshold != 0 {
+		fmt.Fprintf(&sb, "\tmutation_threshold = %d\n", cfg.MutationThreshold)
+	}
+	if cfg.ScheduleDelay != "" {
+		fmt.Fprintf(&sb, "\tschedule_delay = %q\n", cfg.ScheduleDelay)
+	}
+	if cfg.StartupDelay != "" {
+		fmt.Fprintf(&sb, "\tstartup_delay = %q\n", cfg.StartupDelay)
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "\n[plugins.\"io.containerd.gc.v1.scheduler\"]\n" + strings.TrimSuffix(sb.String(), "\n")
+}
+
+// isGCConfigured reports whether config.toml holds the configured garbage collection and layer settings
+func (i *Installer) isGCConfigured() bool {
+	data, err := os.ReadFile(containerdConfigFile)
+	if err != nil {
+		return false
+	}
+	var file struct {
+		Plugins struct {
+			GC  containerdGCSettings `toml:"io.containerd.gc.v1.scheduler"`
+			CRI struct {
+				Containerd struct {
+					DiscardUnpackedLayers bool `toml:"discard_unpacked_layers"`
+				} `toml:"containerd"`
+			} `toml:"io.containerd.grpc.v1.cri"`
+		} `toml:"plugins"`
+	}
+	if err := toml.Unmarshal(data, &file); err != nil {
+		return false
+	}
+	gc := i.config.Containerd.GC
+	want := containerdGCSettings{
+		PauseThreshold:    gc.PauseThreshold,
+		DeletionThreshold: gc.DeletionThreshold,
+		MutationThreshold: gc.MutationThreshold,
+		ScheduleDelay:     gc.ScheduleDelay,
+		StartupDelay:      gc.StartupDelay,
+	}
+	return file.Plugins.GC == want && file.Plugins.CRI.Containerd.DiscardUnpackedLayers == i.config.Containerd.DiscardUnpackedLayers
+}
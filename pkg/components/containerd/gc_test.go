@@ -0,0 +1,49 @@
+package containerd
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestGCConfig(t *testing.T) {
+	if got := gcPluginConfig(&config.ContainerdGCConfig{}); got != "" {
+		t.Errorf("gcPluginConfig() with containerd's defaults = %q, want none", got)
+	}
+	if got := gcCRIConfig(&config.ContainerdConfig{}); got != "" {
+		t.Errorf("gcCRIConfig() without discardUnpackedLayers = %q, want none", got)
+	}
+
+	cfg := config.ContainerdConfig{
+		DiscardUnpackedLayers: true,
+		GC:                    config.ContainerdGCConfig{PauseThreshold: 0.05, DeletionThreshold: 10, ScheduleDelay: "10ms"},
+	}
+	content := `version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+	[plugins."io.containerd.grpc.v1.cri".containerd]
+` + gcCRIConfig(&cfg) + `[metrics]
+	address = "0.0.0.0:10257"` + gcPluginConfig(&cfg.GC)
+
+	var got struct {
+		Plugins struct {
+			GC  containerdGCSettings `toml:"io.containerd.gc.v1.scheduler"`
+			CRI struct {
+				Containerd struct {
+					DiscardUnpackedLayers bool `toml:"discard_unpacked_layers"`
+				} `toml:"containerd"`
+			} `toml:"io.containerd.grpc.v1.cri"`
+		} `toml:"plugins"`
+	}
+	if err := toml.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("configuration is not valid TOML: %v\n%s", err, content)
+	}
+	want := containerdGCSettings{PauseThreshold: 0.05, DeletionThreshold: 10, ScheduleDelay: "10ms"}
+	if got.Plugins.GC != want {
+		t.Errorf("gc scheduler settings = %+v, want %+v", got.Plugins.GC, want)
+	}
+	if !got.Plugins.CRI.Containerd.DiscardUnpackedLayers {
+		t.Errorf("discard_unpacked_layers not set")
+	}
+}
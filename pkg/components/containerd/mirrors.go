@@ -0,0 +1,99 @@
+package containerd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// hostsFileHeader marks the hosts.toml files written from containerd.mirrors
+const hostsFileHeader = "# Generated by aks-flex-node from containerd.mirrors\n"
+
+// configureMirrors writes a hosts.toml per registry in containerd.mirrors and removes those of registries no
+// longer listed. containerd reads the files on every pull, so changes apply without a restart.
+func (i *Installer) configureMirrors() error {
+	for _, registry := range staleMirrors(i.config.Containerd.Mirrors) {
+		if err := os.RemoveAll(filepath.Join(containerdHostsDir, registry)); err != nil {
+			return fmt.Errorf("failed to remove the mirrors of %s: %w", registry, err)
+		}
+	}
+	for _, mirror := range i.config.Containerd.Mirrors {
+		path := filepath.Join(containerdHostsDir, mirror.Registry, "hosts.toml")
+		if err := utilio.WriteFile(path, []byte(hostsTOML(&mirror)), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		i.logger.Infof("Pulling %s through %s", mirror.Registry, strings.Join(mirror.Endpoints, ", "))
+	}
+	return nil
+}
+
+// areMirrorsConfigured reports whether the hosts.toml files match containerd.mirrors
+func (i *Installer) areMirrorsConfigured() bool {
+	if len(staleMirrors(i.config.Containerd.Mirrors)) > 0 {
+		return false
+	}
+	for _, mirror := range i.config.Containerd.Mirrors {
+		content, err := os.ReadFile(filepath.Join(containerdHostsDir, mirror.Registry, "hosts.toml"))
+		if err != nil || string(content) != hostsTOML(&mirror) {
+			return false
+		}
+	}
+	return true
+}
+
+// staleMirrors returns the registries with a generated hosts.toml that containerd.mirrors no longer lists.
+// Files written by hand are left alone.
+func staleMirrors(mirrors []config.RegistryMirrorConfig) []string {
+	entries, err := os.ReadDir(containerdHostsDir)
+	if err != nil {
+		return nil
+	}
+	configured := make(map[string]bool, len(mirrors))
+	for _, mirror := range mirrors {
+		configured[mirror.Registry] = true
+	}
+	var stale []string
+	for _, entry := range entries {
+		if !entry.IsDir() || configured[entry.Name()] {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(containerdHostsDir, entry.Name(), "hosts.toml"))
+		if err == nil && strings.HasPrefix(string(content), hostsFileHeader) {
+			stale = append(stale, entry.Name())
+		}
+	}
+	return stale
+}
+
+// hostsTOML renders the hosts.toml of a registry. The endpoints only pull and resolve, so pushes still go to
+// the registry. With skipUpstream, the first endpoint replaces the registry as the server containerd falls
+// back to.
+func hostsTOML(mirror *config.RegistryMirrorConfig) string {
+	var sb strings.Builder
+	sb.WriteString(hostsFileHeader)
+	switch {
+	case mirror.SkipUpstream:
+		fmt.Fprintf(&sb, "server = %q\n", mirror.Endpoints[0])
+	case mirror.Registry == "docker.io":
+		sb.WriteString("server = \"https://registry-1.docker.io\"\n")
+	case mirror.Registry != config.RegistryMirrorDefault:
+		fmt.Fprintf(&sb, "server = %q\n", "https://"+mirror.Registry)
+	}
+	for _, endpoint := range mirror.Endpoints {
+		fmt.Fprintf(&sb, "\n[host.%q]\n  capabilities = [\"pull\", \"resolve\"]\n", endpoint)
+		if mirror.CAFile != "" {
+			fmt.Fprintf(&sb, "  ca = %q\n", mirror.CAFile)
+		}
+		if mirror.SkipVerify {
+			sb.WriteString("  skip_verify = true\n")
+		}
+		if mirror.OverridePath {
+			sb.WriteString("  override_path = true\n")
+		}
+	}
+	return sb.String()
+}
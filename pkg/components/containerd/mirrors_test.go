@@ -0,0 +1,73 @@
+package containerd
+
+import (
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestHostsTOML(t *testing.T) {
+	tests := []struct {
+		name   string
+		mirror config.RegistryMirrorConfig
+		want   string
+	}{
+		{
+			name:   "docker hub cache",
+			mirror: config.RegistryMirrorConfig{Registry: "docker.io", Endpoints: []string{"https://cache.contoso.local"}},
+			want: hostsFileHeader + `server = "https://registry-1.docker.io"
+
+[host."https://cache.contoso.local"]
+  capabilities = ["pull", "resolve"]
+`,
+		},
+		{
+			name: "air-gapped mcr with private CA",
+			mirror: config.RegistryMirrorConfig{
+				Registry:     "mcr.microsoft.com",
+				Endpoints:    []string{"https://harbor.contoso.local/v2/mcr-proxy", "http://10.0.0.5:5000"},
+				SkipUpstream: true,
+				OverridePath: true,
+				CAFile:       "/etc/ssl/certs/contoso.pem",
+			},
+			want: hostsFileHeader + `server = "https://harbor.contoso.local/v2/mcr-proxy"
+
+[host."https://harbor.contoso.local/v2/mcr-proxy"]
+  capabilities = ["pull", "resolve"]
+  ca = "/etc/ssl/certs/contoso.pem"
+  override_path = true
+
+[host."http://10.0.0.5:5000"]
+  capabilities = ["pull", "resolve"]
+  ca = "/etc/ssl/certs/contoso.pem"
+  override_path = true
+`,
+		},
+		{
+			name:   "default for every registry",
+			mirror: config.RegistryMirrorConfig{Registry: "_default", Endpoints: []string{"https://proxy.contoso.local"}, SkipVerify: true},
+			want: hostsFileHeader + `
+[host."https://proxy.contoso.local"]
+  capabilities = ["pull", "resolve"]
+  skip_verify = true
+`,
+		},
+		{
+			name:   "registry with port",
+			mirror: config.RegistryMirrorConfig{Registry: "registry.contoso.local:5000", Endpoints: []string{"https://replica.contoso.local"}},
+			want: hostsFileHeader + `server = "https://registry.contoso.local:5000"
+
+[host."https://replica.contoso.local"]
+  capabilities = ["pull", "resolve"]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hostsTOML(&tt.mirror); got != tt.want {
+				t.Errorf("hostsTOML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
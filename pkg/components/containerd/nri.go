@@ -0,0 +1,92 @@
+package containerd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// nriSettings are the settings of containerd's NRI plugin, as read back from config.toml
+type nriSettings struct {
+	Disable             bool   `toml:"disable"`
+	DisableConnections  bool   `toml:"disable_connections"`
+	PluginPath          string `toml:"plugin_path"`
+	PluginConfigPath    string `toml:"plugin_config_path"`
+	SocketPath          string `toml:"socket_path"`
+	RegistrationTimeout string `toml:"plugin_registration_timeout"`
+	RequestTimeout      string `toml:"plugin_request_timeout"`
+}
+
+// nriPluginConfig returns the section enabling NRI, or nothing when containerd.nri is off, which keeps
+// containerd's default
+func nriPluginConfig(cfg *config.NRIConfig) string {
+	if !cfg.Enabled {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `
+[plugins."io.containerd.nri.v1.nri"]
+	disable = false
+	disable_connections = %t
+	plugin_path = %q
+	plugin_config_path = %q
+	socket_path = %q`, cfg.DisableConnections, cfg.PluginDir, cfg.PluginConfigDir, cfg.SocketPath)
+	if cfg.RegistrationTimeout != "" {
+		fmt.Fprintf(&sb, "\n\tplugin_registration_timeout = %q", cfg.RegistrationTimeout)
+	}
+	if cfg.RequestTimeout != "" {
+		fmt.Fprintf(&sb, "\n\tplugin_request_timeout = %q", cfg.RequestTimeout)
+	}
+	return sb.String()
+}
+
+// prepareNRIDirectories creates the directories NRI plugins and their configuration are installed in
+func (i *Installer) prepareNRIDirectories() error {
+	nri := &i.config.Containerd.NRI
+	if !nri.Enabled {
+		return nil
+	}
+	for _, dir := range []string{nri.PluginDir, nri.PluginConfigDir} {
+		if err := utilio.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create NRI directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// isNRIConfigured reports whether config.toml enables NRI as configured, and the plugin directories exist
+func (i *Installer) isNRIConfigured() bool {
+	nri := &i.config.Containerd.NRI
+	if !nri.Enabled {
+		// Left to containerd's default or to configuration fragments
+		return true
+	}
+	data, err := os.ReadFile(containerdConfigFile)
+	if err != nil {
+		return false
+	}
+	var file struct {
+		Plugins struct {
+			NRI *nriSettings `toml:"io.containerd.nri.v1.nri"`
+		} `toml:"plugins"`
+	}
+	if err := toml.Unmarshal(data, &file); err != nil {
+		return false
+	}
+	want := nriSettings{
+		DisableConnections:  nri.DisableConnections,
+		PluginPath:          nri.PluginDir,
+		PluginConfigPath:    nri.PluginConfigDir,
+		SocketPath:          nri.SocketPath,
+		RegistrationTimeout: nri.RegistrationTimeout,
+		RequestTimeout:      nri.RequestTimeout,
+	}
+	return file.Plugins.NRI != nil && *file.Plugins.NRI == want &&
+		utils.DirectoryExists(nri.PluginDir) && utils.DirectoryExists(nri.PluginConfigDir)
+}
@@ -0,0 +1,43 @@
+package containerd
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestNRIPluginConfig(t *testing.T) {
+	if got := nriPluginConfig(&config.NRIConfig{}); got != "" {
+		t.Errorf("nriPluginConfig() with NRI off = %q, want none", got)
+	}
+
+	cfg := config.NRIConfig{
+		Enabled:            true,
+		PluginDir:          config.DefaultNRIPluginDir,
+		PluginConfigDir:    config.DefaultNRIPluginConfigDir,
+		SocketPath:         "/var/run/nri/nri.sock",
+		DisableConnections: true,
+		RequestTimeout:     "3s",
+	}
+	content := "version = 2\n[metrics]\n\taddress = \"0.0.0.0:10257\"" + nriPluginConfig(&cfg)
+	var got struct {
+		Plugins struct {
+			NRI nriSettings `toml:"io.containerd.nri.v1.nri"`
+		} `toml:"plugins"`
+	}
+	if err := toml.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("configuration is not valid TOML: %v\n%s", err, content)
+	}
+	want := nriSettings{
+		DisableConnections: true,
+		PluginPath:         config.DefaultNRIPluginDir,
+		PluginConfigPath:   config.DefaultNRIPluginConfigDir,
+		SocketPath:         "/var/run/nri/nri.sock",
+		RequestTimeout:     "3s",
+	}
+	if got.Plugins.NRI != want {
+		t.Errorf("NRI settings = %+v, want %+v", got.Plugins.NRI, want)
+	}
+}
@@ -0,0 +1,203 @@
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/auth"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// acrExchangeTimeout bounds the exchange of an Entra token for an ACR refresh token
+const acrExchangeTimeout = 30 * time.Second
+
+// RegistryCredential is the auth entry containerd's CRI plugin holds for one registry. CRI-O's auth file takes
+// the same credentials.
+type RegistryCredential struct {
+	Username      string `toml:"username,omitempty"`
+	Password      string `toml:"password,omitempty"`
+	IdentityToken string `toml:"identitytoken,omitempty"`
+}
+
+// containerdConfigRegistry is the part of config.toml holding the registry credentials
+type containerdConfigRegistry struct {
+	Plugins struct {
+		CRI struct {
+			Registry struct {
+				Configs map[string]struct {
+					Auth RegistryCredential `toml:"auth"`
+				} `toml:"configs"`
+			} `toml:"registry"`
+		} `toml:"io.containerd.grpc.v1.cri"`
+	} `toml:"plugins"`
+}
+
+// isRegistryAuthConfigured reports whether config.toml holds the configured credentials and is private when it
+// does. Tokens obtained with the node identity change on every exchange, so only their presence is checked.
+func (i *Installer) isRegistryAuthConfigured() bool {
+	data, err := os.ReadFile(containerdConfigFile)
+	if err != nil {
+		return false
+	}
+	var file containerdConfigRegistry
+	if err := toml.Unmarshal(data, &file); err != nil {
+		return false
+	}
+	configs := file.Plugins.CRI.Registry.Configs
+	if len(configs) != len(i.config.Containerd.RegistryAuth) {
+		return false
+	}
+	if len(configs) == 0 {
+		return true
+	}
+	if info, err := os.Stat(containerdConfigFile); err != nil || info.Mode().Perm() != 0o600 {
+		return false
+	}
+	for _, registryAuth := range i.config.Containerd.RegistryAuth {
+		entry, ok := configs[registryAuth.Registry]
+		if !ok {
+			return false
+		}
+		if registryAuth.ManagedIdentity {
+			if entry.Auth.IdentityToken == "" {
+				return false
+			}
+			continue
+		}
+		if entry.Auth != staticRegistryCredential(&registryAuth) {
+			return false
+		}
+	}
+	return true
+}
+
+// RefreshRegistryAuth renews the ACR refresh tokens obtained with the node identity, which expire after a few
+// hours, and restarts containerd so its CRI plugin picks them up. It does nothing when no registry uses the node
+// identity.
+func RefreshRegistryAuth(ctx context.Context, cfg *config.Config, logger *logrus.Logger) error {
+	if !cfg.RegistryAuthUsesNodeIdentity() {
+		return nil
+	}
+	i := &Installer{config: cfg, logger: logger}
+	socketGID, err := utilhost.EnsureGroup(cfg.System.Sockets.Containerd.Group)
+	if err != nil {
+		return fmt.Errorf("failed to look up the containerd socket group: %w", err)
+	}
+	if err := i.createContainerdConfigFile(ctx, socketGID); err != nil {
+		return err
+	}
+	if !utils.IsServiceActive("containerd") {
+		return nil
+	}
+	if err := utils.RestartService("containerd"); err != nil {
+		return fmt.Errorf("failed to restart containerd: %w", err)
+	}
+	logger.Info("Registry credentials refreshed")
+	return nil
+}
+
+// RegistryCredentials resolves the credentials of containerd.registryAuth, exchanging node identity tokens for
+// ACR refresh tokens
+func RegistryCredentials(ctx context.Context, cfg *config.Config) (map[string]RegistryCredential, error) {
+	credentials := make(map[string]RegistryCredential, len(cfg.Containerd.RegistryAuth))
+	for _, registryAuth := range cfg.Containerd.RegistryAuth {
+		if !registryAuth.ManagedIdentity {
+			credentials[registryAuth.Registry] = staticRegistryCredential(&registryAuth)
+			continue
+		}
+		token, err := acrRefreshToken(ctx, cfg, registryAuth.Registry)
+		if err != nil {
+			return nil, err
+		}
+		credentials[registryAuth.Registry] = RegistryCredential{IdentityToken: token}
+	}
+	return credentials, nil
+}
+
+// staticRegistryCredential returns the credential given directly in the configuration
+func staticRegistryCredential(registryAuth *config.RegistryAuthConfig) RegistryCredential {
+	return RegistryCredential{
+		Username:      registryAuth.Username,
+		Password:      registryAuth.Password,
+		IdentityToken: registryAuth.IdentityToken,
+	}
+}
+
+// registryAuthTOML renders the CRI registry configs holding the given credentials. containerd replaces a whole
+// plugin section with that of an imported file, so they are appended to config.toml rather than imported.
+func registryAuthTOML(credentials map[string]RegistryCredential) (string, error) {
+	registries := make([]string, 0, len(credentials))
+	for registry := range credentials {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+
+	var sb strings.Builder
+	for _, registry := range registries {
+		auth, err := toml.Marshal(credentials[registry])
+		if err != nil {
+			return "", fmt.Errorf("failed to render the credentials of %s: %w", registry, err)
+		}
+		fmt.Fprintf(&sb, "\n[plugins.\"io.containerd.grpc.v1.cri\".registry.configs.%q.auth]\n%s", registry, auth)
+	}
+	return sb.String(), nil
+}
+
+// acrRefreshToken exchanges an Entra token of the node identity for a refresh token of an Azure Container
+// Registry, which containerd then trades for access tokens on each pull
+func acrRefreshToken(ctx context.Context, cfg *config.Config, registry string) (string, error) {
+	authProvider := auth.NewAuthProvider()
+	cred, err := authProvider.NodeCredential(cfg)
+	if err != nil {
+		return "", err
+	}
+	accessToken, err := authProvider.GetAccessToken(ctx, cred)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a token for %s: %w", registry, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, acrExchangeTimeout)
+	defer cancel()
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {registry},
+		"access_token": {accessToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+registry+"/oauth2/exchange", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create the token exchange request for %s: %w", registry, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange a token with %s: %w", registry, err)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	_ = resp.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the token exchange response of %s: %w", registry, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token exchange with %s failed with HTTP %d: %s", registry, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var exchange struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &exchange); err != nil || exchange.RefreshToken == "" {
+		return "", fmt.Errorf("token exchange with %s returned no refresh token", registry)
+	}
+	return exchange.RefreshToken, nil
+}
@@ -0,0 +1,40 @@
+package containerd
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+func TestRegistryAuthTOML(t *testing.T) {
+	credentials := map[string]RegistryCredential{
+		"registry.contoso.local:5000": {Username: "puller", Password: `se"cret`},
+		"contoso.azurecr.io":          {IdentityToken: "refresh-token"},
+	}
+	auth, err := registryAuthTOML(credentials)
+	if err != nil {
+		t.Fatalf("registryAuthTOML() error = %v", err)
+	}
+
+	// The sections are appended after the registry section of config.toml
+	content := `version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+	[plugins."io.containerd.grpc.v1.cri".registry]
+		config_path = "/etc/containerd/certs.d"
+[metrics]
+	address = "0.0.0.0:10257"
+` + auth
+	var file containerdConfigRegistry
+	if err := toml.Unmarshal([]byte(content), &file); err != nil {
+		t.Fatalf("config with credentials is not valid TOML: %v\n%s", err, content)
+	}
+	configs := file.Plugins.CRI.Registry.Configs
+	if len(configs) != len(credentials) {
+		t.Fatalf("got %d registry configs, want %d:\n%s", len(configs), len(credentials), content)
+	}
+	for registry, want := range credentials {
+		if got := configs[registry].Auth; got != want {
+			t.Errorf("auth of %s = %+v, want %+v", registry, got, want)
+		}
+	}
+}
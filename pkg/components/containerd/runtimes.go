@@ -0,0 +1,131 @@
+package containerd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// ociRuntimeBinary returns the low-level runtime the runc handlers run containers with
+func ociRuntimeBinary(cfg *config.RuntimeConfig) string {
+	if cfg.OCIRuntime == config.OCIRuntimeCrun {
+		return crunBinaryPath
+	}
+	return runcBinaryPath
+}
+
+// runtimeHandlersConfig returns the CRI runtime sections of the sandbox runtimes in runtime, which pods select
+// through the handler of their RuntimeClass
+func runtimeHandlersConfig(cfg *config.Config) string {
+	var sb strings.Builder
+	if cfg.Runtime.GVisor.Enabled {
+		fmt.Fprintf(&sb, `		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.%[1]s]
+			runtime_type = "io.containerd.runsc.v1"
+		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.%[1]s.options]
+			TypeUrl = "io.containerd.runsc.v1.options"
+			ConfigPath = %[2]q
+`, config.GVisorRuntimeHandler, runscConfigFile)
+	}
+	if cfg.Runtime.Kata.Enabled {
+		// Devices of privileged pods belong to the host, not to the pod VM
+		fmt.Fprintf(&sb, `		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.%[1]s]
+			runtime_type = "io.containerd.kata.v2"
+			privileged_without_host_devices = true
+			pod_annotations = ["io.katacontainers.*"]
+		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.%[1]s.options]
+			ConfigPath = %[2]q
+`, config.KataRuntimeHandler, kataConfigPath(&cfg.Runtime.Kata))
+	}
+	if isKataCCEnabled(cfg) {
+		// Confidential VMs run the release's QEMU build and firmware for the technology
+		fmt.Fprintf(&sb, `		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.%[1]s]
+			runtime_type = "io.containerd.kata.v2"
+			privileged_without_host_devices = true
+			pod_annotations = ["io.katacontainers.*"]
+		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.%[1]s.options]
+			ConfigPath = %[2]q
+`, config.KataCCRuntimeHandler, kataCCConfigPath(&cfg.Runtime.Confidential))
+	}
+	return sb.String()
+}
+
+// runscConfig renders the runsc options containerd's gVisor shim passes to runsc
+func runscConfig(cfg *config.GVisorConfig) string {
+	return fmt.Sprintf("[runsc_config]\n  platform = %q\n", cfg.Platform)
+}
+
+// kataConfigPath returns the configuration file of the Kata release for the configured hypervisor
+func kataConfigPath(cfg *config.KataConfig) string {
+	return fmt.Sprintf("%s/configuration-%s.toml", kataConfigDir, cfg.Hypervisor)
+}
+
+// isKataCCEnabled reports whether the node runs confidential Kata VMs
+func isKataCCEnabled(cfg *config.Config) bool {
+	return cfg.Runtime.Kata.Enabled && cfg.Runtime.Confidential.Enabled && cfg.Runtime.Confidential.Technology != ""
+}
+
+// kataCCConfigPath returns the configuration file of the Kata release for confidential VMs of the technology
+func kataCCConfigPath(cfg *config.ConfidentialConfig) string {
+	return fmt.Sprintf("%s/configuration-qemu-%s.toml", kataConfigDir, cfg.Technology)
+}
+
+// writeRuntimeConfigs writes the configuration files of the sandbox runtimes, removing those of runtimes that
+// are turned off
+func (i *Installer) writeRuntimeConfigs() error {
+	if !i.config.Runtime.GVisor.Enabled {
+		return utils.RunCleanupCommand(runscConfigFile)
+	}
+	if err := utilio.WriteFile(runscConfigFile, []byte(runscConfig(&i.config.Runtime.GVisor)), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", runscConfigFile, err)
+	}
+	return nil
+}
+
+// areRuntimeHandlersConfigured reports whether config.toml runs the default handler with the configured OCI
+// runtime and registers exactly the enabled sandbox runtimes, and their configuration files are up to date
+func (i *Installer) areRuntimeHandlersConfigured() bool {
+	data, err := os.ReadFile(containerdConfigFile)
+	if err != nil {
+		return false
+	}
+	var file struct {
+		Plugins struct {
+			CRI struct {
+				Containerd struct {
+					Runtimes map[string]struct {
+						Options map[string]any `toml:"options"`
+					} `toml:"runtimes"`
+				} `toml:"containerd"`
+			} `toml:"io.containerd.grpc.v1.cri"`
+		} `toml:"plugins"`
+	}
+	if err := toml.Unmarshal(data, &file); err != nil {
+		return false
+	}
+	if file.Plugins.CRI.Containerd.Runtimes["runc"].Options["BinaryName"] != ociRuntimeBinary(&i.config.Runtime) {
+		return false
+	}
+	gvisor := &i.config.Runtime.GVisor
+	if _, ok := file.Plugins.CRI.Containerd.Runtimes[config.GVisorRuntimeHandler]; ok != gvisor.Enabled {
+		return false
+	}
+	kata, ok := file.Plugins.CRI.Containerd.Runtimes[config.KataRuntimeHandler]
+	if ok != i.config.Runtime.Kata.Enabled || ok && kata.Options["ConfigPath"] != kataConfigPath(&i.config.Runtime.Kata) {
+		return false
+	}
+	kataCC, ok := file.Plugins.CRI.Containerd.Runtimes[config.KataCCRuntimeHandler]
+	if ok != isKataCCEnabled(i.config) || ok && kataCC.Options["ConfigPath"] != kataCCConfigPath(&i.config.Runtime.Confidential) {
+		return false
+	}
+	if gvisor.Enabled {
+		content, err := os.ReadFile(runscConfigFile)
+		return err == nil && string(content) == runscConfig(gvisor)
+	}
+	return true
+}
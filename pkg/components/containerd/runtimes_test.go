@@ -0,0 +1,69 @@
+package containerd
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestRuntimeHandlersConfig(t *testing.T) {
+	cfg := &config.Config{Runtime: config.RuntimeConfig{
+		GVisor:       config.GVisorConfig{Enabled: true, Platform: "systrap"},
+		Kata:         config.KataConfig{Enabled: true, Hypervisor: "clh"},
+		Confidential: config.ConfidentialConfig{Enabled: true, Technology: "snp"},
+	}}
+	content := `version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+	[plugins."io.containerd.grpc.v1.cri".containerd]
+		[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+			runtime_type = "io.containerd.runc.v2"
+` + runtimeHandlersConfig(cfg) + `	[plugins."io.containerd.grpc.v1.cri".cni]
+		bin_dir = "/opt/cni/bin"`
+
+	var got struct {
+		Plugins struct {
+			CRI struct {
+				Containerd struct {
+					Runtimes map[string]struct {
+						RuntimeType string         `toml:"runtime_type"`
+						Options     map[string]any `toml:"options"`
+					} `toml:"runtimes"`
+				} `toml:"containerd"`
+			} `toml:"io.containerd.grpc.v1.cri"`
+		} `toml:"plugins"`
+	}
+	if err := toml.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("configuration is not valid TOML: %v\n%s", err, content)
+	}
+	runsc, ok := got.Plugins.CRI.Containerd.Runtimes[config.GVisorRuntimeHandler]
+	if !ok || runsc.RuntimeType != "io.containerd.runsc.v1" || runsc.Options["ConfigPath"] != runscConfigFile {
+		t.Errorf("runsc runtime = %+v", runsc)
+	}
+	kata, ok := got.Plugins.CRI.Containerd.Runtimes[config.KataRuntimeHandler]
+	if !ok || kata.RuntimeType != "io.containerd.kata.v2" || kata.Options["ConfigPath"] != "/opt/kata/share/defaults/kata-containers/configuration-clh.toml" {
+		t.Errorf("kata runtime = %+v", kata)
+	}
+	kataCC, ok := got.Plugins.CRI.Containerd.Runtimes[config.KataCCRuntimeHandler]
+	if !ok || kataCC.Options["ConfigPath"] != "/opt/kata/share/defaults/kata-containers/configuration-qemu-snp.toml" {
+		t.Errorf("kata-cc runtime = %+v", kataCC)
+	}
+	if _, ok := got.Plugins.CRI.Containerd.Runtimes["runc"]; !ok {
+		t.Errorf("runc runtime missing")
+	}
+
+	if got := ociRuntimeBinary(&config.RuntimeConfig{OCIRuntime: config.OCIRuntimeCrun}); got != crunBinaryPath {
+		t.Errorf("ociRuntimeBinary(crun) = %q, want %q", got, crunBinaryPath)
+	}
+	if got := ociRuntimeBinary(&config.RuntimeConfig{}); got != runcBinaryPath {
+		t.Errorf("ociRuntimeBinary() = %q, want %q", got, runcBinaryPath)
+	}
+
+	if got := runtimeHandlersConfig(&config.Config{}); got != "" {
+		t.Errorf("runtimeHandlersConfig() without sandbox runtimes = %q, want none", got)
+	}
+	if got := runscConfig(&cfg.Runtime.GVisor); got != "[runsc_config]\n  platform = \"systrap\"\n" {
+		t.Errorf("runscConfig() = %q", got)
+	}
+}
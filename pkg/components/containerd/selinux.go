@@ -0,0 +1,11 @@
+package containerd
+
+import "go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+
+// selinuxCRIConfig labels containers with SELinux contexts unless SELinux is disabled
+func selinuxCRIConfig(mode string) string {
+	if mode == utilhost.SELinuxDisabled {
+		return ""
+	}
+	return "\n\tenable_selinux = true"
+}
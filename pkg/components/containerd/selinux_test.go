@@ -0,0 +1,39 @@
+package containerd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+func TestSELinuxCRIConfig(t *testing.T) {
+	for mode, want := range map[string]bool{
+		utilhost.SELinuxEnforcing:  true,
+		utilhost.SELinuxPermissive: true,
+		utilhost.SELinuxDisabled:   false,
+	} {
+		t.Run(mode, func(t *testing.T) {
+			content := fmt.Sprintf(`[plugins."io.containerd.grpc.v1.cri"]
+	sandbox_image = "mcr.microsoft.com/oss/kubernetes/pause:3.6"%s
+	[plugins."io.containerd.grpc.v1.cri".containerd]
+		default_runtime_name = "runc"`, selinuxCRIConfig(mode))
+
+			var got struct {
+				Plugins struct {
+					CRI struct {
+						EnableSELinux bool `toml:"enable_selinux"`
+					} `toml:"io.containerd.grpc.v1.cri"`
+				} `toml:"plugins"`
+			}
+			if err := toml.Unmarshal([]byte(content), &got); err != nil {
+				t.Fatalf("configuration is not valid TOML: %v\n%s", err, content)
+			}
+			if got.Plugins.CRI.EnableSELinux != want {
+				t.Errorf("enable_selinux = %v, want %v", got.Plugins.CRI.EnableSELinux, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package containerd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// validateSnapshotter checks that the kernel and the filesystem under containerd's data directory support the
+// configured snapshotter
+func (i *Installer) validateSnapshotter() error {
+	snapshotter := i.config.Containerd.Snapshotter
+	switch snapshotter {
+	case config.SnapshotterOverlayfs:
+		if err := i.requireFilesystem("overlay", snapshotter); err != nil {
+			return err
+		}
+		// Overlay mounts cannot use ZFS for their upper directories before OpenZFS 2.2, which most hosts predate
+		if fsType, err := utilhost.FilesystemType(containerdDataDir); err == nil && fsType == "zfs" {
+			return fmt.Errorf("%s is on zfs, which the overlayfs snapshotter cannot use; set containerd.snapshotter to zfs or native", containerdDataDir)
+		}
+	case config.SnapshotterZFS:
+		if !utils.BinaryExists("zfs") {
+			return fmt.Errorf("the zfs snapshotter requires the zfs utilities")
+		}
+		fsType, err := utilhost.FilesystemType(zfsSnapshotterDir)
+		if err != nil {
+			return err
+		}
+		if fsType != "zfs" {
+			return fmt.Errorf("the zfs snapshotter requires a ZFS dataset mounted on %s, found %s; create one with "+
+				"\"zfs create -o mountpoint=%s <pool>/containerd\"", zfsSnapshotterDir, fsType, zfsSnapshotterDir)
+		}
+	case config.SnapshotterEROFS:
+		if err := i.requireFilesystem("erofs", snapshotter); err != nil {
+			return err
+		}
+		if !utils.BinaryExists("mkfs.erofs") {
+			return fmt.Errorf("the erofs snapshotter requires mkfs.erofs from erofs-utils")
+		}
+	case config.SnapshotterStargz:
+		// Installed and started by the step before this one
+		if !utils.IsServiceActive(stargzSnapshotterService) {
+			return fmt.Errorf("the stargz snapshotter requires the %s service to be running", stargzSnapshotterService)
+		}
+	}
+	return nil
+}
+
+// requireFilesystem loads the kernel module of a filesystem type and checks that the kernel supports it
+func (i *Installer) requireFilesystem(fsType, snapshotter string) error {
+	if err := utilhost.LoadKernelModule(fsType); err != nil {
+		i.logger.Debugf("Failed to load the %s module: %v", fsType, err)
+	}
+	supported, err := utilhost.FilesystemSupported(fsType)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return fmt.Errorf("the %s snapshotter requires %s support in the kernel", snapshotter, fsType)
+	}
+	return nil
+}
+
+// snapshotterCRIConfig returns the settings of the CRI containerd section selecting the snapshotter
+func snapshotterCRIConfig(snapshotter string) string {
+	settings := fmt.Sprintf("\t\tsnapshotter = %q\n", snapshotter)
+	if snapshotter == config.SnapshotterStargz {
+		// Lazy pulling needs the layer annotations passed to the snapshotter
+		settings += "\t\tdisable_snapshot_annotations = false\n"
+	}
+	return settings
+}
+
+// snapshotterPluginConfig returns the top-level sections the snapshotter needs, if any
+func snapshotterPluginConfig(snapshotter string) string {
+	switch snapshotter {
+	case config.SnapshotterEROFS:
+		// Layers are applied as erofs images; the walking differ is kept for other snapshotters
+		return `
+[plugins."io.containerd.service.v1.diff-service"]
+	default = ["erofs", "walking"]`
+	case config.SnapshotterStargz:
+		return fmt.Sprintf(`
+[proxy_plugins]
+	[proxy_plugins.stargz]
+		type = "snapshot"
+		address = %q`, stargzSnapshotterSocket)
+	}
+	return ""
+}
+
+// isSnapshotterConfigured reports whether config.toml selects the configured snapshotter
+func (i *Installer) isSnapshotterConfigured() bool {
+	data, err := os.ReadFile(containerdConfigFile)
+	if err != nil {
+		return false
+	}
+	var file struct {
+		Plugins struct {
+			CRI struct {
+				Containerd struct {
+					Snapshotter string `toml:"snapshotter"`
+				} `toml:"containerd"`
+			} `toml:"io.containerd.grpc.v1.cri"`
+		} `toml:"plugins"`
+	}
+	if err := toml.Unmarshal(data, &file); err != nil {
+		return false
+	}
+	return file.Plugins.CRI.Containerd.Snapshotter == i.config.Containerd.Snapshotter
+}
@@ -0,0 +1,49 @@
+package containerd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestSnapshotterConfig(t *testing.T) {
+	for _, snapshotter := range []string{config.SnapshotterOverlayfs, config.SnapshotterNative, config.SnapshotterZFS, config.SnapshotterEROFS, config.SnapshotterStargz} {
+		t.Run(snapshotter, func(t *testing.T) {
+			content := fmt.Sprintf(`version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+	[plugins."io.containerd.grpc.v1.cri".containerd]
+		default_runtime_name = "runc"
+%s[metrics]
+	address = "0.0.0.0:10257"%s`, snapshotterCRIConfig(snapshotter), snapshotterPluginConfig(snapshotter))
+
+			var got struct {
+				Plugins map[string]map[string]any `toml:"plugins"`
+				Proxy   map[string]struct {
+					Type    string `toml:"type"`
+					Address string `toml:"address"`
+				} `toml:"proxy_plugins"`
+			}
+			if err := toml.Unmarshal([]byte(content), &got); err != nil {
+				t.Fatalf("configuration is not valid TOML: %v\n%s", err, content)
+			}
+			cri := got.Plugins["io.containerd.grpc.v1.cri"]["containerd"].(map[string]any)
+			if cri["snapshotter"] != snapshotter {
+				t.Errorf("snapshotter = %v, want %s", cri["snapshotter"], snapshotter)
+			}
+
+			stargz, ok := got.Proxy["stargz"]
+			if wantProxy := snapshotter == config.SnapshotterStargz; ok != wantProxy {
+				t.Errorf("stargz proxy plugin configured = %v, want %v", ok, wantProxy)
+			}
+			if ok && (stargz.Type != "snapshot" || stargz.Address != stargzSnapshotterSocket) {
+				t.Errorf("stargz proxy plugin = %+v", stargz)
+			}
+			if _, ok := got.Plugins["io.containerd.service.v1.diff-service"]; ok != (snapshotter == config.SnapshotterEROFS) {
+				t.Errorf("erofs differ configured = %v", ok)
+			}
+		})
+	}
+}
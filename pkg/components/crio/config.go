@@ -0,0 +1,132 @@
+package crio
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/cni"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/containerd"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// ociRuntimePaths are the binaries of the low-level runtimes runtime.ociRuntime selects
+var ociRuntimePaths = map[string]string{
+	config.OCIRuntimeRunc: "/usr/bin/runc",
+	config.OCIRuntimeCrun: "/usr/bin/crun",
+}
+
+// crioConfig renders the CRI-O drop-in: image storage of its own, which podman on the host does not share,
+// systemd cgroups, the low-level runtime, the pause image, the CNI directories and the credentials file
+func crioConfig(cfg *config.Config) string {
+	ociRuntime := cfg.Runtime.OCIRuntime
+	if ociRuntime == "" {
+		ociRuntime = config.OCIRuntimeRunc
+	}
+	var sb strings.Builder
+	sb.WriteString(generatedHeader)
+	fmt.Fprintf(&sb, `[crio]
+root = %[5]q
+runroot = %[6]q
+
+[crio.runtime]
+cgroup_manager = "systemd"
+conmon_cgroup = "pod"
+default_runtime = %[1]q
+
+[crio.runtime.runtimes.%[1]s]
+runtime_path = %[2]q
+runtime_type = "oci"
+runtime_root = "/run/%[1]s"
+monitor_path = %[3]q
+
+[crio.image]
+pause_image = %[4]q
+`, ociRuntime, ociRuntimePaths[ociRuntime], crioBinDir+"/conmon", cfg.GetImage(config.ImagePause),
+		crioStateDir+"/storage", crioRunDir+"/storage")
+	if len(cfg.Containerd.RegistryAuth) > 0 {
+		fmt.Fprintf(&sb, "global_auth_file = %q\n", crioAuthFile)
+	}
+	fmt.Fprintf(&sb, `
+[crio.network]
+network_dir = %q
+plugin_dirs = [%q]
+`, cni.DefaultCNIConfDir, cni.DefaultCNIBinDir)
+	return sb.String()
+}
+
+// registriesConf renders containerd.mirrors as containers-registries.conf entries. With skipUpstream, the
+// first endpoint replaces the registry.
+func registriesConf(mirrors []config.RegistryMirrorConfig) string {
+	if len(mirrors) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(generatedHeader)
+	for _, mirror := range mirrors {
+		endpoints := mirror.Endpoints
+		fmt.Fprintf(&sb, "\n[[registry]]\nprefix = %q\n", mirror.Registry)
+		if mirror.SkipUpstream {
+			location, insecure := mirrorLocation(endpoints[0], &mirror)
+			fmt.Fprintf(&sb, "location = %q\n", location)
+			if insecure {
+				sb.WriteString("insecure = true\n")
+			}
+			endpoints = endpoints[1:]
+		} else {
+			fmt.Fprintf(&sb, "location = %q\n", mirror.Registry)
+		}
+		for _, endpoint := range endpoints {
+			location, insecure := mirrorLocation(endpoint, &mirror)
+			fmt.Fprintf(&sb, "\n[[registry.mirror]]\nlocation = %q\n", location)
+			if insecure {
+				sb.WriteString("insecure = true\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// mirrorLocation converts a mirror URL to a registries.conf location, which has no scheme and no /v2 API path,
+// and reports whether it is reached without TLS verification
+func mirrorLocation(endpoint string, mirror *config.RegistryMirrorConfig) (string, bool) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint, mirror.SkipVerify
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	if mirror.OverridePath {
+		path = strings.TrimPrefix(path, "/v2")
+	}
+	return u.Host + path, u.Scheme == "http" || mirror.SkipVerify
+}
+
+// authEntry is a registry entry of a containers-auth.json file
+type authEntry struct {
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// authJSON renders the credentials as the containers-auth.json file CRI-O pulls with
+func authJSON(credentials map[string]containerd.RegistryCredential) ([]byte, error) {
+	auths := make(map[string]authEntry, len(credentials))
+	for registry, credential := range credentials {
+		entry := authEntry{IdentityToken: credential.IdentityToken}
+		if credential.Username != "" || credential.Password != "" {
+			entry.Auth = base64.StdEncoding.EncodeToString([]byte(credential.Username + ":" + credential.Password))
+		}
+		auths[registry] = entry
+	}
+	content, err := json.MarshalIndent(map[string]any{"auths": auths}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the registry credentials: %w", err)
+	}
+	return append(content, '\n'), nil
+}
+
+// crictlConfig points crictl at the CRI-O socket
+func crictlConfig() string {
+	return fmt.Sprintf("runtime-endpoint: unix://%[1]s\nimage-endpoint: unix://%[1]s\n", config.CRIOSocketPath)
+}
@@ -0,0 +1,59 @@
+package crio
+
+const (
+	crioService        = "crio"
+	crioBinDir         = "/usr/local/bin"
+	crioServiceFile    = "/etc/systemd/system/crio.service"
+	crioServiceDropIn  = "/etc/systemd/system/crio.service.d"
+	crioProxyDropIn    = "/etc/systemd/system/crio.service.d/10-proxy.conf"
+	crioConfigDir      = "/etc/crio"
+	crioConfigFile     = "/etc/crio/crio.conf.d/10-aks-flex-node.conf"
+	crioAuthFile       = "/etc/crio/auth.json"
+	crictlConfigFile   = "/etc/crictl.yaml"
+	crioStateDir       = "/var/lib/crio"
+	crioRunDir         = "/run/crio"
+	registriesConfFile = "/etc/containers/registries.conf.d/50-aks-flex-node.conf"
+	signaturePolicy    = "/etc/containers/policy.json"
+	certsDir           = "/etc/containers/certs.d"
+
+	// mirrorCAFile is the name of the CA bundles copied from containerd.mirrors into certs.d
+	mirrorCAFile = "aks-flex-node.crt"
+
+	// generatedHeader marks the files rendered from the configuration
+	generatedHeader = "# Generated by aks-flex-node\n"
+)
+
+// crioBinaries are the binaries installed from the CRI-O bundle. runc and crun come from their own steps.
+var crioBinaries = []string{"crio", "pinns", "conmon", "conmonrs", "crictl"}
+
+// crioDownloadURL is the URL of the static CRI-O bundle, by architecture and release
+var crioDownloadURL = "https://storage.googleapis.com/cri-o/artifacts/cri-o.%s.v%s.tar.gz"
+
+// defaultSignaturePolicy accepts any image, as containerd does. It is only written when the host has none.
+const defaultSignaturePolicy = `{
+  "default": [{"type": "insecureAcceptAnything"}]
+}
+`
+
+// crioServiceUnit runs CRI-O the way the upstream bundle does
+const crioServiceUnit = `[Unit]
+Description=Container Runtime Interface for OCI (CRI-O)
+Documentation=https://github.com/cri-o/cri-o
+Wants=network-online.target
+Before=kubelet.service
+After=network-online.target
+[Service]
+Type=notify
+ExecStart=/usr/local/bin/crio
+ExecReload=/bin/kill -s HUP $MAINPID
+TasksMax=infinity
+LimitNOFILE=1048576
+LimitNPROC=1048576
+LimitCORE=infinity
+OOMScoreAdjust=-999
+TimeoutStartSec=0
+Restart=on-failure
+RestartSec=10
+[Install]
+WantedBy=multi-user.target
+`
@@ -0,0 +1,321 @@
+package crio
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/containerd"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer installs and configures CRI-O when runtime.containerRuntime is crio. It pulls with the registry
+// settings of containerd.mirrors and containerd.registryAuth, so switching runtimes keeps image access the same.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new CRI-O Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "CRIOInstaller"
+}
+
+// Validate checks that a CRI-O release is known
+func (i *Installer) Validate(ctx context.Context) error {
+	if i.config.UsesCRIO() && i.config.Runtime.CRIO.Version == "" {
+		return fmt.Errorf("runtime.crio.version is required when kubernetes.version is not set")
+	}
+	return nil
+}
+
+// Execute installs and configures CRI-O, or removes it when containerd runs the pods
+func (i *Installer) Execute(ctx context.Context) error {
+	if !i.config.UsesCRIO() {
+		if isCRIOInstalled() {
+			i.logger.Info("runtime.containerRuntime is not crio, removing CRI-O")
+			removeCRIO(i.logger)
+		}
+		return nil
+	}
+
+	if !i.isVersionInstalled() {
+		i.logger.Infof("Installing CRI-O %s", i.config.Runtime.CRIO.Version)
+		if err := i.installCRIO(ctx); err != nil {
+			return err
+		}
+	}
+	if err := i.configure(ctx); err != nil {
+		return err
+	}
+	i.logger.Infof("CRI-O %s installed and configured", i.config.Runtime.CRIO.Version)
+	return nil
+}
+
+// IsCompleted reports whether the configured release is installed and its configuration is up to date, or CRI-O
+// is absent when containerd runs the pods
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if !i.config.UsesCRIO() {
+		return !isCRIOInstalled()
+	}
+	return i.isVersionInstalled() &&
+		utils.FileHasContent(crioServiceFile, crioServiceUnit) &&
+		utils.FileHasContent(crioConfigFile, crioConfig(i.config)) &&
+		utils.FileHasContent(crictlConfigFile, crictlConfig()) &&
+		i.isProxyConfigured() &&
+		optionalFileHasContent(registriesConfFile, registriesConf(i.config.Containerd.Mirrors)) &&
+		i.areMirrorCAsInstalled() &&
+		i.isRegistryAuthConfigured()
+}
+
+// installCRIO extracts the CRI-O binaries from the static bundle
+func (i *Installer) installCRIO(ctx context.Context) error {
+	version := strings.TrimPrefix(i.config.Runtime.CRIO.Version, "v")
+	url := fmt.Sprintf(crioDownloadURL, utilhost.GetArch(), version)
+	wanted := make(map[string]bool, len(crioBinaries))
+	for _, binary := range crioBinaries {
+		wanted[binary] = true
+	}
+	for tarFile, err := range utilio.DecompressTarGzFromRemote(ctx, url) {
+		if err != nil {
+			return fmt.Errorf("failed to download CRI-O %s: %w", version, err)
+		}
+		dir, name := filepath.Split(tarFile.Name)
+		if filepath.Base(filepath.Clean(dir)) != "bin" || !wanted[name] {
+			continue
+		}
+		if err := utilio.InstallFile(filepath.Join(crioBinDir, name), tarFile.Body, 0o755); err != nil {
+			return fmt.Errorf("failed to install %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// configure writes the service, the CRI-O drop-in and the registry settings
+func (i *Installer) configure(ctx context.Context) error {
+	files := map[string]string{
+		crioServiceFile:  crioServiceUnit,
+		crioConfigFile:   crioConfig(i.config),
+		crictlConfigFile: crictlConfig(),
+	}
+	for path, content := range files {
+		if err := utilio.WriteFile(path, []byte(content), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	if !utils.FileExists(signaturePolicy) {
+		if err := utilio.WriteFile(signaturePolicy, []byte(defaultSignaturePolicy), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", signaturePolicy, err)
+		}
+	}
+	if err := i.configureProxy(); err != nil {
+		return err
+	}
+	if err := i.configureMirrors(); err != nil {
+		return err
+	}
+	if err := i.configureRegistryAuth(ctx); err != nil {
+		return err
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd after configuring CRI-O: %w", err)
+	}
+	return nil
+}
+
+// configureProxy installs a drop-in passing network.proxy to CRI-O, or removes it when no proxy is configured
+func (i *Installer) configureProxy() error {
+	env := i.config.GetProxyEnvironment()
+	if len(env) == 0 {
+		return utils.RunCleanupCommand(crioProxyDropIn)
+	}
+	if err := utilio.MkdirAll(crioServiceDropIn, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", crioServiceDropIn, err)
+	}
+	if err := utilio.WriteFile(crioProxyDropIn, []byte(utils.SystemdEnvironmentDropIn(env)), 0o644); err != nil {
+		return fmt.Errorf("failed to write CRI-O proxy drop-in: %w", err)
+	}
+	return nil
+}
+
+// isProxyConfigured reports whether the CRI-O proxy drop-in matches network.proxy
+func (i *Installer) isProxyConfigured() bool {
+	env := i.config.GetProxyEnvironment()
+	if len(env) == 0 {
+		return !utils.FileExists(crioProxyDropIn)
+	}
+	return utils.FileHasContent(crioProxyDropIn, utils.SystemdEnvironmentDropIn(env))
+}
+
+// configureMirrors writes containerd.mirrors as registries.conf entries and copies their CA bundles to the
+// certs.d directories of the endpoints. CRI-O reads both on every pull.
+func (i *Installer) configureMirrors() error {
+	content := registriesConf(i.config.Containerd.Mirrors)
+	if content == "" {
+		if err := utils.RunCleanupCommand(registriesConfFile); err != nil {
+			return err
+		}
+	} else if err := utilio.WriteFile(registriesConfFile, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", registriesConfFile, err)
+	}
+
+	wanted := i.mirrorCAs()
+	for _, path := range installedMirrorCAs() {
+		if _, ok := wanted[path]; !ok {
+			if err := utils.RunCleanupCommand(path); err != nil {
+				return err
+			}
+		}
+	}
+	for path, caFile := range wanted {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the mirror CA bundle %s: %w", caFile, err)
+		}
+		if err := utilio.WriteFile(path, ca, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// mirrorCAs maps the certs.d files of mirror endpoints with a caFile to that file
+func (i *Installer) mirrorCAs() map[string]string {
+	cas := make(map[string]string)
+	for _, mirror := range i.config.Containerd.Mirrors {
+		if mirror.CAFile == "" {
+			continue
+		}
+		for _, endpoint := range mirror.Endpoints {
+			location, _ := mirrorLocation(endpoint, &mirror)
+			host, _, _ := strings.Cut(location, "/")
+			cas[filepath.Join(certsDir, host, mirrorCAFile)] = mirror.CAFile
+		}
+	}
+	return cas
+}
+
+// installedMirrorCAs returns the CA bundles copied into certs.d by an earlier bootstrap
+func installedMirrorCAs() []string {
+	paths, _ := filepath.Glob(filepath.Join(certsDir, "*", mirrorCAFile))
+	return paths
+}
+
+// areMirrorCAsInstalled reports whether exactly the configured CA bundles are in certs.d
+func (i *Installer) areMirrorCAsInstalled() bool {
+	wanted := i.mirrorCAs()
+	if len(installedMirrorCAs()) != len(wanted) {
+		return false
+	}
+	for path, caFile := range wanted {
+		ca, err := os.ReadFile(caFile)
+		if err != nil || !utils.FileHasContent(path, string(ca)) {
+			return false
+		}
+	}
+	return true
+}
+
+// configureRegistryAuth writes containerd.registryAuth to the auth file only root can read, or removes it
+func (i *Installer) configureRegistryAuth(ctx context.Context) error {
+	if len(i.config.Containerd.RegistryAuth) == 0 {
+		return utils.RunCleanupCommand(crioAuthFile)
+	}
+	credentials, err := containerd.RegistryCredentials(ctx, i.config)
+	if err != nil {
+		return err
+	}
+	content, err := authJSON(credentials)
+	if err != nil {
+		return err
+	}
+	if err := utilio.WriteFile(crioAuthFile, content, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", crioAuthFile, err)
+	}
+	return nil
+}
+
+// isRegistryAuthConfigured reports whether the auth file holds the configured credentials. Tokens obtained with
+// the node identity change on every exchange, so only the file's presence is checked then.
+func (i *Installer) isRegistryAuthConfigured() bool {
+	registryAuth := i.config.Containerd.RegistryAuth
+	if len(registryAuth) == 0 {
+		return !utils.FileExists(crioAuthFile)
+	}
+	info, err := os.Stat(crioAuthFile)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		return false
+	}
+	if i.config.RegistryAuthUsesNodeIdentity() {
+		return true
+	}
+	credentials, err := containerd.RegistryCredentials(context.Background(), i.config)
+	if err != nil {
+		return false
+	}
+	content, err := authJSON(credentials)
+	return err == nil && utils.FileHasContent(crioAuthFile, string(content))
+}
+
+// RefreshRegistryAuth renews the ACR refresh tokens obtained with the node identity. CRI-O reads the auth file
+// on every pull, so no restart is needed. It does nothing when no registry uses the node identity.
+func RefreshRegistryAuth(ctx context.Context, cfg *config.Config, logger *logrus.Logger) error {
+	if !cfg.RegistryAuthUsesNodeIdentity() {
+		return nil
+	}
+	i := &Installer{config: cfg, logger: logger}
+	if err := i.configureRegistryAuth(ctx); err != nil {
+		return err
+	}
+	logger.Info("Registry credentials refreshed")
+	return nil
+}
+
+// isVersionInstalled reports whether every CRI-O binary is installed and crio is the configured release
+func (i *Installer) isVersionInstalled() bool {
+	for _, binary := range crioBinaries {
+		if !utils.FileExists(filepath.Join(crioBinDir, binary)) {
+			return false
+		}
+	}
+	output, err := utils.RunCommandWithOutput(filepath.Join(crioBinDir, "crio"), "--version")
+	return err == nil && reportsVersion(output, strings.TrimPrefix(i.config.Runtime.CRIO.Version, "v"))
+}
+
+// reportsVersion reports whether crio --version output names a release, e.g. "crio version 1.32.1" or the
+// "Version: 1.32.1" line of the table newer releases print
+func reportsVersion(output, version string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 3 && fields[0] == "crio" && fields[1] == "version":
+			return fields[2] == version
+		case len(fields) == 2 && fields[0] == "Version:":
+			return fields[1] == version
+		}
+	}
+	return false
+}
+
+// optionalFileHasContent reports whether a file has the given content, or is absent when the content is empty
+func optionalFileHasContent(path, content string) bool {
+	if content == "" {
+		return !utils.FileExists(path)
+	}
+	return utils.FileHasContent(path, content)
+}
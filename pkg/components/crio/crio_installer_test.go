@@ -0,0 +1,117 @@
+package crio
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/containerd"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestCRIOConfig(t *testing.T) {
+	cfg := &config.Config{
+		Runtime:    config.RuntimeConfig{OCIRuntime: config.OCIRuntimeCrun},
+		Containerd: config.ContainerdConfig{RegistryAuth: []config.RegistryAuthConfig{{Registry: "myregistry.azurecr.io", ManagedIdentity: true}}},
+	}
+	var got struct {
+		Crio struct {
+			Runtime struct {
+				DefaultRuntime string `toml:"default_runtime"`
+				Runtimes       map[string]struct {
+					RuntimePath string `toml:"runtime_path"`
+				} `toml:"runtimes"`
+			} `toml:"runtime"`
+			Image struct {
+				GlobalAuthFile string `toml:"global_auth_file"`
+			} `toml:"image"`
+		} `toml:"crio"`
+	}
+	content := crioConfig(cfg)
+	if err := toml.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("configuration is not valid TOML: %v\n%s", err, content)
+	}
+	if got.Crio.Runtime.DefaultRuntime != "crun" || got.Crio.Runtime.Runtimes["crun"].RuntimePath != "/usr/bin/crun" {
+		t.Errorf("runtime = %+v, want crun", got.Crio.Runtime)
+	}
+	if got.Crio.Image.GlobalAuthFile != crioAuthFile {
+		t.Errorf("global_auth_file = %q, want %q", got.Crio.Image.GlobalAuthFile, crioAuthFile)
+	}
+
+	if content := crioConfig(&config.Config{}); strings.Contains(content, "global_auth_file") || !strings.Contains(content, `default_runtime = "runc"`) {
+		t.Errorf("crioConfig() without settings =\n%s", content)
+	}
+}
+
+func TestRegistriesConf(t *testing.T) {
+	mirrors := []config.RegistryMirrorConfig{
+		{Registry: "docker.io", Endpoints: []string{"http://mirror.contoso.local:5000"}},
+		{Registry: "mcr.microsoft.com", Endpoints: []string{"https://harbor.contoso.local/v2/mcr-proxy", "https://backup.contoso.local"}, SkipUpstream: true, OverridePath: true},
+	}
+	var got struct {
+		Registry []struct {
+			Prefix   string `toml:"prefix"`
+			Location string `toml:"location"`
+			Mirror   []struct {
+				Location string `toml:"location"`
+				Insecure bool   `toml:"insecure"`
+			} `toml:"mirror"`
+		} `toml:"registry"`
+	}
+	content := registriesConf(mirrors)
+	if err := toml.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("registries.conf is not valid TOML: %v\n%s", err, content)
+	}
+	if len(got.Registry) != 2 {
+		t.Fatalf("registries = %+v, want 2", got.Registry)
+	}
+	docker := got.Registry[0]
+	if docker.Location != "docker.io" || len(docker.Mirror) != 1 || docker.Mirror[0].Location != "mirror.contoso.local:5000" || !docker.Mirror[0].Insecure {
+		t.Errorf("docker.io = %+v", docker)
+	}
+	mcr := got.Registry[1]
+	if mcr.Location != "harbor.contoso.local/mcr-proxy" || len(mcr.Mirror) != 1 || mcr.Mirror[0].Location != "backup.contoso.local" {
+		t.Errorf("mcr.microsoft.com = %+v", mcr)
+	}
+	if registriesConf(nil) != "" {
+		t.Errorf("registriesConf() without mirrors is not empty")
+	}
+}
+
+func TestAuthJSON(t *testing.T) {
+	content, err := authJSON(map[string]containerd.RegistryCredential{
+		"contoso.azurecr.io": {Username: "user", Password: "secret"},
+		"fabrikam.io":        {IdentityToken: "token"},
+	})
+	if err != nil {
+		t.Fatalf("authJSON() error = %v", err)
+	}
+	var got struct {
+		Auths map[string]authEntry `json:"auths"`
+	}
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatalf("auth file is not valid JSON: %v", err)
+	}
+	if got.Auths["contoso.azurecr.io"].Auth != "dXNlcjpzZWNyZXQ=" || got.Auths["fabrikam.io"].IdentityToken != "token" || got.Auths["fabrikam.io"].Auth != "" {
+		t.Errorf("auths = %+v", got.Auths)
+	}
+}
+
+func TestReportsVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{output: "crio version 1.32.1\n", want: true},
+		{output: "Version:        1.32.1\nGitCommit:      abc\n", want: true},
+		{output: "crio version 1.31.4\n"},
+		{output: ""},
+	}
+	for _, tt := range tests {
+		if got := reportsVersion(tt.output, "1.32.1"); got != tt.want {
+			t.Errorf("reportsVersion(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
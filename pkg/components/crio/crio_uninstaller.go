@@ -0,0 +1,85 @@
+package crio
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/installed_files"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller stops and removes CRI-O, its configuration and its image storage
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new CRI-O UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "CRIOUnInstaller"
+}
+
+// Execute removes CRI-O
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isCRIOInstalled() {
+		return nil
+	}
+	removeCRIO(u.logger)
+	u.logger.Info("CRI-O removed")
+	return nil
+}
+
+// IsCompleted reports whether nothing of CRI-O is left on the node
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isCRIOInstalled()
+}
+
+// isCRIOInstalled reports whether the crio binary, its service or the configuration bootstrap created is present
+func isCRIOInstalled() bool {
+	return utils.FileExists(filepath.Join(crioBinDir, "crio")) || utils.FileExists(crioServiceFile) ||
+		slices.ContainsFunc(installed_files.CreatedPaths(crioConfigDir), utils.DirectoryExists)
+}
+
+// removeCRIO stops CRI-O and removes its binaries, configuration and state. Failures are logged, so cleanup
+// continues.
+func removeCRIO(logger *logrus.Logger) {
+	if utils.ServiceExists(crioService) {
+		if err := utils.StopService(crioService); err != nil {
+			logger.Warnf("Failed to stop CRI-O: %v", err)
+		}
+		if err := utils.DisableService(crioService); err != nil {
+			logger.Warnf("Failed to disable CRI-O: %v", err)
+		}
+	}
+
+	files := []string{crioServiceFile, crictlConfigFile, registriesConfFile}
+	for _, binary := range crioBinaries {
+		files = append(files, filepath.Join(crioBinDir, binary))
+	}
+	files = append(files, installedMirrorCAs()...)
+	if utils.FileHasContent(signaturePolicy, defaultSignaturePolicy) {
+		files = append(files, signaturePolicy)
+	}
+	for _, err := range utils.RemoveFiles(files, logger) {
+		logger.Warnf("Failed to remove CRI-O file: %v", err)
+	}
+	dirs := append(installed_files.CreatedPaths(crioServiceDropIn, crioConfigDir), crioStateDir, crioRunDir)
+	for _, err := range utils.RemoveDirectories(dirs, logger) {
+		logger.Warnf("Failed to remove CRI-O directory: %v", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		logger.Warnf("Failed to reload systemd: %v", err)
+	}
+}
@@ -0,0 +1,9 @@
+package crun
+
+const (
+	// crunBinaryPath is where containerd's runc handlers find crun when runtime.ociRuntime is crun
+	crunBinaryPath = "/usr/bin/crun"
+)
+
+// crunDownloadURL is the URL of a crun release binary, by release and architecture
+var crunDownloadURL = "https://github.com/containers/crun/releases/download/%[1]s/crun-%[1]s-linux-%[2]s"
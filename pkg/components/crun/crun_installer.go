@@ -0,0 +1,88 @@
+package crun
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer installs crun when runtime.ociRuntime selects it. containerd then runs containers of its default
+// runc handler with crun; runc stays installed.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new crun Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "CrunInstaller"
+}
+
+// Validate validates prerequisites before installing crun
+func (i *Installer) Validate(ctx context.Context) error {
+	return nil
+}
+
+// Execute installs the configured crun release, or removes crun when another OCI runtime is selected
+func (i *Installer) Execute(ctx context.Context) error {
+	if i.config.Runtime.OCIRuntime != config.OCIRuntimeCrun {
+		if utils.FileExists(crunBinaryPath) {
+			i.logger.Info("runtime.ociRuntime is not crun, removing crun")
+			if err := utils.RunCleanupCommand(crunBinaryPath); err != nil {
+				return fmt.Errorf("failed to remove crun: %w", err)
+			}
+		}
+		return nil
+	}
+	if i.isVersionInstalled() {
+		return nil
+	}
+
+	version := i.config.Runtime.Crun.Version
+	i.logger.Infof("Installing crun %s", version)
+	url := fmt.Sprintf(crunDownloadURL, version, utilhost.GetArch())
+	if err := utilio.DownloadToLocalFile(ctx, url, crunBinaryPath, 0o755); err != nil {
+		return fmt.Errorf("failed to install crun: %w", err)
+	}
+	i.logger.Infof("crun %s installed", version)
+	return nil
+}
+
+// IsCompleted reports whether the configured crun release is installed, or crun is absent when not selected
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if i.config.Runtime.OCIRuntime != config.OCIRuntimeCrun {
+		return !utils.FileExists(crunBinaryPath)
+	}
+	return i.isVersionInstalled()
+}
+
+// isVersionInstalled reports whether crun of the configured release is installed
+func (i *Installer) isVersionInstalled() bool {
+	output, err := utils.RunCommandWithOutput(crunBinaryPath, "--version")
+	return err == nil && reportsVersion(output, i.config.Runtime.Crun.Version)
+}
+
+// reportsVersion reports whether crun --version output, e.g. "crun version 1.19.1", names a release
+func reportsVersion(output, version string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "crun version "+version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package crun
+
+import "testing"
+
+func TestReportsVersion(t *testing.T) {
+	output := "crun version 1.19.1\ncommit: 3e32a70c93f5aa5fea69b50256cca7fd4aa23c80\nrundir: /run/crun\nspec: 1.0.0\n+SYSTEMD +SELINUX +APPARMOR +CAP +SECCOMP +EBPF +YAJL\n"
+	tests := map[string]bool{
+		"1.19.1": true,
+		"1.19":   false,
+		"1.19.2": false,
+	}
+	for version, want := range tests {
+		if got := reportsVersion(output, version); got != want {
+			t.Errorf("reportsVersion(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package crun
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller removes crun
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new crun UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "CrunUnInstaller"
+}
+
+// Execute removes the crun binary
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !utils.FileExists(crunBinaryPath) {
+		return nil
+	}
+	if err := utils.RunCleanupCommand(crunBinaryPath); err != nil {
+		u.logger.Warnf("Failed to remove %s: %v", crunBinaryPath, err)
+		return nil
+	}
+	u.logger.Info("crun removed")
+	return nil
+}
+
+// IsCompleted reports whether crun is gone
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !utils.FileExists(crunBinaryPath)
+}
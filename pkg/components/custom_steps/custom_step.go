@@ -0,0 +1,138 @@
+package custom_steps
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer runs a custom step declared in the configuration. The files it writes are recorded in the installed
+// files manifest, so unbootstrap removes them, or puts back the files they replaced, with the agent's own files.
+type Installer struct {
+	step   config.CustomStepConfig
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new custom step Installer
+func NewInstaller(logger *logrus.Logger, step config.CustomStepConfig) *Installer {
+	return &Installer{
+		step:   step,
+		logger: logger,
+	}
+}
+
+// GetName returns the step name, the custom step name
+func (i *Installer) GetName() string {
+	return i.step.Name
+}
+
+// Validate checks that the host has a known package manager when the step installs packages
+func (i *Installer) Validate(ctx context.Context) error {
+	if len(i.step.Packages) > 0 {
+		if _, err := utilhost.PackageManager(); err != nil {
+			return fmt.Errorf("custom step %s installs packages: %w", i.step.Name, err)
+		}
+	}
+	return nil
+}
+
+// Execute installs the missing packages, writes the files that differ, and enables and starts the units. Units
+// are restarted when a file changed, so they pick up their new configuration.
+func (i *Installer) Execute(ctx context.Context) error {
+	if missing := i.missingPackages(); len(missing) > 0 {
+		i.logger.Infof("Installing %s...", strings.Join(missing, ", "))
+		if err := utilhost.InstallPackages(missing...); err != nil {
+			return fmt.Errorf("failed to install %s: %w", strings.Join(missing, ", "), err)
+		}
+	}
+
+	changed, reloadSystemd := false, false
+	for _, file := range i.step.Files {
+		if fileInPlace(file) {
+			continue
+		}
+		if err := utilio.WriteFile(file.Path, []byte(file.Content), file.Perm()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+		// WriteFile keeps the mode of a file it replaces and applies the umask to new ones
+		if err := os.Chmod(file.Path, file.Perm()); err != nil {
+			return fmt.Errorf("failed to set the mode of %s: %w", file.Path, err)
+		}
+		i.logger.Infof("Wrote %s", file.Path)
+		changed = true
+		reloadSystemd = reloadSystemd || strings.HasPrefix(file.Path, "/etc/systemd/")
+	}
+	if reloadSystemd {
+		if err := utils.ReloadSystemd(); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
+	}
+
+	for _, unit := range i.step.Units {
+		if err := utils.EnableAndStartService(unit); err != nil {
+			return fmt.Errorf("failed to enable and start %s: %w", unit, err)
+		}
+		if changed {
+			if err := utils.RestartService(unit); err != nil {
+				return fmt.Errorf("failed to restart %s: %w", unit, err)
+			}
+		}
+	}
+	i.logger.Infof("Custom step %s applied", i.step.Name)
+	return nil
+}
+
+// IsCompleted reports whether the packages are installed, the files have their content and mode, and the
+// units are enabled and running
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if len(i.missingPackages()) > 0 {
+		return false
+	}
+	for _, file := range i.step.Files {
+		if !fileInPlace(file) {
+			return false
+		}
+	}
+	for _, unit := range i.step.Units {
+		if !utils.IsServiceActive(unit) || !isServiceEnabled(unit) {
+			return false
+		}
+	}
+	return true
+}
+
+// missingPackages returns the packages of the step that are not installed
+func (i *Installer) missingPackages() []string {
+	var missing []string
+	for _, pkg := range i.step.Packages {
+		if !utilhost.PackageInstalled(pkg) {
+			missing = append(missing, pkg)
+		}
+	}
+	return missing
+}
+
+// fileInPlace reports whether a file exists with the configured content and mode
+func fileInPlace(file config.CustomFileConfig) bool {
+	info, err := os.Stat(file.Path)
+	if err != nil || info.Mode().Perm() != file.Perm() {
+		return false
+	}
+	content, err := os.ReadFile(file.Path) // #nosec G304 -- custom step files are configured by the node operator
+	return err == nil && bytes.Equal(content, []byte(file.Content))
+}
+
+// isServiceEnabled reports whether a systemd unit starts at boot
+func isServiceEnabled(unit string) bool {
+	output, err := utils.RunCommandWithOutput("systemctl", "is-enabled", unit)
+	return err == nil && strings.TrimSpace(output) == "enabled"
+}
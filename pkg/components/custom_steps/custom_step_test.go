@@ -0,0 +1,49 @@
+package custom_steps
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	motd := filepath.Join(dir, "motd")
+	if err := os.WriteFile(motd, []byte("Welcome\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	step := config.CustomStepConfig{
+		Name: "SiteFiles",
+		Files: []config.CustomFileConfig{
+			{Path: motd, Content: "Managed by AKS Flex Node\n", Mode: "0644"},
+			{Path: filepath.Join(dir, "site", "banner"), Content: "site banner\n", Mode: "0640"},
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	installer := NewInstaller(logger, step)
+
+	if installer.IsCompleted(t.Context()) {
+		t.Fatal("IsCompleted() = true before execute")
+	}
+	if err := installer.Execute(t.Context()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !installer.IsCompleted(t.Context()) {
+		t.Error("IsCompleted() = false after execute")
+	}
+	for _, file := range step.Files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != file.Perm() {
+			t.Errorf("mode of %s = %o, want %o", file.Path, info.Mode().Perm(), file.Perm())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package drain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// UnInstaller cordons the node and evicts its pods before unbootstrap stops kubelet, so workloads move to
+// other nodes instead of being killed in place. Evictions honor PodDisruptionBudgets. When they do not finish
+// within the timeout, the remaining pods are deleted without evictions if force is set, and unbootstrap stops
+// otherwise.
+type UnInstaller struct {
+	logger  *logrus.Logger
+	timeout time.Duration
+	force   bool
+}
+
+// NewUnInstaller creates a new drain UnInstaller. A zero timeout skips the drain.
+func NewUnInstaller(logger *logrus.Logger, timeout time.Duration, force bool) *UnInstaller {
+	return &UnInstaller{
+		logger:  logger,
+		timeout: timeout,
+		force:   force,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "NodeDrained"
+}
+
+// IsCompleted reports whether there is nothing to drain: the drain is skipped or kubelet has no credentials
+// to reach the API server with
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	if u.timeout <= 0 {
+		return true
+	}
+	_, err := os.Stat(kubelet.KubeletKubeconfigPath)
+	return err != nil
+}
+
+// StopsCleanupOnFailure stops unbootstrap when the drain fails, so kubelet keeps running the remaining pods
+func (u *UnInstaller) StopsCleanupOnFailure() bool {
+	return true
+}
+
+// Execute cordons and drains the node, deleting the remaining pods when evictions time out and force is set
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		return err
+	}
+
+	u.logger.Infof("Draining node %s (timeout: %s)", nodeName, u.timeout)
+	output, err := utils.RunCommandWithOutput("kubectl", drainArgs(nodeName, u.timeout, false)...)
+	if err == nil {
+		u.logger.Infof("Drained node %s", nodeName)
+		return nil
+	}
+	if strings.Contains(output, "NotFound") {
+		u.logger.Infof("Node %s is not registered in the cluster, nothing to drain", nodeName)
+		return nil
+	}
+	if !u.force {
+		return fmt.Errorf("failed to drain node %s within %s: %w, output: %s; rerun with --force to delete the remaining pods without honoring PodDisruptionBudgets",
+			nodeName, u.timeout, err, strings.TrimSpace(output))
+	}
+
+	u.logger.Warnf("Failed to drain node %s within %s, deleting the remaining pods without honoring PodDisruptionBudgets: %s",
+		nodeName, u.timeout, strings.TrimSpace(output))
+	if output, err := utils.RunCommandWithOutput("kubectl", drainArgs(nodeName, u.timeout, true)...); err != nil {
+		return fmt.Errorf("failed to force drain node %s: %w, output: %s", nodeName, err, strings.TrimSpace(output))
+	}
+	u.logger.Infof("Force drained node %s", nodeName)
+	return nil
+}
+
+// drainArgs returns the kubectl arguments draining the node. Without force, pods are evicted through the
+// eviction API, which honors PodDisruptionBudgets. With force, pods are deleted directly, including pods no
+// controller recreates.
+func drainArgs(nodeName string, timeout time.Duration, force bool) []string {
+	args := []string{"--kubeconfig", kubelet.KubeletKubeconfigPath, "drain", nodeName,
+		"--ignore-daemonsets", "--delete-emptydir-data", "--timeout", timeout.String()}
+	if force {
+		args = append(args, "--force", "--disable-eviction")
+	}
+	return args
+}
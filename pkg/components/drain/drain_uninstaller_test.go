@@ -0,0 +1,31 @@
+package drain
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDrainArgs(t *testing.T) {
+	args := drainArgs("flex-node", 5*time.Minute, false)
+	if !slices.Contains(args, "flex-node") || !slices.Contains(args, "--ignore-daemonsets") {
+		t.Errorf("drainArgs() = %v, missing the node or --ignore-daemonsets", args)
+	}
+	if i := slices.Index(args, "--timeout"); i < 0 || args[i+1] != "5m0s" {
+		t.Errorf("drainArgs() = %v, want --timeout 5m0s", args)
+	}
+	if slices.Contains(args, "--disable-eviction") {
+		t.Errorf("drainArgs() = %v, evictions must honor PodDisruptionBudgets without force", args)
+	}
+
+	forced := drainArgs("flex-node", 5*time.Minute, true)
+	if !slices.Contains(forced, "--force") || !slices.Contains(forced, "--disable-eviction") {
+		t.Errorf("drainArgs(force) = %v, want --force --disable-eviction", forced)
+	}
+}
+
+func TestIsCompletedWithoutTimeout(t *testing.T) {
+	if !NewUnInstaller(nil, 0, false).IsCompleted(t.Context()) {
+		t.Error("IsCompleted() = false, want the drain skipped with a zero timeout")
+	}
+}
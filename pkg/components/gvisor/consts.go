@@ -0,0 +1,15 @@
+package gvisor
+
+const (
+	// runscBinaryPath is the gVisor runtime
+	runscBinaryPath = "/usr/local/bin/runsc"
+
+	// shimBinaryPath is the containerd shim for the io.containerd.runsc.v1 runtime type, found by containerd on PATH
+	shimBinaryPath = "/usr/local/bin/containerd-shim-runsc-v1"
+
+	// runtimeClassName is the RuntimeClass pods set to run with gVisor
+	runtimeClassName = "gvisor"
+)
+
+// gvisorDownloadURL is the URL of a gVisor release binary, by release, machine architecture and binary name
+var gvisorDownloadURL = "https://storage.googleapis.com/gvisor/releases/release/%s/%s/%s"
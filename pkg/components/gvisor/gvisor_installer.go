@@ -0,0 +1,111 @@
+package gvisor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer installs gVisor's runsc and its containerd shim. containerd registers the runsc runtime handler
+// when runtime.gvisor is enabled, so pods can select it through a RuntimeClass.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new gVisor Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "GVisorInstaller"
+}
+
+// Validate checks that the host can run the configured platform
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.config.Runtime.GVisor.Enabled {
+		return nil
+	}
+	if i.config.Runtime.GVisor.Platform == config.GVisorPlatformKVM && !utils.FileExists("/dev/kvm") {
+		return fmt.Errorf("the kvm gVisor platform requires /dev/kvm; use the systrap platform on hosts without virtualization support")
+	}
+	return nil
+}
+
+// Execute installs runsc and the shim and creates the RuntimeClass when requested, or removes gVisor when
+// runtime.gvisor is turned off
+func (i *Installer) Execute(ctx context.Context) error {
+	gvisor := &i.config.Runtime.GVisor
+	if !gvisor.Enabled {
+		if isGVisorInstalled() {
+			i.logger.Info("runtime.gvisor.enabled is off, removing gVisor")
+			removeGVisor(i.logger)
+		}
+		return nil
+	}
+
+	if !i.isVersionInstalled() {
+		i.logger.Infof("Installing gVisor release %s", gvisor.Version)
+		for _, binaryPath := range []string{runscBinaryPath, shimBinaryPath} {
+			url := fmt.Sprintf(gvisorDownloadURL, gvisor.Version, utilhost.GetMachineArch(), path.Base(binaryPath))
+			if err := utilio.DownloadToLocalFile(ctx, url, binaryPath, 0o755); err != nil {
+				return fmt.Errorf("failed to install %s: %w", path.Base(binaryPath), err)
+			}
+		}
+	}
+
+	if gvisor.RuntimeClass {
+		// Best effort: the node's credentials may not be allowed to write cluster-wide objects
+		err := utils.ApplyRuntimeClass(ctx, kubelet.KubeletKubeconfigPath, runtimeClassName, config.GVisorRuntimeHandler, config.GVisorRuntimeLabel)
+		if err != nil {
+			i.logger.Warnf("Failed to create the %s RuntimeClass, create it with cluster admin credentials: %v", runtimeClassName, err)
+		}
+	}
+	i.logger.Infof("gVisor release %s installed", gvisor.Version)
+	return nil
+}
+
+// IsCompleted reports whether the configured release is installed, or gVisor is absent when turned off
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if !i.config.Runtime.GVisor.Enabled {
+		return !isGVisorInstalled()
+	}
+	return i.isVersionInstalled()
+}
+
+// isVersionInstalled reports whether runsc and the shim of the configured release are installed
+func (i *Installer) isVersionInstalled() bool {
+	if !utils.FileExists(shimBinaryPath) {
+		return false
+	}
+	output, err := utils.RunCommandWithOutput(runscBinaryPath, "--version")
+	return err == nil && reportsRelease(output, i.config.Runtime.GVisor.Version)
+}
+
+// reportsRelease reports whether runsc --version output, e.g. "runsc version release-20250106.0", names a
+// release. A release without point number is its first build, .0.
+func reportsRelease(output, release string) bool {
+	if !strings.Contains(release, ".") {
+		release += ".0"
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "runsc version release-"+release {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package gvisor
+
+import "testing"
+
+func TestReportsRelease(t *testing.T) {
+	output := "runsc version release-20250106.0\nspec: 1.1.0-rc.1\n"
+	tests := map[string]bool{
+		"20250106.0": true,
+		"20250106":   true,
+		"20250106.1": false,
+		"20241202.0": false,
+	}
+	for release, want := range tests {
+		if got := reportsRelease(output, release); got != want {
+			t.Errorf("reportsRelease(%q) = %v, want %v", release, got, want)
+		}
+	}
+}
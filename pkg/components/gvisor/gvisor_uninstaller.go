@@ -0,0 +1,56 @@
+package gvisor
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller removes runsc and its shim. The RuntimeClass is cluster-wide and stays for the other nodes.
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new gVisor UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "GVisorUnInstaller"
+}
+
+// Execute removes the gVisor binaries
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isGVisorInstalled() {
+		return nil
+	}
+	removeGVisor(u.logger)
+	u.logger.Info("gVisor removed")
+	return nil
+}
+
+// IsCompleted reports whether no gVisor binaries are left on the node
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isGVisorInstalled()
+}
+
+// isGVisorInstalled reports whether any gVisor binary is present
+func isGVisorInstalled() bool {
+	return utils.FileExists(runscBinaryPath) || utils.FileExists(shimBinaryPath)
+}
+
+// removeGVisor removes the gVisor binaries. Failures are logged, so cleanup continues.
+func removeGVisor(logger *logrus.Logger) {
+	for _, err := range utils.RemoveFiles([]string{runscBinaryPath, shimBinaryPath}, logger) {
+		logger.Warnf("Failed to remove gVisor binary: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package image_prepull
+
+const (
+	// containerdService is started to pull with when the services step has not run yet
+	containerdService = "containerd"
+
+	// containerdK8sNamespace holds the images of the CRI plugin, which kubelet sees
+	containerdK8sNamespace = "k8s.io"
+
+	// containerdHostsDir holds the hosts.toml files of the registry mirrors
+	containerdHostsDir = "/etc/containerd/certs.d"
+
+	// configMapImagesKey is the key of imagePrePull.configMap that lists the images
+	configMapImagesKey = "images"
+)
@@ -0,0 +1,222 @@
+package image_prepull
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// Installer pulls the images of imagePrePull before kubelet starts, so the pods scheduled as soon as the node is
+// Ready start from local images instead of all pulling at once over the node's link
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new image pre-pull Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "ImagePrePull"
+}
+
+// Validate checks that ctr is installed
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.config.ImagePrePull.Enabled {
+		return nil
+	}
+	if !utils.BinaryExists("ctr") {
+		return fmt.Errorf("ctr is required to pre-pull images")
+	}
+	return nil
+}
+
+// Execute pulls the images that are not present yet. A failed pull only delays the pods that need the image,
+// which kubelet pulls again, so failures are logged and retried on the next bootstrap instead of failing it.
+func (i *Installer) Execute(ctx context.Context) error {
+	if !i.config.ImagePrePull.Enabled {
+		return nil
+	}
+
+	// containerd is configured by now but only started by the services step
+	if !utils.IsServiceActive(containerdService) {
+		if err := utils.EnableAndStartService(containerdService); err != nil {
+			return fmt.Errorf("failed to start containerd to pre-pull images: %w", err)
+		}
+	}
+
+	missing := i.missingImages(i.images())
+	if len(missing) == 0 {
+		i.logger.Info("All pre-pull images are present")
+		return nil
+	}
+	i.logger.Infof("Pre-pulling %d images, %d at a time", len(missing), i.config.ImagePrePull.Parallelism)
+	failed := i.pullImages(ctx, missing)
+	if len(failed) > 0 {
+		i.logger.Warnf("Failed to pre-pull %d of %d images, kubelet pulls them when pods need them: %s",
+			len(failed), len(missing), strings.Join(failed, ", "))
+		return nil
+	}
+	i.logger.Infof("Pre-pulled %d images", len(missing))
+	return nil
+}
+
+// IsCompleted reports whether every pre-pull image is present
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if !i.config.ImagePrePull.Enabled {
+		return true
+	}
+	return utils.IsServiceActive(containerdService) && len(i.missingImages(i.images())) == 0
+}
+
+// images returns the normalized, deduplicated references to pre-pull: the built-in images the node runs, then
+// imagePrePull.images, then those listed by imagePrePull.configMap
+func (i *Installer) images() []string {
+	var images []string
+	for _, image := range i.config.ResolveImages() {
+		images = append(images, image.Reference)
+	}
+	images = append(images, i.config.ImagePrePull.Images...)
+	if i.config.ImagePrePull.ConfigMap != "" {
+		listed, err := i.configMapImages()
+		if err != nil {
+			i.logger.Warnf("Failed to read the pre-pull images of %s: %v", i.config.ImagePrePull.ConfigMap, err)
+		}
+		images = append(images, listed...)
+	}
+
+	seen := make(map[string]bool, len(images))
+	var normalized []string
+	for _, image := range images {
+		reference := normalizeImageReference(image)
+		if !seen[reference] {
+			seen[reference] = true
+			normalized = append(normalized, reference)
+		}
+	}
+	return normalized
+}
+
+// configMapImages reads the images listed by imagePrePull.configMap with kubelet's credentials, which may read
+// ConfigMaps when the node authenticates through Azure RBAC
+func (i *Installer) configMapImages() ([]string, error) {
+	namespace, name, _ := strings.Cut(i.config.ImagePrePull.ConfigMap, "/")
+	output, err := utils.RunCommandWithOutput("kubectl", "--kubeconfig", kubelet.KubeletKubeconfigPath,
+		"get", "configmap", name, "--namespace", namespace, "--output", "jsonpath={.data."+configMapImagesKey+"}")
+	if err != nil {
+		return nil, fmt.Errorf("%w, output: %s", err, strings.TrimSpace(output))
+	}
+	return parseImageList(output), nil
+}
+
+// missingImages returns the images containerd does not have
+func (i *Installer) missingImages(images []string) []string {
+	output, err := i.ctr(context.Background(), "images", "list", "--quiet")
+	if err != nil {
+		i.logger.Debugf("Failed to list containerd images: %v, output: %s", err, output)
+		return images
+	}
+	present := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+	var missing []string
+	for _, image := range images {
+		if !present[image] {
+			missing = append(missing, image)
+		}
+	}
+	return missing
+}
+
+// pullImages pulls the images with up to imagePrePull.parallelism pulls at a time and returns those that failed
+func (i *Installer) pullImages(ctx context.Context, images []string) []string {
+	var (
+		mu     sync.Mutex
+		failed []string
+		wg     sync.WaitGroup
+	)
+	slots := make(chan struct{}, max(i.config.ImagePrePull.Parallelism, 1))
+	for _, image := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			// Unpack with the snapshotter containerd runs containers with, so kubelet finds the image ready
+			output, err := i.ctr(ctx, "images", "pull", "--snapshotter="+i.config.Containerd.Snapshotter, "--hosts-dir", containerdHostsDir, image)
+			if err != nil {
+				i.logger.Warnf("Failed to pre-pull %s: %v, output: %s", image, err, strings.TrimSpace(lastLine(output)))
+				mu.Lock()
+				failed = append(failed, image)
+				mu.Unlock()
+				return
+			}
+			i.logger.Infof("Pre-pulled %s", image)
+		}()
+	}
+	wg.Wait()
+	return failed
+}
+
+// ctr runs a ctr command in the Kubernetes namespace of containerd. ctr fetches images itself rather than
+// through containerd, so it gets the network.proxy settings.
+func (i *Installer) ctr(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "ctr", append([]string{"--namespace", containerdK8sNamespace}, args...)...) // #nosec - arguments come from the configuration
+	cmd.Env = append(os.Environ(), i.config.GetProxyEnvironment()...)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// parseImageList splits a list of images, one per line or separated by spaces, skipping # comments
+func parseImageList(list string) []string {
+	var images []string
+	for _, line := range strings.Split(list, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		images = append(images, strings.Fields(line)...)
+	}
+	return images
+}
+
+// normalizeImageReference expands a short reference the way kubelet does, e.g. nginx to
+// docker.io/library/nginx:latest, since ctr only accepts and lists full references
+func normalizeImageReference(image string) string {
+	name, digest, hasDigest := strings.Cut(image, "@")
+	domain, remainder, hasDomain := strings.Cut(name, "/")
+	if !hasDomain || (!strings.ContainsAny(domain, ".:") && domain != "localhost") {
+		domain, remainder = "docker.io", name
+	}
+	if domain == "docker.io" && !strings.Contains(remainder, "/") {
+		remainder = "library/" + remainder
+	}
+	reference := domain + "/" + remainder
+	if hasDigest {
+		return reference + "@" + digest
+	}
+	if !strings.Contains(remainder[strings.LastIndex(remainder, "/")+1:], ":") {
+		reference += ":latest"
+	}
+	return reference
+}
+
+// lastLine returns the last non-empty line of ctr's progress output, which holds the error
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,31 @@
+package image_prepull
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeImageReference(t *testing.T) {
+	tests := map[string]string{
+		"nginx":         "docker.io/library/nginx:latest",
+		"nginx:1.27":    "docker.io/library/nginx:1.27",
+		"bitnami/redis": "docker.io/bitnami/redis:latest",
+		"mcr.microsoft.com/oss/kubernetes/pause:3.6": "mcr.microsoft.com/oss/kubernetes/pause:3.6",
+		"mirror.contoso.local:5000/aks/pause":        "mirror.contoso.local:5000/aks/pause:latest",
+		"localhost/app":                              "localhost/app:latest",
+		"nginx@sha256:0123":                          "docker.io/library/nginx@sha256:0123",
+	}
+	for image, want := range tests {
+		if got := normalizeImageReference(image); got != want {
+			t.Errorf("normalizeImageReference(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestParseImageList(t *testing.T) {
+	list := "# critical DaemonSets\nmcr.microsoft.com/oss/kubernetes/pause:3.6\n\n  nginx:1.27 busybox # debugging\n"
+	want := []string{"mcr.microsoft.com/oss/kubernetes/pause:3.6", "nginx:1.27", "busybox"}
+	if got := parseImageList(list); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseImageList() = %v, want %v", got, want)
+	}
+}
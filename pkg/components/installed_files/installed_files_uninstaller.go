@@ -0,0 +1,222 @@
+package installed_files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// StartRecording records every path bootstrap and upgrade create into the manifest until the returned function
+// is called. The agent's logs and state are left out, since unbootstrap keeps writing them.
+func StartRecording(cfg *config.Config) (stop func()) {
+	manifest := stateManifest{cfg: cfg}
+	return utilio.RecordCreatedPaths(manifest, manifest.originalsDir(), cfg.Agent.LogDir, cfg.Agent.StateStore.Path)
+}
+
+// CreatedPaths narrows the paths a component uninstaller removes to those bootstrap created according to the
+// manifest, so directories such as /etc/default or /opt/cni/bin that existed before bootstrap are kept. Once
+// unbootstrap cleared the manifest none are left. Nodes bootstrapped before the manifest existed get all paths.
+func CreatedPaths(paths ...string) []string {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return paths
+	}
+	return createdPaths(stateManifest{cfg: cfg}, paths)
+}
+
+// createdPaths returns the paths the manifest lists as created, or all of them without a manifest
+func createdPaths(store utilio.ManifestStore, paths []string) []string {
+	manifest, err := store.LoadManifest()
+	if err != nil {
+		return paths
+	}
+	var created []string
+	for _, path := range paths {
+		if manifest.Created(path) {
+			created = append(created, path)
+		}
+	}
+	return created
+}
+
+// LoadedModules returns the kernel modules bootstrap loaded according to the manifest, which unbootstrap
+// unloads. Nodes bootstrapped before modules were recorded get none.
+func LoadedModules() []string {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return nil
+	}
+	manifest, err := stateManifest{cfg: cfg}.LoadManifest()
+	if err != nil {
+		return nil
+	}
+	return manifest.LoadedModules()
+}
+
+// UnInstaller removes what the manifest lists after the component uninstallers ran. The manifest is written
+// by the agent version that created the paths, so it also covers paths a newer or older version no longer
+// knows about. Nodes bootstrapped before the manifest existed rely on the component uninstallers alone.
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new installed files UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "InstalledFilesRemoved"
+}
+
+// IsCompleted reports whether the manifest lists nothing left to clean up
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	manifest, err := stateManifest{cfg: u.config}.LoadManifest()
+	return errors.Is(err, os.ErrNotExist) || (err == nil && len(manifest.Entries) == 0)
+}
+
+// Execute stops and disables the recorded units, removes the recorded files and the recorded directories
+// that are empty then, puts back the originals of replaced files, and clears the manifest once everything
+// is done
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	store := stateManifest{cfg: u.config}
+	manifest, err := store.LoadManifest()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range manifest.Entries {
+		if entry.Kind == utilio.ManifestKindUnit && utils.FileExists(entry.Path) {
+			unit := filepath.Base(entry.Path)
+			if err := utils.StopService(unit); err != nil {
+				u.logger.Debugf("Failed to stop %s: %v", unit, err)
+			}
+			if err := utils.DisableService(unit); err != nil {
+				u.logger.Debugf("Failed to disable %s: %v", unit, err)
+			}
+		}
+	}
+
+	var failed []string
+	reloadSystemd, reloadSysctl := false, false
+	for _, entry := range removalOrder(manifest.Entries) {
+		removed, err := removeEntry(entry)
+		if err != nil {
+			u.logger.Warnf("Failed to remove %s: %v", entry.Path, err)
+			failed = append(failed, entry.Path)
+			continue
+		}
+		if !removed {
+			continue
+		}
+		u.logger.Debugf("Removed or restored %s %s", entry.Kind, entry.Path)
+		if strings.HasPrefix(entry.Path, "/etc/systemd/") {
+			reloadSystemd = true
+		}
+		if entry.Kind == utilio.ManifestKindSysctl || strings.HasPrefix(entry.Path, "/etc/sysctl.d/") {
+			reloadSysctl = true
+		}
+	}
+
+	if reloadSystemd {
+		if err := utils.ReloadSystemd(); err != nil {
+			u.logger.Warnf("Failed to reload systemd: %v", err)
+		}
+	}
+	if reloadSysctl {
+		if err := utils.RunSystemCommand("sysctl", "--system"); err != nil {
+			u.logger.Warnf("Failed to reload sysctl settings: %v", err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove %d installed paths: %s", len(failed), strings.Join(failed, ", "))
+	}
+	if err := utilio.ClearManifest(store, store.originalsDir()); err != nil {
+		return fmt.Errorf("failed to clear the installed files manifest: %w", err)
+	}
+	u.logger.Infof("Removed %d installed paths recorded in the state store", len(manifest.Entries))
+	return nil
+}
+
+// removalOrder returns the files before the directories, and the directories deepest first, so each
+// directory is empty by the time it is removed unless something else was put there
+func removalOrder(entries []utilio.ManifestEntry) []utilio.ManifestEntry {
+	ordered := slices.Clone(entries)
+	slices.SortStableFunc(ordered, func(a, b utilio.ManifestEntry) int {
+		aDir, bDir := a.Kind == utilio.ManifestKindDirectory, b.Kind == utilio.ManifestKindDirectory
+		switch {
+		case aDir != bDir && aDir:
+			return 1
+		case aDir != bDir:
+			return -1
+		case aDir:
+			return strings.Count(b.Path, "/") - strings.Count(a.Path, "/")
+		default:
+			return 0
+		}
+	})
+	return ordered
+}
+
+// removeEntry removes a recorded path, or puts back the original of a replaced file, and reports whether
+// anything changed. Directories that are not empty are kept, since they hold files the agent did not create.
+func removeEntry(entry utilio.ManifestEntry) (bool, error) {
+	switch entry.Kind {
+	case utilio.ManifestKindReplaced:
+		return restoreOriginal(entry)
+	case utilio.ManifestKindModule:
+		// Unloaded by the host snapshot restorer
+		return false, nil
+	}
+	err := os.Remove(entry.Path)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, os.ErrNotExist):
+		return false, nil
+	case entry.Kind == utilio.ManifestKindDirectory:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// restoreOriginal puts back the original of a replaced file, also where a component uninstaller removed the
+// file or its directory
+func restoreOriginal(entry utilio.ManifestEntry) (bool, error) {
+	original, err := os.Open(entry.Backup)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = original.Close() }()
+	info, err := original.Stat()
+	if err != nil {
+		return false, err
+	}
+	if err := utilio.InstallFile(entry.Path, original, info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
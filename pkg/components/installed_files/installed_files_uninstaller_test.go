@@ -0,0 +1,58 @@
+package installed_files
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+func TestRemovalOrder(t *testing.T) {
+	entries := []utilio.ManifestEntry{
+		{Path: "/opt/cni", Kind: utilio.ManifestKindDirectory},
+		{Path: "/opt/cni/bin", Kind: utilio.ManifestKindDirectory},
+		{Path: "/opt/cni/bin/bridge", Kind: utilio.ManifestKindFile},
+		{Path: "/etc/systemd/system/flanneld.service", Kind: utilio.ManifestKindUnit},
+	}
+	want := []utilio.ManifestEntry{entries[2], entries[3], entries[1], entries[0]}
+	if got := removalOrder(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("removalOrder() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCreatedPaths(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agent.StateStore = config.StateStoreConfig{Type: config.StateStoreTypeFile, Path: t.TempDir()}
+	store := stateManifest{cfg: cfg}
+
+	paths := []string{"/etc/default", "/etc/kubernetes/manifests", "/opt/cni/bin"}
+	if got := createdPaths(store, paths); !reflect.DeepEqual(got, paths) {
+		t.Errorf("createdPaths() without a manifest = %v, want all paths", got)
+	}
+
+	manifest := &utilio.Manifest{Entries: []utilio.ManifestEntry{
+		{Path: "/etc/kubernetes/manifests", Kind: utilio.ManifestKindDirectory},
+		{Path: "/etc/default", Kind: utilio.ManifestKindReplaced},
+	}}
+	if err := store.SaveManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if got := createdPaths(store, paths); !reflect.DeepEqual(got, []string{"/etc/kubernetes/manifests"}) {
+		t.Errorf("createdPaths() = %v, want only the recorded directory", got)
+	}
+
+	if err := utilio.ClearManifest(store, store.originalsDir()); err != nil {
+		t.Fatal(err)
+	}
+	if got := createdPaths(store, paths); len(got) != 0 {
+		t.Errorf("createdPaths() after unbootstrap = %v, want none", got)
+	}
+	uninstaller := &UnInstaller{config: cfg, logger: logrus.New()}
+	if !uninstaller.IsCompleted(context.Background()) {
+		t.Error("IsCompleted() = false with a cleared manifest")
+	}
+}
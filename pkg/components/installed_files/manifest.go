@@ -0,0 +1,66 @@
+package installed_files
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/state"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// manifestKey is the state key of the manifest of the files, directories, systemd units and sysctl files
+// bootstrap created
+const manifestKey = "installed-files"
+
+// stateManifest keeps the manifest in the agent's state store. It is written once per created path, so it
+// bypasses the ConfigMap mirror, which picks it up with the next checkpoint.
+type stateManifest struct {
+	cfg *config.Config
+}
+
+// originalsDir returns the directory holding the originals of the files bootstrap replaced, under their own
+// paths. It lives next to the state, which is never recorded.
+func (m stateManifest) originalsDir() string {
+	return filepath.Join(m.cfg.Agent.StateStore.Path, "originals")
+}
+
+// LoadManifest returns the recorded manifest, or an error wrapping os.ErrNotExist when nothing was recorded
+func (m stateManifest) LoadManifest() (*utilio.Manifest, error) {
+	store, err := state.OpenLocal(m.cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = store.Close() }()
+
+	data, err := store.Get(manifestKey)
+	if errors.Is(err, state.ErrNotFound) {
+		return nil, fmt.Errorf("no installed files manifest: %w", os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest := &utilio.Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode installed files manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// SaveManifest replaces the recorded manifest
+func (m stateManifest) SaveManifest(manifest *utilio.Manifest) error {
+	store, err := state.OpenLocal(m.cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode installed files manifest: %w", err)
+	}
+	return store.Put(manifestKey, data)
+}
@@ -0,0 +1,21 @@
+package kata
+
+const (
+	// kataInstallDir holds the Kata static release: its runtime, shim, hypervisors, guest kernel and image
+	kataInstallDir = "/opt/kata"
+
+	// kataRuntimePath is the kata-runtime CLI, linked from the release
+	kataRuntimePath = "/usr/local/bin/kata-runtime"
+
+	// shimBinaryPath is the containerd shim for the io.containerd.kata.v2 runtime type, found by containerd on PATH
+	shimBinaryPath = "/usr/local/bin/containerd-shim-kata-v2"
+
+	// kvmDevice is the device the Kata hypervisors start pod VMs through
+	kvmDevice = "/dev/kvm"
+
+	// runtimeClassName is the RuntimeClass pods set to run in Kata VMs
+	runtimeClassName = "kata"
+)
+
+// kataDownloadURL is the URL of a Kata static release, by release and architecture
+var kataDownloadURL = "https://github.com/kata-containers/kata-containers/releases/download/%[1]s/kata-static-%[1]s-%[2]s.tar.xz"
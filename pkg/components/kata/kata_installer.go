@@ -0,0 +1,194 @@
+package kata
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer installs the Kata Containers static release into /opt/kata and links its runtime and containerd
+// shim into /usr/local/bin. containerd registers the kata runtime handler when runtime.kata is enabled, so
+// pods can select it through a RuntimeClass.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new Kata Containers Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "KataInstaller"
+}
+
+// Validate checks that the host supports hardware virtualization and that the release can be extracted
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.config.Runtime.Kata.Enabled {
+		return nil
+	}
+	for _, binary := range []string{"tar", "xz"} {
+		if !utils.BinaryExists(binary) {
+			return fmt.Errorf("%s is required to extract the Kata Containers release", binary)
+		}
+	}
+	return i.checkVirtualization()
+}
+
+// Execute installs the configured release and creates the RuntimeClass when requested, or removes Kata when
+// runtime.kata is turned off
+func (i *Installer) Execute(ctx context.Context) error {
+	kata := &i.config.Runtime.Kata
+	if !kata.Enabled {
+		if isKataInstalled() {
+			i.logger.Info("runtime.kata.enabled is off, removing Kata Containers")
+			removeKata(i.logger)
+		}
+		return nil
+	}
+
+	if !i.isVersionInstalled() {
+		i.logger.Infof("Installing Kata Containers %s", kata.Version)
+		if err := installRelease(ctx, kata.Version); err != nil {
+			return err
+		}
+	}
+	for _, link := range []string{kataRuntimePath, shimBinaryPath} {
+		if err := linkBinary(link); err != nil {
+			return err
+		}
+	}
+
+	// kata-runtime check probes the host the way pod VMs use it; containerd still runs them if it complains
+	if output, err := utils.RunCommandWithOutput(kataRuntimePath, "check", "--no-network-checks"); err != nil {
+		i.logger.Warnf("kata-runtime check reports problems, Kata pods may fail to start: %s", lastLine(output))
+	}
+
+	if kata.RuntimeClass {
+		// Best effort: the node's credentials may not be allowed to write cluster-wide objects
+		err := utils.ApplyRuntimeClass(ctx, kubelet.KubeletKubeconfigPath, runtimeClassName, config.KataRuntimeHandler, config.KataRuntimeLabel)
+		if err != nil {
+			i.logger.Warnf("Failed to create the %s RuntimeClass, create it with cluster admin credentials: %v", runtimeClassName, err)
+		}
+	}
+	i.logger.Infof("Kata Containers %s installed", kata.Version)
+	return nil
+}
+
+// IsCompleted reports whether the configured release is installed and linked, or Kata is absent when turned off
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if !i.config.Runtime.Kata.Enabled {
+		return !isKataInstalled()
+	}
+	return i.isVersionInstalled() && utils.FileExists(kataRuntimePath) && utils.FileExists(shimBinaryPath)
+}
+
+// checkVirtualization checks that the CPU exposes VT-x or AMD-V and that KVM is available, loading its module
+// if needed
+func (i *Installer) checkVirtualization() error {
+	if utilhost.GetArch() == "amd64" {
+		flags, err := utilhost.CPUFlags()
+		if err != nil {
+			return err
+		}
+		if !utilhost.HasHardwareVirtualization(flags) {
+			if utilhost.IsVirtualMachine(flags) {
+				return fmt.Errorf("kata requires hardware virtualization, but this VM does not expose VT-x or AMD-V; use a VM size with nested virtualization")
+			}
+			return fmt.Errorf("kata requires hardware virtualization; enable VT-x or AMD-V in the firmware")
+		}
+		if !utils.FileExists(kvmDevice) {
+			module := "kvm_intel"
+			if flags["svm"] {
+				module = "kvm_amd"
+			}
+			if err := utilhost.LoadKernelModule(module); err != nil {
+				i.logger.Warnf("Failed to load the %s module: %v", module, err)
+			}
+		}
+	}
+	if !utils.FileExists(kvmDevice) {
+		return fmt.Errorf("kata requires %s; load the KVM module for the CPU", kvmDevice)
+	}
+	return nil
+}
+
+// isVersionInstalled reports whether the installed release is the configured one
+func (i *Installer) isVersionInstalled() bool {
+	output, err := utils.RunCommandWithOutput(filepath.Join(kataInstallDir, "bin", "kata-runtime"), "--version")
+	return err == nil && reportsVersion(output, i.config.Runtime.Kata.Version)
+}
+
+// installRelease replaces /opt/kata with the static release. Its archive holds the tree below ./opt/kata.
+func installRelease(ctx context.Context, version string) error {
+	archive, err := os.CreateTemp("", "kata-static-*.tar.xz")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file: %w", err)
+	}
+	archivePath := archive.Name()
+	_ = archive.Close()
+	defer func() { _ = os.Remove(archivePath) }()
+
+	url := fmt.Sprintf(kataDownloadURL, version, utilhost.GetArch())
+	if err := utilio.DownloadToLocalFile(ctx, url, archivePath, 0o644); err != nil {
+		return fmt.Errorf("failed to download Kata Containers %s: %w", version, err)
+	}
+	if err := utils.RunCleanupCommand(kataInstallDir); err != nil {
+		return fmt.Errorf("failed to remove the previous Kata Containers release: %w", err)
+	}
+	recordRelease := utilio.TrackCreated(kataInstallDir)
+	if err := utils.RunSystemCommand("tar", "-xJf", archivePath, "-C", "/", "./opt/kata"); err != nil {
+		return fmt.Errorf("failed to extract Kata Containers %s: %w", version, err)
+	}
+	recordRelease()
+	return nil
+}
+
+// linkBinary links a binary in /usr/local/bin to the one of the same name in the release
+func linkBinary(link string) error {
+	target := filepath.Join(kataInstallDir, "bin", filepath.Base(link))
+	if current, err := os.Readlink(link); err == nil && current == target {
+		return nil
+	}
+	if err := utils.RunCleanupCommand(link); err != nil {
+		return err
+	}
+	recordLink := utilio.TrackCreated(link)
+	if err := os.Symlink(target, link); err != nil {
+		return fmt.Errorf("failed to link %s: %w", link, err)
+	}
+	recordLink()
+	return nil
+}
+
+// reportsVersion reports whether kata-runtime --version output, e.g. "kata-runtime  : 3.13.0", names a release
+func reportsVersion(output, version string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(name) == "kata-runtime" {
+			return strings.TrimSpace(value) == version
+		}
+	}
+	return false
+}
+
+// lastLine returns the last non-empty line of command output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,20 @@
+package kata
+
+import "testing"
+
+func TestReportsVersion(t *testing.T) {
+	output := "kata-runtime  : 3.13.0\n   commit   : 8d0b2c3d1d1b2b0e\n   OCI specs: 1.1.0\n"
+	tests := map[string]bool{
+		"3.13.0": true,
+		"3.13.1": false,
+		"3.1":    false,
+	}
+	for version, want := range tests {
+		if got := reportsVersion(output, version); got != want {
+			t.Errorf("reportsVersion(%q) = %v, want %v", version, got, want)
+		}
+	}
+	if reportsVersion("", "3.13.0") {
+		t.Errorf("reportsVersion() without output = true")
+	}
+}
@@ -0,0 +1,69 @@
+package kata
+
+import (
+	"context"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/installed_files"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller removes the Kata Containers release and its links. The RuntimeClass is cluster-wide and stays for
+// the other nodes.
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new Kata Containers UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "KataUnInstaller"
+}
+
+// Execute removes the Kata release and its links
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isKataInstalled() {
+		return nil
+	}
+	removeKata(u.logger)
+	u.logger.Info("Kata Containers removed")
+	return nil
+}
+
+// IsCompleted reports whether nothing of Kata is left on the node
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isKataInstalled()
+}
+
+// isKataInstalled reports whether the release or any of its links is present. FileExists follows links, so
+// dangling links are checked with Lstat.
+func isKataInstalled() bool {
+	return utils.DirectoryExists(kataInstallDir) || isLink(kataRuntimePath) || isLink(shimBinaryPath)
+}
+
+// removeKata removes the Kata links and release. Failures are logged, so cleanup continues.
+func removeKata(logger *logrus.Logger) {
+	for _, err := range utils.RemoveFiles([]string{kataRuntimePath, shimBinaryPath}, logger) {
+		logger.Warnf("Failed to remove Kata Containers link: %v", err)
+	}
+	for _, err := range utils.RemoveDirectories(installed_files.CreatedPaths(kataInstallDir), logger) {
+		logger.Warnf("Failed to remove Kata Containers: %v", err)
+	}
+}
+
+// isLink reports whether path is a symbolic link, even if its target is gone
+func isLink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -74,7 +74,8 @@ func (i *Installer) installKubeBinaries(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	// Give kubelet the kubelet_exec_t label on SELinux hosts
+	return utilhost.RestoreSELinuxContexts(kubeBinariesPaths...)
 }
 
 // IsCompleted checks if all Kube binaries are installed
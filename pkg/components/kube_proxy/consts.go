@@ -0,0 +1,25 @@
+package kube_proxy
+
+const (
+	// kube-proxy binary, installed by the kube binaries step from the Kubernetes node archive
+	kubeProxyBinaryPath = "/usr/local/bin/kube-proxy"
+
+	// Configuration and service files
+	kubeProxyConfigDir    = "/var/lib/kube-proxy"
+	kubeProxyConfigPath   = "/var/lib/kube-proxy/config.yaml"
+	kubeProxyServiceName  = "kube-proxy"
+	kubeProxyServicePath  = "/etc/systemd/system/kube-proxy.service"
+	kubeProxyManifestPath = "/etc/kubernetes/manifests/kube-proxy.yaml"
+
+	// ipvsModulesPath loads the IPVS kernel modules on every boot
+	ipvsModulesPath = "/etc/modules-load.d/kube-proxy-ipvs.conf"
+)
+
+// ipvsModules are the kernel modules IPVS mode needs, one per scheduler plus connection tracking
+var ipvsModules = []string{
+	"ip_vs",
+	"ip_vs_rr",
+	"ip_vs_wrr",
+	"ip_vs_sh",
+	"nf_conntrack",
+}
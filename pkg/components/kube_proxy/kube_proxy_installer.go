@@ -0,0 +1,290 @@
+package kube_proxy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer runs kube-proxy on the node, as a systemd service or as a static pod, for clusters that do not
+// schedule the kube-proxy DaemonSet onto flex nodes
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new kube-proxy Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "KubeProxyInstaller"
+}
+
+// Validate checks that the kube-proxy binary is present when kube-proxy runs as a systemd service
+func (i *Installer) Validate(ctx context.Context) error {
+	kubeProxy := &i.config.KubeProxy
+	if kubeProxy.Enabled && kubeProxy.RunAs == config.KubeProxyRunAsSystemd && !utils.FileExists(kubeProxyBinaryPath) {
+		return fmt.Errorf("kube-proxy binary %s not found; it is installed from the Kubernetes node archive", kubeProxyBinaryPath)
+	}
+	return nil
+}
+
+// Execute writes the kube-proxy configuration and starts kube-proxy, or removes it when kubeProxy.enabled
+// is turned off
+func (i *Installer) Execute(ctx context.Context) error {
+	if !i.config.KubeProxy.Enabled {
+		if isKubeProxyInstalled() {
+			i.logger.Info("kubeProxy.enabled is off, removing kube-proxy")
+			removeKubeProxy(i.logger)
+		}
+		return nil
+	}
+
+	kubeProxy := &i.config.KubeProxy
+	i.logger.Infof("Configuring kube-proxy in %s mode as %s", kubeProxy.Mode, kubeProxy.RunAs)
+	if !utils.BinaryExists("conntrack") {
+		i.logger.Warn("conntrack is not installed; kube-proxy cannot clear stale UDP connections after endpoints change")
+	}
+
+	if err := i.configureIPVSModules(); err != nil {
+		return err
+	}
+
+	if err := utilio.MkdirAll(kubeProxyConfigDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", kubeProxyConfigDir, err)
+	}
+	if err := utilio.WriteFile(kubeProxyConfigPath, []byte(kubeProxyConfiguration(kubeProxy)), 0o644); err != nil {
+		return fmt.Errorf("failed to write kube-proxy configuration: %w", err)
+	}
+
+	env, err := i.proxyEnvironment()
+	if err != nil {
+		return err
+	}
+	if kubeProxy.RunAs == config.KubeProxyRunAsStaticPod {
+		return i.installStaticPod(env)
+	}
+	return i.installService(env)
+}
+
+// IsCompleted reports whether kube-proxy runs with the current configuration, or is absent when disabled
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if !i.config.KubeProxy.Enabled {
+		return !isKubeProxyInstalled()
+	}
+
+	kubeProxy := &i.config.KubeProxy
+	if !utils.FileHasContent(kubeProxyConfigPath, kubeProxyConfiguration(kubeProxy)) {
+		return false
+	}
+	if utils.FileExists(ipvsModulesPath) != (kubeProxy.Mode == config.KubeProxyModeIPVS) {
+		return false
+	}
+	env, err := i.proxyEnvironment()
+	if err != nil {
+		i.logger.Debugf("Failed to read the proxy environment of kube-proxy: %v", err)
+		return false
+	}
+	if kubeProxy.RunAs == config.KubeProxyRunAsStaticPod {
+		return !utils.FileExists(kubeProxyServicePath) &&
+			utils.FileHasContent(kubeProxyManifestPath, kubeProxyManifest(i.config.GetImage(config.ImageKubeProxy), env))
+	}
+	return !utils.FileExists(kubeProxyManifestPath) &&
+		utils.FileHasContent(kubeProxyServicePath, kubeProxyUnit(env)) &&
+		utils.IsServiceActive(kubeProxyServiceName)
+}
+
+// configureIPVSModules loads the IPVS kernel modules now and on every boot in IPVS mode, and stops loading
+// them otherwise
+func (i *Installer) configureIPVSModules() error {
+	if i.config.KubeProxy.Mode != config.KubeProxyModeIPVS {
+		if err := utils.RunCleanupCommand(ipvsModulesPath); err != nil {
+			i.logger.Warnf("Failed to remove %s: %v", ipvsModulesPath, err)
+		}
+		return nil
+	}
+	for _, module := range ipvsModules {
+		if err := utilhost.LoadKernelModule(module); err != nil {
+			return fmt.Errorf("failed to load kernel module %s required by IPVS mode: %w", module, err)
+		}
+	}
+	if err := utilio.WriteFile(ipvsModulesPath, []byte(strings.Join(ipvsModules, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ipvsModulesPath, err)
+	}
+	if !utils.BinaryExists("ipset") {
+		i.logger.Warn("ipset is not installed; kube-proxy in IPVS mode needs it for NodePort and masquerade rules")
+	}
+	return nil
+}
+
+// installService runs kube-proxy as a systemd service, replacing a static pod from an earlier configuration
+func (i *Installer) installService(env []string) error {
+	if err := utils.RunCleanupCommand(kubeProxyManifestPath); err != nil {
+		i.logger.Warnf("Failed to remove kube-proxy static pod manifest: %v", err)
+	}
+	if err := utilio.WriteFile(kubeProxyServicePath, []byte(kubeProxyUnit(env)), 0o644); err != nil {
+		return fmt.Errorf("failed to write kube-proxy service file: %w", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := utils.EnableAndStartService(kubeProxyServiceName); err != nil {
+		return fmt.Errorf("failed to start kube-proxy: %w", err)
+	}
+	// Pick up configuration changes when the service was already running
+	if err := utils.RestartService(kubeProxyServiceName); err != nil {
+		return fmt.Errorf("failed to restart kube-proxy: %w", err)
+	}
+	i.logger.Info("kube-proxy service started")
+	return nil
+}
+
+// installStaticPod hands kube-proxy to kubelet as a static pod, replacing a systemd service from an earlier
+// configuration
+func (i *Installer) installStaticPod(env []string) error {
+	if utils.FileExists(kubeProxyServicePath) {
+		stopKubeProxyService(i.logger)
+	}
+	manifest := kubeProxyManifest(i.config.GetImage(config.ImageKubeProxy), env)
+	if err := utilio.WriteFile(kubeProxyManifestPath, []byte(manifest), 0o644); err != nil {
+		return fmt.Errorf("failed to write kube-proxy static pod manifest: %w", err)
+	}
+	i.logger.Infof("kube-proxy static pod manifest written to %s", kubeProxyManifestPath)
+	return nil
+}
+
+// proxyEnvironment returns the network.proxy variables for kube-proxy, with the API server of the kubelet
+// kubeconfig reached directly
+func (i *Installer) proxyEnvironment() ([]string, error) {
+	if !i.config.IsProxyConfigured() {
+		return nil, nil
+	}
+	kubeconfig, err := os.ReadFile(kubelet.KubeletKubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubelet kubeconfig file: %w", err)
+	}
+	serverURL, _, err := utils.ExtractClusterInfo(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract cluster info: %w", err)
+	}
+	return i.config.GetProxyEnvironment(serverURL), nil
+}
+
+// kubeProxyConfiguration renders the KubeProxyConfiguration, which reads the kubelet kubeconfig so kube-proxy
+// authenticates as the node
+func kubeProxyConfiguration(cfg *config.KubeProxyConfig) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `apiVersion: kubeproxy.config.k8s.io/v1alpha1
+kind: KubeProxyConfiguration
+clientConnection:
+  kubeconfig: %s
+mode: %s
+`, kubelet.KubeletKubeconfigPath, cfg.Mode)
+	if cfg.ClusterCIDR != "" {
+		fmt.Fprintf(&sb, "clusterCIDR: %q\n", cfg.ClusterCIDR)
+	}
+	fmt.Fprintf(&sb, `conntrack:
+  maxPerCore: %d
+  min: %d
+  tcpEstablishedTimeout: %s
+  tcpCloseWaitTimeout: %s
+`, cfg.Conntrack.MaxPerCore, cfg.Conntrack.Min, cfg.Conntrack.TCPEstablishedTimeout, cfg.Conntrack.TCPCloseWaitTimeout)
+	if cfg.Mode == config.KubeProxyModeIPVS {
+		sb.WriteString("ipvs:\n  scheduler: rr\n")
+	}
+	return sb.String()
+}
+
+// kubeProxyUnit renders the kube-proxy systemd service with the given environment variables
+func kubeProxyUnit(env []string) string {
+	var environment strings.Builder
+	for _, variable := range env {
+		fmt.Fprintf(&environment, "Environment=\"%s\"\n", variable)
+	}
+	return fmt.Sprintf(`[Unit]
+Description=Kubernetes network proxy
+Documentation=https://kubernetes.io/docs/reference/command-line-tools-reference/kube-proxy/
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+ExecStart=%s --config=%s
+%sRestart=always
+RestartSec=5s
+
+[Install]
+WantedBy=multi-user.target
+`, kubeProxyBinaryPath, kubeProxyConfigPath, environment.String())
+}
+
+// kubeProxyManifest renders the kube-proxy static pod with the given image and environment variables
+func kubeProxyManifest(image string, env []string) string {
+	var environment strings.Builder
+	if len(env) > 0 {
+		environment.WriteString("    env:\n")
+		for _, variable := range env {
+			name, value, _ := strings.Cut(variable, "=")
+			fmt.Fprintf(&environment, "    - name: %s\n      value: %q\n", name, value)
+		}
+	}
+	return fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: kube-proxy
+  namespace: kube-system
+  labels:
+    component: kube-proxy
+spec:
+  hostNetwork: true
+  priorityClassName: system-node-critical
+  containers:
+  - name: kube-proxy
+    image: %s
+    command:
+    - kube-proxy
+    - --config=%s
+%s    securityContext:
+      privileged: true
+    volumeMounts:
+    - name: config
+      mountPath: %s
+      readOnly: true
+    - name: kubelet
+      mountPath: /var/lib/kubelet
+      readOnly: true
+    - name: xtables-lock
+      mountPath: /run/xtables.lock
+    - name: lib-modules
+      mountPath: /lib/modules
+      readOnly: true
+  volumes:
+  - name: config
+    hostPath:
+      path: %s
+  - name: kubelet
+    hostPath:
+      path: /var/lib/kubelet
+  - name: xtables-lock
+    hostPath:
+      path: /run/xtables.lock
+      type: FileOrCreate
+  - name: lib-modules
+    hostPath:
+      path: /lib/modules
+`, image, kubeProxyConfigPath, environment.String(), kubeProxyConfigDir, kubeProxyConfigDir)
+}
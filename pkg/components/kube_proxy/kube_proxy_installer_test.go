@@ -0,0 +1,68 @@
+package kube_proxy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestKubeProxyConfiguration(t *testing.T) {
+	cfg := &config.KubeProxyConfig{
+		Mode:        config.KubeProxyModeIPVS,
+		ClusterCIDR: "10.244.0.0/16",
+		Conntrack: config.KubeProxyConntrackConfig{
+			MaxPerCore:            65536,
+			Min:                   262144,
+			TCPEstablishedTimeout: 2 * time.Hour,
+			TCPCloseWaitTimeout:   time.Hour,
+		},
+	}
+	got := kubeProxyConfiguration(cfg)
+	for _, want := range []string{
+		"kind: KubeProxyConfiguration",
+		"  kubeconfig: /var/lib/kubelet/kubeconfig\n",
+		"mode: ipvs\n",
+		`clusterCIDR: "10.244.0.0/16"`,
+		"  maxPerCore: 65536\n",
+		"  min: 262144\n",
+		"  tcpEstablishedTimeout: 2h0m0s\n",
+		"  tcpCloseWaitTimeout: 1h0m0s\n",
+		"ipvs:\n  scheduler: rr\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("kubeProxyConfiguration() is missing %q:\n%s", want, got)
+		}
+	}
+
+	cfg.Mode = config.KubeProxyModeIptables
+	cfg.ClusterCIDR = ""
+	if got := kubeProxyConfiguration(cfg); strings.Contains(got, "ipvs:") || strings.Contains(got, "clusterCIDR") {
+		t.Errorf("kubeProxyConfiguration() in iptables mode without a cluster CIDR:\n%s", got)
+	}
+}
+
+func TestKubeProxyUnitAndManifest(t *testing.T) {
+	env := []string{"HTTPS_PROXY=http://proxy.corp:3128", "NO_PROXY=localhost,10.0.0.0/16"}
+
+	unit := kubeProxyUnit(env)
+	if !strings.Contains(unit, "ExecStart=/usr/local/bin/kube-proxy --config=/var/lib/kube-proxy/config.yaml\n") ||
+		!strings.Contains(unit, "Environment=\"HTTPS_PROXY=http://proxy.corp:3128\"\n") {
+		t.Errorf("kubeProxyUnit() =\n%s", unit)
+	}
+
+	manifest := kubeProxyManifest("mcr.microsoft.com/oss/kubernetes/kube-proxy:v1.30.9", env)
+	for _, want := range []string{
+		"    image: mcr.microsoft.com/oss/kubernetes/kube-proxy:v1.30.9\n",
+		"    - name: NO_PROXY\n      value: \"localhost,10.0.0.0/16\"\n",
+		"  hostNetwork: true\n",
+	} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("kubeProxyManifest() is missing %q:\n%s", want, manifest)
+		}
+	}
+	if strings.Contains(kubeProxyManifest("kube-proxy:v1.30.9", nil), "env:") {
+		t.Error("kubeProxyManifest() without proxy variables has an env section")
+	}
+}
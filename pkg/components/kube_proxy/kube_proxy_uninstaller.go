@@ -0,0 +1,81 @@
+package kube_proxy
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/installed_files"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller stops kube-proxy and removes its configuration
+type UnInstaller struct {
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new kube-proxy UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "KubeProxyUnInstaller"
+}
+
+// Execute stops kube-proxy and removes its service, static pod manifest and configuration
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isKubeProxyInstalled() {
+		return nil
+	}
+	removeKubeProxy(u.logger)
+	u.logger.Info("kube-proxy removed")
+	return nil
+}
+
+// IsCompleted reports whether no kube-proxy files are left on the node
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isKubeProxyInstalled()
+}
+
+// isKubeProxyInstalled reports whether any file written by the kube-proxy installer is present
+func isKubeProxyInstalled() bool {
+	for _, path := range append([]string{kubeProxyServicePath, kubeProxyManifestPath, ipvsModulesPath}, installed_files.CreatedPaths(kubeProxyConfigDir)...) {
+		if utils.FileExists(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeKubeProxy stops kube-proxy and removes its files. Failures are logged, so cleanup continues.
+func removeKubeProxy(logger *logrus.Logger) {
+	if utils.FileExists(kubeProxyServicePath) {
+		stopKubeProxyService(logger)
+	}
+	for _, err := range utils.RemoveFiles([]string{kubeProxyManifestPath, ipvsModulesPath}, logger) {
+		logger.Warnf("Failed to remove kube-proxy file: %v", err)
+	}
+	for _, err := range utils.RemoveDirectories(installed_files.CreatedPaths(kubeProxyConfigDir), logger) {
+		logger.Warnf("Failed to remove kube-proxy configuration: %v", err)
+	}
+}
+
+// stopKubeProxyService stops and disables the kube-proxy service and removes its unit
+func stopKubeProxyService(logger *logrus.Logger) {
+	if err := utils.StopService(kubeProxyServiceName); err != nil {
+		logger.Debugf("Failed to stop kube-proxy: %v", err)
+	}
+	if err := utils.DisableService(kubeProxyServiceName); err != nil {
+		logger.Debugf("Failed to disable kube-proxy: %v", err)
+	}
+	if err := utils.RunCleanupCommand(kubeProxyServicePath); err != nil {
+		logger.Warnf("Failed to remove kube-proxy service file: %v", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		logger.Warnf("Failed to reload systemd: %v", err)
+	}
+}
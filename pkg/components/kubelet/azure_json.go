@@ -0,0 +1,125 @@
+package kubelet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// vmType values of azure.json
+const (
+	vmTypeStandard = "standard"
+	vmTypeVMSS     = "vmss"
+)
+
+// azureCloudConfig holds the fields of azure.json the Azure cloud provider, the Azure CSI drivers and the
+// acr-credential-provider read
+type azureCloudConfig struct {
+	Cloud                                 string `json:"cloud"`
+	TenantID                              string `json:"tenantId"`
+	SubscriptionID                        string `json:"subscriptionId,omitempty"`
+	AADClientID                           string `json:"aadClientId,omitempty"`
+	AADClientSecret                       string `json:"aadClientSecret,omitempty"`
+	AADFederatedTokenFile                 string `json:"aadFederatedTokenFile,omitempty"`
+	UseFederatedWorkloadIdentityExtension bool   `json:"useFederatedWorkloadIdentityExtension,omitempty"`
+	UseManagedIdentityExtension           bool   `json:"useManagedIdentityExtension,omitempty"`
+	UserAssignedIdentityID                string `json:"userAssignedIdentityID,omitempty"`
+
+	// The VM and its network, set on Azure VMs
+	ResourceGroup       string `json:"resourceGroup,omitempty"`
+	Location            string `json:"location,omitempty"`
+	VMType              string `json:"vmType,omitempty"`
+	PrimaryScaleSetName string `json:"primaryScaleSetName,omitempty"`
+	VnetName            string `json:"vnetName,omitempty"`
+	VnetResourceGroup   string `json:"vnetResourceGroup,omitempty"`
+	SubnetName          string `json:"subnetName,omitempty"`
+	SecurityGroupName   string `json:"securityGroupName,omitempty"`
+	RouteTableName      string `json:"routeTableName,omitempty"`
+	UseInstanceMetadata bool   `json:"useInstanceMetadata,omitempty"`
+}
+
+// configureAzureJSON writes /etc/kubernetes/azure.json on Azure VMs, where the Azure cloud provider and CSI
+// drivers read the node's cloud settings from it, and wherever the acr-credential-provider authenticates with it
+func (i *Installer) configureAzureJSON(ctx context.Context) error {
+	vm := utilhost.DetectAzureVM(ctx)
+	if vm == nil && !i.config.Node.Kubelet.AcrCredentialProvider.Enabled {
+		return nil
+	}
+	data, err := azureJSON(i.config, i.identityClientID, vm)
+	if err != nil {
+		return err
+	}
+	// azure.json may hold the service principal secret
+	if err := utilio.WriteFile(azureJSONPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", azureJSONPath, err)
+	}
+	return nil
+}
+
+// azureJSON renders azure.json with the managed identity, service principal or federated identity of the
+// configuration, and the VM and its network when vm is not nil. Nodes joining with a bootstrap token or Arc get no
+// credentials: the instance metadata endpoint gives Arc machines no tokens. identityClientID is the client ID
+// resolved for a managed identity configured by resource ID.
+func azureJSON(cfg *config.Config, identityClientID string, vm *utilhost.AzureVMMetadata) ([]byte, error) {
+	cloudConfig := azureCloudConfig{
+		Cloud:          cfg.Azure.Cloud,
+		TenantID:       cfg.GetTenantID(),
+		SubscriptionID: cfg.GetSubscriptionID(),
+	}
+	switch {
+	case cfg.IsSPConfigured():
+		sp := cfg.Azure.ServicePrincipal
+		cloudConfig.TenantID = sp.TenantID
+		cloudConfig.AADClientID = sp.ClientID
+		cloudConfig.AADClientSecret = sp.ClientSecret
+	case cfg.IsFederatedIdentityConfigured():
+		federated := cfg.Azure.FederatedIdentity
+		cloudConfig.TenantID = federated.TenantID
+		cloudConfig.AADClientID = federated.ClientID
+		cloudConfig.AADFederatedTokenFile = federated.TokenFile
+		cloudConfig.UseFederatedWorkloadIdentityExtension = true
+	case cfg.IsMIConfigured():
+		cloudConfig.UseManagedIdentityExtension = true
+		if mi := cfg.Azure.ManagedIdentity; mi != nil {
+			switch {
+			case mi.ClientID != "":
+				cloudConfig.UserAssignedIdentityID = mi.ClientID
+			case identityClientID != "":
+				cloudConfig.UserAssignedIdentityID = identityClientID
+			default:
+				cloudConfig.UserAssignedIdentityID = mi.ResourceID
+			}
+		}
+	}
+
+	if vm != nil {
+		network := cfg.Azure.CloudProvider
+		cloudConfig.SubscriptionID = vm.SubscriptionID
+		cloudConfig.ResourceGroup = vm.ResourceGroup
+		cloudConfig.Location = vm.Location
+		cloudConfig.VMType = vmTypeStandard
+		if vm.VMScaleSetName != "" {
+			cloudConfig.VMType = vmTypeVMSS
+			cloudConfig.PrimaryScaleSetName = vm.VMScaleSetName
+		}
+		cloudConfig.VnetName = network.VnetName
+		cloudConfig.VnetResourceGroup = network.VnetResourceGroup
+		if cloudConfig.VnetName != "" && cloudConfig.VnetResourceGroup == "" {
+			cloudConfig.VnetResourceGroup = vm.ResourceGroup
+		}
+		cloudConfig.SubnetName = network.SubnetName
+		cloudConfig.SecurityGroupName = network.SecurityGroupName
+		cloudConfig.RouteTableName = network.RouteTableName
+		cloudConfig.UseInstanceMetadata = true
+	}
+
+	data, err := json.MarshalIndent(cloudConfig, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", azureJSONPath, err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,82 @@
+package kubelet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+func TestAzureJSON(t *testing.T) {
+	vm := &utilhost.AzureVMMetadata{SubscriptionID: "vm-subscription", ResourceGroup: "vm-rg", Location: "eastus"}
+	tests := []struct {
+		name          string
+		azure         config.AzureConfig
+		vm            *utilhost.AzureVMMetadata
+		scaleSet      string
+		cloudProvider config.CloudProviderConfig
+		want          azureCloudConfig
+	}{
+		{
+			name: "service principal",
+			azure: config.AzureConfig{ServicePrincipal: &config.ServicePrincipalConfig{
+				TenantID: "sp-tenant", ClientID: "sp-client", ClientSecret: "secret"}},
+			want: azureCloudConfig{TenantID: "sp-tenant", SubscriptionID: "subscription", AADClientID: "sp-client", AADClientSecret: "secret"},
+		},
+		{
+			name: "federated identity",
+			azure: config.AzureConfig{FederatedIdentity: &config.FederatedIdentityConfig{
+				TenantID: "fi-tenant", ClientID: "fi-client", TokenFile: "/var/run/token"}},
+			want: azureCloudConfig{TenantID: "fi-tenant", SubscriptionID: "subscription", AADClientID: "fi-client",
+				AADFederatedTokenFile: "/var/run/token", UseFederatedWorkloadIdentityExtension: true},
+		},
+		{
+			name: "bootstrap token on a VM",
+			azure: config.AzureConfig{BootstrapToken: &config.BootstrapTokenConfig{
+				Token: "abcdef.0123456789abcdef"}},
+			vm: vm,
+			want: azureCloudConfig{TenantID: "tenant", SubscriptionID: "vm-subscription", ResourceGroup: "vm-rg", Location: "eastus",
+				VMType: vmTypeStandard, UseInstanceMetadata: true},
+		},
+		{
+			name: "scale set instance with its network",
+			azure: config.AzureConfig{ServicePrincipal: &config.ServicePrincipalConfig{
+				TenantID: "sp-tenant", ClientID: "sp-client", ClientSecret: "secret"}},
+			vm:            vm,
+			scaleSet:      "flex-vmss",
+			cloudProvider: config.CloudProviderConfig{VnetName: "flex-vnet", SubnetName: "nodes", SecurityGroupName: "flex-nsg"},
+			want: azureCloudConfig{TenantID: "sp-tenant", SubscriptionID: "vm-subscription", AADClientID: "sp-client", AADClientSecret: "secret",
+				ResourceGroup: "vm-rg", Location: "eastus", VMType: vmTypeVMSS, PrimaryScaleSetName: "flex-vmss",
+				VnetName: "flex-vnet", VnetResourceGroup: "vm-rg", SubnetName: "nodes", SecurityGroupName: "flex-nsg", UseInstanceMetadata: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Azure: tt.azure}
+			cfg.Azure.Cloud = "AzurePublicCloud"
+			cfg.Azure.TenantID = "tenant"
+			cfg.Azure.SubscriptionID = "subscription"
+			cfg.Azure.CloudProvider = tt.cloudProvider
+			var metadata *utilhost.AzureVMMetadata
+			if tt.vm != nil {
+				instance := *tt.vm
+				instance.VMScaleSetName = tt.scaleSet
+				metadata = &instance
+			}
+			data, err := azureJSON(cfg, "", metadata)
+			if err != nil {
+				t.Fatalf("azureJSON() error = %v", err)
+			}
+			var got azureCloudConfig
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("azureJSON() returned invalid JSON: %v", err)
+			}
+			tt.want.Cloud = "AzurePublicCloud"
+			if got != tt.want {
+				t.Errorf("azureJSON() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package kubelet
 
+import "time"
+
 const (
 	// System directories
 	etcDefaultDir     = "/etc/default"
@@ -16,6 +18,8 @@ const (
 	kubeletServicePath        = "/etc/systemd/system/kubelet.service"
 	kubeletContainerdConfig   = "/etc/systemd/system/kubelet.service.d/10-containerd.conf"
 	kubeletTLSBootstrapConfig = "/etc/systemd/system/kubelet.service.d/10-tlsbootstrap.conf"
+	kubeletSocketAccessConfig = "/etc/systemd/system/kubelet.service.d/20-socket-access.conf"
+	kubeletProxyConfig        = "/etc/systemd/system/kubelet.service.d/10-proxy.conf"
 
 	// Runtime configuration paths
 	kubeletConfigPath          = "/var/lib/kubelet/config.yaml"
@@ -24,10 +28,58 @@ const (
 	kubeletVarDir              = "/var/lib/kubelet"
 	KubeletKubeconfigPath      = "/var/lib/kubelet/kubeconfig"
 	kubeletTokenScriptPath     = "/var/lib/kubelet/token.sh"
+	kubeletPodResourcesDir     = "/var/lib/kubelet/pod-resources"
+	kubeletPodResourcesSocket  = "/var/lib/kubelet/pod-resources/kubelet.sock"
+
+	// Resource manager checkpoints, which kubelet refuses to start with when they hold another policy
+	cpuManagerStatePath    = "/var/lib/kubelet/cpu_manager_state"
+	memoryManagerStatePath = "/var/lib/kubelet/memory_manager_state"
+
+	// Image credential provider kubelet execs for Azure Container Registry
+	credentialProviderBinDir         = "/var/lib/kubelet/credential-provider"
+	credentialProviderConfigPath     = "/var/lib/kubelet/credential-provider-config.yaml"
+	acrCredentialProviderPath        = "/var/lib/kubelet/credential-provider/acr-credential-provider"
+	acrCredentialProviderVersionFile = "/var/lib/kubelet/credential-provider/acr-credential-provider-version"
+
+	// Cloud settings of the Azure cloud provider, the Azure CSI drivers and the acr-credential-provider
+	azureJSONPath = "/etc/kubernetes/azure.json"
 
 	// PKI certificate paths
 	apiserverClientCAPath = "/etc/kubernetes/pki/apiserver-client-ca.crt"
 
+	// Serving certificate kubelet obtains from the cluster CA with serverTLSBootstrap, renewed in place
+	kubeletServingCertPath    = "/var/lib/kubelet/pki/kubelet-server-current.pem"
+	kubeletServingSigner      = "kubernetes.io/kubelet-serving"
+	servingCertificateTimeout = 3 * time.Minute
+
 	// Azure resource identifiers
-	aksServiceResourceID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+	AKSServiceResourceID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+
+	// privateServerFQDN asks for cluster credentials pointing at the private FQDN of a private cluster
+	privateServerFQDN = "private"
 )
+
+// tokenBrokerScriptPrelude is prepended to kubelet token scripts when the agent token broker is enabled.
+// Tokens are served from the broker's cache; the script falls back to fetching directly if the broker is unavailable.
+const tokenBrokerScriptPrelude = `
+# Prefer the aks-flex-node token broker, which caches tokens and shields the identity endpoint from bursts
+BROKER_SOCKET="%s"
+if [ -S "$BROKER_SOCKET" ]; then
+    if BROKER_RESPONSE=$(curl -sf --max-time 10 --unix-socket "$BROKER_SOCKET" http://localhost/token); then
+        echo "$BROKER_RESPONSE"
+        exit 0
+    fi
+fi
+`
+
+// tlsCipherSuites are the TLS cipher suites the kubelet server accepts
+var tlsCipherSuites = []any{
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305",
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305",
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	"TLS_RSA_WITH_AES_256_GCM_SHA384",
+	"TLS_RSA_WITH_AES_128_GCM_SHA256",
+}
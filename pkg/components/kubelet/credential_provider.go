@@ -0,0 +1,93 @@
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// acrCredentialProviderDownloadURL is the URL of the cloud-provider-azure archive holding the
+// acr-credential-provider, by release and architecture
+var acrCredentialProviderDownloadURL = "https://acs-mirror.azureedge.net/cloud-provider-azure/v%[1]s/binaries/azure-acr-credential-provider-linux-%[2]s-v%[1]s.tar.gz"
+
+// configureAcrCredentialProvider installs the acr-credential-provider of the configured release and writes the
+// CredentialProviderConfig it is run with. kubelet is pointed at them by its flags, and the provider authenticates
+// with azure.json.
+func (i *Installer) configureAcrCredentialProvider(ctx context.Context) error {
+	provider := &i.config.Node.Kubelet.AcrCredentialProvider
+	if !provider.Enabled {
+		return nil
+	}
+	if installedAcrCredentialProviderVersion() != provider.Version {
+		i.logger.Infof("Installing acr-credential-provider %s", provider.Version)
+		if err := i.installAcrCredentialProvider(ctx, provider.Version); err != nil {
+			return err
+		}
+	}
+	if err := utilio.WriteFile(credentialProviderConfigPath, []byte(credentialProviderConfig(provider)), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", credentialProviderConfigPath, err)
+	}
+	return nil
+}
+
+// installAcrCredentialProvider installs the acr-credential-provider from the release archive and records its
+// version, since the binary does not report it
+func (i *Installer) installAcrCredentialProvider(ctx context.Context, version string) error {
+	url := fmt.Sprintf(acrCredentialProviderDownloadURL, version, utilhost.GetArch())
+	installed := false
+	for tarFile, err := range utilio.DecompressTarGzFromRemote(ctx, url) {
+		if err != nil {
+			return err
+		}
+		if path.Base(tarFile.Name) != "azure-acr-credential-provider" {
+			continue
+		}
+		i.logger.Debugf("installing %q to %q", tarFile.Name, acrCredentialProviderPath)
+		if err := utilio.InstallFile(acrCredentialProviderPath, tarFile.Body, 0o755); err != nil {
+			return fmt.Errorf("failed to install %s: %w", acrCredentialProviderPath, err)
+		}
+		installed = true
+	}
+	if !installed {
+		return fmt.Errorf("azure-acr-credential-provider not found in %s", url)
+	}
+	return utilio.WriteFile(acrCredentialProviderVersionFile, []byte(version+"\n"), 0o644)
+}
+
+// installedAcrCredentialProviderVersion returns the recorded release of the installed provider, or an empty
+// string when it is not installed
+func installedAcrCredentialProviderVersion() string {
+	if _, err := os.Stat(acrCredentialProviderPath); err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(acrCredentialProviderVersionFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// credentialProviderConfig renders the CredentialProviderConfig kubelet execs the acr-credential-provider with
+// for images of the matched registries
+func credentialProviderConfig(provider *config.AcrCredentialProviderConfig) string {
+	var matchImages strings.Builder
+	for _, image := range provider.MatchImages {
+		fmt.Fprintf(&matchImages, "      - %q\n", image)
+	}
+	return fmt.Sprintf(`apiVersion: kubelet.config.k8s.io/v1
+kind: CredentialProviderConfig
+providers:
+  - name: %s
+    matchImages:
+%s    defaultCacheDuration: %q
+    apiVersion: credentialprovider.kubelet.k8s.io/v1
+    args:
+      - %s
+`, path.Base(acrCredentialProviderPath), matchImages.String(), provider.CacheDuration, azureJSONPath)
+}
@@ -0,0 +1,23 @@
+package kubelet
+
+import (
+	"strings"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestCredentialProviderConfig(t *testing.T) {
+	provider := &config.AcrCredentialProviderConfig{MatchImages: []string{"myacr.azurecr.io", "*.azurecr.io"}, CacheDuration: "10m"}
+	got := credentialProviderConfig(provider)
+	for _, want := range []string{
+		"kind: CredentialProviderConfig\n",
+		"  - name: acr-credential-provider\n",
+		"      - \"myacr.azurecr.io\"\n      - \"*.azurecr.io\"\n    defaultCacheDuration: \"10m\"\n",
+		"      - /etc/kubernetes/azure.json\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("credentialProviderConfig() = %s, missing %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package kubelet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// createKubeletConfigFile writes the KubeletConfiguration passed with --config, with node.kubelet.configOverrides
+// merged in. Resource manager checkpoints of another policy are removed, since kubelet does not start with them.
+func (i *Installer) createKubeletConfigFile() error {
+	kubeletConfig, err := kubeletConfiguration(i.config)
+	if err != nil {
+		return err
+	}
+	// JSON is valid YAML, which kubelet reads config files as
+	data, err := json.MarshalIndent(kubeletConfig, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the kubelet configuration: %w", err)
+	}
+	if err := utilio.WriteFile(kubeletConfigPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to create kubelet config file: %w", err)
+	}
+
+	checkpoints := map[string]string{
+		cpuManagerStatePath:    policyName(kubeletConfig["cpuManagerPolicy"], config.CPUManagerPolicyNone),
+		memoryManagerStatePath: policyName(kubeletConfig["memoryManagerPolicy"], config.MemoryManagerPolicyNone),
+	}
+	for path, policy := range checkpoints {
+		checkpointed, ok := checkpointPolicy(path)
+		if !ok || strings.EqualFold(checkpointed, policy) {
+			continue
+		}
+		i.logger.Infof("Removing %s, which holds the %s policy instead of %s", path, checkpointed, policy)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// kubeletConfiguration renders the KubeletConfiguration from the node settings, then merges
+// node.kubelet.configOverrides into it
+func kubeletConfiguration(cfg *config.Config) (map[string]any, error) {
+	kubeletConfig, err := kubeletSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mergeOverrides(kubeletConfig, cfg.Node.Kubelet.ConfigOverrides)
+	return kubeletConfig, nil
+}
+
+// WindowsConfiguration renders the KubeletConfiguration of a Windows node, which trusts clientCAFile. Windows
+// has no cgroups, so QoS cgroups and node allocatable enforcement are off, and the Linux-only settings are left
+// out. node.kubelet.configOverrides is merged in last.
+func WindowsConfiguration(cfg *config.Config, clientCAFile string) (map[string]any, error) {
+	kubeletConfig, err := kubeletSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"cgroupDriver", "protectKernelDefaults", "failSwapOn", "memorySwap", "featureGates",
+		"cpuManagerPolicy", "memoryManagerPolicy", "reservedMemory", "topologyManagerPolicy", "reservedSystemCPUs"} {
+		delete(kubeletConfig, key)
+	}
+	kubeletConfig["cgroupsPerQOS"] = false
+	kubeletConfig["enforceNodeAllocatable"] = []any{}
+	kubeletConfig["resolvConf"] = ""
+	authentication, ok := kubeletConfig["authentication"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("kubelet configuration authentication is not a mapping")
+	}
+	authentication["x509"] = map[string]any{"clientCAFile": clientCAFile}
+	mergeOverrides(kubeletConfig, cfg.Node.Kubelet.ConfigOverrides)
+	return kubeletConfig, nil
+}
+
+// kubeletSettings renders the KubeletConfiguration from the node settings
+func kubeletSettings(cfg *config.Config) (map[string]any, error) {
+	kubelet := &cfg.Node.Kubelet
+	kubeletConfig := map[string]any{
+		"apiVersion": "kubelet.config.k8s.io/v1beta1",
+		"kind":       "KubeletConfiguration",
+		"address":    "0.0.0.0",
+		"authentication": map[string]any{
+			"anonymous": map[string]any{"enabled": false},
+			"webhook":   map[string]any{"enabled": true},
+			"x509":      map[string]any{"clientCAFile": apiserverClientCAPath},
+		},
+		"authorization":                  map[string]any{"mode": "Webhook"},
+		"cgroupDriver":                   "systemd",
+		"cgroupsPerQOS":                  true,
+		"enforceNodeAllocatable":         []any{"pods"},
+		"clusterDNS":                     []any{cfg.ClusterDNS()},
+		"clusterDomain":                  "cluster.local",
+		"eventRecordQPS":                 0,
+		"imageGCHighThresholdPercent":    kubelet.ImageGCHighThreshold,
+		"imageGCLowThresholdPercent":     kubelet.ImageGCLowThreshold,
+		"maxPods":                        cfg.Node.MaxPods,
+		"nodeStatusUpdateFrequency":      "10s",
+		"podPidsLimit":                   -1,
+		"protectKernelDefaults":          true,
+		"readOnlyPort":                   0,
+		"resolvConf":                     "/run/systemd/resolve/resolv.conf",
+		"streamingConnectionIdleTimeout": "4h",
+		// Bootstrap token mode: kubelet rotates its certificate after TLS bootstrap. Other modes authenticate
+		// with the exec credential provider.
+		"rotateCertificates": cfg.IsBootstrapTokenConfigured(),
+		// Request the serving certificate from the cluster CA instead of signing one itself
+		"serverTLSBootstrap": kubelet.ServerTLSBootstrap,
+		"tlsCipherSuites":    tlsCipherSuites,
+	}
+	// NodeSwap keeps swap on and limits which pods may use it. The feature gate is on by default from 1.34.
+	if kubelet.SwapEnabled() {
+		kubeletConfig["failSwapOn"] = false
+		kubeletConfig["memorySwap"] = map[string]any{"swapBehavior": kubelet.SwapBehavior}
+		if !cfg.KubernetesVersionAtLeast(1, 34) {
+			kubeletConfig["featureGates"] = map[string]any{"NodeSwap": true}
+		}
+	}
+	if len(kubelet.EvictionHard) > 0 {
+		kubeletConfig["evictionHard"] = stringMap(kubelet.EvictionHard)
+	}
+	if len(kubelet.EvictionSoft) > 0 {
+		kubeletConfig["evictionSoft"] = stringMap(kubelet.EvictionSoft)
+		kubeletConfig["evictionSoftGracePeriod"] = stringMap(kubelet.EvictionSoftGracePeriod)
+	}
+	if kubelet.EvictionMaxPodGracePeriod != 0 {
+		kubeletConfig["evictionMaxPodGracePeriod"] = kubelet.EvictionMaxPodGracePeriod
+	}
+	if len(kubelet.EvictionMinimumReclaim) > 0 {
+		kubeletConfig["evictionMinimumReclaim"] = stringMap(kubelet.EvictionMinimumReclaim)
+	}
+	if kubelet.EvictionPressureTransitionPeriod != "" {
+		kubeletConfig["evictionPressureTransitionPeriod"] = kubelet.EvictionPressureTransitionPeriod
+	}
+	if len(kubelet.KubeReserved) > 0 {
+		kubeletConfig["kubeReserved"] = stringMap(kubelet.KubeReserved)
+	}
+	if len(kubelet.SystemReserved) > 0 {
+		kubeletConfig["systemReserved"] = stringMap(kubelet.SystemReserved)
+	}
+	// Image GC age and log rotation, which keep small disks from filling up
+	if kubelet.ImageMinimumGCAge != "" {
+		kubeletConfig["imageMinimumGCAge"] = kubelet.ImageMinimumGCAge
+	}
+	if kubelet.ContainerLogMaxSize != "" {
+		kubeletConfig["containerLogMaxSize"] = kubelet.ContainerLogMaxSize
+	}
+	if kubelet.ContainerLogMaxFiles != 0 {
+		kubeletConfig["containerLogMaxFiles"] = kubelet.ContainerLogMaxFiles
+	}
+	if kubelet.CPUManagerPolicy != "" {
+		kubeletConfig["cpuManagerPolicy"] = kubelet.CPUManagerPolicy
+	}
+	if kubelet.TopologyManagerPolicy != "" {
+		kubeletConfig["topologyManagerPolicy"] = kubelet.TopologyManagerPolicy
+	}
+	if kubelet.ReservedSystemCPUs != "" {
+		kubeletConfig["reservedSystemCPUs"] = kubelet.ReservedSystemCPUs
+	}
+	if kubelet.MemoryManagerPolicy != "" {
+		kubeletConfig["memoryManagerPolicy"] = kubelet.MemoryManagerPolicy
+	}
+	if kubelet.MemoryManagerPolicy == config.MemoryManagerPolicyStatic {
+		// The reservations across NUMA nodes must add up to the reserved memory; NUMA node 0 holds it all
+		reserved, err := kubelet.ReservedMemory()
+		if err != nil {
+			return nil, err
+		}
+		kubeletConfig["reservedMemory"] = []any{map[string]any{
+			"numaNode": 0,
+			"limits":   map[string]any{"memory": reserved.String()},
+		}}
+	}
+	return kubeletConfig, nil
+}
+
+// mergeOverrides deep-merges overrides into dst: objects are merged key by key, other values replace the
+// earlier value as a whole, and null removes the key
+func mergeOverrides(dst, overrides map[string]any) {
+	for key, value := range overrides {
+		if value == nil {
+			delete(dst, key)
+			continue
+		}
+		overrideMap, overrideIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if overrideIsMap && dstIsMap {
+			mergeOverrides(dstMap, overrideMap)
+			continue
+		}
+		if overrideIsMap {
+			// Copy the object, so later merges into it leave the configured overrides untouched
+			dstMap = map[string]any{}
+			mergeOverrides(dstMap, overrideMap)
+			value = dstMap
+		}
+		dst[key] = value
+	}
+}
+
+// stringMap converts a map of strings into a JSON object that overrides can be merged into
+func stringMap(m map[string]string) map[string]any {
+	object := make(map[string]any, len(m))
+	for key, value := range m {
+		object[key] = value
+	}
+	return object
+}
+
+// policyName returns the resource manager policy set in the KubeletConfiguration, or fallback when it is unset
+func policyName(value any, fallback string) string {
+	if policy, ok := value.(string); ok && policy != "" {
+		return policy
+	}
+	return fallback
+}
+
+// checkpointPolicy returns the policy recorded in a resource manager checkpoint, if there is one
+func checkpointPolicy(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	var checkpoint struct {
+		PolicyName string `json:"policyName"`
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil || checkpoint.PolicyName == "" {
+		return "", false
+	}
+	return checkpoint.PolicyName, true
+}
@@ -0,0 +1,185 @@
+package kubelet
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestKubeletConfiguration(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Node.MaxPods = 110
+	cfg.Node.Kubelet = config.KubeletConfig{
+		KubeReserved:          map[string]string{"memory": "1Gi"},
+		EvictionHard:          map[string]string{"memory.available": "100Mi"},
+		DNSServiceIP:          "10.0.0.10",
+		CPUManagerPolicy:      "static",
+		TopologyManagerPolicy: "single-numa-node",
+		MemoryManagerPolicy:   "Static",
+		ReservedSystemCPUs:    "0-1",
+
+		SystemReserved:          map[string]string{"cpu": "250m"},
+		EvictionSoft:            map[string]string{"nodefs.available": "15%"},
+		EvictionSoftGracePeriod: map[string]string{"nodefs.available": "2m"},
+	}
+	kubeletConfig, err := kubeletConfiguration(cfg)
+	if err != nil {
+		t.Fatalf("kubeletConfiguration() error = %v", err)
+	}
+	for key, want := range map[string]any{
+		"apiVersion":            "kubelet.config.k8s.io/v1beta1",
+		"kind":                  "KubeletConfiguration",
+		"maxPods":               110,
+		"clusterDNS":            []any{"10.0.0.10"},
+		"evictionHard":          map[string]any{"memory.available": "100Mi"},
+		"cpuManagerPolicy":      "static",
+		"topologyManagerPolicy": "single-numa-node",
+		"memoryManagerPolicy":   "Static",
+		"reservedSystemCPUs":    "0-1",
+
+		"systemReserved":          map[string]any{"cpu": "250m"},
+		"evictionSoft":            map[string]any{"nodefs.available": "15%"},
+		"evictionSoftGracePeriod": map[string]any{"nodefs.available": "2m"},
+	} {
+		if got := kubeletConfig[key]; !reflect.DeepEqual(got, want) {
+			t.Errorf("kubeletConfiguration()[%q] = %#v, want %#v", key, got, want)
+		}
+	}
+	data, err := json.Marshal(kubeletConfig["reservedMemory"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"limits":{"memory":"1124Mi"},"numaNode":0}]`; string(data) != want {
+		t.Errorf("reservedMemory = %s, want %s", data, want)
+	}
+	if _, ok := kubeletConfig["imageMinimumGCAge"]; ok {
+		t.Error("kubeletConfiguration() sets imageMinimumGCAge, which is not configured")
+	}
+}
+
+func TestKubeletConfigurationOverrides(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Node.MaxPods = 110
+	cfg.Node.Kubelet.EvictionHard = map[string]string{"memory.available": "100Mi"}
+	cfg.Node.Kubelet.ConfigOverrides = map[string]any{
+		"maxPods":             250.0,
+		"serializeImagePulls": false,
+		"featureGates":        map[string]any{"InPlacePodVerticalScaling": true},
+		"evictionHard":        map[string]any{"nodefs.available": "5%"},
+		"authentication":      map[string]any{"webhook": map[string]any{"cacheTTL": "30s"}},
+		"podPidsLimit":        nil,
+	}
+	kubeletConfig, err := kubeletConfiguration(cfg)
+	if err != nil {
+		t.Fatalf("kubeletConfiguration() error = %v", err)
+	}
+	for key, want := range map[string]any{
+		"maxPods":             250.0,
+		"serializeImagePulls": false,
+		"featureGates":        map[string]any{"InPlacePodVerticalScaling": true},
+		"evictionHard":        map[string]any{"memory.available": "100Mi", "nodefs.available": "5%"},
+		"authentication": map[string]any{
+			"anonymous": map[string]any{"enabled": false},
+			"webhook":   map[string]any{"enabled": true, "cacheTTL": "30s"},
+			"x509":      map[string]any{"clientCAFile": apiserverClientCAPath},
+		},
+	} {
+		if got := kubeletConfig[key]; !reflect.DeepEqual(got, want) {
+			t.Errorf("kubeletConfiguration()[%q] = %#v, want %#v", key, got, want)
+		}
+	}
+	if _, ok := kubeletConfig["podPidsLimit"]; ok {
+		t.Error("kubeletConfiguration() keeps podPidsLimit, which an override removed")
+	}
+	if featureGates := cfg.Node.Kubelet.ConfigOverrides["featureGates"].(map[string]any); len(featureGates) != 1 {
+		t.Errorf("kubeletConfiguration() modified the configured overrides: %v", featureGates)
+	}
+}
+
+func TestKubeletConfigurationSwap(t *testing.T) {
+	tests := []struct {
+		name             string
+		version          string
+		swapBehavior     string
+		wantFeatureGates any
+	}{
+		{name: "swap off", version: "1.33.2"},
+		{name: "beta", version: "1.33.2", swapBehavior: "LimitedSwap", wantFeatureGates: map[string]any{"NodeSwap": true}},
+		{name: "generally available", version: "1.34.1", swapBehavior: "NoSwap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Kubernetes.Version = tt.version
+			cfg.Node.Kubelet.SwapBehavior = tt.swapBehavior
+			kubeletConfig, err := kubeletConfiguration(cfg)
+			if err != nil {
+				t.Fatalf("kubeletConfiguration() error = %v", err)
+			}
+			if got := kubeletConfig["featureGates"]; !reflect.DeepEqual(got, tt.wantFeatureGates) {
+				t.Errorf("featureGates = %#v, want %#v", got, tt.wantFeatureGates)
+			}
+			if tt.swapBehavior == "" {
+				if _, ok := kubeletConfig["failSwapOn"]; ok {
+					t.Error("kubeletConfiguration() sets failSwapOn with swap off")
+				}
+				return
+			}
+			if got := kubeletConfig["failSwapOn"]; got != false {
+				t.Errorf("failSwapOn = %#v, want false", got)
+			}
+			if got, want := kubeletConfig["memorySwap"], map[string]any{"swapBehavior": tt.swapBehavior}; !reflect.DeepEqual(got, want) {
+				t.Errorf("memorySwap = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestWindowsConfiguration(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Kubernetes.Version = "1.33.2"
+	cfg.Node.MaxPods = 30
+	cfg.Node.Kubelet.DNSServiceIP = "10.0.0.10"
+	cfg.Node.Kubelet.CPUManagerPolicy = "static"
+	cfg.Node.Kubelet.SwapBehavior = "LimitedSwap"
+	cfg.Node.Kubelet.ConfigOverrides = map[string]any{"cgroupsPerQOS": true, "featureGates": map[string]any{"WindowsHostNetwork": true}}
+
+	kubeletConfig, err := WindowsConfiguration(cfg, `C:\k\ca.crt`)
+	if err != nil {
+		t.Fatalf("WindowsConfiguration() error = %v", err)
+	}
+	for key, want := range map[string]any{
+		"maxPods":                30,
+		"clusterDNS":             []any{"10.0.0.10"},
+		"enforceNodeAllocatable": []any{},
+		"resolvConf":             "",
+		// Overrides still win over the Windows settings
+		"cgroupsPerQOS": true,
+		"featureGates":  map[string]any{"WindowsHostNetwork": true},
+	} {
+		if got := kubeletConfig[key]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %#v, want %#v", key, got, want)
+		}
+	}
+	for _, key := range []string{"cgroupDriver", "protectKernelDefaults", "cpuManagerPolicy", "failSwapOn", "memorySwap"} {
+		if got, ok := kubeletConfig[key]; ok {
+			t.Errorf("WindowsConfiguration() sets the Linux-only %s = %#v", key, got)
+		}
+	}
+	x509 := kubeletConfig["authentication"].(map[string]any)["x509"]
+	if want := map[string]any{"clientCAFile": `C:\k\ca.crt`}; !reflect.DeepEqual(x509, want) {
+		t.Errorf("authentication.x509 = %#v, want %#v", x509, want)
+	}
+
+	// The Linux configuration keeps its own client CA
+	linuxConfig, err := kubeletConfiguration(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x509 := linuxConfig["authentication"].(map[string]any)["x509"]; !reflect.DeepEqual(x509, map[string]any{"clientCAFile": apiserverClientCAPath}) {
+		t.Errorf("Linux authentication.x509 = %#v", x509)
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
-	"os"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v5"
@@ -15,6 +16,7 @@ import (
 	"go.goms.io/aks/AKSFlexNode/pkg/auth"
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
 )
 
@@ -23,6 +25,12 @@ type Installer struct {
 	config   *config.Config
 	logger   *logrus.Logger
 	mcClient *armcontainerservice.ManagedClustersClient
+
+	// identityClientID is the client ID resolved for a managed identity configured by resource ID
+	identityClientID string
+
+	// serverURL is the API server URL written to the kubeconfig, which kubelet reaches without the proxy
+	serverURL string
 }
 
 // NewInstaller creates a new kubelet Installer
@@ -47,6 +55,8 @@ func (i *Installer) Execute(ctx context.Context) error {
 		return fmt.Errorf("failed to set up Azure SDK clients: %w", err)
 	}
 
+	i.resolveManagedIdentity(ctx)
+
 	// Configure kubelet service with systemd unit file and default settings
 	if err := i.configure(ctx); err != nil {
 		return fmt.Errorf("failed to configure kubelet: %w", err)
@@ -90,11 +100,26 @@ func (i *Installer) configure(ctx context.Context) error {
 		return fmt.Errorf("failed to create required directories: %w", err)
 	}
 
+	// Create the KubeletConfiguration file holding the settings without flags
+	if err := i.createKubeletConfigFile(); err != nil {
+		return err
+	}
+
 	// Create kubelet defaults file
 	if err := i.createKubeletDefaultsFile(); err != nil {
 		return err
 	}
 
+	// Give the Azure cloud provider and CSI drivers the node's cloud settings
+	if err := i.configureAzureJSON(ctx); err != nil {
+		return err
+	}
+
+	// Let kubelet pull from Azure Container Registry with the node identity
+	if err := i.configureAcrCredentialProvider(ctx); err != nil {
+		return err
+	}
+
 	// Create authentication configuration based on auth method
 	if i.config.IsBootstrapTokenConfigured() {
 		// Bootstrap token authentication uses a simple token-based kubeconfig
@@ -124,6 +149,16 @@ func (i *Installer) configure(ctx context.Context) error {
 		return err
 	}
 
+	// Restrict the kubelet pod-resources socket to root and the socket group
+	if err := i.createKubeletSocketAccessConfig(); err != nil {
+		return err
+	}
+
+	// Pass the node proxy to kubelet and its credential scripts
+	if err := i.createKubeletProxyConfig(); err != nil {
+		return err
+	}
+
 	// Create main kubelet service
 	if err := i.createKubeletServiceFile(); err != nil {
 		return err
@@ -143,6 +178,7 @@ func (i *Installer) cleanupExistingConfiguration() error {
 		kubeletServicePath,
 		kubeletContainerdConfig,
 		kubeletTLSBootstrapConfig,
+		kubeletProxyConfig,
 		kubeconfigPath,
 		kubeletTokenScriptPath,
 	}
@@ -172,7 +208,7 @@ func (i *Installer) createRequiredDirectories() error {
 
 	for _, dir := range directories {
 		i.logger.Debugf("Creating directory: %s", dir)
-		if err := utils.RunSystemCommand("mkdir", "-p", dir); err != nil {
+		if err := utilio.MkdirAll(dir, 0o755); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
 	}
@@ -190,7 +226,7 @@ func (i *Installer) ensureRequiredPackages() error {
 	for _, pkg := range packages {
 		if err := utils.RunSystemCommand("which", pkg); err != nil {
 			i.logger.Infof("Installing %s...", pkg)
-			if err := utils.RunSystemCommand("apt", "install", "-y", pkg); err != nil {
+			if err := utilhost.InstallPackages(pkg); err != nil {
 				return fmt.Errorf("failed to install %s: %w", pkg, err)
 			}
 			i.logger.Infof("Successfully installed %s", pkg)
@@ -207,57 +243,44 @@ func (i *Installer) ensureRequiredPackages() error {
 func (i *Installer) createKubeletDefaultsFile() error {
 	// Create kubelet default config
 	labels := make([]string, 0, len(i.config.Node.Labels))
-	for key, value := range i.config.Node.Labels {
-		labels = append(labels, fmt.Sprintf("%s=%s", key, value))
+	for _, key := range slices.Sorted(maps.Keys(i.config.Node.Labels)) {
+		labels = append(labels, fmt.Sprintf("%s=%s", key, i.config.Node.Labels[key]))
 	}
 
-	// Set --rotate-certificates based on authentication mode
-	// Bootstrap token mode: true (kubelet will rotate certificates after TLS bootstrap)
-	// Other modes (Arc/SP/MSI): false (authentication is handled via exec credential provider)
-	rotateCerts := i.config.IsBootstrapTokenConfigured()
+	// Flags that are only set when configured, one per line. The other settings are in the config file.
+	var optionalFlags strings.Builder
+	nodeIPs, err := i.config.GetNodeIPs()
+	if err != nil {
+		return err
+	}
+	if len(nodeIPs) > 0 {
+		ips := make([]string, 0, len(nodeIPs))
+		for _, nodeIP := range nodeIPs {
+			i.logger.Infof("Advertising node IP %s of interface %s", nodeIP.IP, nodeIP.Interface)
+			ips = append(ips, nodeIP.IP.String())
+		}
+		fmt.Fprintf(&optionalFlags, "  --node-ip=%s \\\n", strings.Join(ips, ","))
+	}
+	if len(i.config.Node.Taints) > 0 {
+		fmt.Fprintf(&optionalFlags, "  --register-with-taints=%s \\\n", strings.Join(i.config.Node.Taints, ","))
+	}
+	if i.config.Node.Kubelet.AcrCredentialProvider.Enabled {
+		fmt.Fprintf(&optionalFlags, "  --image-credential-provider-config=%s \\\n", credentialProviderConfigPath)
+		fmt.Fprintf(&optionalFlags, "  --image-credential-provider-bin-dir=%s \\\n", credentialProviderBinDir)
+	}
 
 	kubeletDefaults := fmt.Sprintf(`KUBELET_NODE_LABELS="%s"
-KUBELET_CONFIG_FILE_FLAGS=""
+KUBELET_CONFIG_FILE_FLAGS="--config=%s"
 KUBELET_FLAGS="\
   --v=%d \
-  --address=0.0.0.0 \
-  --anonymous-auth=false \
-  --authentication-token-webhook=true \
-  --authorization-mode=Webhook \
-  --client-ca-file=%s \
-  --cgroup-driver=systemd \
-  --cgroups-per-qos=true \
-  --enforce-node-allocatable=pods \
-  --cluster-dns=%s \
-  --cluster-domain=cluster.local \
-  --event-qps=0  \
-  --eviction-hard=%s  \
-  --kube-reserved=%s  \
-  --image-gc-high-threshold=%d  \
-  --image-gc-low-threshold=%d  \
-  --max-pods=%d  \
-  --node-status-update-frequency=10s  \
-  --pod-max-pids=-1  \
-  --protect-kernel-defaults=true  \
-  --read-only-port=0  \
-  --resolv-conf=/run/systemd/resolve/resolv.conf  \
-  --streaming-connection-idle-timeout=4h  \
-  --rotate-certificates=%t \
-  --tls-cipher-suites=TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_RSA_WITH_AES_256_GCM_SHA384,TLS_RSA_WITH_AES_128_GCM_SHA256 \
-  "`,
+%s  "`,
 		strings.Join(labels, ","),
+		kubeletConfigPath,
 		i.config.Node.Kubelet.Verbosity,
-		apiserverClientCAPath,
-		i.config.Node.Kubelet.DNSServiceIP,
-		mapToEvictionThresholds(i.config.Node.Kubelet.EvictionHard, ","),
-		mapToKeyValuePairs(i.config.Node.Kubelet.KubeReserved, ","),
-		i.config.Node.Kubelet.ImageGCHighThreshold,
-		i.config.Node.Kubelet.ImageGCLowThreshold,
-		i.config.Node.MaxPods,
-		rotateCerts)
+		optionalFlags.String())
 
 	// Ensure /etc/default directory exists
-	if err := utils.RunSystemCommand("mkdir", "-p", etcDefaultDir); err != nil {
+	if err := utilio.MkdirAll(etcDefaultDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create %s directory: %w", etcDefaultDir, err)
 	}
 
@@ -272,7 +295,7 @@ KUBELET_FLAGS="\
 // createSystemdDropInFile creates a systemd drop-in file with the given content
 func (i *Installer) createSystemdDropInFile(filePath, content, description string) error {
 	// Ensure kubelet service.d directory exists
-	if err := utils.RunSystemCommand("mkdir", "-p", kubeletServiceDir); err != nil {
+	if err := utilio.MkdirAll(kubeletServiceDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create %s directory: %w", kubeletServiceDir, err)
 	}
 
@@ -284,10 +307,11 @@ func (i *Installer) createSystemdDropInFile(filePath, content, description strin
 	return nil
 }
 
-// createKubeletContainerdConfig creates the kubelet containerd configuration
+// createKubeletContainerdConfig creates the kubelet container runtime configuration, pointing kubelet at the CRI
+// socket of containerd or CRI-O
 func (i *Installer) createKubeletContainerdConfig() error {
-	containerdConf := `[Service]
-Environment=KUBELET_CONTAINERD_FLAGS="--runtime-request-timeout=15m --container-runtime-endpoint=unix:///run/containerd/containerd.sock"`
+	containerdConf := fmt.Sprintf(`[Service]
+Environment=KUBELET_CONTAINERD_FLAGS="--runtime-request-timeout=15m --container-runtime-endpoint=%s"`, i.config.ContainerRuntimeEndpoint())
 
 	return i.createSystemdDropInFile(kubeletContainerdConfig, containerdConf, "kubelet containerd config file")
 }
@@ -300,6 +324,42 @@ Environment=KUBELET_TLS_BOOTSTRAP_FLAGS="--kubeconfig /var/lib/kubelet/kubeconfi
 	return i.createSystemdDropInFile(kubeletTLSBootstrapConfig, tlsBootstrapConf, "kubelet TLS bootstrap config file")
 }
 
+// createKubeletProxyConfig creates the kubelet proxy drop-in when network.proxy is configured.
+// The API server is added to NO_PROXY, so kubelet reaches it directly.
+func (i *Installer) createKubeletProxyConfig() error {
+	env := i.config.GetProxyEnvironment(i.serverURL)
+	if len(env) == 0 {
+		return nil
+	}
+	return i.createSystemdDropInFile(kubeletProxyConfig, utils.SystemdEnvironmentDropIn(env), "kubelet proxy config file")
+}
+
+// createKubeletSocketAccessConfig creates the kubelet socket group, adds the configured users to it and
+// installs a drop-in that hands the pod-resources socket to the group once kubelet has created it
+func (i *Installer) createKubeletSocketAccessConfig() error {
+	access := i.config.System.Sockets.Kubelet
+	if _, err := utilhost.EnsureGroup(access.Group); err != nil {
+		return fmt.Errorf("failed to set up kubelet socket group: %w", err)
+	}
+	for _, user := range access.Users {
+		if err := utilhost.EnsureGroupMember(user, access.Group); err != nil {
+			return fmt.Errorf("failed to grant kubelet socket access: %w", err)
+		}
+		i.logger.Infof("Granted user %s access to the kubelet pod-resources socket", user)
+	}
+
+	return i.createSystemdDropInFile(kubeletSocketAccessConfig, podResourcesSocketDropIn(access.Group), "kubelet socket access config file")
+}
+
+// podResourcesSocketDropIn renders the kubelet drop-in handing the pod-resources socket to group. kubelet creates
+// the socket with the process umask shortly after start, so the command waits for it before adjusting ownership.
+// A failure is logged to the kubelet journal rather than failing kubelet, which runs fine without the group access.
+func podResourcesSocketDropIn(group string) string {
+	return fmt.Sprintf(`[Service]
+ExecStartPost=/bin/bash -c 'for _ in $(seq 1 60); do [ -S %[2]s ] && break; sleep 1; done; { chown root:%[3]s %[1]s %[2]s && chmod 0750 %[1]s && chmod 0660 %[2]s; } || echo "failed to grant group %[3]s access to %[2]s" >&2'`,
+		kubeletPodResourcesDir, kubeletPodResourcesSocket, group)
+}
+
 // createKubeletServiceFile creates the main kubelet systemd service file
 func (i *Installer) createKubeletServiceFile() error {
 	kubeletService := `[Unit]
@@ -342,12 +402,30 @@ func (i *Installer) createTokenScript() error {
 		return i.createMSITokenScript()
 	} else if i.config.IsSPConfigured() {
 		return i.createServicePrincipalTokenScript()
+	} else if i.config.IsFederatedIdentityConfigured() {
+		return i.createFederatedIdentityTokenScript()
 	} else if i.config.IsBootstrapTokenConfigured() {
 		// Bootstrap token doesn't need a token script
 		return nil
 	} else {
-		return fmt.Errorf("no valid authentication method configured - either Arc, MSI, Service Principal, or Bootstrap Token must be configured")
+		return fmt.Errorf("no valid authentication method configured - either Arc, MSI, Service Principal, Federated Identity, or Bootstrap Token must be configured")
+	}
+}
+
+// resolveManagedIdentity resolves the client ID of a managed identity configured by resource ID.
+// When the identity cannot read its own resource, the token script selects it by resource ID instead.
+func (i *Installer) resolveManagedIdentity(ctx context.Context) {
+	mi := i.config.Azure.ManagedIdentity
+	if !i.config.IsMIConfigured() || mi == nil || mi.ResourceID == "" || mi.ClientID != "" {
+		return
+	}
+	identity, err := auth.NewAuthProvider().ResolveUserAssignedIdentity(ctx, i.config)
+	if err != nil {
+		i.logger.Warnf("Could not resolve managed identity %s via ARM, selecting it by resource ID: %v", mi.ResourceID, err)
+		return
 	}
+	i.logger.Infof("Resolved managed identity %s: clientId=%s principalId=%s", mi.ResourceID, identity.ClientID, identity.PrincipalID)
+	i.identityClientID = identity.ClientID
 }
 
 // createArcTokenScript creates the Arc token script for exec credential authentication
@@ -381,16 +459,20 @@ if [ $? -ne 0 ]; then
     exit 255
 fi
 
-curl -s -H Metadata:true -H "Authorization: Basic $CHALLENGE_TOKEN" $TOKEN_URL | jq "$EXECCREDENTIAL"`, aksServiceResourceID)
+curl -s -H Metadata:true -H "Authorization: Basic $CHALLENGE_TOKEN" $TOKEN_URL | jq "$EXECCREDENTIAL"`, AKSServiceResourceID)
 
 	return i.writeTokenScript(tokenScript)
 }
 
 // createMSITokenScript creates the MSI token script for exec credential authentication using Azure VM Managed Identity
 func (i *Installer) createMSITokenScript() error {
-	clientIDParam := ""
+	identityParam := ""
 	if i.config.Azure.ManagedIdentity != nil && i.config.Azure.ManagedIdentity.ClientID != "" {
-		clientIDParam = fmt.Sprintf("\nCLIENT_ID=\"%s\"", i.config.Azure.ManagedIdentity.ClientID)
+		identityParam = fmt.Sprintf("\nCLIENT_ID=\"%s\"", i.config.Azure.ManagedIdentity.ClientID)
+	} else if i.identityClientID != "" {
+		identityParam = fmt.Sprintf("\nCLIENT_ID=\"%s\"", i.identityClientID)
+	} else if i.config.Azure.ManagedIdentity != nil && i.config.Azure.ManagedIdentity.ResourceID != "" {
+		identityParam = fmt.Sprintf("\nMSI_RES_ID=\"%s\"", i.config.Azure.ManagedIdentity.ResourceID)
 	}
 
 	// Azure VM MSI token script using IMDS endpoint
@@ -403,10 +485,12 @@ IMDS_ENDPOINT="http://169.254.169.254/metadata/identity/oauth2/token"
 API_VERSION="2018-02-01"
 RESOURCE="%s"%s
 
-# Build IMDS URL with optional client_id parameter
+# Build IMDS URL with optional client_id or msi_res_id parameter
 IMDS_URL="$IMDS_ENDPOINT?api-version=$API_VERSION&resource=$RESOURCE"
 if [ -n "${CLIENT_ID:-}" ]; then
     IMDS_URL="$IMDS_URL&client_id=$CLIENT_ID"
+elif [ -n "${MSI_RES_ID:-}" ]; then
+    IMDS_URL="$IMDS_URL&msi_res_id=$MSI_RES_ID"
 fi
 
 # Get token from IMDS
@@ -440,7 +524,7 @@ cat <<EOF
   }
 }
 EOF
-`, aksServiceResourceID, clientIDParam)
+`, AKSServiceResourceID, identityParam)
 
 	return i.writeTokenScript(tokenScript)
 }
@@ -491,7 +575,66 @@ cat <<EOF
     "token": "${ACCESS_TOKEN}"
   }
 }
-EOF`, sp.ClientID, sp.ClientSecret, sp.TenantID, aksServiceResourceID)
+EOF`, sp.ClientID, sp.ClientSecret, i.config.ClusterTenantID(sp.TenantID), AKSServiceResourceID)
+
+	return i.writeTokenScript(tokenScript)
+}
+
+// createFederatedIdentityTokenScript creates the token script exchanging an OIDC token (client assertion) for an AKS token
+func (i *Installer) createFederatedIdentityTokenScript() error {
+	federated := i.config.Azure.FederatedIdentity
+	tokenScript := fmt.Sprintf(`#!/bin/bash
+
+# Exchange an externally issued OIDC token for an Azure AD token using a federated credential (client assertion)
+# https://learn.microsoft.com/entra/identity-platform/v2-oauth2-client-creds-grant-flow#third-case-access-token-request-with-a-federated-credential
+
+CLIENT_ID="%s"
+TENANT_ID="%s"
+TOKEN_FILE="%s"
+
+CLIENT_ASSERTION=$(cat "$TOKEN_FILE")
+if [ $? -ne 0 ] || [ -z "$CLIENT_ASSERTION" ]; then
+    echo "Failed to read federated token file $TOKEN_FILE" >&2
+    exit 255
+fi
+
+TOKEN_RESPONSE=$(curl -s -X POST \
+  "https://login.microsoftonline.com/${TENANT_ID}/oauth2/v2.0/token" \
+  -H "Content-Type: application/x-www-form-urlencoded" \
+  -d "client_id=${CLIENT_ID}" \
+  -d "client_assertion_type=urn:ietf:params:oauth:client-assertion-type:jwt-bearer" \
+  --data-urlencode "client_assertion=${CLIENT_ASSERTION}" \
+  -d "scope=%s/.default" \
+  -d "grant_type=client_credentials")
+
+if [ $? -ne 0 ]; then
+    echo "Failed to get token from Azure AD" >&2
+    exit 255
+fi
+
+ACCESS_TOKEN=$(echo "$TOKEN_RESPONSE" | jq -r '.access_token')
+if [ "$ACCESS_TOKEN" == "null" ] || [ -z "$ACCESS_TOKEN" ]; then
+    echo "Failed to extract access token from response: $(echo "$TOKEN_RESPONSE" | jq -r '.error_description')" >&2
+    exit 255
+fi
+
+EXPIRES_IN=$(echo "$TOKEN_RESPONSE" | jq -r '.expires_in')
+EXPIRY_TIME=$(date -d "+${EXPIRES_IN} seconds" --iso-8601=seconds)
+
+# Return in ExecCredential format
+cat <<EOF
+{
+  "kind": "ExecCredential",
+  "apiVersion": "client.authentication.k8s.io/v1beta1",
+  "spec": {
+    "interactive": false
+  },
+  "status": {
+    "expirationTimestamp": "${EXPIRY_TIME}",
+    "token": "${ACCESS_TOKEN}"
+  }
+}
+EOF`, federated.ClientID, i.config.ClusterTenantID(federated.TenantID), federated.TokenFile, AKSServiceResourceID)
 
 	return i.writeTokenScript(tokenScript)
 }
@@ -499,10 +642,16 @@ EOF`, sp.ClientID, sp.ClientSecret, sp.TenantID, aksServiceResourceID)
 // writeTokenScript helper method to write the token script with proper permissions
 func (i *Installer) writeTokenScript(tokenScript string) error {
 	// Ensure /var/lib/kubelet directory exists
-	if err := utils.RunSystemCommand("mkdir", "-p", kubeletVarDir); err != nil {
+	if err := utilio.MkdirAll(kubeletVarDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create kubelet var directory: %w", err)
 	}
 
+	// Prefer the agent's token broker when it is enabled; the direct fetch remains as fallback
+	if i.config.Agent.TokenBroker.Enabled {
+		tokenScript = strings.Replace(tokenScript, "#!/bin/bash\n",
+			"#!/bin/bash\n"+fmt.Sprintf(tokenBrokerScriptPrelude, i.config.Agent.TokenBroker.SocketPath), 1)
+	}
+
 	// Write token script atomically with executable permissions
 	if err := utilio.WriteFile(kubeletTokenScriptPath, []byte(tokenScript), 0o755); err != nil {
 		return fmt.Errorf("failed to create token script: %w", err)
@@ -554,6 +703,7 @@ func (i *Installer) createKubeconfigWithExecCredential(ctx context.Context) erro
 	if err != nil {
 		return fmt.Errorf("failed to extract cluster info from kubeconfig: %w", err)
 	}
+	i.serverURL = serverURL
 
 	// Write CA certificate to file for kubelet client authentication
 	if err := i.writeClientCACertificate(caCertData); err != nil {
@@ -633,14 +783,12 @@ func (i *Installer) createKubeconfigWithBootstrapToken(ctx context.Context) erro
 		return fmt.Errorf("failed to write client CA certificate: %w", err)
 	}
 
-	// Get node hostname for audit logging
-	hostname, err := os.Hostname()
+	// Include node name in username for better auditing in Kubernetes API server logs
+	nodeName, err := utilhost.NodeName()
 	if err != nil {
-		return fmt.Errorf("failed to get hostname for bootstrap kubeconfig: %w", err)
+		return fmt.Errorf("failed to get node name for bootstrap kubeconfig: %w", err)
 	}
-
-	// Include node name in username for better auditing in Kubernetes API server logs
-	username := fmt.Sprintf("kubelet-bootstrap-%s", hostname)
+	username := fmt.Sprintf("kubelet-bootstrap-%s", nodeName)
 
 	// Create cluster configuration based on whether we have CA cert
 	var clusterConfig string
@@ -689,14 +837,32 @@ users:
 	return nil
 }
 
+// ClusterEndpoint returns the API server URL and the base64-encoded cluster CA from the cluster's user
+// credentials, for nodes that authenticate with an Azure identity instead of a bootstrap token
+func ClusterEndpoint(ctx context.Context, cfg *config.Config, logger *logrus.Logger) (string, string, error) {
+	i := &Installer{config: cfg, logger: logger}
+	if err := i.setUpClients(); err != nil {
+		return "", "", fmt.Errorf("failed to set up Azure SDK clients: %w", err)
+	}
+	kubeconfig, err := i.getClusterCredentials(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get cluster credentials: %w", err)
+	}
+	serverURL, caCertData, err := utils.ExtractClusterInfo(kubeconfig)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to extract cluster info from kubeconfig: %w", err)
+	}
+	return serverURL, caCertData, nil
+}
+
 // setUpClients sets up Azure SDK clients for fetching cluster credentials
 func (i *Installer) setUpClients() error {
-	cred, err := auth.NewAuthProvider().UserCredential(config.GetConfig())
+	cred, err := auth.NewAuthProvider().ClusterCredential(config.GetConfig())
 	if err != nil {
 		return fmt.Errorf("failed to get authentication credential: %w", err)
 	}
 	clusterSubID := i.config.GetTargetClusterSubscriptionID()
-	clientFactory, err := armcontainerservice.NewClientFactory(clusterSubID, cred, nil)
+	clientFactory, err := armcontainerservice.NewClientFactory(clusterSubID, cred, i.config.GetARMClientOptions())
 	if err != nil {
 		return fmt.Errorf("failed to create Azure Container Service client factory: %w", err)
 	}
@@ -704,7 +870,9 @@ func (i *Installer) setUpClients() error {
 	return nil
 }
 
-// getClusterCredentials retrieves cluster kube admin credentials using Azure SDK
+// getClusterCredentials retrieves the cluster's user kubeconfig using Azure SDK. Only its server URL and CA are
+// used, so the admin kubeconfig, which holds a cluster-admin client certificate on clusters with local
+// accounts, is never requested.
 func (i *Installer) getClusterCredentials(ctx context.Context) ([]byte, error) {
 	cfg := config.GetConfig()
 	clusterResourceGroup := cfg.GetTargetClusterResourceGroup()
@@ -712,14 +880,20 @@ func (i *Installer) getClusterCredentials(ctx context.Context) ([]byte, error) {
 	i.logger.Infof("Fetching cluster credentials for cluster %s in resource group %s using Azure SDK",
 		clusterName, clusterResourceGroup)
 
-	// Get cluster admin credentials using the Azure SDK
-	resp, err := i.mcClient.ListClusterAdminCredentials(ctx, clusterResourceGroup, clusterName, nil)
+	// Private clusters are reached through the private FQDN over ExpressRoute or VPN
+	var options *armcontainerservice.ManagedClustersClientListClusterUserCredentialsOptions
+	if cfg.IsPrivateCluster() {
+		options = &armcontainerservice.ManagedClustersClientListClusterUserCredentialsOptions{ServerFqdn: to.StringPtr(privateServerFQDN)}
+	}
+
+	// Get cluster user credentials using the Azure SDK
+	resp, err := i.mcClient.ListClusterUserCredentials(ctx, clusterResourceGroup, clusterName, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cluster admin credentials for %s in resource group %s: %w", clusterName, clusterResourceGroup, err)
+		return nil, fmt.Errorf("failed to get cluster user credentials for %s in resource group %s: %w", clusterName, clusterResourceGroup, err)
 	}
 
 	if len(resp.Kubeconfigs) == 0 {
-		return nil, fmt.Errorf("no kubeconfig found in cluster admin credentials response")
+		return nil, fmt.Errorf("no kubeconfig found in cluster user credentials response")
 	}
 
 	kubeconfig := resp.Kubeconfigs[0]
@@ -736,21 +910,3 @@ func (i *Installer) getClusterCredentials(ctx context.Context) ([]byte, error) {
 	// The Value field is already []byte containing the kubeconfig data, no decoding needed
 	return kubeconfig.Value, nil
 }
-
-// mapToKeyValuePairs converts a map to key=value pairs joined by separator
-func mapToKeyValuePairs(m map[string]string, separator string) string {
-	pairs := make([]string, 0, len(m))
-	for k, v := range m {
-		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
-	}
-	return strings.Join(pairs, separator)
-}
-
-// mapToEvictionThresholds converts a map to key<value pairs for kubelet eviction thresholds
-func mapToEvictionThresholds(m map[string]string, separator string) string {
-	pairs := make([]string, 0, len(m))
-	for k, v := range m {
-		pairs = append(pairs, fmt.Sprintf("%s<%s", k, v))
-	}
-	return strings.Join(pairs, separator)
-}
@@ -0,0 +1,45 @@
+package kubelet
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestPodResourcesSocketDropIn(t *testing.T) {
+	dropIn := podResourcesSocketDropIn("kubelet-sock")
+
+	lines := strings.Split(dropIn, "\n")
+	if len(lines) != 2 || lines[0] != "[Service]" {
+		t.Fatalf("drop-in = %q, want a [Service] section with one ExecStartPost", dropIn)
+	}
+	command, ok := strings.CutPrefix(lines[1], "ExecStartPost=")
+	if !ok {
+		t.Fatalf("drop-in line %q is not an ExecStartPost", lines[1])
+	}
+	// With a - prefix systemd ignores the exit code, and a failure to hand over the socket would go unnoticed
+	if strings.HasPrefix(command, "-") {
+		t.Errorf("ExecStartPost %q ignores failures", command)
+	}
+	for _, want := range []string{
+		"chown root:kubelet-sock " + kubeletPodResourcesDir + " " + kubeletPodResourcesSocket,
+		"chmod 0750 " + kubeletPodResourcesDir,
+		"chmod 0660 " + kubeletPodResourcesSocket,
+		`echo "failed to grant group kubelet-sock access to ` + kubeletPodResourcesSocket + `" >&2`,
+	} {
+		if !strings.Contains(command, want) {
+			t.Errorf("ExecStartPost %q does not contain %q", command, want)
+		}
+	}
+
+	script, ok := strings.CutPrefix(command, "/bin/bash -c ")
+	if !ok || !strings.HasPrefix(script, "'") || !strings.HasSuffix(script, "'") {
+		t.Fatalf("ExecStartPost %q is not a single-quoted bash script", command)
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not available")
+	}
+	if output, err := exec.Command("bash", "-n", "-c", strings.Trim(script, "'")).CombinedOutput(); err != nil {
+		t.Errorf("script is not valid bash: %v: %s", err, output)
+	}
+}
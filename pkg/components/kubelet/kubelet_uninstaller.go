@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/sirupsen/logrus"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/installed_files"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils"
 )
 
@@ -45,15 +46,16 @@ func (u *UnInstaller) Execute(ctx context.Context) error {
 		kubeletBootstrapKubeConfig,
 		kubeletTokenScriptPath,
 		apiserverClientCAPath,
+		azureJSONPath,
 	}
 
-	// Remove kubelet configuration directories
-	kubeletDirectories := []string{
+	// Remove kubelet configuration directories, unless they existed before bootstrap
+	kubeletDirectories := installed_files.CreatedPaths(
 		kubeletServiceDir,      // /etc/systemd/system/kubelet.service.d
 		kubeletVarDir,          // /var/lib/kubelet
 		kubeletManifestsDir,    // Static pod manifests (kubelet-specific)
 		kubeletVolumePluginDir, // Volume plugins (kubelet-specific)
-	}
+	)
 
 	// Remove individual files
 	if fileErrors := utils.RemoveFiles(kubeletFiles, u.logger); len(fileErrors) > 0 {
@@ -0,0 +1,187 @@
+package kubelet
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// ServingCertificateVerifier waits for kubelet to obtain its serving certificate from the cluster CA when
+// node.kubelet.serverTLSBootstrap is set. kubelet requests it with a CertificateSigningRequest once it starts,
+// and serves its API without TLS until the request is approved.
+type ServingCertificateVerifier struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewServingCertificateVerifier creates a new ServingCertificateVerifier
+func NewServingCertificateVerifier(logger *logrus.Logger) *ServingCertificateVerifier {
+	return &ServingCertificateVerifier{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (v *ServingCertificateVerifier) GetName() string {
+	return "KubeletServingCertificateIssued"
+}
+
+// Validate has no preconditions beyond kubelet running, which the services step ensures
+func (v *ServingCertificateVerifier) Validate(ctx context.Context) error {
+	return nil
+}
+
+// Execute waits for kubelet's serving certificate, reporting its pending CertificateSigningRequest on timeout
+func (v *ServingCertificateVerifier) Execute(ctx context.Context) error {
+	if !v.config.Node.Kubelet.ServerTLSBootstrap {
+		return nil
+	}
+	v.logger.Info("Waiting for kubelet's serving certificate from the cluster CA")
+	deadline := time.Now().Add(servingCertificateTimeout)
+	for {
+		cert, err := ServingCertificate()
+		if err == nil {
+			v.logger.Infof("kubelet serves with a certificate from the cluster CA, valid until %s", cert.NotAfter.Format(time.RFC3339))
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return v.pendingRequestError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// IsCompleted reports whether serverTLSBootstrap is off or kubelet holds a valid serving certificate
+func (v *ServingCertificateVerifier) IsCompleted(ctx context.Context) bool {
+	if !v.config.Node.Kubelet.ServerTLSBootstrap {
+		return true
+	}
+	_, err := ServingCertificate()
+	return err == nil
+}
+
+// pendingRequestError explains why kubelet has no serving certificate, naming its pending request if there is one
+func (v *ServingCertificateVerifier) pendingRequestError(certErr error) error {
+	output, err := utils.RunCommandWithOutput("kubectl", "--kubeconfig", KubeletKubeconfigPath,
+		"get", "certificatesigningrequests", "--output", "json")
+	if err != nil {
+		v.logger.Debugf("Failed to list certificate signing requests: %v, output: %s", err, strings.TrimSpace(output))
+		return fmt.Errorf("kubelet has no serving certificate from the cluster CA (%v); list the pending requests with "+
+			"kubectl get csr --field-selector spec.signerName=%s and approve the node's", certErr, kubeletServingSigner)
+	}
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		return err
+	}
+	name, err := pendingServingRequest([]byte(output), "system:node:"+nodeName)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("kubelet has no serving certificate from the cluster CA (%v) and no pending request; check the kubelet logs", certErr)
+	}
+	return fmt.Errorf("kubelet's serving certificate request %s is not approved; approve it with kubectl certificate approve %s, "+
+		"or run an approver for %s requests in the cluster", name, name, kubeletServingSigner)
+}
+
+// certificateSigningRequestList holds the fields of kubectl get csr -o json the verifier reads
+type certificateSigningRequestList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			SignerName string `json:"signerName"`
+			Username   string `json:"username"`
+		} `json:"spec"`
+		Status struct {
+			Conditions []struct {
+				Type string `json:"type"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// pendingServingRequest returns the name of the last serving certificate request of the user that is neither
+// approved nor denied, or an empty string when there is none
+func pendingServingRequest(data []byte, username string) (string, error) {
+	var list certificateSigningRequestList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return "", fmt.Errorf("failed to parse certificate signing requests: %w", err)
+	}
+	var pending string
+	for _, csr := range list.Items {
+		if csr.Spec.SignerName == kubeletServingSigner && csr.Spec.Username == username && len(csr.Status.Conditions) == 0 {
+			pending = csr.Metadata.Name
+		}
+	}
+	return pending, nil
+}
+
+// RenewalOverdue reports whether kubelet should have renewed the certificate by now. kubelet requests a new one
+// once 70 to 90% of its lifetime has passed, so one with less than a tenth left has not been renewed in time.
+func RenewalOverdue(cert *x509.Certificate, now time.Time) bool {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotAfter.Sub(now) < lifetime/10
+}
+
+// ServingCertificate returns kubelet's current serving certificate when the cluster CA issued it and it has not
+// expired
+func ServingCertificate() (*x509.Certificate, error) {
+	certPEM, err := os.ReadFile(kubeletServingCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", kubeletServingCertPath, err)
+	}
+	caPEM, err := os.ReadFile(apiserverClientCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", apiserverClientCAPath, err)
+	}
+	return verifyServingCertificate(certPEM, caPEM, time.Now())
+}
+
+// verifyServingCertificate parses the first certificate of certPEM and verifies it is a serving certificate the
+// CA of caPEM issued and that it is valid at now
+func verifyServingCertificate(certPEM, caPEM []byte, now time.Time) (*x509.Certificate, error) {
+	var block *pem.Block
+	for rest := certPEM; ; {
+		block, rest = pem.Decode(rest)
+		if block == nil || block.Type == "CERTIFICATE" {
+			break
+		}
+	}
+	if block == nil {
+		return nil, fmt.Errorf("no certificate found in %s", kubeletServingCertPath)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the serving certificate: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no CA certificate found in %s", apiserverClientCAPath)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: now,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		return nil, fmt.Errorf("the serving certificate is not valid: %w", err)
+	}
+	return cert, nil
+}
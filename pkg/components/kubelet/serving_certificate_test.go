@@ -0,0 +1,101 @@
+package kubelet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCertificate creates a certificate signed by parent, or self-signed when parent is nil
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestVerifyServingCertificate(t *testing.T) {
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	ca, caKey, caPEM := newTestCertificate(t, caTemplate, nil, nil)
+	_, _, otherCAPEM := newTestCertificate(t, caTemplate, nil, nil)
+	_, _, servingPEM := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "system:node:node1"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(12 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")})
+
+	// kubelet writes the certificate and its key into the same file
+	cert, err := verifyServingCertificate(append(servingPEM, keyPEM...), caPEM, now)
+	if err != nil {
+		t.Fatalf("verifyServingCertificate() error = %v", err)
+	}
+	if cert.Subject.CommonName != "system:node:node1" {
+		t.Errorf("verifyServingCertificate() = %s, want the serving certificate", cert.Subject.CommonName)
+	}
+	if _, err := verifyServingCertificate(servingPEM, otherCAPEM, now); err == nil {
+		t.Error("verifyServingCertificate() accepted a certificate of another CA")
+	}
+	if _, err := verifyServingCertificate(servingPEM, caPEM, now.Add(13*time.Hour)); err == nil {
+		t.Error("verifyServingCertificate() accepted an expired certificate")
+	}
+	if _, err := verifyServingCertificate(keyPEM, caPEM, now); err == nil {
+		t.Error("verifyServingCertificate() accepted a file without certificate")
+	}
+
+	if RenewalOverdue(cert, now) {
+		t.Error("RenewalOverdue() = true for a certificate with most of its lifetime left")
+	}
+	if !RenewalOverdue(cert, now.Add(11*time.Hour)) {
+		t.Error("RenewalOverdue() = false for a certificate with less than a tenth of its lifetime left")
+	}
+}
+
+func TestPendingServingRequest(t *testing.T) {
+	data := []byte(`{"items": [
+		{"metadata": {"name": "csr-old"}, "spec": {"signerName": "kubernetes.io/kubelet-serving", "username": "system:node:node1"}, "status": {"conditions": [{"type": "Approved"}]}},
+		{"metadata": {"name": "csr-client"}, "spec": {"signerName": "kubernetes.io/kube-apiserver-client-kubelet", "username": "system:node:node1"}, "status": {}},
+		{"metadata": {"name": "csr-other"}, "spec": {"signerName": "kubernetes.io/kubelet-serving", "username": "system:node:node2"}, "status": {}},
+		{"metadata": {"name": "csr-new"}, "spec": {"signerName": "kubernetes.io/kubelet-serving", "username": "system:node:node1"}, "status": {}}
+	]}`)
+	name, err := pendingServingRequest(data, "system:node:node1")
+	if err != nil {
+		t.Fatalf("pendingServingRequest() error = %v", err)
+	}
+	if name != "csr-new" {
+		t.Errorf("pendingServingRequest() = %q, want csr-new", name)
+	}
+	if name, _ := pendingServingRequest(data, "system:node:node3"); name != "" {
+		t.Errorf("pendingServingRequest() = %q for a node without requests", name)
+	}
+}
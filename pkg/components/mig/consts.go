@@ -0,0 +1,27 @@
+package mig
+
+const (
+	// setupScriptPath applies the MIG geometry on every boot, run by setupServiceName before kubelet starts so
+	// the device plugin finds the MIG devices. GPU instances do not survive a reboot.
+	setupScriptPath  = "/etc/aks-flex-node/mig-setup.sh"
+	setupServiceName = "aks-flex-node-mig"
+	setupServicePath = "/etc/systemd/system/aks-flex-node-mig.service"
+
+	// migModeUnsupported is what nvidia-smi reports as MIG mode of GPUs without MIG
+	migModeUnsupported = "[N/A]"
+	migModeEnabled     = "Enabled"
+)
+
+var setupServiceUnit = `[Unit]
+Description=Partition NVIDIA GPUs into MIG devices
+After=systemd-modules-load.service nvidia-persistenced.service
+Before=kubelet.service
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/bin/sh ` + setupScriptPath + `
+
+[Install]
+WantedBy=multi-user.target
+`
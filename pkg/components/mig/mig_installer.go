@@ -0,0 +1,220 @@
+package mig
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer partitions NVIDIA GPUs into Multi-Instance GPU devices. A boot script enables MIG mode and creates
+// the GPU and compute instances of gpu.mig.profiles on each GPU; it runs before kubelet on every boot. The
+// NVIDIA driver and device plugin are not managed by the agent.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new MIG Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "MIGInstaller"
+}
+
+// Validate checks that the NVIDIA driver is installed and that every GPU to partition supports MIG
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.config.GPU.MIG.Enabled {
+		return nil
+	}
+	if !utils.BinaryExists("nvidia-smi") {
+		return fmt.Errorf("nvidia-smi is required to partition GPUs; install the NVIDIA driver first")
+	}
+	modes, err := migModes()
+	if err != nil {
+		return err
+	}
+	gpus, err := i.gpus(modes)
+	if err != nil {
+		return err
+	}
+	for _, gpu := range gpus {
+		if modes[gpu] == migModeUnsupported {
+			return fmt.Errorf("GPU %d does not support MIG; MIG requires an A100, H100 or later GPU", gpu)
+		}
+	}
+	return nil
+}
+
+// Execute applies the MIG geometry, or removes it when gpu.mig is turned off
+func (i *Installer) Execute(ctx context.Context) error {
+	mig := &i.config.GPU.MIG
+	if !mig.Enabled {
+		if isMIGInstalled() {
+			i.logger.Info("gpu.mig.enabled is off, removing the MIG geometry")
+			removeMIG(i.logger, mig.GPUs)
+		}
+		return nil
+	}
+
+	if err := utilio.WriteFile(setupScriptPath, []byte(setupScript(mig)), 0o755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", setupScriptPath, err)
+	}
+	if err := utilio.WriteFile(setupServicePath, []byte(setupServiceUnit), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", setupServicePath, err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := utils.RunSystemCommand("systemctl", "enable", setupServiceName); err != nil {
+		return fmt.Errorf("failed to enable %s: %w", setupServiceName, err)
+	}
+	if i.geometryApplied() {
+		return nil
+	}
+	// Re-run the setup, which recreates the instances; it fails while pods use them
+	if err := utils.RestartService(setupServiceName); err != nil {
+		return fmt.Errorf("failed to partition the GPUs, stop the pods using them and retry: %w", err)
+	}
+	i.logger.Infof("GPUs partitioned into %s", strings.Join(mig.Profiles, ", "))
+	return nil
+}
+
+// IsCompleted reports whether the boot setup is in place and the GPUs hold the configured instances, or the
+// setup is removed when disabled
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	mig := &i.config.GPU.MIG
+	if !mig.Enabled {
+		return !isMIGInstalled()
+	}
+	if !utils.FileHasContent(setupScriptPath, setupScript(mig)) || !utils.FileHasContent(setupServicePath, setupServiceUnit) {
+		return false
+	}
+	return i.geometryApplied()
+}
+
+// geometryApplied reports whether every GPU to partition is in MIG mode with the configured GPU instances
+func (i *Installer) geometryApplied() bool {
+	modes, err := migModes()
+	if err != nil {
+		return false
+	}
+	gpus, err := i.gpus(modes)
+	if err != nil {
+		return false
+	}
+	output, err := utils.RunCommandWithOutput("nvidia-smi", "mig", "-lgi")
+	if err != nil {
+		return false
+	}
+	instances := parseGPUInstances(output)
+	want := slices.Sorted(slices.Values(i.config.GPU.MIG.Profiles))
+	for _, gpu := range gpus {
+		if modes[gpu] != migModeEnabled || !slices.Equal(slices.Sorted(slices.Values(instances[gpu])), want) {
+			return false
+		}
+	}
+	return true
+}
+
+// gpus returns the indexes of the GPUs to partition: gpu.mig.gpus, or all GPUs when unset
+func (i *Installer) gpus(modes map[int]string) ([]int, error) {
+	if len(i.config.GPU.MIG.GPUs) == 0 {
+		return slices.Sorted(maps.Keys(modes)), nil
+	}
+	for _, gpu := range i.config.GPU.MIG.GPUs {
+		if _, ok := modes[gpu]; !ok {
+			return nil, fmt.Errorf("gpu.mig.gpus lists GPU %d, which does not exist", gpu)
+		}
+	}
+	return i.config.GPU.MIG.GPUs, nil
+}
+
+// setupScript renders the boot script that enables MIG mode and recreates the GPU instances, each with a
+// compute instance spanning it. Some GPUs only switch to MIG mode after a reset.
+func setupScript(cfg *config.MIGConfig) string {
+	var sb strings.Builder
+	sb.WriteString(`#!/bin/sh
+# Generated by aks-flex-node from the gpu.mig configuration
+set -eu
+
+mig_mode() {
+    nvidia-smi -i "$1" --query-gpu=mig.mode.current --format=csv,noheader
+}
+
+configure_gpu() {
+    if [ "$(mig_mode "$1")" != "` + migModeEnabled + `" ]; then
+        nvidia-smi -i "$1" -mig 1
+        if [ "$(mig_mode "$1")" != "` + migModeEnabled + `" ]; then
+            nvidia-smi -i "$1" -r
+        fi
+    fi
+    nvidia-smi mig -i "$1" -dci >/dev/null 2>&1 || true
+    nvidia-smi mig -i "$1" -dgi >/dev/null 2>&1 || true
+    nvidia-smi mig -i "$1" -cgi "$2" -C
+}
+
+`)
+	profiles := strings.Join(cfg.Profiles, ",")
+	if len(cfg.GPUs) == 0 {
+		fmt.Fprintf(&sb, "for gpu in $(nvidia-smi --query-gpu=index --format=csv,noheader); do\n    configure_gpu \"$gpu\" %s\ndone\n", profiles)
+		return sb.String()
+	}
+	for _, gpu := range cfg.GPUs {
+		fmt.Fprintf(&sb, "configure_gpu %d %s\n", gpu, profiles)
+	}
+	return sb.String()
+}
+
+// migModes returns the current MIG mode of each GPU by index: Enabled, Disabled or [N/A] without MIG support
+func migModes() (map[int]string, error) {
+	output, err := utils.RunCommandWithOutput("nvidia-smi", "--query-gpu=index,mig.mode.current", "--format=csv,noheader")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the GPUs: %w: %s", err, strings.TrimSpace(output))
+	}
+	modes := map[int]string{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		index, mode, ok := strings.Cut(line, ",")
+		if !ok {
+			continue
+		}
+		gpu, err := strconv.Atoi(strings.TrimSpace(index))
+		if err != nil {
+			continue
+		}
+		modes[gpu] = strings.TrimSpace(mode)
+	}
+	return modes, nil
+}
+
+// parseGPUInstances parses the table of nvidia-smi mig -lgi into the profiles of the GPU instances of each GPU,
+// from rows such as "|   0  MIG 3g.40gb          9        1          4:4     |"
+func parseGPUInstances(output string) map[int][]string {
+	instances := map[int][]string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(strings.Trim(strings.TrimSpace(line), "|"))
+		if len(fields) < 3 || fields[1] != "MIG" {
+			continue
+		}
+		gpu, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		instances[gpu] = append(instances[gpu], fields[2])
+	}
+	return instances
+}
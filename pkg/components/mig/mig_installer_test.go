@@ -0,0 +1,42 @@
+package mig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestParseGPUInstances(t *testing.T) {
+	output := `+-------------------------------------------------------+
+| GPU instances:                                        |
+| GPU   Name             Profile  Instance   Placement  |
+|                          ID       ID       Start:Size |
+|=======================================================|
+|   0  MIG 3g.40gb          9        1          4:4     |
++-------------------------------------------------------+
+|   0  MIG 3g.40gb          9        2          0:4     |
++-------------------------------------------------------+
+|   1  MIG 1g.10gb+me      20        7          0:1     |
++-------------------------------------------------------+
+`
+	want := map[int][]string{0: {"3g.40gb", "3g.40gb"}, 1: {"1g.10gb+me"}}
+	if got := parseGPUInstances(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGPUInstances() = %v, want %v", got, want)
+	}
+	if got := parseGPUInstances("No GPU instances found: Not Found\n"); len(got) != 0 {
+		t.Errorf("parseGPUInstances() without instances = %v, want none", got)
+	}
+}
+
+func TestSetupScript(t *testing.T) {
+	all := setupScript(&config.MIGConfig{Profiles: []string{"3g.40gb", "3g.40gb"}})
+	if !strings.Contains(all, "for gpu in $(nvidia-smi --query-gpu=index --format=csv,noheader); do\n    configure_gpu \"$gpu\" 3g.40gb,3g.40gb\ndone\n") {
+		t.Errorf("setupScript() for all GPUs = %s", all)
+	}
+	selected := setupScript(&config.MIGConfig{Profiles: []string{"7g.80gb"}, GPUs: []int{0, 2}})
+	if !strings.HasSuffix(selected, "configure_gpu 0 7g.80gb\nconfigure_gpu 2 7g.80gb\n") {
+		t.Errorf("setupScript() for selected GPUs = %s", selected)
+	}
+}
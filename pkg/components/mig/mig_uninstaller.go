@@ -0,0 +1,94 @@
+package mig
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller removes the MIG boot setup, destroys the MIG devices and turns MIG mode off
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new MIG UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "MIGUnInstaller"
+}
+
+// Execute removes the MIG boot setup and the MIG devices
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isMIGInstalled() {
+		return nil
+	}
+	removeMIG(u.logger, u.config.GPU.MIG.GPUs)
+	u.logger.Info("MIG geometry removed")
+	return nil
+}
+
+// IsCompleted reports whether no MIG boot setup is left on the node
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isMIGInstalled()
+}
+
+// isMIGInstalled reports whether any file added by the MIG installer is present
+func isMIGInstalled() bool {
+	return utils.FileExists(setupServicePath) || utils.FileExists(setupScriptPath)
+}
+
+// removeMIG removes the boot setup, then destroys the instances of the given GPUs, or all GPUs, and turns MIG
+// mode off. Failures are logged, so cleanup continues.
+func removeMIG(logger *logrus.Logger, gpus []int) {
+	if utils.FileExists(setupServicePath) {
+		if err := utils.StopService(setupServiceName); err != nil {
+			logger.Debugf("Failed to stop %s: %v", setupServiceName, err)
+		}
+		if err := utils.DisableService(setupServiceName); err != nil {
+			logger.Debugf("Failed to disable %s: %v", setupServiceName, err)
+		}
+	}
+	for _, err := range utils.RemoveFiles([]string{setupServicePath, setupScriptPath}, logger) {
+		logger.Warnf("Failed to remove MIG file: %v", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		logger.Warnf("Failed to reload systemd: %v", err)
+	}
+
+	if !utils.BinaryExists("nvidia-smi") {
+		return
+	}
+	if len(gpus) == 0 {
+		modes, err := migModes()
+		if err != nil {
+			logger.Warnf("Failed to list the GPUs to turn MIG off: %v", err)
+			return
+		}
+		for gpu, mode := range modes {
+			if mode != migModeUnsupported {
+				gpus = append(gpus, gpu)
+			}
+		}
+	}
+	for _, gpu := range gpus {
+		index := strconv.Itoa(gpu)
+		// Destroying fails when the GPU holds no instances
+		_ = utils.RunSystemCommand("nvidia-smi", "mig", "-i", index, "-dci")
+		_ = utils.RunSystemCommand("nvidia-smi", "mig", "-i", index, "-dgi")
+		if err := utils.RunSystemCommand("nvidia-smi", "-i", index, "-mig", "0"); err != nil {
+			logger.Warnf("Failed to turn MIG mode off on GPU %d: %v", gpu, err)
+		}
+	}
+}
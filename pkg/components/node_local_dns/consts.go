@@ -0,0 +1,40 @@
+package node_local_dns
+
+const (
+	// setupScriptPath adds the cache's interface and iptables rules on boot and removes them on stop, run by
+	// setupServiceName before kubelet starts so pods never get an address nothing listens on
+	setupScriptPath  = "/etc/aks-flex-node/node-local-dns-setup.sh"
+	setupServiceName = "aks-flex-node-node-local-dns"
+	setupServicePath = "/etc/systemd/system/aks-flex-node-node-local-dns.service"
+
+	// interfaceName is the dummy interface holding the local IP, the name node-cache looks for
+	interfaceName = "nodelocaldns"
+
+	// Corefile of the cache and the static pod running it
+	corefileDir  = "/etc/aks-flex-node/node-local-dns"
+	corefilePath = "/etc/aks-flex-node/node-local-dns/Corefile"
+	manifestPath = "/etc/kubernetes/manifests/node-local-dns.yaml"
+
+	// upstreamResolvConf lists the host's DNS servers, the same file kubelet hands to pods with dnsPolicy Default
+	upstreamResolvConf = "/run/systemd/resolve/resolv.conf"
+
+	// healthPort and metricsPort are served by the cache, health on the local IP only
+	healthPort  = 8080
+	metricsPort = 9253
+)
+
+var setupServiceUnit = `[Unit]
+Description=Interface and iptables rules of the node-local DNS cache
+After=network-online.target
+Wants=network-online.target
+Before=kubelet.service
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/bin/sh ` + setupScriptPath + ` up
+ExecStop=/bin/sh ` + setupScriptPath + ` down
+
+[Install]
+WantedBy=multi-user.target
+`
@@ -0,0 +1,270 @@
+package node_local_dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer runs a node-local DNS cache: a dummy interface holding a link-local address, iptables rules that
+// exempt DNS traffic to it from connection tracking, and node-cache as a static pod. kubelet hands the address
+// to pods, so their queries are answered on the node instead of crossing the network to the cluster's CoreDNS.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new node-local DNS Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "NodeLocalDNSInstaller"
+}
+
+// Validate checks that the tools the setup script uses are installed
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.config.NodeLocalDNS.Enabled {
+		return nil
+	}
+	for _, binary := range []string{"ip", "iptables"} {
+		if !utils.BinaryExists(binary) {
+			return fmt.Errorf("%s is required for the node-local DNS cache", binary)
+		}
+	}
+	return nil
+}
+
+// Execute sets up the node-local DNS cache, or removes it when nodeLocalDNS.enabled is turned off
+func (i *Installer) Execute(ctx context.Context) error {
+	if !i.config.NodeLocalDNS.Enabled {
+		if isNodeLocalDNSInstalled() {
+			i.logger.Info("nodeLocalDNS.enabled is off, removing the node-local DNS cache")
+			removeNodeLocalDNS(i.logger)
+		}
+		return nil
+	}
+
+	localIP := i.config.NodeLocalDNS.LocalIP
+	script := setupScript(localIP)
+	if utils.FileExists(setupScriptPath) && !utils.FileHasContent(setupScriptPath, script) {
+		// Remove the rules of the previous address with the script that added them
+		if err := utils.StopService(setupServiceName); err != nil {
+			i.logger.Debugf("Failed to stop %s: %v", setupServiceName, err)
+		}
+	}
+	if err := utilio.WriteFile(setupScriptPath, []byte(script), 0o755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", setupScriptPath, err)
+	}
+	if err := utilio.WriteFile(setupServicePath, []byte(setupServiceUnit), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", setupServicePath, err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := utils.EnableAndStartService(setupServiceName); err != nil {
+		return fmt.Errorf("failed to set up the node-local DNS interface: %w", err)
+	}
+	// Re-run the setup so rules flushed since boot are added again
+	if err := utils.RestartService(setupServiceName); err != nil {
+		return fmt.Errorf("failed to set up the node-local DNS interface: %w", err)
+	}
+
+	if err := utilio.MkdirAll(corefileDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", corefileDir, err)
+	}
+	if err := utilio.WriteFile(corefilePath, []byte(corefile(localIP, i.config.Node.Kubelet.DNSServiceIP)), 0o644); err != nil {
+		return fmt.Errorf("failed to write the node-local DNS Corefile: %w", err)
+	}
+	manifest := nodeLocalDNSManifest(i.config.GetImage(config.ImageNodeLocalDNS), localIP)
+	if err := utilio.WriteFile(manifestPath, []byte(manifest), 0o644); err != nil {
+		return fmt.Errorf("failed to write the node-local DNS static pod manifest: %w", err)
+	}
+	i.logger.Infof("Node-local DNS cache configured on %s, forwarding cluster queries to %s", localIP, i.config.Node.Kubelet.DNSServiceIP)
+	return nil
+}
+
+// IsCompleted reports whether the cache runs with the current configuration, or is absent when disabled
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if !i.config.NodeLocalDNS.Enabled {
+		return !isNodeLocalDNSInstalled()
+	}
+	localIP := i.config.NodeLocalDNS.LocalIP
+	return utils.FileHasContent(setupScriptPath, setupScript(localIP)) &&
+		utils.FileHasContent(setupServicePath, setupServiceUnit) &&
+		utils.FileHasContent(corefilePath, corefile(localIP, i.config.Node.Kubelet.DNSServiceIP)) &&
+		utils.FileHasContent(manifestPath, nodeLocalDNSManifest(i.config.GetImage(config.ImageNodeLocalDNS), localIP)) &&
+		utils.IsServiceActive(setupServiceName)
+}
+
+// iptablesRule is a rule of the setup script, given as table, chain and match
+type iptablesRule struct {
+	table string
+	chain string
+	spec  string
+}
+
+// iptablesRules returns the rules for DNS traffic to and from localIP. The raw table rules skip connection
+// tracking, so queries neither race on conntrack entries nor exhaust the table; the filter rules keep host
+// firewalls from dropping them.
+func iptablesRules(localIP string) []iptablesRule {
+	var rules []iptablesRule
+	for _, proto := range []string{"udp", "tcp"} {
+		rules = append(rules,
+			iptablesRule{"raw", "PREROUTING", fmt.Sprintf("-d %s/32 -p %s --dport 53 -j NOTRACK", localIP, proto)},
+			iptablesRule{"raw", "OUTPUT", fmt.Sprintf("-d %s/32 -p %s --dport 53 -j NOTRACK", localIP, proto)},
+			iptablesRule{"raw", "OUTPUT", fmt.Sprintf("-s %s/32 -p %s --sport 53 -j NOTRACK", localIP, proto)},
+			iptablesRule{"filter", "INPUT", fmt.Sprintf("-d %s/32 -p %s --dport 53 -j ACCEPT", localIP, proto)},
+			iptablesRule{"filter", "OUTPUT", fmt.Sprintf("-s %s/32 -p %s --sport 53 -j ACCEPT", localIP, proto)},
+		)
+	}
+	return rules
+}
+
+// setupScript renders the script that adds the interface and rules with "up" and removes them with "down".
+// Rules are checked before they are inserted, so running it twice adds nothing.
+func setupScript(localIP string) string {
+	var up, down strings.Builder
+	for _, rule := range iptablesRules(localIP) {
+		fmt.Fprintf(&up, "    iptables -w -t %s -C %s %s 2>/dev/null || iptables -w -t %s -I %s 1 %s\n",
+			rule.table, rule.chain, rule.spec, rule.table, rule.chain, rule.spec)
+		fmt.Fprintf(&down, "    iptables -w -t %s -D %s %s 2>/dev/null || true\n", rule.table, rule.chain, rule.spec)
+	}
+	return fmt.Sprintf(`#!/bin/sh
+# Generated by aks-flex-node from the nodeLocalDNS configuration
+set -eu
+
+case "$1" in
+up)
+    ip link add %s type dummy 2>/dev/null || true
+    ip link set %s up
+    ip addr replace %s/32 dev %s
+%s    ;;
+down)
+%s    ip link del %s 2>/dev/null || true
+    ;;
+esac
+`, interfaceName, interfaceName, localIP, interfaceName, up.String(), down.String(), interfaceName)
+}
+
+// corefile renders the cache configuration: cluster names and reverse lookups go to the cluster DNS service
+// over TCP, which keeps responses intact across links that drop UDP fragments, and other names to the host's
+// resolvers
+func corefile(localIP, clusterDNS string) string {
+	var sb strings.Builder
+	for _, zone := range []string{"cluster.local", "in-addr.arpa", "ip6.arpa"} {
+		fmt.Fprintf(&sb, `%s:53 {
+    errors
+    cache {
+        success 9984 30
+        denial 9984 5
+    }
+    reload
+    loop
+    bind %s
+    forward . %s {
+        force_tcp
+    }
+    prometheus :%d
+`, zone, localIP, clusterDNS, metricsPort)
+		if zone == "cluster.local" {
+			fmt.Fprintf(&sb, "    health %s:%d\n", localIP, healthPort)
+		}
+		sb.WriteString("}\n")
+	}
+	fmt.Fprintf(&sb, `.:53 {
+    errors
+    cache 30
+    reload
+    loop
+    bind %s
+    forward . %s
+    prometheus :%d
+}
+`, localIP, upstreamResolvConf, metricsPort)
+	return sb.String()
+}
+
+// nodeLocalDNSManifest renders the node-cache static pod. The setup service owns the interface and rules, so
+// node-cache neither adds them nor removes them when it exits.
+func nodeLocalDNSManifest(image, localIP string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: node-local-dns
+  namespace: kube-system
+  labels:
+    k8s-app: node-local-dns
+spec:
+  hostNetwork: true
+  dnsPolicy: Default
+  priorityClassName: system-node-critical
+  containers:
+  - name: node-cache
+    image: %s
+    args:
+    - -localip=%s
+    - -conf=%s
+    - -interfacename=%s
+    - -setupinterface=false
+    - -setupiptables=false
+    - -skipteardown=true
+    - -health-port=%d
+    resources:
+      requests:
+        cpu: 25m
+        memory: 5Mi
+    securityContext:
+      capabilities:
+        add:
+        - NET_ADMIN
+    ports:
+    - name: dns
+      containerPort: 53
+      protocol: UDP
+    - name: dns-tcp
+      containerPort: 53
+      protocol: TCP
+    - name: metrics
+      containerPort: %d
+      protocol: TCP
+    livenessProbe:
+      httpGet:
+        host: %s
+        path: /health
+        port: %d
+      initialDelaySeconds: 60
+      timeoutSeconds: 5
+    volumeMounts:
+    - name: config
+      mountPath: %s
+      readOnly: true
+    - name: resolv
+      mountPath: /run/systemd/resolve
+      readOnly: true
+    - name: xtables-lock
+      mountPath: /run/xtables.lock
+  volumes:
+  - name: config
+    hostPath:
+      path: %s
+  - name: resolv
+    hostPath:
+      path: /run/systemd/resolve
+  - name: xtables-lock
+    hostPath:
+      path: /run/xtables.lock
+      type: FileOrCreate
+`, image, localIP, corefilePath, interfaceName, healthPort, metricsPort, localIP, healthPort, corefileDir, corefileDir)
+}
@@ -0,0 +1,47 @@
+package node_local_dns
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetupScript(t *testing.T) {
+	got := setupScript("169.254.20.10")
+	for _, want := range []string{
+		"    ip link add nodelocaldns type dummy 2>/dev/null || true\n",
+		"    ip addr replace 169.254.20.10/32 dev nodelocaldns\n",
+		"    iptables -w -t raw -C PREROUTING -d 169.254.20.10/32 -p udp --dport 53 -j NOTRACK 2>/dev/null || iptables -w -t raw -I PREROUTING 1 -d 169.254.20.10/32 -p udp --dport 53 -j NOTRACK\n",
+		"    iptables -w -t raw -C OUTPUT -s 169.254.20.10/32 -p tcp --sport 53 -j NOTRACK 2>/dev/null || iptables -w -t raw -I OUTPUT 1 -s 169.254.20.10/32 -p tcp --sport 53 -j NOTRACK\n",
+		"    iptables -w -t filter -D INPUT -d 169.254.20.10/32 -p tcp --dport 53 -j ACCEPT 2>/dev/null || true\n",
+		"    ip link del nodelocaldns 2>/dev/null || true\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("setupScript() is missing %q:\n%s", want, got)
+		}
+	}
+	if up, down := strings.Count(got, " -I "), strings.Count(got, " -D "); up != 10 || down != 10 {
+		t.Errorf("setupScript() inserts %d and deletes %d rules, want 10 each", up, down)
+	}
+}
+
+func TestCorefile(t *testing.T) {
+	got := corefile("169.254.20.10", "10.0.0.10")
+	for _, want := range []string{
+		"cluster.local:53 {\n",
+		"in-addr.arpa:53 {\n",
+		"ip6.arpa:53 {\n",
+		"    forward . 10.0.0.10 {\n        force_tcp\n    }\n",
+		"    health 169.254.20.10:8080\n",
+		"    forward . /run/systemd/resolve/resolv.conf\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("corefile() is missing %q:\n%s", want, got)
+		}
+	}
+	if binds := strings.Count(got, "    bind 169.254.20.10\n"); binds != 4 {
+		t.Errorf("corefile() binds %d server blocks to the local IP, want 4", binds)
+	}
+	if health := strings.Count(got, "health"); health != 1 {
+		t.Errorf("corefile() has %d health endpoints, want 1", health)
+	}
+}
@@ -0,0 +1,80 @@
+package node_local_dns
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/installed_files"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller removes the node-local DNS cache, its interface and its iptables rules
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new node-local DNS UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "NodeLocalDNSUnInstaller"
+}
+
+// Execute stops the cache and removes its files
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isNodeLocalDNSInstalled() {
+		return nil
+	}
+	removeNodeLocalDNS(u.logger)
+	u.logger.Info("Node-local DNS cache removed")
+	return nil
+}
+
+// IsCompleted reports whether no node-local DNS files are left on the node
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isNodeLocalDNSInstalled()
+}
+
+// isNodeLocalDNSInstalled reports whether any file written by the node-local DNS installer is present
+func isNodeLocalDNSInstalled() bool {
+	for _, path := range []string{setupServicePath, setupScriptPath, corefileDir, manifestPath} {
+		if utils.FileExists(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeNodeLocalDNS removes the static pod, then stops the setup service, which deletes the interface and the
+// iptables rules. Failures are logged, so cleanup continues.
+func removeNodeLocalDNS(logger *logrus.Logger) {
+	if err := utils.RunCleanupCommand(manifestPath); err != nil {
+		logger.Warnf("Failed to remove the node-local DNS static pod manifest: %v", err)
+	}
+	if utils.FileExists(setupServicePath) {
+		if err := utils.StopService(setupServiceName); err != nil {
+			logger.Debugf("Failed to stop %s: %v", setupServiceName, err)
+		}
+		if err := utils.DisableService(setupServiceName); err != nil {
+			logger.Debugf("Failed to disable %s: %v", setupServiceName, err)
+		}
+	}
+	for _, err := range utils.RemoveFiles([]string{setupServicePath, setupScriptPath}, logger) {
+		logger.Warnf("Failed to remove node-local DNS file: %v", err)
+	}
+	for _, err := range utils.RemoveDirectories(installed_files.CreatedPaths(corefileDir), logger) {
+		logger.Warnf("Failed to remove the node-local DNS configuration: %v", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		logger.Warnf("Failed to reload systemd: %v", err)
+	}
+}
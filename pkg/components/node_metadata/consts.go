@@ -0,0 +1,27 @@
+package node_metadata
+
+import "time"
+
+// Annotations recording how the node was provisioned
+const (
+	agentVersionAnnotation      = "kubernetes.azure.com/flex-node-version"
+	bootstrapTimeAnnotation     = "kubernetes.azure.com/flex-bootstrap-time"
+	sourceAnnotation            = "kubernetes.azure.com/flex-source"
+	machineResourceIDAnnotation = "kubernetes.azure.com/flex-machine-resource-id"
+	componentVersionsAnnotation = "kubernetes.azure.com/flex-component-versions"
+)
+
+// Values of sourceAnnotation: the machine is connected through Azure Arc, is an Azure VM, or neither
+const (
+	sourceArc     = "arc"
+	sourceAzureVM = "azurevm"
+	sourceOther   = "other"
+)
+
+const (
+	// kubeletBinaryPath is where kube_binaries installs kubelet, which is not always on the PATH
+	kubeletBinaryPath = "/usr/local/bin/kubelet"
+
+	// nodeRegistrationTimeout bounds the wait for kubelet to register the node after the services start
+	nodeRegistrationTimeout = 2 * time.Minute
+)
@@ -0,0 +1,173 @@
+package node_metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// versionPattern matches the version in the --version output of kubelet, the container runtimes and the OCI
+// runtimes, e.g. "Kubernetes v1.32.7" or "runc version 1.1.12"
+var versionPattern = regexp.MustCompile(`\bv?(\d+\.\d+\.\d+\S*)`)
+
+// Installer annotates the Node object with how the node was provisioned: the agent version, the bootstrap
+// time, whether the machine is an Arc machine or an Azure VM, its resource ID and the versions of the node
+// components. Operators can then audit how each node was built.
+type Installer struct {
+	config       *config.Config
+	logger       *logrus.Logger
+	agentVersion string
+}
+
+// NewInstaller creates a new node metadata Installer
+func NewInstaller(logger *logrus.Logger, agentVersion string) *Installer {
+	return &Installer{
+		config:       config.GetConfig(),
+		logger:       logger,
+		agentVersion: agentVersion,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "NodeMetadataAnnotated"
+}
+
+// Validate has no preconditions beyond kubelet running, which the services step ensures
+func (i *Installer) Validate(ctx context.Context) error {
+	return nil
+}
+
+// Execute waits for kubelet to register the node and annotates it
+func (i *Installer) Execute(ctx context.Context) error {
+	annotations := i.annotations(ctx)
+	annotations[bootstrapTimeAnnotation] = time.Now().UTC().Format(time.RFC3339)
+
+	deadline := time.Now().Add(nodeRegistrationTimeout)
+	for {
+		_, err := nodeAnnotations()
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the node was not registered within %s: %w", nodeRegistrationTimeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		return err
+	}
+	args := []string{"--kubeconfig", kubelet.KubeletKubeconfigPath, "annotate", "node", nodeName, "--overwrite"}
+	for _, key := range slices.Sorted(maps.Keys(annotations)) {
+		args = append(args, key+"="+annotations[key])
+	}
+	if output, err := utils.RunCommandWithOutput("kubectl", args...); err != nil {
+		return fmt.Errorf("failed to annotate node %s: %w, output: %s", nodeName, err, strings.TrimSpace(output))
+	}
+	i.logger.Infof("Annotated node %s with its provisioning metadata", nodeName)
+	return nil
+}
+
+// IsCompleted reports whether the node carries the current provisioning metadata. The bootstrap time only
+// changes when the rest of the metadata does.
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	current, err := nodeAnnotations()
+	if err != nil || current[bootstrapTimeAnnotation] == "" {
+		return false
+	}
+	for key, value := range i.annotations(ctx) {
+		if current[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// annotations returns the provisioning metadata, except the bootstrap time
+func (i *Installer) annotations(ctx context.Context) map[string]string {
+	annotations := map[string]string{
+		agentVersionAnnotation: i.agentVersion,
+		sourceAnnotation:       sourceOther,
+	}
+	if i.config.IsARCEnabled() {
+		annotations[sourceAnnotation] = sourceArc
+		annotations[machineResourceIDAnnotation] = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.HybridCompute/machines/%s",
+			i.config.GetSubscriptionID(), i.config.GetArcResourceGroup(), i.config.GetArcMachineName())
+	} else if vm := utilhost.DetectAzureVM(ctx); vm != nil {
+		annotations[sourceAnnotation] = sourceAzureVM
+		annotations[machineResourceIDAnnotation] = vm.ResourceID
+	}
+	if versions, err := json.Marshal(i.componentVersions()); err == nil {
+		annotations[componentVersionsAnnotation] = string(versions)
+	}
+	return annotations
+}
+
+// componentVersions returns the versions of kubelet and the container and OCI runtimes, leaving out those that
+// cannot be read
+func (i *Installer) componentVersions() map[string]string {
+	binaries := map[string]string{
+		"kubelet":                         kubeletBinaryPath,
+		i.config.Runtime.ContainerRuntime: i.config.Runtime.ContainerRuntime,
+		i.config.Runtime.OCIRuntime:       i.config.Runtime.OCIRuntime,
+	}
+	versions := make(map[string]string, len(binaries))
+	for component, binary := range binaries {
+		output, err := utils.RunCommandWithOutput(binary, "--version")
+		if err != nil {
+			i.logger.Debugf("Failed to read the %s version: %v", component, err)
+			continue
+		}
+		if version := parseVersion(output); version != "" {
+			versions[component] = version
+		}
+	}
+	return versions
+}
+
+// parseVersion returns the first version in the --version output of a binary
+func parseVersion(output string) string {
+	if match := versionPattern.FindStringSubmatch(output); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// nodeAnnotations returns the annotations of this node
+func nodeAnnotations() (map[string]string, error) {
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		return nil, err
+	}
+	output, err := utils.RunCommandWithOutput("kubectl", "--kubeconfig", kubelet.KubeletKubeconfigPath,
+		"get", "node", nodeName, "--output", "jsonpath={.metadata.annotations}")
+	if err != nil {
+		return nil, fmt.Errorf("%w, output: %s", err, strings.TrimSpace(output))
+	}
+	annotations := map[string]string{}
+	if strings.TrimSpace(output) == "" {
+		return annotations, nil
+	}
+	if err := json.Unmarshal([]byte(output), &annotations); err != nil {
+		return nil, fmt.Errorf("failed to parse the annotations of node %s: %w", nodeName, err)
+	}
+	return annotations, nil
+}
@@ -0,0 +1,18 @@
+package node_metadata
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]string{
+		"Kubernetes v1.32.7\n": "1.32.7",
+		"containerd github.com/containerd/containerd/v2 v2.0.4 1a43cb6a1035441f9aca8f5666a9b3ef9e70ab20\n": "2.0.4",
+		"runc version 1.2.5\ncommit: v1.2.5-0-g59923ef1\nspec: 1.2.0\n":                                    "1.2.5",
+		"crio version 1.32.1\n": "1.32.1",
+		"no version here\n":     "",
+	}
+	for output, want := range tests {
+		if got := parseVersion(output); got != want {
+			t.Errorf("parseVersion(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
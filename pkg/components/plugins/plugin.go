@@ -0,0 +1,131 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// Actions a plugin executable is called with, as its first argument
+const (
+	actionValidate    = "validate"
+	actionIsCompleted = "is-completed"
+	actionExecute     = "execute"
+	actionUninstall   = "uninstall"
+)
+
+// Installer runs a plugin as a bootstrap step. The plugin is called with validate, is-completed and execute,
+// the same contract as the agent's own components: is-completed exits with 0 when the component is in place.
+type Installer struct {
+	plugin config.PluginConfig
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new plugin Installer
+func NewInstaller(logger *logrus.Logger, plugin config.PluginConfig) *Installer {
+	return &Installer{
+		plugin: plugin,
+		logger: logger,
+	}
+}
+
+// GetName returns the step name, the plugin name
+func (i *Installer) GetName() string {
+	return i.plugin.Name
+}
+
+// Validate checks the plugin executable exists and calls its validate action
+func (i *Installer) Validate(ctx context.Context) error {
+	if _, err := os.Stat(i.plugin.Path); err != nil {
+		return fmt.Errorf("plugin %s: %w", i.plugin.Name, err)
+	}
+	return run(ctx, i.plugin, actionValidate, "bootstrap", i.logger)
+}
+
+// IsCompleted calls the is-completed action
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	return run(ctx, i.plugin, actionIsCompleted, "bootstrap", i.logger) == nil
+}
+
+// Execute calls the execute action
+func (i *Installer) Execute(ctx context.Context) error {
+	return run(ctx, i.plugin, actionExecute, "bootstrap", i.logger)
+}
+
+// UnInstaller removes a plugin's component on unbootstrap by calling its uninstall action, which must succeed
+// when there is nothing left to remove
+type UnInstaller struct {
+	plugin config.PluginConfig
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new plugin UnInstaller
+func NewUnInstaller(logger *logrus.Logger, plugin config.PluginConfig) *UnInstaller {
+	return &UnInstaller{
+		plugin: plugin,
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return u.plugin.Name + "UnInstaller"
+}
+
+// IsCompleted reports whether the plugin executable is gone, leaving nothing to call
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	_, err := os.Stat(u.plugin.Path)
+	return err != nil
+}
+
+// Execute calls the uninstall action
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	return run(ctx, u.plugin, actionUninstall, "unbootstrap", u.logger)
+}
+
+// run calls the plugin with an action and logs its output. The plugin learns the operation and where the
+// kubelet kubeconfig is from its environment.
+func run(ctx context.Context, plugin config.PluginConfig, action, operation string, logger *logrus.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, plugin.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, plugin.Path, append([]string{action}, plugin.Args...)...) // #nosec G204 -- plugins are configured by the node operator
+	cmd.Env = append(os.Environ(), pluginEnv(plugin, action, operation)...)
+	// Stop waiting for the output of processes the plugin left behind once it was killed
+	cmd.WaitDelay = time.Second
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	for line := range strings.Lines(output.String()) {
+		logger.Infof("[%s %s] %s", plugin.Name, action, strings.TrimRight(line, "\n"))
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("plugin %s %s timed out after %s", plugin.Name, action, plugin.Timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("plugin %s %s failed: %w", plugin.Name, action, err)
+	}
+	return nil
+}
+
+// pluginEnv returns the environment the agent sets for a plugin, followed by the configured variables
+func pluginEnv(plugin config.PluginConfig, action, operation string) []string {
+	env := []string{
+		"AKS_FLEX_NODE_PLUGIN=" + plugin.Name,
+		"AKS_FLEX_NODE_ACTION=" + action,
+		"AKS_FLEX_NODE_OPERATION=" + operation,
+		"AKS_FLEX_NODE_KUBECONFIG=" + kubelet.KubeletKubeconfigPath,
+	}
+	return append(env, plugin.Env...)
+}
@@ -0,0 +1,85 @@
+package plugins
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// pluginScript is a plugin that installs by creating a marker file and reports the calls it gets
+const pluginScript = `#!/bin/sh
+echo "$1 $AKS_FLEX_NODE_OPERATION $SENSOR_TENANT $2" >> "$CALLS"
+case "$1" in
+validate) exit 0 ;;
+is-completed) test -f "$MARKER" ;;
+execute) touch "$MARKER" ;;
+uninstall) rm -f "$MARKER" ;;
+*) exit 2 ;;
+esac
+`
+
+func TestPlugin(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plugin")
+	if err := os.WriteFile(path, []byte(pluginScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	calls := filepath.Join(dir, "calls")
+	plugin := config.PluginConfig{
+		Name:    "SecuritySensor",
+		Path:    path,
+		Args:    []string{"--verbose"},
+		Env:     []string{"SENSOR_TENANT=contoso", "CALLS=" + calls, "MARKER=" + filepath.Join(dir, "installed")},
+		Timeout: 10 * time.Second,
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	installer := NewInstaller(logger, plugin)
+	uninstaller := NewUnInstaller(logger, plugin)
+
+	if err := installer.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if installer.IsCompleted(t.Context()) {
+		t.Fatal("IsCompleted() = true before execute")
+	}
+	if err := installer.Execute(t.Context()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !installer.IsCompleted(t.Context()) {
+		t.Error("IsCompleted() = false after execute")
+	}
+	if err := uninstaller.Execute(t.Context()); err != nil {
+		t.Fatalf("uninstall error = %v", err)
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "validate bootstrap contoso --verbose\nis-completed bootstrap contoso --verbose\nexecute bootstrap contoso --verbose\n" +
+		"is-completed bootstrap contoso --verbose\nuninstall unbootstrap contoso --verbose\n"
+	if got := string(data); got != want {
+		t.Errorf("plugin calls = %q, want %q", got, want)
+	}
+}
+
+func TestPluginTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nsleep 5\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	plugin := config.PluginConfig{Name: "Slow", Path: path, Timeout: 100 * time.Millisecond}
+	if err := NewInstaller(logger, plugin).Execute(t.Context()); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Execute() error = %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,63 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// checkAgentPool checks that node.agentPool names an agent pool of the target cluster and that the node
+// carries the pool's mode, labels and taints, so pods selecting the pool schedule onto it as onto its VMs.
+// A pool the cluster does not have is a logical one, known only by the node's agentpool labels.
+func (c *Checker) checkAgentPool(ctx context.Context) CheckResult {
+	name := c.config.Node.AgentPool.Name
+	if name == "" {
+		return pass("node is not associated with an agent pool")
+	}
+	if c.collectSpec == nil {
+		return warn("could not verify that agent pool %s exists without a target cluster", name)
+	}
+	clusterSpec, err := c.collectSpec(ctx)
+	if err != nil {
+		return warn("could not read the agent pools of the target cluster: %v", err)
+	}
+	pool := clusterSpec.AgentPool(name)
+	if pool == nil {
+		pools := make([]string, 0, len(clusterSpec.AgentPools))
+		for _, p := range clusterSpec.AgentPools {
+			pools = append(pools, p.Name)
+		}
+		return warn("the target cluster has no agent pool %s (it has %s), so the node forms a logical pool known by its %s and %s labels",
+			name, strings.Join(pools, ", "), config.AgentPoolLabel, config.AgentPoolModeLabel)
+	}
+
+	mode := c.config.Node.AgentPool.Mode
+	if pool.Mode != "" && !strings.EqualFold(pool.Mode, mode) {
+		return fail("node.agentPool.mode is %s but agent pool %s is a %s pool. Set node.agentPool.mode to %s", mode, name, pool.Mode, pool.Mode)
+	}
+	var missingLabels []string
+	for _, key := range slices.Sorted(maps.Keys(pool.NodeLabels)) {
+		if value, ok := c.config.Node.Labels[key]; !ok || value != pool.NodeLabels[key] {
+			missingLabels = append(missingLabels, fmt.Sprintf("%s=%s", key, pool.NodeLabels[key]))
+		}
+	}
+	if len(missingLabels) > 0 {
+		return fail("agent pool %s labels its nodes %s, which node.labels lacks; add them so pods selecting the pool schedule onto the node",
+			name, strings.Join(missingLabels, ", "))
+	}
+	var missingTaints []string
+	for _, taint := range pool.NodeTaints {
+		if !slices.Contains(c.config.Node.Taints, taint) {
+			missingTaints = append(missingTaints, taint)
+		}
+	}
+	if len(missingTaints) > 0 {
+		return warn("agent pool %s taints its nodes %s, which node.taints lacks, so pods not tolerating them may schedule onto the node",
+			name, strings.Join(missingTaints, ", "))
+	}
+	return pass("node presents as %s agent pool %s", mode, name)
+}
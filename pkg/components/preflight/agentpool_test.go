@@ -0,0 +1,52 @@
+package preflight
+
+import (
+	"context"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+)
+
+func TestCheckAgentPool(t *testing.T) {
+	cluster := &spec.ManagedClusterSpec{AgentPools: []spec.AgentPoolSpec{
+		{Name: "system", Mode: "System"},
+		{Name: "gpu", Mode: "User", NodeLabels: map[string]string{"sku": "gpu"}, NodeTaints: []string{"sku=gpu:NoSchedule"}},
+	}}
+
+	tests := []struct {
+		name       string
+		pool       config.AgentPoolConfig
+		labels     map[string]string
+		taints     []string
+		cluster    *spec.ManagedClusterSpec
+		wantStatus string
+	}{
+		{name: "no agent pool", wantStatus: StatusPass},
+		{name: "without target cluster", pool: config.AgentPoolConfig{Name: "gpu", Mode: "User"}, wantStatus: StatusWarn},
+		{name: "logical pool", pool: config.AgentPoolConfig{Name: "edge", Mode: "User"}, cluster: cluster, wantStatus: StatusWarn},
+		{name: "mode mismatch", pool: config.AgentPoolConfig{Name: "system", Mode: "User"}, cluster: cluster, wantStatus: StatusFail},
+		{name: "missing pool labels", pool: config.AgentPoolConfig{Name: "gpu", Mode: "User"}, cluster: cluster, wantStatus: StatusFail},
+		{name: "missing pool taints", pool: config.AgentPoolConfig{Name: "gpu", Mode: "User"}, labels: map[string]string{"sku": "gpu"}, cluster: cluster, wantStatus: StatusWarn},
+		{
+			name: "matching pool", pool: config.AgentPoolConfig{Name: "gpu", Mode: "User"}, labels: map[string]string{"sku": "gpu"},
+			taints: []string{"sku=gpu:NoSchedule"}, cluster: cluster, wantStatus: StatusPass,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Node.AgentPool = tt.pool
+			cfg.Node.Labels = tt.labels
+			cfg.Node.Taints = tt.taints
+			c := &Checker{config: cfg}
+			if tt.cluster != nil {
+				c.collectSpec = func(ctx context.Context) (*spec.ManagedClusterSpec, error) { return tt.cluster, nil }
+			}
+			if result := c.checkAgentPool(context.Background()); result.Status != tt.wantStatus {
+				t.Errorf("checkAgentPool() = %+v, want %s", result, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// endpoint is an HTTPS endpoint bootstrap or the running node needs to reach
+type endpoint struct {
+	name  string
+	url   string
+	proxy string // Proxy URL used instead of network.proxy, e.g. azure.arc.proxy.url for the Arc endpoints
+}
+
+// EndpointResult is the outcome of probing a single endpoint
+type EndpointResult struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// checkConnectivity probes every endpoint bootstrap needs, through the configured proxy, so that a firewall or
+// proxy rule missing for one of them fails before the host is changed instead of halfway through bootstrap
+func (c *Checker) checkConnectivity(ctx context.Context) CheckResult {
+	results := probeEndpoints(ctx, c.endpoints())
+
+	var unreachable []string
+	for _, result := range results {
+		if !result.Reachable {
+			unreachable = append(unreachable, fmt.Sprintf("%s (%s): %s", result.Name, result.URL, result.Error))
+		}
+	}
+	result := pass("all %d required endpoints are reachable", len(results))
+	if len(unreachable) > 0 {
+		result = fail("%d of %d endpoints unreachable: %s", len(unreachable), len(results), strings.Join(unreachable, "; "))
+	}
+	result.Endpoints = results
+	return result
+}
+
+// endpoints returns the endpoints to probe, without duplicates
+func (c *Checker) endpoints() []endpoint {
+	endpoints := []endpoint{
+		{name: "ARM", url: armEndpoint},
+		{name: "AAD", url: aadEndpoint},
+	}
+
+	registryHost, _, _ := strings.Cut(c.config.GetImageRegistry(), "/")
+	endpoints = append(endpoints, endpoint{name: "image registry", url: "https://" + registryHost + "/v2/"})
+
+	if serverURL := c.config.Node.Kubelet.ServerURL; serverURL != "" {
+		endpoints = append(endpoints, endpoint{name: "API server", url: serverURL})
+	}
+
+	if c.config.IsARCEnabled() {
+		proxy := c.config.Azure.Arc.Proxy.URL
+		endpoints = append(endpoints, endpoint{name: "Arc", url: arcGlobalEndpoint, proxy: proxy})
+		if location := c.config.GetArcLocation(); location != "" {
+			endpoints = append(endpoints, endpoint{name: "Arc", url: fmt.Sprintf(arcRegionalEndpoint, location), proxy: proxy})
+		}
+	}
+
+	kubernetesURL := defaultKubernetesURLTemplate
+	if c.config.Kubernetes.URLTemplate != "" {
+		kubernetesURL = c.config.Kubernetes.URLTemplate
+	}
+	endpoints = append(endpoints,
+		endpoint{name: "Kubernetes binaries", url: siteURL(kubernetesURL)},
+		endpoint{name: "runtime and plugin releases", url: githubReleasesEndpoint},
+	)
+
+	for _, extra := range c.config.Preflight.Connectivity.Endpoints {
+		endpoints = append(endpoints, endpoint{name: "preflight.connectivity", url: extra})
+	}
+
+	seen := make(map[string]bool, len(endpoints))
+	unique := endpoints[:0]
+	for _, ep := range endpoints {
+		if !seen[ep.url] {
+			seen[ep.url] = true
+			unique = append(unique, ep)
+		}
+	}
+	return unique
+}
+
+// siteURL returns the scheme and host of a download URL, which may still contain format verbs in its path
+func siteURL(rawURL string) string {
+	scheme, rest, found := strings.Cut(rawURL, "://")
+	if !found {
+		return rawURL
+	}
+	host, _, _ := strings.Cut(rest, "/")
+	return scheme + "://" + host + "/"
+}
+
+// probeEndpoints sends a request to every endpoint at the same time. Any HTTP response counts as reachable,
+// including 401 and 404, since only connection, proxy and TLS failures block bootstrap.
+func probeEndpoints(ctx context.Context, endpoints []endpoint) []EndpointResult {
+	results := make([]EndpointResult, len(endpoints))
+	var wg sync.WaitGroup
+	for index, ep := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[index] = EndpointResult{Name: ep.name, URL: ep.url}
+			if err := probeEndpoint(ctx, ep); err != nil {
+				results[index].Error = err.Error()
+				return
+			}
+			results[index].Reachable = true
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// probeEndpoint sends a HEAD request to the endpoint through its proxy, or through the proxy environment
+// network.proxy set up for the agent
+func probeEndpoint(ctx context.Context, ep endpoint) error {
+	transport := &http.Transport{}
+	if defaultTransport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = defaultTransport.Clone()
+	}
+	if ep.proxy != "" {
+		proxyURL, err := url.Parse(ep.proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy %s: %w", ep.proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	client := &http.Client{Transport: transport, Timeout: probeRequestTimeout}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, ep.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,74 @@
+package preflight
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestProbeEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Authentication errors still prove the endpoint is reachable
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	closedURL := "http://" + listener.Addr().String() + "/"
+	_ = listener.Close()
+
+	results := probeEndpoints(context.Background(), []endpoint{
+		{name: "up", url: server.URL},
+		{name: "down", url: closedURL},
+	})
+	if !results[0].Reachable || results[0].Error != "" {
+		t.Errorf("expected %s to be reachable, got %+v", server.URL, results[0])
+	}
+	if results[1].Reachable || results[1].Error == "" {
+		t.Errorf("expected %s to be unreachable, got %+v", closedURL, results[1])
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Images.Registry = "myregistry.azurecr.io/mirror"
+	cfg.Node.Kubelet.ServerURL = "https://cluster.hcp.eastus.azmk8s.io:443"
+	cfg.Kubernetes.URLTemplate = "https://artifacts.corp.example.com/kubernetes/v%s/kubernetes-node-linux-%s.tar.gz"
+	cfg.Azure.Arc = &config.ArcConfig{Enabled: true, Location: "westus2", Proxy: config.ArcProxyConfig{URL: "http://arc-proxy:3128"}}
+	cfg.Preflight.Connectivity.Endpoints = []string{"https://management.azure.com/", "https://mirror.corp.example.com/"}
+
+	c := &Checker{config: cfg}
+	got := map[string]endpoint{}
+	for _, ep := range c.endpoints() {
+		if _, dup := got[ep.url]; dup {
+			t.Errorf("endpoint %s listed twice", ep.url)
+		}
+		got[ep.url] = ep
+	}
+
+	for _, want := range []string{
+		armEndpoint,
+		aadEndpoint,
+		"https://myregistry.azurecr.io/v2/",
+		"https://cluster.hcp.eastus.azmk8s.io:443",
+		arcGlobalEndpoint,
+		"https://westus2.his.arc.azure.com/",
+		"https://artifacts.corp.example.com/",
+		githubReleasesEndpoint,
+		"https://mirror.corp.example.com/",
+	} {
+		if _, ok := got[want]; !ok {
+			t.Errorf("endpoints() is missing %s", want)
+		}
+	}
+	if got[arcGlobalEndpoint].proxy != "http://arc-proxy:3128" {
+		t.Errorf("Arc endpoint proxy = %q, want azure.arc.proxy.url", got[arcGlobalEndpoint].proxy)
+	}
+}
@@ -0,0 +1,55 @@
+package preflight
+
+import "time"
+
+// Check outcomes, from least to most severe
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+const (
+	// probeDialTimeout bounds each connection of the SNAT probe
+	probeDialTimeout = 10 * time.Second
+
+	// Connections the node itself keeps open (kubelet, containerd pulls, Arc agents, NPD) on top of pod traffic
+	nodeBaselineConnections = 64
+
+	// Warn when the estimated demand exceeds this fraction of the NAT port capacity, since ports stay
+	// reserved in TIME_WAIT for minutes after a connection closes
+	snatCapacityWarnRatio = 0.8
+
+	conntrackMaxPath = "/proc/sys/net/netfilter/nf_conntrack_max"
+
+	// probeRequestTimeout bounds each request of the connectivity check
+	probeRequestTimeout = 15 * time.Second
+
+	// rttSamples is the number of TCP handshakes the median round-trip time is taken from
+	rttSamples = 5
+
+	// The throughput sample is large enough to leave TCP slow start behind on fast links and small enough
+	// to finish within the timeout on slow ones
+	throughputSampleBytes = 16 << 20
+	throughputTimeout     = 30 * time.Second
+)
+
+// Endpoints probed by the connectivity check
+const (
+	armEndpoint                  = "https://management.azure.com/"
+	aadEndpoint                  = "https://login.microsoftonline.com/"
+	arcGlobalEndpoint            = "https://gbl.his.arc.azure.com/"
+	arcRegionalEndpoint          = "https://%s.his.arc.azure.com/"
+	defaultKubernetesURLTemplate = "https://acs-mirror.azureedge.net/kubernetes/v%s/binaries/kubernetes-node-linux-%s.tar.gz"
+	githubReleasesEndpoint       = "https://github.com/"
+)
+
+// requiredPorts are the ports a node must accept connections on or open connections to
+var requiredPorts = []portRequirement{
+	{name: "kubelet API", protocol: "tcp", from: 10250, to: 10250, inbound: true},
+	{name: "NodePort services", protocol: "tcp", from: 30000, to: 32767, inbound: true},
+	{name: "NodePort services", protocol: "udp", from: 30000, to: 32767, inbound: true},
+	{name: "API server and registries", protocol: "tcp", from: 443, to: 443},
+	{name: "DNS", protocol: "udp", from: 53, to: 53},
+	{name: "DNS", protocol: "tcp", from: 53, to: 53},
+}
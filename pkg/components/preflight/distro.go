@@ -0,0 +1,35 @@
+package preflight
+
+import (
+	"context"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// containerSELinuxPackage ships the SELinux policy that lets containerd and its containers run in enforcing mode
+const containerSELinuxPackage = "container-selinux"
+
+// checkDistro checks that the host runs a distribution bootstrap is tested on
+func (c *Checker) checkDistro(ctx context.Context) CheckResult {
+	distro, err := utilhost.DetectDistro()
+	if err != nil {
+		return warn("could not detect the distribution: %v", err)
+	}
+	mode := utilhost.SELinuxMode()
+	return distroResult(distro, mode, mode == utilhost.SELinuxDisabled || utilhost.PackageInstalled(containerSELinuxPackage))
+}
+
+// distroResult evaluates the host's distribution and SELinux mode, and whether the container SELinux policy is installed
+func distroResult(distro utilhost.Distro, selinuxMode string, containerPolicy bool) CheckResult {
+	if err := distro.Supported(); err != nil {
+		return fail("%v", err)
+	}
+	if selinuxMode == utilhost.SELinuxEnforcing && !containerPolicy {
+		return warn("%s runs SELinux in enforcing mode without %s, which bootstrap installs. Until then containers "+
+			"cannot start", distro, containerSELinuxPackage)
+	}
+	if distro.Family() == utilhost.DistroFamilyRHEL {
+		return pass("%s, SELinux %s", distro, selinuxMode)
+	}
+	return pass("%s", distro)
+}
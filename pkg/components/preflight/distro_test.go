@@ -0,0 +1,34 @@
+package preflight
+
+import (
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+func TestDistroResult(t *testing.T) {
+	ubuntu := utilhost.Distro{ID: "ubuntu", IDLike: []string{"debian"}, VersionID: "24.04"}
+	rocky := utilhost.Distro{ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}, VersionID: "9.4"}
+	tests := []struct {
+		name            string
+		distro          utilhost.Distro
+		selinuxMode     string
+		containerPolicy bool
+		want            string
+	}{
+		{name: "ubuntu", distro: ubuntu, selinuxMode: utilhost.SELinuxDisabled, want: StatusPass},
+		{name: "rocky enforcing", distro: rocky, selinuxMode: utilhost.SELinuxEnforcing, containerPolicy: true, want: StatusPass},
+		{name: "rocky without container policy", distro: rocky, selinuxMode: utilhost.SELinuxEnforcing, want: StatusWarn},
+		{name: "rocky permissive", distro: rocky, selinuxMode: utilhost.SELinuxPermissive, want: StatusPass},
+		{name: "old release", distro: utilhost.Distro{ID: "rhel", VersionID: "7.9"}, selinuxMode: utilhost.SELinuxEnforcing, want: StatusFail},
+		{name: "unsupported distribution", distro: utilhost.Distro{ID: "arch"}, selinuxMode: utilhost.SELinuxDisabled, want: StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := distroResult(tt.distro, tt.selinuxMode, tt.containerPolicy); got.Status != tt.want {
+				t.Errorf("distroResult() = %+v, want status %s", got, tt.want)
+			}
+		})
+	}
+}
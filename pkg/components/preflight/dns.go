@@ -0,0 +1,195 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// errNotPrivateCluster is returned when azure.targetCluster.private is set for a cluster with a public API server
+var errNotPrivateCluster = errors.New("azure.targetCluster.private is set but the target cluster is not a private cluster")
+
+// apiServer is the API server endpoint the node joins through
+type apiServer struct {
+	host           string
+	port           string
+	private        bool   // The host is the private endpoint of a private cluster
+	privateDNSZone string // "system", "none" or the resource ID of a custom private DNS zone, when known
+}
+
+// checkDNS verifies that the API server FQDN resolves from this machine and that one of its addresses accepts
+// connections. Private cluster FQDNs only resolve through the cluster's private DNS zone, so failures there
+// come with instructions for linking the zone or forwarding it from the site DNS servers.
+func (c *Checker) checkDNS(ctx context.Context) CheckResult {
+	server, err := c.apiServer(ctx)
+	if errors.Is(err, errNotPrivateCluster) {
+		return fail("%v", err)
+	}
+	if err != nil {
+		return warn("could not determine the API server FQDN: %v", err)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, server.host)
+	if err != nil {
+		if server.private {
+			return fail("private API server FQDN %s does not resolve: %v. %s", server.host, err, privateDNSFix(server))
+		}
+		return fail("API server FQDN %s does not resolve: %v", server.host, err)
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	if server.private && !containsPrivateIP(ips) {
+		return fail("private API server FQDN %s resolves to public addresses %v instead of its private endpoint. %s", server.host, ips, privateDNSFix(server))
+	}
+
+	reachable, err := dialAny(ctx, ips, server.port)
+	if err != nil {
+		if server.private {
+			return fail("private API server FQDN %s resolves to %v but none of them is reachable on port %s: %v. "+
+				"Check that the ExpressRoute or VPN connection is up and routes the cluster's virtual network to this machine", server.host, ips, server.port, err)
+		}
+		return fail("API server FQDN %s resolves to %v but none of them is reachable on port %s: %v", server.host, ips, server.port, err)
+	}
+	if server.private {
+		host, _, _ := net.SplitHostPort(reachable)
+		return pass("private API server FQDN %s resolves to %v and %s is reachable%s", server.host, ips, reachable, routeDescription(host))
+	}
+	return pass("API server FQDN %s resolves to %v and %s is reachable", server.host, ips, reachable)
+}
+
+// routeDescription describes the interface and gateway traffic to ip leaves through, which for private clusters
+// shows whether the ExpressRoute or VPN path is used, e.g. " via 10.0.0.1 dev wg0"
+func routeDescription(ip string) string {
+	output, err := utils.RunCommandWithOutput("ip", "route", "get", ip)
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(output)
+	var route []string
+	for index := 0; index+1 < len(fields); index++ {
+		if fields[index] == "via" || fields[index] == "dev" {
+			route = append(route, fields[index], fields[index+1])
+		}
+	}
+	if len(route) == 0 {
+		return ""
+	}
+	return " " + strings.Join(route, " ")
+}
+
+// apiServer returns the configured node.kubelet.serverURL, or the API server of the target cluster in Azure
+func (c *Checker) apiServer(ctx context.Context) (apiServer, error) {
+	if serverURL := c.config.Node.Kubelet.ServerURL; serverURL != "" {
+		u, err := url.Parse(serverURL)
+		if err != nil || u.Hostname() == "" {
+			return apiServer{}, fmt.Errorf("invalid node.kubelet.serverURL %s", serverURL)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "443"
+		}
+		return apiServer{host: u.Hostname(), port: port, private: c.config.IsPrivateCluster() || isPrivateLinkFQDN(u.Hostname())}, nil
+	}
+
+	if c.collectSpec == nil {
+		return apiServer{}, fmt.Errorf("node.kubelet.serverURL is not set and no target cluster is configured")
+	}
+	clusterSpec, err := c.collectSpec(ctx)
+	if err != nil {
+		return apiServer{}, err
+	}
+	if c.config.IsPrivateCluster() && !clusterSpec.PrivateCluster {
+		return apiServer{}, errNotPrivateCluster
+	}
+	host := clusterSpec.APIServerFQDN()
+	return apiServer{
+		host:           host,
+		port:           "443",
+		private:        clusterSpec.PrivateCluster || isPrivateLinkFQDN(host),
+		privateDNSZone: clusterSpec.PrivateDNSZone,
+	}, nil
+}
+
+// collectClusterSpec returns a function that reads the target cluster from Azure, or nil without a target cluster.
+// The first successful read is reused, so checks sharing the spec make a single ARM call.
+func (c *Checker) collectClusterSpec() func(ctx context.Context) (*spec.ManagedClusterSpec, error) {
+	if c.config.Azure.TargetCluster == nil {
+		return nil
+	}
+	collector := spec.NewManagedClusterSpecCollector(c.config, c.logger)
+	var collected *spec.ManagedClusterSpec
+	return func(ctx context.Context) (*spec.ManagedClusterSpec, error) {
+		if collected != nil {
+			return collected, nil
+		}
+		clusterSpec, err := collector.Collect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		collected = clusterSpec
+		return collected, nil
+	}
+}
+
+// isPrivateLinkFQDN reports whether host is in an AKS private link zone, e.g. c1-abc.privatelink.eastus.azmk8s.io
+func isPrivateLinkFQDN(host string) bool {
+	return strings.Contains(host, ".privatelink.")
+}
+
+// privateDNSZoneName returns the private DNS zone the API server FQDN lives in
+func privateDNSZoneName(server apiServer) string {
+	zone := server.privateDNSZone
+	if strings.HasPrefix(zone, "/subscriptions/") {
+		return zone[strings.LastIndex(zone, "/")+1:]
+	}
+	if _, parent, found := strings.Cut(server.host, "."); found {
+		return parent
+	}
+	return server.host
+}
+
+// privateDNSFix explains how to make the private DNS zone of the cluster resolvable from this machine
+func privateDNSFix(server apiServer) string {
+	zone := privateDNSZoneName(server)
+	if server.privateDNSZone == "none" {
+		return fmt.Sprintf("The cluster publishes its private FQDN in public DNS (privateDNSZone=none); check that this machine's DNS servers can resolve %s", zone)
+	}
+	return fmt.Sprintf("The private DNS zone %s is not linked to the DNS path of this machine. On an Azure VM, add a virtual network link from the zone to the VM's virtual network. "+
+		"Outside Azure, add a conditional forwarder for %s on the site DNS servers pointing to an Azure DNS Private Resolver inbound endpoint in a virtual network linked to the zone", zone, zone)
+}
+
+// containsPrivateIP reports whether any of the addresses is a private (RFC 1918 or ULA) address
+func containsPrivateIP(ips []net.IP) bool {
+	for _, ip := range ips {
+		if ip.IsPrivate() {
+			return true
+		}
+	}
+	return false
+}
+
+// dialAny connects to the addresses in order and returns the first one that accepts a TCP connection on port
+func dialAny(ctx context.Context, ips []net.IP, port string) (string, error) {
+	dialer := net.Dialer{Timeout: probeDialTimeout}
+	var lastErr error
+	for _, ip := range ips {
+		address := net.JoinHostPort(ip.String(), port)
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_ = conn.Close()
+		return address, nil
+	}
+	return "", lastErr
+}
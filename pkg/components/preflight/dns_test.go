@@ -0,0 +1,85 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+)
+
+func TestCheckDNS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	cfg := &config.Config{}
+	cfg.Node.Kubelet.ServerURL = "https://" + listener.Addr().String()
+	if result := (&Checker{config: cfg}).checkDNS(context.Background()); result.Status != StatusPass {
+		t.Errorf("checkDNS() = %+v, want pass", result)
+	}
+
+	// Without a server URL the FQDN comes from the cluster in Azure
+	c := &Checker{config: &config.Config{}, collectSpec: func(ctx context.Context) (*spec.ManagedClusterSpec, error) {
+		return nil, errors.New("no credential")
+	}}
+	if result := c.checkDNS(context.Background()); result.Status != StatusWarn {
+		t.Errorf("checkDNS() without cluster spec = %+v, want warn", result)
+	}
+
+	// A public cluster configured as private is a configuration error
+	private := &config.Config{}
+	private.Azure.TargetCluster = &config.TargetClusterConfig{Private: true}
+	c = &Checker{config: private, collectSpec: func(ctx context.Context) (*spec.ManagedClusterSpec, error) {
+		return &spec.ManagedClusterSpec{Fqdn: "c1-abc.hcp.eastus.azmk8s.io"}, nil
+	}}
+	if result := c.checkDNS(context.Background()); result.Status != StatusFail || !strings.Contains(result.Message, "not a private cluster") {
+		t.Errorf("checkDNS() for a public cluster configured as private = %+v, want fail", result)
+	}
+}
+
+func TestPrivateDNSFix(t *testing.T) {
+	tests := []struct {
+		name   string
+		server apiServer
+		want   string
+	}{
+		{
+			name:   "system zone",
+			server: apiServer{host: "c1-abc.privatelink.eastus.azmk8s.io", privateDNSZone: "system"},
+			want:   "privatelink.eastus.azmk8s.io",
+		},
+		{
+			name: "custom zone",
+			server: apiServer{host: "c1-abc.contoso.privatelink.eastus.azmk8s.io",
+				privateDNSZone: "/subscriptions/sub/resourceGroups/dns/providers/Microsoft.Network/privateDnsZones/contoso.privatelink.eastus.azmk8s.io"},
+			want: "contoso.privatelink.eastus.azmk8s.io",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := privateDNSZoneName(tt.server); got != tt.want {
+				t.Errorf("privateDNSZoneName() = %q, want %q", got, tt.want)
+			}
+			if fix := privateDNSFix(tt.server); !strings.Contains(fix, tt.want) || !strings.Contains(fix, "conditional forwarder") {
+				t.Errorf("privateDNSFix() = %q, want instructions for %s", fix, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsPrivateIP(t *testing.T) {
+	if containsPrivateIP([]net.IP{net.ParseIP("20.1.2.3")}) {
+		t.Error("expected a public address not to count as private")
+	}
+	if !containsPrivateIP([]net.IP{net.ParseIP("20.1.2.3"), net.ParseIP("10.224.0.4")}) {
+		t.Error("expected 10.224.0.4 to count as private")
+	}
+}
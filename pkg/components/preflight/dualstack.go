@@ -0,0 +1,65 @@
+package preflight
+
+import (
+	"context"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// checkDualStack compares network.ipFamilies with the IP families of the target cluster. A dual-stack node
+// cannot join a single-stack cluster, since kubelet would advertise an IPv6 address the cluster has no use
+// for, and each family needs a node IP the host actually has.
+func (c *Checker) checkDualStack(ctx context.Context) CheckResult {
+	nodeFamilies := c.config.Network.IPFamilies
+	if len(nodeFamilies) == 0 {
+		nodeFamilies = []string{config.IPFamilyIPv4}
+	}
+
+	if c.collectSpec == nil {
+		if c.config.IsDualStack() {
+			return c.checkDualStackNodeIPs("could not verify that the cluster is dual-stack without a target cluster; ")
+		}
+		return pass("node is single-stack %s", strings.Join(nodeFamilies, ", "))
+	}
+	clusterSpec, err := c.collectSpec(ctx)
+	if err != nil {
+		return warn("could not read the IP families of the target cluster: %v", err)
+	}
+	clusterFamilies := clusterSpec.IPFamilies
+	if len(clusterFamilies) == 0 {
+		clusterFamilies = []string{config.IPFamilyIPv4}
+	}
+
+	switch {
+	case c.config.IsDualStack() && !clusterSpec.IsDualStack():
+		return fail("network.ipFamilies is %v but the target cluster's ipFamilies is %v. Remove IPv6 from network.ipFamilies", nodeFamilies, clusterFamilies)
+	case !c.config.IsDualStack() && clusterSpec.IsDualStack():
+		return warn("the target cluster is dual-stack %v but the node is single-stack, so pods on it only get IPv4 addresses. "+
+			"Set network.ipFamilies to %v for dual-stack pods", clusterFamilies, clusterFamilies)
+	case c.config.IsDualStack():
+		var note string
+		if !strings.EqualFold(nodeFamilies[0], clusterFamilies[0]) {
+			note = "primary family " + nodeFamilies[0] + " differs from the cluster's " + clusterFamilies[0] + "; "
+		}
+		return c.checkDualStackNodeIPs(note)
+	}
+	return pass("node and cluster are single-stack %s", strings.Join(nodeFamilies, ", "))
+}
+
+// checkDualStackNodeIPs selects a node IP of each family, failing when the host lacks one. note is prepended
+// to the outcome and turns a pass into a warning.
+func (c *Checker) checkDualStackNodeIPs(note string) CheckResult {
+	nodeIPs, err := c.config.GetNodeIPs()
+	if err != nil {
+		return fail("%s%v", note, err)
+	}
+	ips := make([]string, 0, len(nodeIPs))
+	for _, nodeIP := range nodeIPs {
+		ips = append(ips, nodeIP.IP.String()+" ("+nodeIP.Interface+")")
+	}
+	if note != "" {
+		return warn("%sdual-stack node IPs %s", note, strings.Join(ips, ", "))
+	}
+	return pass("dual-stack node IPs %s", strings.Join(ips, ", "))
+}
@@ -0,0 +1,41 @@
+package preflight
+
+import (
+	"context"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+)
+
+func TestCheckDualStack(t *testing.T) {
+	singleStack := &spec.ManagedClusterSpec{IPFamilies: []string{"IPv4"}}
+	dualStack := &spec.ManagedClusterSpec{IPFamilies: []string{"IPv4", "IPv6"}}
+
+	tests := []struct {
+		name       string
+		families   []string
+		cluster    *spec.ManagedClusterSpec
+		wantStatus string
+	}{
+		{name: "single-stack without target cluster", wantStatus: StatusPass},
+		{name: "single-stack node and cluster", cluster: singleStack, wantStatus: StatusPass},
+		{name: "single-stack node on dual-stack cluster", cluster: dualStack, wantStatus: StatusWarn},
+		{name: "dual-stack node on single-stack cluster", families: []string{"IPv4", "IPv6"}, cluster: singleStack, wantStatus: StatusFail},
+		{name: "dual-stack node on cluster without network profile", families: []string{"IPv4", "IPv6"}, cluster: &spec.ManagedClusterSpec{}, wantStatus: StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Network.IPFamilies = tt.families
+			c := &Checker{config: cfg}
+			if tt.cluster != nil {
+				c.collectSpec = func(ctx context.Context) (*spec.ManagedClusterSpec, error) { return tt.cluster, nil }
+			}
+			if result := c.checkDualStack(context.Background()); result.Status != tt.wantStatus {
+				t.Errorf("checkDualStack() = %+v, want %s", result, tt.wantStatus)
+			}
+		})
+	}
+}
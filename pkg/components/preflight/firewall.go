@@ -0,0 +1,282 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// portRequirement is a port range the node must accept or send traffic on
+type portRequirement struct {
+	name     string
+	protocol string // "tcp" or "udp"
+	from, to int
+	inbound  bool
+}
+
+func (p portRequirement) String() string {
+	return p.protocol + "/" + p.ports("-")
+}
+
+// ports formats the port or port range, joining ranges with separator
+func (p portRequirement) ports(separator string) string {
+	if p.from == p.to {
+		return strconv.Itoa(p.from)
+	}
+	return fmt.Sprintf("%d%s%d", p.from, separator, p.to)
+}
+
+func (p portRequirement) direction() string {
+	if p.inbound {
+		return "inbound"
+	}
+	return "outbound"
+}
+
+// portRange is a port range a firewall rule allows, for one protocol or for all of them when protocol is empty
+type portRange struct {
+	protocol string
+	from, to int
+}
+
+// covers reports whether the allowed ranges include the whole required range
+func covers(allowed []portRange, required portRequirement) bool {
+	for _, r := range allowed {
+		if (r.protocol == "" || r.protocol == required.protocol) && r.from <= required.from && r.to >= required.to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFirewall looks for host firewall and, on Azure VMs, network security group rules that block the ports a
+// node needs. Firewall rules can match on much more than ports, so findings are warnings rather than failures.
+func (c *Checker) checkFirewall(ctx context.Context) CheckResult {
+	firewall, issues := hostFirewallIssues()
+
+	if vm := utilhost.DetectAzureVM(ctx); vm != nil {
+		nsgIssues, err := c.nsgIssues(ctx, vm)
+		if err != nil {
+			issues = append(issues, fmt.Sprintf("could not read the network security groups of Azure VM %s: %v", vm.Name, err))
+		}
+		issues = append(issues, nsgIssues...)
+	}
+
+	if len(issues) > 0 {
+		return warn("%s", strings.Join(issues, "; "))
+	}
+	if firewall == "" {
+		return pass("no active host firewall restricts the required ports")
+	}
+	return pass("%s allows the required ports", firewall)
+}
+
+// hostFirewallIssues inspects the first active host firewall among ufw, firewalld and iptables and returns its name
+// and the required ports it blocks
+func hostFirewallIssues() (string, []string) {
+	if output, err := utils.RunCommandWithOutput("ufw", "status", "verbose"); err == nil && strings.Contains(output, "Status: active") {
+		return "ufw", ufwIssues(output)
+	}
+	if output, err := utils.RunCommandWithOutput("firewall-cmd", "--state"); err == nil && strings.TrimSpace(output) == "running" {
+		ports, _ := utils.RunCommandWithOutput("firewall-cmd", "--list-ports")
+		services, _ := utils.RunCommandWithOutput("firewall-cmd", "--list-services")
+		return "firewalld", firewalldIssues(ports, services)
+	}
+	input, err := utils.RunCommandWithOutput("iptables", "-S", "INPUT")
+	if err != nil {
+		return "", nil
+	}
+	output, _ := utils.RunCommandWithOutput("iptables", "-S", "OUTPUT")
+	return "iptables", iptablesIssues(input, output)
+}
+
+// ufwIssues parses "ufw status verbose" and returns the required ports that the default deny policy blocks
+func ufwIssues(status string) []string {
+	denyIncoming, denyOutgoing := false, false
+	var allowedIn, allowedOut []portRange
+	for _, line := range strings.Split(status, "\n") {
+		if defaults, found := strings.CutPrefix(line, "Default:"); found {
+			denyIncoming = strings.Contains(defaults, "deny (incoming)") || strings.Contains(defaults, "reject (incoming)")
+			denyOutgoing = strings.Contains(defaults, "deny (outgoing)") || strings.Contains(defaults, "reject (outgoing)")
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != "ALLOW" {
+			continue
+		}
+		ranges := parseUFWPorts(fields[0])
+		switch fields[2] {
+		case "IN":
+			allowedIn = append(allowedIn, ranges...)
+		case "OUT":
+			allowedOut = append(allowedOut, ranges...)
+		}
+	}
+
+	var issues []string
+	for _, required := range requiredPorts {
+		deny, allowed := denyIncoming, allowedIn
+		if !required.inbound {
+			deny, allowed = denyOutgoing, allowedOut
+		}
+		if deny && !covers(allowed, required) {
+			issues = append(issues, fmt.Sprintf("ufw denies %s %s (%s), allow it with: ufw allow %s", required.direction(), required, required.name, ufwRuleSpec(required)))
+		}
+	}
+	return issues
+}
+
+// parseUFWPorts parses the port column of a ufw rule, e.g. 10250/tcp, 30000:32767/udp, 80,443/tcp or Anywhere
+func parseUFWPorts(spec string) []portRange {
+	if spec == "Anywhere" {
+		return []portRange{{from: 0, to: 65535}}
+	}
+	ports, protocol, _ := strings.Cut(spec, "/")
+	var ranges []portRange
+	for _, port := range strings.Split(ports, ",") {
+		if from, to, ok := parsePortRange(port, ":"); ok {
+			ranges = append(ranges, portRange{protocol: protocol, from: from, to: to})
+		}
+	}
+	return ranges
+}
+
+// ufwRuleSpec formats a requirement the way ufw rules take it
+func ufwRuleSpec(required portRequirement) string {
+	rule := required.ports(":") + "/" + required.protocol
+	if !required.inbound {
+		rule = "out " + rule
+	}
+	return rule
+}
+
+// firewalldServicePorts are the ports opened by the firewalld services for Kubernetes nodes
+var firewalldServicePorts = map[string][]portRange{
+	"kubelet":                {{protocol: "tcp", from: 10250, to: 10250}},
+	"kube-nodeport-services": {{protocol: "tcp", from: 30000, to: 32767}, {protocol: "udp", from: 30000, to: 32767}},
+}
+
+// firewalldIssues parses "firewall-cmd --list-ports" and "--list-services" of the default zone and returns the
+// required inbound ports it blocks. firewalld does not filter outbound traffic by default.
+func firewalldIssues(ports, services string) []string {
+	var allowed []portRange
+	for _, port := range strings.Fields(ports) {
+		numbers, protocol, _ := strings.Cut(port, "/")
+		if from, to, ok := parsePortRange(numbers, "-"); ok {
+			allowed = append(allowed, portRange{protocol: protocol, from: from, to: to})
+		}
+	}
+	for _, service := range strings.Fields(services) {
+		allowed = append(allowed, firewalldServicePorts[service]...)
+	}
+
+	var issues []string
+	for _, required := range requiredPorts {
+		if required.inbound && !covers(allowed, required) {
+			issues = append(issues, fmt.Sprintf("firewalld blocks inbound %s (%s), open it with: firewall-cmd --permanent --add-port=%s/%s && firewall-cmd --reload",
+				required, required.name, required.ports("-"), required.protocol))
+		}
+	}
+	return issues
+}
+
+// iptablesIssues parses "iptables -S INPUT" and "iptables -S OUTPUT" and returns the required ports a DROP or
+// REJECT policy blocks without an ACCEPT rule. Rules that also match on addresses or state are ignored, so the
+// result is an approximation.
+func iptablesIssues(input, output string) []string {
+	var issues []string
+	inputDrops, inputAllowed, loopback := parseIptablesChain(input)
+	if inputDrops && !loopback {
+		issues = append(issues, "iptables drops inbound loopback traffic, which kubelet uses to reach the containerd streaming server for exec, attach and port-forward")
+	}
+	outputDrops, outputAllowed, _ := parseIptablesChain(output)
+
+	for _, required := range requiredPorts {
+		drops, allowed := inputDrops, inputAllowed
+		if !required.inbound {
+			drops, allowed = outputDrops, outputAllowed
+		}
+		if drops && !covers(allowed, required) {
+			issues = append(issues, fmt.Sprintf("iptables drops %s %s (%s)", required.direction(), required, required.name))
+		}
+	}
+	return issues
+}
+
+// parseIptablesChain returns whether the chain drops traffic by default, the port ranges it accepts and whether
+// it accepts loopback traffic
+func parseIptablesChain(rules string) (bool, []portRange, bool) {
+	drops, loopback := false, false
+	var allowed []portRange
+	for _, line := range strings.Split(rules, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "-P" {
+			drops = fields[2] == "DROP" || fields[2] == "REJECT"
+			continue
+		}
+		if len(fields) < 2 || fields[0] != "-A" {
+			continue
+		}
+
+		var protocol, ports, target, iface string
+		restricted := false
+		for index := 2; index+1 < len(fields); index++ {
+			value := fields[index+1]
+			switch fields[index] {
+			case "-p":
+				protocol = value
+			case "--dport", "--dports":
+				ports = value
+			case "-j":
+				target = value
+			case "-i", "-o":
+				iface = value
+				restricted = true
+			case "-s", "-d", "--ctstate", "--state":
+				restricted = true
+			}
+		}
+
+		switch {
+		case target == "DROP" || target == "REJECT":
+			// A trailing catch-all DROP works like a DROP policy
+			if len(fields) == 4 {
+				drops = true
+			}
+		case target != "ACCEPT":
+		case iface == "lo":
+			loopback = true
+		case restricted:
+		case ports == "":
+			allowed = append(allowed, portRange{protocol: protocol, from: 0, to: 65535})
+		default:
+			for _, port := range strings.Split(ports, ",") {
+				if from, to, ok := parsePortRange(port, ":"); ok {
+					allowed = append(allowed, portRange{protocol: protocol, from: from, to: to})
+				}
+			}
+		}
+	}
+	return drops, allowed, loopback
+}
+
+// parsePortRange parses a single port or a range with the given separator, e.g. 30000:32767
+func parsePortRange(value, separator string) (int, int, bool) {
+	first, last, isRange := strings.Cut(value, separator)
+	from, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !isRange {
+		return from, from, true
+	}
+	to, err := strconv.Atoi(last)
+	if err != nil {
+		return 0, 0, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,88 @@
+package preflight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+)
+
+func TestUFWIssues(t *testing.T) {
+	status := `Status: active
+Logging: on (low)
+Default: deny (incoming), allow (outgoing), disabled (routed)
+New profiles: skip
+
+To                         Action      From
+--                         ------      ----
+22/tcp                     ALLOW IN    Anywhere
+10250/tcp                  ALLOW IN    Anywhere
+30000:32767/tcp            ALLOW IN    Anywhere
+`
+	issues := ufwIssues(status)
+	if len(issues) != 1 || !strings.Contains(issues[0], "udp/30000-32767") || !strings.Contains(issues[0], "ufw allow 30000:32767/udp") {
+		t.Errorf("ufwIssues() = %v, want only the UDP NodePort range", issues)
+	}
+}
+
+func TestFirewalldIssues(t *testing.T) {
+	if issues := firewalldIssues("30000-32767/tcp 30000-32767/udp", "ssh kubelet"); len(issues) != 0 {
+		t.Errorf("firewalldIssues() = %v, want none", issues)
+	}
+	issues := firewalldIssues("", "ssh")
+	if len(issues) != 3 || !strings.Contains(issues[0], "--add-port=10250/tcp") {
+		t.Errorf("firewalldIssues() = %v, want the kubelet and NodePort ports", issues)
+	}
+}
+
+func TestIptablesIssues(t *testing.T) {
+	input := `-P INPUT DROP
+-A INPUT -i lo -j ACCEPT
+-A INPUT -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 10250 -j ACCEPT
+-A INPUT -p tcp -m multiport --dports 22,30000:32767 -j ACCEPT
+-A INPUT -p udp -m multiport --dports 30000:32767 -j ACCEPT
+`
+	if issues := iptablesIssues(input, "-P OUTPUT ACCEPT\n"); len(issues) != 0 {
+		t.Errorf("iptablesIssues() = %v, want none", issues)
+	}
+
+	issues := iptablesIssues("-P INPUT ACCEPT\n-A INPUT -p tcp --dport 22 -j ACCEPT\n-A INPUT -j DROP\n", "-P OUTPUT ACCEPT\n")
+	if len(issues) != 4 || !strings.Contains(issues[0], "loopback") {
+		t.Errorf("iptablesIssues() = %v, want loopback, kubelet and NodePort issues", issues)
+	}
+}
+
+func TestEvaluateNSG(t *testing.T) {
+	rule := func(name string, priority int32, access armnetwork.SecurityRuleAccess, source, ports string) *armnetwork.EffectiveNetworkSecurityRule {
+		return &armnetwork.EffectiveNetworkSecurityRule{
+			Name:                 &name,
+			Priority:             &priority,
+			Access:               &access,
+			Direction:            ptrTo(armnetwork.SecurityRuleDirectionInbound),
+			Protocol:             ptrTo(armnetwork.EffectiveSecurityRuleProtocolAll),
+			SourceAddressPrefix:  &source,
+			DestinationPortRange: &ports,
+		}
+	}
+	group := &armnetwork.EffectiveNetworkSecurityGroup{
+		NetworkSecurityGroup: &armnetwork.SubResource{ID: ptrTo("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/vm1-nsg")},
+		EffectiveSecurityRules: []*armnetwork.EffectiveNetworkSecurityRule{
+			rule("DenyAllInBound", 65500, armnetwork.SecurityRuleAccessDeny, "0.0.0.0/0", "0-65535"),
+			rule("AllowVnetInBound", 65000, armnetwork.SecurityRuleAccessAllow, "VirtualNetwork", "0-65535"),
+			rule("kubelet", 100, armnetwork.SecurityRuleAccessAllow, "*", "10250-10250"),
+		},
+	}
+
+	issues := evaluateNSG("vm1-nic", group)
+	if len(issues) != 2 {
+		t.Fatalf("evaluateNSG() = %v, want the two NodePort ranges", issues)
+	}
+	for _, issue := range issues {
+		if !strings.Contains(issue, "NSG vm1-nsg on vm1-nic denies inbound") || !strings.Contains(issue, "30000-32767") || !strings.Contains(issue, "DenyAllInBound") {
+			t.Errorf("unexpected issue %q", issue)
+		}
+	}
+}
+
+func ptrTo[T any](v T) *T { return &v }
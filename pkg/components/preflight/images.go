@@ -0,0 +1,18 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// checkImages reports the final reference of every built-in image before bootstrap pulls any of them, so
+// sovereign-cloud and air-gapped users can confirm that images.registry redirected all of them to their mirror
+func (c *Checker) checkImages(ctx context.Context) CheckResult {
+	images := c.config.ResolveImages()
+	references := make([]string, 0, len(images))
+	for _, image := range images {
+		references = append(references, fmt.Sprintf("%s=%s (%s)", image.Name, image.Reference, image.Source))
+	}
+	return pass("built-in images resolve to %s", strings.Join(references, ", "))
+}
@@ -0,0 +1,130 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// checkLatency measures the round-trip time to the API server and the image registry and the download throughput
+// of the node, since hybrid nodes behind slow VPNs suffer kubelet watch timeouts and flaky image pulls rather than
+// failing outright
+func (c *Checker) checkLatency(ctx context.Context) CheckResult {
+	thresholds := c.config.Preflight.Latency
+
+	targets := []struct{ name, address string }{{name: "image registry", address: c.config.ImageRegistryAddress()}}
+	if server, err := c.apiServer(ctx); err == nil {
+		targets = append([]struct{ name, address string }{{name: "API server", address: net.JoinHostPort(server.host, server.port)}}, targets...)
+	}
+
+	var measurements, risks []string
+	for _, target := range targets {
+		rtt, err := measureRTT(ctx, target.address, rttSamples)
+		if err != nil {
+			risks = append(risks, fmt.Sprintf("could not measure round-trip time to %s %s: %v", target.name, target.address, err))
+			continue
+		}
+		measurements = append(measurements, fmt.Sprintf("%s RTT %v", target.name, rtt.Round(time.Millisecond)))
+		if rtt > thresholds.MaxRTT {
+			risks = append(risks, fmt.Sprintf("round-trip time to %s %s is %v, above %v; expect slow watches and lease renewals",
+				target.name, target.address, rtt.Round(time.Millisecond), thresholds.MaxRTT))
+		}
+	}
+
+	if throughputURL := c.throughputURL(); throughputURL != "" {
+		mbps, err := measureThroughput(ctx, throughputURL, throughputSampleBytes)
+		switch {
+		case err != nil:
+			risks = append(risks, fmt.Sprintf("could not measure throughput from %s: %v", throughputURL, err))
+		case mbps < float64(thresholds.MinThroughputMbps):
+			risks = append(risks, fmt.Sprintf("download throughput from %s is %.1f Mbit/s, below %d Mbit/s; expect large image pulls to time out",
+				throughputURL, mbps, thresholds.MinThroughputMbps))
+		default:
+			measurements = append(measurements, fmt.Sprintf("throughput %.1f Mbit/s", mbps))
+		}
+	}
+
+	if len(risks) > 0 {
+		return warn("%s", strings.Join(risks, "; "))
+	}
+	return pass("%s", strings.Join(measurements, ", "))
+}
+
+// throughputURL returns the URL downloaded to measure throughput, by default the Kubernetes node binaries archive
+// bootstrap downloads anyway
+func (c *Checker) throughputURL() string {
+	if c.config.Preflight.Latency.ThroughputURL != "" {
+		return c.config.Preflight.Latency.ThroughputURL
+	}
+	version := c.config.GetKubernetesVersion()
+	if version == "" {
+		return ""
+	}
+	template := defaultKubernetesURLTemplate
+	if c.config.Kubernetes.URLTemplate != "" {
+		template = c.config.Kubernetes.URLTemplate
+	}
+	return fmt.Sprintf(template, version, utilhost.GetArch())
+}
+
+// measureRTT returns the median time of samples TCP handshakes with address, which each take one round trip
+func measureRTT(ctx context.Context, address string, samples int) (time.Duration, error) {
+	dialer := net.Dialer{Timeout: probeDialTimeout}
+	durations := make([]time.Duration, 0, samples)
+	for range samples {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return 0, err
+		}
+		durations = append(durations, time.Since(start))
+		_ = conn.Close()
+	}
+	slices.Sort(durations)
+	return durations[len(durations)/2], nil
+}
+
+// measureThroughput downloads up to limit bytes of rawURL through the configured proxy and returns the throughput
+// in Mbit/s. Time to the first byte is excluded, so the result does not depend on the round-trip time.
+func measureThroughput(ctx context.Context, rawURL string, limit int64) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, throughputTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", limit-1))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("download returned %s", resp.Status)
+	}
+
+	// Start the clock once the first byte arrived
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(resp.Body, first); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	read, err := io.Copy(io.Discard, io.LimitReader(resp.Body, limit-1))
+	elapsed := time.Since(start)
+	// A download cut short by the timeout still gives a usable estimate
+	if err != nil && ctx.Err() == nil {
+		return 0, err
+	}
+	if read == 0 || elapsed <= 0 {
+		return 0, fmt.Errorf("downloaded too little data to measure throughput")
+	}
+	return float64(read*8) / elapsed.Seconds() / 1e6, nil
+}
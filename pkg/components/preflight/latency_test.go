@@ -0,0 +1,52 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMeasureRTT(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	rtt, err := measureRTT(context.Background(), listener.Addr().String(), 3)
+	if err != nil {
+		t.Fatalf("measureRTT() error = %v", err)
+	}
+	if rtt <= 0 || rtt > time.Second {
+		t.Errorf("measureRTT() = %v, want a small positive duration on loopback", rtt)
+	}
+}
+
+func TestMeasureThroughput(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 1<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ServeContent honors the Range header like the artifact CDNs do
+		http.ServeContent(w, r, "archive.tar.gz", time.Time{}, bytes.NewReader(payload))
+	}))
+	defer server.Close()
+
+	mbps, err := measureThroughput(context.Background(), server.URL, 256<<10)
+	if err != nil {
+		t.Fatalf("measureThroughput() error = %v", err)
+	}
+	if mbps <= 0 {
+		t.Errorf("measureThroughput() = %f, want a positive throughput", mbps)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	if _, err := measureThroughput(context.Background(), missing.URL, 256<<10); err == nil {
+		t.Error("expected an error for a missing download")
+	}
+}
@@ -0,0 +1,137 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/auth"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// anySource are the prefixes of NSG rules matching traffic from or to anywhere outside the virtual network
+var anySource = []string{"*", "0.0.0.0/0", "Internet"}
+
+// nsgIssues reads the effective network security rules of the VM's network interfaces, which combine the NSGs of
+// the interfaces and their subnets, and returns the required ports they deny
+func (c *Checker) nsgIssues(ctx context.Context, vm *utilhost.AzureVMMetadata) ([]string, error) {
+	cred, err := auth.NewAuthProvider().UserCredential(c.config)
+	if err != nil {
+		return nil, err
+	}
+	client, err := armnetwork.NewInterfacesClient(vm.SubscriptionID, cred, c.config.GetARMClientOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	var nics []string
+	pager := client.NewListPager(vm.ResourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+		}
+		for _, nic := range page.Value {
+			if nic.Name != nil && nic.Properties != nil && nic.Properties.VirtualMachine != nil &&
+				nic.Properties.VirtualMachine.ID != nil && strings.EqualFold(*nic.Properties.VirtualMachine.ID, vm.ResourceID) {
+				nics = append(nics, *nic.Name)
+			}
+		}
+	}
+
+	var issues []string
+	for _, nic := range nics {
+		poller, err := client.BeginListEffectiveNetworkSecurityGroups(ctx, vm.ResourceGroup, nic, nil)
+		if err != nil {
+			return issues, fmt.Errorf("failed to get effective security rules of %s: %w", nic, err)
+		}
+		resp, err := poller.PollUntilDone(ctx, nil)
+		if err != nil {
+			return issues, fmt.Errorf("failed to get effective security rules of %s: %w", nic, err)
+		}
+		for _, group := range resp.Value {
+			issues = append(issues, evaluateNSG(nic, group)...)
+		}
+	}
+	return issues, nil
+}
+
+// evaluateNSG returns the required ports the first matching rule of an effective NSG denies for traffic from or
+// to outside the virtual network, which is where hybrid clusters reach the node from
+func evaluateNSG(nic string, group *armnetwork.EffectiveNetworkSecurityGroup) []string {
+	if group == nil {
+		return nil
+	}
+	name := "NSG"
+	if group.NetworkSecurityGroup != nil && group.NetworkSecurityGroup.ID != nil {
+		name = "NSG " + path.Base(*group.NetworkSecurityGroup.ID)
+	}
+
+	rules := slices.Clone(group.EffectiveSecurityRules)
+	slices.SortFunc(rules, func(a, b *armnetwork.EffectiveNetworkSecurityRule) int {
+		return int(value(a.Priority) - value(b.Priority))
+	})
+
+	var issues []string
+	for _, required := range requiredPorts {
+		for _, rule := range rules {
+			if !nsgRuleMatches(rule, required) {
+				continue
+			}
+			if value(rule.Access) == armnetwork.SecurityRuleAccessDeny {
+				issues = append(issues, fmt.Sprintf("%s on %s denies %s %s (%s) by rule %s",
+					name, nic, required.direction(), required, required.name, value(rule.Name)))
+			}
+			break
+		}
+	}
+	return issues
+}
+
+// nsgRuleMatches reports whether an effective rule applies to the first port of a requirement for traffic from
+// (inbound) or to (outbound) anywhere
+func nsgRuleMatches(rule *armnetwork.EffectiveNetworkSecurityRule, required portRequirement) bool {
+	if rule == nil {
+		return false
+	}
+	direction := armnetwork.SecurityRuleDirectionOutbound
+	if required.inbound {
+		direction = armnetwork.SecurityRuleDirectionInbound
+	}
+	if value(rule.Direction) != direction {
+		return false
+	}
+	if protocol := value(rule.Protocol); protocol != armnetwork.EffectiveSecurityRuleProtocolAll && !strings.EqualFold(string(protocol), required.protocol) {
+		return false
+	}
+
+	peer, peers := rule.SourceAddressPrefix, rule.SourceAddressPrefixes
+	if !required.inbound {
+		peer, peers = rule.DestinationAddressPrefix, rule.DestinationAddressPrefixes
+	}
+	if !slices.Contains(anySource, value(peer)) && !slices.ContainsFunc(peers, func(p *string) bool { return slices.Contains(anySource, value(p)) }) {
+		return false
+	}
+
+	ports := append([]*string{rule.DestinationPortRange}, rule.DestinationPortRanges...)
+	return slices.ContainsFunc(ports, func(p *string) bool {
+		if value(p) == "*" {
+			return true
+		}
+		from, to, ok := parsePortRange(value(p), "-")
+		return ok && from <= required.from && required.from <= to
+	})
+}
+
+// value dereferences an optional field of an Azure SDK model
+func value[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
@@ -0,0 +1,129 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+)
+
+// ErrChecksFailed is returned when a preflight check fails; bootstrap stops before it changes the host
+var ErrChecksFailed = errors.New("preflight checks failed")
+
+// CheckResult is the outcome of a single preflight check
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+
+	Endpoints []EndpointResult `json:"endpoints,omitempty"` // Per-endpoint outcome of the connectivity check
+}
+
+// check is a single named preflight check
+type check struct {
+	name string
+	run  func(ctx context.Context) CheckResult
+}
+
+// Checker runs host and network preflight checks before the node is bootstrapped
+type Checker struct {
+	config *config.Config
+	logger *logrus.Logger
+
+	// collectSpec reads the target cluster from Azure, nil when no target cluster is configured
+	collectSpec func(ctx context.Context) (*spec.ManagedClusterSpec, error)
+}
+
+// NewChecker creates a new preflight Checker
+func NewChecker(logger *logrus.Logger) *Checker {
+	c := &Checker{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+	c.collectSpec = c.collectClusterSpec()
+	return c
+}
+
+// GetName returns the step name
+func (c *Checker) GetName() string {
+	return "PreflightChecker"
+}
+
+// IsCompleted always returns false so the checks run on every bootstrap.
+// Checker does not describe node state, so it is not a StepExecutor and verify skips it.
+func (c *Checker) IsCompleted(ctx context.Context) bool {
+	return false
+}
+
+// Execute runs all preflight checks, logging warnings and failing if any check failed
+func (c *Checker) Execute(ctx context.Context) error {
+	var failed []string
+	for _, result := range c.Run(ctx) {
+		switch result.Status {
+		case StatusFail:
+			for _, ep := range result.Endpoints {
+				if !ep.Reachable {
+					c.logger.Errorf("❌ Preflight %s: %s %s unreachable: %s", result.Name, ep.Name, ep.URL, ep.Error)
+				}
+			}
+			c.logger.Errorf("❌ Preflight %s: %s", result.Name, result.Message)
+			failed = append(failed, result.Name)
+		case StatusWarn:
+			c.logger.Warnf("⚠️  Preflight %s: %s", result.Name, result.Message)
+		default:
+			c.logger.Infof("✅ Preflight %s: %s", result.Name, result.Message)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %v (skip with preflight.skip)", ErrChecksFailed, failed)
+	}
+	return nil
+}
+
+// Run executes every check that is not skipped in the configuration and returns the results
+func (c *Checker) Run(ctx context.Context) []CheckResult {
+	var results []CheckResult
+	for _, chk := range c.checks() {
+		if slices.Contains(c.config.Preflight.Skip, chk.name) {
+			c.logger.Debugf("Skipping preflight check %s", chk.name)
+			continue
+		}
+		result := chk.run(ctx)
+		result.Name = chk.name
+		results = append(results, result)
+	}
+	return results
+}
+
+// checks returns the preflight checks in execution order
+func (c *Checker) checks() []check {
+	return []check{
+		{name: "distro", run: c.checkDistro},
+		{name: "images", run: c.checkImages},
+		{name: "dns", run: c.checkDNS},
+		{name: "dualstack", run: c.checkDualStack},
+		{name: "agentpool", run: c.checkAgentPool},
+		{name: "connectivity", run: c.checkConnectivity},
+		{name: "latency", run: c.checkLatency},
+		{name: "firewall", run: c.checkFirewall},
+		{name: "snat", run: c.checkSNAT},
+		{name: "swap", run: c.checkSwap},
+	}
+}
+
+func pass(format string, args ...any) CheckResult {
+	return CheckResult{Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(format string, args ...any) CheckResult {
+	return CheckResult{Status: StatusWarn, Message: fmt.Sprintf(format, args...)}
+}
+
+func fail(format string, args ...any) CheckResult {
+	return CheckResult{Status: StatusFail, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,101 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// checkSNAT estimates the outbound connections the node needs at full pod density and compares them with
+// the port capacity of the site NAT device and the local conntrack table. It then opens a burst of concurrent
+// connections through the NAT, because an exhausted NAT shows up as some connections timing out while others
+// succeed, which after join surfaces as random image pull and API server timeouts.
+func (c *Checker) checkSNAT(ctx context.Context) CheckResult {
+	snat := c.config.Preflight.SNAT
+	required := estimateOutboundConnections(c.config.Node.MaxPods, snat.EndpointsPerPod)
+
+	var risks []string
+	if snat.AvailablePorts > 0 && float64(required) > float64(snat.AvailablePorts)*snatCapacityWarnRatio {
+		risks = append(risks, fmt.Sprintf("estimated %d concurrent outbound connections (%d pods x %d endpoints + %d node) exceed %d%% of the %d SNAT ports available to this node",
+			required, c.config.Node.MaxPods, snat.EndpointsPerPod, nodeBaselineConnections, int(snatCapacityWarnRatio*100), snat.AvailablePorts))
+	}
+
+	if conntrackMax, err := readConntrackMax(); err == nil && conntrackMax < required {
+		risks = append(risks, fmt.Sprintf("nf_conntrack_max is %d, below the estimated %d concurrent connections", conntrackMax, required))
+	}
+
+	failed, err := probeConcurrentConnections(ctx, snat.ProbeTarget, snat.ProbeConnections)
+	switch {
+	case err != nil:
+		risks = append(risks, fmt.Sprintf("could not probe outbound connections: %v", err))
+	case failed > 0:
+		risks = append(risks, fmt.Sprintf("%d of %d concurrent connections to %s failed while others succeeded, a typical symptom of NAT port exhaustion",
+			failed, snat.ProbeConnections, snat.ProbeTarget))
+	}
+
+	if len(risks) > 0 {
+		return warn("SNAT exhaustion risk: %s", strings.Join(risks, "; "))
+	}
+	if snat.AvailablePorts == 0 {
+		return pass("%d concurrent connections to %s succeeded; estimated demand is %d connections, set preflight.snat.availablePorts to compare with the NAT capacity",
+			snat.ProbeConnections, snat.ProbeTarget, required)
+	}
+	return pass("estimated demand of %d connections fits the %d available SNAT ports", required, snat.AvailablePorts)
+}
+
+// estimateOutboundConnections returns the concurrent outbound connections expected at full pod density
+func estimateOutboundConnections(maxPods, endpointsPerPod int) int {
+	return maxPods*endpointsPerPod + nodeBaselineConnections
+}
+
+// readConntrackMax returns the size of the kernel connection tracking table
+func readConntrackMax() (int, error) {
+	data, err := os.ReadFile(conntrackMaxPath)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// probeConcurrentConnections opens count TCP connections to target at the same time and returns how many
+// failed. It returns an error when every connection fails, since that is a reachability problem rather than
+// exhaustion.
+func probeConcurrentConnections(ctx context.Context, target string, count int) (int, error) {
+	dialer := net.Dialer{Timeout: probeDialTimeout}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		conns   []net.Conn
+		lastErr error
+	)
+	for range count {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := dialer.DialContext(ctx, "tcp", target)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lastErr = err
+				return
+			}
+			conns = append(conns, conn)
+		}()
+	}
+	// Keep every connection open until all attempts finished so they occupy NAT ports concurrently
+	wg.Wait()
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+
+	failed := count - len(conns)
+	if failed == count && count > 0 {
+		return failed, fmt.Errorf("no connection to %s succeeded: %w", target, lastErr)
+	}
+	return failed, nil
+}
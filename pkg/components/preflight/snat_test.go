@@ -0,0 +1,46 @@
+package preflight
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestProbeConcurrentConnections(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	go func() {
+		// The kernel completes the handshake before Accept, so connections only need to be drained
+		for {
+			if _, err := listener.Accept(); err != nil {
+				return
+			}
+		}
+	}()
+
+	failed, err := probeConcurrentConnections(context.Background(), listener.Addr().String(), 16)
+	if err != nil {
+		t.Fatalf("probeConcurrentConnections() error = %v", err)
+	}
+	if failed != 0 {
+		t.Errorf("expected no failed connections, got %d", failed)
+	}
+
+	// Closing the listener makes every connection fail, which is a reachability problem
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	if _, err := probeConcurrentConnections(context.Background(), addr, 4); err == nil {
+		t.Error("expected an error when no connection succeeds")
+	}
+}
+
+func TestEstimateOutboundConnections(t *testing.T) {
+	if got := estimateOutboundConnections(110, 8); got != 110*8+nodeBaselineConnections {
+		t.Errorf("estimateOutboundConnections() = %d", got)
+	}
+}
@@ -0,0 +1,37 @@
+package preflight
+
+import (
+	"context"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// checkSwap checks the kernel requirements of node.kubelet.swapBehavior: kubelet limits the swap of pods through
+// cgroup v2, which needs the kernel to account swap per cgroup
+func (c *Checker) checkSwap(ctx context.Context) CheckResult {
+	behavior := c.config.Node.Kubelet.SwapBehavior
+	if behavior == "" {
+		return pass("swap is turned off for kubelet")
+	}
+	devices, err := utilhost.SwapDevices()
+	return swapResult(behavior, utilhost.CgroupV2(), utilhost.SwapAccounting(), devices, err)
+}
+
+// swapResult evaluates the host's cgroup hierarchy, swap accounting and swap devices for a swap behavior
+func swapResult(behavior string, cgroupV2, swapAccounting bool, devices []string, devicesErr error) CheckResult {
+	switch {
+	case !cgroupV2:
+		return fail("node.kubelet.swapBehavior %s requires cgroup v2, but the host runs cgroup v1. "+
+			"Boot with systemd.unified_cgroup_hierarchy=1 or remove swapBehavior", behavior)
+	case !swapAccounting:
+		return fail("node.kubelet.swapBehavior %s requires swap accounting, which the kernel lacks (CONFIG_MEMCG_SWAP, "+
+			"or swapaccount=0 on the kernel command line)", behavior)
+	case devicesErr != nil:
+		return warn("could not list the swap devices: %v", devicesErr)
+	case len(devices) == 0:
+		return warn("node.kubelet.swapBehavior is %s but no swap device is active. Add one to /etc/fstab, "+
+			"or kubelet runs without swap", behavior)
+	}
+	return pass("swap behavior %s with swap on %s", behavior, strings.Join(devices, ", "))
+}
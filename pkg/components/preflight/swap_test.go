@@ -0,0 +1,31 @@
+package preflight
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSwapResult(t *testing.T) {
+	tests := []struct {
+		name           string
+		cgroupV2       bool
+		swapAccounting bool
+		devices        []string
+		devicesErr     error
+		want           string
+	}{
+		{name: "ready", cgroupV2: true, swapAccounting: true, devices: []string{"/swap.img"}, want: StatusPass},
+		{name: "cgroup v1", swapAccounting: true, devices: []string{"/swap.img"}, want: StatusFail},
+		{name: "no swap accounting", cgroupV2: true, devices: []string{"/swap.img"}, want: StatusFail},
+		{name: "no swap device", cgroupV2: true, swapAccounting: true, want: StatusWarn},
+		{name: "unreadable swaps", cgroupV2: true, swapAccounting: true, devicesErr: errors.New("permission denied"), want: StatusWarn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := swapResult("LimitedSwap", tt.cgroupV2, tt.swapAccounting, tt.devices, tt.devicesErr); got.Status != tt.want {
+				t.Errorf("swapResult() = %+v, want status %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package rdma
+
+const (
+	// modulesLoadFile loads the verbs and connection manager modules at boot
+	modulesLoadFile = "/etc/modules-load.d/aks-flex-node-rdma.conf"
+
+	// modprobeConfigFile sets the network namespace mode of ib_core, which only applies when it loads
+	modprobeConfigFile = "/etc/modprobe.d/aks-flex-node-rdma.conf"
+
+	// udevRulesFile opens the verbs and connection manager devices to the non-root users of pods
+	udevRulesFile = "/etc/udev/rules.d/90-aks-flex-node-rdma.rules"
+
+	// memlockDropInName lifts the locked memory limit of the container runtime, which containers inherit and
+	// RDMA needs to register memory with the HCA
+	memlockDropInName = "20-rdma-memlock.conf"
+)
+
+// sysClassInfiniband holds the RDMA devices, a variable so tests can point it at a temporary directory
+var sysClassInfiniband = "/sys/class/infiniband"
+
+// defaultModules are the user-space verbs, connection manager and management datagram modules. The HCA driver,
+// such as mlx5_ib, is loaded by udev with the NIC.
+var defaultModules = []string{"ib_uverbs", "rdma_ucm", "ib_umad"}
+
+// packages are the rdma-core packages, each with its name on RHEL when it differs and a binary telling whether
+// it is installed
+var packages = []struct {
+	name     string
+	rhelName string
+	binary   string
+}{
+	{name: "rdma-core", binary: "rdma-ndd"},
+	{name: "ibverbs-utils", rhelName: "libibverbs-utils", binary: "ibv_devinfo"},
+	{name: "infiniband-diags", binary: "ibstat"},
+}
+
+const udevRules = `# Generated by aks-flex-node from the rdma configuration
+SUBSYSTEM=="infiniband_verbs", MODE="0666"
+KERNEL=="rdma_cm", MODE="0666"
+`
+
+const memlockDropIn = `[Service]
+LimitMEMLOCK=infinity
+`
@@ -0,0 +1,312 @@
+package rdma
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer prepares the host side of RDMA workloads: the rdma-core packages, the kernel modules, device
+// permissions, the locked memory limit of containers and the RDMA network namespace mode. It fails unless the
+// RDMA devices have an active link. The RDMA device plugin DaemonSet stays in the cluster.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new RDMA Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "RDMAInstaller"
+}
+
+// Validate checks that the NIC driver exposes the configured RDMA devices
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.config.RDMA.Enabled {
+		return nil
+	}
+	devices, err := rdmaDevices()
+	if err != nil || len(devices) == 0 {
+		return fmt.Errorf("no RDMA devices found in %s; install the NIC driver, e.g. mlx5_ib", sysClassInfiniband)
+	}
+	for _, device := range i.config.RDMA.Devices {
+		if !slices.Contains(devices, device) {
+			return fmt.Errorf("RDMA device %s not found; the node has %s", device, strings.Join(devices, ", "))
+		}
+	}
+	return nil
+}
+
+// Execute prepares the host for RDMA, or removes the preparation when rdma.enabled is turned off
+func (i *Installer) Execute(ctx context.Context) error {
+	rdma := &i.config.RDMA
+	if !rdma.Enabled {
+		if isRDMAConfigured() {
+			i.logger.Info("rdma.enabled is off, removing the RDMA configuration")
+			removeRDMA(i.logger)
+		}
+		return nil
+	}
+
+	distro, _ := utilhost.DetectDistro()
+	for _, pkg := range packages {
+		if utils.BinaryExists(pkg.binary) {
+			continue
+		}
+		name := pkg.name
+		if distro.Family() == utilhost.DistroFamilyRHEL && pkg.rhelName != "" {
+			name = pkg.rhelName
+		}
+		i.logger.Infof("Installing %s...", name)
+		if err := utilhost.InstallPackages(name); err != nil {
+			return fmt.Errorf("failed to install %s: %w", name, err)
+		}
+	}
+
+	if err := i.configureModules(); err != nil {
+		return err
+	}
+	if err := i.configureNetNSMode(); err != nil {
+		return err
+	}
+
+	if err := utilio.WriteFile(udevRulesFile, []byte(udevRules), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", udevRulesFile, err)
+	}
+	if err := utils.RunSystemCommand("udevadm", "control", "--reload"); err != nil {
+		return fmt.Errorf("failed to reload the udev rules: %w", err)
+	}
+	if err := utils.RunSystemCommand("udevadm", "trigger", "--subsystem-match=infiniband_verbs", "--subsystem-match=infiniband_cm"); err != nil {
+		return fmt.Errorf("failed to apply the udev rules: %w", err)
+	}
+
+	// The services step restarts the runtime, which picks the limit up
+	if err := utilio.WriteFile(i.memlockDropInPath(), []byte(memlockDropIn), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", i.memlockDropInPath(), err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+
+	if err := i.checkLinks(); err != nil {
+		return err
+	}
+	i.logger.Info("RDMA prepared")
+	return nil
+}
+
+// IsCompleted reports whether the host is prepared and the links are up, or clean when disabled
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	rdma := &i.config.RDMA
+	if !rdma.Enabled {
+		return !isRDMAConfigured()
+	}
+	for _, pkg := range packages {
+		if !utils.BinaryExists(pkg.binary) {
+			return false
+		}
+	}
+	for _, module := range i.modules() {
+		// /sys/module names use underscores for dashes
+		if !utils.DirectoryExists(filepath.Join("/sys/module", strings.ReplaceAll(module, "-", "_"))) {
+			return false
+		}
+	}
+	if !utils.FileHasContent(modulesLoadFile, modulesLoad(i.modules())) || !utils.FileHasContent(udevRulesFile, udevRules) ||
+		!utils.FileHasContent(i.memlockDropInPath(), memlockDropIn) {
+		return false
+	}
+	if (rdma.NetNSMode == config.RDMANetNSExclusive) != utils.FileExists(modprobeConfigFile) {
+		return false
+	}
+	if mode, err := currentNetNSMode(); err != nil || mode != rdma.NetNSMode {
+		return false
+	}
+	return i.checkLinks() == nil
+}
+
+// configureModules loads the RDMA modules now and at boot
+func (i *Installer) configureModules() error {
+	modules := i.modules()
+	if err := utilio.WriteFile(modulesLoadFile, []byte(modulesLoad(modules)), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", modulesLoadFile, err)
+	}
+	for _, module := range modules {
+		if err := utilhost.LoadKernelModule(module); err != nil {
+			return fmt.Errorf("failed to load %s: %w", module, err)
+		}
+	}
+	return nil
+}
+
+// configureNetNSMode sets the RDMA network namespace mode at boot through ib_core, and now if no RDMA device is
+// in use
+func (i *Installer) configureNetNSMode() error {
+	mode := i.config.RDMA.NetNSMode
+	if mode == config.RDMANetNSExclusive {
+		if err := utilio.WriteFile(modprobeConfigFile, []byte("options ib_core netns_mode=0\n"), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", modprobeConfigFile, err)
+		}
+	} else if err := removeFile(modprobeConfigFile); err != nil {
+		return err
+	}
+
+	if current, err := currentNetNSMode(); err == nil && current == mode {
+		return nil
+	}
+	if err := utils.RunSystemCommand("rdma", "system", "set", "netns", mode); err != nil {
+		return fmt.Errorf("failed to set the RDMA network namespace mode to %s; it applies on the next boot: %w", mode, err)
+	}
+	return nil
+}
+
+// checkLinks fails unless every device of rdma.devices, or any device when unset, has an active port
+func (i *Installer) checkLinks() error {
+	devices := i.config.RDMA.Devices
+	if len(devices) == 0 {
+		all, err := rdmaDevices()
+		if err != nil {
+			return err
+		}
+		var states []string
+		for _, device := range all {
+			ports := portStates(device)
+			if hasActivePort(ports) {
+				return nil
+			}
+			states = append(states, device+": "+strings.Join(ports, ", "))
+		}
+		return fmt.Errorf("no RDMA device has an active port (%s); check the cables and the subnet manager", strings.Join(states, "; "))
+	}
+	for _, device := range devices {
+		if ports := portStates(device); !hasActivePort(ports) {
+			return fmt.Errorf("RDMA device %s has no active port (%s); check the cable and the subnet manager", device, strings.Join(ports, ", "))
+		}
+	}
+	return nil
+}
+
+// modules returns the kernel modules to load: the defaults and rdma.modules
+func (i *Installer) modules() []string {
+	modules := slices.Clone(defaultModules)
+	for _, module := range i.config.RDMA.Modules {
+		if !slices.Contains(modules, module) {
+			modules = append(modules, module)
+		}
+	}
+	return modules
+}
+
+// memlockDropInPath returns the locked memory drop-in of the configured container runtime
+func (i *Installer) memlockDropInPath() string {
+	return memlockDropInPathOf(i.config.ContainerRuntimeService())
+}
+
+// memlockDropInPathOf returns the locked memory drop-in of a service
+func memlockDropInPathOf(service string) string {
+	return filepath.Join("/etc/systemd/system", service+".service.d", memlockDropInName)
+}
+
+// modulesLoad renders a modules-load.d file
+func modulesLoad(modules []string) string {
+	return strings.Join(modules, "\n") + "\n"
+}
+
+// rdmaDevices lists the RDMA devices, e.g. mlx5_0
+func rdmaDevices() ([]string, error) {
+	entries, err := os.ReadDir(sysClassInfiniband)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the RDMA devices: %w", err)
+	}
+	var devices []string
+	for _, entry := range entries {
+		devices = append(devices, entry.Name())
+	}
+	return devices, nil
+}
+
+// portStates returns the state of each port of a device as "<port>: <state>/<physical state>", e.g.
+// "1: ACTIVE/LinkUp", from the sysfs values such as "4: ACTIVE" and "5: LinkUp"
+func portStates(device string) []string {
+	portsDir := filepath.Join(sysClassInfiniband, device, "ports")
+	entries, err := os.ReadDir(portsDir)
+	if err != nil {
+		return nil
+	}
+	var states []string
+	for _, entry := range entries {
+		state := sysfsState(filepath.Join(portsDir, entry.Name(), "state"))
+		physState := sysfsState(filepath.Join(portsDir, entry.Name(), "phys_state"))
+		states = append(states, fmt.Sprintf("%s: %s/%s", entry.Name(), state, physState))
+	}
+	return states
+}
+
+// hasActivePort reports whether any port state returned by portStates is active with the link up
+func hasActivePort(states []string) bool {
+	for _, state := range states {
+		if strings.HasSuffix(state, ": ACTIVE/LinkUp") {
+			return true
+		}
+	}
+	return false
+}
+
+// sysfsState returns the name of an RDMA port state file such as "4: ACTIVE", or "unknown"
+func sysfsState(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "unknown"
+	}
+	_, name, ok := strings.Cut(strings.TrimSpace(string(data)), ":")
+	if !ok {
+		return strings.TrimSpace(string(data))
+	}
+	return strings.TrimSpace(name)
+}
+
+// currentNetNSMode returns the RDMA network namespace mode from "rdma system show", e.g.
+// "netns shared copy-on-fork on"
+func currentNetNSMode() (string, error) {
+	output, err := utils.RunCommandWithOutput("rdma", "system", "show")
+	if err != nil {
+		return "", fmt.Errorf("failed to show the RDMA network namespace mode: %w", err)
+	}
+	return parseNetNSMode(output), nil
+}
+
+// parseNetNSMode returns the word following netns in the output of "rdma system show"
+func parseNetNSMode(output string) string {
+	fields := strings.Fields(output)
+	for i, field := range fields {
+		if field == "netns" && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
+// removeFile removes a file that may not exist
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package rdma
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPortStates(t *testing.T) {
+	dir := t.TempDir()
+	sysClassInfiniband = dir
+	t.Cleanup(func() { sysClassInfiniband = "/sys/class/infiniband" })
+
+	ports := map[string][2]string{
+		"mlx5_0/ports/1": {"4: ACTIVE", "5: LinkUp"},
+		"mlx5_1/ports/1": {"1: DOWN", "2: Polling"},
+	}
+	for port, state := range ports {
+		path := filepath.Join(dir, port)
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "state"), []byte(state[0]+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "phys_state"), []byte(state[1]+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := portStates("mlx5_0"); !reflect.DeepEqual(got, []string{"1: ACTIVE/LinkUp"}) || !hasActivePort(got) {
+		t.Errorf("portStates(mlx5_0) = %v, want an active port", got)
+	}
+	if got := portStates("mlx5_1"); !reflect.DeepEqual(got, []string{"1: DOWN/Polling"}) || hasActivePort(got) {
+		t.Errorf("portStates(mlx5_1) = %v, want no active port", got)
+	}
+	devices, err := rdmaDevices()
+	if err != nil || !reflect.DeepEqual(devices, []string{"mlx5_0", "mlx5_1"}) {
+		t.Errorf("rdmaDevices() = %v, %v", devices, err)
+	}
+}
+
+func TestParseNetNSMode(t *testing.T) {
+	if got := parseNetNSMode("netns exclusive copy-on-fork on\n"); got != "exclusive" {
+		t.Errorf("parseNetNSMode() = %q, want exclusive", got)
+	}
+	if got := parseNetNSMode(""); got != "" {
+		t.Errorf("parseNetNSMode() of empty output = %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package rdma
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/services"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller removes the RDMA configuration. The rdma-core packages stay installed and the loaded modules stay
+// loaded until the next reboot.
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new RDMA UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "RDMAUnInstaller"
+}
+
+// Execute removes the RDMA configuration files
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isRDMAConfigured() {
+		return nil
+	}
+	removeRDMA(u.logger)
+	u.logger.Info("RDMA configuration removed")
+	return nil
+}
+
+// IsCompleted reports whether no RDMA configuration is left on the node
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isRDMAConfigured()
+}
+
+// rdmaFiles returns the files the RDMA installer writes, with the locked memory drop-ins of both runtimes
+func rdmaFiles() []string {
+	return []string{
+		modulesLoadFile, modprobeConfigFile, udevRulesFile,
+		memlockDropInPathOf(services.ContainerdService), memlockDropInPathOf(services.CRIOService),
+	}
+}
+
+// isRDMAConfigured reports whether any file added by the RDMA installer is present
+func isRDMAConfigured() bool {
+	for _, file := range rdmaFiles() {
+		if utils.FileExists(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeRDMA removes the configuration files and reloads udev and systemd. Failures are logged, so cleanup
+// continues.
+func removeRDMA(logger *logrus.Logger) {
+	for _, err := range utils.RemoveFiles(rdmaFiles(), logger) {
+		logger.Warnf("Failed to remove RDMA file: %v", err)
+	}
+	if err := utils.RunSystemCommand("udevadm", "control", "--reload"); err != nil {
+		logger.Warnf("Failed to reload the udev rules: %v", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		logger.Warnf("Failed to reload systemd: %v", err)
+	}
+	// Switching back fails while devices are in use; ib_core then starts in shared mode on the next boot
+	if mode, err := currentNetNSMode(); err == nil && mode == config.RDMANetNSExclusive {
+		if err := utils.RunSystemCommand("rdma", "system", "set", "netns", config.RDMANetNSShared); err != nil {
+			logger.Warnf("Failed to set the RDMA network namespace mode back to shared, it applies on the next boot: %v", err)
+		}
+	}
+}
@@ -60,7 +60,7 @@ func (i *Installer) installRunc(ctx context.Context) error {
 		return err
 	}
 
-	return nil
+	return utilhost.RestoreSELinuxContexts(runcBinaryPath)
 }
 
 // constructRuncDownloadURL constructs the download URL for the specified Runc version
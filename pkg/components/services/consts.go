@@ -5,8 +5,12 @@ import "time"
 const (
 	// Service names
 	ContainerdService = "containerd"
+	CRIOService       = "crio"
 	KubeletService    = "kubelet"
 
 	// Service startup timeout
 	ServiceStartupTimeout = 30 * time.Second
 )
+
+// containerRuntimeServices are the CRI runtimes a node may run
+var containerRuntimeServices = []string{ContainerdService, CRIOService}
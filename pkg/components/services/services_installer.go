@@ -24,7 +24,7 @@ func NewInstaller(logger *logrus.Logger) *Installer {
 	}
 }
 
-// Execute enables and starts required services (containerd and kubelet)
+// Execute enables and starts required services (containerd or CRI-O, and kubelet)
 func (i *Installer) Execute(ctx context.Context) error {
 	i.logger.Info("Enabling and starting services")
 
@@ -33,18 +33,19 @@ func (i *Installer) Execute(ctx context.Context) error {
 		return fmt.Errorf("failed to reload systemd: %w", err)
 	}
 
-	// Enable and start containerd
-	i.logger.Info("Enabling and starting containerd service")
-	if err := utils.EnableAndStartService("containerd"); err != nil {
-		i.logger.Errorf("Failed to enable and start containerd: %v", err)
-		return fmt.Errorf("failed to enable and start containerd: %w", err)
+	// Enable and start the container runtime
+	runtime := i.config.ContainerRuntimeService()
+	i.logger.Infof("Enabling and starting %s service", runtime)
+	if err := utils.EnableAndStartService(runtime); err != nil {
+		i.logger.Errorf("Failed to enable and start %s: %v", runtime, err)
+		return fmt.Errorf("failed to enable and start %s: %w", runtime, err)
 	}
 
-	// Restart containerd to pick up CNI configuration changes
-	i.logger.Info("Restarting containerd service to apply CNI configuration")
-	if err := utils.RestartService("containerd"); err != nil {
-		i.logger.Errorf("Failed to restart containerd: %v", err)
-		return fmt.Errorf("failed to restart containerd for CNI reload: %w", err)
+	// Restart the container runtime to pick up CNI configuration changes
+	i.logger.Infof("Restarting %s service to apply CNI configuration", runtime)
+	if err := utils.RestartService(runtime); err != nil {
+		i.logger.Errorf("Failed to restart %s: %v", runtime, err)
+		return fmt.Errorf("failed to restart %s for CNI reload: %w", runtime, err)
 	}
 
 	// Enable and start kubelet
@@ -42,14 +42,17 @@ func (su *UnInstaller) Execute(ctx context.Context) error {
 		}
 	}
 
-	// Stop and disable containerd
-	if utils.ServiceExists("containerd") {
-		su.logger.Info("Stopping and disabling containerd service")
-		if err := utils.StopService("containerd"); err != nil {
-			su.logger.Warnf("Failed to stop containerd: %v", err)
+	// Stop and disable the container runtimes, including one replaced by changing runtime.containerRuntime
+	for _, runtime := range containerRuntimeServices {
+		if !utils.ServiceExists(runtime) {
+			continue
 		}
-		if err := utils.DisableService("containerd"); err != nil {
-			su.logger.Warnf("Failed to disable containerd: %v", err)
+		su.logger.Infof("Stopping and disabling %s service", runtime)
+		if err := utils.StopService(runtime); err != nil {
+			su.logger.Warnf("Failed to stop %s: %v", runtime, err)
+		}
+		if err := utils.DisableService(runtime); err != nil {
+			su.logger.Warnf("Failed to disable %s: %v", runtime, err)
 		}
 	}
 
@@ -60,5 +63,10 @@ func (su *UnInstaller) Execute(ctx context.Context) error {
 // IsCompleted checks if services have been stopped and disabled
 func (su *UnInstaller) IsCompleted(ctx context.Context) bool {
 	// Services are considered Executeed if they are not active
-	return !utils.IsServiceActive("containerd") && !utils.IsServiceActive("kubelet")
+	for _, runtime := range containerRuntimeServices {
+		if utils.IsServiceActive(runtime) {
+			return false
+		}
+	}
+	return !utils.IsServiceActive("kubelet")
 }
@@ -0,0 +1,41 @@
+package sriov
+
+const (
+	// setupScriptPath creates the VFs, binds DPDK VFs and reserves hugepages on every boot, run by setupServiceName
+	// before kubelet starts so the device plugin finds the VFs
+	setupScriptPath  = "/etc/aks-flex-node/sriov-setup.sh"
+	setupServiceName = "aks-flex-node-sriov"
+	setupServicePath = "/etc/systemd/system/aks-flex-node-sriov.service"
+
+	// kernelArgsName names the kernel arguments this component adds to the boot entries
+	kernelArgsName = "sriov"
+
+	// vfioDriver is the driver DPDK applications drive VFs through from user space
+	vfioDriver = "vfio-pci"
+
+	// sriov CNI plugin, which sits at /usr/bin/sriov in its image
+	sriovPlugin         = "sriov"
+	sriovPluginImageDir = "/usr/bin"
+	sriovImageFile      = "/var/lib/cni/sriov-image"
+
+	// nrHugepages2M reserves 2M hugepages at runtime; 1G pages are reserved on the kernel command line
+	nrHugepages2M = "/sys/kernel/mm/hugepages/hugepages-2048kB/nr_hugepages"
+)
+
+// sysClassNet holds the network interfaces, a variable so tests can point it at a temporary directory
+var sysClassNet = "/sys/class/net"
+
+var setupServiceUnit = `[Unit]
+Description=Create SR-IOV virtual functions and reserve hugepages
+After=systemd-modules-load.service network-pre.target
+Before=kubelet.service
+Wants=network-pre.target
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/bin/sh ` + setupScriptPath + `
+
+[Install]
+WantedBy=multi-user.target
+`
@@ -0,0 +1,218 @@
+package sriov
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/cni"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer prepares the host side of SR-IOV device plugin workloads: IOMMU kernel arguments, virtual
+// functions, vfio-pci bindings for DPDK, hugepages and the sriov CNI plugin
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new SR-IOV Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "SRIOVInstaller"
+}
+
+// Validate checks that every configured physical function supports as many VFs as requested
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.config.SRIOV.Enabled {
+		return nil
+	}
+	for _, device := range i.config.SRIOV.Devices {
+		total, err := totalVFs(device.PF)
+		if err != nil {
+			return err
+		}
+		if device.NumVFs > total {
+			return fmt.Errorf("%s supports %d virtual functions, %d requested", device.PF, total, device.NumVFs)
+		}
+	}
+	return nil
+}
+
+// Execute configures SR-IOV, or removes the configuration when sriov.enabled is turned off
+func (i *Installer) Execute(ctx context.Context) error {
+	if !i.config.SRIOV.Enabled {
+		if isSRIOVInstalled() {
+			i.logger.Info("sriov.enabled is off, removing the SR-IOV configuration")
+			removeSRIOV(i.logger, i.config.SRIOV.Devices)
+		}
+		return nil
+	}
+
+	args := kernelArgs(&i.config.SRIOV, cpuVendor())
+	if err := utilhost.SetKernelArgs(kernelArgsName, args); err != nil {
+		return err
+	}
+	active, err := utilhost.KernelArgsActive(args)
+	if err != nil {
+		return err
+	}
+
+	if err := utilio.WriteFile(setupScriptPath, []byte(setupScript(&i.config.SRIOV)), 0o755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", setupScriptPath, err)
+	}
+	if err := utilio.WriteFile(setupServicePath, []byte(setupServiceUnit), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", setupServicePath, err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := utils.RunSystemCommand("systemctl", "enable", setupServiceName); err != nil {
+		return fmt.Errorf("failed to enable %s: %w", setupServiceName, err)
+	}
+	if active {
+		// Re-run the setup so changed devices or hugepages apply without a reboot
+		if err := utils.RestartService(setupServiceName); err != nil {
+			return fmt.Errorf("failed to set up SR-IOV devices: %w", err)
+		}
+		i.logger.Info("SR-IOV virtual functions created and hugepages reserved")
+	} else {
+		i.logger.Warnf("Reboot the node to boot with %s; virtual functions are created on the next boot", strings.Join(args, " "))
+	}
+
+	if err := cni.InstallPluginsFromImage(ctx, i.config, i.logger, i.config.GetImage(config.ImageSRIOVCNI), sriovPluginImageDir, []string{sriovPlugin}, sriovImageFile); err != nil {
+		return fmt.Errorf("failed to install the sriov CNI plugin: %w", err)
+	}
+	return nil
+}
+
+// IsCompleted reports whether the host is set up for the configured devices, or clean when disabled
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if !i.config.SRIOV.Enabled {
+		return !isSRIOVInstalled()
+	}
+
+	args := kernelArgs(&i.config.SRIOV, cpuVendor())
+	if strings.Join(utilhost.KernelArgs(kernelArgsName), " ") != strings.Join(args, " ") {
+		return false
+	}
+	if !utils.FileHasContent(setupScriptPath, setupScript(&i.config.SRIOV)) || !utils.FileHasContent(setupServicePath, setupServiceUnit) {
+		return false
+	}
+	if active, err := utilhost.KernelArgsActive(args); err != nil || (active && !utils.IsServiceActive(setupServiceName)) {
+		return false
+	}
+	return cni.PluginsInstalledFrom(i.config.GetImage(config.ImageSRIOVCNI), []string{sriovPlugin}, sriovImageFile)
+}
+
+// kernelArgs returns the kernel arguments SR-IOV needs: the IOMMU in passthrough mode, so VFs can be handed to
+// user space, and 1G hugepages, which can only be reserved reliably at boot
+func kernelArgs(cfg *config.SRIOVConfig, vendor string) []string {
+	var args []string
+	switch vendor {
+	case "GenuineIntel":
+		args = append(args, "intel_iommu=on", "iommu=pt")
+	case "AuthenticAMD":
+		args = append(args, "amd_iommu=on", "iommu=pt")
+	default:
+		// Arm SMMUs are on by default; only skip translation for devices the host drives
+		args = append(args, "iommu.passthrough=1")
+	}
+	if cfg.Hugepages.Size == config.HugepageSize1G && cfg.Hugepages.Count > 0 {
+		args = append(args, "default_hugepagesz=1G", "hugepagesz=1G", "hugepages="+strconv.Itoa(cfg.Hugepages.Count))
+	}
+	return args
+}
+
+// setupScript renders the boot script that creates the VFs, binds the DPDK VFs to vfio-pci and reserves 2M
+// hugepages. Changing the number of VFs requires going through 0, which the kernel enforces.
+func setupScript(cfg *config.SRIOVConfig) string {
+	var sb strings.Builder
+	sb.WriteString(`#!/bin/sh
+# Generated by aks-flex-node from the sriov configuration
+set -eu
+
+create_vfs() {
+    numvfs="/sys/class/net/$1/device/sriov_numvfs"
+    if [ "$(cat "$numvfs")" != "$2" ]; then
+        echo 0 > "$numvfs"
+        echo "$2" > "$numvfs"
+    fi
+}
+
+bind_vfio() {
+    addr="$(basename "$(readlink "/sys/class/net/$1/device/virtfn$2")")"
+    echo ` + vfioDriver + ` > "/sys/bus/pci/devices/$addr/driver_override"
+    if [ -e "/sys/bus/pci/devices/$addr/driver" ]; then
+        echo "$addr" > "/sys/bus/pci/devices/$addr/driver/unbind"
+    fi
+    echo "$addr" > /sys/bus/pci/drivers_probe
+}
+
+`)
+	if hasVFIO(cfg) {
+		sb.WriteString("modprobe " + vfioDriver + "\n")
+	}
+	for _, device := range cfg.Devices {
+		fmt.Fprintf(&sb, "create_vfs %s %d\n", device.PF, device.NumVFs)
+		for _, vf := range device.VFIOVFs {
+			fmt.Fprintf(&sb, "bind_vfio %s %d\n", device.PF, vf)
+		}
+	}
+	if cfg.Hugepages.Size != config.HugepageSize1G && cfg.Hugepages.Count > 0 {
+		fmt.Fprintf(&sb, "echo %d > %s\n", cfg.Hugepages.Count, nrHugepages2M)
+	}
+	return sb.String()
+}
+
+// hasVFIO reports whether any VF is bound to vfio-pci
+func hasVFIO(cfg *config.SRIOVConfig) bool {
+	for _, device := range cfg.Devices {
+		if len(device.VFIOVFs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// totalVFs returns the number of virtual functions a physical function supports
+func totalVFs(pf string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(sysClassNet, pf, "device", "sriov_totalvfs"))
+	if err != nil {
+		return 0, fmt.Errorf("%s is not an SR-IOV capable interface: %w", pf, err)
+	}
+	total, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid sriov_totalvfs %q for %s", strings.TrimSpace(string(data)), pf)
+	}
+	return total, nil
+}
+
+// cpuVendor returns the vendor_id of the CPU, e.g. GenuineIntel, or an empty string on Arm
+func cpuVendor() string {
+	data, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(name) == "vendor_id" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
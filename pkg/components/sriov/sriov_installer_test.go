@@ -0,0 +1,87 @@
+package sriov
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestKernelArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		vendor    string
+		hugepages config.SRIOVHugepagesConfig
+		want      []string
+	}{
+		{name: "intel", vendor: "GenuineIntel", want: []string{"intel_iommu=on", "iommu=pt"}},
+		{name: "amd with 2M hugepages", vendor: "AuthenticAMD", hugepages: config.SRIOVHugepagesConfig{Size: "2M", Count: 1024}, want: []string{"amd_iommu=on", "iommu=pt"}},
+		{name: "arm", vendor: "", want: []string{"iommu.passthrough=1"}},
+		{
+			name:      "intel with 1G hugepages",
+			vendor:    "GenuineIntel",
+			hugepages: config.SRIOVHugepagesConfig{Size: "1G", Count: 8},
+			want:      []string{"intel_iommu=on", "iommu=pt", "default_hugepagesz=1G", "hugepagesz=1G", "hugepages=8"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := kernelArgs(&config.SRIOVConfig{Hugepages: tt.hugepages}, tt.vendor)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kernelArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetupScript(t *testing.T) {
+	cfg := &config.SRIOVConfig{
+		Devices: []config.SRIOVDeviceConfig{
+			{PF: "ens1f0", NumVFs: 4, VFIOVFs: []int{0, 1}},
+			{PF: "ens1f1", NumVFs: 2},
+		},
+		Hugepages: config.SRIOVHugepagesConfig{Size: "2M", Count: 1024},
+	}
+	script := setupScript(cfg)
+	for _, want := range []string{
+		"modprobe vfio-pci\n",
+		"create_vfs ens1f0 4\nbind_vfio ens1f0 0\nbind_vfio ens1f0 1\ncreate_vfs ens1f1 2\n",
+		"echo 1024 > " + nrHugepages2M + "\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("setupScript() does not contain %q:\n%s", want, script)
+		}
+	}
+
+	// Without DPDK VFs or 2M hugepages, vfio-pci is not loaded and the hugepage pool is left alone
+	cfg.Devices[0].VFIOVFs = nil
+	cfg.Hugepages = config.SRIOVHugepagesConfig{Size: "1G", Count: 8}
+	script = setupScript(cfg)
+	if strings.Contains(script, "modprobe") || strings.Contains(script, nrHugepages2M) {
+		t.Errorf("setupScript() = %s, want no vfio-pci and no 2M hugepages", script)
+	}
+}
+
+func TestTotalVFs(t *testing.T) {
+	original := sysClassNet
+	defer func() { sysClassNet = original }()
+	sysClassNet = t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(sysClassNet, "ens1f0", "device"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sysClassNet, "ens1f0", "device", "sriov_totalvfs"), []byte("64\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if total, err := totalVFs("ens1f0"); err != nil || total != 64 {
+		t.Errorf("totalVFs(ens1f0) = %d, %v, want 64", total, err)
+	}
+	if _, err := totalVFs("eth0"); err == nil {
+		t.Error("totalVFs(eth0) succeeded for an interface without SR-IOV")
+	}
+}
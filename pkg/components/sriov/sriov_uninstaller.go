@@ -0,0 +1,87 @@
+package sriov
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// UnInstaller removes the SR-IOV configuration and the virtual functions of the configured devices
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new SR-IOV UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "SRIOVUnInstaller"
+}
+
+// Execute removes the SR-IOV boot setup and kernel arguments and destroys the virtual functions
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isSRIOVInstalled() {
+		return nil
+	}
+	removeSRIOV(u.logger, u.config.SRIOV.Devices)
+	u.logger.Info("SR-IOV configuration removed")
+	return nil
+}
+
+// IsCompleted reports whether no SR-IOV configuration is left on the node
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isSRIOVInstalled()
+}
+
+// isSRIOVInstalled reports whether any file or kernel argument added by the SR-IOV installer is present
+func isSRIOVInstalled() bool {
+	return utils.FileExists(setupServicePath) || utils.FileExists(setupScriptPath) ||
+		utils.FileExists(sriovImageFile) || len(utilhost.KernelArgs(kernelArgsName)) > 0
+}
+
+// removeSRIOV removes the boot setup, destroys the VFs of devices and removes the kernel arguments, which
+// takes effect on the next boot. Failures are logged, so cleanup continues.
+func removeSRIOV(logger *logrus.Logger, devices []config.SRIOVDeviceConfig) {
+	if utils.FileExists(setupServicePath) {
+		if err := utils.StopService(setupServiceName); err != nil {
+			logger.Debugf("Failed to stop %s: %v", setupServiceName, err)
+		}
+		if err := utils.DisableService(setupServiceName); err != nil {
+			logger.Debugf("Failed to disable %s: %v", setupServiceName, err)
+		}
+	}
+	for _, err := range utils.RemoveFiles([]string{setupServicePath, setupScriptPath, sriovImageFile}, logger) {
+		logger.Warnf("Failed to remove SR-IOV file: %v", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		logger.Warnf("Failed to reload systemd: %v", err)
+	}
+
+	for _, device := range devices {
+		numVFs := filepath.Join(sysClassNet, device.PF, "device", "sriov_numvfs")
+		if err := os.WriteFile(numVFs, []byte("0"), 0o644); err != nil {
+			logger.Warnf("Failed to remove the virtual functions of %s: %v", device.PF, err)
+		}
+	}
+
+	if len(utilhost.KernelArgs(kernelArgsName)) > 0 {
+		if err := utilhost.RemoveKernelArgs(kernelArgsName); err != nil {
+			logger.Warnf("Failed to remove SR-IOV kernel arguments: %v", err)
+		} else {
+			logger.Info("SR-IOV kernel arguments removed; they stay in effect until the next reboot")
+		}
+	}
+}
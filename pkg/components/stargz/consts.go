@@ -0,0 +1,27 @@
+package stargz
+
+const (
+	// stargzBinaryPath is the snapshotter daemon, which containerd reaches as the stargz proxy plugin
+	stargzBinaryPath = "/usr/local/bin/containerd-stargz-grpc"
+
+	stargzConfigDir  = "/etc/containerd-stargz-grpc"
+	stargzConfigPath = "/etc/containerd-stargz-grpc/config.toml"
+
+	// stargzVersionFile records the installed release, since the binary does not report it
+	stargzVersionFile = "/etc/containerd-stargz-grpc/version"
+
+	stargzServiceName = "stargz-snapshotter"
+	stargzServicePath = "/etc/systemd/system/stargz-snapshotter.service"
+
+	// stargzDataDir holds the lazily fetched layers and their caches
+	stargzDataDir = "/var/lib/containerd-stargz-grpc"
+)
+
+var stargzDownloadURL = "https://github.com/containerd/stargz-snapshotter/releases/download/v%s/stargz-snapshotter-v%s-linux-%s.tar.gz"
+
+// stargzConfig prefetches the files an image marks as needed at startup and fetches the remaining chunks in the
+// background, so containers start before their layers are complete without stalling on later reads
+const stargzConfig = `# Generated by aks-flex-node for containerd.snapshotter stargz
+noprefetch = false
+no_background_fetch = false
+`
@@ -0,0 +1,163 @@
+package stargz
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer runs the stargz snapshotter, which lets containers start from eStargz images before their layers are
+// downloaded by fetching file contents on demand. containerd uses it when containerd.snapshotter is stargz.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new stargz snapshotter Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "StargzSnapshotterInstaller"
+}
+
+// Validate checks that the kernel can mount the FUSE filesystems the snapshotter serves layers with
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.isEnabled() {
+		return nil
+	}
+	if err := utilhost.LoadKernelModule("fuse"); err != nil {
+		i.logger.Debugf("Failed to load the fuse module: %v", err)
+	}
+	if !utils.FileExists("/dev/fuse") {
+		return fmt.Errorf("the stargz snapshotter requires FUSE support in the kernel, but /dev/fuse is missing")
+	}
+	return nil
+}
+
+// Execute installs and starts the snapshotter, or removes it when containerd.snapshotter is no longer stargz
+func (i *Installer) Execute(ctx context.Context) error {
+	if !i.isEnabled() {
+		if isStargzInstalled() {
+			i.logger.Info("containerd.snapshotter is not stargz, removing the stargz snapshotter")
+			removeStargz(i.logger)
+		}
+		return nil
+	}
+
+	version := i.config.Containerd.Stargz.Version
+	if installedVersion() != version {
+		i.logger.Infof("Installing stargz snapshotter version %s", version)
+		if err := i.installBinary(ctx, version); err != nil {
+			return fmt.Errorf("stargz snapshotter installation failed: %w", err)
+		}
+	}
+
+	if err := utilio.WriteFile(stargzConfigPath, []byte(stargzConfig), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", stargzConfigPath, err)
+	}
+	if err := utilio.WriteFile(stargzServicePath, []byte(i.serviceUnit()), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", stargzServicePath, err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := utils.EnableAndStartService(stargzServiceName); err != nil {
+		return fmt.Errorf("failed to start the stargz snapshotter: %w", err)
+	}
+	// Pick up a new binary, configuration or proxy
+	if err := utils.RestartService(stargzServiceName); err != nil {
+		return fmt.Errorf("failed to restart the stargz snapshotter: %w", err)
+	}
+	i.logger.Infof("stargz snapshotter version %s running", version)
+	return nil
+}
+
+// IsCompleted reports whether the configured release runs with the current configuration, or is absent when
+// containerd uses another snapshotter
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if !i.isEnabled() {
+		return !isStargzInstalled()
+	}
+	return utils.FileExists(stargzBinaryPath) &&
+		installedVersion() == i.config.Containerd.Stargz.Version &&
+		utils.FileHasContent(stargzConfigPath, stargzConfig) &&
+		utils.FileHasContent(stargzServicePath, i.serviceUnit()) &&
+		utils.IsServiceActive(stargzServiceName)
+}
+
+// isEnabled reports whether containerd is configured to use the stargz snapshotter
+func (i *Installer) isEnabled() bool {
+	return i.config.Containerd.Snapshotter == config.SnapshotterStargz
+}
+
+// installBinary installs containerd-stargz-grpc from the release archive and records its version
+func (i *Installer) installBinary(ctx context.Context, version string) error {
+	url := fmt.Sprintf(stargzDownloadURL, version, version, utilhost.GetArch())
+	installed := false
+	for tarFile, err := range utilio.DecompressTarGzFromRemote(ctx, url) {
+		if err != nil {
+			return err
+		}
+		if path.Base(tarFile.Name) != path.Base(stargzBinaryPath) {
+			continue
+		}
+		i.logger.Debugf("installing %q to %q", tarFile.Name, stargzBinaryPath)
+		if err := utilio.InstallFile(stargzBinaryPath, tarFile.Body, 0o755); err != nil {
+			return fmt.Errorf("failed to install %s: %w", stargzBinaryPath, err)
+		}
+		installed = true
+	}
+	if !installed {
+		return fmt.Errorf("%s not found in %s", path.Base(stargzBinaryPath), url)
+	}
+	return utilio.WriteFile(stargzVersionFile, []byte(version+"\n"), 0o644)
+}
+
+// serviceUnit renders the snapshotter's unit. It starts before containerd, which fails pulls for the stargz
+// proxy plugin while the socket is missing, and fetches layers through the node proxy.
+func (i *Installer) serviceUnit() string {
+	var env strings.Builder
+	for _, variable := range i.config.GetProxyEnvironment() {
+		fmt.Fprintf(&env, "Environment=\"%s\"\n", variable)
+	}
+	return fmt.Sprintf(`[Unit]
+Description=stargz snapshotter for lazily pulled images
+After=network-online.target local-fs.target
+Wants=network-online.target
+Before=containerd.service
+
+[Service]
+Type=notify
+Environment=HOME=/root
+%sExecStart=%s --log-level=info --config=%s --root=%s
+Restart=always
+RestartSec=1
+
+[Install]
+WantedBy=multi-user.target
+`, env.String(), stargzBinaryPath, stargzConfigPath, stargzDataDir)
+}
+
+// installedVersion returns the recorded release of the installed snapshotter, or "" when none is
+func installedVersion() string {
+	data, err := os.ReadFile(stargzVersionFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,49 @@
+package stargz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestServiceUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxy   config.ProxyConfig
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "direct",
+			want:    []string{"Before=containerd.service", "Type=notify", "ExecStart=" + stargzBinaryPath + " --log-level=info --config=" + stargzConfigPath + " --root=" + stargzDataDir},
+			notWant: []string{"HTTPS_PROXY"},
+		},
+		{
+			name:  "through the node proxy",
+			proxy: config.ProxyConfig{HTTPSProxy: "http://proxy.contoso.local:3128"},
+			want:  []string{`Environment="HTTPS_PROXY=http://proxy.contoso.local:3128"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Containerd: config.ContainerdConfig{Snapshotter: config.SnapshotterStargz}}
+			cfg.Network.Proxy = tt.proxy
+			installer := &Installer{config: cfg, logger: logrus.New()}
+			unit := installer.serviceUnit()
+			for _, want := range tt.want {
+				if !strings.Contains(unit, want) {
+					t.Errorf("unit is missing %q:\n%s", want, unit)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(unit, notWant) {
+					t.Errorf("unit should not contain %q:\n%s", notWant, unit)
+				}
+			}
+		})
+	}
+}
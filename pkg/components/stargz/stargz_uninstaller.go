@@ -0,0 +1,76 @@
+package stargz
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// UnInstaller stops and removes the stargz snapshotter and its cached layers
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new stargz snapshotter UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "StargzSnapshotterUnInstaller"
+}
+
+// Execute stops the snapshotter and removes its files
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	if !isStargzInstalled() {
+		return nil
+	}
+	removeStargz(u.logger)
+	u.logger.Info("stargz snapshotter removed")
+	return nil
+}
+
+// IsCompleted reports whether no stargz snapshotter files are left on the node
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	return !isStargzInstalled()
+}
+
+// isStargzInstalled reports whether any file written by the stargz installer is present
+func isStargzInstalled() bool {
+	for _, path := range []string{stargzServicePath, stargzBinaryPath, stargzConfigDir, stargzDataDir} {
+		if utils.FileExists(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeStargz stops the snapshotter, which unmounts its layers, then removes its files. Failures are logged, so
+// cleanup continues.
+func removeStargz(logger *logrus.Logger) {
+	if utils.FileExists(stargzServicePath) {
+		if err := utils.StopService(stargzServiceName); err != nil {
+			logger.Debugf("Failed to stop %s: %v", stargzServiceName, err)
+		}
+		if err := utils.DisableService(stargzServiceName); err != nil {
+			logger.Debugf("Failed to disable %s: %v", stargzServiceName, err)
+		}
+	}
+	for _, err := range utils.RemoveFiles([]string{stargzServicePath, stargzBinaryPath}, logger) {
+		logger.Warnf("Failed to remove stargz snapshotter file: %v", err)
+	}
+	for _, err := range utils.RemoveDirectories([]string{stargzConfigDir, stargzDataDir}, logger) {
+		logger.Warnf("Failed to remove stargz snapshotter directory: %v", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		logger.Warnf("Failed to reload systemd: %v", err)
+	}
+}
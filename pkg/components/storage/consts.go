@@ -0,0 +1,18 @@
+package storage
+
+const (
+	// dataDiskLabel names the data partition and labels its filesystem, so an existing one is found and reused
+	dataDiskLabel = "aksdata"
+
+	// azureResourceDiskPath is the udev link to the Azure ephemeral resource disk
+	azureResourceDiskPath = "/dev/disk/azure/resource"
+
+	// containerdDataDir holds containerd's images, snapshots and container state
+	containerdDataDir = "/var/lib/containerd"
+
+	// containerdService is stopped while its state is copied to the data disk
+	containerdService = "containerd"
+)
+
+// fstabPath is a variable so tests can point it at a temporary file
+var fstabPath = "/etc/fstab"
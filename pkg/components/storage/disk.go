@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// blockDevice is a disk or partition as listed by lsblk
+type blockDevice struct {
+	Name       string        `json:"name"`
+	Type       string        `json:"type"`
+	FSType     string        `json:"fstype"`
+	Label      string        `json:"label"`
+	PartLabel  string        `json:"partlabel"`
+	UUID       string        `json:"uuid"`
+	MountPoint string        `json:"mountpoint"`
+	Children   []blockDevice `json:"children"`
+}
+
+// listBlockDevice returns a disk together with its partitions
+func listBlockDevice(device string) (blockDevice, error) {
+	output, err := exec.Command("lsblk", "--json", "--paths", "--output", "NAME,TYPE,FSTYPE,LABEL,PARTLABEL,UUID,MOUNTPOINT", device).Output() // #nosec - device comes from the configuration
+	if err != nil {
+		return blockDevice{}, fmt.Errorf("failed to list %s: %w", device, err)
+	}
+	return parseBlockDevice(output)
+}
+
+// parseBlockDevice parses the lsblk output of a single device
+func parseBlockDevice(output []byte) (blockDevice, error) {
+	var list struct {
+		BlockDevices []blockDevice `json:"blockdevices"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return blockDevice{}, fmt.Errorf("failed to parse lsblk output: %w", err)
+	}
+	if len(list.BlockDevices) != 1 {
+		return blockDevice{}, fmt.Errorf("lsblk listed %d devices, expected 1", len(list.BlockDevices))
+	}
+	return list.BlockDevices[0], nil
+}
+
+// dataPartition returns the partition created for the data disk, if any
+func (d *blockDevice) dataPartition(fsType string) (blockDevice, bool) {
+	for _, child := range d.Children {
+		if child.Type == "part" && child.PartLabel == dataDiskLabel && child.Label == dataDiskLabel && child.FSType == fsType {
+			return child, true
+		}
+	}
+	return blockDevice{}, false
+}
+
+// inUse reports whether the disk holds a partition table, partitions or a filesystem
+func (d *blockDevice) inUse() bool {
+	if d.FSType != "" || len(d.Children) > 0 {
+		return true
+	}
+	output, err := exec.Command("blkid", "--probe", d.Name).Output() // #nosec - device comes from the configuration
+	return err == nil && len(output) > 0
+}
+
+// mountPoints returns where the disk and its partitions are mounted
+func (d *blockDevice) mountPoints() []string {
+	var mountPoints []string
+	if d.MountPoint != "" {
+		mountPoints = append(mountPoints, d.MountPoint)
+	}
+	for _, child := range d.Children {
+		mountPoints = append(mountPoints, child.mountPoints()...)
+	}
+	return mountPoints
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// fstabEntry is a line of /etc/fstab
+type fstabEntry struct {
+	source     string
+	mountPoint string
+	fsType     string
+	options    string
+	pass       int
+}
+
+func (e fstabEntry) String() string {
+	return fmt.Sprintf("%s %s %s %s 0 %d", e.source, e.mountPoint, e.fsType, e.options, e.pass)
+}
+
+// updateFstab replaces the entries of the given mount points with entries, keeping all other lines. Without
+// entries, the lines of the mount points are only removed.
+func updateFstab(mountPoints []string, entries []fstabEntry) error {
+	data, err := os.ReadFile(fstabPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", fstabPath, err)
+	}
+	content := replaceFstabEntries(string(data), mountPoints, entries)
+	if content == string(data) {
+		return nil
+	}
+	if err := utilio.WriteFile(fstabPath, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fstabPath, err)
+	}
+	return nil
+}
+
+// replaceFstabEntries removes the lines of the given mount points from fstab and appends entries
+func replaceFstabEntries(fstab string, mountPoints []string, entries []fstabEntry) string {
+	remove := make(map[string]bool, len(mountPoints))
+	for _, mountPoint := range mountPoints {
+		remove[mountPoint] = true
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(fstab, "\n"), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") && remove[fields[1]] {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	for _, entry := range entries {
+		lines = append(lines, entry.String())
+	}
+	return strings.TrimLeft(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// hasFstabEntries reports whether fstab holds exactly the given entries for their mount points
+func hasFstabEntries(entries []fstabEntry) bool {
+	data, err := os.ReadFile(fstabPath)
+	if err != nil {
+		return false
+	}
+	lines := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") {
+			lines[fields[1]] = strings.Join(fields, " ")
+		}
+	}
+	for _, entry := range entries {
+		if lines[entry.mountPoint] != entry.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAnyFstabEntry reports whether fstab has a line for any of the given mount points
+func hasAnyFstabEntry(mountPoints []string) bool {
+	data, err := os.ReadFile(fstabPath)
+	if err != nil {
+		return false
+	}
+	return replaceFstabEntries(string(data), mountPoints, nil) != replaceFstabEntries(string(data), nil, nil)
+}
@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Installer moves the state of containerd and kubelet to a dedicated data disk. The disk is mounted on
+// storage.dataDisk.mountPoint and its containerd and kubelet directories are bind-mounted over those on the OS
+// disk, so images and pod volumes cannot fill the OS disk.
+type Installer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewInstaller creates a new data disk Installer
+func NewInstaller(logger *logrus.Logger) *Installer {
+	return &Installer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *Installer) GetName() string {
+	return "StorageInstaller"
+}
+
+// Validate checks that the disk exists and that the tools to partition and format it are installed
+func (i *Installer) Validate(ctx context.Context) error {
+	if !i.config.Storage.DataDisk.Enabled {
+		return nil
+	}
+	for _, binary := range []string{"lsblk", "blkid", "parted", "wipefs", "mkfs." + i.config.Storage.DataDisk.Filesystem} {
+		if !utils.BinaryExists(binary) {
+			return fmt.Errorf("%s is required to set up the data disk", binary)
+		}
+	}
+	device, err := i.device()
+	if err != nil {
+		return err
+	}
+	disk, err := listBlockDevice(device)
+	if err != nil {
+		return err
+	}
+	if disk.Type != "disk" {
+		return fmt.Errorf("storage.dataDisk.device %s is a %s, expected a whole disk", i.config.Storage.DataDisk.Device, disk.Type)
+	}
+	return nil
+}
+
+// Execute prepares, mounts and bind-mounts the data disk. Turning storage.dataDisk.enabled off leaves a mounted
+// disk in place, since containerd and kubelet keep their state on it; unbootstrap unmounts it.
+func (i *Installer) Execute(ctx context.Context) error {
+	dataDisk := &i.config.Storage.DataDisk
+	if !dataDisk.Enabled {
+		if utilhost.IsMountPoint(dataDisk.MountPoint) {
+			i.logger.Warn("storage.dataDisk.enabled is off, the data disk stays mounted until unbootstrap")
+		}
+		return nil
+	}
+
+	partition, err := i.ensureDataPartition()
+	if err != nil {
+		return err
+	}
+	if err := updateFstab(i.mountPoints(), i.fstabEntries(partition.UUID)); err != nil {
+		return err
+	}
+
+	if !utilhost.IsMountPoint(dataDisk.MountPoint) {
+		if err := utilio.MkdirAll(dataDisk.MountPoint, 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dataDisk.MountPoint, err)
+		}
+		if err := utils.RunSystemCommand("mount", dataDisk.MountPoint); err != nil {
+			return fmt.Errorf("failed to mount the data disk on %s: %w", dataDisk.MountPoint, err)
+		}
+	}
+	for name, dir := range i.dataDirs() {
+		if err := i.bindMount(filepath.Join(dataDisk.MountPoint, name), dir); err != nil {
+			return err
+		}
+	}
+	i.logger.Infof("containerd and kubelet state is on the data disk %s mounted on %s", partition.Name, dataDisk.MountPoint)
+	return nil
+}
+
+// IsCompleted reports whether the data disk and the state directories are mounted and in fstab
+func (i *Installer) IsCompleted(ctx context.Context) bool {
+	dataDisk := &i.config.Storage.DataDisk
+	if !dataDisk.Enabled {
+		return true
+	}
+	mount, err := utilhost.MountOf(dataDisk.MountPoint)
+	if err != nil || mount.Point != filepath.Clean(dataDisk.MountPoint) {
+		return false
+	}
+	partition, err := listBlockDevice(mount.Source)
+	if err != nil || partition.Label != dataDiskLabel || !hasFstabEntries(i.fstabEntries(partition.UUID)) {
+		return false
+	}
+	for _, dir := range i.dataDirs() {
+		if !utilhost.IsMountPoint(dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// device resolves storage.dataDisk.device to the disk's device node
+func (i *Installer) device() (string, error) {
+	device := i.config.Storage.DataDisk.Device
+	if device == config.DataDiskResource {
+		device = azureResourceDiskPath
+	}
+	resolved, err := filepath.EvalSymlinks(device)
+	if err != nil {
+		if device == azureResourceDiskPath {
+			return "", fmt.Errorf("no Azure resource disk found at %s; the VM size may not have one: %w", device, err)
+		}
+		return "", fmt.Errorf("storage.dataDisk.device %s not found: %w", device, err)
+	}
+	return resolved, nil
+}
+
+// ensureDataPartition returns the data partition of the disk, creating and formatting it unless it exists.
+// A disk holding anything else is only formatted if it is the ephemeral resource disk or wipe is set.
+func (i *Installer) ensureDataPartition() (blockDevice, error) {
+	dataDisk := &i.config.Storage.DataDisk
+	device, err := i.device()
+	if err != nil {
+		return blockDevice{}, err
+	}
+	disk, err := listBlockDevice(device)
+	if err != nil {
+		return blockDevice{}, err
+	}
+	if partition, ok := disk.dataPartition(dataDisk.Filesystem); ok {
+		i.logger.Infof("Reusing the data partition %s", partition.Name)
+		return partition, nil
+	}
+
+	resourceDisk := dataDisk.Device == config.DataDiskResource
+	if disk.inUse() && !resourceDisk && !dataDisk.Wipe {
+		return blockDevice{}, fmt.Errorf("%s holds partitions or a filesystem; set storage.dataDisk.wipe to format it, destroying its data", device)
+	}
+	if mountPoints := disk.mountPoints(); len(mountPoints) > 0 {
+		// The resource disk comes mounted on /mnt by cloud-init or the Azure agent
+		for _, mountPoint := range mountPoints {
+			i.logger.Infof("Unmounting %s from %s", mountPoint, device)
+			if err := utils.RunSystemCommand("umount", mountPoint); err != nil {
+				return blockDevice{}, fmt.Errorf("failed to unmount %s: %w", mountPoint, err)
+			}
+		}
+		if err := updateFstab(mountPoints, nil); err != nil {
+			return blockDevice{}, err
+		}
+	}
+
+	i.logger.Infof("Partitioning %s and formatting it with %s", device, dataDisk.Filesystem)
+	if err := utils.RunSystemCommand("wipefs", "--all", device); err != nil {
+		return blockDevice{}, fmt.Errorf("failed to wipe %s: %w", device, err)
+	}
+	if err := utils.RunSystemCommand("parted", "--script", device, "mklabel", "gpt", "mkpart", dataDiskLabel, dataDisk.Filesystem, "0%", "100%"); err != nil {
+		return blockDevice{}, fmt.Errorf("failed to partition %s: %w", device, err)
+	}
+	partition, err := waitForPartition(device)
+	if err != nil {
+		return blockDevice{}, err
+	}
+	if err := utils.RunSystemCommand("mkfs."+dataDisk.Filesystem, mkfsArgs(dataDisk.Filesystem, partition.Name)...); err != nil {
+		return blockDevice{}, fmt.Errorf("failed to format %s: %w", partition.Name, err)
+	}
+	if _, err := waitForPartition(device); err != nil {
+		return blockDevice{}, err
+	}
+	disk, err = listBlockDevice(device)
+	if err != nil {
+		return blockDevice{}, err
+	}
+	partition, ok := disk.dataPartition(dataDisk.Filesystem)
+	if !ok {
+		return blockDevice{}, fmt.Errorf("the data partition of %s was not found after formatting it", device)
+	}
+	return partition, nil
+}
+
+// waitForPartition waits for udev to create the device node of the first partition of a disk
+func waitForPartition(device string) (blockDevice, error) {
+	if err := utils.RunSystemCommand("udevadm", "settle"); err != nil {
+		return blockDevice{}, fmt.Errorf("failed to wait for udev: %w", err)
+	}
+	for range 10 {
+		disk, err := listBlockDevice(device)
+		if err == nil && len(disk.Children) > 0 && utils.FileExists(disk.Children[0].Name) {
+			return disk.Children[0], nil
+		}
+		time.Sleep(time.Second)
+	}
+	return blockDevice{}, fmt.Errorf("no partition of %s appeared", device)
+}
+
+// mkfsArgs returns the arguments formatting a partition with the data disk label
+func mkfsArgs(fsType, partition string) []string {
+	if fsType == config.DataDiskFilesystemXFS {
+		return []string{"-f", "-L", dataDiskLabel, partition}
+	}
+	return []string{"-F", "-L", dataDiskLabel, partition}
+}
+
+// bindMount bind-mounts source over dir. Existing state in dir is copied to the disk first, unless the disk
+// already holds state from an earlier bootstrap.
+func (i *Installer) bindMount(source, dir string) error {
+	if utilhost.IsMountPoint(dir) {
+		return nil
+	}
+	if err := utilio.MkdirAll(source, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", source, err)
+	}
+	if err := utilio.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if !isEmptyDir(dir) && isEmptyDir(source) {
+		if dir == containerdDataDir && utils.IsServiceActive(containerdService) {
+			if err := utils.StopService(containerdService); err != nil {
+				return fmt.Errorf("failed to stop containerd to move its state: %w", err)
+			}
+		}
+		i.logger.Infof("Copying %s to the data disk", dir)
+		// Stay on the OS disk, so pod volumes mounted below the kubelet directory are not copied
+		if err := utils.RunSystemCommand("cp", "--archive", "--one-file-system", dir+"/.", source); err != nil {
+			return fmt.Errorf("failed to copy %s to the data disk: %w", dir, err)
+		}
+	}
+	if err := utils.RunSystemCommand("mount", dir); err != nil {
+		return fmt.Errorf("failed to mount %s from the data disk: %w", dir, err)
+	}
+	return nil
+}
+
+// dataDirs maps the directories on the data disk to the state directories mounted from them
+func (i *Installer) dataDirs() map[string]string {
+	return map[string]string{
+		"containerd": containerdDataDir,
+		"kubelet":    i.config.Paths.Kubernetes.KubeletDir,
+	}
+}
+
+// mountPoints returns the mount points of the fstab entries
+func (i *Installer) mountPoints() []string {
+	var mountPoints []string
+	for _, entry := range i.fstabEntries("") {
+		mountPoints = append(mountPoints, entry.mountPoint)
+	}
+	return mountPoints
+}
+
+// fstabEntries returns the fstab entries of the data partition with the given UUID and of the bind mounts.
+// nofail keeps the node booting when the ephemeral resource disk comes back empty after a redeployment.
+func (i *Installer) fstabEntries(uuid string) []fstabEntry {
+	dataDisk := &i.config.Storage.DataDisk
+	mountPoint := filepath.Clean(dataDisk.MountPoint)
+	entries := []fstabEntry{{
+		source:     "UUID=" + uuid,
+		mountPoint: mountPoint,
+		fsType:     dataDisk.Filesystem,
+		options:    "defaults,nofail,x-systemd.device-timeout=30s",
+		pass:       2,
+	}}
+	for _, name := range []string{"containerd", "kubelet"} {
+		entries = append(entries, fstabEntry{
+			source:     filepath.Join(mountPoint, name),
+			mountPoint: i.dataDirs()[name],
+			fsType:     "none",
+			options:    "bind,nofail,x-systemd.requires-mounts-for=" + mountPoint,
+		})
+	}
+	return entries
+}
+
+// isEmptyDir reports whether dir has no entries or does not exist
+func isEmptyDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err != nil || len(entries) == 0
+}
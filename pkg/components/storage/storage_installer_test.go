@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestParseBlockDevice(t *testing.T) {
+	output := `{
+   "blockdevices": [
+      {"name":"/dev/sdb", "type":"disk", "fstype":null, "label":null, "partlabel":null, "uuid":null, "mountpoint":null,
+         "children": [
+            {"name":"/dev/sdb1", "type":"part", "fstype":"ext4", "label":"aksdata", "partlabel":"aksdata", "uuid":"0b6b2a4e-5c1e-4a43-9a6b-2f1f7e0c9d11", "mountpoint":"/mnt/aks-data"}
+         ]
+      }
+   ]
+}`
+	disk, err := parseBlockDevice([]byte(output))
+	if err != nil {
+		t.Fatalf("parseBlockDevice() error = %v", err)
+	}
+	if disk.Name != "/dev/sdb" || disk.Type != "disk" {
+		t.Errorf("parseBlockDevice() = %+v", disk)
+	}
+	partition, ok := disk.dataPartition(config.DataDiskFilesystemExt4)
+	if !ok || partition.UUID != "0b6b2a4e-5c1e-4a43-9a6b-2f1f7e0c9d11" {
+		t.Errorf("dataPartition(ext4) = %+v, %v", partition, ok)
+	}
+	if _, ok := disk.dataPartition(config.DataDiskFilesystemXFS); ok {
+		t.Errorf("dataPartition(xfs) found the ext4 partition")
+	}
+	if got := disk.mountPoints(); !reflect.DeepEqual(got, []string{"/mnt/aks-data"}) {
+		t.Errorf("mountPoints() = %v", got)
+	}
+}
+
+func TestFstabEntries(t *testing.T) {
+	i := &Installer{config: &config.Config{
+		Storage: config.StorageConfig{DataDisk: config.DataDiskConfig{Enabled: true, Filesystem: "xfs", MountPoint: "/mnt/aks-data/"}},
+		Paths:   config.PathsConfig{Kubernetes: config.KubernetesPathsConfig{KubeletDir: "/var/lib/kubelet"}},
+	}}
+	var got []string
+	for _, entry := range i.fstabEntries("1234") {
+		got = append(got, entry.String())
+	}
+	want := []string{
+		"UUID=1234 /mnt/aks-data xfs defaults,nofail,x-systemd.device-timeout=30s 0 2",
+		"/mnt/aks-data/containerd /var/lib/containerd none bind,nofail,x-systemd.requires-mounts-for=/mnt/aks-data 0 0",
+		"/mnt/aks-data/kubelet /var/lib/kubelet none bind,nofail,x-systemd.requires-mounts-for=/mnt/aks-data 0 0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fstabEntries() = %v, want %v", got, want)
+	}
+}
+
+func TestUpdateFstab(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fstab")
+	original := fstabPath
+	fstabPath = path
+	t.Cleanup(func() { fstabPath = original })
+
+	initial := "# /etc/fstab\nLABEL=cloudimg-rootfs / ext4 discard,errors=remount-ro 0 1\n" +
+		"/dev/disk/cloud/azure_resource-part1 /mnt auto defaults,nofail,x-systemd.requires=cloud-init.service,comment=cloudconfig 0 2\n"
+	if err := os.WriteFile(path, []byte(initial), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The resource disk's cloud-init mount is dropped before the disk is formatted
+	if err := updateFstab([]string{"/mnt"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	entries := []fstabEntry{
+		{source: "UUID=1234", mountPoint: "/mnt/aks-data", fsType: "ext4", options: "defaults,nofail", pass: 2},
+		{source: "/mnt/aks-data/kubelet", mountPoint: "/var/lib/kubelet", fsType: "none", options: "bind,nofail"},
+	}
+	mountPoints := []string{"/mnt/aks-data", "/var/lib/kubelet"}
+	for range 2 {
+		if err := updateFstab(mountPoints, entries); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	want := "# /etc/fstab\nLABEL=cloudimg-rootfs / ext4 discard,errors=remount-ro 0 1\n" +
+		"UUID=1234 /mnt/aks-data ext4 defaults,nofail 0 2\n/mnt/aks-data/kubelet /var/lib/kubelet none bind,nofail 0 0\n"
+	if string(data) != want {
+		t.Errorf("fstab = %q, want %q", data, want)
+	}
+	if !hasFstabEntries(entries) || !hasAnyFstabEntry(mountPoints) {
+		t.Errorf("fstab entries not found")
+	}
+
+	if err := updateFstab(mountPoints, nil); err != nil {
+		t.Fatal(err)
+	}
+	if hasAnyFstabEntry(mountPoints) {
+		t.Errorf("fstab entries left after removing them")
+	}
+}
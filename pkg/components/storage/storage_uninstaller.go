@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// UnInstaller unmounts the data disk and removes its fstab entries. It runs before the containerd and kubelet
+// cleanup, which then removes the directories left on the OS disk; their state on the data disk is removed here.
+// The disk itself keeps its partition, so a later bootstrap reuses it.
+type UnInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new data disk UnInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "StorageUnInstaller"
+}
+
+// Execute unmounts the state directories, removes their state from the disk, unmounts the disk and removes the
+// fstab entries so nothing is mounted again on boot
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	i := &Installer{config: u.config, logger: u.logger}
+	mountPoints := i.mountPoints()
+	diskMountPoint := mountPoints[0]
+	if !isDataDiskSetUp(diskMountPoint) {
+		return nil
+	}
+	// Bind mounts first, with anything kubelet left mounted below them
+	for _, mountPoint := range mountPoints[1:] {
+		if err := u.unmount(mountPoint); err != nil {
+			return err
+		}
+	}
+	if utilhost.IsMountPoint(diskMountPoint) {
+		var stateDirs []string
+		for name := range i.dataDirs() {
+			stateDirs = append(stateDirs, filepath.Join(diskMountPoint, name))
+		}
+		if errs := utils.RemoveDirectories(stateDirs, u.logger); len(errs) > 0 {
+			u.logger.Warnf("Failed to remove state from the data disk: %v", errs)
+		}
+	}
+	if err := u.unmount(diskMountPoint); err != nil {
+		return err
+	}
+	if err := updateFstab(mountPoints, nil); err != nil {
+		return err
+	}
+	if err := os.Remove(u.config.Storage.DataDisk.MountPoint); err != nil && !os.IsNotExist(err) {
+		u.logger.Debugf("Failed to remove %s: %v", u.config.Storage.DataDisk.MountPoint, err)
+	}
+	return nil
+}
+
+// unmount unmounts mountPoint and everything mounted below it, if mounted
+func (u *UnInstaller) unmount(mountPoint string) error {
+	if !utilhost.IsMountPoint(mountPoint) {
+		return nil
+	}
+	u.logger.Infof("Unmounting %s", mountPoint)
+	if err := utils.RunSystemCommand("umount", "--recursive", mountPoint); err != nil {
+		return fmt.Errorf("failed to unmount %s: %w", mountPoint, err)
+	}
+	return nil
+}
+
+// IsCompleted reports whether the data disk is neither mounted nor in fstab
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	i := &Installer{config: u.config, logger: u.logger}
+	return !isDataDiskSetUp(i.mountPoints()[0])
+}
+
+// isDataDiskSetUp reports whether the data disk is mounted or in fstab. The state directories are only unmounted
+// then, so mounts of them set up by other means are left alone.
+func isDataDiskSetUp(diskMountPoint string) bool {
+	return utilhost.IsMountPoint(diskMountPoint) || hasAnyFstabEntry([]string{diskMountPoint})
+}
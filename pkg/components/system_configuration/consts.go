@@ -1,8 +1,137 @@
 package system_configuration
 
+import "time"
+
 const (
 	// Configuration file paths
 	sysctlConfigPath = "/etc/sysctl.d/999-sysctl-aks.conf"
 	resolvConfPath   = "/etc/resolv.conf"
 	resolvConfSource = "/run/systemd/resolve/resolv.conf"
+
+	// Core dump configuration paths
+	coreDumpSysctlPath      = "/etc/sysctl.d/50-aks-flex-node-coredump.conf"
+	coreDumpConfigDir       = "/etc/systemd/coredump.conf.d"
+	coreDumpConfigPath      = "/etc/systemd/coredump.conf.d/99-aks-flex-node.conf"
+	kubeletCoreDumpDropIn   = "/etc/systemd/system/kubelet.service.d/20-coredump.conf"
+	systemdCoreDumpBinary   = "/lib/systemd/systemd-coredump"
+	systemdCoreDumpPackage  = "systemd-coredump"
+	apportService           = "apport"
+	coreDumpUnitLimitConfig = `[Service]
+LimitCORE=infinity
+`
+
+	// containerSELinuxPackage holds the SELinux policy of container runtimes and kubelet on RHEL
+	containerSELinuxPackage = "container-selinux"
+
+	// Time synchronization: chrony on RHEL, systemd-timesyncd on Ubuntu
+	chronyPackage    = "chrony"
+	chronydService   = "chronyd"
+	timesyncdService = "systemd-timesyncd"
+)
+
+// timeSyncServices are the services that keep the clock synchronized, any of which is enough
+var timeSyncServices = []string{chronydService, "chrony", timesyncdService, "ntpd", "ntp", "ntpsec"}
+
+// kubernetesSysctlConfig holds the sysctl settings every node needs, written to sysctlConfigPath
+const kubernetesSysctlConfig = `# Kubernetes sysctl settings
+net.bridge.bridge-nf-call-iptables = 1
+net.bridge.bridge-nf-call-ip6tables = 1
+net.ipv4.ip_forward = 1
+vm.overcommit_memory = 1
+kernel.panic = 10
+kernel.panic_on_oops = 1`
+
+const (
+	// hostSnapshotKey is the state key of the sysctl values, swap devices and kernel modules found before the
+	// first bootstrap, which unbootstrap restores
+	hostSnapshotKey = "host-snapshot"
+)
+
+// Kernel state files, variables so tests can point them at temporary files
+var (
+	procSysDir      = "/proc/sys"
+	procModulesPath = "/proc/modules"
+)
+
+const (
+	// hugepagesSysctlPath reserves the 2Mi hugepages, early at boot before memory fragments
+	hugepagesSysctlPath = "/etc/sysctl.d/60-aks-flex-node-hugepages.conf"
+
+	// hugepagesKernelArgsName names the kernel arguments reserving the 1Gi hugepages
+	hugepagesKernelArgsName = "hugepages"
+
+	// hugepagesRebootPath records the hugepages and the boot a reboot was scheduled from, so a node that still
+	// lacks pages after rebooting fails instead of rebooting again
+	hugepagesRebootPath = "/var/lib/aks-flex-node/hugepages-reboot"
+
+	// hugepagesCapacityTimeout bounds the wait for kubelet to report the hugepages capacity after its restart
+	hugepagesCapacityTimeout = 2 * time.Minute
+)
+
+// Hugepage pools and the boot ID, variables so tests can point them at temporary files
+var (
+	hugepagesDir = "/sys/kernel/mm/hugepages"
+	bootIDPath   = "/proc/sys/kernel/random/boot_id"
 )
+
+const (
+	// tuningKernelArgsName names the kernel arguments isolating CPUs for the realtime tuning profile
+	tuningKernelArgsName = "tuning"
+
+	// tuningRebootPath records the tuning a reboot was scheduled for, like hugepagesRebootPath
+	tuningRebootPath = "/var/lib/aks-flex-node/tuning-reboot"
+
+	// irqbalanceDropInPath keeps irqbalance from moving interrupts onto the isolated CPUs
+	irqbalanceService    = "irqbalance"
+	irqbalanceDropInPath = "/etc/systemd/system/irqbalance.service.d/20-aks-flex-node-isolation.conf"
+
+	// governorScriptPath sets the performance CPU frequency governor on every boot, run by governorServiceName
+	// before kubelet starts. governorStatePath keeps the governor found before, which unbootstrap restores.
+	governorScriptPath  = "/etc/aks-flex-node/cpu-governor.sh"
+	governorServiceName = "aks-flex-node-cpu-governor"
+	governorServicePath = "/etc/systemd/system/aks-flex-node-cpu-governor.service"
+	governorStatePath   = "/var/lib/aks-flex-node/cpu-governor"
+	governorPerformance = "performance"
+)
+
+// cpufreqDir holds a directory per CPU frequency policy, a variable so tests can point it at a temporary directory
+var cpufreqDir = "/sys/devices/system/cpu/cpufreq"
+
+var governorScript = `#!/bin/sh
+# Managed by aks-flex-node: run every CPU at its highest frequency for the realtime tuning profile
+for governor in ` + cpufreqDir + `/policy*/scaling_governor; do
+	[ -w "$governor" ] && echo ` + governorPerformance + ` > "$governor"
+done
+exit 0
+`
+
+var governorServiceUnit = `[Unit]
+Description=Set the performance CPU frequency governor
+Before=kubelet.service
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/bin/sh ` + governorScriptPath + `
+
+[Install]
+WantedBy=multi-user.target
+`
+
+const (
+	// firewallStatePath records the host firewall rules added during bootstrap
+	firewallStatePath = "/etc/aks-flex-node/firewall-rules.json"
+
+	// firewallRuleComment marks the nftables rules added by the agent
+	firewallRuleComment = "aks-flex-node"
+
+	// cniBridgeInterface is the bridge pod traffic enters the host through
+	cniBridgeInterface = "cni0"
+)
+
+// defaultFirewallPorts are the inbound ports every node opens: kubelet, NodePort services and VXLAN overlays
+var defaultFirewallPorts = []string{"10250/tcp", "30000-32767/tcp", "30000-32767/udp", "8472/udp"}
+
+// nftTable is the family and name of the table holding the host's input and forward chains, as set up by the
+// nftables packages of Debian, Ubuntu and RHEL
+var nftTable = [2]string{"inet", "filter"}
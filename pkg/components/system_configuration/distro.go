@@ -0,0 +1,55 @@
+package system_configuration
+
+import (
+	"fmt"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// configureSELinux installs the container-selinux policy on RHEL hosts running SELinux. The policy gives
+// containerd, kubelet and containers their types, so SELinux can stay in enforcing mode.
+func (i *Installer) configureSELinux(distro utilhost.Distro) error {
+	mode := utilhost.SELinuxMode()
+	if mode == utilhost.SELinuxDisabled || distro.Family() != utilhost.DistroFamilyRHEL || utilhost.PackageInstalled(containerSELinuxPackage) {
+		return nil
+	}
+	i.logger.Infof("SELinux is %s, installing %s", mode, containerSELinuxPackage)
+	if err := utilhost.InstallPackages(containerSELinuxPackage); err != nil {
+		return fmt.Errorf("failed to install %s: %w", containerSELinuxPackage, err)
+	}
+	return nil
+}
+
+// isSELinuxConfigured reports whether the container-selinux policy is installed where it is needed
+func isSELinuxConfigured(distro utilhost.Distro) bool {
+	return utilhost.SELinuxMode() == utilhost.SELinuxDisabled || distro.Family() != utilhost.DistroFamilyRHEL ||
+		utilhost.PackageInstalled(containerSELinuxPackage)
+}
+
+// configureTimeSync starts a time synchronization service when none runs, since certificates and tokens are
+// checked against the clock: chronyd on RHEL, which does not ship systemd-timesyncd, and systemd-timesyncd
+// elsewhere. A failure is only logged, since the node can still join with a clock that is right for now.
+func (i *Installer) configureTimeSync(distro utilhost.Distro) {
+	for _, service := range timeSyncServices {
+		if utils.IsServiceActive(service) {
+			return
+		}
+	}
+
+	service := timesyncdService
+	if distro.Family() == utilhost.DistroFamilyRHEL {
+		service = chronydService
+		if !utilhost.PackageInstalled(chronyPackage) {
+			i.logger.Infof("Installing %s...", chronyPackage)
+			if err := utilhost.InstallPackages(chronyPackage); err != nil {
+				i.logger.WithError(err).Warnf("Failed to install %s, the clock is not synchronized", chronyPackage)
+				return
+			}
+		}
+	}
+	i.logger.Infof("No time synchronization service is running, starting %s", service)
+	if err := utils.EnableAndStartService(service); err != nil {
+		i.logger.WithError(err).Warnf("Failed to start %s, the clock is not synchronized", service)
+	}
+}
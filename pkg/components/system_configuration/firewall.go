@@ -0,0 +1,241 @@
+package system_configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// firewallRule opens an inbound port range ("30000-32767/udp") or trusts an interface ("cni0")
+type firewallRule struct {
+	Port      string `json:"port,omitempty"`
+	Interface string `json:"interface,omitempty"`
+}
+
+func (r firewallRule) String() string {
+	if r.Interface != "" {
+		return "interface " + r.Interface
+	}
+	return r.Port
+}
+
+// firewallState records the rules added to the host firewall, so unbootstrap removes exactly those even when
+// the configuration changed in between
+type firewallState struct {
+	Backend string         `json:"backend"`
+	Rules   []firewallRule `json:"rules"`
+}
+
+// firewallRules returns the rules a node needs: kubelet, NodePort services, VXLAN overlays, pod traffic on the
+// CNI bridge and the configured extra ports
+func firewallRules(cfg *config.FirewallConfig) []firewallRule {
+	rules := make([]firewallRule, 0, len(defaultFirewallPorts)+len(cfg.ExtraPorts)+1)
+	for _, port := range append(append([]string{}, defaultFirewallPorts...), cfg.ExtraPorts...) {
+		rules = append(rules, firewallRule{Port: port})
+	}
+	return append(rules, firewallRule{Interface: cniBridgeInterface})
+}
+
+// detectFirewallBackend returns the active host firewall, or an empty string when none is active
+func detectFirewallBackend() string {
+	if output, err := utils.RunCommandWithOutput("ufw", "status"); err == nil && strings.Contains(output, "Status: active") {
+		return config.FirewallBackendUFW
+	}
+	if output, err := utils.RunCommandWithOutput("firewall-cmd", "--state"); err == nil && strings.TrimSpace(output) == "running" {
+		return config.FirewallBackendFirewalld
+	}
+	if _, err := utils.RunCommandWithOutput("nft", "list", "chain", nftTable[0], nftTable[1], "input"); err == nil {
+		return config.FirewallBackendNftables
+	}
+	return ""
+}
+
+// configureFirewall opens the node's ports in the host firewall, replacing the rules of a previous bootstrap
+func (i *Installer) configureFirewall() error {
+	backend := i.config.System.Firewall.Backend
+	if backend == "" {
+		backend = detectFirewallBackend()
+	}
+	if backend == "" {
+		i.logger.Info("No active host firewall found, skipping firewall rules")
+		return nil
+	}
+
+	if previous, err := readFirewallState(); err == nil {
+		removeFirewallRules(previous, i.logger)
+	}
+
+	state := firewallState{Backend: backend}
+	for _, rule := range firewallRules(&i.config.System.Firewall) {
+		if err := addFirewallRule(backend, rule); err != nil {
+			return fmt.Errorf("failed to open %s in %s: %w", rule, backend, err)
+		}
+		state.Rules = append(state.Rules, rule)
+	}
+	if err := reloadFirewall(backend); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utilio.MkdirAll(filepath.Dir(firewallStatePath), 0o755); err != nil {
+		return err
+	}
+	if err := utilio.WriteFile(firewallStatePath, data, 0o644); err != nil {
+		return err
+	}
+	i.logger.Infof("Opened %d firewall rules in %s", len(state.Rules), backend)
+	return nil
+}
+
+// isFirewallConfigured reports whether exactly the given rules were added. nftables rules live only in the
+// running ruleset, so they are looked up there, since a reboot drops them.
+func isFirewallConfigured(rules []firewallRule) bool {
+	state, err := readFirewallState()
+	if err != nil || !slices.Equal(state.Rules, rules) {
+		return false
+	}
+	if state.Backend != config.FirewallBackendNftables {
+		return true
+	}
+	output, err := utils.RunCommandWithOutput("nft", "list", "chain", nftTable[0], nftTable[1], "input")
+	return err == nil && strings.Contains(output, firewallRuleComment)
+}
+
+// readFirewallState reads the rules added by the last bootstrap
+func readFirewallState() (*firewallState, error) {
+	data, err := os.ReadFile(firewallStatePath)
+	if err != nil {
+		return nil, err
+	}
+	var state firewallState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", firewallStatePath, err)
+	}
+	return &state, nil
+}
+
+// removeFirewallRules removes recorded rules and the state file. Failures are logged, so that a rule removed by
+// hand does not block the rest.
+func removeFirewallRules(state *firewallState, logger *logrus.Logger) {
+	if state.Backend == config.FirewallBackendNftables {
+		if err := removeNftablesRules(); err != nil {
+			logger.Warnf("Failed to remove nftables rules: %v", err)
+		}
+	} else {
+		for _, rule := range state.Rules {
+			if err := removeFirewallRule(state.Backend, rule); err != nil {
+				logger.Warnf("Failed to remove firewall rule %s from %s: %v", rule, state.Backend, err)
+			}
+		}
+		if err := reloadFirewall(state.Backend); err != nil {
+			logger.Warnf("Failed to reload %s: %v", state.Backend, err)
+		}
+	}
+	if err := utils.RunCleanupCommand(firewallStatePath); err != nil {
+		logger.Warnf("Failed to remove %s: %v", firewallStatePath, err)
+	}
+}
+
+// firewallCommands returns the commands adding (or with remove, deleting) a rule in a backend
+func firewallCommands(backend string, rule firewallRule, remove bool) [][]string {
+	switch backend {
+	case config.FirewallBackendUFW:
+		// ufw deletes a rule when given the same rule after "delete"
+		var prefix []string
+		if remove {
+			prefix = []string{"delete"}
+		}
+		if rule.Interface != "" {
+			return [][]string{
+				append(append([]string{"ufw"}, prefix...), "allow", "in", "on", rule.Interface),
+				append(append([]string{"ufw", "route"}, prefix...), "allow", "in", "on", rule.Interface),
+			}
+		}
+		return [][]string{append(append([]string{"ufw"}, prefix...), "allow", strings.Replace(rule.Port, "-", ":", 1))}
+	case config.FirewallBackendFirewalld:
+		action := "--add-"
+		if remove {
+			action = "--remove-"
+		}
+		if rule.Interface != "" {
+			return [][]string{{"firewall-cmd", "--permanent", "--zone=trusted", action + "interface=" + rule.Interface}}
+		}
+		return [][]string{{"firewall-cmd", "--permanent", action + "port=" + rule.Port}}
+	case config.FirewallBackendNftables:
+		comment := []string{"comment", `"` + firewallRuleComment + `"`}
+		add := []string{"nft", "add", "rule", nftTable[0], nftTable[1]}
+		if rule.Interface != "" {
+			return [][]string{
+				append(append(append([]string{}, add...), "input", "iifname", rule.Interface, "accept"), comment...),
+				append(append(append([]string{}, add...), "forward", "iifname", rule.Interface, "accept"), comment...),
+				append(append(append([]string{}, add...), "forward", "oifname", rule.Interface, "accept"), comment...),
+			}
+		}
+		port, protocol, _ := strings.Cut(rule.Port, "/")
+		return [][]string{append(append(append([]string{}, add...), "input", protocol, "dport", port, "accept"), comment...)}
+	}
+	return nil
+}
+
+// addFirewallRule adds a rule to the backend
+func addFirewallRule(backend string, rule firewallRule) error {
+	for _, command := range firewallCommands(backend, rule, false) {
+		if output, err := utils.RunCommandWithOutput(command[0], command[1:]...); err != nil {
+			return fmt.Errorf("%s: %w, output: %s", strings.Join(command, " "), err, output)
+		}
+	}
+	return nil
+}
+
+// removeFirewallRule removes a rule from a ufw or firewalld backend
+func removeFirewallRule(backend string, rule firewallRule) error {
+	for _, command := range firewallCommands(backend, rule, true) {
+		if output, err := utils.RunCommandWithOutput(command[0], command[1:]...); err != nil {
+			return fmt.Errorf("%s: %w, output: %s", strings.Join(command, " "), err, output)
+		}
+	}
+	return nil
+}
+
+// reloadFirewall applies permanent firewalld rules; ufw and nftables apply rules immediately
+func reloadFirewall(backend string) error {
+	if backend != config.FirewallBackendFirewalld {
+		return nil
+	}
+	if output, err := utils.RunCommandWithOutput("firewall-cmd", "--reload"); err != nil {
+		return fmt.Errorf("failed to reload firewalld: %w, output: %s", err, output)
+	}
+	return nil
+}
+
+// nftRuleHandle matches the handle of a rule added by the agent in "nft -a list chain" output
+var nftRuleHandle = regexp.MustCompile(`comment "` + firewallRuleComment + `" # handle (\d+)`)
+
+// removeNftablesRules deletes the rules carrying the agent's comment from the input and forward chains
+func removeNftablesRules() error {
+	for _, chain := range []string{"input", "forward"} {
+		output, err := utils.RunCommandWithOutput("nft", "-a", "list", "chain", nftTable[0], nftTable[1], chain)
+		if err != nil {
+			continue
+		}
+		for _, match := range nftRuleHandle.FindAllStringSubmatch(output, -1) {
+			if output, err := utils.RunCommandWithOutput("nft", "delete", "rule", nftTable[0], nftTable[1], chain, "handle", match[1]); err != nil {
+				return fmt.Errorf("failed to delete nftables rule %s: %w, output: %s", match[1], err, output)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package system_configuration
+
+import (
+	"reflect"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestFirewallCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		rule    firewallRule
+		remove  bool
+		want    [][]string
+	}{
+		{
+			name:    "ufw port range",
+			backend: config.FirewallBackendUFW,
+			rule:    firewallRule{Port: "30000-32767/udp"},
+			want:    [][]string{{"ufw", "allow", "30000:32767/udp"}},
+		},
+		{
+			name:    "ufw interface removal",
+			backend: config.FirewallBackendUFW,
+			rule:    firewallRule{Interface: "cni0"},
+			remove:  true,
+			want: [][]string{
+				{"ufw", "delete", "allow", "in", "on", "cni0"},
+				{"ufw", "route", "delete", "allow", "in", "on", "cni0"},
+			},
+		},
+		{
+			name:    "firewalld port",
+			backend: config.FirewallBackendFirewalld,
+			rule:    firewallRule{Port: "10250/tcp"},
+			want:    [][]string{{"firewall-cmd", "--permanent", "--add-port=10250/tcp"}},
+		},
+		{
+			name:    "nftables port",
+			backend: config.FirewallBackendNftables,
+			rule:    firewallRule{Port: "8472/udp"},
+			want:    [][]string{{"nft", "add", "rule", "inet", "filter", "input", "udp", "dport", "8472", "accept", "comment", `"aks-flex-node"`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firewallCommands(tt.backend, tt.rule, tt.remove); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("firewallCommands() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirewallRules(t *testing.T) {
+	rules := firewallRules(&config.FirewallConfig{ExtraPorts: []string{"179/tcp"}})
+	if len(rules) != len(defaultFirewallPorts)+2 {
+		t.Fatalf("firewallRules() = %v", rules)
+	}
+	if rules[len(defaultFirewallPorts)].Port != "179/tcp" || rules[len(rules)-1].Interface != cniBridgeInterface {
+		t.Errorf("firewallRules() = %v, want the extra port and the CNI bridge last", rules)
+	}
+}
+
+func TestNftRuleHandle(t *testing.T) {
+	output := `table inet filter {
+	chain input {
+		type filter hook input priority filter; policy drop;
+		ct state established,related accept # handle 4
+		tcp dport 10250 accept comment "aks-flex-node" # handle 12
+		udp dport 30000-32767 accept comment "aks-flex-node" # handle 13
+	}
+}`
+	matches := nftRuleHandle.FindAllStringSubmatch(output, -1)
+	if len(matches) != 2 || matches[0][1] != "12" || matches[1][1] != "13" {
+		t.Errorf("nftRuleHandle matches = %v, want handles 12 and 13", matches)
+	}
+}
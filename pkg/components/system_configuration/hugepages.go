@@ -0,0 +1,260 @@
+package system_configuration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// hugepagePool is a hugepage size, with the sysfs pool holding its pages and the resource kubelet reports
+type hugepagePool struct {
+	pool     string
+	resource string
+	size     int64
+}
+
+var (
+	hugepages2Mi = hugepagePool{pool: "hugepages-2048kB", resource: "hugepages-2Mi", size: 2 << 20}
+	hugepages1Gi = hugepagePool{pool: "hugepages-1048576kB", resource: "hugepages-1Gi", size: 1 << 30}
+)
+
+// isHugepagesConfigured reports whether system.hugepages reserves any pages
+func isHugepagesConfigured(cfg *config.HugepagesConfig) bool {
+	return cfg.Pages2Mi > 0 || cfg.Pages1Gi > 0
+}
+
+// configureHugepages reserves the 2Mi pages with sysctl and the 1Gi pages on the kernel command line. When the
+// kernel cannot reserve them all without a reboot, it reboots the node if system.hugepages.reboot allows it, at
+// most once for the same configuration, and fails either way so bootstrap resumes after the reboot.
+func (i *Installer) configureHugepages() error {
+	hugepages := &i.config.System.Hugepages
+	if hugepages.Pages2Mi > 0 {
+		sysctl := fmt.Sprintf("# Hugepages managed by aks-flex-node\nvm.nr_hugepages = %d\n", hugepages.Pages2Mi)
+		if err := utilio.WriteFile(hugepagesSysctlPath, []byte(sysctl), 0o644); err != nil {
+			return err
+		}
+		if err := utils.RunSystemCommand("sysctl", "-p", hugepagesSysctlPath); err != nil {
+			return fmt.Errorf("failed to reserve 2Mi hugepages: %w", err)
+		}
+	} else if utils.FileExists(hugepagesSysctlPath) {
+		// Release the pages reserved before, which only frees those pods do not use
+		if err := utils.RunCleanupCommand(hugepagesSysctlPath); err != nil {
+			return err
+		}
+		if err := utils.RunSystemCommand("sysctl", "vm.nr_hugepages=0"); err != nil {
+			i.logger.WithError(err).Warn("Failed to release 2Mi hugepages")
+		}
+	}
+	if err := utilhost.SetKernelArgs(hugepagesKernelArgsName, hugepagesKernelArgs(hugepages)); err != nil {
+		return err
+	}
+
+	shortfall := hugepagesShortfall(hugepages)
+	if len(shortfall) == 0 {
+		clearReboot(hugepagesRebootPath, i.logger)
+		i.logger.Infof("Reserved %d 2Mi and %d 1Gi hugepages", hugepages.Pages2Mi, hugepages.Pages1Gi)
+		return nil
+	}
+	missing := strings.Join(shortfall, ", ")
+	if !hugepages.Reboot {
+		return fmt.Errorf("the kernel reserved %s; reboot the node, or set system.hugepages.reboot to let bootstrap do it", missing)
+	}
+	scheduled, err := scheduleReboot(hugepagesRebootPath, hugepages, "reserve hugepages")
+	if err != nil {
+		return err
+	}
+	if !scheduled {
+		return fmt.Errorf("the kernel reserved %s even after rebooting; the node may not have enough memory", missing)
+	}
+	return fmt.Errorf("the kernel reserved %s; the node reboots in a minute to reserve the rest and bootstrap resumes afterwards", missing)
+}
+
+// isHugepagesApplied reports whether the configured hugepages are reserved, or their settings removed when none
+// are configured
+func (i *Installer) isHugepagesApplied() bool {
+	hugepages := &i.config.System.Hugepages
+	if !isHugepagesConfigured(hugepages) {
+		return !utils.FileExists(hugepagesSysctlPath) && len(utilhost.KernelArgs(hugepagesKernelArgsName)) == 0
+	}
+	if (hugepages.Pages2Mi > 0) != utils.FileExists(hugepagesSysctlPath) {
+		return false
+	}
+	if strings.Join(utilhost.KernelArgs(hugepagesKernelArgsName), " ") != strings.Join(hugepagesKernelArgs(hugepages), " ") {
+		return false
+	}
+	return len(hugepagesShortfall(hugepages)) == 0
+}
+
+// hugepagesKernelArgs returns the kernel arguments reserving the 1Gi pages. The default hugepage size stays
+// 2Mi, so vm.nr_hugepages keeps counting 2Mi pages.
+func hugepagesKernelArgs(cfg *config.HugepagesConfig) []string {
+	if cfg.Pages1Gi == 0 {
+		return nil
+	}
+	return []string{"hugepagesz=1G", "hugepages=" + strconv.Itoa(cfg.Pages1Gi)}
+}
+
+// hugepagesShortfall describes the pools holding fewer pages than configured, e.g. "256 of 512 2Mi pages"
+func hugepagesShortfall(cfg *config.HugepagesConfig) []string {
+	var shortfall []string
+	for _, want := range []struct {
+		pool  hugepagePool
+		count int
+		name  string
+	}{{hugepages2Mi, cfg.Pages2Mi, "2Mi"}, {hugepages1Gi, cfg.Pages1Gi, "1Gi"}} {
+		if want.count == 0 {
+			continue
+		}
+		if reserved := reservedHugepages(want.pool); reserved < want.count {
+			shortfall = append(shortfall, fmt.Sprintf("%d of %d %s pages", reserved, want.count, want.name))
+		}
+	}
+	return shortfall
+}
+
+// reservedHugepages returns the number of pages in a hugepage pool, 0 when the kernel has no such pool
+func reservedHugepages(pool hugepagePool) int {
+	data, err := os.ReadFile(filepath.Join(hugepagesDir, pool.pool, "nr_hugepages"))
+	if err != nil {
+		return 0
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// removeHugepages removes the hugepages settings and releases the 2Mi pages pods do not use. The 1Gi pages stay
+// reserved until the next reboot.
+func removeHugepages(logger *logrus.Logger) {
+	hadSysctl := utils.FileExists(hugepagesSysctlPath)
+	for _, err := range utils.RemoveFiles([]string{hugepagesSysctlPath, hugepagesRebootPath}, logger) {
+		logger.Warnf("Failed to remove hugepages file: %v", err)
+	}
+	if hadSysctl {
+		if err := utils.RunSystemCommand("sysctl", "vm.nr_hugepages=0"); err != nil {
+			logger.WithError(err).Warn("Failed to release 2Mi hugepages")
+		}
+	}
+	if len(utilhost.KernelArgs(hugepagesKernelArgsName)) > 0 {
+		if err := utilhost.RemoveKernelArgs(hugepagesKernelArgsName); err != nil {
+			logger.WithError(err).Warn("Failed to remove hugepages kernel arguments")
+		} else {
+			logger.Info("Hugepages kernel arguments removed; the 1Gi pages stay reserved until the next reboot")
+		}
+	}
+}
+
+// HugepagesVerifier checks that kubelet reports the configured hugepages as node capacity. kubelet only reads
+// the hugepage pools when it starts, so it is restarted when the capacity is stale.
+type HugepagesVerifier struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewHugepagesVerifier creates a new HugepagesVerifier
+func NewHugepagesVerifier(logger *logrus.Logger) *HugepagesVerifier {
+	return &HugepagesVerifier{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (v *HugepagesVerifier) GetName() string {
+	return "HugepagesCapacityVerified"
+}
+
+// Validate has no preconditions beyond kubelet running, which the services step ensures
+func (v *HugepagesVerifier) Validate(ctx context.Context) error {
+	return nil
+}
+
+// Execute restarts kubelet and waits for the node to report the hugepages capacity
+func (v *HugepagesVerifier) Execute(ctx context.Context) error {
+	v.logger.Info("Restarting kubelet to report the hugepages capacity")
+	if err := utils.RestartService("kubelet"); err != nil {
+		return fmt.Errorf("failed to restart kubelet: %w", err)
+	}
+	deadline := time.Now().Add(hugepagesCapacityTimeout)
+	for {
+		mismatch, err := v.capacityMismatch()
+		if err == nil && mismatch == "" {
+			v.logger.Info("kubelet reports the configured hugepages capacity")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("failed to read the node's hugepages capacity: %w", err)
+			}
+			return fmt.Errorf("kubelet reports %s", mismatch)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// IsCompleted reports whether no hugepages are configured or the node already reports them
+func (v *HugepagesVerifier) IsCompleted(ctx context.Context) bool {
+	if !isHugepagesConfigured(&v.config.System.Hugepages) {
+		return true
+	}
+	mismatch, err := v.capacityMismatch()
+	return err == nil && mismatch == ""
+}
+
+// capacityMismatch describes the hugepage resources whose capacity differs from the configuration, or returns
+// an empty string when they all match
+func (v *HugepagesVerifier) capacityMismatch() (string, error) {
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		return "", err
+	}
+	output, err := utils.RunCommandWithOutput("kubectl", "--kubeconfig", kubelet.KubeletKubeconfigPath,
+		"get", "node", nodeName, "--output", "jsonpath={.status.capacity}")
+	if err != nil {
+		return "", fmt.Errorf("%w, output: %s", err, strings.TrimSpace(output))
+	}
+	var capacity map[string]string
+	if err := json.Unmarshal([]byte(output), &capacity); err != nil {
+		return "", fmt.Errorf("failed to parse the node capacity %q: %w", output, err)
+	}
+	return hugepagesCapacityMismatch(&v.config.System.Hugepages, capacity), nil
+}
+
+// hugepagesCapacityMismatch compares the hugepage resources of a node capacity with the configured pages
+func hugepagesCapacityMismatch(cfg *config.HugepagesConfig, capacity map[string]string) string {
+	var mismatches []string
+	for _, want := range []struct {
+		pool  hugepagePool
+		count int
+	}{{hugepages2Mi, cfg.Pages2Mi}, {hugepages1Gi, cfg.Pages1Gi}} {
+		if want.count == 0 {
+			continue
+		}
+		expected := resource.NewQuantity(int64(want.count)*want.pool.size, resource.BinarySI)
+		reported, err := resource.ParseQuantity(capacity[want.pool.resource])
+		if err != nil || reported.Cmp(*expected) != 0 {
+			mismatches = append(mismatches, fmt.Sprintf("%s %q instead of %s", want.pool.resource, capacity[want.pool.resource], expected.String()))
+		}
+	}
+	return strings.Join(mismatches, ", ")
+}
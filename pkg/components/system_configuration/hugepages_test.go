@@ -0,0 +1,54 @@
+package system_configuration
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestHugepagesShortfall(t *testing.T) {
+	dir := t.TempDir()
+	hugepagesDir = dir
+	t.Cleanup(func() { hugepagesDir = "/sys/kernel/mm/hugepages" })
+
+	for pool, count := range map[string]string{"hugepages-2048kB": "256\n", "hugepages-1048576kB": "8\n"} {
+		if err := os.MkdirAll(filepath.Join(dir, pool), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, pool, "nr_hugepages"), []byte(count), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := hugepagesShortfall(&config.HugepagesConfig{Pages2Mi: 256, Pages1Gi: 8}); len(got) != 0 {
+		t.Errorf("hugepagesShortfall() = %v, want none", got)
+	}
+	want := []string{"256 of 512 2Mi pages"}
+	if got := hugepagesShortfall(&config.HugepagesConfig{Pages2Mi: 512, Pages1Gi: 4}); !reflect.DeepEqual(got, want) {
+		t.Errorf("hugepagesShortfall() = %v, want %v", got, want)
+	}
+}
+
+func TestHugepagesKernelArgs(t *testing.T) {
+	if got := hugepagesKernelArgs(&config.HugepagesConfig{Pages2Mi: 512}); got != nil {
+		t.Errorf("hugepagesKernelArgs() without 1Gi pages = %v, want none", got)
+	}
+	want := []string{"hugepagesz=1G", "hugepages=16"}
+	if got := hugepagesKernelArgs(&config.HugepagesConfig{Pages1Gi: 16}); !reflect.DeepEqual(got, want) {
+		t.Errorf("hugepagesKernelArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestHugepagesCapacityMismatch(t *testing.T) {
+	cfg := &config.HugepagesConfig{Pages2Mi: 512, Pages1Gi: 8}
+	if got := hugepagesCapacityMismatch(cfg, map[string]string{"hugepages-2Mi": "1Gi", "hugepages-1Gi": "8Gi", "cpu": "64"}); got != "" {
+		t.Errorf("hugepagesCapacityMismatch() = %q, want no mismatch", got)
+	}
+	want := `hugepages-1Gi "0" instead of 8Gi`
+	if got := hugepagesCapacityMismatch(cfg, map[string]string{"hugepages-2Mi": "1073741824", "hugepages-1Gi": "0"}); got != want {
+		t.Errorf("hugepagesCapacityMismatch() = %q, want %q", got, want)
+	}
+}
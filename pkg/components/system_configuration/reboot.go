@@ -0,0 +1,75 @@
+package system_configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// rebootRecord is the content of a reboot record: the settings a reboot was scheduled for and the boot it was
+// scheduled from
+type rebootRecord struct {
+	Settings json.RawMessage `json:"settings"`
+	BootID   string          `json:"bootID"`
+}
+
+// scheduleReboot reboots the node in a minute to apply settings, recording them at path. It returns false
+// without rebooting when the record shows the node already rebooted for the same settings, so a setting the
+// kernel still does not apply fails instead of rebooting the node over and over.
+func scheduleReboot(path string, settings any, reason string) (bool, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode the reboot record: %w", err)
+	}
+	bootID := currentBootID()
+	if record, err := readRebootRecord(path); err == nil && string(record.Settings) == string(data) && record.BootID != bootID {
+		return false, nil
+	}
+	record, err := json.Marshal(rebootRecord{Settings: data, BootID: bootID})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode the reboot record: %w", err)
+	}
+	if err := utilio.WriteFile(path, record, 0o644); err != nil {
+		return false, err
+	}
+	// The delay lets the agent record the outcome of this bootstrap
+	if err := utils.RunSystemCommand("shutdown", "--reboot", "+1", "aks-flex-node: rebooting to "+reason); err != nil {
+		return false, fmt.Errorf("failed to schedule a reboot to %s: %w", reason, err)
+	}
+	return true, nil
+}
+
+// clearReboot removes the reboot record at path once its settings apply
+func clearReboot(path string, logger *logrus.Logger) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.WithError(err).Warnf("Failed to remove the reboot record %s", path)
+	}
+}
+
+// currentBootID returns the ID of the running boot
+func currentBootID() string {
+	data, err := os.ReadFile(bootIDPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readRebootRecord reads the record of the last reboot scheduled for some settings
+func readRebootRecord(path string) (*rebootRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var record rebootRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
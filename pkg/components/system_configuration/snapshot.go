@@ -0,0 +1,308 @@
+package system_configuration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/installed_files"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/state"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// hostSnapshot is the state of the host before bootstrap changed it. Files the agent overwrites are kept by the
+// installed files manifest instead.
+type hostSnapshot struct {
+	TakenAt time.Time         `json:"takenAt"`
+	Sysctl  map[string]string `json:"sysctl"`  // Values of the sysctl keys the agent sets, empty for keys that did not exist
+	Swap    []string          `json:"swap"`    // Active swap devices
+	Modules []string          `json:"modules"` // Loaded kernel modules
+}
+
+// Snapshotter records the sysctl values, swap devices and kernel modules of the host before the first
+// bootstrap changes them, so unbootstrap can put them back instead of resetting them to defaults
+type Snapshotter struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewSnapshotter creates a new host Snapshotter
+func NewSnapshotter(logger *logrus.Logger) *Snapshotter {
+	return &Snapshotter{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (s *Snapshotter) GetName() string {
+	return "HostSnapshotTaken"
+}
+
+// Validate has no preconditions
+func (s *Snapshotter) Validate(ctx context.Context) error {
+	return nil
+}
+
+// IsCompleted reports whether the snapshot holds every sysctl key the configuration sets
+func (s *Snapshotter) IsCompleted(ctx context.Context) bool {
+	snapshot, err := readHostSnapshot(s.config, s.logger)
+	if err != nil || snapshot == nil {
+		return false
+	}
+	for _, key := range s.sysctlKeys() {
+		if _, ok := snapshot.Sysctl[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Execute takes the snapshot. A later bootstrap only adds the sysctl keys a configuration change introduced,
+// since the rest of the host no longer is in its original state.
+func (s *Snapshotter) Execute(ctx context.Context) error {
+	snapshot, err := readHostSnapshot(s.config, s.logger)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		swap, err := utilhost.SwapDevices()
+		if err != nil {
+			return err
+		}
+		modules, err := loadedModules()
+		if err != nil {
+			return err
+		}
+		snapshot = &hostSnapshot{TakenAt: time.Now().UTC(), Sysctl: map[string]string{}, Swap: swap, Modules: modules}
+	}
+	for _, key := range s.sysctlKeys() {
+		if _, ok := snapshot.Sysctl[key]; !ok {
+			snapshot.Sysctl[key] = readSysctl(key)
+		}
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode host snapshot: %w", err)
+	}
+	store, err := state.Open(s.config, s.logger)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+	if err := store.Put(hostSnapshotKey, data); err != nil {
+		return fmt.Errorf("failed to record the host snapshot: %w", err)
+	}
+	s.logger.Infof("Recorded %d sysctl values, %d swap devices and %d kernel modules in the state store",
+		len(snapshot.Sysctl), len(snapshot.Swap), len(snapshot.Modules))
+	return nil
+}
+
+// sysctlKeys returns the sysctl keys bootstrap sets with the configuration
+func (s *Snapshotter) sysctlKeys() []string {
+	keys := sysctlConfigKeys(kubernetesSysctlConfig)
+	if s.config.IsDualStack() {
+		if dualStack, err := (&Installer{config: s.config}).dualStackSysctl(); err == nil {
+			keys = append(keys, sysctlConfigKeys(dualStack)...)
+		}
+	}
+	return append(keys, "kernel.core_pattern", "fs.suid_dumpable", "vm.nr_hugepages")
+}
+
+// SnapshotRestorer puts back the sysctl values, swap devices and kernel modules of the snapshot. It runs after the
+// component uninstallers removed the agent's sysctl files, modules-load files and fstab entries.
+type SnapshotRestorer struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewSnapshotRestorer creates a new host SnapshotRestorer
+func NewSnapshotRestorer(logger *logrus.Logger) *SnapshotRestorer {
+	return &SnapshotRestorer{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (r *SnapshotRestorer) GetName() string {
+	return "HostSnapshotRestored"
+}
+
+// IsCompleted reports whether there is no snapshot left to restore
+func (r *SnapshotRestorer) IsCompleted(ctx context.Context) bool {
+	snapshot, err := readHostSnapshot(r.config, r.logger)
+	return err == nil && snapshot == nil
+}
+
+// Execute restores the snapshot and removes it
+func (r *SnapshotRestorer) Execute(ctx context.Context) error {
+	snapshot, err := readHostSnapshot(r.config, r.logger)
+	if err != nil || snapshot == nil {
+		return err
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(snapshot.Sysctl)) {
+		original := snapshot.Sysctl[key]
+		if original == "" || readSysctl(key) == original {
+			continue
+		}
+		if err := writeSysctl(key, original); err != nil {
+			r.logger.Warnf("Failed to restore %s: %v", key, err)
+			continue
+		}
+		r.logger.Infof("Restored %s = %s", key, original)
+	}
+
+	active, err := utilhost.SwapDevices()
+	if err != nil {
+		r.logger.Warnf("Failed to read the active swap devices: %v", err)
+	}
+	swapOn, swapOff := diffLists(snapshot.Swap, active)
+	for _, device := range swapOn {
+		if err := utils.RunSystemCommand("swapon", device); err != nil {
+			r.logger.Warnf("Failed to turn swap device %s back on: %v", device, err)
+		}
+	}
+	for _, device := range swapOff {
+		if err := utils.RunSystemCommand("swapoff", device); err != nil {
+			r.logger.Warnf("Failed to turn swap device %s off: %v", device, err)
+		}
+	}
+
+	// Modules the agent loaded since the snapshot are unloaded; modprobe refuses modules that are still in use
+	loaded, err := loadedModules()
+	if err != nil {
+		r.logger.Warnf("Failed to read the loaded kernel modules: %v", err)
+	}
+	for _, module := range modulesToUnload(snapshot.Modules, loaded, installed_files.LoadedModules()) {
+		if err := utils.RunSystemCommand("modprobe", "--remove", module); err != nil {
+			r.logger.Debugf("Kept kernel module %s loaded: %v", module, err)
+		}
+	}
+
+	store, err := state.Open(r.config, r.logger)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+	if err := store.Delete(hostSnapshotKey); err != nil {
+		return fmt.Errorf("failed to remove the host snapshot: %w", err)
+	}
+	r.logger.Infof("Restored the host state recorded at %s", snapshot.TakenAt.Format(time.RFC3339))
+	return nil
+}
+
+// modulesToUnload returns the modules loaded since the snapshot that the agent loaded itself, leaving those
+// loaded by the host, such as by udev or another service, in place. /proc/modules names modules with
+// underscores where modprobe also takes dashes.
+func modulesToUnload(snapshotted, loaded, agentLoaded []string) []string {
+	_, added := diffLists(snapshotted, loaded)
+	var unload []string
+	for _, module := range added {
+		if slices.ContainsFunc(agentLoaded, func(name string) bool { return strings.ReplaceAll(name, "-", "_") == module }) {
+			unload = append(unload, module)
+		}
+	}
+	return unload
+}
+
+// readHostSnapshot returns the snapshot recorded in the state store, or nil when none was taken
+func readHostSnapshot(cfg *config.Config, logger *logrus.Logger) (*hostSnapshot, error) {
+	store, err := state.Open(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = store.Close() }()
+
+	data, err := store.Get(hostSnapshotKey)
+	if errors.Is(err, state.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the host snapshot: %w", err)
+	}
+	snapshot := &hostSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode the host snapshot: %w", err)
+	}
+	if snapshot.Sysctl == nil {
+		snapshot.Sysctl = map[string]string{}
+	}
+	return snapshot, nil
+}
+
+// sysctlConfigKeys returns the keys set by a sysctl configuration file
+func sysctlConfigKeys(content string) []string {
+	var keys []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if key, _, found := strings.Cut(line, "="); found {
+			keys = append(keys, strings.TrimSpace(key))
+		}
+	}
+	return keys
+}
+
+// sysctlPath returns the /proc/sys file of a sysctl key
+func sysctlPath(key string) string {
+	return filepath.Join(procSysDir, strings.ReplaceAll(key, ".", "/"))
+}
+
+// readSysctl returns the value of a sysctl key, or an empty string when the kernel does not have it
+func readSysctl(key string) string {
+	data, err := os.ReadFile(sysctlPath(key))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// writeSysctl sets a sysctl key
+func writeSysctl(key, value string) error {
+	return os.WriteFile(sysctlPath(key), []byte(value+"\n"), 0o644)
+}
+
+// loadedModules returns the names of the loaded kernel modules
+func loadedModules() ([]string, error) {
+	data, err := os.ReadFile(procModulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", procModulesPath, err)
+	}
+	var modules []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			modules = append(modules, fields[0])
+		}
+	}
+	return modules, nil
+}
+
+// diffLists returns the entries of original that current lacks, and the entries of current original lacks
+func diffLists(original, current []string) (missing, added []string) {
+	for _, entry := range original {
+		if !slices.Contains(current, entry) {
+			missing = append(missing, entry)
+		}
+	}
+	for _, entry := range current {
+		if !slices.Contains(original, entry) {
+			added = append(added, entry)
+		}
+	}
+	return missing, added
+}
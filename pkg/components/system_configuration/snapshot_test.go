@@ -0,0 +1,118 @@
+package system_configuration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/state"
+)
+
+func TestSysctlConfigKeys(t *testing.T) {
+	want := []string{
+		"net.bridge.bridge-nf-call-iptables", "net.bridge.bridge-nf-call-ip6tables", "net.ipv4.ip_forward",
+		"vm.overcommit_memory", "kernel.panic", "kernel.panic_on_oops",
+	}
+	if got := sysctlConfigKeys(kubernetesSysctlConfig); !reflect.DeepEqual(got, want) {
+		t.Errorf("sysctlConfigKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestSysctlValues(t *testing.T) {
+	procSysDir = t.TempDir()
+	t.Cleanup(func() { procSysDir = "/proc/sys" })
+	if err := os.MkdirAll(filepath.Join(procSysDir, "net", "ipv4"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(procSysDir, "net", "ipv4", "ip_forward"), []byte("0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readSysctl("net.ipv4.ip_forward"); got != "0" {
+		t.Errorf("readSysctl() = %q, want %q", got, "0")
+	}
+	if got := readSysctl("net.bridge.bridge-nf-call-iptables"); got != "" {
+		t.Errorf("readSysctl() of a missing key = %q, want empty", got)
+	}
+	if err := writeSysctl("net.ipv4.ip_forward", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readSysctl("net.ipv4.ip_forward"); got != "1" {
+		t.Errorf("readSysctl() after writeSysctl() = %q, want %q", got, "1")
+	}
+}
+
+func TestLoadedModules(t *testing.T) {
+	procModulesPath = filepath.Join(t.TempDir(), "modules")
+	t.Cleanup(func() { procModulesPath = "/proc/modules" })
+	modules := "br_netfilter 32768 0 - Live 0x0000000000000000\noverlay 151552 12 - Live 0x0000000000000000\n"
+	if err := os.WriteFile(procModulesPath, []byte(modules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadedModules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"br_netfilter", "overlay"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("loadedModules() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffLists(t *testing.T) {
+	missing, added := diffLists([]string{"/swap.img", "overlay"}, []string{"overlay", "br_netfilter"})
+	if !reflect.DeepEqual(missing, []string{"/swap.img"}) || !reflect.DeepEqual(added, []string{"br_netfilter"}) {
+		t.Errorf("diffLists() = %v, %v", missing, added)
+	}
+}
+
+func TestModulesToUnload(t *testing.T) {
+	snapshotted := []string{"overlay", "kvm"}
+	loaded := []string{"overlay", "kvm", "kvm_intel", "br_netfilter", "nvidia"}
+	unload := modulesToUnload(snapshotted, loaded, []string{"br_netfilter", "kvm-intel", "overlay", "ip_vs"})
+	if !reflect.DeepEqual(unload, []string{"kvm_intel", "br_netfilter"}) {
+		t.Errorf("modulesToUnload() = %v, want only the modules the agent loaded since the snapshot", unload)
+	}
+	if unload := modulesToUnload(snapshotted, loaded, nil); unload != nil {
+		t.Errorf("modulesToUnload() = %v without modules recorded by the agent", unload)
+	}
+}
+
+func TestHostSnapshotInStateStore(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agent.StateStore = config.StateStoreConfig{Type: config.StateStoreTypeFile, Path: t.TempDir()}
+	logger := logrus.New()
+	restorer := &SnapshotRestorer{config: cfg, logger: logger}
+
+	if snapshot, err := readHostSnapshot(cfg, logger); err != nil || snapshot != nil {
+		t.Fatalf("readHostSnapshot() = %v, %v, want no snapshot", snapshot, err)
+	}
+	if !restorer.IsCompleted(context.Background()) {
+		t.Error("IsCompleted() = false without a snapshot")
+	}
+
+	store, err := state.Open(cfg, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(hostSnapshotKey, []byte(`{"swap": ["/swap.img"], "modules": ["overlay"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	snapshot, err := readHostSnapshot(cfg, logger)
+	if err != nil || snapshot == nil {
+		t.Fatalf("readHostSnapshot() = %v, %v, want the recorded snapshot", snapshot, err)
+	}
+	if !reflect.DeepEqual(snapshot.Swap, []string{"/swap.img"}) || snapshot.Sysctl == nil {
+		t.Errorf("readHostSnapshot() = %+v, want the swap device and an empty sysctl map", snapshot)
+	}
+	if restorer.IsCompleted(context.Background()) {
+		t.Error("IsCompleted() = true with a snapshot left to restore")
+	}
+}
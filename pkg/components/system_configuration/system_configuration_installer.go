@@ -3,10 +3,13 @@ package system_configuration
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
 )
 
@@ -28,6 +31,16 @@ func NewInstaller(logger *logrus.Logger) *Installer {
 func (i *Installer) Execute(ctx context.Context) error {
 	i.logger.Info("Configuring system settings")
 
+	// Distribution-specific settings are skipped on unknown distributions
+	distro, err := utilhost.DetectDistro()
+	if err != nil {
+		i.logger.WithError(err).Warn("Failed to detect the distribution")
+	}
+	if err := i.configureSELinux(distro); err != nil {
+		return fmt.Errorf("failed to configure SELinux: %w", err)
+	}
+	i.configureTimeSync(distro)
+
 	// Configure sysctl settings
 	if err := i.configureSysctl(); err != nil {
 		return fmt.Errorf("failed to configure sysctl settings: %w", err)
@@ -38,16 +51,73 @@ func (i *Installer) Execute(ctx context.Context) error {
 		return fmt.Errorf("failed to configure resolv.conf: %w", err)
 	}
 
+	// Configure crash dump collection for node daemons
+	if i.config.System.CoreDump.Enabled {
+		if err := i.configureCoreDump(); err != nil {
+			return fmt.Errorf("failed to configure core dumps: %w", err)
+		}
+	}
+
+	// Reserve hugepages, or release those reserved before; this may reboot the node
+	if isHugepagesConfigured(&i.config.System.Hugepages) || !i.isHugepagesApplied() {
+		if err := i.configureHugepages(); err != nil {
+			return fmt.Errorf("failed to configure hugepages: %w", err)
+		}
+	}
+
+	// Apply the tuning profile, or revert the one applied before; this may reboot the node
+	if isTuningConfigured(&i.config.System.Tuning) || !i.isTuningApplied() {
+		if err := i.configureTuning(); err != nil {
+			return fmt.Errorf("failed to apply the tuning profile: %w", err)
+		}
+	}
+
+	// Open the node's ports in the host firewall
+	if i.config.System.Firewall.Manage {
+		if err := i.configureFirewall(); err != nil {
+			return fmt.Errorf("failed to configure host firewall: %w", err)
+		}
+	}
+
 	i.logger.Info("System configuration completed successfully")
 	return nil
 }
 
 // IsCompleted checks if system configuration has been applied
 func (i *Installer) IsCompleted(ctx context.Context) bool {
+	if i.config.System.CoreDump.Enabled && !utils.FileExists(coreDumpConfigPath) {
+		return false
+	}
+	if firewall := &i.config.System.Firewall; firewall.Manage && (firewall.Backend != "" || detectFirewallBackend() != "") &&
+		!isFirewallConfigured(firewallRules(firewall)) {
+		return false
+	}
+	if !i.isHugepagesApplied() || !i.isTuningApplied() {
+		return false
+	}
+	if distro, err := utilhost.DetectDistro(); err == nil && !isSELinuxConfigured(distro) {
+		return false
+	}
 	return utils.FileExists(sysctlConfigPath) &&
 		utils.FileExists(resolvConfPath)
 }
 
+// dualStackSysctl enables IPv6 forwarding for dual-stack pods. Interfaces that forward ignore router
+// advertisements unless accept_ra is 2, so the node interface keeps its SLAAC address and default route.
+func (i *Installer) dualStackSysctl() (string, error) {
+	nodeIPs, err := i.config.GetNodeIPs()
+	if err != nil {
+		return "", err
+	}
+	config := "\nnet.ipv6.conf.all.forwarding = 1"
+	for _, nodeIP := range nodeIPs {
+		if nodeIP.IP.To4() == nil {
+			config += fmt.Sprintf("\nnet.ipv6.conf.%s.accept_ra = 2", nodeIP.Interface)
+		}
+	}
+	return config, nil
+}
+
 // Validate validates the system configuration installation
 func (i *Installer) Validate(ctx context.Context) error {
 	return nil
@@ -57,17 +127,21 @@ func (i *Installer) Validate(ctx context.Context) error {
 func (i *Installer) configureSysctl() error {
 	// Disable swap immediately - kubelet sees no active swap devices
 	// so it can start successfully. This is a critical step for kubelet compatibility.
-	if err := i.disableSwap(); err != nil {
+	// With NodeSwap, kubelet starts with swap on and limits which pods use it instead.
+	if i.config.Node.Kubelet.SwapEnabled() {
+		i.enableSwap()
+	} else if err := i.disableSwap(); err != nil {
 		return fmt.Errorf("failed to disable swap: %w", err)
 	}
 
-	sysctlConfig := `# Kubernetes sysctl settings
-net.bridge.bridge-nf-call-iptables = 1
-net.bridge.bridge-nf-call-ip6tables = 1
-net.ipv4.ip_forward = 1
-vm.overcommit_memory = 1
-kernel.panic = 10
-kernel.panic_on_oops = 1`
+	sysctlConfig := kubernetesSysctlConfig
+	if i.config.IsDualStack() {
+		ipv6Config, err := i.dualStackSysctl()
+		if err != nil {
+			return err
+		}
+		sysctlConfig += ipv6Config
+	}
 
 	if err := utilio.WriteFile(sysctlConfigPath, []byte(sysctlConfig), 0644); err != nil {
 		return err
@@ -97,6 +171,95 @@ func (i *Installer) configureResolvConf() error {
 	return nil
 }
 
+// coreDumpSysctlConfig renders the core dump sysctl file. Setuid dumps stay at the host setting unless enabled,
+// since their cores can hold the secrets of privileged processes.
+func coreDumpSysctlConfig(coreDump *config.CoreDumpConfig) string {
+	sysctlConfig := fmt.Sprintf(`# Core dump settings managed by aks-flex-node
+kernel.core_pattern = %s
+`, coreDump.CorePattern)
+	if coreDump.SetuidDumps {
+		sysctlConfig += "fs.suid_dumpable = 2\n"
+	}
+	return sysctlConfig
+}
+
+// configureCoreDump routes kernel core dumps to systemd-coredump with bounded storage so that
+// crashes of containerd and kubelet can be inspected (and collected into diagnostics bundles)
+func (i *Installer) configureCoreDump() error {
+	coreDump := i.config.System.CoreDump
+	i.logger.Info("Configuring core dump collection for node daemons")
+
+	if strings.HasPrefix(coreDump.CorePattern, "|"+systemdCoreDumpBinary) && !utils.FileExists(systemdCoreDumpBinary) {
+		i.logger.Infof("Installing %s...", systemdCoreDumpPackage)
+		if err := utilhost.InstallPackages(systemdCoreDumpPackage); err != nil {
+			return fmt.Errorf("failed to install %s: %w", systemdCoreDumpPackage, err)
+		}
+	}
+
+	// apport rewrites kernel.core_pattern when it starts, which would silently undo our setting
+	if utils.ServiceExists(apportService) {
+		i.logger.Info("Disabling apport so it does not override kernel.core_pattern")
+		if err := utils.StopService(apportService); err != nil {
+			i.logger.WithError(err).Warn("Failed to stop apport")
+		}
+		if err := utils.DisableService(apportService); err != nil {
+			i.logger.WithError(err).Warn("Failed to disable apport")
+		}
+	}
+
+	// A plain file pattern needs its target directory to exist before the kernel can write cores
+	if !strings.HasPrefix(coreDump.CorePattern, "|") && strings.Contains(coreDump.CorePattern, "/") {
+		if err := utilio.MkdirAll(filepath.Dir(coreDump.CorePattern), 0o755); err != nil {
+			return fmt.Errorf("failed to create core dump directory: %w", err)
+		}
+	}
+
+	if err := utilio.WriteFile(coreDumpSysctlPath, []byte(coreDumpSysctlConfig(&coreDump)), 0644); err != nil {
+		return err
+	}
+
+	if err := utilio.MkdirAll(coreDumpConfigDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", coreDumpConfigDir, err)
+	}
+	coreDumpConfig := fmt.Sprintf(`# systemd-coredump settings managed by aks-flex-node
+[Coredump]
+Storage=%s
+Compress=yes
+ProcessSizeMax=%s
+ExternalSizeMax=%s
+MaxUse=%s
+`, coreDump.Storage, coreDump.ProcessSizeMax, coreDump.ExternalSizeMax, coreDump.MaxUse)
+	if err := utilio.WriteFile(coreDumpConfigPath, []byte(coreDumpConfig), 0644); err != nil {
+		return err
+	}
+
+	// containerd already runs with LimitCORE=infinity; give kubelet the same limit
+	if err := utilio.MkdirAll(filepath.Dir(kubeletCoreDumpDropIn), 0o755); err != nil {
+		return fmt.Errorf("failed to create kubelet drop-in directory: %w", err)
+	}
+	if err := utilio.WriteFile(kubeletCoreDumpDropIn, []byte(coreDumpUnitLimitConfig), 0644); err != nil {
+		return err
+	}
+
+	if err := utils.RunSystemCommand("sysctl", "-p", coreDumpSysctlPath); err != nil {
+		return fmt.Errorf("failed to apply core dump sysctl settings: %w", err)
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+
+	i.logger.Infof("Core dumps configured with pattern %q and storage %s", coreDump.CorePattern, coreDump.Storage)
+	return nil
+}
+
+// enableSwap turns on the swap devices of /etc/fstab, which an earlier bootstrap without NodeSwap turned off
+func (i *Installer) enableSwap() {
+	i.logger.Infof("Keeping swap on for kubelet's NodeSwap with swap behavior %s", i.config.Node.Kubelet.SwapBehavior)
+	if err := utils.RunSystemCommand("swapon", "-a"); err != nil {
+		i.logger.WithError(err).Warning("Failed to turn on the swap devices of /etc/fstab")
+	}
+}
+
 // disableSwap disables swap immediately for kubelet compatibility
 func (i *Installer) disableSwap() error {
 	i.logger.Info("Disabling swap for kubelet compatibility")
@@ -0,0 +1,30 @@
+package system_configuration
+
+import (
+	"strings"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestCoreDumpSysctlConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		coreDump   config.CoreDumpConfig
+		wantSetuid bool
+	}{
+		{name: "setuid dumps off by default", coreDump: config.CoreDumpConfig{CorePattern: "|/usr/lib/systemd/systemd-coredump %P %u %g %s %t %c %h"}},
+		{name: "setuid dumps enabled", coreDump: config.CoreDumpConfig{CorePattern: "/var/crash/core.%e.%p", SetuidDumps: true}, wantSetuid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := coreDumpSysctlConfig(&tt.coreDump)
+			if !strings.Contains(got, "kernel.core_pattern = "+tt.coreDump.CorePattern+"\n") {
+				t.Errorf("sysctl config %q does not set kernel.core_pattern", got)
+			}
+			if setuid := strings.Contains(got, "fs.suid_dumpable = 2\n"); setuid != tt.wantSetuid {
+				t.Errorf("sysctl config %q sets fs.suid_dumpable = 2: %v, want %v", got, setuid, tt.wantSetuid)
+			}
+		})
+	}
+}
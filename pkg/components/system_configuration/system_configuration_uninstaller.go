@@ -6,6 +6,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
 )
 
 // UnInstaller handles system configuration cleanup
@@ -41,6 +42,21 @@ func (su *UnInstaller) Execute(ctx context.Context) error {
 		su.logger.WithError(err).Warn("Failed to cleanup resolv.conf configuration")
 	}
 
+	// Remove core dump configuration
+	su.cleanupCoreDumpConfig()
+
+	// Release hugepages and remove their kernel arguments
+	removeHugepages(su.logger)
+
+	// Revert the tuning profile
+	removeTuning(su.logger)
+
+	// Close the ports opened in the host firewall
+	if state, err := readFirewallState(); err == nil {
+		removeFirewallRules(state, su.logger)
+		su.logger.Infof("Removed firewall rules from %s", state.Backend)
+	}
+
 	// Reload sysctl to apply changes
 	if err := utils.RunSystemCommand("sysctl", "--system"); err != nil {
 		su.logger.WithError(err).Warn("Failed to reload sysctl settings")
@@ -53,7 +69,8 @@ func (su *UnInstaller) Execute(ctx context.Context) error {
 // IsCompleted checks if system configuration has been removed
 func (su *UnInstaller) IsCompleted(ctx context.Context) bool {
 	// Check if sysctl config exists
-	if utils.FileExists(sysctlConfigPath) {
+	if utils.FileExists(sysctlConfigPath) || utils.FileExists(coreDumpConfigPath) || utils.FileExists(firewallStatePath) ||
+		utils.FileExists(hugepagesSysctlPath) || len(utilhost.KernelArgs(hugepagesKernelArgsName)) > 0 || isTuningInstalled() {
 		return false
 	}
 	// Note: We don't check resolv.conf as it may have been restored to original state
@@ -72,6 +89,15 @@ func (su *UnInstaller) cleanupSysctlConfig() error {
 	return nil
 }
 
+// cleanupCoreDumpConfig removes the core dump configuration written during bootstrap.
+// Cores already stored by systemd-coredump are left in place for later inspection.
+func (su *UnInstaller) cleanupCoreDumpConfig() {
+	files := []string{coreDumpSysctlPath, coreDumpConfigPath, kubeletCoreDumpDropIn}
+	if errs := utils.RemoveFiles(files, su.logger); len(errs) > 0 {
+		su.logger.Warnf("Failed to remove some core dump configuration files: %v", errs)
+	}
+}
+
 // cleanupResolvConf restores original resolv.conf configuration
 func (su *UnInstaller) cleanupResolvConf() error {
 	// Check if resolv.conf is a symlink to systemd-resolved that we created
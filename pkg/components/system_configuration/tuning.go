@@ -0,0 +1,262 @@
+package system_configuration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// isTuningConfigured reports whether system.tuning selects a profile
+func isTuningConfigured(cfg *config.TuningConfig) bool {
+	return cfg.Profile == config.TuningProfileRealtime
+}
+
+// configureTuning applies the realtime tuning profile, or reverts it when no profile is configured. It keeps
+// irqbalance off the isolated CPUs, sets the performance CPU governor and isolates the CPUs on the kernel command
+// line. The kernel arguments need a reboot, which is handled like that of system.hugepages.
+func (i *Installer) configureTuning() error {
+	tuning := &i.config.System.Tuning
+	if !isTuningConfigured(tuning) {
+		removeTuning(i.logger)
+		return nil
+	}
+	isolated, reserved, err := i.tuningCPUs()
+	if err != nil {
+		return err
+	}
+
+	if utils.ServiceExists(irqbalanceService) {
+		if err := utilio.WriteFile(irqbalanceDropInPath, []byte(irqbalanceDropIn(isolated)), 0o644); err != nil {
+			return err
+		}
+		if err := utils.ReloadSystemd(); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
+		if utils.IsServiceActive(irqbalanceService) {
+			if err := utils.RestartService(irqbalanceService); err != nil {
+				return fmt.Errorf("failed to restart irqbalance: %w", err)
+			}
+		}
+	}
+
+	if err := i.configureGovernor(); err != nil {
+		return err
+	}
+
+	args := tuningKernelArgs(isolated, reserved)
+	if err := utilhost.SetKernelArgs(tuningKernelArgsName, args); err != nil {
+		return err
+	}
+	active, err := utilhost.KernelArgsActive(args)
+	if err != nil {
+		return err
+	}
+	if active {
+		clearReboot(tuningRebootPath, i.logger)
+		i.logger.Infof("Applied the realtime tuning profile, isolating CPUs %s", isolated)
+		return nil
+	}
+	if !tuning.Reboot {
+		return fmt.Errorf("the kernel was not booted with %s; reboot the node, or set system.tuning.reboot to let bootstrap do it", strings.Join(args, " "))
+	}
+	scheduled, err := scheduleReboot(tuningRebootPath, args, "isolate CPUs")
+	if err != nil {
+		return err
+	}
+	if !scheduled {
+		return fmt.Errorf("the kernel was not booted with %s even after rebooting; check the boot loader configuration", strings.Join(args, " "))
+	}
+	return fmt.Errorf("the node reboots in a minute to isolate CPUs %s and bootstrap resumes afterwards", isolated)
+}
+
+// isTuningApplied reports whether the configured profile is in effect, or reverted when none is configured
+func (i *Installer) isTuningApplied() bool {
+	tuning := &i.config.System.Tuning
+	if !isTuningConfigured(tuning) {
+		return !isTuningInstalled()
+	}
+	isolated, reserved, err := i.tuningCPUs()
+	if err != nil {
+		return false
+	}
+	if utils.ServiceExists(irqbalanceService) && !utils.FileHasContent(irqbalanceDropInPath, irqbalanceDropIn(isolated)) {
+		return false
+	}
+	if !utils.FileHasContent(governorScriptPath, governorScript) || !utils.FileHasContent(governorServicePath, governorServiceUnit) {
+		return false
+	}
+	args := tuningKernelArgs(isolated, reserved)
+	if !slices.Equal(utilhost.KernelArgs(tuningKernelArgsName), args) {
+		return false
+	}
+	active, err := utilhost.KernelArgsActive(args)
+	return err == nil && active
+}
+
+// tuningCPUs returns the CPU lists isolated for pods and reserved for system daemons and interrupts
+func (i *Installer) tuningCPUs() (string, string, error) {
+	online, err := utilhost.OnlineCPUs()
+	if err != nil {
+		return "", "", err
+	}
+	return isolatedCPUs(&i.config.System.Tuning, i.config.Node.Kubelet.ReservedSystemCPUs, online)
+}
+
+// isolatedCPUs returns the CPUs to isolate, system.tuning.isolatedCPUs or else the online CPUs kubelet does not
+// reserve, along with the reserved CPUs. Both come back as normalized CPU lists.
+func isolatedCPUs(cfg *config.TuningConfig, reservedCPUs string, online []int) (string, string, error) {
+	reserved, err := utilhost.ParseCPUList(reservedCPUs)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid node.kubelet.reservedSystemCPUs: %w", err)
+	}
+	var isolated []int
+	if cfg.IsolatedCPUs != "" {
+		if isolated, err = utilhost.ParseCPUList(cfg.IsolatedCPUs); err != nil {
+			return "", "", fmt.Errorf("invalid system.tuning.isolatedCPUs: %w", err)
+		}
+	} else {
+		for _, cpu := range online {
+			if !slices.Contains(reserved, cpu) {
+				isolated = append(isolated, cpu)
+			}
+		}
+	}
+	for _, cpus := range [][]int{isolated, reserved} {
+		for _, cpu := range cpus {
+			if !slices.Contains(online, cpu) {
+				return "", "", fmt.Errorf("CPU %d is not online; the node has CPUs %s", cpu, utilhost.FormatCPUList(online))
+			}
+		}
+	}
+	if len(isolated) == 0 {
+		return "", "", fmt.Errorf("node.kubelet.reservedSystemCPUs %s leaves no CPU to isolate", reservedCPUs)
+	}
+	return utilhost.FormatCPUList(isolated), utilhost.FormatCPUList(reserved), nil
+}
+
+// tuningKernelArgs returns the kernel arguments isolating CPUs from timer ticks, RCU callbacks and managed
+// interrupts, and steering the other interrupts to the reserved CPUs. Scheduler domains are not isolated, so
+// pods in kubelet's shared pool are still balanced across the isolated CPUs.
+func tuningKernelArgs(isolated, reserved string) []string {
+	return []string{
+		"isolcpus=managed_irq," + isolated,
+		"nohz_full=" + isolated,
+		"rcu_nocbs=" + isolated,
+		"irqaffinity=" + reserved,
+	}
+}
+
+// irqbalanceDropIn renders the irqbalance unit drop-in banning the isolated CPUs
+func irqbalanceDropIn(isolated string) string {
+	return fmt.Sprintf("# Managed by aks-flex-node: keep interrupts off the isolated CPUs\n[Service]\nEnvironment=IRQBALANCE_BANNED_CPULIST=%s\n", isolated)
+}
+
+// configureGovernor installs the boot service setting the performance CPU governor and runs it, after recording
+// the governor in use so unbootstrap can restore it
+func (i *Installer) configureGovernor() error {
+	if !utils.FileExists(governorStatePath) {
+		if governor := currentGovernor(); governor != "" && governor != governorPerformance {
+			if err := utilio.WriteFile(governorStatePath, []byte(governor+"\n"), 0o644); err != nil {
+				return err
+			}
+		}
+	}
+	if err := utilio.WriteFile(governorScriptPath, []byte(governorScript), 0o755); err != nil {
+		return err
+	}
+	if err := utilio.WriteFile(governorServicePath, []byte(governorServiceUnit), 0o644); err != nil {
+		return err
+	}
+	if err := utils.ReloadSystemd(); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := utils.RunSystemCommand("systemctl", "enable", governorServiceName); err != nil {
+		return fmt.Errorf("failed to enable %s: %w", governorServiceName, err)
+	}
+	if err := utils.RestartService(governorServiceName); err != nil {
+		return fmt.Errorf("failed to set the performance CPU governor: %w", err)
+	}
+	if currentGovernor() == "" {
+		i.logger.Info("The kernel exposes no CPU frequency scaling; the hypervisor or firmware controls the CPU frequency")
+	}
+	return nil
+}
+
+// currentGovernor returns the CPU frequency governor of the first policy, or an empty string without
+// frequency scaling, as on most VMs
+func currentGovernor() string {
+	policies, _ := filepath.Glob(filepath.Join(cpufreqDir, "policy*", "scaling_governor"))
+	if len(policies) == 0 {
+		return ""
+	}
+	data, err := os.ReadFile(policies[0])
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// setGovernor sets the CPU frequency governor of every policy
+func setGovernor(governor string) error {
+	policies, _ := filepath.Glob(filepath.Join(cpufreqDir, "policy*", "scaling_governor"))
+	for _, policy := range policies {
+		if err := os.WriteFile(policy, []byte(governor), 0o644); err != nil {
+			return fmt.Errorf("failed to set the CPU governor %s: %w", governor, err)
+		}
+	}
+	return nil
+}
+
+// isTuningInstalled reports whether any setting of a tuning profile is present
+func isTuningInstalled() bool {
+	return len(utilhost.KernelArgs(tuningKernelArgsName)) > 0 || utils.FileExists(irqbalanceDropInPath) ||
+		utils.FileExists(governorServicePath) || utils.FileExists(governorScriptPath)
+}
+
+// removeTuning reverts the tuning profile: it removes the kernel arguments, lets irqbalance use every CPU again
+// and restores the CPU governor found before. The CPUs stay isolated until the next reboot.
+func removeTuning(logger *logrus.Logger) {
+	if utils.FileExists(governorServicePath) {
+		if err := utils.StopService(governorServiceName); err != nil {
+			logger.Debugf("Failed to stop %s: %v", governorServiceName, err)
+		}
+		if err := utils.DisableService(governorServiceName); err != nil {
+			logger.Debugf("Failed to disable %s: %v", governorServiceName, err)
+		}
+	}
+	if data, err := os.ReadFile(governorStatePath); err == nil {
+		if err := setGovernor(strings.TrimSpace(string(data))); err != nil {
+			logger.WithError(err).Warn("Failed to restore the CPU governor")
+		}
+	}
+	hadDropIn := utils.FileExists(irqbalanceDropInPath)
+	for _, err := range utils.RemoveFiles([]string{governorServicePath, governorScriptPath, governorStatePath, irqbalanceDropInPath, tuningRebootPath}, logger) {
+		logger.Warnf("Failed to remove tuning file: %v", err)
+	}
+	if hadDropIn {
+		if err := utils.ReloadSystemd(); err != nil {
+			logger.WithError(err).Warn("Failed to reload systemd")
+		}
+		if utils.IsServiceActive(irqbalanceService) {
+			if err := utils.RestartService(irqbalanceService); err != nil {
+				logger.WithError(err).Warn("Failed to restart irqbalance")
+			}
+		}
+	}
+	if len(utilhost.KernelArgs(tuningKernelArgsName)) > 0 {
+		if err := utilhost.RemoveKernelArgs(tuningKernelArgsName); err != nil {
+			logger.WithError(err).Warn("Failed to remove the tuning kernel arguments")
+		} else {
+			logger.Info("Tuning kernel arguments removed; the CPUs stay isolated until the next reboot")
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package system_configuration
+
+import (
+	"reflect"
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestIsolatedCPUs(t *testing.T) {
+	online := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	tests := []struct {
+		name         string
+		cfg          config.TuningConfig
+		reserved     string
+		wantIsolated string
+		wantReserved string
+		wantErr      bool
+	}{
+		{name: "all but reserved", reserved: "1,0", wantIsolated: "2-7", wantReserved: "0-1"},
+		{name: "configured", cfg: config.TuningConfig{IsolatedCPUs: "4-7"}, reserved: "0", wantIsolated: "4-7", wantReserved: "0"},
+		{name: "offline CPU", cfg: config.TuningConfig{IsolatedCPUs: "4-9"}, reserved: "0", wantErr: true},
+		{name: "nothing left", reserved: "0-7", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolated, reserved, err := isolatedCPUs(&tt.cfg, tt.reserved, online)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("isolatedCPUs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if isolated != tt.wantIsolated || reserved != tt.wantReserved {
+				t.Errorf("isolatedCPUs() = %q, %q, want %q, %q", isolated, reserved, tt.wantIsolated, tt.wantReserved)
+			}
+		})
+	}
+}
+
+func TestTuningKernelArgs(t *testing.T) {
+	want := []string{"isolcpus=managed_irq,2-7", "nohz_full=2-7", "rcu_nocbs=2-7", "irqaffinity=0-1"}
+	if got := tuningKernelArgs("2-7", "0-1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("tuningKernelArgs() = %v, want %v", got, want)
+	}
+}
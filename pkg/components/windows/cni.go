@@ -0,0 +1,130 @@
+package windows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// CNIInstaller installs the Windows CNI plugins and the win-bridge configuration attaching pods to an HNS
+// network
+type CNIInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewCNIInstaller creates a new Windows CNI installer
+func NewCNIInstaller(logger *logrus.Logger) *CNIInstaller {
+	return &CNIInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *CNIInstaller) GetName() string {
+	return "WindowsCNIInstaller"
+}
+
+// Validate checks that the configuration only uses what Windows nodes support
+func (i *CNIInstaller) Validate(ctx context.Context) error {
+	return validateWindowsConfig(i.config)
+}
+
+// Execute installs win-bridge and host-local and writes the network configuration
+func (i *CNIInstaller) Execute(ctx context.Context) error {
+	i.logger.Info("Setting up Windows CNI configuration")
+	if !i.pluginsInstalled() {
+		version := cniVersion(i.config)
+		url := fmt.Sprintf(cniDownloadURL, version, version)
+		if err := extractBinaries(ctx, url, "", cniBinDir, cniPlugins); err != nil {
+			return fmt.Errorf("failed to install CNI plugins: %w", err)
+		}
+	}
+
+	conf, err := winBridgeConfig(i.config)
+	if err != nil {
+		return err
+	}
+	if err := utilio.MkdirAll(cniConfDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", cniConfDir, err)
+	}
+	if err := utilio.WriteFile(cniConfigPath, conf, 0o644); err != nil {
+		return fmt.Errorf("failed to write CNI configuration: %w", err)
+	}
+	i.logger.Info("win-bridge CNI configuration created")
+	return nil
+}
+
+// IsCompleted checks that the plugins are installed and the configuration is current
+func (i *CNIInstaller) IsCompleted(ctx context.Context) bool {
+	conf, err := winBridgeConfig(i.config)
+	return err == nil && i.pluginsInstalled() && utils.FileHasContent(cniConfigPath, string(conf))
+}
+
+// pluginsInstalled reports whether every CNI plugin is installed
+func (i *CNIInstaller) pluginsInstalled() bool {
+	for _, plugin := range cniPlugins {
+		if !utils.FileExists(filepath.Join(cniBinDir, plugin)) {
+			return false
+		}
+	}
+	return true
+}
+
+// winBridgeConfig renders the win-bridge configuration. Pods get addresses from the node pod subnet and
+// reach other networks through outbound NAT, except for the pod and service ranges, which stay routed so
+// kube-proxy's load balancers apply.
+func winBridgeConfig(cfg *config.Config) ([]byte, error) {
+	exceptions := []string{podSubnet.subnet}
+	if cfg.KubeProxy.ClusterCIDR != "" {
+		exceptions = append(exceptions, cfg.KubeProxy.ClusterCIDR)
+	}
+	conf := map[string]any{
+		"cniVersion": cniSpecVersion,
+		"name":       hnsNetworkName,
+		"type":       "win-bridge",
+		"dns": map[string]any{
+			"Nameservers": []string{cfg.ClusterDNS()},
+			"Search":      []string{"svc.cluster.local"},
+		},
+		"ipam": map[string]any{
+			"type":   "host-local",
+			"subnet": podSubnet.subnet,
+			"routes": []any{map[string]any{"GW": podSubnet.gateway}},
+		},
+		"policies": []any{
+			map[string]any{
+				"name":  "EndpointPolicy",
+				"value": map[string]any{"Type": "OutBoundNAT", "ExceptionList": exceptions},
+			},
+			map[string]any{
+				"name":  "EndpointPolicy",
+				"value": map[string]any{"Type": "ROUTE", "DestinationPrefix": serviceCIDR(cfg), "NeedEncap": true},
+			},
+		},
+	}
+	data, err := json.MarshalIndent(conf, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the CNI configuration: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// serviceCIDR returns the /16 holding the cluster DNS service, which AKS takes from the service CIDR
+func serviceCIDR(cfg *config.Config) string {
+	ip := net.ParseIP(cfg.Node.Kubelet.DNSServiceIP).To4()
+	if ip == nil {
+		return "10.0.0.0/16"
+	}
+	network := net.IPNet{IP: ip.Mask(net.CIDRMask(16, 32)), Mask: net.CIDRMask(16, 32)}
+	return network.String()
+}
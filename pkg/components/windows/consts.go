@@ -0,0 +1,76 @@
+package windows
+
+const (
+	// Kubernetes binaries, configuration and credentials
+	kubernetesDir     = `C:\k`
+	kubeletPath       = `C:\k\kubelet.exe`
+	kubeProxyPath     = `C:\k\kube-proxy.exe`
+	kubectlPath       = `C:\k\kubectl.exe`
+	kubeletConfigPath = `C:\k\kubelet-config.yaml`
+	KubeconfigPath    = `C:\k\config`
+	tokenScriptPath   = `C:\k\token.ps1`
+	clientCAPath      = `C:\k\ca.crt`
+	kubeletRootDir    = `C:\var\lib\kubelet`
+	kubeletCertDir    = `C:\var\lib\kubelet\pki`
+
+	// containerd binaries in Program Files, its data and state in ProgramData
+	containerdDir        = `C:\Program Files\containerd`
+	containerdPath       = `C:\Program Files\containerd\containerd.exe`
+	containerdConfigPath = `C:\Program Files\containerd\config.toml`
+	containerdDataDir    = `C:\ProgramData\containerd\root`
+	containerdStateDir   = `C:\ProgramData\containerd\state`
+	containerdEndpoint   = "npipe:////./pipe/containerd-containerd"
+
+	// CNI plugins and configuration
+	cniBinDir     = `C:\opt\cni\bin`
+	cniConfDir    = `C:\etc\cni\net.d`
+	cniConfigPath = `C:\etc\cni\net.d\10-flexnode.conf`
+
+	// hnsNetworkName is the HNS network win-bridge attaches pods to and kube-proxy programs service policies on
+	hnsNetworkName = "flexnode"
+
+	// Windows services
+	containerdService = "containerd"
+	kubeletService    = "kubelet"
+	kubeProxyService  = "kube-proxy"
+
+	// firewallRuleGroup groups the inbound firewall rules bootstrap creates, so unbootstrap removes only those
+	firewallRuleGroup = "AKS Flex Node"
+
+	// aksServiceResourceID is the Azure AD application of AKS that kubelet tokens are issued for
+	aksServiceResourceID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+
+	// Versions used when the configuration does not set one
+	defaultContainerdVersion = "1.7.20"
+	defaultCNIVersion        = "1.5.1"
+
+	// cniSpecVersion is the CNI specification version of the win-bridge configuration
+	cniSpecVersion = "0.2.0"
+)
+
+var (
+	containerdDownloadURL        = "https://github.com/containerd/containerd/releases/download/v%s/containerd-%s-windows-amd64.tar.gz"
+	defaultKubernetesURLTemplate = "https://dl.k8s.io/v%s/kubernetes-node-windows-%s.tar.gz"
+	cniDownloadURL               = "https://github.com/containernetworking/plugins/releases/download/v%s/cni-plugins-windows-amd64-v%s.tgz"
+
+	// Archive prefixes of the binaries bootstrap installs
+	containerdTarPath = "bin/"
+	kubernetesTarPath = "kubernetes/node/bin/"
+)
+
+// kubeBinaries are the binaries installed from the Kubernetes node archive
+var kubeBinaries = []string{"kubelet.exe", "kube-proxy.exe", "kubectl.exe"}
+
+// cniPlugins are the plugins installed from the CNI release: win-bridge attaches pods to the HNS network and
+// host-local assigns their addresses
+var cniPlugins = []string{"win-bridge.exe", "host-local.exe"}
+
+// podSubnet is the address range win-bridge assigns pods from, with the first address as the gateway. It is
+// the range the Linux bridge configuration uses.
+var podSubnet = struct{ subnet, gateway string }{subnet: "10.244.0.0/16", gateway: "10.244.0.1"}
+
+// defenderExclusions keeps Microsoft Defender from scanning container layers and the node binaries
+var defenderExclusions = []string{kubernetesDir, containerdDir, `C:\ProgramData\containerd`, cniBinDir}
+
+// kubeletPorts are the inbound ports the cluster reaches the node on
+var kubeletPorts = []string{"10250/tcp"}
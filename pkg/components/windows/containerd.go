@@ -0,0 +1,142 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// ContainerdInstaller installs containerd for Windows and registers it as a Windows service
+type ContainerdInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewContainerdInstaller creates a new Windows containerd installer
+func NewContainerdInstaller(logger *logrus.Logger) *ContainerdInstaller {
+	return &ContainerdInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *ContainerdInstaller) GetName() string {
+	return "WindowsContainerdInstaller"
+}
+
+// Validate checks that the configuration only uses what Windows nodes support
+func (i *ContainerdInstaller) Validate(ctx context.Context) error {
+	return validateWindowsConfig(i.config)
+}
+
+// Execute downloads the containerd release, writes its configuration and registers the containerd service
+func (i *ContainerdInstaller) Execute(ctx context.Context) error {
+	version := containerdVersion(i.config)
+	i.logger.Infof("Installing containerd %s for Windows", version)
+	if !i.binaryIsCurrent() {
+		url := fmt.Sprintf(containerdDownloadURL, version, version)
+		if err := extractBinaries(ctx, url, containerdTarPath, containerdDir, nil); err != nil {
+			return fmt.Errorf("failed to install containerd: %w", err)
+		}
+	}
+
+	for _, dir := range []string{containerdDataDir, containerdStateDir} {
+		if err := utilio.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	if err := utilio.WriteFile(containerdConfigPath, []byte(containerdConfig(i.config)), 0o644); err != nil {
+		return fmt.Errorf("failed to write containerd configuration: %w", err)
+	}
+
+	if _, err := runPowerShell(ctx, registerContainerdScript(i.config.GetProxyEnvironment())); err != nil {
+		return fmt.Errorf("failed to register the containerd service: %w", err)
+	}
+	i.logger.Info("containerd for Windows installed")
+	return nil
+}
+
+// IsCompleted checks that the configured containerd release is installed, configured and registered
+func (i *ContainerdInstaller) IsCompleted(ctx context.Context) bool {
+	return i.binaryIsCurrent() &&
+		utils.FileHasContent(containerdConfigPath, containerdConfig(i.config)) &&
+		serviceStatus(ctx, containerdService) != ""
+}
+
+// binaryIsCurrent reports whether the installed containerd is the configured release
+func (i *ContainerdInstaller) binaryIsCurrent() bool {
+	output, err := utils.RunCommandWithOutput(containerdPath, "--version")
+	return err == nil && strings.Contains(output, containerdVersion(i.config))
+}
+
+// containerdConfig renders the containerd configuration: process-isolated Windows containers with runhcs,
+// pods networked by the CNI plugins and the pause image of the configured registry
+func containerdConfig(cfg *config.Config) string {
+	return fmt.Sprintf(`version = 2
+root = '%s'
+state = '%s'
+
+[grpc]
+  address = '\\.\pipe\containerd-containerd'
+
+[plugins]
+  [plugins."io.containerd.grpc.v1.cri"]
+    sandbox_image = "%s"
+    [plugins."io.containerd.grpc.v1.cri".containerd]
+      snapshotter = "windows"
+      default_runtime_name = "runhcs-wcow-process"
+      [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runhcs-wcow-process]
+        runtime_type = "io.containerd.runhcs.v1"
+    [plugins."io.containerd.grpc.v1.cri".cni]
+      bin_dir = '%s'
+      conf_dir = '%s'
+`, containerdDataDir, containerdStateDir, cfg.GetImage(config.ImagePause), cniBinDir, cniConfDir)
+}
+
+// registerContainerdScript registers the containerd service when it does not exist yet and sets its
+// environment, which holds the node proxy
+func registerContainerdScript(env []string) string {
+	return fmt.Sprintf(`if (-not (Get-Service -Name %s -ErrorAction SilentlyContinue)) {
+    & %s --register-service --config %s
+    if ($LASTEXITCODE -ne 0) { throw "containerd --register-service failed with exit code $LASTEXITCODE" }
+}
+`, psQuote(containerdService), psQuote(containerdPath), psQuote(containerdConfigPath)) + serviceEnvironmentScript(containerdService, env)
+}
+
+// extractBinaries installs the files under prefix in the archive at url into dir. Only the named files are
+// installed when names is not empty.
+func extractBinaries(ctx context.Context, url, prefix, dir string, names []string) error {
+	installed := 0
+	for tarFile, err := range utilio.DecompressTarGzFromRemote(ctx, url) {
+		if err != nil {
+			return err
+		}
+		// Entry names use the platform separator once cleaned
+		name := filepath.ToSlash(tarFile.Name)
+		if !strings.HasPrefix(name, prefix) || strings.Contains(strings.TrimPrefix(name, prefix), "/") {
+			continue
+		}
+		base := path.Base(name)
+		if len(names) > 0 && !slices.Contains(names, base) {
+			continue
+		}
+		if err := utilio.InstallFile(filepath.Join(dir, base), tarFile.Body, 0o755); err != nil {
+			return fmt.Errorf("failed to write %s: %w", base, err)
+		}
+		installed++
+	}
+	if installed == 0 || (len(names) > 0 && installed != len(names)) {
+		return fmt.Errorf("%s holds %d of the expected files under %q", url, installed, prefix)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// KubeBinariesInstaller installs kubelet, kube-proxy and kubectl from the Windows Kubernetes node archive
+type KubeBinariesInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewKubeBinariesInstaller creates a new Windows Kubernetes binaries installer
+func NewKubeBinariesInstaller(logger *logrus.Logger) *KubeBinariesInstaller {
+	return &KubeBinariesInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *KubeBinariesInstaller) GetName() string {
+	return "WindowsKubeBinariesInstaller"
+}
+
+// Validate checks that a Kubernetes version is configured
+func (i *KubeBinariesInstaller) Validate(ctx context.Context) error {
+	if i.config.GetKubernetesVersion() == "" {
+		return fmt.Errorf("kubernetes version not specified")
+	}
+	return nil
+}
+
+// Execute downloads the Kubernetes node archive and installs the binaries into C:\k
+func (i *KubeBinariesInstaller) Execute(ctx context.Context) error {
+	i.logger.Infof("Installing Kube Binaries of version %s for Windows", i.config.GetKubernetesVersion())
+	if err := extractBinaries(ctx, kubernetesURL(i.config), kubernetesTarPath, kubernetesDir, kubeBinaries); err != nil {
+		return fmt.Errorf("failed to install Kubernetes: %w", err)
+	}
+	i.logger.Info("Kubernetes binaries installed successfully")
+	return nil
+}
+
+// IsCompleted checks that the binaries are installed and kubelet is the configured version
+func (i *KubeBinariesInstaller) IsCompleted(ctx context.Context) bool {
+	for _, path := range []string{kubeletPath, kubeProxyPath, kubectlPath} {
+		if !utils.FileExists(path) {
+			return false
+		}
+	}
+	output, err := utils.RunCommandWithOutput(kubeletPath, "--version")
+	return err == nil && strings.Contains(output, i.config.GetKubernetesVersion())
+}
+
+// kubernetesURL returns the URL of the Windows node archive, from kubernetes.urlTemplate when it is set
+func kubernetesURL(cfg *config.Config) string {
+	template := defaultKubernetesURLTemplate
+	if cfg.Kubernetes.URLTemplate != "" {
+		template = cfg.Kubernetes.URLTemplate
+	}
+	return fmt.Sprintf(template, cfg.GetKubernetesVersion(), "amd64")
+}
@@ -0,0 +1,95 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// KubeProxyInstaller runs kube-proxy as a Windows service in kernelspace mode, which programs service load
+// balancers into HNS, for clusters that do not schedule kube-proxy onto flex nodes
+type KubeProxyInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewKubeProxyInstaller creates a new Windows kube-proxy installer
+func NewKubeProxyInstaller(logger *logrus.Logger) *KubeProxyInstaller {
+	return &KubeProxyInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *KubeProxyInstaller) GetName() string {
+	return "WindowsKubeProxyInstaller"
+}
+
+// Validate checks that the configuration only uses what Windows nodes support
+func (i *KubeProxyInstaller) Validate(ctx context.Context) error {
+	return validateWindowsConfig(i.config)
+}
+
+// Execute registers the kube-proxy service, or removes it when kubeProxy.enabled is turned off
+func (i *KubeProxyInstaller) Execute(ctx context.Context) error {
+	if !i.config.KubeProxy.Enabled {
+		if _, err := runPowerShell(ctx, removeServiceScript(kubeProxyService)); err != nil {
+			return fmt.Errorf("failed to remove the kube-proxy service: %w", err)
+		}
+		return nil
+	}
+
+	i.logger.Info("Configuring kube-proxy in kernelspace mode as a Windows service")
+	if !utils.FileExists(kubeProxyPath) {
+		return fmt.Errorf("kube-proxy binary %s not found; it is installed from the Kubernetes node archive", kubeProxyPath)
+	}
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		return fmt.Errorf("failed to get node name for kube-proxy: %w", err)
+	}
+	binPath := strings.Join(append([]string{kubeProxyPath}, kubeProxyArgs(i.config, nodeName)...), " ")
+	// The API server is added to NO_PROXY, so kube-proxy reaches it directly like kubelet
+	kubeconfig, err := os.ReadFile(KubeconfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to read kubelet kubeconfig file: %w", err)
+	}
+	serverURL, _, err := utils.ExtractClusterInfo(kubeconfig)
+	if err != nil {
+		return err
+	}
+	env := i.config.GetProxyEnvironment(serverURL)
+	if _, err := runPowerShell(ctx, registerServiceScript(kubeProxyService, binPath, []string{kubeletService}, env)); err != nil {
+		return fmt.Errorf("failed to register the kube-proxy service: %w", err)
+	}
+	return nil
+}
+
+// IsCompleted returns false, so the service is registered with the current flags every time
+func (i *KubeProxyInstaller) IsCompleted(ctx context.Context) bool {
+	return false
+}
+
+// kubeProxyArgs returns the flags of the kube-proxy service. kube-proxy reads the kubelet kubeconfig and
+// programs the HNS network win-bridge attaches pods to.
+func kubeProxyArgs(cfg *config.Config, nodeName string) []string {
+	args := []string{
+		"--windows-service",
+		"--proxy-mode=kernelspace",
+		"--kubeconfig=" + KubeconfigPath,
+		"--hostname-override=" + nodeName,
+		"--network-name=" + hnsNetworkName,
+		fmt.Sprintf("--v=%d", cfg.Node.Kubelet.Verbosity),
+	}
+	if cfg.KubeProxy.ClusterCIDR != "" {
+		args = append(args, "--cluster-cidr="+cfg.KubeProxy.ClusterCIDR)
+	}
+	return args
+}
@@ -0,0 +1,263 @@
+package windows
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// clusterEndpoint looks up the API server URL and CA of the target cluster. Tests replace it.
+var clusterEndpoint = kubelet.ClusterEndpoint
+
+// KubeletInstaller configures kubelet and registers it as a Windows service
+type KubeletInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewKubeletInstaller creates a new Windows kubelet installer
+func NewKubeletInstaller(logger *logrus.Logger) *KubeletInstaller {
+	return &KubeletInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *KubeletInstaller) GetName() string {
+	return "WindowsKubeletInstaller"
+}
+
+// Validate checks that the configuration only uses what Windows nodes support
+func (i *KubeletInstaller) Validate(ctx context.Context) error {
+	return validateWindowsConfig(i.config)
+}
+
+// Execute writes the kubelet configuration, kubeconfig and token script, and registers the kubelet service
+func (i *KubeletInstaller) Execute(ctx context.Context) error {
+	i.logger.Info("Installing and configuring kubelet for Windows")
+	serverURL, caCertData := i.config.Node.Kubelet.ServerURL, i.config.Node.Kubelet.CACertData
+	if !i.config.IsBootstrapTokenConfigured() {
+		var err error
+		if serverURL, caCertData, err = clusterEndpoint(ctx, i.config, i.logger); err != nil {
+			return err
+		}
+	}
+
+	for _, dir := range []string{kubernetesDir, kubeletRootDir} {
+		if err := utilio.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	if caCertData != "" {
+		caCert, err := base64.StdEncoding.DecodeString(caCertData)
+		if err != nil {
+			return fmt.Errorf("failed to decode CA certificate data: %w", err)
+		}
+		if err := utilio.WriteFile(clientCAPath, caCert, 0o644); err != nil {
+			return fmt.Errorf("failed to write API server client CA certificate: %w", err)
+		}
+	}
+
+	kubeletConfig, err := kubelet.WindowsConfiguration(i.config, clientCAPath)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(kubeletConfig, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the kubelet configuration: %w", err)
+	}
+	if err := utilio.WriteFile(kubeletConfigPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to create kubelet config file: %w", err)
+	}
+
+	if !i.config.IsBootstrapTokenConfigured() {
+		script, err := tokenScript(i.config)
+		if err != nil {
+			return err
+		}
+		if err := utilio.WriteFile(tokenScriptPath, []byte(script), 0o600); err != nil {
+			return fmt.Errorf("failed to create token script: %w", err)
+		}
+	}
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		return fmt.Errorf("failed to get node name for the kubeconfig: %w", err)
+	}
+	kubeconfig, err := kubeconfigFor(i.config, serverURL, caCertData, nodeName)
+	if err != nil {
+		return err
+	}
+	if err := utilio.WriteFile(KubeconfigPath, kubeconfig, 0o600); err != nil {
+		return fmt.Errorf("failed to create kubeconfig file: %w", err)
+	}
+	// The kubeconfig and token script hold credentials, so only SYSTEM and administrators may read them
+	if _, err := runPowerShell(ctx, restrictAccessScript(KubeconfigPath, tokenScriptPath)); err != nil {
+		return fmt.Errorf("failed to restrict access to the kubelet credentials: %w", err)
+	}
+
+	args, err := kubeletArgs(i.config)
+	if err != nil {
+		return err
+	}
+	binPath := strings.Join(append([]string{kubeletPath}, args...), " ")
+	env := i.config.GetProxyEnvironment(serverURL)
+	if _, err := runPowerShell(ctx, registerServiceScript(kubeletService, binPath, []string{containerdService}, env)); err != nil {
+		return fmt.Errorf("failed to register the kubelet service: %w", err)
+	}
+	i.logger.Info("Kubelet installed and configured successfully")
+	return nil
+}
+
+// IsCompleted returns false, so the kubelet configuration is rewritten with the latest settings every time
+func (i *KubeletInstaller) IsCompleted(ctx context.Context) bool {
+	return false
+}
+
+// kubeletArgs returns the flags of the kubelet service. The other settings are in the config file.
+func kubeletArgs(cfg *config.Config) ([]string, error) {
+	args := []string{
+		"--windows-service",
+		"--config=" + kubeletConfigPath,
+		"--kubeconfig=" + KubeconfigPath,
+		"--root-dir=" + kubeletRootDir,
+		"--cert-dir=" + kubeletCertDir,
+		"--container-runtime-endpoint=" + containerdEndpoint,
+		"--runtime-request-timeout=15m",
+		fmt.Sprintf("--v=%d", cfg.Node.Kubelet.Verbosity),
+	}
+	if len(cfg.Node.Labels) > 0 {
+		labels := make([]string, 0, len(cfg.Node.Labels))
+		for _, key := range slices.Sorted(maps.Keys(cfg.Node.Labels)) {
+			labels = append(labels, fmt.Sprintf("%s=%s", key, cfg.Node.Labels[key]))
+		}
+		args = append(args, "--node-labels="+strings.Join(labels, ","))
+	}
+	if len(cfg.Node.Taints) > 0 {
+		args = append(args, "--register-with-taints="+strings.Join(cfg.Node.Taints, ","))
+	}
+	nodeIPs, err := cfg.GetNodeIPs()
+	if err != nil {
+		return nil, err
+	}
+	if len(nodeIPs) > 0 {
+		ips := make([]string, 0, len(nodeIPs))
+		for _, nodeIP := range nodeIPs {
+			ips = append(ips, nodeIP.IP.String())
+		}
+		args = append(args, "--node-ip="+strings.Join(ips, ","))
+	}
+	return args, nil
+}
+
+// kubeconfigFor renders kubelet's kubeconfig, authenticating with the bootstrap token or with the token
+// script. JSON is valid YAML, and keeps the Windows paths of the exec command intact.
+func kubeconfigFor(cfg *config.Config, serverURL, caCertData, nodeName string) ([]byte, error) {
+	clusterName := cfg.Azure.TargetCluster.Name
+	cluster := map[string]any{"server": serverURL}
+	if caCertData != "" {
+		cluster["certificate-authority-data"] = caCertData
+	} else {
+		cluster["insecure-skip-tls-verify"] = true
+	}
+
+	userName := "sp-user"
+	user := map[string]any{
+		"exec": map[string]any{
+			"apiVersion":         "client.authentication.k8s.io/v1beta1",
+			"command":            "powershell.exe",
+			"args":               []string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", tokenScriptPath},
+			"provideClusterInfo": false,
+		},
+	}
+	if cfg.IsBootstrapTokenConfigured() {
+		userName = "kubelet-bootstrap-" + nodeName
+		user = map[string]any{"token": cfg.Azure.BootstrapToken.Token}
+	}
+
+	kubeconfig := map[string]any{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"clusters":        []any{map[string]any{"name": clusterName, "cluster": cluster}},
+		"contexts":        []any{map[string]any{"name": clusterName, "context": map[string]any{"cluster": clusterName, "user": userName}}},
+		"current-context": clusterName,
+		"users":           []any{map[string]any{"name": userName, "user": user}},
+	}
+	data, err := json.MarshalIndent(kubeconfig, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the kubeconfig: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// tokenScript renders the PowerShell script printing an ExecCredential with an AKS token, fetched with the
+// service principal or from the instance metadata service with the managed identity
+func tokenScript(cfg *config.Config) (string, error) {
+	var fetch string
+	switch {
+	case cfg.IsMIConfigured():
+		uri := fmt.Sprintf("http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource=%s", aksServiceResourceID)
+		if mi := cfg.Azure.ManagedIdentity; mi != nil && mi.ClientID != "" {
+			uri += "&client_id=" + mi.ClientID
+		} else if mi != nil && mi.ResourceID != "" {
+			uri += "&msi_res_id=" + mi.ResourceID
+		}
+		fetch = fmt.Sprintf(`# Fetch an AAD token from Azure Instance Metadata Service (IMDS) using VM Managed Identity
+$response = Invoke-RestMethod -Headers @{ Metadata = 'true' } -Uri %s -UseBasicParsing
+$expiry = [DateTimeOffset]::FromUnixTimeSeconds([long]$response.expires_on).UtcDateTime`, psQuote(uri))
+	case cfg.IsSPConfigured():
+		sp := cfg.Azure.ServicePrincipal
+		fetch = fmt.Sprintf(`# Get Azure AD token using Service Principal credentials for direct AKS authentication
+$body = @{
+    client_id     = %s
+    client_secret = %s
+    scope         = %s
+    grant_type    = 'client_credentials'
+}
+$response = Invoke-RestMethod -Method Post -Uri %s -Body $body -UseBasicParsing
+$expiry = (Get-Date).ToUniversalTime().AddSeconds([int]$response.expires_in)`,
+			psQuote(sp.ClientID), psQuote(sp.ClientSecret), psQuote(aksServiceResourceID+"/.default"),
+			psQuote(fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", cfg.ClusterTenantID(sp.TenantID))))
+	default:
+		return "", fmt.Errorf("no supported authentication method configured - Windows nodes need a bootstrap token, a service principal or a managed identity")
+	}
+
+	return fmt.Sprintf(`$ErrorActionPreference = 'Stop'
+
+%s
+
+# Return in ExecCredential format
+@{
+    kind       = 'ExecCredential'
+    apiVersion = 'client.authentication.k8s.io/v1beta1'
+    spec       = @{ interactive = $false }
+    status     = @{
+        expirationTimestamp = $expiry.ToString('yyyy-MM-ddTHH:mm:ssZ')
+        token               = $response.access_token
+    }
+} | ConvertTo-Json -Compress
+`, fetch), nil
+}
+
+// restrictAccessScript limits access to files to SYSTEM and administrators, named by their well-known SIDs
+// since account names are localized. Files that do not exist are skipped.
+func restrictAccessScript(paths ...string) string {
+	return fmt.Sprintf(`foreach ($path in %s) {
+    if (Test-Path $path) {
+        icacls.exe $path /inheritance:r /grant:r '*S-1-5-18:F' '*S-1-5-32-544:F' | Out-Null
+        if ($LASTEXITCODE -ne 0) { throw "icacls failed on $path with exit code $LASTEXITCODE" }
+    }
+}
+`, psArray(paths))
+}
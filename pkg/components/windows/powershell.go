@@ -0,0 +1,81 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runPowerShell runs a PowerShell script and returns its output. Tests replace it to record the scripts.
+var runPowerShell = func(ctx context.Context, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-Command", script)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("powershell failed: %w, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// psQuote quotes a string for PowerShell, which takes single-quoted strings literally
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// psArray renders strings as a PowerShell array
+func psArray(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, psQuote(value))
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
+
+// registerServiceScript creates or updates an automatically started service running binPath, restarted
+// when it fails, with env as its environment
+func registerServiceScript(name, binPath string, dependsOn []string, env []string) string {
+	var script strings.Builder
+	fmt.Fprintf(&script, `$name = %s
+$binPath = %s
+if (Get-Service -Name $name -ErrorAction SilentlyContinue) {
+    sc.exe config $name binPath= $binPath start= auto | Out-Null
+} else {
+    New-Service -Name $name -BinaryPathName $binPath -StartupType Automatic`, psQuote(name), psQuote(binPath))
+	if len(dependsOn) > 0 {
+		fmt.Fprintf(&script, " -DependsOn %s", psArray(dependsOn))
+	}
+	script.WriteString(` | Out-Null
+}
+sc.exe failure $name reset= 0 actions= restart/10000/restart/10000/restart/10000 | Out-Null
+`)
+	script.WriteString(serviceEnvironmentScript(name, env))
+	return script.String()
+}
+
+// serviceEnvironmentScript sets the environment of a service, or clears it when env is empty
+func serviceEnvironmentScript(name string, env []string) string {
+	key := psQuote(`HKLM:\SYSTEM\CurrentControlSet\Services\` + name)
+	if len(env) == 0 {
+		return fmt.Sprintf("Remove-ItemProperty -Path %s -Name Environment -ErrorAction SilentlyContinue\n", key)
+	}
+	return fmt.Sprintf("Set-ItemProperty -Path %s -Name Environment -Type MultiString -Value %s\n", key, psArray(env))
+}
+
+// serviceStatus returns the status of a service, such as Running or Stopped, or an empty string when the
+// service does not exist
+func serviceStatus(ctx context.Context, name string) string {
+	output, err := runPowerShell(ctx, fmt.Sprintf("(Get-Service -Name %s -ErrorAction SilentlyContinue).Status", psQuote(name)))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
+
+// removeServiceScript stops and deletes a service when it exists
+func removeServiceScript(name string) string {
+	return fmt.Sprintf(`if (Get-Service -Name %[1]s -ErrorAction SilentlyContinue) {
+    Stop-Service -Name %[1]s -Force -ErrorAction SilentlyContinue
+    sc.exe delete %[1]s | Out-Null
+}
+`, psQuote(name))
+}
@@ -0,0 +1,110 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// serviceStartupTimeout is how long kubelet may take to report Running after it is started
+var serviceStartupTimeout = 30 * time.Second
+
+// ServicesInstaller starts containerd, kubelet and kube-proxy
+type ServicesInstaller struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewServicesInstaller creates a new Windows services installer
+func NewServicesInstaller(logger *logrus.Logger) *ServicesInstaller {
+	return &ServicesInstaller{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (i *ServicesInstaller) GetName() string {
+	return "WindowsServicesEnabled"
+}
+
+// Validate validates prerequisites for starting services
+func (i *ServicesInstaller) Validate(ctx context.Context) error {
+	return nil
+}
+
+// Execute restarts containerd to pick up the CNI configuration, then starts kubelet and kube-proxy and waits
+// for kubelet to run
+func (i *ServicesInstaller) Execute(ctx context.Context) error {
+	i.logger.Info("Starting Windows services")
+	script := fmt.Sprintf("Restart-Service -Name %s -Force\nStart-Service -Name %s\n", psQuote(containerdService), psQuote(kubeletService))
+	if i.config.KubeProxy.Enabled {
+		script += fmt.Sprintf("Start-Service -Name %s\n", psQuote(kubeProxyService))
+	}
+	if _, err := runPowerShell(ctx, script); err != nil {
+		return fmt.Errorf("failed to start services: %w", err)
+	}
+
+	i.logger.Info("Waiting for kubelet to start...")
+	deadline := time.Now().Add(serviceStartupTimeout)
+	for serviceStatus(ctx, kubeletService) != "Running" {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("kubelet failed to start properly: not running after %s", serviceStartupTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	i.logger.Info("All services started successfully")
+	return nil
+}
+
+// IsCompleted returns false, so services are restarted each time
+func (i *ServicesInstaller) IsCompleted(ctx context.Context) bool {
+	return false
+}
+
+// ServicesUnInstaller stops kube-proxy, kubelet and containerd
+type ServicesUnInstaller struct {
+	logger *logrus.Logger
+}
+
+// NewServicesUnInstaller creates a new Windows services unInstaller
+func NewServicesUnInstaller(logger *logrus.Logger) *ServicesUnInstaller {
+	return &ServicesUnInstaller{
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *ServicesUnInstaller) GetName() string {
+	return "WindowsServicesDisabled"
+}
+
+// Execute stops the services, the dependents first
+func (u *ServicesUnInstaller) Execute(ctx context.Context) error {
+	u.logger.Info("Stopping Windows services")
+	for _, service := range []string{kubeProxyService, kubeletService, containerdService} {
+		script := fmt.Sprintf("Stop-Service -Name %s -Force -ErrorAction SilentlyContinue", psQuote(service))
+		if _, err := runPowerShell(ctx, script); err != nil {
+			u.logger.Warnf("Failed to stop %s: %v", service, err)
+		}
+	}
+	return nil
+}
+
+// IsCompleted checks that none of the services runs
+func (u *ServicesUnInstaller) IsCompleted(ctx context.Context) bool {
+	for _, service := range []string{kubeProxyService, kubeletService, containerdService} {
+		if serviceStatus(ctx, service) == "Running" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// SystemConfigurator prepares Windows for containers: the Containers feature, Defender exclusions and the
+// firewall rules the cluster reaches kubelet through
+type SystemConfigurator struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewSystemConfigurator creates a new Windows system configurator
+func NewSystemConfigurator(logger *logrus.Logger) *SystemConfigurator {
+	return &SystemConfigurator{
+		config: config.GetConfig(),
+		logger: logger,
+	}
+}
+
+// GetName returns the step name
+func (s *SystemConfigurator) GetName() string {
+	return "WindowsSystemConfigured"
+}
+
+// Validate checks that the configuration only uses what Windows nodes support
+func (s *SystemConfigurator) Validate(ctx context.Context) error {
+	return validateWindowsConfig(s.config)
+}
+
+// Execute enables the Containers feature, excludes the node directories from Defender scans and opens the
+// inbound ports. Enabling the feature needs a restart, after which bootstrap is run again.
+func (s *SystemConfigurator) Execute(ctx context.Context) error {
+	s.logger.Info("Configuring Windows for containers")
+	output, err := runPowerShell(ctx, enableContainersScript)
+	if err != nil {
+		return fmt.Errorf("failed to enable the Containers feature: %w", err)
+	}
+	if strings.TrimSpace(output) == "RestartNeeded" {
+		return fmt.Errorf("the Containers feature was enabled and Windows must restart; restart the machine and run bootstrap again")
+	}
+
+	if _, err := runPowerShell(ctx, defenderExclusionsScript()); err != nil {
+		return fmt.Errorf("failed to add Defender exclusions: %w", err)
+	}
+
+	if _, err := runPowerShell(ctx, firewallRulesScript(s.firewallPorts())); err != nil {
+		return fmt.Errorf("failed to open firewall ports: %w", err)
+	}
+	s.logger.Info("Windows system configuration completed")
+	return nil
+}
+
+// IsCompleted checks that the Containers feature is enabled and the firewall rules match the configured ports
+func (s *SystemConfigurator) IsCompleted(ctx context.Context) bool {
+	output, err := runPowerShell(ctx, fmt.Sprintf(`$feature = (Get-WindowsOptionalFeature -Online -FeatureName Containers).State
+$rules = @(Get-NetFirewallRule -Group %s -ErrorAction SilentlyContinue | ForEach-Object { $_.DisplayName }) | Sort-Object
+"$feature;$($rules -join ',')"`, psQuote(firewallRuleGroup)))
+	if err != nil {
+		return false
+	}
+	var names []string
+	for _, port := range s.firewallPorts() {
+		names = append(names, firewallRuleName(port))
+	}
+	slices.Sort(names)
+	return strings.TrimSpace(output) == "Enabled;"+strings.Join(names, ",")
+}
+
+// firewallPorts returns kubelet's ports and system.firewall.extraPorts
+func (s *SystemConfigurator) firewallPorts() []string {
+	return append(append([]string{}, kubeletPorts...), s.config.System.Firewall.ExtraPorts...)
+}
+
+// enableContainersScript enables the Containers feature, printing RestartNeeded when Windows must restart first
+const enableContainersScript = `$feature = Get-WindowsOptionalFeature -Online -FeatureName Containers
+if ($feature.State -ne 'Enabled') {
+    $result = Enable-WindowsOptionalFeature -Online -FeatureName Containers -All -NoRestart
+    if ($result.RestartNeeded) { 'RestartNeeded' }
+}
+`
+
+// defenderExclusionsScript excludes the node directories from Defender scans, when Defender is installed
+func defenderExclusionsScript() string {
+	return fmt.Sprintf(`if (Get-Command Add-MpPreference -ErrorAction SilentlyContinue) {
+    Add-MpPreference -ExclusionPath %s
+}
+`, psArray(defenderExclusions))
+}
+
+// firewallRulesScript replaces the inbound rules of the agent with one per port, given as <port>[-<port>]/<tcp|udp>
+func firewallRulesScript(ports []string) string {
+	var script strings.Builder
+	fmt.Fprintf(&script, "Remove-NetFirewallRule -Group %s -ErrorAction SilentlyContinue\n", psQuote(firewallRuleGroup))
+	for _, port := range ports {
+		number, protocol, _ := strings.Cut(port, "/")
+		fmt.Fprintf(&script, "New-NetFirewallRule -DisplayName %s -Group %s -Direction Inbound -Action Allow -Protocol %s -LocalPort %s | Out-Null\n",
+			psQuote(firewallRuleName(port)), psQuote(firewallRuleGroup), strings.ToUpper(protocol), psQuote(number))
+	}
+	return script.String()
+}
+
+// firewallRuleName returns the display name of the rule opening port
+func firewallRuleName(port string) string {
+	return firewallRuleGroup + " " + port
+}
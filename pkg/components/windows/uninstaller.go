@@ -0,0 +1,76 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// UnInstaller removes what bootstrap set up on a Windows node: the services, the HNS network, the firewall
+// rules, the Defender exclusions and the node directories
+type UnInstaller struct {
+	logger *logrus.Logger
+}
+
+// NewUnInstaller creates a new Windows node unInstaller
+func NewUnInstaller(logger *logrus.Logger) *UnInstaller {
+	return &UnInstaller{
+		logger: logger,
+	}
+}
+
+// GetName returns the cleanup step name
+func (u *UnInstaller) GetName() string {
+	return "WindowsNodeRemoved"
+}
+
+// Execute deletes the services before removing their binaries. Failures are logged and the remaining
+// cleanup continues.
+func (u *UnInstaller) Execute(ctx context.Context) error {
+	u.logger.Info("Removing the Windows node setup")
+	if _, err := runPowerShell(ctx, cleanupScript()); err != nil {
+		u.logger.Warnf("Failed to remove the Windows services and settings: %v", err)
+	}
+	for _, dir := range nodeDirs() {
+		if err := os.RemoveAll(dir); err != nil {
+			u.logger.Warnf("Failed to remove %s: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+// IsCompleted checks that the services and node directories are gone
+func (u *UnInstaller) IsCompleted(ctx context.Context) bool {
+	for _, service := range []string{kubeProxyService, kubeletService, containerdService} {
+		if serviceStatus(ctx, service) != "" {
+			return false
+		}
+	}
+	for _, dir := range nodeDirs() {
+		if _, err := os.Stat(dir); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeDirs are the directories bootstrap creates
+func nodeDirs() []string {
+	return []string{kubernetesDir, kubeletRootDir, containerdDir, `C:\ProgramData\containerd`, cniBinDir, cniConfDir}
+}
+
+// cleanupScript deletes the services, the HNS network win-bridge created, the firewall rules and the
+// Defender exclusions. The HNS cmdlets are only present when the HostNetworkingService module is installed.
+func cleanupScript() string {
+	script := removeServiceScript(kubeProxyService) + removeServiceScript(kubeletService) + removeServiceScript(containerdService)
+	return script + fmt.Sprintf(`if (Get-Command Get-HnsNetwork -ErrorAction SilentlyContinue) {
+    Get-HnsNetwork | Where-Object { $_.Name -eq %s } | Remove-HnsNetwork
+}
+Remove-NetFirewallRule -Group %s -ErrorAction SilentlyContinue
+if (Get-Command Remove-MpPreference -ErrorAction SilentlyContinue) {
+    Remove-MpPreference -ExclusionPath %s
+}
+`, psQuote(hnsNetworkName), psQuote(firewallRuleGroup), psArray(defenderExclusions))
+}
@@ -0,0 +1,52 @@
+package windows
+
+import (
+	"fmt"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// validateWindowsConfig rejects settings Windows nodes do not support yet, instead of bootstrapping a node
+// that silently ignores them
+func validateWindowsConfig(cfg *config.Config) error {
+	switch {
+	case cfg.IsARCEnabled():
+		return fmt.Errorf("azure.arc is not supported on Windows nodes yet; use a bootstrap token, a service principal or a managed identity")
+	case cfg.IsFederatedIdentityConfigured():
+		return fmt.Errorf("azure.federatedIdentity is not supported on Windows nodes yet; use a bootstrap token, a service principal or a managed identity")
+	case cfg.UsesCRIO():
+		return fmt.Errorf("runtime.containerRuntime %s is not supported on Windows nodes; Windows nodes run containerd", cfg.Runtime.ContainerRuntime)
+	case !strings.HasPrefix(containerdVersion(cfg), "1."):
+		return fmt.Errorf("containerd.version %s is not supported on Windows nodes yet; use a 1.x release such as %s", containerdVersion(cfg), defaultContainerdVersion)
+	case cfg.CNI.Provider != config.CNIProviderBridge:
+		return fmt.Errorf("cni.provider %s is not supported on Windows nodes; Windows nodes use the bridge provider, set up with win-bridge", cfg.CNI.Provider)
+	case cfg.CNI.ConfigTemplateFile != "":
+		return fmt.Errorf("cni.configTemplateFile is not supported on Windows nodes")
+	case cfg.NodeLocalDNS.Enabled:
+		return fmt.Errorf("nodeLocalDNS is not supported on Windows nodes yet")
+	case cfg.IsDualStack():
+		return fmt.Errorf("dual-stack nodes are not supported on Windows yet")
+	case cfg.KubeProxy.Enabled && cfg.KubeProxy.RunAs == config.KubeProxyRunAsStaticPod:
+		return fmt.Errorf("kubeProxy.runAs %s is not supported on Windows nodes; kube-proxy runs as a Windows service", cfg.KubeProxy.RunAs)
+	case cfg.KubeProxy.Enabled && cfg.KubeProxy.Mode == config.KubeProxyModeIPVS:
+		return fmt.Errorf("kubeProxy.mode %s is not supported on Windows nodes; kube-proxy runs in kernelspace mode", cfg.KubeProxy.Mode)
+	}
+	return nil
+}
+
+// containerdVersion returns containerd.version, or the default release
+func containerdVersion(cfg *config.Config) string {
+	if cfg.Containerd.Version != "" {
+		return cfg.Containerd.Version
+	}
+	return defaultContainerdVersion
+}
+
+// cniVersion returns cni.version, or the default release
+func cniVersion(cfg *config.Config) string {
+	if cfg.CNI.Version != "" {
+		return cfg.CNI.Version
+	}
+	return defaultCNIVersion
+}
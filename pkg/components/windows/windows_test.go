@@ -0,0 +1,311 @@
+package windows
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// newTestConfig returns a configuration joining a cluster with a service principal
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Azure.TargetCluster = &config.TargetClusterConfig{Name: "cluster"}
+	cfg.Azure.ServicePrincipal = &config.ServicePrincipalConfig{TenantID: "tenant", ClientID: "client", ClientSecret: "it's secret"}
+	cfg.CNI.Provider = config.CNIProviderBridge
+	cfg.Node.Kubelet.DNSServiceIP = "10.0.0.10"
+	return cfg
+}
+
+// stubPowerShell replaces runPowerShell with run, restoring it when the test ends
+func stubPowerShell(t *testing.T, run func(script string) (string, error)) {
+	t.Helper()
+	orig := runPowerShell
+	t.Cleanup(func() { runPowerShell = orig })
+	runPowerShell = func(_ context.Context, script string) (string, error) { return run(script) }
+}
+
+func TestValidateWindowsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.Config)
+		wantErr string
+	}{
+		{name: "service principal and bridge", modify: func(cfg *config.Config) {}},
+		{name: "Arc", modify: func(cfg *config.Config) { cfg.Azure.Arc = &config.ArcConfig{Enabled: true} }, wantErr: "azure.arc"},
+		{name: "CRI-O", modify: func(cfg *config.Config) { cfg.Runtime.ContainerRuntime = config.ContainerRuntimeCRIO }, wantErr: "runtime.containerRuntime"},
+		{name: "containerd 2", modify: func(cfg *config.Config) { cfg.Containerd.Version = "2.0.0" }, wantErr: "containerd.version"},
+		{name: "Cilium", modify: func(cfg *config.Config) { cfg.CNI.Provider = config.CNIProviderCilium }, wantErr: "cni.provider"},
+		{name: "node-local DNS", modify: func(cfg *config.Config) { cfg.NodeLocalDNS.Enabled = true }, wantErr: "nodeLocalDNS"},
+		{name: "IPVS", modify: func(cfg *config.Config) {
+			cfg.KubeProxy = config.KubeProxyConfig{Enabled: true, Mode: config.KubeProxyModeIPVS}
+		}, wantErr: "kubeProxy.mode"},
+		{name: "kube-proxy static pod", modify: func(cfg *config.Config) {
+			cfg.KubeProxy = config.KubeProxyConfig{Enabled: true, RunAs: config.KubeProxyRunAsStaticPod}
+		}, wantErr: "kubeProxy.runAs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			tt.modify(cfg)
+			err := validateWindowsConfig(cfg)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("validateWindowsConfig() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("validateWindowsConfig() error = %v, want one about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWinBridgeConfig(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Node.Kubelet.DNSServiceIP = "172.16.0.10"
+	cfg.KubeProxy.ClusterCIDR = "10.240.0.0/12"
+
+	data, err := winBridgeConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conf struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		DNS  struct {
+			Nameservers []string
+		} `json:"dns"`
+		IPAM struct {
+			Subnet string `json:"subnet"`
+		} `json:"ipam"`
+		Policies []struct {
+			Value map[string]any `json:"value"`
+		} `json:"policies"`
+	}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		t.Fatalf("CNI configuration is not JSON: %v\n%s", err, data)
+	}
+	if conf.Name != hnsNetworkName || conf.Type != "win-bridge" || conf.IPAM.Subnet != podSubnet.subnet {
+		t.Errorf("CNI configuration = %s", data)
+	}
+	if !reflect.DeepEqual(conf.DNS.Nameservers, []string{"172.16.0.10"}) {
+		t.Errorf("nameservers = %v, want the cluster DNS service", conf.DNS.Nameservers)
+	}
+	if len(conf.Policies) != 2 {
+		t.Fatalf("policies = %+v, want outbound NAT and the service route", conf.Policies)
+	}
+	if exceptions := conf.Policies[0].Value["ExceptionList"]; !reflect.DeepEqual(exceptions, []any{podSubnet.subnet, "10.240.0.0/12"}) {
+		t.Errorf("outbound NAT exceptions = %v, want the pod ranges", exceptions)
+	}
+	if route := conf.Policies[1].Value; route["Type"] != "ROUTE" || route["DestinationPrefix"] != "172.16.0.0/16" {
+		t.Errorf("service route = %v, want the /16 of the DNS service", route)
+	}
+}
+
+func TestContainerdConfig(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Containerd.PauseImage = "registry.example.com/pause:3.9"
+	conf := containerdConfig(cfg)
+	for _, want := range []string{
+		`sandbox_image = "registry.example.com/pause:3.9"`,
+		`default_runtime_name = "runhcs-wcow-process"`,
+		`runtime_type = "io.containerd.runhcs.v1"`,
+		`bin_dir = 'C:\opt\cni\bin'`,
+		`address = '\\.\pipe\containerd-containerd'`,
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("containerd configuration does not contain %s:\n%s", want, conf)
+		}
+	}
+}
+
+func TestKubeconfigFor(t *testing.T) {
+	cfg := newTestConfig()
+	var kubeconfig struct {
+		Clusters []struct {
+			Cluster map[string]any `json:"cluster"`
+		} `json:"clusters"`
+		Users []struct {
+			Name string         `json:"name"`
+			User map[string]any `json:"user"`
+		} `json:"users"`
+	}
+
+	data, err := kubeconfigFor(cfg, "https://cluster.hcp.eastus.azmk8s.io:443", "Y2E=", "node-1")
+	if err != nil || json.Unmarshal(data, &kubeconfig) != nil {
+		t.Fatalf("kubeconfigFor() = %s, %v", data, err)
+	}
+	if kubeconfig.Clusters[0].Cluster["certificate-authority-data"] != "Y2E=" {
+		t.Errorf("cluster = %v, want the CA", kubeconfig.Clusters[0].Cluster)
+	}
+	exec, _ := kubeconfig.Users[0].User["exec"].(map[string]any)
+	if exec["command"] != "powershell.exe" || !strings.Contains(string(data), `C:\\k\\token.ps1`) {
+		t.Errorf("user = %v, want the token script", kubeconfig.Users[0].User)
+	}
+
+	cfg.Azure.BootstrapToken = &config.BootstrapTokenConfig{Token: "abcdef.0123456789abcdef"}
+	data, err = kubeconfigFor(cfg, "https://cluster.example.com", "", "node-1")
+	if err != nil || json.Unmarshal(data, &kubeconfig) != nil {
+		t.Fatalf("kubeconfigFor() = %s, %v", data, err)
+	}
+	if user := kubeconfig.Users[0]; user.Name != "kubelet-bootstrap-node-1" || user.User["token"] != "abcdef.0123456789abcdef" {
+		t.Errorf("user = %+v, want the bootstrap token", user)
+	}
+	if kubeconfig.Clusters[0].Cluster["insecure-skip-tls-verify"] != true {
+		t.Errorf("cluster = %v, want TLS verification skipped without a CA", kubeconfig.Clusters[0].Cluster)
+	}
+}
+
+func TestTokenScript(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Azure.TargetCluster.TenantID = "cluster-tenant"
+	script, err := tokenScript(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"client_secret = 'it''s secret'",
+		"'https://login.microsoftonline.com/cluster-tenant/oauth2/v2.0/token'",
+		"'" + aksServiceResourceID + "/.default'",
+		"ConvertTo-Json -Compress",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("token script does not contain %s:\n%s", want, script)
+		}
+	}
+
+	cfg.Azure.ServicePrincipal = nil
+	if _, err := tokenScript(cfg); err == nil {
+		t.Error("tokenScript() succeeded without a service principal or managed identity")
+	}
+}
+
+func TestKubeletArgs(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Node.Kubelet.Verbosity = 2
+	cfg.Node.Labels = map[string]string{"zone": "edge", "app": "web"}
+	cfg.Node.Taints = []string{"os=windows:NoSchedule"}
+
+	args, err := kubeletArgs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"--windows-service",
+		"--container-runtime-endpoint=" + containerdEndpoint,
+		"--node-labels=app=web,zone=edge",
+		"--register-with-taints=os=windows:NoSchedule",
+		"--v=2",
+	} {
+		if !strings.Contains(strings.Join(args, " "), want) {
+			t.Errorf("kubelet args %v do not contain %s", args, want)
+		}
+	}
+}
+
+func TestRegisterServiceScript(t *testing.T) {
+	script := registerServiceScript(kubeletService, `C:\k\kubelet.exe --v=2`, []string{containerdService}, []string{"HTTPS_PROXY=http://proxy:3128"})
+	for _, want := range []string{
+		`$binPath = 'C:\k\kubelet.exe --v=2'`,
+		"-DependsOn @('containerd')",
+		"sc.exe failure $name",
+		`Set-ItemProperty -Path 'HKLM:\SYSTEM\CurrentControlSet\Services\kubelet' -Name Environment -Type MultiString -Value @('HTTPS_PROXY=http://proxy:3128')`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("service script does not contain %s:\n%s", want, script)
+		}
+	}
+	if script := registerServiceScript(kubeProxyService, kubeProxyPath, nil, nil); !strings.Contains(script, "Remove-ItemProperty") || strings.Contains(script, "-DependsOn") {
+		t.Errorf("service script without dependencies or environment:\n%s", script)
+	}
+}
+
+func TestSystemConfiguratorIsCompleted(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.System.Firewall.ExtraPorts = []string{"179/tcp"}
+	configurator := &SystemConfigurator{config: cfg, logger: logrus.New()}
+
+	tests := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{name: "configured", output: "Enabled;AKS Flex Node 10250/tcp,AKS Flex Node 179/tcp\r\n", want: true},
+		{name: "feature disabled", output: "Disabled;AKS Flex Node 10250/tcp,AKS Flex Node 179/tcp"},
+		{name: "port missing", output: "Enabled;AKS Flex Node 10250/tcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubPowerShell(t, func(string) (string, error) { return tt.output, nil })
+			if got := configurator.IsCompleted(context.Background()); got != tt.want {
+				t.Errorf("IsCompleted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if script := firewallRulesScript(configurator.firewallPorts()); !strings.Contains(script, "-Protocol TCP -LocalPort '179'") {
+		t.Errorf("firewall script does not open 179/tcp:\n%s", script)
+	}
+}
+
+func TestExtractBinaries(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"kubernetes/node/bin/kubelet.exe":    "kubelet",
+		"kubernetes/node/bin/kube-proxy.exe": "kube-proxy",
+		"kubernetes/node/bin/kubectl.exe":    "kubectl",
+		"kubernetes/node/bin/kubeadm.exe":    "kubeadm",
+		"kubernetes/LICENSES":                "licenses",
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := extractBinaries(context.Background(), server.URL, kubernetesTarPath, dir, kubeBinaries); err != nil {
+		t.Fatalf("extractBinaries() error = %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"kube-proxy.exe", "kubectl.exe", "kubelet.exe"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("installed %v, want %v", names, want)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "kubelet.exe")); string(content) != "kubelet" {
+		t.Errorf("kubelet.exe = %q", content)
+	}
+
+	if err := extractBinaries(context.Background(), server.URL, kubernetesTarPath, t.TempDir(), []string{"kubelet.exe", "missing.exe"}); err == nil {
+		t.Error("extractBinaries() succeeded with a file missing from the archive")
+	}
+}
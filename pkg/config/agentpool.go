@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Modes of an AKS agent pool
+const (
+	AgentPoolModeSystem = "System"
+	AgentPoolModeUser   = "User"
+)
+
+// Labels AKS puts on the nodes of an agent pool, which node selectors and affinities of existing workloads use
+const (
+	AgentPoolLabel       = "kubernetes.azure.com/agentpool"
+	LegacyAgentPoolLabel = "agentpool"
+	AgentPoolModeLabel   = "kubernetes.azure.com/mode"
+)
+
+// agentPoolNamePattern matches the names AKS accepts for Linux agent pools
+var agentPoolNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]{0,11}$`)
+
+func (c *Config) setAgentPoolDefaults() {
+	pool := &c.Node.AgentPool
+	if pool.Name == "" {
+		return
+	}
+	if pool.Mode == "" {
+		pool.Mode = AgentPoolModeUser
+	}
+	c.setNodeLabelIfMissing(AgentPoolLabel, pool.Name)
+	c.setNodeLabelIfMissing(LegacyAgentPoolLabel, pool.Name)
+	c.setNodeLabelIfMissing(AgentPoolModeLabel, strings.ToLower(pool.Mode))
+}
+
+// validateAgentPool validates node.agentPool and rejects node.labels contradicting it
+func validateAgentPool(c *Config) error {
+	pool := &c.Node.AgentPool
+	if pool.Name == "" {
+		if pool.Mode != "" {
+			return fmt.Errorf("node.agentPool.mode requires node.agentPool.name")
+		}
+		return nil
+	}
+	if !agentPoolNamePattern.MatchString(pool.Name) {
+		return fmt.Errorf("invalid node.agentPool.name: %s. Expected up to 12 lowercase letters and digits, starting with a letter", pool.Name)
+	}
+	if pool.Mode != AgentPoolModeSystem && pool.Mode != AgentPoolModeUser {
+		return fmt.Errorf("invalid node.agentPool.mode: %s. Valid values are: %s, %s", pool.Mode, AgentPoolModeSystem, AgentPoolModeUser)
+	}
+	for key, value := range map[string]string{
+		AgentPoolLabel:       pool.Name,
+		LegacyAgentPoolLabel: pool.Name,
+		AgentPoolModeLabel:   strings.ToLower(pool.Mode),
+	} {
+		if c.Node.Labels[key] != value {
+			return fmt.Errorf("node.labels sets %s=%s, which contradicts node.agentPool; remove the label", key, c.Node.Labels[key])
+		}
+	}
+	return nil
+}
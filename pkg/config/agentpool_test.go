@@ -0,0 +1,41 @@
+package config
+
+import "testing"
+
+func TestAgentPool(t *testing.T) {
+	tests := []struct {
+		name     string
+		pool     AgentPoolConfig
+		labels   map[string]string
+		wantMode string
+		wantErr  bool
+	}{
+		{name: "no agent pool"},
+		{name: "default mode", pool: AgentPoolConfig{Name: "nodepool1"}, wantMode: "user"},
+		{name: "system pool", pool: AgentPoolConfig{Name: "system", Mode: "System"}, wantMode: "system"},
+		{name: "mode without name", pool: AgentPoolConfig{Mode: "User"}, wantErr: true},
+		{name: "invalid name", pool: AgentPoolConfig{Name: "Pool-1"}, wantErr: true},
+		{name: "name too long", pool: AgentPoolConfig{Name: "averylongpoolname"}, wantErr: true},
+		{name: "invalid mode", pool: AgentPoolConfig{Name: "pool1", Mode: "user"}, wantErr: true},
+		{name: "contradicting label", pool: AgentPoolConfig{Name: "pool1"}, labels: map[string]string{"agentpool": "pool2"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Node: NodeConfig{AgentPool: tt.pool, Labels: tt.labels}}
+			c.setAgentPoolDefaults()
+			err := validateAgentPool(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateAgentPool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr || tt.pool.Name == "" {
+				return
+			}
+			for key, want := range map[string]string{AgentPoolLabel: tt.pool.Name, LegacyAgentPoolLabel: tt.pool.Name, AgentPoolModeLabel: tt.wantMode} {
+				if got := c.Node.Labels[key]; got != want {
+					t.Errorf("label %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
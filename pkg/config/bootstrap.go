@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// applyBootstrapKubeconfig takes the bootstrap token, API server URL and CA certificate from
+// azure.bootstrapToken.kubeconfig, so a single Key Vault secret can carry everything a bootstrap token join
+// needs. Values set in the configuration are kept.
+func (c *Config) applyBootstrapKubeconfig() error {
+	tokenCfg := c.Azure.BootstrapToken
+	if tokenCfg == nil || tokenCfg.Kubeconfig == "" {
+		return nil
+	}
+	kubeconfig, err := clientcmd.Load([]byte(tokenCfg.Kubeconfig))
+	if err != nil {
+		return fmt.Errorf("invalid azure.bootstrapToken.kubeconfig: %w", err)
+	}
+	cluster, user := currentClusterAndUser(kubeconfig)
+	if cluster == nil || user == nil {
+		return fmt.Errorf("invalid azure.bootstrapToken.kubeconfig: no current context, or more than one cluster and user to choose from")
+	}
+	if user.Token == "" {
+		return fmt.Errorf("invalid azure.bootstrapToken.kubeconfig: user has no token")
+	}
+	if tokenCfg.Token == "" {
+		tokenCfg.Token = user.Token
+	}
+	if c.Node.Kubelet.ServerURL == "" {
+		c.Node.Kubelet.ServerURL = cluster.Server
+	}
+	if c.Node.Kubelet.CACertData == "" && len(cluster.CertificateAuthorityData) > 0 {
+		c.Node.Kubelet.CACertData = base64.StdEncoding.EncodeToString(cluster.CertificateAuthorityData)
+	}
+	return nil
+}
+
+// currentClusterAndUser returns the cluster and user of the kubeconfig's current context, or its only cluster and
+// user when it has no current context
+func currentClusterAndUser(kubeconfig *clientcmdapi.Config) (*clientcmdapi.Cluster, *clientcmdapi.AuthInfo) {
+	if context, ok := kubeconfig.Contexts[kubeconfig.CurrentContext]; ok {
+		return kubeconfig.Clusters[context.Cluster], kubeconfig.AuthInfos[context.AuthInfo]
+	}
+	if len(kubeconfig.Clusters) != 1 || len(kubeconfig.AuthInfos) != 1 {
+		return nil, nil
+	}
+	var cluster *clientcmdapi.Cluster
+	var user *clientcmdapi.AuthInfo
+	for _, c := range kubeconfig.Clusters {
+		cluster = c
+	}
+	for _, u := range kubeconfig.AuthInfos {
+		user = u
+	}
+	return cluster, user
+}
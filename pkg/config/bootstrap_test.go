@@ -0,0 +1,83 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const testBootstrapKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: Y2EtZGF0YQ==
+    server: https://test-cluster.hcp.eastus.azmk8s.io:443
+  name: test-cluster
+contexts:
+- context:
+    cluster: test-cluster
+    user: kubelet-bootstrap
+  name: bootstrap
+current-context: bootstrap
+users:
+- name: kubelet-bootstrap
+  user:
+    token: abcdef.0123456789abcdef
+`
+
+func TestApplyBootstrapKubeconfig(t *testing.T) {
+	c := &Config{Azure: AzureConfig{BootstrapToken: &BootstrapTokenConfig{Kubeconfig: testBootstrapKubeconfig}}}
+	if err := c.applyBootstrapKubeconfig(); err != nil {
+		t.Fatalf("applyBootstrapKubeconfig() error = %v", err)
+	}
+	if got := c.Azure.BootstrapToken.Token; got != "abcdef.0123456789abcdef" {
+		t.Errorf("token = %q", got)
+	}
+	if got := c.Node.Kubelet.ServerURL; got != "https://test-cluster.hcp.eastus.azmk8s.io:443" {
+		t.Errorf("serverURL = %q", got)
+	}
+	if got := c.Node.Kubelet.CACertData; got != "Y2EtZGF0YQ==" {
+		t.Errorf("caCertData = %q", got)
+	}
+}
+
+func TestApplyBootstrapKubeconfig_ConfiguredValuesKept(t *testing.T) {
+	c := &Config{
+		Azure: AzureConfig{BootstrapToken: &BootstrapTokenConfig{Token: "zyxwvu.0123456789abcdef", Kubeconfig: testBootstrapKubeconfig}},
+		Node:  NodeConfig{Kubelet: KubeletConfig{ServerURL: "https://10.0.0.4:443"}},
+	}
+	if err := c.applyBootstrapKubeconfig(); err != nil {
+		t.Fatalf("applyBootstrapKubeconfig() error = %v", err)
+	}
+	if got := c.Azure.BootstrapToken.Token; got != "zyxwvu.0123456789abcdef" {
+		t.Errorf("token = %q", got)
+	}
+	if got := c.Node.Kubelet.ServerURL; got != "https://10.0.0.4:443" {
+		t.Errorf("serverURL = %q", got)
+	}
+	if got := c.Node.Kubelet.CACertData; got != "Y2EtZGF0YQ==" {
+		t.Errorf("caCertData = %q", got)
+	}
+}
+
+func TestApplyBootstrapKubeconfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		kubeconfig string
+		errMsg     string
+	}{
+		{name: "not a kubeconfig", kubeconfig: "{", errMsg: "invalid azure.bootstrapToken.kubeconfig"},
+		{name: "no token", kubeconfig: strings.Replace(testBootstrapKubeconfig, "token: abcdef.0123456789abcdef", "username: admin", 1), errMsg: "user has no token"},
+		{name: "no current context", kubeconfig: strings.Replace(testBootstrapKubeconfig, "current-context: bootstrap", "current-context: other", 1) +
+			"- name: admin\n  user:\n    token: admin-token\n", errMsg: "no current context"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Azure: AzureConfig{BootstrapToken: &BootstrapTokenConfig{Kubeconfig: tt.kubeconfig}}}
+			err := c.applyBootstrapKubeconfig()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("applyBootstrapKubeconfig() error = %v, want containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// agentCACertDir holds the extra CA certificates trusted by the agent process itself. The OS trust store
+// only receives them during bootstrap, after the agent has already made its first TLS connections.
+const agentCACertDir = "/etc/aks-flex-node/ca-certificates"
+
+// systemCertDirs are the directories Go reads trusted certificates from on Linux
+var systemCertDirs = []string{"/etc/ssl/certs", "/etc/pki/tls/certs"}
+
+// GetCACertificates returns the PEM encoded certificates of network.caCertificates, one per certificate.
+// Entries are inline PEM data or absolute paths to PEM files.
+func (cfg *Config) GetCACertificates() ([][]byte, error) {
+	var certificates [][]byte
+	for _, entry := range cfg.Network.CACertificates {
+		data := []byte(entry)
+		if !strings.HasPrefix(strings.TrimSpace(entry), "-----BEGIN") {
+			var err error
+			if data, err = os.ReadFile(entry); err != nil {
+				return nil, fmt.Errorf("failed to read network.caCertificates file %s: %w", entry, err)
+			}
+		}
+
+		found := false
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, fmt.Errorf("invalid certificate in network.caCertificates: %w", err)
+			}
+			certificates = append(certificates, pem.EncodeToMemory(block))
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("network.caCertificates entry %.40q contains no PEM certificate", entry)
+		}
+	}
+	return certificates, nil
+}
+
+// applyCATrustEnvironment makes the agent process trust network.caCertificates on top of the OS trust store,
+// which TLS-intercepting proxies require before the first Azure call. Like the proxy, it must run before the
+// first TLS connection, because Go loads the trusted certificates only once.
+func (cfg *Config) applyCATrustEnvironment() error {
+	certificates, err := cfg.GetCACertificates()
+	if err != nil || len(certificates) == 0 {
+		return err
+	}
+
+	if err := os.MkdirAll(agentCACertDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", agentCACertDir, err)
+	}
+	bundle := filepath.Join(agentCACertDir, "ca-bundle.crt")
+	var data []byte
+	for _, certificate := range certificates {
+		data = append(data, certificate...)
+	}
+	if err := utilio.WriteFile(bundle, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", bundle, err)
+	}
+
+	// SSL_CERT_DIR replaces Go's default directories, so they are listed as well
+	dirs := append(append([]string{}, systemCertDirs...), agentCACertDir)
+	return os.Setenv("SSL_CERT_DIR", strings.Join(dirs, ":"))
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCACertificate returns a self-signed CA certificate in PEM form
+func testCACertificate(t *testing.T, name string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestGetCACertificates(t *testing.T) {
+	inline := testCACertificate(t, "Inline CA")
+	bundlePath := filepath.Join(t.TempDir(), "bundle.pem")
+	bundle := testCACertificate(t, "Proxy CA") + testCACertificate(t, "Registry CA")
+	if err := os.WriteFile(bundlePath, []byte(bundle), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		entries []string
+		want    int
+		wantErr string
+	}{
+		{name: "none", want: 0},
+		{name: "inline PEM", entries: []string{inline}, want: 1},
+		{name: "file with two certificates", entries: []string{inline, bundlePath}, want: 3},
+		{name: "missing file", entries: []string{"/nonexistent/ca.pem"}, wantErr: "failed to read"},
+		{name: "no certificate", entries: []string{"-----BEGIN GARBAGE-----"}, wantErr: "contains no PEM certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Network: NetworkConfig{CACertificates: tt.entries}}
+			certificates, err := cfg.GetCACertificates()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetCACertificates() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCACertificates() error = %v", err)
+			}
+			if len(certificates) != tt.want {
+				t.Errorf("GetCACertificates() returned %d certificates, want %d", len(certificates), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"context"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// cloudMetadataTimeout bounds the EC2 and GCE metadata lookups at config load
+const cloudMetadataTimeout = 5 * time.Second
+
+// Node labels recording the non-Azure cloud instance. Instance type, region and zone use the well-known
+// labels kubelet is allowed to set on its own node.
+const (
+	CloudProviderLabel = "kubernetes.azure.com/flex-cloud-provider"
+	InstanceTypeLabel  = "node.kubernetes.io/instance-type"
+	RegionLabel        = "topology.kubernetes.io/region"
+	ZoneLabel          = "topology.kubernetes.io/zone"
+)
+
+// Arc machine tags recording the non-Azure cloud instance
+const (
+	cloudProviderTag = "cloudProvider"
+	instanceTypeTag  = "cloudInstanceType"
+	regionTag        = "cloudRegion"
+	zoneTag          = "cloudZone"
+)
+
+// detectCloudMetadata records the EC2 or GCE instance the machine runs on as node labels and Arc machine
+// tags when node.detectCloudMetadata is set. Labels and tags set in the configuration are kept.
+func (cfg *Config) detectCloudMetadata(ctx context.Context) {
+	if !cfg.Node.DetectCloudMetadata {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, cloudMetadataTimeout)
+	defer cancel()
+	cfg.applyCloudMetadata(utilhost.DetectCloud(ctx))
+}
+
+// applyCloudMetadata adds the detected cloud instance details without overriding configured values
+func (cfg *Config) applyCloudMetadata(metadata *utilhost.CloudMetadata) {
+	if metadata == nil {
+		return
+	}
+
+	if cfg.Node.Labels == nil {
+		cfg.Node.Labels = make(map[string]string)
+	}
+	setIfMissing(cfg.Node.Labels, CloudProviderLabel, metadata.Provider)
+	setIfMissing(cfg.Node.Labels, InstanceTypeLabel, metadata.InstanceType)
+	setIfMissing(cfg.Node.Labels, RegionLabel, metadata.Region)
+	setIfMissing(cfg.Node.Labels, ZoneLabel, metadata.Zone)
+
+	if cfg.Azure.Arc == nil {
+		return
+	}
+	if cfg.Azure.Arc.Tags == nil {
+		cfg.Azure.Arc.Tags = make(map[string]string)
+	}
+	setIfMissing(cfg.Azure.Arc.Tags, cloudProviderTag, metadata.Provider)
+	setIfMissing(cfg.Azure.Arc.Tags, instanceTypeTag, metadata.InstanceType)
+	setIfMissing(cfg.Azure.Arc.Tags, regionTag, metadata.Region)
+	setIfMissing(cfg.Azure.Arc.Tags, zoneTag, metadata.Zone)
+}
+
+// setIfMissing sets a non-empty value unless the key is already present
+func setIfMissing(values map[string]string, key, value string) {
+	if _, ok := values[key]; !ok && value != "" {
+		values[key] = value
+	}
+}
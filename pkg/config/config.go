@@ -1,10 +1,21 @@
 package config
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/pelletier/go-toml/v2"
 	"github.com/spf13/viper"
 )
 
@@ -15,6 +26,50 @@ const (
 	defaultLogLevel   = "info"
 	defaultAzureCloud = "AzurePublicCloud"
 
+	// ARM retry defaults, tuned so fleets bootstrapping in parallel ride out subscription throttling
+	defaultARMMaxRetries    = 6
+	defaultARMRetryDelay    = 4 * time.Second
+	defaultARMMaxRetryDelay = 5 * time.Minute
+
+	defaultCustomRoleName    = "AKS Flex Node"
+	defaultTokenBrokerSocket = "/run/aks-flex-node/token.sock"
+	defaultStateStorePath    = "/var/lib/aks-flex-node/state"
+
+	defaultContainerdSocketGroup = "containerd"
+	defaultKubeletSocketGroup    = "kubelet"
+
+	// Bounds of network.mtu: the IPv6 minimum and the common jumbo frame size
+	minMTU = 1280
+	maxMTU = 9000
+
+	// RoleScopeCluster assigns the Arc machine identity's roles on the target cluster resource
+	RoleScopeCluster = "cluster"
+	// RoleScopeNodeResourceGroup assigns the Arc machine identity's roles on the cluster's node resource group
+	RoleScopeNodeResourceGroup = "nodeResourceGroup"
+
+	// userAssignedIdentityType is the ARM resource type of user-assigned managed identities
+	userAssignedIdentityType = "Microsoft.ManagedIdentity/userAssignedIdentities"
+	// ArcMachineNameHostnamePlaceholder in azure.arc.machineName is replaced with the system hostname
+	ArcMachineNameHostnamePlaceholder = "{hostname}"
+
+	// Azure Resource Manager limits on Arc machine names and tags
+	maxArcTags        = 50
+	maxArcTagKeyLen   = 512
+	maxArcTagValueLen = 256
+
+	// arcPrivateLinkScopeType is the ARM resource type of Azure Arc Private Link Scopes
+	arcPrivateLinkScopeType = "Microsoft.HybridCompute/privateLinkScopes"
+
+	// ClusterLossActionNone keeps the node configured after its cluster is deleted
+	ClusterLossActionNone = "none"
+	// ClusterLossActionUnbootstrap unbootstraps the node once its cluster stays deleted for the grace period
+	ClusterLossActionUnbootstrap = "unbootstrap"
+
+	// StateStoreTypeFile stores each state key as a file
+	StateStoreTypeFile = "file"
+	// StateStoreTypeBolt stores state in an embedded bbolt database
+	StateStoreTypeBolt = "bolt"
+
 	// Environment variable prefix
 	envPrefix = "AKS_NODE_CONTROLLER"
 )
@@ -35,10 +90,17 @@ func GetConfig() *Config {
 }
 
 // LoadConfig loads configuration from a JSON file and environment variables.
-// The configPath parameter is required and cannot be empty.
+// The configPath parameter is required and cannot be empty. Overlay files are merged onto it in order,
+// after its drop-in and host layers, so the last overlay wins.
 // Environment variables can override config file values using the AKS_NODE_CONTROLLER_ prefix.
 // For example: AKS_NODE_CONTROLLER_AZURE_LOCATION=westus2
-func LoadConfig(configPath string) (*Config, error) {
+func LoadConfig(configPath string, overlays ...string) (*Config, error) {
+	return LoadConfigWithOverrides(configPath, overlays, nil)
+}
+
+// LoadConfigWithOverrides loads configuration like LoadConfig, then sets the fields of the overrides before
+// defaults and validation are applied, so they take precedence over every config file.
+func LoadConfigWithOverrides(configPath string, overlays []string, overrides []Override) (*Config, error) {
 	// Require config path to be specified
 	if configPath == "" {
 		return nil, fmt.Errorf("config file path is required")
@@ -50,9 +112,28 @@ func LoadConfig(configPath string) (*Config, error) {
 	v.AutomaticEnv()
 	v.SetEnvPrefix(envPrefix)
 
-	// Load the specified config file
-	v.SetConfigFile(configPath)
-	if err := v.ReadInConfig(); err != nil {
+	// Merge the base file with its site and host layers
+	layers, err := configLayers(configPath, overlays...)
+	if err != nil {
+		return nil, err
+	}
+	// Fetch the layers given as URLs, falling back to their cached copies
+	fetchCtx, cancel := context.WithTimeout(context.Background(), remoteConfigTimeout)
+	defer cancel()
+	remote, remoteSources, err := fetchRemoteLayers(fetchCtx, layers)
+	if err != nil {
+		return nil, err
+	}
+	merged, provenance, err := mergeLayers(layers, remote)
+	if err != nil {
+		return nil, err
+	}
+	applyOverrides(merged, overrides, provenance)
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merged config: %w", err)
+	}
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
 		return nil, fmt.Errorf("failed to read config file at %s: %w", configPath, err)
 	}
 
@@ -66,10 +147,62 @@ func LoadConfig(configPath string) (*Config, error) {
 	// This is necessary because viper unmarshals empty JSON objects {} as nil pointers
 	// Using viper.IsSet() correctly detects if the key was present in the config file
 	config.isMIExplicitlySet = v.IsSet("azure.managedIdentity")
+	config.isFirewallManageSet = v.IsSet("system.firewall.manage")
+	// viper lowercases map keys, but KubeletConfiguration fields and label keys are case-sensitive
+	config.Node.Kubelet.ConfigOverrides = rawObject(merged, "node", "kubelet", "configOverrides")
+	if labels := rawLabels(merged, "node", "labels"); labels != nil {
+		config.Node.Labels = labels
+	}
+	for _, layer := range layers {
+		config.layers = append(config.layers, LayerName(layer))
+	}
+	config.provenance = provenance
+	config.remoteSources = remoteSources
+
+	// Export the proxy and extra trusted CAs before the first outbound request below
+	if err := config.applyProxyEnvironment(); err != nil {
+		return nil, err
+	}
+	if err := config.applyCATrustEnvironment(); err != nil {
+		return nil, err
+	}
+
+	// Resolve Key Vault secret references so secrets never need to be stored in plaintext on disk
+	resolveCtx, cancel := context.WithTimeout(context.Background(), keyVaultResolveTimeout)
+	defer cancel()
+	resolver := newKeyVaultResolver(config)
+	if err := resolveSecretReferences(resolveCtx, config, resolver); err != nil {
+		return nil, fmt.Errorf("failed to resolve secret references: %w", err)
+	}
+
+	// Decrypt values encrypted with the TPM or a Key Vault data key, so a copied config file leaks no secret
+	if err := decryptValues(resolveCtx, config, newValueCipher(resolver)); err != nil {
+		return nil, fmt.Errorf("failed to decrypt config values: %w", err)
+	}
+
+	// Take the bootstrap token, server and CA from a supplied bootstrap kubeconfig
+	if err := config.applyBootstrapKubeconfig(); err != nil {
+		return nil, err
+	}
 
 	// Set defaults for any missing values
 	config.SetDefaults()
 
+	// Record the EC2 or GCE instance as node labels and Arc tags, before validation checks the tags
+	config.detectCloudMetadata(context.Background())
+
+	// Resolve the confidential computing technology from the CPU and label the node with it
+	config.detectConfidential()
+
+	// Apply the defaults of the host's distribution, such as managing firewalld on RHEL
+	config.detectDistroDefaults()
+
+	// Size kubelet's reservations from the host's CPUs and memory
+	config.detectReservedResources()
+
+	// Size the disk space eviction thresholds from the disk holding kubelet's directory
+	config.detectEvictionThresholds()
+
 	// Validate the configuration
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
@@ -88,12 +221,31 @@ func LoadConfig(configPath string) (*Config, error) {
 // SetDefaults sets default values for any missing configuration fields
 func (c *Config) SetDefaults() {
 	c.setAzureCloudDefaults()
+	c.setArcDefaults()
 	c.setAgentDefaults()
 	c.setPathDefaults()
 	c.setNodeDefaults()
+	c.setAgentPoolDefaults()
+	c.setAcrCredentialProviderDefaults()
 	c.setContainerdDefaults()
 	c.setRuncDefaults()
 	c.setNpdDefaults()
+	c.setCNIDefaults()
+	c.setKubeProxyDefaults()
+	c.setNodeLocalDNSDefaults()
+	c.setImagePrePullDefaults()
+	c.setSRIOVDefaults()
+	c.setStorageDefaults()
+	c.setNRIDefaults()
+	c.setRuntimeDefaults()
+	c.setGPUDefaults()
+	c.setRDMADefaults()
+	c.setSystemDefaults()
+	c.setTuningDefaults()
+	c.setPreflightDefaults()
+	c.setHooksDefaults()
+	c.setPluginsDefaults()
+	c.setCustomStepsDefaults()
 }
 
 func (c *Config) setAzureCloudDefaults() {
@@ -101,6 +253,22 @@ func (c *Config) setAzureCloudDefaults() {
 	if c.Azure.Cloud == "" {
 		c.Azure.Cloud = defaultAzureCloud
 	}
+	armRetry := profileARMRetry[c.GetProfile()]
+	if c.Azure.ARMRetry.MaxRetries == 0 {
+		c.Azure.ARMRetry.MaxRetries = armRetry.MaxRetries
+	}
+	if c.Azure.ARMRetry.RetryDelay == 0 {
+		c.Azure.ARMRetry.RetryDelay = armRetry.RetryDelay
+	}
+	if c.Azure.ARMRetry.MaxRetryDelay == 0 {
+		c.Azure.ARMRetry.MaxRetryDelay = armRetry.MaxRetryDelay
+	}
+}
+
+func (c *Config) setArcDefaults() {
+	if c.Azure.Arc != nil && c.Azure.Arc.CustomRole.Enabled && c.Azure.Arc.CustomRole.Name == "" {
+		c.Azure.Arc.CustomRole.Name = defaultCustomRoleName
+	}
 }
 
 func (c *Config) setAgentDefaults() {
@@ -111,6 +279,35 @@ func (c *Config) setAgentDefaults() {
 	if c.Agent.LogDir == "" {
 		c.Agent.LogDir = defaultLogDir
 	}
+	if c.Agent.TokenBroker.SocketPath == "" {
+		c.Agent.TokenBroker.SocketPath = defaultTokenBrokerSocket
+	}
+	if c.Agent.TokenBroker.RefreshBefore == 0 {
+		c.Agent.TokenBroker.RefreshBefore = 10 * time.Minute
+	}
+	if c.Agent.StateStore.Type == "" {
+		c.Agent.StateStore.Type = StateStoreTypeFile
+	}
+	if c.Agent.StateStore.Path == "" {
+		c.Agent.StateStore.Path = defaultStateStorePath
+	}
+	if c.Agent.Webhook.URL != "" && c.Agent.Webhook.Timeout == 0 {
+		c.Agent.Webhook.Timeout = 10 * time.Second
+	}
+	if c.Agent.ClusterLoss.Action == "" {
+		c.Agent.ClusterLoss.Action = ClusterLossActionNone
+	}
+	if c.Agent.ClusterLoss.GracePeriod == 0 {
+		c.Agent.ClusterLoss.GracePeriod = time.Hour
+	}
+	if c.Agent.ConfigRefresh == 0 {
+		c.Agent.ConfigRefresh = 5 * time.Minute
+	}
+	if c.Agent.Profile == "" {
+		c.Agent.Profile = ProfileProduction
+	}
+	c.Agent.Timeouts = c.GetTimeouts()
+	c.Agent.Retries = c.GetRetries()
 }
 
 func (c *Config) setPathDefaults() {
@@ -169,12 +366,27 @@ func (c *Config) setNodeDefaults() {
 	if c.Node.Kubelet.EvictionHard == nil {
 		c.Node.Kubelet.EvictionHard = make(map[string]string)
 	}
+	for signal, threshold := range defaultEvictionHard {
+		setIfMissing(c.Node.Kubelet.EvictionHard, signal, threshold)
+	}
+	if c.Node.Kubelet.ReservedResources == "" {
+		c.Node.Kubelet.ReservedResources = ReservedResourcesAuto
+	}
+	if c.Node.Kubelet.ServerTLSBootstrap {
+		c.setNodeLabelIfMissing(KubeletServingCALabel, "cluster")
+	}
 }
 
 func (c *Config) setContainerdDefaults() {
 	if c.Containerd.MetricsAddress == "" {
 		c.Containerd.MetricsAddress = "0.0.0.0:10257"
 	}
+	if c.Containerd.Snapshotter == "" {
+		c.Containerd.Snapshotter = SnapshotterOverlayfs
+	}
+	if c.Containerd.Snapshotter == SnapshotterStargz && c.Containerd.Stargz.Version == "" {
+		c.Containerd.Stargz.Version = defaultStargzVersion
+	}
 }
 
 func (c *Config) setRuncDefaults() {
@@ -191,6 +403,126 @@ func (c *Config) setNpdDefaults() {
 	}
 }
 
+func (c *Config) setCNIDefaults() {
+	if c.CNI.Provider == "" {
+		c.CNI.Provider = CNIProviderBridge
+	}
+	if c.CNI.Provider == CNIProviderCilium && c.CNI.Cilium.Version == "" {
+		c.CNI.Cilium.Version = defaultCiliumVersion
+	}
+	if c.CNI.Provider == CNIProviderCalico && c.CNI.Calico.Version == "" {
+		c.CNI.Calico.Version = defaultCalicoVersion
+	}
+	if c.CNI.Multus.Enabled && c.CNI.Multus.Version == "" {
+		c.CNI.Multus.Version = defaultMultusVersion
+	}
+	if c.CNI.Provider == CNIProviderFlannel {
+		flannel := &c.CNI.Flannel
+		if flannel.Version == "" {
+			flannel.Version = defaultFlannelVersion
+		}
+		if flannel.PluginVersion == "" {
+			flannel.PluginVersion = defaultFlannelPluginVersion
+		}
+		if flannel.Backend == "" {
+			flannel.Backend = FlannelBackendVXLAN
+		}
+	}
+}
+
+func (c *Config) setKubeProxyDefaults() {
+	// kube-proxy defaults only matter when kube-proxy runs on the node
+	if !c.KubeProxy.Enabled {
+		return
+	}
+	if c.KubeProxy.Mode == "" {
+		c.KubeProxy.Mode = KubeProxyModeIptables
+	}
+	if c.KubeProxy.RunAs == "" {
+		c.KubeProxy.RunAs = KubeProxyRunAsSystemd
+	}
+	if c.KubeProxy.Conntrack.MaxPerCore == 0 {
+		c.KubeProxy.Conntrack.MaxPerCore = 32768
+	}
+	if c.KubeProxy.Conntrack.Min == 0 {
+		c.KubeProxy.Conntrack.Min = 131072
+	}
+	if c.KubeProxy.Conntrack.TCPEstablishedTimeout == 0 {
+		c.KubeProxy.Conntrack.TCPEstablishedTimeout = 24 * time.Hour
+	}
+	if c.KubeProxy.Conntrack.TCPCloseWaitTimeout == 0 {
+		c.KubeProxy.Conntrack.TCPCloseWaitTimeout = time.Hour
+	}
+}
+
+func (c *Config) setNodeLocalDNSDefaults() {
+	if !c.NodeLocalDNS.Enabled {
+		return
+	}
+	if c.NodeLocalDNS.LocalIP == "" {
+		c.NodeLocalDNS.LocalIP = defaultNodeLocalDNSIP
+	}
+	if c.NodeLocalDNS.Version == "" {
+		c.NodeLocalDNS.Version = defaultNodeLocalDNSVersion
+	}
+}
+
+func (c *Config) setSRIOVDefaults() {
+	if !c.SRIOV.Enabled {
+		return
+	}
+	if c.SRIOV.Hugepages.Size == "" {
+		c.SRIOV.Hugepages.Size = HugepageSize2M
+	}
+}
+
+func (c *Config) setSystemDefaults() {
+	if c.System.Sockets.Containerd.Group == "" {
+		c.System.Sockets.Containerd.Group = defaultContainerdSocketGroup
+	}
+	if c.System.Sockets.Kubelet.Group == "" {
+		c.System.Sockets.Kubelet.Group = defaultKubeletSocketGroup
+	}
+
+	// Core dump defaults only matter when core dump management is enabled
+	if !c.System.CoreDump.Enabled {
+		return
+	}
+	if c.System.CoreDump.CorePattern == "" {
+		c.System.CoreDump.CorePattern = "|/lib/systemd/systemd-coredump %P %u %g %s %t 9223372036854775808 %h"
+	}
+	if c.System.CoreDump.Storage == "" {
+		c.System.CoreDump.Storage = "external"
+	}
+	if c.System.CoreDump.MaxUse == "" {
+		c.System.CoreDump.MaxUse = "2G"
+	}
+	if c.System.CoreDump.ProcessSizeMax == "" {
+		c.System.CoreDump.ProcessSizeMax = "2G"
+	}
+	if c.System.CoreDump.ExternalSizeMax == "" {
+		c.System.CoreDump.ExternalSizeMax = "2G"
+	}
+}
+
+func (c *Config) setPreflightDefaults() {
+	if c.Preflight.SNAT.EndpointsPerPod == 0 {
+		c.Preflight.SNAT.EndpointsPerPod = 8
+	}
+	if c.Preflight.SNAT.ProbeConnections == 0 {
+		c.Preflight.SNAT.ProbeConnections = 64
+	}
+	if c.Preflight.SNAT.ProbeTarget == "" {
+		c.Preflight.SNAT.ProbeTarget = c.ImageRegistryAddress()
+	}
+	if c.Preflight.Latency.MaxRTT == 0 {
+		c.Preflight.Latency.MaxRTT = 150 * time.Millisecond
+	}
+	if c.Preflight.Latency.MinThroughputMbps == 0 {
+		c.Preflight.Latency.MinThroughputMbps = 20
+	}
+}
+
 // AKSClusterResourceIDPattern is AKS cluster resource ID regex pattern with capture groups
 // Format: /subscriptions/{subscription-id}/resourceGroups/{resource-group}/providers/Microsoft.ContainerService/managedClusters/{cluster-name}
 // Pattern is case insensitive to handle variations in Azure resource path casing
@@ -211,6 +543,18 @@ func validateAzureResourceID(resourceID string) error {
 	return nil
 }
 
+// validateResourceType checks that resourceID is a valid ARM resource ID of the given resource type
+func validateResourceType(resourceID, resourceType string) error {
+	id, err := arm.ParseResourceID(resourceID)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(id.ResourceType.String(), resourceType) {
+		return fmt.Errorf("expected a %s resource, got %s", resourceType, id.ResourceType)
+	}
+	return nil
+}
+
 // validateBootstrapToken validates the bootstrap token configuration
 func validateBootstrapToken(cfg *Config) error {
 	tokenCfg := cfg.Azure.BootstrapToken
@@ -236,6 +580,19 @@ func validateBootstrapToken(cfg *Config) error {
 	return nil
 }
 
+// validatePrivateCluster checks that private clusters are joined through their private FQDN. The public FQDN
+// of a private cluster either does not exist or resolves to the private endpoint only through public DNS, so
+// the node would depend on internet DNS for every API server connection.
+func (c *Config) validatePrivateCluster() error {
+	if !c.IsPrivateCluster() || c.Node.Kubelet.ServerURL == "" {
+		return nil
+	}
+	if host := hostOf(c.Node.Kubelet.ServerURL); strings.Contains(host, ".hcp.") && strings.HasSuffix(host, ".azmk8s.io") {
+		return fmt.Errorf("invalid node.kubelet.serverURL: %s. azure.targetCluster.private is set, use the private FQDN of the cluster (<name>.privatelink.<region>.azmk8s.io or the custom private DNS zone)", c.Node.Kubelet.ServerURL)
+	}
+	return nil
+}
+
 // validLogLevels defines the allowed logging levels for the agent
 var validLogLevels = map[string]bool{
 	"debug":   true,
@@ -250,6 +607,430 @@ var validAzureClouds = map[string]bool{
 	"AzurePublicCloud": true,
 }
 
+// validCoreDumpStorage defines the storage modes supported by systemd-coredump
+var validCoreDumpStorage = map[string]bool{
+	"external": true,
+	"journal":  true,
+	"none":     true,
+}
+
+// validateCoreDump validates the core dump configuration
+func validateCoreDump(cfg *CoreDumpConfig) error {
+	if !validCoreDumpStorage[cfg.Storage] {
+		return fmt.Errorf("invalid system.coreDump.storage: %s. Valid values are: external, journal, none", cfg.Storage)
+	}
+	if strings.ContainsAny(cfg.CorePattern, "\n") {
+		return fmt.Errorf("system.coreDump.corePattern must be a single line")
+	}
+	if len(cfg.CorePattern) > 127 {
+		// The kernel silently truncates core_pattern to 128 bytes (including the NUL terminator)
+		return fmt.Errorf("system.coreDump.corePattern must be at most 127 characters")
+	}
+	return nil
+}
+
+// CNI providers the node can be prepared for
+const (
+	CNIProviderBridge  = "bridge"
+	CNIProviderCilium  = "cilium"
+	CNIProviderCalico  = "calico"
+	CNIProviderFlannel = "flannel"
+)
+
+// flanneld backends
+const (
+	FlannelBackendVXLAN  = "vxlan"
+	FlannelBackendHostGW = "host-gw"
+)
+
+// Releases whose CNI plugins are installed when cni.cilium.version or cni.calico.version is unset
+const (
+	defaultCiliumVersion = "1.16.6"
+	defaultCalicoVersion = "3.29.1"
+
+	defaultFlannelVersion       = "0.26.2"
+	defaultFlannelPluginVersion = "1.6.2-flannel1"
+
+	defaultMultusVersion = "4.1.4"
+)
+
+// cniReleasePattern matches a release version of a CNI provider, e.g. 1.16.6
+var cniReleasePattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+
+// flannelPluginReleasePattern matches a flannel CNI plugin release, e.g. 1.6.2-flannel1
+var flannelPluginReleasePattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-flannel\d+)?$`)
+
+// validateCNI validates the CNI provider configuration
+func validateCNI(cfg *CNIConfig) error {
+	if cfg.Version != "" && !cniReleasePattern.MatchString(cfg.Version) {
+		return fmt.Errorf("invalid cni.version: %s. Expected a release version such as 1.5.1", cfg.Version)
+	}
+	if cfg.ConfigTemplateFile != "" && cfg.Provider != "" && cfg.Provider != CNIProviderBridge {
+		return fmt.Errorf("invalid cni.configTemplateFile: the %s agent writes the CNI configuration. Expected cni.provider bridge", cfg.Provider)
+	}
+	if cfg.Multus.Enabled && cfg.Multus.Version != "" && !cniReleasePattern.MatchString(cfg.Multus.Version) {
+		return fmt.Errorf("invalid cni.multus.version: %s. Expected a release version such as 4.1.4", cfg.Multus.Version)
+	}
+	switch cfg.Provider {
+	case "", CNIProviderBridge:
+	case CNIProviderCilium:
+		if cfg.Cilium.Version != "" && !cniReleasePattern.MatchString(cfg.Cilium.Version) {
+			return fmt.Errorf("invalid cni.cilium.version: %s. Expected a release version such as 1.16.6", cfg.Cilium.Version)
+		}
+	case CNIProviderCalico:
+		if cfg.Calico.Version != "" && !cniReleasePattern.MatchString(cfg.Calico.Version) {
+			return fmt.Errorf("invalid cni.calico.version: %s. Expected a release version such as 3.29.1", cfg.Calico.Version)
+		}
+	case CNIProviderFlannel:
+		return validateFlannel(&cfg.Flannel)
+	default:
+		return fmt.Errorf("invalid cni.provider: %s. Valid values are: bridge, cilium, calico, flannel", cfg.Provider)
+	}
+	return nil
+}
+
+// defaultStargzVersion is the stargz-snapshotter release installed when containerd.stargz.version is unset
+const defaultStargzVersion = "0.16.3"
+
+// containerd snapshotters, which store the unpacked image layers and container filesystems
+const (
+	SnapshotterOverlayfs = "overlayfs"
+	SnapshotterNative    = "native"
+	SnapshotterZFS       = "zfs"
+	SnapshotterEROFS     = "erofs"
+	SnapshotterStargz    = "stargz"
+)
+
+// validateSnapshotter validates containerd.snapshotter. Whether the kernel and filesystem support it is checked
+// on the node, before containerd is configured.
+func validateSnapshotter(cfg *ContainerdConfig) error {
+	switch cfg.Snapshotter {
+	case "", SnapshotterOverlayfs, SnapshotterNative, SnapshotterZFS:
+	case SnapshotterStargz:
+		if cfg.Stargz.Version != "" && !cniReleasePattern.MatchString(cfg.Stargz.Version) {
+			return fmt.Errorf("invalid containerd.stargz.version: %s. Expected a release version such as 0.16.3", cfg.Stargz.Version)
+		}
+	case SnapshotterEROFS:
+		// The erofs snapshotter first shipped with containerd 2.1, newer than the default version
+		if !versionAtLeast(cfg.Version, 2, 1) {
+			return fmt.Errorf("invalid containerd.snapshotter: erofs requires containerd.version 2.1 or later")
+		}
+	default:
+		return fmt.Errorf("invalid containerd.snapshotter: %s. Valid values are: overlayfs, native, zfs, erofs, stargz", cfg.Snapshotter)
+	}
+	return nil
+}
+
+// versionAtLeast reports whether a release version such as 2.1.4 is major.minor or newer
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	releaseMajor, errMajor := strconv.Atoi(parts[0])
+	releaseMinor, errMinor := strconv.Atoi(parts[1])
+	if errMajor != nil || errMinor != nil {
+		return false
+	}
+	if releaseMajor != major {
+		return releaseMajor > major
+	}
+	return releaseMinor >= minor
+}
+
+// validateContainerdConfigPatches checks that each containerd.configPatches entry is a TOML document
+func validateContainerdConfigPatches(patches []string) error {
+	for i, patch := range patches {
+		var fragment map[string]any
+		if err := toml.Unmarshal([]byte(patch), &fragment); err != nil {
+			return fmt.Errorf("invalid containerd.configPatches[%d]: %v. Expected a TOML fragment of config.toml", i, err)
+		}
+		if _, ok := fragment["version"]; ok {
+			return fmt.Errorf("invalid containerd.configPatches[%d]: the configuration version cannot be changed", i)
+		}
+	}
+	return nil
+}
+
+// validateFlannel validates the flannel settings
+func validateFlannel(cfg *FlannelConfig) error {
+	if cfg.Version != "" && !cniReleasePattern.MatchString(cfg.Version) {
+		return fmt.Errorf("invalid cni.flannel.version: %s. Expected a release version such as 0.26.2", cfg.Version)
+	}
+	if cfg.PluginVersion != "" && !flannelPluginReleasePattern.MatchString(cfg.PluginVersion) {
+		return fmt.Errorf("invalid cni.flannel.pluginVersion: %s. Expected a release version such as 1.6.2-flannel1", cfg.PluginVersion)
+	}
+	switch cfg.Backend {
+	case "", FlannelBackendVXLAN, FlannelBackendHostGW:
+	default:
+		return fmt.Errorf("invalid cni.flannel.backend: %s. Valid values are: vxlan, host-gw", cfg.Backend)
+	}
+	if cfg.PodCIDR != "" {
+		cidrs := strings.Split(cfg.PodCIDR, ",")
+		if len(cidrs) > 2 {
+			return fmt.Errorf("invalid cni.flannel.podCIDR: %s. Expected at most one CIDR per IP family", cfg.PodCIDR)
+		}
+		for _, cidr := range cidrs {
+			if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+				return fmt.Errorf("invalid cni.flannel.podCIDR: %s. Expected a CIDR such as 10.244.0.0/16", cfg.PodCIDR)
+			}
+		}
+	}
+	return nil
+}
+
+// kube-proxy modes and the ways it can run on the node
+const (
+	KubeProxyModeIptables   = "iptables"
+	KubeProxyModeIPVS       = "ipvs"
+	KubeProxyRunAsSystemd   = "systemd"
+	KubeProxyRunAsStaticPod = "staticPod"
+)
+
+// validateKubeProxy validates the kube-proxy configuration
+func validateKubeProxy(c *Config) error {
+	cfg := &c.KubeProxy
+	switch cfg.Mode {
+	case "", KubeProxyModeIptables, KubeProxyModeIPVS:
+	default:
+		return fmt.Errorf("invalid kubeProxy.mode: %s. Valid values are: iptables, ipvs", cfg.Mode)
+	}
+	switch cfg.RunAs {
+	case "", KubeProxyRunAsSystemd:
+	case KubeProxyRunAsStaticPod:
+		// The static pod reads the kubelet kubeconfig, whose exec credential scripts need the host's tools;
+		// only the client certificate of TLS bootstrap works from inside the container
+		if !c.IsBootstrapTokenConfigured() {
+			return fmt.Errorf("kubeProxy.runAs staticPod requires bootstrap token authentication. Use systemd with Arc, service principal or managed identity")
+		}
+	default:
+		return fmt.Errorf("invalid kubeProxy.runAs: %s. Valid values are: systemd, staticPod", cfg.RunAs)
+	}
+	for _, cidr := range strings.Split(cfg.ClusterCIDR, ",") {
+		if cidr == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			return fmt.Errorf("invalid kubeProxy.clusterCIDR: %s. Expected a range such as 10.244.0.0/16", cfg.ClusterCIDR)
+		}
+	}
+	conntrack := cfg.Conntrack
+	if conntrack.MaxPerCore < 0 || conntrack.Min < 0 || conntrack.TCPEstablishedTimeout < 0 || conntrack.TCPCloseWaitTimeout < 0 {
+		return fmt.Errorf("kubeProxy.conntrack values must not be negative")
+	}
+	return nil
+}
+
+// Defaults of the node-local DNS cache, whose link-local address is the one upstream manifests use
+const (
+	defaultNodeLocalDNSIP      = "169.254.20.10"
+	defaultNodeLocalDNSVersion = "1.23.1"
+)
+
+// validateNodeLocalDNS validates the node-local DNS cache settings
+func validateNodeLocalDNS(cfg *NodeLocalDNSConfig) error {
+	if cfg.LocalIP != "" {
+		ip := net.ParseIP(cfg.LocalIP)
+		if ip == nil || ip.To4() == nil {
+			return fmt.Errorf("invalid nodeLocalDNS.localIP: %s. Expected an IPv4 address such as %s", cfg.LocalIP, defaultNodeLocalDNSIP)
+		}
+	}
+	if cfg.Version != "" && !cniReleasePattern.MatchString(cfg.Version) {
+		return fmt.Errorf("invalid nodeLocalDNS.version: %s. Expected a release version such as %s", cfg.Version, defaultNodeLocalDNSVersion)
+	}
+	return nil
+}
+
+// ClusterDNS returns the DNS server kubelet hands to pods: the node-local cache when enabled, otherwise the
+// cluster DNS service
+func (cfg *Config) ClusterDNS() string {
+	if cfg.NodeLocalDNS.Enabled {
+		return cfg.NodeLocalDNS.LocalIP
+	}
+	return cfg.Node.Kubelet.DNSServiceIP
+}
+
+// Hugepage sizes DPDK workloads can reserve
+const (
+	HugepageSize2M = "2M"
+	HugepageSize1G = "1G"
+)
+
+// validateSRIOV validates the SR-IOV devices and hugepages
+func validateSRIOV(cfg *SRIOVConfig) error {
+	pfs := map[string]bool{}
+	for _, device := range cfg.Devices {
+		if device.PF == "" || strings.ContainsAny(device.PF, "/ ") {
+			return fmt.Errorf("invalid sriov.devices pf: %q. Expected a network interface name such as ens1f0", device.PF)
+		}
+		if pfs[device.PF] {
+			return fmt.Errorf("invalid sriov.devices: %s is listed more than once", device.PF)
+		}
+		pfs[device.PF] = true
+		if device.NumVFs < 1 {
+			return fmt.Errorf("invalid sriov.devices numVFs for %s: %d. Expected at least 1", device.PF, device.NumVFs)
+		}
+		vfs := map[int]bool{}
+		for _, vf := range device.VFIOVFs {
+			if vf < 0 || vf >= device.NumVFs || vfs[vf] {
+				return fmt.Errorf("invalid sriov.devices vfioVFs for %s: %v. Expected distinct indexes below numVFs %d", device.PF, device.VFIOVFs, device.NumVFs)
+			}
+			vfs[vf] = true
+		}
+	}
+	switch cfg.Hugepages.Size {
+	case "", HugepageSize2M, HugepageSize1G:
+	default:
+		return fmt.Errorf("invalid sriov.hugepages.size: %s. Valid values are: 2M, 1G", cfg.Hugepages.Size)
+	}
+	if cfg.Hugepages.Count < 0 {
+		return fmt.Errorf("invalid sriov.hugepages.count: %d. Expected 0 or more", cfg.Hugepages.Count)
+	}
+	return nil
+}
+
+// validateHugepages validates system.hugepages, which must not compete with sriov.hugepages for the same
+// kernel settings
+func validateHugepages(c *Config) error {
+	hugepages := &c.System.Hugepages
+	if hugepages.Pages2Mi < 0 || hugepages.Pages1Gi < 0 {
+		return fmt.Errorf("invalid system.hugepages: %d 2Mi and %d 1Gi pages. Expected 0 or more", hugepages.Pages2Mi, hugepages.Pages1Gi)
+	}
+	if (hugepages.Pages2Mi > 0 || hugepages.Pages1Gi > 0) && c.SRIOV.Enabled && c.SRIOV.Hugepages.Count > 0 {
+		return fmt.Errorf("invalid system.hugepages: sriov.hugepages also reserves hugepages. Configure only one of them")
+	}
+	return nil
+}
+
+// Host firewall backends the agent can program
+const (
+	FirewallBackendUFW       = "ufw"
+	FirewallBackendFirewalld = "firewalld"
+	FirewallBackendNftables  = "nftables"
+)
+
+// firewallPortPattern matches a port or port range with its protocol, e.g. 10250/tcp or 30000-32767/udp
+var firewallPortPattern = regexp.MustCompile(`^\d{1,5}(-\d{1,5})?/(tcp|udp)$`)
+
+// validateFirewall validates the host firewall configuration
+func validateFirewall(cfg *FirewallConfig) error {
+	switch cfg.Backend {
+	case "", FirewallBackendUFW, FirewallBackendFirewalld, FirewallBackendNftables:
+	default:
+		return fmt.Errorf("invalid system.firewall.backend: %s. Valid values are: ufw, firewalld, nftables", cfg.Backend)
+	}
+	for _, port := range cfg.ExtraPorts {
+		if !firewallPortPattern.MatchString(port) {
+			return fmt.Errorf("invalid system.firewall.extraPorts entry: %s. Expected <port>[-<port>]/<tcp|udp>", port)
+		}
+	}
+	return nil
+}
+
+// validateWebhook checks that bootstrap results are only sent signed and over HTTPS
+func validateWebhook(cfg *WebhookConfig) error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid agent.webhook.url: %s", cfg.URL)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("agent.webhook.url must use https")
+	}
+	if cfg.Secret == "" {
+		return fmt.Errorf("agent.webhook.secret is required to sign webhook payloads")
+	}
+	return nil
+}
+
+// validArcProxyBypass lists the traffic categories azcmagent can send around the proxy
+var validArcProxyBypass = map[string]bool{
+	"AAD": true,
+	"ARM": true,
+	"Arc": true,
+}
+
+// validateArcProxy checks the Arc agent proxy URL and bypass list
+func validateArcProxy(cfg *ArcProxyConfig) error {
+	if cfg.URL == "" {
+		if len(cfg.Bypass) > 0 {
+			return fmt.Errorf("azure.arc.proxy.bypass requires azure.arc.proxy.url")
+		}
+		return nil
+	}
+	// The URL may carry credentials, so errors show it with the password masked
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return fmt.Errorf("invalid azure.arc.proxy.url. Expected http://host:port or https://host:port")
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid azure.arc.proxy.url: %s. Expected http://host:port or https://host:port", u.Redacted())
+	}
+	for _, bypass := range cfg.Bypass {
+		if !validArcProxyBypass[bypass] {
+			return fmt.Errorf("invalid azure.arc.proxy.bypass entry: %s. Valid values are: AAD, ARM, Arc", bypass)
+		}
+	}
+	return nil
+}
+
+// arcMachineNamePattern matches valid Microsoft.HybridCompute/machines resource names
+var arcMachineNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_\-\.]{1,54}$`)
+
+// validateArcMachine checks the Arc machine resource name and tags against Azure Resource Manager limits,
+// so a bad naming convention fails before azcmagent connect does
+func validateArcMachine(c *Config) error {
+	name := c.GetArcMachineName()
+	if !arcMachineNamePattern.MatchString(name) || strings.HasSuffix(name, ".") {
+		return fmt.Errorf("invalid azure.arc.machineName: %q. Must be 1-54 letters, digits, hyphens, underscores or periods and must not end with a period", name)
+	}
+
+	tags := c.GetArcTags()
+	if len(tags) > maxArcTags {
+		return fmt.Errorf("azure.arc.tags has %d tags, at most %d are allowed", len(tags), maxArcTags)
+	}
+	for key, value := range tags {
+		// azcmagent connect takes the tags as a single comma-separated list
+		if key == "" || len(key) > maxArcTagKeyLen || strings.ContainsAny(key, "<>%&\\?/,=") {
+			return fmt.Errorf("invalid azure.arc.tags key: %q. Must be 1-%d characters without <>%%&\\?/,=", key, maxArcTagKeyLen)
+		}
+		if len(value) > maxArcTagValueLen || strings.Contains(value, ",") {
+			return fmt.Errorf("invalid azure.arc.tags value for %s: must be at most %d characters without commas", key, maxArcTagValueLen)
+		}
+	}
+	return nil
+}
+
+// validateRoleScope checks that a role scope is one of the named scopes or an Azure resource ID
+func validateRoleScope(scope string) error {
+	switch {
+	case scope == "", scope == RoleScopeCluster, scope == RoleScopeNodeResourceGroup:
+		return nil
+	case strings.HasPrefix(strings.ToLower(scope), "/subscriptions/"):
+		return nil
+	default:
+		return fmt.Errorf("invalid azure.arc.roleScope: %s. Valid values are: %s, %s or a resource ID starting with /subscriptions/",
+			scope, RoleScopeCluster, RoleScopeNodeResourceGroup)
+	}
+}
+
+// systemNamePattern matches group and user names accepted by groupadd and useradd
+var systemNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+
+func validateSocketAccess(field string, cfg *SocketAccessConfig) error {
+	if cfg.Group != "" && !systemNamePattern.MatchString(cfg.Group) {
+		return fmt.Errorf("invalid %s.group: %q is not a valid group name", field, cfg.Group)
+	}
+	if cfg.Group == "root" {
+		return fmt.Errorf("invalid %s.group: a dedicated group is required, not root", field)
+	}
+	for _, user := range cfg.Users {
+		if !systemNamePattern.MatchString(user) {
+			return fmt.Errorf("invalid %s.users: %q is not a valid user name", field, user)
+		}
+	}
+	return nil
+}
+
 // Validate validates the configuration and ensures all required fields are set
 func (c *Config) Validate() error {
 	// Validate required Azure configuration (core requirements for Arc discovery)
@@ -271,16 +1052,65 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid azure.targetCluster.resourceId: %w", err)
 	}
 
+	if err := c.validatePrivateCluster(); err != nil {
+		return err
+	}
+
 	// Validate Azure cloud
 	if !validAzureClouds[c.Azure.Cloud] {
 		return fmt.Errorf("invalid azure.cloud: %s. Valid values are: AzurePublicCloud", c.Azure.Cloud)
 	}
 
+	retry := c.Azure.ARMRetry
+	if retry.MaxRetries < 0 || retry.RetryDelay < 0 || retry.MaxRetryDelay < 0 || retry.TryTimeout < 0 {
+		return fmt.Errorf("azure.armRetry values must not be negative")
+	}
+	if retry.RetryDelay > 0 && retry.MaxRetryDelay > 0 && retry.RetryDelay > retry.MaxRetryDelay {
+		return fmt.Errorf("azure.armRetry.retryDelay must not exceed azure.armRetry.maxRetryDelay")
+	}
+
 	// Validate log level
 	if !validLogLevels[c.Agent.LogLevel] {
 		return fmt.Errorf("invalid agent.logLevel: %s. Valid values are: debug, info, warning, error", c.Agent.LogLevel)
 	}
 
+	if c.Agent.Profile != "" && c.Agent.Profile != ProfileProduction && c.Agent.Profile != ProfileFastFail {
+		return fmt.Errorf("invalid agent.profile: %s. Valid values are: production, fastFail", c.Agent.Profile)
+	}
+	timeouts := c.Agent.Timeouts
+	if timeouts.ArcRegistration < 0 || timeouts.IdentityPropagation < 0 || timeouts.RoleAssignmentRetries < 0 ||
+		timeouts.PermissionPropagation < 0 || timeouts.Download < 0 {
+		return fmt.Errorf("agent.timeouts values must not be negative")
+	}
+	if err := validateRetries(c.Agent.Retries); err != nil {
+		return err
+	}
+
+	if action := c.Agent.ClusterLoss.Action; action != "" && action != ClusterLossActionNone && action != ClusterLossActionUnbootstrap {
+		return fmt.Errorf("invalid agent.clusterLoss.action: %s. Valid values are: none, unbootstrap", action)
+	}
+	if c.Agent.ClusterLoss.GracePeriod < 0 {
+		return fmt.Errorf("agent.clusterLoss.gracePeriod must not be negative")
+	}
+	if c.Agent.ConfigRefresh < 0 {
+		return fmt.Errorf("agent.configRefresh must not be negative")
+	}
+
+	// Validate state store type
+	if c.Agent.StateStore.Type != "" && c.Agent.StateStore.Type != StateStoreTypeFile && c.Agent.StateStore.Type != StateStoreTypeBolt {
+		return fmt.Errorf("invalid agent.stateStore.type: %s. Valid values are: file, bolt", c.Agent.StateStore.Type)
+	}
+	if c.Agent.StateStore.ConfigMapMirror && !filepath.IsAbs(c.Agent.StateStore.MirrorKubeconfig) {
+		return fmt.Errorf("agent.stateStore.configMapMirror requires agent.stateStore.mirrorKubeconfig, the absolute path of a kubeconfig " +
+			"allowed to get, create and patch ConfigMaps in kube-system, since node credentials cannot")
+	}
+
+	if c.Agent.Webhook.URL != "" {
+		if err := validateWebhook(&c.Agent.Webhook); err != nil {
+			return err
+		}
+	}
+
 	// Validate authentication configuration - ensure mutual exclusivity
 	authMethodCount := 0
 	if c.IsARCEnabled() {
@@ -292,15 +1122,62 @@ func (c *Config) Validate() error {
 	if c.IsMIConfigured() {
 		authMethodCount++
 	}
-	if c.IsBootstrapTokenConfigured() {
+	// Arc may manage the machine while kubelet joins with a bootstrap token, for clusters without Azure RBAC
+	if c.IsBootstrapTokenConfigured() && !c.IsARCEnabled() {
+		authMethodCount++
+	}
+	if c.IsFederatedIdentityConfigured() {
 		authMethodCount++
 	}
 
 	if authMethodCount == 0 {
-		return fmt.Errorf("at least one authentication method must be configured: Arc, Service Principal, Managed Identity, or Bootstrap Token")
+		return fmt.Errorf("at least one authentication method must be configured: Arc, Service Principal, Managed Identity, Federated Identity, or Bootstrap Token")
 	}
 	if authMethodCount > 1 {
-		return fmt.Errorf("only one authentication method can be enabled at a time: Arc, Service Principal, Managed Identity, or Bootstrap Token")
+		return fmt.Errorf("only one authentication method can be enabled at a time: Arc, Service Principal, Managed Identity, Federated Identity, or Bootstrap Token")
+	}
+
+	// A partially specified federated identity is almost certainly a mistake rather than an intent to use another method
+	if c.Azure.FederatedIdentity != nil && !c.IsFederatedIdentityConfigured() {
+		return fmt.Errorf("azure.federatedIdentity requires tenantId, clientId and tokenFile")
+	}
+
+	if mi := c.Azure.ManagedIdentity; mi != nil && mi.ResourceID != "" {
+		if mi.ClientID != "" {
+			return fmt.Errorf("azure.managedIdentity accepts either clientId or resourceId, not both")
+		}
+		if err := validateResourceType(mi.ResourceID, userAssignedIdentityType); err != nil {
+			return fmt.Errorf("invalid azure.managedIdentity.resourceId: %w", err)
+		}
+	}
+
+	// Azure-assigned identities exist only in their home tenant, so they can neither be granted roles on
+	// a cluster in another tenant nor obtain tokens it accepts (role assignment fails with PrincipalNotFound)
+	if c.IsCrossTenant() && (c.IsARCEnabled() || c.IsMIConfigured()) {
+		return fmt.Errorf("azure.targetCluster.tenantId differs from azure.tenantId: cross-tenant join requires a multi-tenant " +
+			"service principal or federated identity provisioned in the cluster tenant, Arc and managed identities cannot be used")
+	}
+
+	if c.Azure.Arc != nil {
+		if c.Azure.Arc.Enabled {
+			if err := validateArcMachine(c); err != nil {
+				return err
+			}
+		}
+		if err := validateRoleScope(c.Azure.Arc.RoleScope); err != nil {
+			return err
+		}
+		if err := validateArcProxy(&c.Azure.Arc.Proxy); err != nil {
+			return err
+		}
+		if scope := c.Azure.Arc.PrivateLinkScope; scope != "" {
+			if err := validateResourceType(scope, arcPrivateLinkScopeType); err != nil {
+				return fmt.Errorf("invalid azure.arc.privateLinkScope: %w", err)
+			}
+		}
+		if path := c.Azure.Arc.InstallerPath; path != "" && !filepath.IsAbs(path) {
+			return fmt.Errorf("invalid azure.arc.installerPath: %s. Must be an absolute path", path)
+		}
 	}
 
 	// Validate bootstrap token if configured
@@ -310,6 +1187,219 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.System.CoreDump.Enabled {
+		if err := validateCoreDump(&c.System.CoreDump); err != nil {
+			return err
+		}
+	}
+
+	if err := validateEviction(&c.Node.Kubelet); err != nil {
+		return err
+	}
+
+	if err := validateKubeletDiskSettings(&c.Node.Kubelet); err != nil {
+		return err
+	}
+
+	if err := validateReservedResources(&c.Node.Kubelet); err != nil {
+		return err
+	}
+
+	if err := validateKubeletResourceManagers(&c.Node.Kubelet); err != nil {
+		return err
+	}
+
+	if err := validateKubeletSwap(c); err != nil {
+		return err
+	}
+
+	if err := validateAcrCredentialProvider(c); err != nil {
+		return err
+	}
+
+	if err := validateKubeletConfigOverrides(c.Node.Kubelet.ConfigOverrides); err != nil {
+		return err
+	}
+
+	if err := validateAgentPool(c); err != nil {
+		return err
+	}
+
+	if err := validateNodeLabels(c.Node.Labels); err != nil {
+		return err
+	}
+
+	if err := validateNodeTaints(c.Node.Taints); err != nil {
+		return err
+	}
+
+	if err := validateContainerdGC(&c.Containerd.GC); err != nil {
+		return err
+	}
+
+	if err := validateCNI(&c.CNI); err != nil {
+		return err
+	}
+
+	if c.SRIOV.Enabled {
+		if err := validateSRIOV(&c.SRIOV); err != nil {
+			return err
+		}
+	}
+
+	if err := validateHugepages(c); err != nil {
+		return err
+	}
+
+	if err := validateTuning(c); err != nil {
+		return err
+	}
+
+	if err := validateContainerRuntime(c); err != nil {
+		return err
+	}
+
+	if err := validateOCIRuntime(&c.Runtime); err != nil {
+		return err
+	}
+
+	if c.Runtime.GVisor.Enabled {
+		if err := validateGVisor(&c.Runtime.GVisor); err != nil {
+			return err
+		}
+	}
+
+	if c.Runtime.Kata.Enabled {
+		if err := validateKata(&c.Runtime.Kata); err != nil {
+			return err
+		}
+	}
+
+	if c.Runtime.Confidential.Enabled {
+		if err := validateConfidential(&c.Runtime); err != nil {
+			return err
+		}
+	}
+
+	if c.GPU.MIG.Enabled {
+		if err := validateMIG(&c.GPU.MIG); err != nil {
+			return err
+		}
+	}
+
+	if c.RDMA.Enabled {
+		if err := validateRDMA(&c.RDMA); err != nil {
+			return err
+		}
+	}
+
+	if c.Storage.DataDisk.Enabled {
+		if err := validateDataDisk(&c.Storage.DataDisk, c.Paths.Kubernetes.KubeletDir); err != nil {
+			return err
+		}
+	}
+
+	if c.ImagePrePull.Enabled {
+		if err := validateImagePrePull(&c.ImagePrePull); err != nil {
+			return err
+		}
+	}
+
+	if c.KubeProxy.Enabled {
+		if err := validateKubeProxy(c); err != nil {
+			return err
+		}
+	}
+
+	if c.NodeLocalDNS.Enabled {
+		if err := validateNodeLocalDNS(&c.NodeLocalDNS); err != nil {
+			return err
+		}
+	}
+
+	if err := validateProxy(&c.Network.Proxy); err != nil {
+		return err
+	}
+
+	if err := validateImageRegistry(c.Images.Registry); err != nil {
+		return err
+	}
+
+	if err := validateRegistryMirrors(c.Containerd.Mirrors); err != nil {
+		return err
+	}
+	if err := validateRegistryAuth(c); err != nil {
+		return err
+	}
+	if err := validateContainerdConfigPatches(c.Containerd.ConfigPatches); err != nil {
+		return err
+	}
+	if err := validateSnapshotter(&c.Containerd); err != nil {
+		return err
+	}
+	if c.Containerd.NRI.Enabled {
+		if err := validateNRI(&c.Containerd); err != nil {
+			return err
+		}
+	}
+
+	if err := validateIPFamilies(c.Network.IPFamilies); err != nil {
+		return err
+	}
+	if err := validateNodeIP(&c.Node.IP, c.IsDualStack()); err != nil {
+		return err
+	}
+	if err := validatePodIPv6CIDR(c.Network.PodIPv6CIDR, c.IsDualStack()); err != nil {
+		return err
+	}
+
+	if c.Network.MTU != 0 && (c.Network.MTU < minMTU || c.Network.MTU > maxMTU) {
+		return fmt.Errorf("invalid network.mtu: %d. Expected a value between %d and %d, or 0 to detect it", c.Network.MTU, minMTU, maxMTU)
+	}
+
+	if c.Preflight.SNAT.EndpointsPerPod < 0 || c.Preflight.SNAT.AvailablePorts < 0 || c.Preflight.SNAT.ProbeConnections < 0 {
+		return fmt.Errorf("preflight.snat values must not be negative")
+	}
+	if c.Preflight.SNAT.ProbeTarget != "" {
+		if _, _, err := net.SplitHostPort(c.Preflight.SNAT.ProbeTarget); err != nil {
+			return fmt.Errorf("invalid preflight.snat.probeTarget: %w", err)
+		}
+	}
+	if c.Preflight.Latency.MaxRTT < 0 || c.Preflight.Latency.MinThroughputMbps < 0 {
+		return fmt.Errorf("preflight.latency values must not be negative")
+	}
+	if endpoint := c.Preflight.Latency.ThroughputURL; endpoint != "" {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid preflight.latency.throughputURL: %s. Expected an http or https URL", endpoint)
+		}
+	}
+	for _, endpoint := range c.Preflight.Connectivity.Endpoints {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid preflight.connectivity.endpoints entry: %s. Expected an http or https URL", endpoint)
+		}
+	}
+
+	if err := validateFirewall(&c.System.Firewall); err != nil {
+		return err
+	}
+
+	if err := validateSocketAccess("system.sockets.containerd", &c.System.Sockets.Containerd); err != nil {
+		return err
+	}
+	if err := validateSocketAccess("system.sockets.kubelet", &c.System.Sockets.Kubelet); err != nil {
+		return err
+	}
+
+	if err := validateHooks(c.Hooks); err != nil {
+		return err
+	}
+	if err := validatePlugins(c.Plugins); err != nil {
+		return err
+	}
+	if err := validateCustomSteps(c.CustomSteps, c.Plugins); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultAcrCredentialProviderVersion is the cloud-provider-azure release the acr-credential-provider is taken from
+const defaultAcrCredentialProviderVersion = "1.32.4"
+
+// defaultAcrMatchImages are the login servers of Azure Container Registry in every cloud, as on AKS nodes
+var defaultAcrMatchImages = []string{"*.azurecr.io", "*.azurecr.cn", "*.azurecr.de", "*.azurecr.us"}
+
+func (c *Config) setAcrCredentialProviderDefaults() {
+	provider := &c.Node.Kubelet.AcrCredentialProvider
+	if !provider.Enabled {
+		return
+	}
+	if provider.Version == "" {
+		provider.Version = defaultAcrCredentialProviderVersion
+	}
+	if len(provider.MatchImages) == 0 {
+		provider.MatchImages = append([]string(nil), defaultAcrMatchImages...)
+	}
+	if provider.CacheDuration == "" {
+		provider.CacheDuration = "10m"
+	}
+}
+
+// validateAcrCredentialProvider validates node.kubelet.acrCredentialProvider. The provider requests tokens from
+// the Azure instance metadata endpoint or with a service principal or federated identity; it cannot reach the
+// identity endpoint of Arc machines.
+func validateAcrCredentialProvider(c *Config) error {
+	provider := &c.Node.Kubelet.AcrCredentialProvider
+	if !provider.Enabled {
+		return nil
+	}
+	if c.IsARCEnabled() || (!c.IsMIConfigured() && !c.IsSPConfigured() && !c.IsFederatedIdentityConfigured()) {
+		return fmt.Errorf("node.kubelet.acrCredentialProvider requires managed identity, service principal or federated identity authentication. " +
+			"With Arc, use containerd.registryAuth with managedIdentity instead")
+	}
+	for _, image := range provider.MatchImages {
+		if image == "" || strings.Contains(image, "://") || strings.ContainsAny(image, " @") {
+			return fmt.Errorf("invalid node.kubelet.acrCredentialProvider.matchImages entry: %q. Expected a registry host such as myregistry.azurecr.io or *.azurecr.io", image)
+		}
+	}
+	if d, err := time.ParseDuration(provider.CacheDuration); err != nil || d < 0 {
+		return fmt.Errorf("invalid node.kubelet.acrCredentialProvider.cacheDuration: %s. Expected a duration such as 10m", provider.CacheDuration)
+	}
+	return nil
+}
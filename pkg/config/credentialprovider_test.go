@@ -0,0 +1,48 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAcrCredentialProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider AcrCredentialProviderConfig
+		azure    AzureConfig
+		mi       bool
+		wantErr  bool
+	}{
+		{name: "disabled"},
+		{name: "managed identity", provider: AcrCredentialProviderConfig{Enabled: true}, mi: true},
+		{name: "service principal", provider: AcrCredentialProviderConfig{Enabled: true}, azure: AzureConfig{
+			ServicePrincipal: &ServicePrincipalConfig{TenantID: "tenant", ClientID: "client", ClientSecret: "secret"}}},
+		{name: "arc", provider: AcrCredentialProviderConfig{Enabled: true}, azure: AzureConfig{Arc: &ArcConfig{Enabled: true}}, wantErr: true},
+		{name: "bootstrap token only", provider: AcrCredentialProviderConfig{Enabled: true}, azure: AzureConfig{
+			BootstrapToken: &BootstrapTokenConfig{Token: "abcdef.0123456789abcdef"}}, wantErr: true},
+		{name: "invalid match image", provider: AcrCredentialProviderConfig{Enabled: true, MatchImages: []string{"https://myacr.azurecr.io"}}, mi: true, wantErr: true},
+		{name: "invalid cache duration", provider: AcrCredentialProviderConfig{Enabled: true, CacheDuration: "ten minutes"}, mi: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Azure: tt.azure, Node: NodeConfig{Kubelet: KubeletConfig{AcrCredentialProvider: tt.provider}}, isMIExplicitlySet: tt.mi}
+			c.setAcrCredentialProviderDefaults()
+			if err := validateAcrCredentialProvider(c); (err != nil) != tt.wantErr {
+				t.Fatalf("validateAcrCredentialProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAcrCredentialProviderDefaults(t *testing.T) {
+	c := &Config{Node: NodeConfig{Kubelet: KubeletConfig{AcrCredentialProvider: AcrCredentialProviderConfig{Enabled: true}}}}
+	c.setAcrCredentialProviderDefaults()
+	provider := c.Node.Kubelet.AcrCredentialProvider
+	if provider.Version != defaultAcrCredentialProviderVersion || provider.CacheDuration != "10m" {
+		t.Errorf("version = %q, cacheDuration = %q", provider.Version, provider.CacheDuration)
+	}
+	if !slices.Equal(provider.MatchImages, defaultAcrMatchImages) {
+		t.Errorf("matchImages = %v, want %v", provider.MatchImages, defaultAcrMatchImages)
+	}
+}
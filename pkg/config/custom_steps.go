@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// defaultCustomFileMode is the mode of custom step files without one
+const defaultCustomFileMode = "0644"
+
+func (c *Config) setCustomStepsDefaults() {
+	for i := range c.CustomSteps {
+		for j := range c.CustomSteps[i].Files {
+			if c.CustomSteps[i].Files[j].Mode == "" {
+				c.CustomSteps[i].Files[j].Mode = defaultCustomFileMode
+			}
+		}
+	}
+}
+
+// Perm returns the permissions of the file
+func (f CustomFileConfig) Perm() os.FileMode {
+	mode, err := strconv.ParseUint(f.Mode, 8, 32)
+	if err != nil {
+		mode, _ = strconv.ParseUint(defaultCustomFileMode, 8, 32)
+	}
+	return os.FileMode(mode).Perm()
+}
+
+// validateCustomSteps validates the custom steps. Their names must not be taken by a plugin either.
+func validateCustomSteps(steps []CustomStepConfig, plugins []PluginConfig) error {
+	names := map[string]bool{}
+	for _, plugin := range plugins {
+		names[plugin.Name] = true
+	}
+	for i, step := range steps {
+		if !stepNamePattern.MatchString(step.Name) {
+			return fmt.Errorf("invalid customSteps[%d].name: %q. Expected a letter followed by letters, digits, '_' or '-'", i, step.Name)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("duplicate customSteps[%d].name: %s", i, step.Name)
+		}
+		names[step.Name] = true
+		if len(step.Packages) == 0 && len(step.Files) == 0 && len(step.Units) == 0 {
+			return fmt.Errorf("customSteps[%d] (%s) has no packages, files or units", i, step.Name)
+		}
+		if slices.Contains(step.Packages, "") {
+			return fmt.Errorf("customSteps[%d].packages must not contain empty names", i)
+		}
+		if slices.Contains(step.Units, "") {
+			return fmt.Errorf("customSteps[%d].units must not contain empty names", i)
+		}
+		for j, file := range step.Files {
+			if !filepath.IsAbs(file.Path) {
+				return fmt.Errorf("invalid customSteps[%d].files[%d].path: %q. Expected an absolute path", i, j, file.Path)
+			}
+			if mode, err := strconv.ParseUint(file.Mode, 8, 32); err != nil || mode > 0o777 {
+				return fmt.Errorf("invalid customSteps[%d].files[%d].mode: %q. Expected octal permissions, e.g. 0644", i, j, file.Mode)
+			}
+		}
+	}
+	return nil
+}
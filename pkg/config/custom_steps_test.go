@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestValidateCustomSteps(t *testing.T) {
+	chrony := CustomStepConfig{
+		Name:     "SiteChrony",
+		After:    "SystemConfigured",
+		Packages: []string{"chrony"},
+		Files:    []CustomFileConfig{{Path: "/etc/chrony/conf.d/site.conf", Content: "server ntp.example.com iburst\n"}},
+		Units:    []string{"chrony.service"},
+	}
+	tests := []struct {
+		name    string
+		steps   []CustomStepConfig
+		plugins []PluginConfig
+		wantErr bool
+	}{
+		{name: "valid", steps: []CustomStepConfig{chrony}},
+		{name: "invalid name", steps: []CustomStepConfig{{Name: "site chrony", Units: []string{"chrony.service"}}}, wantErr: true},
+		{name: "duplicate name", steps: []CustomStepConfig{chrony, chrony}, wantErr: true},
+		{name: "name of a plugin", steps: []CustomStepConfig{chrony}, plugins: []PluginConfig{{Name: "SiteChrony"}}, wantErr: true},
+		{name: "no actions", steps: []CustomStepConfig{{Name: "Empty"}}, wantErr: true},
+		{name: "relative file path", steps: []CustomStepConfig{{Name: "Motd", Files: []CustomFileConfig{{Path: "motd"}}}}, wantErr: true},
+		{name: "invalid mode", steps: []CustomStepConfig{{Name: "Motd", Files: []CustomFileConfig{{Path: "/etc/motd", Mode: "rw-r--r--"}}}}, wantErr: true},
+		{name: "empty unit", steps: []CustomStepConfig{{Name: "Units", Units: []string{""}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{CustomSteps: tt.steps}
+			c.setCustomStepsDefaults()
+			if err := validateCustomSteps(c.CustomSteps, tt.plugins); (err != nil) != tt.wantErr {
+				t.Fatalf("validateCustomSteps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCustomFilePerm(t *testing.T) {
+	if perm := (CustomFileConfig{Mode: "0600"}).Perm(); perm != 0o600 {
+		t.Errorf("Perm() = %o, want 600", perm)
+	}
+}
@@ -0,0 +1,23 @@
+package config
+
+import "go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+
+// detectDistro reads the host's distribution, a variable so tests can replace it
+var detectDistro = utilhost.DetectDistro
+
+// detectDistroDefaults applies the defaults of the host's distribution
+func (c *Config) detectDistroDefaults() {
+	distro, err := detectDistro()
+	if err != nil {
+		return
+	}
+	c.applyDistroDefaults(distro)
+}
+
+// applyDistroDefaults opens the node's ports in the host firewall on RHEL and its rebuilds, which run firewalld
+// with only SSH open by default, unless system.firewall.manage is set
+func (c *Config) applyDistroDefaults(distro utilhost.Distro) {
+	if distro.Family() == utilhost.DistroFamilyRHEL && !c.isFirewallManageSet {
+		c.System.Firewall.Manage = true
+	}
+}
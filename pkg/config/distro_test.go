@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+func TestApplyDistroDefaults(t *testing.T) {
+	rocky := utilhost.Distro{ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}, VersionID: "9.4"}
+	ubuntu := utilhost.Distro{ID: "ubuntu", IDLike: []string{"debian"}, VersionID: "24.04"}
+	tests := []struct {
+		name       string
+		distro     utilhost.Distro
+		manage     bool
+		explicit   bool
+		wantManage bool
+	}{
+		{name: "rhel family", distro: rocky, wantManage: true},
+		{name: "rhel family with manage false", distro: rocky, explicit: true},
+		{name: "ubuntu", distro: ubuntu},
+		{name: "ubuntu with manage true", distro: ubuntu, manage: true, explicit: true, wantManage: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{isFirewallManageSet: tt.explicit}
+			cfg.System.Firewall.Manage = tt.manage
+			cfg.applyDistroDefaults(tt.distro)
+			if cfg.System.Firewall.Manage != tt.wantManage {
+				t.Errorf("System.Firewall.Manage = %v, want %v", cfg.System.Firewall.Manage, tt.wantManage)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// EncryptionKeyTPM selects the TPM of the machine as the key of an encrypted config value
+const EncryptionKeyTPM = "tpm"
+
+// tpmCredentialName is the name systemd-creds binds TPM-sealed values to, so credentials sealed for other
+// services are not accepted
+const tpmCredentialName = "aks-flex-node"
+
+// EncryptedValuePattern matches config values of the form @encrypted(<key>:<base64 ciphertext>), where the key
+// is tpm or a Key Vault secret holding an AES-256 key, https://<vault>.vault.azure.net/secrets/<name>[/<version>]
+var EncryptedValuePattern = regexp.MustCompile(`^@encrypted\((tpm|https://[^/()]+/secrets/[a-zA-Z0-9-]+(?:/[a-zA-Z0-9]+)?):([A-Za-z0-9+/]+={0,2})\)$`)
+
+var (
+	// sealTPM and unsealTPM encrypt and decrypt with a key bound to the TPM of the machine, so the ciphertext
+	// is useless on any other machine
+	sealTPM = func(ctx context.Context, plaintext []byte) ([]byte, error) {
+		return systemdCreds(ctx, plaintext, "encrypt", "--with-key=tpm2", "--name="+tpmCredentialName, "-", "-")
+	}
+	unsealTPM = func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+		return systemdCreds(ctx, ciphertext, "decrypt", "--name="+tpmCredentialName, "-", "-")
+	}
+)
+
+// systemdCreds runs systemd-creds with input on stdin and returns its stdout
+func systemdCreds(ctx context.Context, input []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "systemd-creds", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("systemd-creds %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// valueCipher encrypts and decrypts config values, fetching each Key Vault data key once
+type valueCipher struct {
+	resolve secretResolver
+
+	mu   sync.Mutex
+	keys map[string]cipher.AEAD
+}
+
+func newValueCipher(resolve secretResolver) *valueCipher {
+	return &valueCipher{resolve: resolve, keys: map[string]cipher.AEAD{}}
+}
+
+// aead returns the AES-GCM cipher of the data key stored in a Key Vault secret
+func (c *valueCipher) aead(ctx context.Context, keyURL string) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if aead, ok := c.keys[keyURL]; ok {
+		return aead, nil
+	}
+	matches := KeyVaultReferencePattern.FindStringSubmatch("@keyvault(" + keyURL + ")")
+	if matches == nil {
+		return nil, fmt.Errorf("invalid key %q, expected tpm or https://<vault>/secrets/<name>[/<version>]", keyURL)
+	}
+	secret, err := c.resolve(ctx, matches[1], matches[2], matches[3])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data key %s: %w", matches[2], err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("data key %s must hold 32 base64-encoded bytes", matches[2])
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c.keys[keyURL] = aead
+	return aead, nil
+}
+
+// encrypt returns the @encrypted value of plaintext
+func (c *valueCipher) encrypt(ctx context.Context, key, plaintext string) (string, error) {
+	var ciphertext []byte
+	if key == EncryptionKeyTPM {
+		sealed, err := sealTPM(ctx, []byte(plaintext))
+		if err != nil {
+			return "", err
+		}
+		ciphertext = sealed
+	} else {
+		aead, err := c.aead(ctx, strings.TrimSuffix(key, "/"))
+		if err != nil {
+			return "", err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		ciphertext = aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	}
+	return fmt.Sprintf("@encrypted(%s:%s)", strings.TrimSuffix(key, "/"), base64.StdEncoding.EncodeToString(ciphertext)), nil
+}
+
+// decrypt returns the plaintext of an @encrypted value
+func (c *valueCipher) decrypt(ctx context.Context, value string) (string, error) {
+	matches := EncryptedValuePattern.FindStringSubmatch(value)
+	if matches == nil {
+		return "", fmt.Errorf("malformed encrypted value, expected @encrypted(<tpm or Key Vault secret URL>:<base64>)")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(matches[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	if matches[1] == EncryptionKeyTPM {
+		plaintext, err := unsealTPM(ctx, ciphertext)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt with the TPM: %w", err)
+		}
+		return string(plaintext), nil
+	}
+	aead, err := c.aead(ctx, matches[1])
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value, the ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt with data key %s, it may have been rotated: %w", matches[1], err)
+	}
+	return string(plaintext), nil
+}
+
+// decryptValues replaces every string value in cfg that is an @encrypted value with its plaintext, which is
+// only kept in memory. Like Key Vault references, values that start with "@encrypted(" but are malformed
+// are rejected.
+func decryptValues(ctx context.Context, cfg *Config, values *valueCipher) error {
+	return walkStrings(reflect.ValueOf(cfg).Elem(), "", func(path string, value string) (string, error) {
+		if !strings.HasPrefix(value, "@encrypted(") {
+			return value, nil
+		}
+		plaintext, err := values.decrypt(ctx, value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+		return plaintext, nil
+	})
+}
+
+// EncryptValue encrypts a secret for the config file, with the TPM of this machine (key "tpm") or an AES-256
+// data key stored base64-encoded in a Key Vault secret. The Key Vault secret is read with the Azure CLI login,
+// environment or managed identity, so values can be encrypted away from the node.
+func EncryptValue(ctx context.Context, key, plaintext string) (string, error) {
+	values := newValueCipher(newCredentialResolver(func() (azcore.TokenCredential, error) {
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+		}
+		return cred, nil
+	}))
+	return values.encrypt(ctx, key, plaintext)
+}
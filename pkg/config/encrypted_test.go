@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedValues(t *testing.T) {
+	dataKeys := map[string]string{
+		"data-key":  base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+		"other-key": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)),
+		"short-key": base64.StdEncoding.EncodeToString([]byte("short")),
+	}
+	resolve := func(_ context.Context, _, name, _ string) (string, error) {
+		key, ok := dataKeys[name]
+		if !ok {
+			return "", errors.New("SecretNotFound")
+		}
+		return key, nil
+	}
+
+	// The TPM is replaced by a reversible transform with a marker
+	seal, unseal := sealTPM, unsealTPM
+	t.Cleanup(func() { sealTPM, unsealTPM = seal, unseal })
+	sealTPM = func(_ context.Context, plaintext []byte) ([]byte, error) {
+		return append([]byte("sealed:"), plaintext...), nil
+	}
+	unsealTPM = func(_ context.Context, ciphertext []byte) ([]byte, error) {
+		plaintext, ok := bytes.CutPrefix(ciphertext, []byte("sealed:"))
+		if !ok {
+			return nil, errors.New("the TPM policy does not match")
+		}
+		return plaintext, nil
+	}
+
+	ctx := context.Background()
+	encrypter := newValueCipher(resolve)
+	secret, err := encrypter.encrypt(ctx, "https://kv.vault.azure.net/secrets/data-key/", "sp-secret")
+	if err != nil {
+		t.Fatalf("encrypt() error = %v", err)
+	}
+	if !strings.HasPrefix(secret, "@encrypted(https://kv.vault.azure.net/secrets/data-key:") || strings.Contains(secret, "sp-secret") {
+		t.Errorf("encrypt() = %q", secret)
+	}
+	token, err := encrypter.encrypt(ctx, EncryptionKeyTPM, "abcdef.0123456789abcdef")
+	if err != nil {
+		t.Fatalf("encrypt() with the TPM error = %v", err)
+	}
+	if _, err := encrypter.encrypt(ctx, "kv/data-key", "x"); err == nil {
+		t.Error("encrypt() accepted a malformed key")
+	}
+
+	cfg := &Config{
+		Azure: AzureConfig{
+			ServicePrincipal: &ServicePrincipalConfig{ClientSecret: secret},
+			BootstrapToken:   &BootstrapTokenConfig{Token: token},
+		},
+		Node: NodeConfig{Labels: map[string]string{"plain": "value"}},
+	}
+	if err := decryptValues(ctx, cfg, newValueCipher(resolve)); err != nil {
+		t.Fatalf("decryptValues() error = %v", err)
+	}
+	if cfg.Azure.ServicePrincipal.ClientSecret != "sp-secret" || cfg.Azure.BootstrapToken.Token != "abcdef.0123456789abcdef" || cfg.Node.Labels["plain"] != "value" {
+		t.Errorf("decrypted secret = %q, token = %q, labels = %v", cfg.Azure.ServicePrincipal.ClientSecret, cfg.Azure.BootstrapToken.Token, cfg.Node.Labels)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "malformed", value: "@encrypted(sp-secret)", wantErr: "malformed encrypted value"},
+		{name: "other data key", value: strings.Replace(secret, "data-key", "other-key", 1), wantErr: "it may have been rotated"},
+		{name: "short data key", value: strings.Replace(secret, "data-key", "short-key", 1), wantErr: "must hold 32 base64-encoded bytes"},
+		{name: "missing data key", value: strings.Replace(secret, "data-key", "missing", 1), wantErr: "SecretNotFound"},
+		{name: "sealed elsewhere", value: "@encrypted(tpm:" + base64.StdEncoding.EncodeToString([]byte("other")) + ")", wantErr: "failed to decrypt with the TPM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Azure: AzureConfig{ServicePrincipal: &ServicePrincipalConfig{ClientSecret: tt.value}}}
+			err := decryptValues(ctx, cfg, newValueCipher(resolve))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "azure.servicePrincipal.clientSecret") {
+				t.Errorf("decryptValues() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
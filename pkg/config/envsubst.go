@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envReferencePattern matches ${NAME} and ${NAME:-default} references in config values, and the $${ escape
+// that stands for a literal ${
+var envReferencePattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// envNamePattern matches valid environment variable names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// expandEnv replaces the environment variable references in a config value. ${NAME} requires NAME to be
+// set, so a missing secret fails loading instead of ending up as an empty value. ${NAME:-default} falls back
+// to default when NAME is unset or empty.
+func expandEnv(value string) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+	var expandErr error
+	expanded := envReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		if reference == "$${" {
+			return "${"
+		}
+		name, fallback, hasFallback := strings.Cut(reference[2:len(reference)-1], ":-")
+		if !envNamePattern.MatchString(name) {
+			if expandErr == nil {
+				expandErr = fmt.Errorf("invalid environment variable reference %q, expected ${NAME} or ${NAME:-default}", reference)
+			}
+			return reference
+		}
+		envValue, ok := os.LookupEnv(name)
+		switch {
+		case hasFallback && envValue == "":
+			return fallback
+		case !ok:
+			if expandErr == nil {
+				expandErr = fmt.Errorf("environment variable %s is not set; set it or give a default with ${%s:-default}", name, name)
+			}
+			return reference
+		}
+		return envValue
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expanded, nil
+}
+
+// substituteEnv expands the environment variable references in every string value of a parsed config
+// document. Keys are left as they are.
+func substituteEnv(value any, path string) (any, error) {
+	switch value := value.(type) {
+	case string:
+		expanded, err := expandEnv(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return expanded, nil
+	case map[string]any:
+		for key, item := range value {
+			expanded, err := substituteEnv(item, joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			value[key] = expanded
+		}
+	case []any:
+		for i, item := range value {
+			expanded, err := substituteEnv(item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			value[i] = expanded
+		}
+	}
+	return value, nil
+}
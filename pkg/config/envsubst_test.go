@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("FLEX_TEST_SECRET", "s3cret")
+	t.Setenv("FLEX_TEST_EMPTY", "")
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{value: "plain $value", want: "plain $value"},
+		{value: "${FLEX_TEST_SECRET}", want: "s3cret"},
+		{value: "prefix-${FLEX_TEST_SECRET}-${FLEX_TEST_SECRET}", want: "prefix-s3cret-s3cret"},
+		{value: "${FLEX_TEST_UNSET:-eastus}", want: "eastus"},
+		{value: "${FLEX_TEST_EMPTY:-fallback}", want: "fallback"},
+		{value: "${FLEX_TEST_UNSET:-}", want: ""},
+		{value: "${FLEX_TEST_EMPTY}", want: ""},
+		{value: "$${FLEX_TEST_SECRET}", want: "${FLEX_TEST_SECRET}"},
+		{value: "${FLEX_TEST_UNSET}", wantErr: "environment variable FLEX_TEST_UNSET is not set"},
+		{value: "${FLEX-TEST}", wantErr: `invalid environment variable reference "${FLEX-TEST}"`},
+		{value: "${}", wantErr: "invalid environment variable reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := expandEnv(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expandEnv(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("expandEnv(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSubstitutesEnv(t *testing.T) {
+	t.Setenv("FLEX_TEST_SUBSCRIPTION", "12345678-1234-1234-1234-123456789012")
+	t.Setenv("FLEX_TEST_TOKEN", "abcdef.0123456789abcdef")
+	t.Setenv("FLEX_TEST_ZONE", "rack-7")
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(`{
+		"azure": {
+			"subscriptionId": "${FLEX_TEST_SUBSCRIPTION}",
+			"tenantId": "${FLEX_TEST_SUBSCRIPTION}",
+			"bootstrapToken": {"token": "${FLEX_TEST_TOKEN}"},
+			"targetCluster": {
+				"resourceId": "/subscriptions/${FLEX_TEST_SUBSCRIPTION}/resourceGroups/test-rg/providers/Microsoft.ContainerService/managedClusters/test-cluster",
+				"location": "${FLEX_TEST_LOCATION:-eastus}"
+			}
+		},
+		"node": {
+			"maxPods": "${FLEX_TEST_MAX_PODS:-50}",
+			"labels": {"zone": "${FLEX_TEST_ZONE}"},
+			"kubelet": {
+				"serverURL": "https://test-cluster-abc123.hcp.eastus.azmk8s.io:443",
+				"caCertData": "LS0tLS1CRUdJTi1DRVJUSUZJQ0FURS0tLS0tCk1JSUREekNDQWZlZ0F3SUJBZ0lSQU1kbzBZa0R"
+			}
+		}
+	}`), 0o644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Azure.SubscriptionID != "12345678-1234-1234-1234-123456789012" || cfg.GetTargetClusterName() != "test-cluster" {
+		t.Errorf("subscription = %q, cluster = %q", cfg.Azure.SubscriptionID, cfg.GetTargetClusterName())
+	}
+	if cfg.Azure.BootstrapToken.Token != "abcdef.0123456789abcdef" || cfg.Azure.TargetCluster.Location != "eastus" {
+		t.Errorf("token = %q, location = %q", cfg.Azure.BootstrapToken.Token, cfg.Azure.TargetCluster.Location)
+	}
+	if cfg.Node.MaxPods != 50 || cfg.Node.Labels["zone"] != "rack-7" {
+		t.Errorf("maxPods = %d, labels = %v", cfg.Node.MaxPods, cfg.Node.Labels)
+	}
+
+	// A site drop-in referencing an unset variable fails loading with the file and field
+	dropIn := filepath.Join(dir, "config.d", "10-site.json")
+	if err := os.MkdirAll(filepath.Dir(dropIn), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dropIn, []byte(`{"azure": {"bootstrapToken": {"token": "${FLEX_TEST_UNSET}"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(configFile)
+	if err == nil || !strings.Contains(err.Error(), dropIn) || !strings.Contains(err.Error(), "azure.bootstrapToken.token: environment variable FLEX_TEST_UNSET is not set") {
+		t.Errorf("LoadConfig() error = %v, want the unset variable with its file and field", err)
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// evictionSignals are the signals kubelet evicts pods on
+var evictionSignals = []string{
+	"memory.available",
+	"allocatableMemory.available",
+	"nodefs.available",
+	"nodefs.inodesFree",
+	"imagefs.available",
+	"imagefs.inodesFree",
+	"containerfs.available",
+	"containerfs.inodesFree",
+	"pid.available",
+}
+
+// defaultEvictionHard are kubelet's hard eviction thresholds apart from those of disk space. kubelet drops its
+// defaults for every signal once evictionHard sets one, so they are always written out.
+var defaultEvictionHard = map[string]string{
+	"memory.available":   "100Mi",
+	"nodefs.inodesFree":  "5%",
+	"imagefs.inodesFree": "5%",
+}
+
+// Disk sizes between which nodefs and imagefs thresholds of 10% apply. Below, 5% keeps pods running while
+// gigabytes are free; above, a fixed 25Gi keeps tens of gigabytes from sitting unused.
+const (
+	smallDiskSize = 64 << 30
+	largeDiskSize = 256 << 30
+)
+
+// detectEvictionThresholds sizes the disk space thresholds of node.kubelet.evictionHard from the filesystem
+// holding kubelet's directory. Thresholds set in the configuration are kept.
+func (c *Config) detectEvictionThresholds() {
+	var size int64
+	// The data disk is only mounted over the kubelet directory during bootstrap
+	if !c.Storage.DataDisk.Enabled {
+		size, _ = utilhost.FilesystemSize(c.Paths.Kubernetes.KubeletDir)
+	}
+	c.applyEvictionThresholds(size)
+}
+
+// applyEvictionThresholds adds the disk space thresholds for a disk of the given size in bytes, or of a
+// mid-sized disk when the size is unknown
+func (c *Config) applyEvictionThresholds(diskSize int64) {
+	kubelet := &c.Node.Kubelet
+	if kubelet.EvictionHard == nil {
+		kubelet.EvictionHard = make(map[string]string)
+	}
+	nodefs, imagefs := diskEvictionThresholds(diskSize)
+	setIfMissing(kubelet.EvictionHard, "nodefs.available", nodefs)
+	setIfMissing(kubelet.EvictionHard, "imagefs.available", imagefs)
+}
+
+// diskEvictionThresholds returns the nodefs.available and imagefs.available thresholds for a disk of the given
+// size. kubelet's own 15% imagefs.available would evict pods at the 85% disk usage the default image GC starts at,
+// so both thresholds leave room for image GC to run first.
+func diskEvictionThresholds(size int64) (string, string) {
+	switch {
+	case size > 0 && size < smallDiskSize:
+		return "5%", "5%"
+	case size >= largeDiskSize:
+		return "25Gi", "25Gi"
+	default:
+		return "10%", "10%"
+	}
+}
+
+// validateEviction validates the eviction thresholds and grace periods of node.kubelet. kubelet refuses a soft
+// threshold without a grace period.
+func validateEviction(cfg *KubeletConfig) error {
+	fields := map[string]map[string]string{
+		"evictionHard":           cfg.EvictionHard,
+		"evictionSoft":           cfg.EvictionSoft,
+		"evictionMinimumReclaim": cfg.EvictionMinimumReclaim,
+	}
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		thresholds := fields[field]
+		for _, signal := range slices.Sorted(maps.Keys(thresholds)) {
+			if !slices.Contains(evictionSignals, signal) {
+				return fmt.Errorf("invalid node.kubelet.%s signal: %s. Valid signals are: %s", field, signal, strings.Join(evictionSignals, ", "))
+			}
+			if !validThreshold(thresholds[signal]) {
+				return fmt.Errorf("invalid node.kubelet.%s %s: %s. Expected a quantity such as 500Mi or a percentage such as 10%%", field, signal, thresholds[signal])
+			}
+		}
+	}
+	for _, signal := range slices.Sorted(maps.Keys(cfg.EvictionSoft)) {
+		if _, ok := cfg.EvictionSoftGracePeriod[signal]; !ok {
+			return fmt.Errorf("invalid node.kubelet.evictionSoft %s: a soft threshold needs a grace period in evictionSoftGracePeriod", signal)
+		}
+	}
+	for _, signal := range slices.Sorted(maps.Keys(cfg.EvictionSoftGracePeriod)) {
+		if _, ok := cfg.EvictionSoft[signal]; !ok {
+			return fmt.Errorf("invalid node.kubelet.evictionSoftGracePeriod %s: evictionSoft has no threshold for it", signal)
+		}
+		if d, err := time.ParseDuration(cfg.EvictionSoftGracePeriod[signal]); err != nil || d <= 0 {
+			return fmt.Errorf("invalid node.kubelet.evictionSoftGracePeriod %s: %s. Expected a duration such as 1m30s", signal, cfg.EvictionSoftGracePeriod[signal])
+		}
+	}
+	if cfg.EvictionMaxPodGracePeriod < 0 {
+		return fmt.Errorf("invalid node.kubelet.evictionMaxPodGracePeriod: %d. Must be 0 or more seconds", cfg.EvictionMaxPodGracePeriod)
+	}
+	if period := cfg.EvictionPressureTransitionPeriod; period != "" {
+		if d, err := time.ParseDuration(period); err != nil || d < 0 {
+			return fmt.Errorf("invalid node.kubelet.evictionPressureTransitionPeriod: %s. Expected a duration such as 5m", period)
+		}
+	}
+	return nil
+}
+
+// validThreshold reports whether an eviction threshold is a non-negative quantity or a percentage up to 100
+func validThreshold(threshold string) bool {
+	if percent, ok := strings.CutSuffix(threshold, "%"); ok {
+		value, err := strconv.ParseFloat(percent, 64)
+		return err == nil && value >= 0 && value <= 100
+	}
+	quantity, err := resource.ParseQuantity(threshold)
+	return err == nil && quantity.Sign() >= 0
+}
@@ -0,0 +1,74 @@
+package config
+
+import "testing"
+
+func TestApplyEvictionThresholds(t *testing.T) {
+	const gib = int64(1) << 30
+	tests := []struct {
+		name        string
+		diskSize    int64
+		configured  map[string]string
+		wantNodefs  string
+		wantImagefs string
+	}{
+		{name: "unknown disk", wantNodefs: "10%", wantImagefs: "10%"},
+		{name: "small disk", diskSize: 30 * gib, wantNodefs: "5%", wantImagefs: "5%"},
+		{name: "mid-sized disk", diskSize: 128 * gib, wantNodefs: "10%", wantImagefs: "10%"},
+		{name: "large disk", diskSize: 1024 * gib, wantNodefs: "25Gi", wantImagefs: "25Gi"},
+		{name: "configured threshold kept", diskSize: 30 * gib, configured: map[string]string{"nodefs.available": "2Gi"}, wantNodefs: "2Gi", wantImagefs: "5%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Node: NodeConfig{Kubelet: KubeletConfig{EvictionHard: tt.configured}}}
+			c.applyEvictionThresholds(tt.diskSize)
+			if got := c.Node.Kubelet.EvictionHard["nodefs.available"]; got != tt.wantNodefs {
+				t.Errorf("nodefs.available = %q, want %q", got, tt.wantNodefs)
+			}
+			if got := c.Node.Kubelet.EvictionHard["imagefs.available"]; got != tt.wantImagefs {
+				t.Errorf("imagefs.available = %q, want %q", got, tt.wantImagefs)
+			}
+		})
+	}
+}
+
+func TestValidateEviction(t *testing.T) {
+	tests := []struct {
+		name    string
+		kubelet KubeletConfig
+		wantErr bool
+	}{
+		{name: "defaults", kubelet: KubeletConfig{EvictionHard: defaultEvictionHard}},
+		{
+			name: "soft thresholds with grace periods",
+			kubelet: KubeletConfig{
+				EvictionSoft:            map[string]string{"memory.available": "500Mi", "nodefs.available": "15%"},
+				EvictionSoftGracePeriod: map[string]string{"memory.available": "1m30s", "nodefs.available": "2m"},
+				EvictionMinimumReclaim:  map[string]string{"nodefs.available": "1Gi"},
+			},
+		},
+		{name: "unknown signal", kubelet: KubeletConfig{EvictionHard: map[string]string{"disk.available": "10%"}}, wantErr: true},
+		{name: "invalid threshold", kubelet: KubeletConfig{EvictionHard: map[string]string{"nodefs.available": "110%"}}, wantErr: true},
+		{name: "soft threshold without grace period", kubelet: KubeletConfig{EvictionSoft: map[string]string{"memory.available": "500Mi"}}, wantErr: true},
+		{name: "grace period without soft threshold", kubelet: KubeletConfig{EvictionSoftGracePeriod: map[string]string{"memory.available": "1m"}}, wantErr: true},
+		{
+			name: "invalid grace period",
+			kubelet: KubeletConfig{
+				EvictionSoft:            map[string]string{"memory.available": "500Mi"},
+				EvictionSoftGracePeriod: map[string]string{"memory.available": "90"},
+			},
+			wantErr: true,
+		},
+		{name: "negative max pod grace period", kubelet: KubeletConfig{EvictionMaxPodGracePeriod: -1}, wantErr: true},
+		{name: "invalid pressure transition period", kubelet: KubeletConfig{EvictionPressureTransitionPeriod: "5"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEviction(&tt.kubelet)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateEviction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
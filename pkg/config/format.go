@@ -0,0 +1,352 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// Config file formats, detected from the file extension
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatTOML = "toml"
+)
+
+// configExtensions are the extensions of config layer files, in the order host layers are looked up
+var configExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// configFormat returns the format of a config file. Files without a YAML or TOML extension are JSON,
+// so existing config files keep working whatever they are named.
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".toml":
+		return formatTOML
+	default:
+		return formatJSON
+	}
+}
+
+// decodeLayer decodes a config file into a document of maps, slices and scalars, as JSON decoding would
+func decodeLayer(path string, data []byte) (map[string]any, error) {
+	switch configFormat(path) {
+	case formatYAML:
+		root, err := parseYAML(data)
+		if err != nil {
+			return nil, err
+		}
+		document, ok := root.document().(map[string]any)
+		if !root.object || !ok {
+			return nil, fmt.Errorf("the config must be a YAML mapping")
+		}
+		return document, nil
+	case formatTOML:
+		var layer map[string]any
+		if err := toml.Unmarshal(data, &layer); err != nil {
+			return nil, err
+		}
+		document, ok := normalizeTOML(layer).(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("the config must be a TOML mapping")
+		}
+		return document, nil
+	default:
+		var layer map[string]any
+		if err := json.Unmarshal(data, &layer); err != nil {
+			return nil, err
+		}
+		return layer, nil
+	}
+}
+
+// EncodeDocument encodes a config document in the format of path, detected from its extension like
+// config loading does
+func EncodeDocument(path string, document map[string]any) ([]byte, error) {
+	switch configFormat(path) {
+	case formatYAML:
+		return yaml.Marshal(document)
+	case formatTOML:
+		return toml.Marshal(document)
+	default:
+		data, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+}
+
+// document converts a parsed value into the types JSON decoding produces, with integers kept exact
+func (n *jsonNode) document() any {
+	switch {
+	case n.object:
+		object := make(map[string]any, len(n.fields))
+		for _, field := range n.fields {
+			object[field.key] = field.value.document()
+		}
+		return object
+	case n.array:
+		items := make([]any, len(n.items))
+		for i, item := range n.items {
+			items[i] = item.document()
+		}
+		return items
+	}
+	if number, ok := n.value.(json.Number); ok {
+		if i, err := number.Int64(); err == nil {
+			return i
+		}
+		f, _ := number.Float64()
+		return f
+	}
+	return n.value
+}
+
+// normalizeTOML converts the date and time values of a decoded TOML document to strings
+func normalizeTOML(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			value[key] = normalizeTOML(item)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = normalizeTOML(item)
+		}
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case toml.LocalDate, toml.LocalTime, toml.LocalDateTime:
+		return fmt.Sprint(value)
+	}
+	return value
+}
+
+// yamlLinePattern matches the line yaml.v3 reports syntax errors at
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+):`)
+
+// parseYAML parses a YAML config file into a tree of values with their offsets
+func parseYAML(data []byte) (*jsonNode, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		// An empty file sets nothing
+		return &jsonNode{object: true}, nil
+	}
+	lines := lineOffsets(data)
+	return yamlNode(document.Content[0], lines), nil
+}
+
+// yamlNode converts a YAML node and its children
+func yamlNode(node *yaml.Node, lines []int) *jsonNode {
+	result := &jsonNode{offset: positionOffset(lines, node.Line, node.Column)}
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlNode(node.Alias, lines)
+	case yaml.MappingNode:
+		result.object = true
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			result.fields = append(result.fields, jsonField{
+				key:    key.Value,
+				offset: positionOffset(lines, key.Line, key.Column),
+				value:  yamlNode(node.Content[i+1], lines),
+			})
+		}
+	case yaml.SequenceNode:
+		result.array = true
+		for _, item := range node.Content {
+			result.items = append(result.items, yamlNode(item, lines))
+		}
+	default:
+		result.value = yamlScalar(node)
+	}
+	return result
+}
+
+// yamlScalar returns the value of a YAML scalar. Timestamps and other tags are kept as the text written.
+func yamlScalar(node *yaml.Node) any {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err == nil {
+			return b
+		}
+	case "!!int":
+		var i int64
+		if err := node.Decode(&i); err == nil {
+			return json.Number(strconv.FormatInt(i, 10))
+		}
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+	return node.Value
+}
+
+// parseTOML parses a TOML config file into a tree of values with their offsets. Values without a
+// position of their own, such as booleans and arrays, are placed at their key.
+func parseTOML(data []byte) (*jsonNode, error) {
+	// Decode first for its errors, which carry the position
+	var layer map[string]any
+	if err := toml.Unmarshal(data, &layer); err != nil {
+		return nil, err
+	}
+
+	root := &jsonNode{object: true}
+	current := root
+	parser := unstable.Parser{}
+	parser.Reset(data)
+	for parser.NextExpression() {
+		expression := parser.Expression()
+		switch expression.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			current = tomlTable(root, expression)
+		case unstable.KeyValue:
+			tomlKeyValue(current, expression)
+		}
+	}
+	return root, parser.Error()
+}
+
+// tomlTable returns the object a [table] or [[array table]] header selects, creating it as needed
+func tomlTable(root *jsonNode, header *unstable.Node) *jsonNode {
+	node := root
+	keys := header.Key()
+	for keys.Next() {
+		key := keys.Node()
+		last := keys.IsLast()
+		child := tomlField(node, key)
+		if last && header.Kind == unstable.ArrayTable {
+			child.array = true
+			item := &jsonNode{offset: child.offset, object: true}
+			child.items = append(child.items, item)
+			return item
+		}
+		if child.array && len(child.items) > 0 {
+			// Keys below an array of tables refer to its last table
+			child = child.items[len(child.items)-1]
+		}
+		child.object = true
+		node = child
+	}
+	return node
+}
+
+// tomlKeyValue adds a key = value line, or an entry of an inline table, to an object
+func tomlKeyValue(object *jsonNode, keyValue *unstable.Node) {
+	var field *jsonNode
+	keys := keyValue.Key()
+	for keys.Next() {
+		if field != nil {
+			field.object = true
+			object = field
+		}
+		field = tomlField(object, keys.Node())
+	}
+	*field = *tomlValue(keyValue.Value(), field.offset)
+}
+
+// tomlField returns the value of a key of an object, adding the key if it is new. Keys written twice are
+// listed twice, so the schema check reports them.
+func tomlField(object *jsonNode, key *unstable.Node) *jsonNode {
+	name := string(key.Data)
+	for _, field := range object.fields {
+		if field.key == name && (field.value.object || field.value.array) {
+			return field.value
+		}
+	}
+	value := &jsonNode{offset: int(key.Raw.Offset)}
+	object.fields = append(object.fields, jsonField{key: name, offset: int(key.Raw.Offset), value: value})
+	return value
+}
+
+// tomlValue converts a TOML value, placing it at offset unless it has a position of its own
+func tomlValue(value *unstable.Node, offset int) *jsonNode {
+	if value.Raw.Length > 0 {
+		offset = int(value.Raw.Offset)
+	}
+	node := &jsonNode{offset: offset}
+	switch value.Kind {
+	case unstable.Array:
+		node.array = true
+		items := value.Children()
+		for items.Next() {
+			node.items = append(node.items, tomlValue(items.Node(), offset))
+		}
+	case unstable.InlineTable:
+		node.object = true
+		entries := value.Children()
+		for entries.Next() {
+			tomlKeyValue(node, entries.Node())
+		}
+	case unstable.Bool:
+		node.value = string(value.Data) == "true"
+	case unstable.Integer:
+		if i, err := strconv.ParseInt(strings.ReplaceAll(string(value.Data), "_", ""), 0, 64); err == nil {
+			node.value = json.Number(strconv.FormatInt(i, 10))
+		} else {
+			node.value = string(value.Data)
+		}
+	case unstable.Float:
+		if f, err := strconv.ParseFloat(strings.ReplaceAll(string(value.Data), "_", ""), 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			node.value = json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		} else {
+			node.value = string(value.Data)
+		}
+	default:
+		// Strings, and dates and times kept as written
+		node.value = string(value.Data)
+	}
+	return node
+}
+
+// syntaxErrorOffset returns the offset of a YAML or TOML syntax error, or 0 if it has none
+func syntaxErrorOffset(data []byte, err error) int {
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		line, column := decodeErr.Position()
+		return positionOffset(lineOffsets(data), line, column)
+	}
+	if matches := yamlLinePattern.FindStringSubmatch(err.Error()); matches != nil {
+		line, _ := strconv.Atoi(matches[1])
+		return positionOffset(lineOffsets(data), line, 1)
+	}
+	return 0
+}
+
+// lineOffsets returns the offset each line of data starts at
+func lineOffsets(data []byte) []int {
+	offsets := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// positionOffset converts a 1-based line and column to an offset
+func positionOffset(lines []int, line, column int) int {
+	if line < 1 || line > len(lines) {
+		return 0
+	}
+	return lines[line-1] + max(column-1, 0)
+}
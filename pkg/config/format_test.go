@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeLayer(t *testing.T) {
+	want := map[string]any{
+		"azure": map[string]any{"subscriptionId": "sub", "arc": map[string]any{"enabled": true}},
+		"node":  map[string]any{"maxPods": int64(110), "taints": []any{"a=b:NoSchedule"}, "labels": map[string]any{"release": "2024-05-01"}},
+		"hooks": []any{map[string]any{"step": "KubeletInstaller", "command": []any{"/bin/true"}}},
+	}
+	tests := map[string]string{
+		"config.yaml": `
+azure:
+  subscriptionId: sub
+  arc: {enabled: true}
+node:
+  maxPods: 110
+  taints: [a=b:NoSchedule]
+  labels:
+    release: 2024-05-01
+hooks:
+  - step: KubeletInstaller
+    command: [/bin/true]
+`,
+		"config.toml": `
+hooks = [{step = "KubeletInstaller", command = ["/bin/true"]}]
+
+[azure]
+subscriptionId = "sub"
+arc.enabled = true
+
+[node]
+maxPods = 110
+taints = ["a=b:NoSchedule"]
+
+[node.labels]
+release = 2024-05-01
+`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := decodeLayer(name, []byte(data))
+			if err != nil {
+				t.Fatalf("decodeLayer() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decodeLayer() = %#v, want %#v", got, want)
+			}
+		})
+	}
+
+	if got, err := decodeLayer("empty.yml", nil); err != nil || len(got) != 0 {
+		t.Errorf("decodeLayer() of an empty file = %v, %v", got, err)
+	}
+	if _, err := decodeLayer("list.yaml", []byte("- azure\n")); err == nil {
+		t.Error("decodeLayer() accepted a YAML list")
+	}
+}
+
+func TestCheckDocumentFormats(t *testing.T) {
+	tests := []struct {
+		file string
+		data string
+		want []string
+	}{
+		{
+			file: "f.yaml",
+			data: `azure:
+  subscriptonId: sub
+  arc:
+    enabled: "yes"
+  armRetry: {maxRetries: 1.5, retryDelay: 4s}
+node:
+  maxPods: "${FLEX_TEST_MAX_PODS:-110}"
+  labels: [zone]
+  taints: ~
+`,
+			want: []string{
+				`f.yaml:2:3: azure.subscriptonId: unknown field, did you mean "subscriptionId"?`,
+				`f.yaml:4:14: azure.arc.enabled: expected true or false, got string "yes"`,
+				`f.yaml:5:26: azure.armRetry.maxRetries: expected an integer, got number 1.5`,
+				`f.yaml:8:11: node.labels: expected an object, got an array`,
+			},
+		},
+		{
+			file: "f.yaml",
+			data: "azure:\n  tenantId: t\n bad: indent\n",
+			want: []string{"f.yaml:2:1: invalid YAML: yaml: did not find expected key"},
+		},
+		{
+			file: "f.toml",
+			data: `[azure]
+subscriptonId = "sub"
+arc.enabled = "yes"
+
+[[hooks]]
+step = "KubeletInstaller"
+timeout = "5 minutes"
+
+[[hooks]]
+step = 3
+`,
+			want: []string{
+				`f.toml:2:1: azure.subscriptonId: unknown field, did you mean "subscriptionId"?`,
+				`f.toml:3:15: azure.arc.enabled: expected true or false, got string "yes"`,
+				`f.toml:7:11: hooks[0].timeout: invalid duration "5 minutes", use a number with a unit such as 300ms, 30s or 1h30m`,
+				`f.toml:10:8: hooks[1].step: expected a string, got number 3`,
+			},
+		},
+		{
+			file: "f.toml",
+			data: "[azure]\ntenantId = \n",
+			want: []string{"f.toml:2:12: invalid TOML: toml: incomplete number"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			var got []string
+			for _, err := range checkDocument(tt.file, []byte(tt.data)) {
+				got = append(got, err.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("checkDocument() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	dropIn := filepath.Join(dir, "config.d", "10-site.toml")
+	if err := os.MkdirAll(filepath.Dir(dropIn), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base, []byte(`
+azure:
+  subscriptionId: 12345678-1234-1234-1234-123456789012
+  tenantId: 12345678-1234-1234-1234-123456789012
+  bootstrapToken:
+    token: abcdef.0123456789abcdef
+  targetCluster:
+    resourceId: /subscriptions/12345678-1234-1234-1234-123456789012/resourceGroups/test-rg/providers/Microsoft.ContainerService/managedClusters/test-cluster
+    location: eastus
+node:
+  maxPods: 110
+  kubelet:
+    serverURL: https://test-cluster-abc123.hcp.eastus.azmk8s.io:443
+    caCertData: LS0tLS1CRUdJTi1DRVJUSUZJQ0FURS0tLS0tCk1JSUREekNDQWZlZ0F3SUJBZ0lSQU1kbzBZa0R
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dropIn, []byte("[node]\nmaxPods = 50\n\n[node.labels]\nzone = \"b\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(base)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.GetTargetClusterName() != "test-cluster" || cfg.Node.MaxPods != 50 || cfg.Node.Labels["zone"] != "b" {
+		t.Errorf("cluster = %q, maxPods = %d, labels = %v", cfg.GetTargetClusterName(), cfg.Node.MaxPods, cfg.Node.Labels)
+	}
+	if got := cfg.Layers(); !reflect.DeepEqual(got, []string{base, dropIn}) {
+		t.Errorf("Layers() = %v", got)
+	}
+}
+
+func TestConfigLayersRejectsSeveralHostLayers(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("hostname unavailable: %v", err)
+	}
+	shortName, _, _ := strings.Cut(hostname, ".")
+	dir := t.TempDir()
+	hostDir := filepath.Join(dir, "config.d", hostLayerDir)
+	if err := os.MkdirAll(hostDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, ext := range []string{".json", ".yaml"} {
+		if err := os.WriteFile(filepath.Join(hostDir, shortName+ext), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := configLayers(filepath.Join(dir, "config.json")); err == nil || !strings.Contains(err.Error(), "several host config layers") {
+		t.Errorf("configLayers() error = %v, want the ambiguous host layers", err)
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// imageFilesystemSignals are the eviction signals of the filesystem holding images. Without a separate image
+// filesystem both are measured on the same disk.
+var imageFilesystemSignals = []string{"imagefs.available", "nodefs.available"}
+
+// validateContainerdGC validates containerd.gc
+func validateContainerdGC(cfg *ContainerdGCConfig) error {
+	if cfg.PauseThreshold < 0 || cfg.PauseThreshold > 0.5 {
+		return fmt.Errorf("invalid containerd.gc.pauseThreshold: %g. Must be between 0 and 0.5", cfg.PauseThreshold)
+	}
+	if cfg.DeletionThreshold < 0 {
+		return fmt.Errorf("invalid containerd.gc.deletionThreshold: %d. Must not be negative", cfg.DeletionThreshold)
+	}
+	if cfg.MutationThreshold < 0 {
+		return fmt.Errorf("invalid containerd.gc.mutationThreshold: %d. Must not be negative", cfg.MutationThreshold)
+	}
+	for field, value := range map[string]string{"scheduleDelay": cfg.ScheduleDelay, "startupDelay": cfg.StartupDelay} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("invalid containerd.gc.%s: %s. Expected a duration such as 100ms", field, value)
+		}
+	}
+	return nil
+}
+
+// validateKubeletDiskSettings checks the image GC, eviction and log rotation settings of kubelet, and that image
+// GC starts before kubelet evicts pods for lack of disk space. Otherwise a full disk evicts pods while unused
+// images are still taking up space.
+func validateKubeletDiskSettings(cfg *KubeletConfig) error {
+	high, low := cfg.ImageGCHighThreshold, cfg.ImageGCLowThreshold
+	if high < 0 || high > 100 {
+		return fmt.Errorf("invalid node.kubelet.imageGCHighThreshold: %d. Must be between 0 and 100", high)
+	}
+	if low < 0 || low > 100 {
+		return fmt.Errorf("invalid node.kubelet.imageGCLowThreshold: %d. Must be between 0 and 100", low)
+	}
+	if high != 0 && low != 0 && low >= high {
+		return fmt.Errorf("invalid node.kubelet.imageGCLowThreshold: %d. Must be lower than imageGCHighThreshold (%d)", low, high)
+	}
+	for _, thresholds := range []map[string]string{cfg.EvictionHard, cfg.EvictionSoft} {
+		for _, signal := range imageFilesystemSignals {
+			threshold, ok := thresholds[signal]
+			if !ok || high == 0 {
+				continue
+			}
+			percent, isPercent := strings.CutSuffix(threshold, "%")
+			available, err := strconv.ParseFloat(percent, 64)
+			if !isPercent || err != nil {
+				continue
+			}
+			if float64(high) >= 100-available {
+				return fmt.Errorf("invalid node.kubelet.imageGCHighThreshold: %d. kubelet evicts pods when %s is below %s, "+
+					"so image garbage collection must start below %g%% disk usage", high, signal, threshold, 100-available)
+			}
+		}
+	}
+	if age := cfg.ImageMinimumGCAge; age != "" {
+		if d, err := time.ParseDuration(age); err != nil || d < 0 {
+			return fmt.Errorf("invalid node.kubelet.imageMinimumGCAge: %s. Expected a duration such as 10m", age)
+		}
+	}
+	if size := cfg.ContainerLogMaxSize; size != "" {
+		if quantity, err := resource.ParseQuantity(size); err != nil || quantity.Sign() <= 0 {
+			return fmt.Errorf("invalid node.kubelet.containerLogMaxSize: %s. Expected a size such as 5Mi", size)
+		}
+	}
+	if files := cfg.ContainerLogMaxFiles; files != 0 && files < 2 {
+		return fmt.Errorf("invalid node.kubelet.containerLogMaxFiles: %d. kubelet keeps at least 2 files", files)
+	}
+	return nil
+}
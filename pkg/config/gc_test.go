@@ -0,0 +1,71 @@
+package config
+
+import "testing"
+
+func TestValidateContainerdGC(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ContainerdGCConfig
+		wantErr bool
+	}{
+		{name: "defaults", cfg: ContainerdGCConfig{}},
+		{name: "tuned", cfg: ContainerdGCConfig{PauseThreshold: 0.05, DeletionThreshold: 10, MutationThreshold: 50, ScheduleDelay: "10ms", StartupDelay: "1s"}},
+		{name: "pause threshold too high", cfg: ContainerdGCConfig{PauseThreshold: 0.8}, wantErr: true},
+		{name: "negative deletion threshold", cfg: ContainerdGCConfig{DeletionThreshold: -1}, wantErr: true},
+		{name: "delay without unit", cfg: ContainerdGCConfig{ScheduleDelay: "10"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateContainerdGC(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateContainerdGC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateKubeletDiskSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     KubeletConfig
+		wantErr bool
+	}{
+		{name: "unset", cfg: KubeletConfig{}},
+		{name: "defaults", cfg: KubeletConfig{ImageGCHighThreshold: 85, ImageGCLowThreshold: 80}},
+		{
+			name: "small disk",
+			cfg: KubeletConfig{
+				ImageGCHighThreshold: 70,
+				ImageGCLowThreshold:  50,
+				EvictionHard:         map[string]string{"nodefs.available": "10%", "imagefs.available": "15%", "memory.available": "750Mi"},
+				ImageMinimumGCAge:    "10m",
+				ContainerLogMaxSize:  "5Mi",
+				ContainerLogMaxFiles: 3,
+			},
+		},
+		{name: "low above high", cfg: KubeletConfig{ImageGCHighThreshold: 70, ImageGCLowThreshold: 75}, wantErr: true},
+		{name: "threshold above 100", cfg: KubeletConfig{ImageGCHighThreshold: 101}, wantErr: true},
+		{
+			name:    "eviction before image GC",
+			cfg:     KubeletConfig{ImageGCHighThreshold: 85, ImageGCLowThreshold: 80, EvictionHard: map[string]string{"imagefs.available": "15%"}},
+			wantErr: true,
+		},
+		{
+			name: "absolute eviction threshold",
+			cfg:  KubeletConfig{ImageGCHighThreshold: 85, EvictionHard: map[string]string{"nodefs.available": "1Gi"}},
+		},
+		{name: "invalid minimum age", cfg: KubeletConfig{ImageMinimumGCAge: "2 minutes"}, wantErr: true},
+		{name: "invalid log size", cfg: KubeletConfig{ContainerLogMaxSize: "five"}, wantErr: true},
+		{name: "single log file", cfg: KubeletConfig{ContainerLogMaxFiles: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateKubeletDiskSettings(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateKubeletDiskSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Multi-Instance GPU settings
+const (
+	MIGStrategySingle = "single"
+	MIGStrategyMixed  = "mixed"
+
+	// MIGStrategyLabel records how the device plugin should expose the MIG devices of the node
+	MIGStrategyLabel = "kubernetes.azure.com/flex-mig-strategy"
+
+	// MIGProfileLabelPrefix prefixes the labels counting the MIG devices of a profile on each GPU, e.g.
+	// kubernetes.azure.com/flex-mig.3g.40gb=2
+	MIGProfileLabelPrefix = "kubernetes.azure.com/flex-mig."
+)
+
+// migProfilePattern matches GPU instance profiles such as 1g.10gb, 1g.10gb+me or 7g.80gb
+var migProfilePattern = regexp.MustCompile(`^[1-7]g\.[0-9]+gb(\+me)?$`)
+
+func (c *Config) setGPUDefaults() {
+	mig := &c.GPU.MIG
+	if !mig.Enabled {
+		return
+	}
+	if mig.Strategy == "" {
+		mig.Strategy = MIGStrategyMixed
+	}
+	c.setNodeLabelIfMissing(MIGStrategyLabel, mig.Strategy)
+	for profile, count := range migProfileCounts(mig.Profiles) {
+		c.setNodeLabelIfMissing(MIGProfileLabel(profile), strconv.Itoa(count))
+	}
+}
+
+// MIGProfileLabel returns the label counting the MIG devices of a profile. Label keys cannot hold the + of
+// profiles with media extensions, so it becomes a dash.
+func MIGProfileLabel(profile string) string {
+	return MIGProfileLabelPrefix + strings.ReplaceAll(profile, "+", "-")
+}
+
+// migProfileCounts counts the GPU instances of each profile
+func migProfileCounts(profiles []string) map[string]int {
+	counts := map[string]int{}
+	for _, profile := range profiles {
+		counts[profile]++
+	}
+	return counts
+}
+
+// validateMIG validates gpu.mig. Whether the GPUs support the profiles is left to nvidia-smi, since each GPU
+// model has its own.
+func validateMIG(cfg *MIGConfig) error {
+	if len(cfg.Profiles) == 0 {
+		return fmt.Errorf("invalid gpu.mig.profiles: at least one GPU instance profile is required, e.g. 3g.40gb")
+	}
+	for _, profile := range cfg.Profiles {
+		if !migProfilePattern.MatchString(profile) {
+			return fmt.Errorf("invalid gpu.mig.profiles: %s. Expected a GPU instance profile such as 1g.10gb or 1g.10gb+me", profile)
+		}
+	}
+	gpus := map[int]bool{}
+	for _, gpu := range cfg.GPUs {
+		if gpu < 0 || gpus[gpu] {
+			return fmt.Errorf("invalid gpu.mig.gpus: %v. Expected distinct GPU indexes", cfg.GPUs)
+		}
+		gpus[gpu] = true
+	}
+	switch cfg.Strategy {
+	case "", MIGStrategyMixed:
+	case MIGStrategySingle:
+		// The single strategy exposes every MIG device as nvidia.com/gpu, so they must all be alike
+		if len(migProfileCounts(cfg.Profiles)) > 1 {
+			return fmt.Errorf("invalid gpu.mig.profiles: %v. The single strategy requires one profile; use the mixed strategy", cfg.Profiles)
+		}
+	default:
+		return fmt.Errorf("invalid gpu.mig.strategy: %s. Valid values are: single, mixed", cfg.Strategy)
+	}
+	return nil
+}
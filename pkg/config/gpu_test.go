@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestValidateMIG(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     MIGConfig
+		wantErr bool
+	}{
+		{name: "mixed", cfg: MIGConfig{Profiles: []string{"3g.40gb", "2g.20gb", "1g.10gb"}}},
+		{name: "single", cfg: MIGConfig{Profiles: []string{"1g.10gb", "1g.10gb"}, Strategy: "single", GPUs: []int{0, 1}}},
+		{name: "media extensions", cfg: MIGConfig{Profiles: []string{"1g.10gb+me"}}},
+		{name: "no profiles", cfg: MIGConfig{}, wantErr: true},
+		{name: "invalid profile", cfg: MIGConfig{Profiles: []string{"3g"}}, wantErr: true},
+		{name: "duplicate GPU", cfg: MIGConfig{Profiles: []string{"7g.80gb"}, GPUs: []int{0, 0}}, wantErr: true},
+		{name: "single with several profiles", cfg: MIGConfig{Profiles: []string{"3g.40gb", "1g.10gb"}, Strategy: "single"}, wantErr: true},
+		{name: "unknown strategy", cfg: MIGConfig{Profiles: []string{"7g.80gb"}, Strategy: "none"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMIG(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateMIG() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMIGLabels(t *testing.T) {
+	cfg := &Config{GPU: GPUConfig{MIG: MIGConfig{Enabled: true, Profiles: []string{"3g.40gb", "1g.10gb+me", "3g.40gb"}}}}
+	cfg.setGPUDefaults()
+	want := map[string]string{
+		"kubernetes.azure.com/flex-mig-strategy":   "mixed",
+		"kubernetes.azure.com/flex-mig.3g.40gb":    "2",
+		"kubernetes.azure.com/flex-mig.1g.10gb-me": "1",
+	}
+	for key, value := range want {
+		if got := cfg.Node.Labels[key]; got != value {
+			t.Errorf("label %s = %q, want %q", key, got, value)
+		}
+	}
+}
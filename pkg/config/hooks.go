@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// When a hook runs relative to its step
+const (
+	HookPre  = "pre"
+	HookPost = "post"
+)
+
+// What a failed hook does to its step
+const (
+	HookOnFailureFail   = "fail"
+	HookOnFailureIgnore = "ignore"
+)
+
+// defaultHookTimeout bounds a hook without a timeout
+const defaultHookTimeout = 5 * time.Minute
+
+func (c *Config) setHooksDefaults() {
+	for i := range c.Hooks {
+		hook := &c.Hooks[i]
+		if hook.Timeout == 0 {
+			hook.Timeout = defaultHookTimeout
+		}
+		if hook.OnFailure == "" {
+			hook.OnFailure = HookOnFailureFail
+		}
+	}
+}
+
+// validateHooks validates the step hooks
+func validateHooks(hooks []HookConfig) error {
+	for i, hook := range hooks {
+		if hook.Step == "" {
+			return fmt.Errorf("hooks[%d].step is required", i)
+		}
+		switch hook.When {
+		case HookPre, HookPost:
+		default:
+			return fmt.Errorf("invalid hooks[%d].when: %s. Valid values are: pre, post", i, hook.When)
+		}
+		if (len(hook.Command) == 0) == (hook.Script == "") {
+			return fmt.Errorf("hooks[%d] must set exactly one of command and script", i)
+		}
+		if hook.Timeout < 0 {
+			return fmt.Errorf("hooks[%d].timeout must not be negative", i)
+		}
+		switch hook.OnFailure {
+		case HookOnFailureFail, HookOnFailureIgnore:
+		default:
+			return fmt.Errorf("invalid hooks[%d].onFailure: %s. Valid values are: fail, ignore", i, hook.OnFailure)
+		}
+	}
+	return nil
+}
+
+// StepHooks returns the hooks that run at when of the named step, in configuration order
+func (c *Config) StepHooks(step, when string) []HookConfig {
+	var hooks []HookConfig
+	for _, hook := range c.Hooks {
+		if hook.Step == step && hook.When == when {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    HookConfig
+		wantErr bool
+	}{
+		{name: "command", hook: HookConfig{Step: "ContainerdInstaller", When: HookPost, Command: []string{"/usr/local/bin/load-plugin"}}},
+		{name: "script", hook: HookConfig{Step: "KubeletInstaller", When: HookPre, Script: "mount /mnt/nfs", OnFailure: HookOnFailureIgnore}},
+		{name: "missing step", hook: HookConfig{When: HookPre, Script: "true"}, wantErr: true},
+		{name: "invalid when", hook: HookConfig{Step: "KubeletInstaller", When: "after", Script: "true"}, wantErr: true},
+		{name: "command and script", hook: HookConfig{Step: "KubeletInstaller", When: HookPre, Command: []string{"true"}, Script: "true"}, wantErr: true},
+		{name: "neither command nor script", hook: HookConfig{Step: "KubeletInstaller", When: HookPre}, wantErr: true},
+		{name: "negative timeout", hook: HookConfig{Step: "KubeletInstaller", When: HookPre, Script: "true", Timeout: -time.Second}, wantErr: true},
+		{name: "invalid onFailure", hook: HookConfig{Step: "KubeletInstaller", When: HookPre, Script: "true", OnFailure: "retry"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Hooks: []HookConfig{tt.hook}}
+			c.setHooksDefaults()
+			if err := validateHooks(c.Hooks); (err != nil) != tt.wantErr {
+				t.Fatalf("validateHooks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHooksDefaults(t *testing.T) {
+	c := &Config{Hooks: []HookConfig{{Step: "KubeletInstaller", When: HookPre, Script: "true"}}}
+	c.setHooksDefaults()
+	if hook := c.Hooks[0]; hook.Timeout != defaultHookTimeout || hook.OnFailure != HookOnFailureFail {
+		t.Errorf("defaults = %+v", hook)
+	}
+}
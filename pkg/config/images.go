@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultImageRegistry hosts every built-in image unless images.registry redirects them
+const DefaultImageRegistry = "mcr.microsoft.com"
+
+// Built-in images the agent configures on the node
+const (
+	ImagePause        = "pause"
+	ImageKubeProxy    = "kube-proxy"
+	ImageCilium       = "cilium"
+	ImageCalicoCNI    = "calico-cni"
+	ImageNodeLocalDNS = "node-local-dns"
+	ImageSRIOVCNI     = "sriov-cni"
+)
+
+// builtinImages maps each built-in image to its repository and tag under the registry. %s in a tag is
+// replaced with the version of the image, see imageVersion.
+var builtinImages = map[string]string{
+	ImagePause:        "oss/kubernetes/pause:3.6",
+	ImageKubeProxy:    "oss/kubernetes/kube-proxy:v%s",
+	ImageCilium:       "oss/cilium/cilium:%s",
+	ImageCalicoCNI:    "oss/calico/cni:v%s",
+	ImageNodeLocalDNS: "oss/kubernetes/k8s-dns-node-cache:%s",
+	ImageSRIOVCNI:     "k8snetworkplumbingwg/sriov-cni:v2.8.1",
+}
+
+// builtinImageRegistries holds the default registry of built-in images that are not published to
+// DefaultImageRegistry. images.registry still replaces it.
+var builtinImageRegistries = map[string]string{
+	ImageSRIOVCNI: "ghcr.io",
+}
+
+// Sources of a resolved image reference
+const (
+	ImageSourceDefault  = "default"
+	ImageSourceRegistry = "images.registry"
+)
+
+// ResolvedImage is the final reference of a built-in image and the setting it came from
+type ResolvedImage struct {
+	Name      string `json:"name"`
+	Reference string `json:"reference"`
+	Source    string `json:"source"` // "default", "images.registry" or the per-image setting, e.g. "containerd.pauseImage"
+}
+
+// imageOverride returns the per-image setting that replaces the built-in reference as a whole, if any
+func (cfg *Config) imageOverride(name string) (string, string) {
+	switch name {
+	case ImagePause:
+		return cfg.Containerd.PauseImage, "containerd.pauseImage"
+	case ImageKubeProxy:
+		return cfg.KubeProxy.Image, "kubeProxy.image"
+	case ImageCilium:
+		return cfg.CNI.Cilium.Image, "cni.cilium.image"
+	case ImageCalicoCNI:
+		return cfg.CNI.Calico.Image, "cni.calico.image"
+	case ImageNodeLocalDNS:
+		return cfg.NodeLocalDNS.Image, "nodeLocalDNS.image"
+	case ImageSRIOVCNI:
+		return cfg.SRIOV.CNIImage, "sriov.cniImage"
+	}
+	return "", ""
+}
+
+// imageVersion returns the version in the tag of a built-in image: the CNI release for CNI images, the
+// node-cache release for the node-local DNS cache and the Kubernetes version otherwise, without a leading "v"
+func (cfg *Config) imageVersion(name string) string {
+	switch name {
+	case ImageCilium:
+		return strings.TrimPrefix(cfg.CNI.Cilium.Version, "v")
+	case ImageCalicoCNI:
+		return strings.TrimPrefix(cfg.CNI.Calico.Version, "v")
+	case ImageNodeLocalDNS:
+		return strings.TrimPrefix(cfg.NodeLocalDNS.Version, "v")
+	}
+	return strings.TrimPrefix(cfg.GetKubernetesVersion(), "v")
+}
+
+// usesImage reports whether the node runs a built-in image with the current configuration
+func (cfg *Config) usesImage(name string) bool {
+	switch name {
+	case ImageKubeProxy:
+		return cfg.KubeProxy.Enabled && cfg.KubeProxy.RunAs == KubeProxyRunAsStaticPod
+	case ImageCilium:
+		return cfg.CNI.Provider == CNIProviderCilium
+	case ImageCalicoCNI:
+		return cfg.CNI.Provider == CNIProviderCalico
+	case ImageNodeLocalDNS:
+		return cfg.NodeLocalDNS.Enabled
+	case ImageSRIOVCNI:
+		return cfg.SRIOV.Enabled
+	}
+	return true
+}
+
+// GetImageRegistry returns the registry built-in images are pulled from
+func (cfg *Config) GetImageRegistry() string {
+	if cfg.Images.Registry != "" {
+		return strings.TrimSuffix(cfg.Images.Registry, "/")
+	}
+	return DefaultImageRegistry
+}
+
+// ImageRegistryAddress returns the host:port of the image registry, defaulting to HTTPS
+func (cfg *Config) ImageRegistryAddress() string {
+	host, _, _ := strings.Cut(cfg.GetImageRegistry(), "/")
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, "443")
+}
+
+// ResolveImage returns the reference of a built-in image. A per-image setting wins over images.registry,
+// which wins over the default registry.
+func (cfg *Config) ResolveImage(name string) ResolvedImage {
+	if reference, setting := cfg.imageOverride(name); reference != "" {
+		return ResolvedImage{Name: name, Reference: reference, Source: setting}
+	}
+	source, registry := ImageSourceDefault, cfg.GetImageRegistry()
+	if cfg.Images.Registry != "" {
+		source = ImageSourceRegistry
+	} else if builtinRegistry, ok := builtinImageRegistries[name]; ok {
+		registry = builtinRegistry
+	}
+	reference := builtinImages[name]
+	if strings.Contains(reference, "%s") {
+		reference = fmt.Sprintf(reference, cfg.imageVersion(name))
+	}
+	return ResolvedImage{
+		Name:      name,
+		Reference: fmt.Sprintf("%s/%s", registry, reference),
+		Source:    source,
+	}
+}
+
+// GetImage returns the reference of a built-in image, e.g. mcr.microsoft.com/oss/kubernetes/pause:3.6
+func (cfg *Config) GetImage(name string) string {
+	return cfg.ResolveImage(name).Reference
+}
+
+// ResolveImages returns the references of the built-in images the node runs, sorted by name
+func (cfg *Config) ResolveImages() []ResolvedImage {
+	names := make([]string, 0, len(builtinImages))
+	for name := range builtinImages {
+		if cfg.usesImage(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	images := make([]ResolvedImage, 0, len(names))
+	for _, name := range names {
+		images = append(images, cfg.ResolveImage(name))
+	}
+	return images
+}
+
+// validateImageRegistry checks that images.registry is a registry host with an optional path prefix,
+// without a scheme, tag or digest
+func validateImageRegistry(registry string) error {
+	if registry == "" {
+		return nil
+	}
+	host, path, _ := strings.Cut(strings.TrimSuffix(registry, "/"), "/")
+	if host == "" || strings.Contains(registry, "://") || strings.ContainsAny(registry, " @") || strings.Contains(path, ":") {
+		return fmt.Errorf("invalid images.registry: %s. Expected a registry host and optional path, e.g. myregistry.azurecr.io/mirror", registry)
+	}
+	return nil
+}
+
+// defaultImagePrePullParallelism keeps pre-pulls from saturating slow links
+const defaultImagePrePullParallelism = 3
+
+// configMapReferencePattern matches a namespace/name reference to a ConfigMap
+var configMapReferencePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?/[a-z0-9]([-.a-z0-9]*[a-z0-9])?$`)
+
+func (c *Config) setImagePrePullDefaults() {
+	if c.ImagePrePull.Enabled && c.ImagePrePull.Parallelism == 0 {
+		c.ImagePrePull.Parallelism = defaultImagePrePullParallelism
+	}
+}
+
+// validateImagePrePull checks the image references and the ConfigMap reference of imagePrePull
+func validateImagePrePull(cfg *ImagePrePullConfig) error {
+	for _, image := range cfg.Images {
+		if image == "" || strings.ContainsAny(image, " \t") || strings.Contains(image, "://") {
+			return fmt.Errorf("invalid imagePrePull.images entry: %q. Expected an image reference such as mcr.microsoft.com/azuremonitor/containerinsights/ciprod:3.1.24", image)
+		}
+	}
+	if cfg.ConfigMap != "" && !configMapReferencePattern.MatchString(cfg.ConfigMap) {
+		return fmt.Errorf("invalid imagePrePull.configMap: %s. Expected namespace/name, e.g. kube-system/prepull-images", cfg.ConfigMap)
+	}
+	if cfg.Parallelism < 0 {
+		return fmt.Errorf("invalid imagePrePull.parallelism: %d. Must not be negative", cfg.Parallelism)
+	}
+	return nil
+}
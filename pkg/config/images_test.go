@@ -0,0 +1,165 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestResolveImage(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        *Config
+		wantReference string
+		wantSource    string
+	}{
+		{
+			name:          "default registry",
+			config:        &Config{},
+			wantReference: "mcr.microsoft.com/oss/kubernetes/pause:3.6",
+			wantSource:    ImageSourceDefault,
+		},
+		{
+			name:          "registry override with path prefix",
+			config:        &Config{Images: ImagesConfig{Registry: "mirror.contoso.local:5000/aks/"}},
+			wantReference: "mirror.contoso.local:5000/aks/oss/kubernetes/pause:3.6",
+			wantSource:    ImageSourceRegistry,
+		},
+		{
+			name: "per-image setting wins over registry override",
+			config: &Config{
+				Images:     ImagesConfig{Registry: "mirror.contoso.local"},
+				Containerd: ContainerdConfig{PauseImage: "registry.k8s.io/pause:3.9"},
+			},
+			wantReference: "registry.k8s.io/pause:3.9",
+			wantSource:    "containerd.pauseImage",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.ResolveImage(ImagePause)
+			if got.Reference != tt.wantReference || got.Source != tt.wantSource {
+				t.Errorf("ResolveImage() = %s (%s), want %s (%s)", got.Reference, got.Source, tt.wantReference, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestResolveKubeProxyImage(t *testing.T) {
+	cfg := &Config{Kubernetes: KubernetesConfig{Version: "1.30.9"}}
+	if got := cfg.GetImage(ImageKubeProxy); got != "mcr.microsoft.com/oss/kubernetes/kube-proxy:v1.30.9" {
+		t.Errorf("GetImage(kube-proxy) = %s", got)
+	}
+	for _, image := range cfg.ResolveImages() {
+		if image.Name == ImageKubeProxy {
+			t.Errorf("ResolveImages() lists kube-proxy although it does not run as a static pod")
+		}
+	}
+
+	cfg.KubeProxy = KubeProxyConfig{Enabled: true, RunAs: KubeProxyRunAsStaticPod}
+	if images := cfg.ResolveImages(); len(images) != 2 {
+		t.Errorf("ResolveImages() = %v, want kube-proxy and pause", images)
+	}
+}
+
+func TestResolveCiliumImage(t *testing.T) {
+	cfg := &Config{Kubernetes: KubernetesConfig{Version: "1.30.9"}}
+	for _, image := range cfg.ResolveImages() {
+		if image.Name == ImageCilium {
+			t.Errorf("ResolveImages() lists cilium although cni.provider is not cilium")
+		}
+	}
+
+	cfg.CNI = CNIConfig{Provider: CNIProviderCilium, Cilium: CiliumConfig{Version: "v1.16.6"}}
+	if got := cfg.GetImage(ImageCilium); got != "mcr.microsoft.com/oss/cilium/cilium:1.16.6" {
+		t.Errorf("GetImage(cilium) = %s", got)
+	}
+	cfg.CNI.Cilium.Image = "quay.io/cilium/cilium:v1.16.6"
+	if resolved := cfg.ResolveImage(ImageCilium); resolved.Reference != cfg.CNI.Cilium.Image || resolved.Source != "cni.cilium.image" {
+		t.Errorf("ResolveImage(cilium) = %+v", resolved)
+	}
+}
+
+func TestResolveCalicoCNIImage(t *testing.T) {
+	cfg := &Config{CNI: CNIConfig{Provider: CNIProviderCalico, Calico: CalicoConfig{Version: "3.29.1"}}}
+	if got := cfg.GetImage(ImageCalicoCNI); got != "mcr.microsoft.com/oss/calico/cni:v3.29.1" {
+		t.Errorf("GetImage(calico-cni) = %s", got)
+	}
+	if images := cfg.ResolveImages(); len(images) != 2 || images[0].Name != ImageCalicoCNI {
+		t.Errorf("ResolveImages() = %v, want calico-cni and pause", images)
+	}
+}
+
+func TestResolveNodeLocalDNSImage(t *testing.T) {
+	cfg := &Config{NodeLocalDNS: NodeLocalDNSConfig{Enabled: true, Version: "1.23.1"}}
+	if got := cfg.GetImage(ImageNodeLocalDNS); got != "mcr.microsoft.com/oss/kubernetes/k8s-dns-node-cache:1.23.1" {
+		t.Errorf("GetImage(node-local-dns) = %s", got)
+	}
+	if images := cfg.ResolveImages(); len(images) != 2 || images[0].Name != ImageNodeLocalDNS {
+		t.Errorf("ResolveImages() = %v, want node-local-dns and pause", images)
+	}
+}
+
+func TestValidateImageRegistry(t *testing.T) {
+	tests := []struct {
+		registry string
+		wantErr  bool
+	}{
+		{registry: "", wantErr: false},
+		{registry: "mcr.azure.cn", wantErr: false},
+		{registry: "mirror.contoso.local:5000/aks", wantErr: false},
+		{registry: "https://mirror.contoso.local", wantErr: true},
+		{registry: "mirror.contoso.local/aks:latest", wantErr: true},
+		{registry: "/aks", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.registry, func(t *testing.T) {
+			err := validateImageRegistry(tt.registry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateImageRegistry(%q) error = %v, wantErr %v", tt.registry, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateImagePrePull(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ImagePrePullConfig
+		wantErr bool
+	}{
+		{name: "built-in images only", cfg: ImagePrePullConfig{Enabled: true}},
+		{name: "images and configmap", cfg: ImagePrePullConfig{Enabled: true, Images: []string{"nginx", "mcr.microsoft.com/oss/kubernetes/pause:3.6"}, ConfigMap: "kube-system/prepull-images", Parallelism: 5}},
+		{name: "image with a scheme", cfg: ImagePrePullConfig{Images: []string{"https://mcr.microsoft.com/pause:3.6"}}, wantErr: true},
+		{name: "empty image", cfg: ImagePrePullConfig{Images: []string{""}}, wantErr: true},
+		{name: "configmap without namespace", cfg: ImagePrePullConfig{ConfigMap: "prepull-images"}, wantErr: true},
+		{name: "negative parallelism", cfg: ImagePrePullConfig{Parallelism: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateImagePrePull(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateImagePrePull() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveSRIOVCNIImage(t *testing.T) {
+	cfg := &Config{SRIOV: SRIOVConfig{Enabled: true}}
+	if got := cfg.GetImage(ImageSRIOVCNI); got != "ghcr.io/k8snetworkplumbingwg/sriov-cni:v2.8.1" {
+		t.Errorf("GetImage(sriov-cni) = %s", got)
+	}
+	cfg.Images.Registry = "mirror.contoso.local/aks"
+	if got := cfg.ResolveImage(ImageSRIOVCNI); got.Reference != "mirror.contoso.local/aks/k8snetworkplumbingwg/sriov-cni:v2.8.1" || got.Source != ImageSourceRegistry {
+		t.Errorf("ResolveImage(sriov-cni) with images.registry = %s (%s)", got.Reference, got.Source)
+	}
+	cfg.SRIOV.CNIImage = "registry.contoso.local/sriov-cni:v2.8.1"
+	if got := cfg.ResolveImage(ImageSRIOVCNI); got.Reference != cfg.SRIOV.CNIImage || got.Source != "sriov.cniImage" {
+		t.Errorf("ResolveImage(sriov-cni) with sriov.cniImage = %s (%s)", got.Reference, got.Source)
+	}
+	if images := cfg.ResolveImages(); len(images) != 2 || images[1].Name != ImageSRIOVCNI {
+		t.Errorf("ResolveImages() = %v, want pause and sriov-cni", images)
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+)
+
+// keyVaultResolveTimeout bounds the time spent resolving all secret references while loading config
+const keyVaultResolveTimeout = 2 * time.Minute
+
+// KeyVaultReferencePattern matches config values of the form
+// @keyvault(https://<vault>.vault.azure.net/secrets/<name>[/<version>])
+var KeyVaultReferencePattern = regexp.MustCompile(`^@keyvault\((https://[^/()]+)/secrets/([a-zA-Z0-9-]+)(?:/([a-zA-Z0-9]+))?/?\)$`)
+
+// secretResolver returns the value of a Key Vault secret
+type secretResolver func(ctx context.Context, vaultURL, name, version string) (string, error)
+
+// resolveSecretReferences replaces every string value in cfg that is a Key Vault reference
+// with the referenced secret value. Values that merely start with "@keyvault(" but are not
+// well-formed references are rejected so that typos never end up used as literal secrets.
+func resolveSecretReferences(ctx context.Context, cfg *Config, resolve secretResolver) error {
+	return walkStrings(reflect.ValueOf(cfg).Elem(), "", func(path string, value string) (string, error) {
+		if !strings.HasPrefix(value, "@keyvault(") {
+			return value, nil
+		}
+		matches := KeyVaultReferencePattern.FindStringSubmatch(value)
+		if matches == nil {
+			return "", fmt.Errorf("%s: malformed Key Vault reference, expected @keyvault(https://<vault>/secrets/<name>[/<version>])", path)
+		}
+		secret, err := resolve(ctx, matches[1], matches[2], matches[3])
+		if err != nil {
+			return "", fmt.Errorf("%s: failed to resolve Key Vault secret %s: %w", path, matches[2], err)
+		}
+		return secret, nil
+	})
+}
+
+// walkStrings visits every settable string reachable from v (struct fields, pointers,
+// slices and map values) and replaces it with the result of visit.
+func walkStrings(v reflect.Value, path string, visit func(path, value string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return walkStrings(v.Elem(), path, visit)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				name = field.Name
+			}
+			if err := walkStrings(v.Field(i), joinPath(path, name), visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			resolved, err := visit(joinPath(path, fmt.Sprint(iter.Key().Interface())), iter.Value().String())
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), reflect.ValueOf(resolved).Convert(v.Type().Elem()))
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		resolved, err := visit(path, v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(resolved)
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// newKeyVaultResolver returns a resolver that reads secrets with the machine's managed identity
+// (Azure VM IMDS or Azure Arc HIMDS). Clients are cached per vault.
+func newKeyVaultResolver(cfg *Config) secretResolver {
+	return newCredentialResolver(func() (azcore.TokenCredential, error) {
+		options := &azidentity.ManagedIdentityCredentialOptions{ID: cfg.GetManagedIdentityID()}
+		cred, err := azidentity.NewManagedIdentityCredential(options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create managed identity credential: %w", err)
+		}
+		return cred, nil
+	})
+}
+
+// newCredentialResolver returns a resolver that reads secrets with the credential newCredential creates.
+// Clients are cached per vault.
+func newCredentialResolver(newCredential func() (azcore.TokenCredential, error)) secretResolver {
+	var (
+		mu      sync.Mutex
+		clients = map[string]*azsecrets.Client{}
+	)
+	return func(ctx context.Context, vaultURL, name, version string) (string, error) {
+		mu.Lock()
+		client, ok := clients[vaultURL]
+		if !ok {
+			cred, err := newCredential()
+			if err != nil {
+				mu.Unlock()
+				return "", err
+			}
+			client, err = azsecrets.NewClient(vaultURL, cred, nil)
+			if err != nil {
+				mu.Unlock()
+				return "", fmt.Errorf("failed to create Key Vault client for %s: %w", vaultURL, err)
+			}
+			clients[vaultURL] = client
+		}
+		mu.Unlock()
+
+		resp, err := client.GetSecret(ctx, name, version, nil)
+		if err != nil {
+			return "", err
+		}
+		if resp.Value == nil {
+			return "", fmt.Errorf("secret %s has no value", name)
+		}
+		return *resp.Value, nil
+	}
+}
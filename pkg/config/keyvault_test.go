@@ -0,0 +1,59 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecretReferences(t *testing.T) {
+	resolve := func(_ context.Context, vaultURL, name, version string) (string, error) {
+		if name == "missing" {
+			return "", errors.New("SecretNotFound")
+		}
+		return vaultURL + "|" + name + "|" + version, nil
+	}
+
+	cfg := &Config{
+		Azure: AzureConfig{
+			ServicePrincipal: &ServicePrincipalConfig{
+				ClientSecret: "@keyvault(https://kv.vault.azure.net/secrets/sp-secret)",
+			},
+			BootstrapToken: &BootstrapTokenConfig{
+				Token: "@keyvault(https://kv.vault.azure.net/secrets/token/0123abcd)",
+			},
+		},
+		Node: NodeConfig{Labels: map[string]string{"plain": "value"}},
+	}
+	if err := resolveSecretReferences(context.Background(), cfg, resolve); err != nil {
+		t.Fatalf("resolveSecretReferences() error = %v", err)
+	}
+	if got := cfg.Azure.ServicePrincipal.ClientSecret; got != "https://kv.vault.azure.net|sp-secret|" {
+		t.Errorf("ClientSecret = %q", got)
+	}
+	if got := cfg.Azure.BootstrapToken.Token; got != "https://kv.vault.azure.net|token|0123abcd" {
+		t.Errorf("Token = %q", got)
+	}
+	if got := cfg.Node.Labels["plain"]; got != "value" {
+		t.Errorf("plain values must be untouched, got %q", got)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "malformed reference", value: "@keyvault(kv/sp-secret)", wantErr: "malformed Key Vault reference"},
+		{name: "resolution failure", value: "@keyvault(https://kv.vault.azure.net/secrets/missing)", wantErr: "azure.servicePrincipal.clientSecret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Azure: AzureConfig{ServicePrincipal: &ServicePrincipalConfig{ClientSecret: tt.value}}}
+			err := resolveSecretReferences(context.Background(), cfg, resolve)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
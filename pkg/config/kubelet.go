@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// kubelet resource manager policies
+const (
+	CPUManagerPolicyNone   = "none"
+	CPUManagerPolicyStatic = "static"
+
+	TopologyManagerPolicyNone           = "none"
+	TopologyManagerPolicyBestEffort     = "best-effort"
+	TopologyManagerPolicyRestricted     = "restricted"
+	TopologyManagerPolicySingleNUMANode = "single-numa-node"
+
+	MemoryManagerPolicyNone   = "None"
+	MemoryManagerPolicyStatic = "Static"
+)
+
+// Swap behaviors of kubelet's NodeSwap feature
+const (
+	SwapBehaviorNoSwap      = "NoSwap"
+	SwapBehaviorLimitedSwap = "LimitedSwap"
+)
+
+// KubeletServingCALabel tells that kubelet serves with a certificate of the cluster CA, as on AKS nodes with
+// kubelet serving certificate rotation
+const KubeletServingCALabel = "kubernetes.azure.com/kubelet-serving-ca"
+
+// cpuListPattern matches Linux CPU lists such as 0-1,4 or 2
+var cpuListPattern = regexp.MustCompile(`^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$`)
+
+// validateKubeletResourceManagers validates the CPU, topology and memory manager settings of kubelet
+func validateKubeletResourceManagers(cfg *KubeletConfig) error {
+	switch cfg.CPUManagerPolicy {
+	case "", CPUManagerPolicyNone, CPUManagerPolicyStatic:
+	default:
+		return fmt.Errorf("invalid node.kubelet.cpuManagerPolicy: %s. Valid values are: none, static", cfg.CPUManagerPolicy)
+	}
+	switch cfg.TopologyManagerPolicy {
+	case "", TopologyManagerPolicyNone, TopologyManagerPolicyBestEffort, TopologyManagerPolicyRestricted, TopologyManagerPolicySingleNUMANode:
+	default:
+		return fmt.Errorf("invalid node.kubelet.topologyManagerPolicy: %s. Valid values are: none, best-effort, restricted, single-numa-node", cfg.TopologyManagerPolicy)
+	}
+	if cfg.ReservedSystemCPUs != "" && !cpuListPattern.MatchString(cfg.ReservedSystemCPUs) {
+		return fmt.Errorf("invalid node.kubelet.reservedSystemCPUs: %s. Expected a CPU list such as 0-1 or 0,2", cfg.ReservedSystemCPUs)
+	}
+	// The static CPU manager takes the shared pool's CPUs from the reservation, which must not be empty
+	if cfg.CPUManagerPolicy == CPUManagerPolicyStatic && cfg.ReservedSystemCPUs == "" {
+		if cpu, err := resource.ParseQuantity(cfg.KubeReserved["cpu"]); err != nil || cpu.IsZero() {
+			return fmt.Errorf("invalid node.kubelet.cpuManagerPolicy: static requires reservedSystemCPUs or a cpu kubeReserved")
+		}
+	}
+	switch cfg.MemoryManagerPolicy {
+	case "", MemoryManagerPolicyNone:
+	case MemoryManagerPolicyStatic:
+		reserved, err := cfg.ReservedMemory()
+		if err != nil {
+			return err
+		}
+		if reserved.IsZero() {
+			return fmt.Errorf("invalid node.kubelet.memoryManagerPolicy: Static requires a memory kubeReserved or systemReserved, or a memory.available evictionHard")
+		}
+	default:
+		return fmt.Errorf("invalid node.kubelet.memoryManagerPolicy: %s. Valid values are: None, Static", cfg.MemoryManagerPolicy)
+	}
+	return nil
+}
+
+// ReservedMemory returns the memory kubelet keeps from pods: the memory of kubeReserved and systemReserved and the
+// memory.available hard eviction threshold. The Static memory manager requires the NUMA reservations to add up to it.
+func (cfg *KubeletConfig) ReservedMemory() (resource.Quantity, error) {
+	var total resource.Quantity
+	for field, value := range map[string]string{
+		"kubeReserved memory":           cfg.KubeReserved["memory"],
+		"systemReserved memory":         cfg.SystemReserved["memory"],
+		"evictionHard memory.available": cfg.EvictionHard["memory.available"],
+	} {
+		if value == "" {
+			continue
+		}
+		if strings.HasSuffix(value, "%") {
+			return resource.Quantity{}, fmt.Errorf("invalid node.kubelet.%s: %s. The Static memory manager requires an absolute quantity such as 500Mi", field, value)
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return resource.Quantity{}, fmt.Errorf("invalid node.kubelet.%s: %s. Expected a quantity such as 500Mi", field, value)
+		}
+		total.Add(quantity)
+	}
+	return total, nil
+}
+
+// validateKubeletConfigOverrides rejects overrides of the fields identifying the KubeletConfiguration, which would
+// make kubelet read the file as another kind or version
+func validateKubeletConfigOverrides(overrides map[string]any) error {
+	for key := range overrides {
+		if key == "apiVersion" || key == "kind" {
+			return fmt.Errorf("invalid node.kubelet.configOverrides: %s cannot be overridden", key)
+		}
+	}
+	return nil
+}
+
+// rawObject returns the object at path in a config document as written, matching keys case-insensitively like
+// the rest of config loading, or nil when there is none
+func rawObject(document map[string]any, path ...string) map[string]any {
+	current := document
+	for _, key := range path {
+		var next map[string]any
+		for existing, value := range current {
+			if strings.EqualFold(existing, key) {
+				next, _ = value.(map[string]any)
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// SwapEnabled reports whether swap stays on for kubelet's NodeSwap feature instead of being turned off
+func (cfg *KubeletConfig) SwapEnabled() bool {
+	return cfg.SwapBehavior != ""
+}
+
+// validateKubeletSwap validates node.kubelet.swapBehavior. kubelet limits the swap of pods only since NodeSwap's
+// second beta in Kubernetes 1.28.
+func validateKubeletSwap(c *Config) error {
+	behavior := c.Node.Kubelet.SwapBehavior
+	switch behavior {
+	case "":
+		return nil
+	case SwapBehaviorNoSwap, SwapBehaviorLimitedSwap:
+	default:
+		return fmt.Errorf("invalid node.kubelet.swapBehavior: %s. Valid values are: %s, %s", behavior, SwapBehaviorNoSwap, SwapBehaviorLimitedSwap)
+	}
+	if version := c.GetKubernetesVersion(); version != "" && !versionAtLeast(version, 1, 28) {
+		return fmt.Errorf("node.kubelet.swapBehavior requires Kubernetes 1.28 or later, kubernetes.version is %s", version)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateKubeletResourceManagers(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     KubeletConfig
+		wantErr bool
+	}{
+		{name: "defaults", cfg: KubeletConfig{}},
+		{name: "static with reserved CPUs", cfg: KubeletConfig{CPUManagerPolicy: "static", TopologyManagerPolicy: "single-numa-node", ReservedSystemCPUs: "0-1,32-33"}},
+		{name: "static with kube reserved CPU", cfg: KubeletConfig{CPUManagerPolicy: "static", KubeReserved: map[string]string{"cpu": "500m"}}},
+		{name: "static without reservation", cfg: KubeletConfig{CPUManagerPolicy: "static"}, wantErr: true},
+		{name: "unknown CPU policy", cfg: KubeletConfig{CPUManagerPolicy: "dynamic"}, wantErr: true},
+		{name: "unknown topology policy", cfg: KubeletConfig{TopologyManagerPolicy: "strict"}, wantErr: true},
+		{name: "invalid CPU list", cfg: KubeletConfig{ReservedSystemCPUs: "0-"}, wantErr: true},
+		{name: "static memory", cfg: KubeletConfig{MemoryManagerPolicy: "Static", KubeReserved: map[string]string{"memory": "1Gi"}, EvictionHard: map[string]string{"memory.available": "100Mi"}}},
+		{name: "static memory without reservation", cfg: KubeletConfig{MemoryManagerPolicy: "Static"}, wantErr: true},
+		{name: "static memory with percent threshold", cfg: KubeletConfig{MemoryManagerPolicy: "Static", EvictionHard: map[string]string{"memory.available": "5%"}}, wantErr: true},
+		{name: "lowercase memory policy", cfg: KubeletConfig{MemoryManagerPolicy: "static"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateKubeletResourceManagers(&tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateKubeletResourceManagers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKubeletConfigOverrides(t *testing.T) {
+	document := map[string]any{
+		"Node": map[string]any{
+			"kubelet": map[string]any{
+				"configOverrides": map[string]any{"maxPods": 250.0, "featureGates": map[string]any{"InPlacePodVerticalScaling": true}},
+			},
+		},
+	}
+	want := map[string]any{"maxPods": 250.0, "featureGates": map[string]any{"InPlacePodVerticalScaling": true}}
+	overrides := rawObject(document, "node", "kubelet", "configOverrides")
+	if !reflect.DeepEqual(overrides, want) {
+		t.Errorf("rawObject() = %v, want %v", overrides, want)
+	}
+	if got := rawObject(document, "node", "kubelet", "missing"); got != nil {
+		t.Errorf("rawObject() of a missing key = %v, want nil", got)
+	}
+
+	if err := validateKubeletConfigOverrides(overrides); err != nil {
+		t.Errorf("validateKubeletConfigOverrides() error = %v", err)
+	}
+	if err := validateKubeletConfigOverrides(map[string]any{"kind": "KubeletConfiguration"}); err == nil {
+		t.Error("validateKubeletConfigOverrides() accepted an override of kind")
+	}
+}
+
+func TestValidateKubeletSwap(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      string
+		swapBehavior string
+		wantErr      bool
+	}{
+		{name: "swap off", version: "1.27.9"},
+		{name: "limited swap", version: "1.30.4", swapBehavior: "LimitedSwap"},
+		{name: "no swap", version: "1.34.0", swapBehavior: "NoSwap"},
+		{name: "unlimited swap", version: "1.30.4", swapBehavior: "UnlimitedSwap", wantErr: true},
+		{name: "before the second beta", version: "1.27.9", swapBehavior: "LimitedSwap", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Kubernetes: KubernetesConfig{Version: tt.version}, Node: NodeConfig{Kubelet: KubeletConfig{SwapBehavior: tt.swapBehavior}}}
+			if err := validateKubeletSwap(c); (err != nil) != tt.wantErr {
+				t.Errorf("validateKubeletSwap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
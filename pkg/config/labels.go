@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// kubeletLabels are the kubernetes.io and k8s.io labels the NodeRestriction admission plugin lets kubelet set
+// on its own node. kubelet refuses to start with any other label of these namespaces.
+var kubeletLabels = []string{
+	"kubernetes.io/hostname",
+	"kubernetes.io/arch",
+	"kubernetes.io/os",
+	"beta.kubernetes.io/arch",
+	"beta.kubernetes.io/os",
+	"beta.kubernetes.io/instance-type",
+	"node.kubernetes.io/instance-type",
+	"topology.kubernetes.io/region",
+	"topology.kubernetes.io/zone",
+	"failure-domain.beta.kubernetes.io/region",
+	"failure-domain.beta.kubernetes.io/zone",
+}
+
+// kubeletLabelNamespaces are the namespaces under kubernetes.io whose labels kubelet may set freely
+var kubeletLabelNamespaces = []string{"kubelet.kubernetes.io", "node.kubernetes.io"}
+
+// validateNodeLabels validates the syntax of node.labels and rejects the labels of restricted namespaces,
+// which only the API server may set, e.g. node-role.kubernetes.io/worker
+func validateNodeLabels(labels map[string]string) error {
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid node.labels key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(labels[key]); len(errs) > 0 {
+			return fmt.Errorf("invalid node.labels value %q of %s: %s", labels[key], key, strings.Join(errs, "; "))
+		}
+		if isRestrictedLabel(key) {
+			return fmt.Errorf("invalid node.labels key %s: kubelet cannot set labels of the kubernetes.io and k8s.io namespaces except %s and the %s namespaces; set it with kubectl label after the node joins",
+				key, strings.Join(kubeletLabels, ", "), strings.Join(kubeletLabelNamespaces, ", "))
+		}
+	}
+	return nil
+}
+
+// isRestrictedLabel reports whether kubelet may not set the label key on its node
+func isRestrictedLabel(key string) bool {
+	namespace, _, ok := strings.Cut(key, "/")
+	if !ok || !inLabelNamespace(namespace, "kubernetes.io", "k8s.io") {
+		return false
+	}
+	return !slices.Contains(kubeletLabels, key) && !inLabelNamespace(namespace, kubeletLabelNamespaces...)
+}
+
+// inLabelNamespace reports whether namespace is one of namespaces or a subdomain of one
+func inLabelNamespace(namespace string, namespaces ...string) bool {
+	for _, parent := range namespaces {
+		if namespace == parent || strings.HasSuffix(namespace, "."+parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// Taint effects kubelet can register taints with
+const (
+	TaintEffectNoSchedule       = "NoSchedule"
+	TaintEffectPreferNoSchedule = "PreferNoSchedule"
+	TaintEffectNoExecute        = "NoExecute"
+)
+
+// validateNodeTaints validates node.taints, each of the form key[=value]:effect as --register-with-taints takes it
+func validateNodeTaints(taints []string) error {
+	seen := make(map[string]bool, len(taints))
+	for _, taint := range taints {
+		keyValue, effect, ok := strings.Cut(taint, ":")
+		if !ok {
+			return fmt.Errorf("invalid node.taints entry %q. Expected key[=value]:effect", taint)
+		}
+		key, value, _ := strings.Cut(keyValue, "=")
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid node.taints entry %q: %s", taint, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid node.taints entry %q: %s", taint, strings.Join(errs, "; "))
+		}
+		switch effect {
+		case TaintEffectNoSchedule, TaintEffectPreferNoSchedule, TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("invalid node.taints entry %q: effect %s. Valid effects are: NoSchedule, PreferNoSchedule, NoExecute", taint, effect)
+		}
+		if seen[key+":"+effect] {
+			return fmt.Errorf("invalid node.taints entry %q: the node already has a %s taint with key %s", taint, effect, key)
+		}
+		seen[key+":"+effect] = true
+	}
+	return nil
+}
+
+// rawLabels returns the labels at path in a config document as written, since viper lowercases their keys.
+// Values that are not strings, such as numbers, are formatted as written.
+func rawLabels(document map[string]any, path ...string) map[string]string {
+	object := rawObject(document, path...)
+	if object == nil {
+		return nil
+	}
+	labels := make(map[string]string, len(object))
+	for key, value := range object {
+		if text, ok := value.(string); ok {
+			labels[key] = text
+		} else {
+			labels[key] = fmt.Sprint(value)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateNodeLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "team and hardware", labels: map[string]string{"team": "payments", "example.com/GPU-Model": "A100", "kubernetes.azure.com/managed": "false"}},
+		{name: "allowed kubernetes.io labels", labels: map[string]string{"topology.kubernetes.io/zone": "edge-1", "node.kubernetes.io/exclude-from-external-load-balancers": "", "kubelet.kubernetes.io/pool": "a"}},
+		{name: "node role", labels: map[string]string{"node-role.kubernetes.io/worker": ""}, wantErr: true},
+		{name: "kubernetes.io namespace", labels: map[string]string{"kubernetes.io/role": "edge"}, wantErr: true},
+		{name: "k8s.io subdomain", labels: map[string]string{"node-restriction.k8s.io/pool": "a"}, wantErr: true},
+		{name: "invalid key", labels: map[string]string{"team name": "payments"}, wantErr: true},
+		{name: "invalid value", labels: map[string]string{"team": "pay,ments"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateNodeLabels(tt.labels); (err != nil) != tt.wantErr {
+				t.Errorf("validateNodeLabels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNodeTaints(t *testing.T) {
+	tests := []struct {
+		name    string
+		taints  []string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "with and without value", taints: []string{"example.com/not-ready=true:NoSchedule", "dedicated:NoExecute", "dedicated:PreferNoSchedule"}},
+		{name: "missing effect", taints: []string{"dedicated=gpu"}, wantErr: true},
+		{name: "unknown effect", taints: []string{"dedicated=gpu:NoRun"}, wantErr: true},
+		{name: "invalid key", taints: []string{"dedicated pool=gpu:NoSchedule"}, wantErr: true},
+		{name: "invalid value", taints: []string{"dedicated=gpu/a100:NoSchedule"}, wantErr: true},
+		{name: "duplicate", taints: []string{"dedicated=gpu:NoSchedule", "dedicated=cpu:NoSchedule"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateNodeTaints(tt.taints); (err != nil) != tt.wantErr {
+				t.Errorf("validateNodeTaints() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRawLabels(t *testing.T) {
+	document := map[string]any{"node": map[string]any{"Labels": map[string]any{"Team": "payments", "rack": 12.0}}}
+	want := map[string]string{"Team": "payments", "rack": "12"}
+	if got := rawLabels(document, "node", "labels"); !reflect.DeepEqual(got, want) {
+		t.Errorf("rawLabels() = %v, want %v", got, want)
+	}
+	if got := rawLabels(document, "node", "taints"); got != nil {
+		t.Errorf("rawLabels() of a missing object = %v, want nil", got)
+	}
+}
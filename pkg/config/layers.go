@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// hostLayerDir is the directory under the drop-in directory holding per-host override files
+const hostLayerDir = "hosts"
+
+// configLayers returns the config files merged for configPath, lowest precedence first:
+//
+//  1. the base file itself, e.g. /etc/aks-flex-node/config.json
+//  2. site drop-ins, <base>.d/*.json, *.yaml, *.yml and *.toml in lexical order, e.g. /etc/aks-flex-node/config.d/10-site.json
+//  3. the host override, <base>.d/hosts/<hostname>.json (or .yaml, .yml, .toml), matched on the short host name
+//  4. the overlays, in the order given, e.g. further --config files
+//
+// Missing drop-in directories and host files are not an error, so a single-file config keeps working.
+// A base fetched from a URL has no drop-ins or host layer; local overrides are given as overlays.
+func configLayers(configPath string, overlays ...string) ([]string, error) {
+	layers := []string{configPath}
+	if IsRemote(configPath) {
+		return append(layers, overlays...), nil
+	}
+
+	dropInDir := strings.TrimSuffix(configPath, filepath.Ext(configPath)) + ".d"
+	var siteLayers []string
+	for _, ext := range configExtensions {
+		matches, err := filepath.Glob(filepath.Join(dropInDir, "*"+ext))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list config drop-ins in %s: %w", dropInDir, err)
+		}
+		siteLayers = append(siteLayers, matches...)
+	}
+	sort.Strings(siteLayers)
+	layers = append(layers, siteLayers...)
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname for host config layer: %w", err)
+	}
+	hostname, _, _ = strings.Cut(hostname, ".")
+	var hostLayers []string
+	for _, ext := range configExtensions {
+		hostLayer := filepath.Join(dropInDir, hostLayerDir, hostname+ext)
+		if _, err := os.Stat(hostLayer); err == nil {
+			hostLayers = append(hostLayers, hostLayer)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to check host config layer %s: %w", hostLayer, err)
+		}
+	}
+	if len(hostLayers) > 1 {
+		return nil, fmt.Errorf("found several host config layers %s, keep one of them", strings.Join(hostLayers, ", "))
+	}
+	layers = append(layers, hostLayers...)
+
+	return append(layers, overlays...), nil
+}
+
+// mergeLayers reads the layer files in order and merges them into a single config document. Each file is
+// JSON, YAML or TOML according to its extension. Remote layers are taken from the fetched contents.
+// Environment variable references in string values are expanded in each layer before merging.
+// Later layers win: objects are merged key by key (keys match case-insensitively, like the rest of
+// config loading), arrays and scalars replace the earlier value as a whole, and an explicit null removes
+// the key. The returned provenance maps each dotted leaf path to the layer file that set it.
+func mergeLayers(paths []string, remote map[string][]byte) (map[string]any, map[string]string, error) {
+	merged := map[string]any{}
+	provenance := map[string]string{}
+	for _, path := range paths {
+		data, err := readLayer(path, remote)
+		path = LayerName(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read config file at %s: %w", path, err)
+		}
+		layer, err := decodeLayer(path, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse config file at %s: %w", path, err)
+		}
+		if _, err := substituteEnv(layer, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to substitute environment variables in config file at %s: %w", path, err)
+		}
+		mergeInto(merged, layer, "", path, provenance)
+	}
+	return merged, provenance, nil
+}
+
+// mergeInto merges src into dst, recording the layer of every leaf it sets
+func mergeInto(dst, src map[string]any, prefix, layer string, provenance map[string]string) {
+	// Iterate in a fixed order so that keys differing only in case resolve deterministically
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := src[key]
+		dstKey := key
+		for existing := range dst {
+			if strings.EqualFold(existing, key) {
+				dstKey = existing
+				break
+			}
+		}
+		path := joinPath(prefix, dstKey)
+
+		if value == nil {
+			delete(dst, dstKey)
+			forgetProvenance(provenance, path)
+			continue
+		}
+
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[dstKey].(map[string]any)
+		if srcIsMap {
+			if !dstIsMap {
+				forgetProvenance(provenance, path)
+				dstMap = map[string]any{}
+				dst[dstKey] = dstMap
+			}
+			if len(srcMap) == 0 && len(dstMap) == 0 {
+				// An empty object is still meaningful (e.g. "managedIdentity": {})
+				provenance[path] = layer
+			}
+			mergeInto(dstMap, srcMap, path, layer, provenance)
+			continue
+		}
+
+		forgetProvenance(provenance, path)
+		dst[dstKey] = value
+		provenance[path] = layer
+	}
+}
+
+// forgetProvenance drops the provenance of path and everything below it
+func forgetProvenance(provenance map[string]string, path string) {
+	for key := range provenance {
+		if strings.EqualFold(key, path) || strings.HasPrefix(strings.ToLower(key), strings.ToLower(path)+".") {
+			delete(provenance, key)
+		}
+	}
+}
+
+// Layers returns the config files the configuration was merged from, lowest precedence first
+func (cfg *Config) Layers() []string {
+	return cfg.layers
+}
+
+// Provenance returns the layer file that set each value, keyed by dotted field path (e.g. "node.labels.zone").
+// Values filled in by defaults or environment variables have no entry.
+func (cfg *Config) Provenance() map[string]string {
+	return cfg.provenance
+}
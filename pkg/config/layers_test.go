@@ -0,0 +1,161 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeLayers(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	base := write("base.json", `{
+		"azure": {"subscriptionId": "base-sub", "managedIdentity": {}},
+		"node": {"labels": {"zone": "a", "tier": "edge"}, "maxPods": 110},
+		"containerd": {"mirrors": ["one", "two"]}
+	}`)
+	site := write("site.json", `{
+		"node": {"labels": {"zone": "b"}},
+		"containerd": {"mirrors": ["three"]}
+	}`)
+	host := write("host.json", `{
+		"Azure": {"SubscriptionID": "host-sub", "managedIdentity": null},
+		"node": {"labels": {"tier": null, "rack": "r1"}}
+	}`)
+
+	merged, provenance, err := mergeLayers([]string{base, site, host}, nil)
+	if err != nil {
+		t.Fatalf("mergeLayers() error = %v", err)
+	}
+
+	wantMerged := map[string]any{
+		"azure":      map[string]any{"subscriptionId": "host-sub"},
+		"node":       map[string]any{"labels": map[string]any{"zone": "b", "rack": "r1"}, "maxPods": float64(110)},
+		"containerd": map[string]any{"mirrors": []any{"three"}},
+	}
+	if !reflect.DeepEqual(merged, wantMerged) {
+		t.Errorf("merged = %#v, want %#v", merged, wantMerged)
+	}
+
+	wantProvenance := map[string]string{
+		"azure.subscriptionId": host,
+		"node.labels.zone":     site,
+		"node.labels.rack":     host,
+		"node.maxPods":         base,
+		"containerd.mirrors":   site,
+	}
+	if !reflect.DeepEqual(provenance, wantProvenance) {
+		t.Errorf("provenance = %v, want %v", provenance, wantProvenance)
+	}
+}
+
+func TestConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	dropInDir := filepath.Join(dir, "config.d")
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("hostname unavailable: %v", err)
+	}
+	shortName, _, _ := strings.Cut(hostname, ".")
+
+	for _, path := range []string{
+		configPath,
+		filepath.Join(dropInDir, "20-site.json"),
+		filepath.Join(dropInDir, "10-region.json"),
+		filepath.Join(dropInDir, "notes.txt"),
+		filepath.Join(dropInDir, hostLayerDir, shortName+".json"),
+		filepath.Join(dropInDir, hostLayerDir, "other-host.json"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	layers, err := configLayers(configPath)
+	if err != nil {
+		t.Fatalf("configLayers() error = %v", err)
+	}
+	want := []string{
+		configPath,
+		filepath.Join(dropInDir, "10-region.json"),
+		filepath.Join(dropInDir, "20-site.json"),
+		filepath.Join(dropInDir, hostLayerDir, shortName+".json"),
+	}
+	if !reflect.DeepEqual(layers, want) {
+		t.Errorf("configLayers() = %v, want %v", layers, want)
+	}
+
+	// Overlays follow the host layer in the order given, whether or not they sort before it
+	overlays := []string{filepath.Join(dir, "zone-b.json"), filepath.Join(dir, "a-node.json")}
+	layers, err = configLayers(configPath, overlays...)
+	if err != nil {
+		t.Fatalf("configLayers() with overlays error = %v", err)
+	}
+	if want = append(want, overlays...); !reflect.DeepEqual(layers, want) {
+		t.Errorf("configLayers() with overlays = %v, want %v", layers, want)
+	}
+}
+
+func TestLoadConfigOverlays(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	base := write("base.json", `{
+		"azure": {
+			"subscriptionId": "12345678-1234-1234-1234-123456789012",
+			"tenantId": "12345678-1234-1234-1234-123456789012",
+			"bootstrapToken": {"token": "abcdef.0123456789abcdef"},
+			"targetCluster": {
+				"resourceId": "/subscriptions/12345678-1234-1234-1234-123456789012/resourceGroups/test-rg/providers/Microsoft.ContainerService/managedClusters/test-cluster",
+				"location": "eastus"
+			}
+		},
+		"node": {
+			"maxPods": 110,
+			"labels": {"tier": "edge"},
+			"kubelet": {
+				"serverURL": "https://test-cluster-abc123.hcp.eastus.azmk8s.io:443",
+				"caCertData": "LS0tLS1CRUdJTi1DRVJUSUZJQ0FURS0tLS0tCk1JSUREekNDQWZlZ0F3SUJBZ0lSQU1kbzBZa0R"
+			}
+		}
+	}`)
+	site := write("site.json", `{"node": {"maxPods": 50, "labels": {"zone": "b"}}}`)
+	host := write("host.json", `{"node": {"labels": {"zone": "b1", "tier": null}}}`)
+
+	cfg, err := LoadConfig(base, site, host)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if _, ok := cfg.Node.Labels["tier"]; ok || cfg.Node.MaxPods != 50 || cfg.Node.Labels["zone"] != "b1" {
+		t.Errorf("maxPods = %d, labels = %v, want the overlay values", cfg.Node.MaxPods, cfg.Node.Labels)
+	}
+	if got := cfg.Layers(); !reflect.DeepEqual(got, []string{base, site, host}) {
+		t.Errorf("Layers() = %v", got)
+	}
+	if got := cfg.Provenance()["node.maxPods"]; got != site {
+		t.Errorf("node.maxPods provenance = %q, want %s", got, site)
+	}
+
+	if _, err := LoadConfig(base, filepath.Join(dir, "missing.json")); err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("LoadConfig() with a missing overlay error = %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// IP families of network.ipFamilies, named as in the AKS network profile
+const (
+	IPFamilyIPv4 = "IPv4"
+	IPFamilyIPv6 = "IPv6"
+)
+
+// IsDualStack reports whether network.ipFamilies asks for both IPv4 and IPv6 node and pod addresses
+func (cfg *Config) IsDualStack() bool {
+	return len(cfg.Network.IPFamilies) == 2
+}
+
+// IsIPv6Primary reports whether IPv6 is the primary family of a dual-stack node
+func (cfg *Config) IsIPv6Primary() bool {
+	return cfg.IsDualStack() && cfg.Network.IPFamilies[0] == IPFamilyIPv6
+}
+
+// IsNodeIPConfigured reports whether node.ip selects the node IP
+func (cfg *Config) IsNodeIPConfigured() bool {
+	ip := cfg.Node.IP
+	return ip.Interface != "" || ip.CIDR != "" || ip.Address != ""
+}
+
+// GetNodeIPs returns the node IPs and their interfaces, primary family first: the address node.ip selects,
+// or one address per family on dual-stack nodes. It returns nothing when kubelet picks the node IP itself.
+func (cfg *Config) GetNodeIPs() ([]utilhost.InterfaceAddress, error) {
+	selector := utilhost.NodeIPSelector{
+		Interface: cfg.Node.IP.Interface,
+		CIDR:      cfg.Node.IP.CIDR,
+		Address:   cfg.Node.IP.Address,
+	}
+	switch {
+	case cfg.IsDualStack():
+		addresses, err := utilhost.SelectDualStackNodeIPs(selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select the dual-stack node IPs: %w", err)
+		}
+		if cfg.IsIPv6Primary() {
+			addresses[0], addresses[1] = addresses[1], addresses[0]
+		}
+		return addresses, nil
+	case cfg.IsNodeIPConfigured():
+		address, err := utilhost.SelectNodeIP(selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select the node IP from node.ip: %w", err)
+		}
+		return []utilhost.InterfaceAddress{address}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// validateNodeIP checks that node.ip sets at most one selector and that it is well formed, with one
+// CIDR or address per family on dual-stack nodes
+func validateNodeIP(ip *NodeIPConfig, dualStack bool) error {
+	set := 0
+	for _, value := range []string{ip.Interface, ip.CIDR, ip.Address} {
+		if value != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("node.ip accepts only one of interface, cidr and address")
+	}
+
+	maxValues := 1
+	if dualStack {
+		maxValues = 2
+	}
+	if ip.CIDR != "" {
+		values := strings.Split(ip.CIDR, ",")
+		for _, value := range values {
+			if _, _, err := net.ParseCIDR(strings.TrimSpace(value)); err != nil {
+				return fmt.Errorf("invalid node.ip.cidr: %s. Expected a range such as 192.168.10.0/24", ip.CIDR)
+			}
+		}
+		if len(values) > maxValues {
+			return fmt.Errorf("invalid node.ip.cidr: %s. Expected at most %d ranges", ip.CIDR, maxValues)
+		}
+	}
+	if ip.Address != "" {
+		values := strings.Split(ip.Address, ",")
+		for _, value := range values {
+			if net.ParseIP(strings.TrimSpace(value)) == nil {
+				return fmt.Errorf("invalid node.ip.address: %s. Expected an IPv4 or IPv6 address", ip.Address)
+			}
+		}
+		if len(values) > maxValues {
+			return fmt.Errorf("invalid node.ip.address: %s. Expected at most %d addresses", ip.Address, maxValues)
+		}
+	}
+	return nil
+}
+
+// validatePodIPv6CIDR checks that network.podIPv6CIDR is an IPv6 range, set only on dual-stack nodes
+func validatePodIPv6CIDR(cidr string, dualStack bool) error {
+	if cidr == "" {
+		return nil
+	}
+	if !dualStack {
+		return fmt.Errorf("network.podIPv6CIDR requires network.ipFamilies to list IPv4 and IPv6")
+	}
+	ip, _, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() != nil {
+		return fmt.Errorf("invalid network.podIPv6CIDR: %s. Expected an IPv6 range, e.g. fd12:3456:789a::/64", cidr)
+	}
+	return nil
+}
+
+// validateIPFamilies checks that network.ipFamilies is IPv4 alone, or IPv4 and IPv6 in either order
+func validateIPFamilies(families []string) error {
+	switch len(families) {
+	case 0:
+		return nil
+	case 1:
+		if families[0] == IPFamilyIPv4 {
+			return nil
+		}
+	case 2:
+		if (families[0] == IPFamilyIPv4 && families[1] == IPFamilyIPv6) || (families[0] == IPFamilyIPv6 && families[1] == IPFamilyIPv4) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid network.ipFamilies: %v. Expected [IPv4], [IPv4, IPv6] or [IPv6, IPv4]", families)
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Default NRI locations, those containerd uses
+const (
+	DefaultNRIPluginDir       = "/opt/nri/plugins"
+	DefaultNRIPluginConfigDir = "/etc/nri/conf.d"
+	defaultNRISocketPath      = "/var/run/nri/nri.sock"
+)
+
+func (c *Config) setNRIDefaults() {
+	nri := &c.Containerd.NRI
+	if !nri.Enabled {
+		return
+	}
+	if nri.PluginDir == "" {
+		nri.PluginDir = DefaultNRIPluginDir
+	}
+	if nri.PluginConfigDir == "" {
+		nri.PluginConfigDir = DefaultNRIPluginConfigDir
+	}
+	if nri.SocketPath == "" {
+		nri.SocketPath = defaultNRISocketPath
+	}
+}
+
+// validateNRI validates containerd.nri
+func validateNRI(cfg *ContainerdConfig) error {
+	// NRI first shipped with containerd 1.7, the default version
+	if cfg.Version != "" && !versionAtLeast(cfg.Version, 1, 7) {
+		return fmt.Errorf("invalid containerd.nri.enabled: NRI requires containerd.version 1.7 or later")
+	}
+	nri := &cfg.NRI
+	for field, path := range map[string]string{"pluginDir": nri.PluginDir, "pluginConfigDir": nri.PluginConfigDir, "socketPath": nri.SocketPath} {
+		if path != "" && !filepath.IsAbs(path) {
+			return fmt.Errorf("invalid containerd.nri.%s: %s. Must be an absolute path", field, path)
+		}
+	}
+	for field, value := range map[string]string{"registrationTimeout": nri.RegistrationTimeout, "requestTimeout": nri.RequestTimeout} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid containerd.nri.%s: %s. Expected a duration such as 5s", field, value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestValidateNRI(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ContainerdConfig
+		wantErr bool
+	}{
+		{name: "default containerd", cfg: ContainerdConfig{NRI: NRIConfig{Enabled: true}}},
+		{
+			name: "custom directories and timeouts",
+			cfg: ContainerdConfig{Version: "2.0.4", NRI: NRIConfig{
+				Enabled: true, PluginDir: "/opt/nri/plugins", PluginConfigDir: "/etc/nri/conf.d", RegistrationTimeout: "10s", RequestTimeout: "3s",
+			}},
+		},
+		{name: "containerd 1.6", cfg: ContainerdConfig{Version: "1.6.33", NRI: NRIConfig{Enabled: true}}, wantErr: true},
+		{name: "relative plugin directory", cfg: ContainerdConfig{NRI: NRIConfig{Enabled: true, PluginDir: "nri/plugins"}}, wantErr: true},
+		{name: "timeout without unit", cfg: ContainerdConfig{NRI: NRIConfig{Enabled: true, RequestTimeout: "2"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNRI(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateNRI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
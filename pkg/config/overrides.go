@@ -0,0 +1,154 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// overrideLayer is the provenance of values set with --set
+const overrideLayer = "--set"
+
+// Override sets one config field, given on the command line as path=value
+type Override struct {
+	// Path holds the keys of the field as the config file spells them, e.g. ["node", "labels", "zone"]
+	Path  []string
+	Value any
+}
+
+// ParseOverride parses a path=value override. The path is the dotted path of a config field, with \. for a
+// dot inside a key such as a label name. The value of a string field is taken as written; other values are
+// JSON, so numbers, true, false, arrays and objects can be given, and null removes the field.
+func ParseOverride(expression string) (Override, error) {
+	key, raw, ok := strings.Cut(expression, "=")
+	if !ok {
+		return Override{}, fmt.Errorf("invalid override %q, expected path=value", expression)
+	}
+	keys := splitOverridePath(key)
+
+	// Resolve the path against the config structs, so typos fail instead of being ignored
+	t := reflect.TypeFor[Config]()
+	var path []string
+	for _, part := range keys {
+		if part == "" {
+			return Override{}, fmt.Errorf("invalid override %q, the path has an empty key", expression)
+		}
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch {
+		case t.Kind() == reflect.Struct:
+			fields := schemaFields(t)
+			field, ok := lookupField(fields, part)
+			if !ok {
+				return Override{}, fmt.Errorf("invalid override %q: %s: unknown field%s", expression, joinPath(strings.Join(path, "."), part), suggestField(fields, part))
+			}
+			path, t = append(path, field.key), field.typ
+		case t.Kind() == reflect.Map:
+			path, t = append(path, part), t.Elem()
+		default:
+			return Override{}, fmt.Errorf("invalid override %q: %s is not an object", expression, strings.Join(path, "."))
+		}
+	}
+
+	// Expand environment variable references like in config files
+	value, err := substituteEnv(overrideValue(raw, t), strings.Join(path, "."))
+	if err != nil {
+		return Override{}, fmt.Errorf("invalid override %q: %w", expression, err)
+	}
+	checker := &schemaChecker{}
+	checker.check(valueNode(value), t, strings.Join(path, "."))
+	if len(checker.errors) > 0 {
+		return Override{}, fmt.Errorf("invalid override %q: %s", expression, checker.errors[0].Message)
+	}
+	return Override{Path: path, Value: value}, nil
+}
+
+// splitOverridePath splits a dotted path, keeping escaped dots in their key
+func splitOverridePath(path string) []string {
+	var keys []string
+	var key strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			key.WriteByte('.')
+			i++
+		case path[i] == '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(path[i])
+		}
+	}
+	return append(keys, key.String())
+}
+
+// overrideValue decodes the value of an override for a field of type t
+func overrideValue(raw string, t reflect.Type) any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var value any
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return raw
+	}
+	if _, isString := value.(string); !isString && value != nil && (t.Kind() == reflect.String || t == durationType) {
+		return raw
+	}
+	return normalizeNumbers(value)
+}
+
+// normalizeNumbers converts the JSON numbers of a decoded value to int64 or float64
+func normalizeNumbers(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		return (&jsonNode{value: value}).document()
+	case map[string]any:
+		for key, item := range value {
+			value[key] = normalizeNumbers(item)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = normalizeNumbers(item)
+		}
+	}
+	return value
+}
+
+// valueNode converts a decoded value into a tree the schema check can walk
+func valueNode(value any) *jsonNode {
+	switch value := value.(type) {
+	case map[string]any:
+		node := &jsonNode{object: true}
+		for key, item := range value {
+			node.fields = append(node.fields, jsonField{key: key, value: valueNode(item)})
+		}
+		return node
+	case []any:
+		node := &jsonNode{array: true}
+		for _, item := range value {
+			node.items = append(node.items, valueNode(item))
+		}
+		return node
+	case int64:
+		return &jsonNode{value: json.Number(fmt.Sprint(value))}
+	case float64:
+		return &jsonNode{value: json.Number(fmt.Sprint(value))}
+	default:
+		return &jsonNode{value: value}
+	}
+}
+
+// applyOverrides merges the overrides into a config document as its last layer
+func applyOverrides(document map[string]any, overrides []Override, provenance map[string]string) {
+	for _, override := range overrides {
+		layer := map[string]any{override.Path[len(override.Path)-1]: override.Value}
+		for i := len(override.Path) - 2; i >= 0; i-- {
+			layer = map[string]any{override.Path[i]: layer}
+		}
+		mergeInto(document, layer, "", overrideLayer, provenance)
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOverride(t *testing.T) {
+	t.Setenv("FLEX_TEST_ZONE", "b")
+
+	tests := []struct {
+		expression string
+		want       Override
+		wantErr    string
+	}{
+		{
+			expression: "Azure.SubscriptionID=00000000-0000-0000-0000-000000000001",
+			want:       Override{Path: []string{"azure", "subscriptionId"}, Value: "00000000-0000-0000-0000-000000000001"},
+		},
+		{expression: "node.maxPods=50", want: Override{Path: []string{"node", "maxPods"}, Value: int64(50)}},
+		{expression: "azure.arc.enabled=false", want: Override{Path: []string{"azure", "arc", "enabled"}, Value: false}},
+		// String fields take the value as written, even when it looks like JSON
+		{expression: "kubernetes.version=1.32", want: Override{Path: []string{"kubernetes", "version"}, Value: "1.32"}},
+		{expression: `kubernetes.version="1.32.7"`, want: Override{Path: []string{"kubernetes", "version"}, Value: "1.32.7"}},
+		{expression: "azure.armRetry.retryDelay=4s", want: Override{Path: []string{"azure", "armRetry", "retryDelay"}, Value: "4s"}},
+		{expression: `node.labels.example\.com/zone=${FLEX_TEST_ZONE}`, want: Override{Path: []string{"node", "labels", "example.com/zone"}, Value: "b"}},
+		{expression: "node.taints=[\"a=b:NoSchedule\"]", want: Override{Path: []string{"node", "taints"}, Value: []any{"a=b:NoSchedule"}}},
+		{expression: "azure.managedIdentity=null", want: Override{Path: []string{"azure", "managedIdentity"}, Value: nil}},
+		{expression: "azure.managedIdentity={}", want: Override{Path: []string{"azure", "managedIdentity"}, Value: map[string]any{}}},
+		{expression: "node.maxPods", wantErr: "expected path=value"},
+		{expression: "node..maxPods=1", wantErr: "empty key"},
+		{expression: "node.maxPod=1", wantErr: `node.maxPod: unknown field, did you mean "maxPods"?`},
+		{expression: "node.maxPods.x=1", wantErr: "node.maxPods is not an object"},
+		{expression: "node.maxPods=many", wantErr: `expected an integer, got string "many"`},
+		{expression: "azure.armRetry.retryDelay=soon", wantErr: `invalid duration "soon"`},
+		{expression: `azure.arc={"enabled": "yes"}`, wantErr: `expected true or false, got string "yes"`},
+		{expression: "azure.tenantId=${FLEX_TEST_UNSET}", wantErr: "environment variable FLEX_TEST_UNSET is not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := ParseOverride(tt.expression)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseOverride() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseOverride() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOverride() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigWithOverrides(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{
+		"azure": {
+			"subscriptionId": "12345678-1234-1234-1234-123456789012",
+			"tenantId": "12345678-1234-1234-1234-123456789012",
+			"bootstrapToken": {"token": "abcdef.0123456789abcdef"},
+			"targetCluster": {
+				"resourceId": "/subscriptions/12345678-1234-1234-1234-123456789012/resourceGroups/test-rg/providers/Microsoft.ContainerService/managedClusters/test-cluster",
+				"location": "eastus"
+			}
+		},
+		"node": {
+			"maxPods": 110,
+			"labels": {"tier": "edge"},
+			"kubelet": {
+				"serverURL": "https://test-cluster-abc123.hcp.eastus.azmk8s.io:443",
+				"caCertData": "LS0tLS1CRUdJTi1DRVJUSUZJQ0FURS0tLS0tCk1JSUREekNDQWZlZ0F3SUJBZ0lSQU1kbzBZa0R"
+			}
+		}
+	}`), 0o644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	var overrides []Override
+	for _, expression := range []string{"node.maxPods=50", "node.labels.Zone=b", "node.labels.tier=null"} {
+		override, err := ParseOverride(expression)
+		if err != nil {
+			t.Fatalf("ParseOverride(%q) error = %v", expression, err)
+		}
+		overrides = append(overrides, override)
+	}
+
+	cfg, err := LoadConfigWithOverrides(configFile, nil, overrides)
+	if err != nil {
+		t.Fatalf("LoadConfigWithOverrides() error = %v", err)
+	}
+	if _, ok := cfg.Node.Labels["tier"]; ok || cfg.Node.MaxPods != 50 || cfg.Node.Labels["Zone"] != "b" {
+		t.Errorf("maxPods = %d, labels = %v, want the overrides applied", cfg.Node.MaxPods, cfg.Node.Labels)
+	}
+	if got := cfg.Provenance()["node.maxPods"]; got != overrideLayer {
+		t.Errorf("node.maxPods provenance = %q, want %s", got, overrideLayer)
+	}
+
+	// Overrides are validated with the rest of the config
+	override, err := ParseOverride("azure.targetCluster.resourceId=not-an-id")
+	if err != nil {
+		t.Fatalf("ParseOverride() error = %v", err)
+	}
+	if _, err := LoadConfigWithOverrides(configFile, nil, []Override{override}); err == nil {
+		t.Error("LoadConfigWithOverrides() accepted an invalid cluster resource ID")
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultPluginTimeout bounds each call of a plugin without a timeout
+const defaultPluginTimeout = 10 * time.Minute
+
+// stepNamePattern matches names usable as step names, log file names and environment values
+var stepNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+func (c *Config) setPluginsDefaults() {
+	for i := range c.Plugins {
+		if c.Plugins[i].Timeout == 0 {
+			c.Plugins[i].Timeout = defaultPluginTimeout
+		}
+	}
+}
+
+// validatePlugins validates the plugin components
+func validatePlugins(plugins []PluginConfig) error {
+	names := map[string]bool{}
+	for i, plugin := range plugins {
+		if !stepNamePattern.MatchString(plugin.Name) {
+			return fmt.Errorf("invalid plugins[%d].name: %q. Expected a letter followed by letters, digits, '_' or '-'", i, plugin.Name)
+		}
+		if names[plugin.Name] {
+			return fmt.Errorf("duplicate plugins[%d].name: %s", i, plugin.Name)
+		}
+		names[plugin.Name] = true
+		if !filepath.IsAbs(plugin.Path) {
+			return fmt.Errorf("invalid plugins[%d].path: %q. Expected an absolute path", i, plugin.Path)
+		}
+		for _, env := range plugin.Env {
+			if name, _, found := strings.Cut(env, "="); !found || name == "" {
+				return fmt.Errorf("invalid plugins[%d].env entry: %q. Expected KEY=value", i, env)
+			}
+		}
+		if plugin.Timeout < 0 {
+			return fmt.Errorf("plugins[%d].timeout must not be negative", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidatePlugins(t *testing.T) {
+	sensor := PluginConfig{Name: "SecuritySensor", Path: "/opt/sensor/flex-plugin", Env: []string{"SENSOR_TENANT=contoso"}}
+	tests := []struct {
+		name    string
+		plugins []PluginConfig
+		wantErr bool
+	}{
+		{name: "valid", plugins: []PluginConfig{sensor, {Name: "storage-agent", Path: "/usr/local/bin/storage-plugin", After: "KubeletInstaller"}}},
+		{name: "invalid name", plugins: []PluginConfig{{Name: "security sensor", Path: "/opt/sensor/flex-plugin"}}, wantErr: true},
+		{name: "duplicate name", plugins: []PluginConfig{sensor, sensor}, wantErr: true},
+		{name: "relative path", plugins: []PluginConfig{{Name: "Sensor", Path: "flex-plugin"}}, wantErr: true},
+		{name: "invalid env", plugins: []PluginConfig{{Name: "Sensor", Path: "/opt/sensor/flex-plugin", Env: []string{"SENSOR_TENANT"}}}, wantErr: true},
+		{name: "negative timeout", plugins: []PluginConfig{{Name: "Sensor", Path: "/opt/sensor/flex-plugin", Timeout: -time.Second}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Plugins: tt.plugins}
+			c.setPluginsDefaults()
+			if err := validatePlugins(c.Plugins); (err != nil) != tt.wantErr {
+				t.Fatalf("validatePlugins() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"cmp"
+	"time"
+)
+
+const (
+	// ProfileProduction waits out Azure propagation and throttling delays patiently (default)
+	ProfileProduction = "production"
+	// ProfileFastFail shrinks retry budgets, propagation waits and download timeouts so that CI and lab
+	// runs exercising failure paths fail within minutes
+	ProfileFastFail = "fastFail"
+)
+
+// profileTimeouts holds the timeouts and retry budgets each profile starts from
+var profileTimeouts = map[string]TimeoutsConfig{
+	ProfileProduction: {
+		ArcRegistration:       5 * time.Minute,
+		IdentityPropagation:   10 * time.Second,
+		RoleAssignmentRetries: 5,
+		PermissionPropagation: 10 * time.Minute,
+		Download:              10 * time.Minute,
+	},
+	ProfileFastFail: {
+		ArcRegistration:       1 * time.Minute,
+		IdentityPropagation:   2 * time.Second,
+		RoleAssignmentRetries: 2,
+		PermissionPropagation: 1 * time.Minute,
+		Download:              2 * time.Minute,
+	},
+}
+
+// profileARMRetry holds the ARM client retry policy each profile starts from
+var profileARMRetry = map[string]ARMRetryConfig{
+	ProfileProduction: {
+		MaxRetries:    defaultARMMaxRetries,
+		RetryDelay:    defaultARMRetryDelay,
+		MaxRetryDelay: defaultARMMaxRetryDelay,
+	},
+	ProfileFastFail: {
+		MaxRetries:    2,
+		RetryDelay:    1 * time.Second,
+		MaxRetryDelay: 30 * time.Second,
+	},
+}
+
+// GetProfile returns the timing profile, falling back to production when none or an unknown one is set
+func (cfg *Config) GetProfile() string {
+	if _, ok := profileTimeouts[cfg.Agent.Profile]; ok {
+		return cfg.Agent.Profile
+	}
+	return ProfileProduction
+}
+
+// GetTimeouts returns the configured timeouts, with unset values taken from the profile
+func (cfg *Config) GetTimeouts() TimeoutsConfig {
+	preset := profileTimeouts[cfg.GetProfile()]
+	timeouts := cfg.Agent.Timeouts
+	return TimeoutsConfig{
+		ArcRegistration:       cmp.Or(timeouts.ArcRegistration, preset.ArcRegistration),
+		IdentityPropagation:   cmp.Or(timeouts.IdentityPropagation, preset.IdentityPropagation),
+		RoleAssignmentRetries: cmp.Or(timeouts.RoleAssignmentRetries, preset.RoleAssignmentRetries),
+		PermissionPropagation: cmp.Or(timeouts.PermissionPropagation, preset.PermissionPropagation),
+		Download:              cmp.Or(timeouts.Download, preset.Download),
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// defaultNoProxy lists destinations that are always reached directly: loopback, which also covers the
+// Arc HIMDS token endpoint, and the Azure instance metadata service
+var defaultNoProxy = []string{"localhost", "127.0.0.1", "::1", "169.254.169.254"}
+
+// IsProxyConfigured reports whether network.proxy sets an HTTP or HTTPS proxy
+func (cfg *Config) IsProxyConfigured() bool {
+	return cfg.Network.Proxy.HTTPProxy != "" || cfg.Network.Proxy.HTTPSProxy != ""
+}
+
+// GetNoProxy returns the destinations that bypass the proxy: the defaults, the node network, the configured
+// pod CIDRs, the cluster API server, the given extra hosts and the configured network.proxy.noProxy entries.
+// It only uses inputs that stay the same once the node runs pods, so the runtimes' proxy settings do not drift.
+func (cfg *Config) GetNoProxy(extraHosts ...string) []string {
+	entries := append([]string{}, defaultNoProxy...)
+	entries = append(entries, cfg.nodeNetworkSubnets()...)
+	entries = append(entries, cfg.podCIDRs()...)
+	if host := hostOf(cfg.Node.Kubelet.ServerURL); host != "" {
+		entries = append(entries, host)
+	}
+	for _, extra := range extraHosts {
+		if host := hostOf(extra); host != "" {
+			entries = append(entries, host)
+		}
+	}
+	entries = append(entries, cfg.Network.Proxy.NoProxy...)
+
+	seen := make(map[string]bool, len(entries))
+	noProxy := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !seen[entry] {
+			seen[entry] = true
+			noProxy = append(noProxy, entry)
+		}
+	}
+	return noProxy
+}
+
+// nodeNetworkSubnets returns the subnets of the node IP's interfaces, or of the default route's interface
+// when kubelet picks the node IP
+func (cfg *Config) nodeNetworkSubnets() []string {
+	addresses, err := cfg.GetNodeIPs()
+	if err != nil {
+		return nil
+	}
+	var interfaces []string
+	for _, address := range addresses {
+		interfaces = append(interfaces, address.Interface)
+	}
+	return utilhost.NodeNetworkSubnets(interfaces...)
+}
+
+// podCIDRs returns the pod CIDRs set in the config: cni.flannel.podCIDR, kubeProxy.clusterCIDR and
+// network.podIPv6CIDR
+func (cfg *Config) podCIDRs() []string {
+	var cidrs []string
+	for _, value := range []string{cfg.CNI.Flannel.PodCIDR, cfg.KubeProxy.ClusterCIDR, cfg.Network.PodIPv6CIDR} {
+		for _, cidr := range strings.Split(value, ",") {
+			if cidr = strings.TrimSpace(cidr); cidr != "" {
+				cidrs = append(cidrs, cidr)
+			}
+		}
+	}
+	return cidrs
+}
+
+// GetProxyEnvironment returns the proxy environment variables in upper and lower case, since tools differ
+// in which they read. It returns nothing when no proxy is configured.
+func (cfg *Config) GetProxyEnvironment(extraHosts ...string) []string {
+	if !cfg.IsProxyConfigured() {
+		return nil
+	}
+	var env []string
+	add := func(name, value string) {
+		if value != "" {
+			env = append(env, name+"="+value, strings.ToLower(name)+"="+value)
+		}
+	}
+	add("HTTP_PROXY", cfg.Network.Proxy.HTTPProxy)
+	add("HTTPS_PROXY", cfg.Network.Proxy.HTTPSProxy)
+	add("NO_PROXY", strings.Join(cfg.GetNoProxy(extraHosts...), ","))
+	return env
+}
+
+// applyProxyEnvironment exports network.proxy to the agent process, so every HTTP client (artifact
+// downloads, Azure SDK, Key Vault) and child process (curl, wget, azcmagent) uses it. It must run before
+// the first request, because Go reads the proxy variables only once.
+func (cfg *Config) applyProxyEnvironment() error {
+	for _, variable := range cfg.GetProxyEnvironment() {
+		name, value, _ := strings.Cut(variable, "=")
+		if err := os.Setenv(name, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateProxy checks the network.proxy URLs
+func validateProxy(proxy *ProxyConfig) error {
+	for name, proxyURL := range map[string]string{"httpProxy": proxy.HTTPProxy, "httpsProxy": proxy.HTTPSProxy} {
+		if proxyURL == "" {
+			continue
+		}
+		u, err := url.Parse(proxyURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid network.proxy.%s: %s. Expected http://host:port or https://host:port", name, proxyURL)
+		}
+	}
+	if len(proxy.NoProxy) > 0 && proxy.HTTPProxy == "" && proxy.HTTPSProxy == "" {
+		return fmt.Errorf("network.proxy.noProxy requires network.proxy.httpProxy or network.proxy.httpsProxy")
+	}
+	return nil
+}
+
+// hostOf returns the host of a URL, or the value itself when it is a plain host name
+func hostOf(value string) string {
+	if value == "" {
+		return ""
+	}
+	if u, err := url.Parse(value); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return value
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGetProxyEnvironment(t *testing.T) {
+	cfg := &Config{}
+	if env := cfg.GetProxyEnvironment(); env != nil {
+		t.Fatalf("GetProxyEnvironment() without proxy = %v, want nil", env)
+	}
+
+	cfg.Network.Proxy = ProxyConfig{
+		HTTPSProxy: "http://proxy.corp.example.com:3128",
+		NoProxy:    []string{".corp.example.com"},
+	}
+	cfg.Node.Kubelet.ServerURL = "https://bootstrap-abc.hcp.eastus.azmk8s.io:443"
+	cfg.CNI.Flannel.PodCIDR = "10.244.0.0/16, fd00:10:244::/56"
+	env := cfg.GetProxyEnvironment("https://cluster-xyz.hcp.eastus.azmk8s.io:443")
+
+	for _, want := range []string{"HTTPS_PROXY=http://proxy.corp.example.com:3128", "https_proxy=http://proxy.corp.example.com:3128"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("GetProxyEnvironment() = %v, missing %s", env, want)
+		}
+	}
+	if slices.ContainsFunc(env, func(v string) bool { return strings.HasPrefix(v, "HTTP_PROXY=") }) {
+		t.Errorf("GetProxyEnvironment() = %v, unexpected HTTP_PROXY", env)
+	}
+
+	var noProxy string
+	for _, variable := range env {
+		if value, ok := strings.CutPrefix(variable, "NO_PROXY="); ok {
+			noProxy = value
+		}
+	}
+	for _, want := range []string{"localhost", "169.254.169.254", "10.244.0.0/16", "fd00:10:244::/56", "bootstrap-abc.hcp.eastus.azmk8s.io", "cluster-xyz.hcp.eastus.azmk8s.io", ".corp.example.com"} {
+		if !slices.Contains(strings.Split(noProxy, ","), want) {
+			t.Errorf("NO_PROXY = %s, missing %s", noProxy, want)
+		}
+	}
+}
+
+func TestValidateProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxy   ProxyConfig
+		wantErr bool
+	}{
+		{name: "no proxy", proxy: ProxyConfig{}, wantErr: false},
+		{name: "http and https proxy", proxy: ProxyConfig{HTTPProxy: "http://proxy:3128", HTTPSProxy: "http://proxy:3128"}, wantErr: false},
+		{name: "missing scheme", proxy: ProxyConfig{HTTPSProxy: "proxy:3128"}, wantErr: true},
+		{name: "noProxy without proxy", proxy: ProxyConfig{NoProxy: []string{"example.com"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateProxy(&tt.proxy); (err != nil) != tt.wantErr {
+				t.Errorf("validateProxy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
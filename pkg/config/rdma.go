@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// RDMA settings
+const (
+	RDMANetNSShared    = "shared"
+	RDMANetNSExclusive = "exclusive"
+
+	// RDMALabel marks nodes prepared for RDMA, so the RDMA device plugins schedule to them
+	RDMALabel = "kubernetes.azure.com/flex-rdma"
+
+	// RDMASharedHCALabel marks nodes whose HCAs the RDMA shared device plugin shares among pods
+	RDMASharedHCALabel = "kubernetes.azure.com/flex-rdma-shared-hca"
+)
+
+var (
+	// rdmaDevicePattern matches RDMA device names such as mlx5_0 or mlx5_ib0
+	rdmaDevicePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+	// kernelModulePattern matches kernel module names such as ib_ipoib or mlx5_ib
+	kernelModulePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+func (c *Config) setRDMADefaults() {
+	if !c.RDMA.Enabled {
+		return
+	}
+	if c.RDMA.NetNSMode == "" {
+		c.RDMA.NetNSMode = RDMANetNSShared
+	}
+	c.setNodeLabelIfMissing(RDMALabel, "true")
+	if c.RDMA.SharedHCA {
+		c.setNodeLabelIfMissing(RDMASharedHCALabel, "true")
+	}
+}
+
+// validateRDMA validates the RDMA devices, modules and network namespace mode
+func validateRDMA(cfg *RDMAConfig) error {
+	for _, device := range cfg.Devices {
+		if !rdmaDevicePattern.MatchString(device) {
+			return fmt.Errorf("invalid rdma.devices: %q. Expected an RDMA device name such as mlx5_0", device)
+		}
+	}
+	for _, module := range cfg.Modules {
+		if !kernelModulePattern.MatchString(module) {
+			return fmt.Errorf("invalid rdma.modules: %q. Expected a kernel module name such as ib_ipoib", module)
+		}
+	}
+	switch cfg.NetNSMode {
+	case "", RDMANetNSShared:
+	case RDMANetNSExclusive:
+		// The shared device plugin hands the same HCA to pods in different network namespaces
+		if cfg.SharedHCA {
+			return fmt.Errorf("invalid rdma.sharedHCA: the RDMA shared device plugin requires rdma.netnsMode shared")
+		}
+	default:
+		return fmt.Errorf("invalid rdma.netnsMode: %s. Valid values are: shared, exclusive", cfg.NetNSMode)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestValidateRDMA(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RDMAConfig
+		wantErr bool
+	}{
+		{name: "defaults", cfg: RDMAConfig{}},
+		{name: "devices and modules", cfg: RDMAConfig{Devices: []string{"mlx5_0", "mlx5_1"}, Modules: []string{"ib_ipoib"}, SharedHCA: true}},
+		{name: "exclusive", cfg: RDMAConfig{NetNSMode: "exclusive"}},
+		{name: "invalid device", cfg: RDMAConfig{Devices: []string{"../mlx5_0"}}, wantErr: true},
+		{name: "invalid module", cfg: RDMAConfig{Modules: []string{"ib_ipoib debug=1"}}, wantErr: true},
+		{name: "unknown mode", cfg: RDMAConfig{NetNSMode: "private"}, wantErr: true},
+		{name: "shared HCA in exclusive mode", cfg: RDMAConfig{NetNSMode: "exclusive", SharedHCA: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRDMA(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRDMA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
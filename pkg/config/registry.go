@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// RegistryMirrorDefault declares mirrors for every registry without mirrors of its own
+const RegistryMirrorDefault = "_default"
+
+// validateRegistryMirrors checks that each registry is declared once with at least one http or https endpoint
+func validateRegistryMirrors(mirrors []RegistryMirrorConfig) error {
+	seen := make(map[string]bool, len(mirrors))
+	for _, mirror := range mirrors {
+		if !isRegistryHost(mirror.Registry) {
+			return fmt.Errorf("invalid containerd.mirrors registry: %q. Expected a registry host such as docker.io, optionally with a port, or %s", mirror.Registry, RegistryMirrorDefault)
+		}
+		if seen[mirror.Registry] {
+			return fmt.Errorf("invalid containerd.mirrors: registry %s is declared more than once", mirror.Registry)
+		}
+		seen[mirror.Registry] = true
+
+		if len(mirror.Endpoints) == 0 {
+			return fmt.Errorf("invalid containerd.mirrors: registry %s has no endpoints", mirror.Registry)
+		}
+		for _, endpoint := range mirror.Endpoints {
+			u, err := url.Parse(endpoint)
+			if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("invalid containerd.mirrors endpoint for %s: %s. Expected http://host:port or https://host:port", mirror.Registry, endpoint)
+			}
+		}
+		if mirror.CAFile != "" && !filepath.IsAbs(mirror.CAFile) {
+			return fmt.Errorf("invalid containerd.mirrors caFile for %s: %s. Expected an absolute path", mirror.Registry, mirror.CAFile)
+		}
+	}
+	return nil
+}
+
+// isRegistryHost reports whether value names a registry the way containerd's hosts directory does: a host with
+// an optional port, or _default
+func isRegistryHost(value string) bool {
+	if value == RegistryMirrorDefault {
+		return true
+	}
+	if value == "" || strings.ContainsAny(value, "/@ ") || strings.Contains(value, "://") {
+		return false
+	}
+	if host, port, err := net.SplitHostPort(value); err == nil {
+		return host != "" && port != ""
+	}
+	return !strings.Contains(value, ":")
+}
+
+// validateRegistryAuth checks that each registry is declared once with exactly one kind of credential, and that
+// managed identity is only used for Azure Container Registry with a node identity to authenticate as
+func validateRegistryAuth(cfg *Config) error {
+	seen := make(map[string]bool, len(cfg.Containerd.RegistryAuth))
+	for _, registryAuth := range cfg.Containerd.RegistryAuth {
+		if registryAuth.Registry == RegistryMirrorDefault || !isRegistryHost(registryAuth.Registry) {
+			return fmt.Errorf("invalid containerd.registryAuth registry: %q. Expected a registry host such as myregistry.azurecr.io", registryAuth.Registry)
+		}
+		if seen[registryAuth.Registry] {
+			return fmt.Errorf("invalid containerd.registryAuth: registry %s is declared more than once", registryAuth.Registry)
+		}
+		seen[registryAuth.Registry] = true
+
+		kinds := 0
+		if registryAuth.Username != "" || registryAuth.Password != "" {
+			if registryAuth.Username == "" || registryAuth.Password == "" {
+				return fmt.Errorf("invalid containerd.registryAuth for %s: username and password must be set together", registryAuth.Registry)
+			}
+			kinds++
+		}
+		if registryAuth.IdentityToken != "" {
+			kinds++
+		}
+		if registryAuth.ManagedIdentity {
+			kinds++
+			if !IsAzureContainerRegistry(registryAuth.Registry) {
+				return fmt.Errorf("invalid containerd.registryAuth for %s: managedIdentity only works with Azure Container Registry", registryAuth.Registry)
+			}
+			if !cfg.IsARCEnabled() && !cfg.IsMIConfigured() && !cfg.IsSPConfigured() && !cfg.IsFederatedIdentityConfigured() {
+				return fmt.Errorf("invalid containerd.registryAuth for %s: managedIdentity requires Arc, managed identity, service principal or federated identity authentication", registryAuth.Registry)
+			}
+		}
+		if kinds != 1 {
+			return fmt.Errorf("invalid containerd.registryAuth for %s: set exactly one of username and password, identityToken or managedIdentity", registryAuth.Registry)
+		}
+	}
+	return nil
+}
+
+// IsAzureContainerRegistry reports whether registry is an Azure Container Registry login server in any cloud
+func IsAzureContainerRegistry(registry string) bool {
+	host, _, err := net.SplitHostPort(registry)
+	if err != nil {
+		host = registry
+	}
+	for _, suffix := range []string{".azurecr.io", ".azurecr.cn", ".azurecr.us"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistryAuthUsesNodeIdentity reports whether containerd pulls from any registry with the node identity
+func (cfg *Config) RegistryAuthUsesNodeIdentity() bool {
+	for _, registryAuth := range cfg.Containerd.RegistryAuth {
+		if registryAuth.ManagedIdentity {
+			return true
+		}
+	}
+	return false
+}
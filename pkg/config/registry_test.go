@@ -0,0 +1,77 @@
+package config
+
+import "testing"
+
+func TestValidateRegistryMirrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirrors []RegistryMirrorConfig
+		wantErr bool
+	}{
+		{name: "none"},
+		{
+			name: "docker hub and mcr",
+			mirrors: []RegistryMirrorConfig{
+				{Registry: "docker.io", Endpoints: []string{"https://cache.contoso.local"}},
+				{Registry: "mcr.microsoft.com", Endpoints: []string{"http://10.0.0.5:5000"}, SkipUpstream: true, CAFile: "/etc/ssl/certs/contoso.pem"},
+			},
+		},
+		{name: "default and registry with port", mirrors: []RegistryMirrorConfig{
+			{Registry: "_default", Endpoints: []string{"https://proxy.contoso.local"}},
+			{Registry: "registry.contoso.local:5000", Endpoints: []string{"https://replica.contoso.local"}},
+		}},
+		{name: "registry with scheme", mirrors: []RegistryMirrorConfig{{Registry: "https://docker.io", Endpoints: []string{"https://cache.contoso.local"}}}, wantErr: true},
+		{name: "registry with path", mirrors: []RegistryMirrorConfig{{Registry: "docker.io/library", Endpoints: []string{"https://cache.contoso.local"}}}, wantErr: true},
+		{name: "duplicate registry", mirrors: []RegistryMirrorConfig{
+			{Registry: "docker.io", Endpoints: []string{"https://a.contoso.local"}},
+			{Registry: "docker.io", Endpoints: []string{"https://b.contoso.local"}},
+		}, wantErr: true},
+		{name: "no endpoints", mirrors: []RegistryMirrorConfig{{Registry: "docker.io"}}, wantErr: true},
+		{name: "endpoint without scheme", mirrors: []RegistryMirrorConfig{{Registry: "docker.io", Endpoints: []string{"cache.contoso.local"}}}, wantErr: true},
+		{name: "relative CA file", mirrors: []RegistryMirrorConfig{{Registry: "docker.io", Endpoints: []string{"https://cache.contoso.local"}, CAFile: "ca.pem"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRegistryMirrors(tt.mirrors); (err != nil) != tt.wantErr {
+				t.Errorf("validateRegistryMirrors() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRegistryAuth(t *testing.T) {
+	arc := AzureConfig{Arc: &ArcConfig{Enabled: true}}
+	tests := []struct {
+		name         string
+		azure        AzureConfig
+		registryAuth []RegistryAuthConfig
+		wantErr      bool
+	}{
+		{name: "none"},
+		{name: "username and password", registryAuth: []RegistryAuthConfig{{Registry: "registry.contoso.local:5000", Username: "puller", Password: "secret"}}},
+		{name: "identity token", registryAuth: []RegistryAuthConfig{{Registry: "ghcr.io", IdentityToken: "token"}}},
+		{name: "acr with arc identity", azure: arc, registryAuth: []RegistryAuthConfig{{Registry: "contoso.azurecr.io", ManagedIdentity: true}}},
+		{name: "acr in sovereign cloud", azure: arc, registryAuth: []RegistryAuthConfig{{Registry: "contoso.azurecr.us", ManagedIdentity: true}}},
+		{name: "managed identity without node identity", registryAuth: []RegistryAuthConfig{{Registry: "contoso.azurecr.io", ManagedIdentity: true}}, wantErr: true},
+		{name: "managed identity for another registry", azure: arc, registryAuth: []RegistryAuthConfig{{Registry: "docker.io", ManagedIdentity: true}}, wantErr: true},
+		{name: "username without password", registryAuth: []RegistryAuthConfig{{Registry: "docker.io", Username: "puller"}}, wantErr: true},
+		{name: "no credential", registryAuth: []RegistryAuthConfig{{Registry: "docker.io"}}, wantErr: true},
+		{name: "two credentials", registryAuth: []RegistryAuthConfig{{Registry: "docker.io", Username: "puller", Password: "secret", IdentityToken: "token"}}, wantErr: true},
+		{name: "default registry", registryAuth: []RegistryAuthConfig{{Registry: "_default", IdentityToken: "token"}}, wantErr: true},
+		{name: "registry with scheme", registryAuth: []RegistryAuthConfig{{Registry: "https://ghcr.io", IdentityToken: "token"}}, wantErr: true},
+		{name: "duplicate registry", registryAuth: []RegistryAuthConfig{
+			{Registry: "ghcr.io", IdentityToken: "a"},
+			{Registry: "ghcr.io", IdentityToken: "b"},
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Azure: tt.azure, Containerd: ContainerdConfig{RegistryAuth: tt.registryAuth}}
+			if err := validateRegistryAuth(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateRegistryAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+const (
+	// remoteConfigTimeout bounds fetching all remote config sources while loading config
+	remoteConfigTimeout = time.Minute
+
+	// maxRemoteConfigSize bounds the size of a remote config file
+	maxRemoteConfigSize = 4 << 20
+
+	// storageScope is the token scope of Azure Storage, used for blob URLs without a SAS token
+	storageScope = "https://storage.azure.com/.default"
+
+	// blobAPIVersion is the Storage API version sent with bearer tokens, which need 2017-11-09 or later
+	blobAPIVersion = "2021-08-06"
+)
+
+var (
+	// remoteCacheDir keeps the last fetched copy of each remote config source and its ETag, so the node
+	// starts with its last known config while the source is unreachable
+	remoteCacheDir = "/var/lib/aks-flex-node/config-cache"
+
+	remoteConfigClient = &http.Client{Timeout: 30 * time.Second}
+
+	// storageCredential authenticates requests to Azure Blob Storage URLs that carry no SAS token
+	storageCredential = func() (azcore.TokenCredential, error) {
+		return azidentity.NewDefaultAzureCredential(nil)
+	}
+)
+
+// RemoteSource is a config layer fetched from an HTTPS or Azure Blob Storage URL
+type RemoteSource struct {
+	URL     string // The URL without its query, which may hold a SAS token
+	Cache   string // The local copy of the source
+	Changed bool   // The content differs from the previously cached copy
+	Err     error  // Why the source could not be fetched or cached; the config was loaded all the same
+}
+
+// IsRemote reports whether a config path is a URL rather than a local file
+func IsRemote(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// LayerName returns how a layer is shown in errors, Layers and Provenance. The query and fragment of remote
+// sources are dropped, since they may hold a SAS token.
+func LayerName(path string) string {
+	if !IsRemote(path) {
+		return path
+	}
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// readLayer returns the content of a layer, taking remote sources from the fetched contents
+func readLayer(path string, remote map[string][]byte) ([]byte, error) {
+	if !IsRemote(path) {
+		return os.ReadFile(path)
+	}
+	data, ok := remote[path]
+	if !ok {
+		return nil, fmt.Errorf("config source %s was not fetched", LayerName(path))
+	}
+	return data, nil
+}
+
+// remoteCachePath returns where the last copy of a remote source is kept. It ignores the query, so a
+// rotated SAS token keeps the cached copy.
+func remoteCachePath(rawURL string) string {
+	sum := sha256.Sum256([]byte(LayerName(rawURL)))
+	return filepath.Join(remoteCacheDir, hex.EncodeToString(sum[:16]))
+}
+
+// fetchRemoteLayers fetches the remote layers, revalidating their cached copies with the ETag of the last
+// fetch. A source that cannot be fetched falls back to its cached copy, and is only an error without one.
+func fetchRemoteLayers(ctx context.Context, layers []string) (map[string][]byte, []RemoteSource, error) {
+	contents := map[string][]byte{}
+	var sources []RemoteSource
+	for _, layer := range layers {
+		if !IsRemote(layer) {
+			continue
+		}
+		data, source, err := fetchRemote(ctx, layer)
+		if err != nil {
+			return nil, nil, err
+		}
+		contents[layer] = data
+		sources = append(sources, source)
+	}
+	return contents, sources, nil
+}
+
+// fetchRemote fetches one remote source and refreshes its cached copy
+func fetchRemote(ctx context.Context, rawURL string) ([]byte, RemoteSource, error) {
+	source := RemoteSource{URL: LayerName(rawURL), Cache: remoteCachePath(rawURL)}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, source, fmt.Errorf("invalid config URL %s", source.URL)
+	}
+	if !strings.EqualFold(u.Scheme, "https") {
+		return nil, source, fmt.Errorf("config URL %s must use https", source.URL)
+	}
+
+	cached, cacheErr := os.ReadFile(source.Cache)
+	var etag string
+	if cacheErr == nil {
+		if data, err := os.ReadFile(source.Cache + ".etag"); err == nil {
+			etag = strings.TrimSpace(string(data))
+		}
+	}
+
+	data, newETag, err := requestRemoteConfig(ctx, u, etag)
+	switch {
+	case err != nil && cacheErr != nil:
+		return nil, source, fmt.Errorf("failed to fetch config from %s: %w", source.URL, err)
+	case err != nil:
+		source.Err = fmt.Errorf("failed to fetch config, using the copy cached at %s: %w", source.Cache, err)
+		return cached, source, nil
+	case data == nil:
+		// Not modified since the last fetch
+		return cached, source, nil
+	}
+
+	source.Changed = cacheErr == nil && !bytes.Equal(data, cached)
+	if err := writeRemoteCache(source.Cache, data, newETag); err != nil {
+		source.Err = fmt.Errorf("failed to cache config at %s: %w", source.Cache, err)
+	}
+	return data, source, nil
+}
+
+// requestRemoteConfig requests a remote source. It returns nil data if the source still has the given ETag.
+func requestRemoteConfig(ctx context.Context, u *url.URL, etag string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if strings.Contains(u.Hostname(), ".blob.core.") && !u.Query().Has("sig") {
+		// Blob URLs without a SAS token are read with the identity of the machine
+		cred, err := storageCredential()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create Azure credential: %w", err)
+		}
+		token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{storageScope}})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get a storage token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		req.Header.Set("x-ms-version", blobAPIVersion)
+	}
+
+	resp, err := remoteConfigClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			// Keep the SAS token out of logs
+			urlErr.URL = LayerName(urlErr.URL)
+		}
+		return nil, "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck // body close
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, etag, nil
+	default:
+		return nil, "", fmt.Errorf("server answered with status code %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(data) > maxRemoteConfigSize {
+		return nil, "", fmt.Errorf("config is larger than %d bytes", maxRemoteConfigSize)
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+// writeRemoteCache replaces the cached copy of a remote source and its ETag. Config files may hold secrets,
+// so only root can read them.
+func writeRemoteCache(path string, data []byte, etag string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err := utilio.ReplaceFile(path, data, 0o600); err != nil {
+		return err
+	}
+	if etag == "" {
+		if err := os.Remove(path + ".etag"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return utilio.ReplaceFile(path+".etag", []byte(etag+"\n"), 0o600)
+}
+
+// RemoteSources returns the layers fetched from URLs, with whether they changed and why they could not be
+// fetched, if they could not
+func (cfg *Config) RemoteSources() []RemoteSource {
+	return cfg.remoteSources
+}
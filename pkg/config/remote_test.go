@@ -0,0 +1,152 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// remoteServer serves a config document with an ETag, answering 304 Not Modified to a matching If-None-Match
+type remoteServer struct {
+	mu   sync.Mutex
+	body string
+	etag string
+}
+
+func (s *remoteServer) set(body, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body, s.etag = body, etag
+}
+
+func (s *remoteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Query().Get("sig") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("If-None-Match") == s.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", s.etag)
+	_, _ = w.Write([]byte(s.body))
+}
+
+// useRemoteServer points remote config fetching at a test server and a temporary cache
+func useRemoteServer(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	client, cacheDir := remoteConfigClient, remoteCacheDir
+	remoteConfigClient, remoteCacheDir = server.Client(), t.TempDir()
+	t.Cleanup(func() { remoteConfigClient, remoteCacheDir = client, cacheDir })
+	return server
+}
+
+func TestFetchRemote(t *testing.T) {
+	source := &remoteServer{body: `{"node": {"maxPods": 110}}`, etag: `"v1"`}
+	server := useRemoteServer(t, source)
+	rawURL := server.URL + "/fleet/config.json?sv=2022-11-02&sig=secret"
+	ctx := context.Background()
+
+	data, got, err := fetchRemote(ctx, rawURL)
+	if err != nil {
+		t.Fatalf("fetchRemote() error = %v", err)
+	}
+	if string(data) != source.body || got.URL != server.URL+"/fleet/config.json" || got.Changed || got.Err != nil {
+		t.Errorf("first fetchRemote() = %q, %+v", data, got)
+	}
+	if info, err := os.Stat(got.Cache); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("cached copy = %v, %v, want a file only root reads", info, err)
+	}
+
+	// Unchanged sources are revalidated with their ETag
+	if data, got, err = fetchRemote(ctx, rawURL); err != nil || string(data) != source.body || got.Changed {
+		t.Errorf("revalidating fetchRemote() = %q, %+v, %v", data, got, err)
+	}
+
+	// A rotated SAS token keeps the cache, and new content is reported as a change
+	source.set(`{"node": {"maxPods": 50}}`, `"v2"`)
+	if data, got, err = fetchRemote(ctx, strings.Replace(rawURL, "sv=2022-11-02", "sv=2023-01-03", 1)); err != nil ||
+		string(data) != `{"node": {"maxPods": 50}}` || !got.Changed {
+		t.Errorf("fetchRemote() after a change = %q, %+v, %v", data, got, err)
+	}
+
+	// An unreachable source falls back to the cached copy, without leaking the SAS token
+	server.Close()
+	data, got, err = fetchRemote(ctx, rawURL)
+	if err != nil || string(data) != `{"node": {"maxPods": 50}}` || got.Err == nil {
+		t.Fatalf("fetchRemote() of an unreachable source = %q, %+v, %v", data, got, err)
+	}
+	if strings.Contains(got.Err.Error(), "secret") {
+		t.Errorf("fetchRemote() error %q shows the SAS token", got.Err)
+	}
+
+	// Without a cached copy it fails
+	if _, _, err := fetchRemote(ctx, server.URL+"/other.json?sig=secret"); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("fetchRemote() of an uncached unreachable source error = %v", err)
+	}
+	if _, _, err := fetchRemote(ctx, "http://example.com/config.json"); err == nil || !strings.Contains(err.Error(), "must use https") {
+		t.Errorf("fetchRemote() of a plain http URL error = %v", err)
+	}
+}
+
+func TestLoadConfigRemote(t *testing.T) {
+	source := &remoteServer{etag: `"v1"`, body: `
+azure:
+  subscriptionId: 12345678-1234-1234-1234-123456789012
+  tenantId: 12345678-1234-1234-1234-123456789012
+  bootstrapToken:
+    token: abcdef.0123456789abcdef
+  targetCluster:
+    resourceId: /subscriptions/12345678-1234-1234-1234-123456789012/resourceGroups/test-rg/providers/Microsoft.ContainerService/managedClusters/test-cluster
+    location: eastus
+node:
+  maxPods: 110
+  kubelet:
+    serverURL: https://test-cluster-abc123.hcp.eastus.azmk8s.io:443
+    caCertData: LS0tLS1CRUdJTi1DRVJUSUZJQ0FURS0tLS0tCk1JSUREekNDQWZlZ0F3SUJBZ0lSQU1kbzBZa0R
+`}
+	server := useRemoteServer(t, source)
+	base := server.URL + "/fleet/config.yaml?sig=secret"
+	overlay := filepath.Join(t.TempDir(), "local.json")
+	if err := os.WriteFile(overlay, []byte(`{"node": {"labels": {"zone": "b"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(base, overlay)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	redacted := server.URL + "/fleet/config.yaml"
+	if got := cfg.Layers(); !reflect.DeepEqual(got, []string{redacted, overlay}) {
+		t.Errorf("Layers() = %v", got)
+	}
+	if got := cfg.Provenance()["node.maxPods"]; got != redacted {
+		t.Errorf("node.maxPods provenance = %q, want %s", got, redacted)
+	}
+	if sources := cfg.RemoteSources(); len(sources) != 1 || sources[0].URL != redacted || sources[0].Err != nil {
+		t.Errorf("RemoteSources() = %+v", sources)
+	}
+	if cfg.Node.MaxPods != 110 || cfg.Node.Labels["zone"] != "b" || cfg.Agent.ConfigRefresh <= 0 {
+		t.Errorf("maxPods = %d, labels = %v, configRefresh = %v", cfg.Node.MaxPods, cfg.Node.Labels, cfg.Agent.ConfigRefresh)
+	}
+
+	// Schema errors name the source without its query
+	source.set("node:\n  maxPods: many\n", `"v2"`)
+	schemaErrors, err := CheckSchema(base)
+	if err != nil {
+		t.Fatalf("CheckSchema() error = %v", err)
+	}
+	if len(schemaErrors) != 1 || schemaErrors[0].File != redacted {
+		t.Errorf("CheckSchema() = %v", schemaErrors)
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// How node.kubelet.reservedResources sizes the resources kept from pods
+const (
+	ReservedResourcesAuto = "auto" // Size the cpu and memory of kubeReserved from the host's capacity like AKS
+	ReservedResourcesNone = "none" // Reserve only what kubeReserved and systemReserved set
+)
+
+// detectReservedResources sizes the cpu and memory of node.kubelet.kubeReserved from the host's CPUs and memory
+// when node.kubelet.reservedResources is auto. Values set in the configuration are kept.
+func (c *Config) detectReservedResources() {
+	if c.Node.Kubelet.ReservedResources != ReservedResourcesAuto {
+		return
+	}
+	cpus, err := utilhost.OnlineCPUs()
+	if err != nil {
+		return
+	}
+	memory, err := utilhost.MemoryTotal()
+	if err != nil {
+		return
+	}
+	c.applyReservedResources(len(cpus), memory)
+}
+
+// applyReservedResources adds the reservations AKS gives a managed node of the same capacity and pod density
+// to node.kubelet.kubeReserved. AKS keeps nothing in systemReserved, so it is left as configured.
+func (c *Config) applyReservedResources(cpus int, memory int64) {
+	kubelet := &c.Node.Kubelet
+	if kubelet.KubeReserved == nil {
+		kubelet.KubeReserved = make(map[string]string)
+	}
+	setIfMissing(kubelet.KubeReserved, "cpu", fmt.Sprintf("%dm", reservedCPUMillicores(cpus)))
+	setIfMissing(kubelet.KubeReserved, "memory", fmt.Sprintf("%dMi", reservedMemoryMiB(c.Node.MaxPods, memory)))
+}
+
+// reservedCPUMillicores returns the CPU AKS reserves on a node with the given number of CPUs: 60m for the first
+// CPU, 40m for the second, 20m for each of the next two and 10m for each CPU beyond four
+func reservedCPUMillicores(cpus int) int {
+	millicores := 60
+	if cpus >= 2 {
+		millicores += 40
+	}
+	millicores += 20 * min(max(cpus-2, 0), 2)
+	millicores += 10 * max(cpus-4, 0)
+	return millicores
+}
+
+// reservedMemoryMiB returns the memory AKS reserves on a node: 20Mi per pod plus 50Mi, at most a quarter of the
+// node's memory
+func reservedMemoryMiB(maxPods int, memory int64) int64 {
+	return min(20*int64(maxPods)+50, memory/4/(1<<20))
+}
+
+// validateReservedResources validates node.kubelet.reservedResources
+func validateReservedResources(cfg *KubeletConfig) error {
+	switch cfg.ReservedResources {
+	case "", ReservedResourcesAuto, ReservedResourcesNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid node.kubelet.reservedResources: %s. Valid values are: auto, none", cfg.ReservedResources)
+	}
+}
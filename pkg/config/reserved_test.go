@@ -0,0 +1,39 @@
+package config
+
+import "testing"
+
+func TestReservedCPUMillicores(t *testing.T) {
+	// The reservations AKS documents for managed nodes
+	for cpus, want := range map[int]int{1: 60, 2: 100, 4: 140, 8: 180, 16: 260, 32: 420, 64: 740} {
+		if got := reservedCPUMillicores(cpus); got != want {
+			t.Errorf("reservedCPUMillicores(%d) = %d, want %d", cpus, got, want)
+		}
+	}
+}
+
+func TestApplyReservedResources(t *testing.T) {
+	const gib = int64(1) << 30
+	tests := []struct {
+		name       string
+		maxPods    int
+		memory     int64
+		configured map[string]string
+		want       map[string]string
+	}{
+		{name: "per pod", maxPods: 110, memory: 16 * gib, want: map[string]string{"cpu": "140m", "memory": "2250Mi"}},
+		{name: "capped at a quarter of memory", maxPods: 110, memory: 4 * gib, want: map[string]string{"cpu": "140m", "memory": "1024Mi"}},
+		{name: "configured values kept", maxPods: 30, memory: 16 * gib, configured: map[string]string{"cpu": "500m"}, want: map[string]string{"cpu": "500m", "memory": "650Mi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Node: NodeConfig{MaxPods: tt.maxPods, Kubelet: KubeletConfig{KubeReserved: tt.configured}}}
+			c.applyReservedResources(4, tt.memory)
+			for key, want := range tt.want {
+				if got := c.Node.Kubelet.KubeReserved[key]; got != want {
+					t.Errorf("kubeReserved %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+const (
+	// AllSteps selects every step without a retry policy of its own
+	AllSteps = "*"
+
+	// defaultRetryJitter spreads the retries of nodes that failed at the same time
+	defaultRetryJitter = 0.2
+)
+
+// defaultJitter is the jitter of policies that leave it unset
+var defaultJitter = func() *float64 { jitter := defaultRetryJitter; return &jitter }()
+
+// defaultStepRetry fills the unset values of step retry policies
+var defaultStepRetry = RetryPolicy{Attempts: 3, InitialDelay: 10 * time.Second, MaxDelay: 2 * time.Minute, Jitter: defaultJitter}
+
+// profileRetries holds the role assignment and download retry policies each profile starts from. Role
+// assignment attempts come from agent.timeouts.roleAssignmentRetries unless set here.
+var profileRetries = map[string]RetriesConfig{
+	ProfileProduction: {
+		RoleAssignment: RetryPolicy{InitialDelay: 5 * time.Second, MaxDelay: 30 * time.Second, Jitter: defaultJitter},
+		Download:       RetryPolicy{Attempts: 3, InitialDelay: 2 * time.Second, MaxDelay: 30 * time.Second, Jitter: defaultJitter},
+	},
+	ProfileFastFail: {
+		RoleAssignment: RetryPolicy{InitialDelay: 2 * time.Second, MaxDelay: 5 * time.Second, Jitter: defaultJitter},
+		Download:       RetryPolicy{Attempts: 2, InitialDelay: 1 * time.Second, MaxDelay: 5 * time.Second, Jitter: defaultJitter},
+	},
+}
+
+// GetRetries returns the configured retry policies, with unset values taken from the profile
+func (cfg *Config) GetRetries() RetriesConfig {
+	preset := profileRetries[cfg.GetProfile()]
+	preset.RoleAssignment.Attempts = cfg.GetTimeouts().RoleAssignmentRetries
+	retries := cfg.Agent.Retries
+
+	steps := make([]StepRetryConfig, 0, len(retries.Steps))
+	for _, step := range retries.Steps {
+		steps = append(steps, StepRetryConfig{Step: step.Step, Policy: step.Policy.withDefaults(defaultStepRetry)})
+	}
+	return RetriesConfig{
+		Steps:          steps,
+		RoleAssignment: retries.RoleAssignment.withDefaults(preset.RoleAssignment),
+		Download:       retries.Download.withDefaults(preset.Download),
+	}
+}
+
+// StepRetry returns the retry policy of a step: its own, the one of every step, or a single attempt
+func (cfg *Config) StepRetry(step string) RetryPolicy {
+	policy := RetryPolicy{Attempts: 1}
+	for _, retry := range cfg.GetRetries().Steps {
+		switch retry.Step {
+		case step:
+			return retry.Policy
+		case AllSteps:
+			policy = retry.Policy
+		}
+	}
+	return policy
+}
+
+// withDefaults returns the policy with its unset values taken from defaults
+func (p RetryPolicy) withDefaults(defaults RetryPolicy) RetryPolicy {
+	return RetryPolicy{
+		Attempts:     cmp.Or(p.Attempts, defaults.Attempts),
+		InitialDelay: cmp.Or(p.InitialDelay, defaults.InitialDelay),
+		MaxDelay:     cmp.Or(p.MaxDelay, defaults.MaxDelay),
+		Jitter:       cmp.Or(p.Jitter, defaults.Jitter),
+	}
+}
+
+// Backoff returns the policy as the delays between attempts. An unset jitter is none.
+func (p RetryPolicy) Backoff() utils.Backoff {
+	backoff := utils.Backoff{Attempts: p.Attempts, InitialDelay: p.InitialDelay, MaxDelay: p.MaxDelay}
+	if p.Jitter != nil {
+		backoff.Jitter = *p.Jitter
+	}
+	return backoff
+}
+
+// validateRetries validates the retry policies
+func validateRetries(retries RetriesConfig) error {
+	steps := map[string]bool{}
+	for i, step := range retries.Steps {
+		if step.Step == "" {
+			return fmt.Errorf("agent.retries.steps[%d].step is required", i)
+		}
+		if steps[step.Step] {
+			return fmt.Errorf("duplicate agent.retries.steps[%d].step: %s", i, step.Step)
+		}
+		steps[step.Step] = true
+		if err := validateRetryPolicy(step.Policy, fmt.Sprintf("agent.retries.steps[%d].policy", i)); err != nil {
+			return err
+		}
+	}
+	if err := validateRetryPolicy(retries.RoleAssignment, "agent.retries.roleAssignment"); err != nil {
+		return err
+	}
+	return validateRetryPolicy(retries.Download, "agent.retries.download")
+}
+
+// validateRetryPolicy validates a retry policy at the given configuration path
+func validateRetryPolicy(policy RetryPolicy, path string) error {
+	if policy.Attempts < 0 || policy.InitialDelay < 0 || policy.MaxDelay < 0 {
+		return fmt.Errorf("%s values must not be negative", path)
+	}
+	if policy.Jitter != nil && (*policy.Jitter < 0 || *policy.Jitter > 1) {
+		return fmt.Errorf("invalid %s.jitter: %v. Expected a fraction from 0 to 1", path, *policy.Jitter)
+	}
+	return nil
+}
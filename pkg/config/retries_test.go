@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+)
+
+// jitter returns a pointer to a jitter value for policy literals
+func jitter(value float64) *float64 {
+	return &value
+}
+
+func TestGetRetries(t *testing.T) {
+	cfg := &Config{Agent: AgentConfig{
+		Profile:  ProfileFastFail,
+		Timeouts: TimeoutsConfig{RoleAssignmentRetries: 4},
+		Retries: RetriesConfig{
+			Steps: []StepRetryConfig{
+				{Step: "ArcInstaller", Policy: RetryPolicy{Attempts: 5}},
+				{Step: "KubeletInstaller", Policy: RetryPolicy{Attempts: 2, Jitter: jitter(0)}},
+			},
+			Download: RetryPolicy{Attempts: 6, Jitter: jitter(0.5)},
+		},
+	}}
+	retries := cfg.GetRetries()
+
+	if got := retries.RoleAssignment; got.Attempts != 4 || got.MaxDelay != 5*time.Second {
+		t.Errorf("RoleAssignment = %+v, want roleAssignmentRetries attempts with the fastFail delays", got)
+	}
+	if got := retries.Download; got.Attempts != 6 || *got.Jitter != 0.5 || got.InitialDelay != time.Second {
+		t.Errorf("Download = %+v, want the configured values over the fastFail ones", got)
+	}
+	want := utils.Backoff{Attempts: 5, InitialDelay: 10 * time.Second, MaxDelay: 2 * time.Minute, Jitter: defaultRetryJitter}
+	if got := cfg.StepRetry("ArcInstaller").Backoff(); got != want {
+		t.Errorf("StepRetry(ArcInstaller) = %+v, want %+v", got, want)
+	}
+	if got := cfg.StepRetry("KubeletInstaller").Backoff(); got.Attempts != 2 || got.Jitter != 0 {
+		t.Errorf("StepRetry(KubeletInstaller) = %+v, want the configured attempts without jitter", got)
+	}
+	if got := cfg.StepRetry("ContainerdInstaller"); got.Attempts != 1 {
+		t.Errorf("StepRetry(ContainerdInstaller) = %+v, want a single attempt", got)
+	}
+}
+
+func TestValidateRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries RetriesConfig
+		wantErr bool
+	}{
+		{name: "valid", retries: RetriesConfig{Steps: []StepRetryConfig{{Step: AllSteps, Policy: RetryPolicy{Attempts: 2}}}}},
+		{name: "missing step", retries: RetriesConfig{Steps: []StepRetryConfig{{Policy: RetryPolicy{Attempts: 2}}}}, wantErr: true},
+		{name: "duplicate step", retries: RetriesConfig{Steps: []StepRetryConfig{{Step: "ArcInstaller"}, {Step: "ArcInstaller"}}}, wantErr: true},
+		{name: "negative attempts", retries: RetriesConfig{Download: RetryPolicy{Attempts: -1}}, wantErr: true},
+		{name: "jitter above 1", retries: RetriesConfig{RoleAssignment: RetryPolicy{Jitter: jitter(1.5)}}, wantErr: true},
+		{name: "jitter off", retries: RetriesConfig{RoleAssignment: RetryPolicy{Jitter: jitter(0)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRetries(tt.retries); (err != nil) != tt.wantErr {
+				t.Fatalf("validateRetries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigKeepsJitterOff(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	configJSON := `{
+		"azure": {
+			"subscriptionId": "12345678-1234-1234-1234-123456789012",
+			"tenantId": "12345678-1234-1234-1234-123456789012",
+			"cloud": "AzurePublicCloud",
+			"bootstrapToken": {"token": "abcdef.0123456789abcdef"},
+			"targetCluster": {
+				"resourceId": "/subscriptions/12345678-1234-1234-1234-123456789012/resourceGroups/test-rg/providers/Microsoft.ContainerService/managedClusters/test-cluster",
+				"location": "eastus"
+			}
+		},
+		"agent": {"retries": {"download": {"attempts": 4, "jitter": 0}}},
+		"node": {
+			"kubelet": {
+				"serverURL": "https://test-cluster-abc123.hcp.eastus.azmk8s.io:443",
+				"caCertData": "LS0tLS1CRUdJTi1DRVJUSUZJQ0FURS0tLS0tCk1JSUREekNDQWZlZ0F3SUJBZ0lSQU1kbzBZa0R"
+			}
+		}
+	}`
+	if err := os.WriteFile(configFile, []byte(configJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got := cfg.GetRetries().Download.Backoff(); got.Attempts != 4 || got.Jitter != 0 {
+		t.Errorf("download backoff = %+v, want 4 attempts without jitter", got)
+	}
+	if got := cfg.GetRetries().RoleAssignment.Backoff(); got.Jitter != defaultRetryJitter {
+		t.Errorf("role assignment jitter = %v, want the default %v", got.Jitter, defaultRetryJitter)
+	}
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// CRI runtimes kubelet runs pods with
+const (
+	ContainerRuntimeContainerd = "containerd"
+	ContainerRuntimeCRIO       = "crio"
+
+	// CRIOSocketPath is the CRI socket CRI-O listens on
+	CRIOSocketPath = "/var/run/crio/crio.sock"
+	// ContainerdSocketPath is the CRI socket containerd listens on
+	ContainerdSocketPath = "/run/containerd/containerd.sock"
+)
+
+// Low-level OCI runtimes containerd runs containers with
+const (
+	OCIRuntimeRunc = "runc"
+	OCIRuntimeCrun = "crun"
+
+	defaultCrunVersion = "1.19.1"
+)
+
+// gVisor settings
+const (
+	GVisorPlatformSystrap = "systrap"
+	GVisorPlatformKVM     = "kvm"
+	GVisorPlatformPtrace  = "ptrace"
+
+	// GVisorRuntimeHandler is the containerd runtime handler running pods with gVisor
+	GVisorRuntimeHandler = "runsc"
+
+	// GVisorRuntimeLabel marks nodes with gVisor, so the gvisor RuntimeClass schedules its pods to them
+	GVisorRuntimeLabel = "kubernetes.azure.com/flex-runtime-gvisor"
+
+	defaultGVisorVersion = "20250106.0"
+)
+
+// Kata Containers settings
+const (
+	KataHypervisorQEMU            = "qemu"
+	KataHypervisorCloudHypervisor = "clh"
+
+	// KataRuntimeHandler is the containerd runtime handler running pods in Kata VMs
+	KataRuntimeHandler = "kata"
+
+	// KataRuntimeLabel marks nodes with Kata Containers, so the kata RuntimeClass schedules its pods to them
+	KataRuntimeLabel = "kubernetes.azure.com/flex-runtime-kata"
+
+	defaultKataVersion = "3.13.0"
+)
+
+// Confidential containers settings
+const (
+	ConfidentialSNP = utilhost.ConfidentialSNP
+	ConfidentialTDX = utilhost.ConfidentialTDX
+
+	// KataCCRuntimeHandler is the containerd runtime handler running pods in confidential Kata VMs
+	KataCCRuntimeHandler = "kata-cc"
+
+	// KataCCRuntimeLabel marks nodes running confidential Kata VMs, so the kata-cc RuntimeClass schedules its pods
+	// to them
+	KataCCRuntimeLabel = "kubernetes.azure.com/flex-runtime-kata-cc"
+
+	// ConfidentialTechnologyLabel records the technology confidential pods on the node are protected with
+	ConfidentialTechnologyLabel = "kubernetes.azure.com/flex-confidential-technology"
+
+	// ConfidentialVMLabel records the technology protecting the node itself when it is a confidential VM
+	ConfidentialVMLabel = "kubernetes.azure.com/flex-confidential-vm"
+)
+
+// gvisorReleasePattern matches gVisor releases, which are named after their date
+var gvisorReleasePattern = regexp.MustCompile(`^[0-9]{8}(\.[0-9]+)?$`)
+
+// plainReleasePattern matches releases tagged without a v prefix, such as those of crun and Kata Containers
+var plainReleasePattern = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+
+func (c *Config) setRuntimeDefaults() {
+	if c.Runtime.ContainerRuntime == "" {
+		c.Runtime.ContainerRuntime = ContainerRuntimeContainerd
+	}
+	if c.UsesCRIO() && c.Runtime.CRIO.Version == "" {
+		// CRI-O releases follow Kubernetes minor versions
+		if parts := strings.SplitN(strings.TrimPrefix(c.Kubernetes.Version, "v"), ".", 3); len(parts) >= 2 {
+			c.Runtime.CRIO.Version = parts[0] + "." + parts[1] + ".0"
+		}
+	}
+	if c.Runtime.OCIRuntime == "" {
+		c.Runtime.OCIRuntime = OCIRuntimeRunc
+	}
+	if c.Runtime.OCIRuntime == OCIRuntimeCrun && c.Runtime.Crun.Version == "" {
+		c.Runtime.Crun.Version = defaultCrunVersion
+	}
+
+	gvisor := &c.Runtime.GVisor
+	if gvisor.Enabled {
+		if gvisor.Version == "" {
+			gvisor.Version = defaultGVisorVersion
+		}
+		if gvisor.Platform == "" {
+			gvisor.Platform = GVisorPlatformSystrap
+		}
+		c.setNodeLabelIfMissing(GVisorRuntimeLabel, "true")
+	}
+
+	kata := &c.Runtime.Kata
+	if kata.Enabled {
+		if kata.Version == "" {
+			kata.Version = defaultKataVersion
+		}
+		if kata.Hypervisor == "" {
+			kata.Hypervisor = KataHypervisorQEMU
+		}
+		c.setNodeLabelIfMissing(KataRuntimeLabel, "true")
+	}
+}
+
+// detectConfidential resolves runtime.confidential.technology from the CPU and labels the node with what it
+// offers, when runtime.confidential is enabled
+func (c *Config) detectConfidential() {
+	if !c.Runtime.Confidential.Enabled {
+		return
+	}
+	flags, err := utilhost.CPUFlags()
+	if err != nil {
+		return
+	}
+	c.applyConfidential(utilhost.ConfidentialHostTechnology(flags), utilhost.ConfidentialGuestTechnology(flags))
+}
+
+// applyConfidential records the detected host and guest technologies without overriding configured values
+func (c *Config) applyConfidential(host, guest string) {
+	confidential := &c.Runtime.Confidential
+	if confidential.Technology == "" {
+		confidential.Technology = host
+	}
+	if confidential.Technology != "" {
+		c.setNodeLabelIfMissing(ConfidentialTechnologyLabel, confidential.Technology)
+		c.setNodeLabelIfMissing(KataCCRuntimeLabel, "true")
+	}
+	if guest != "" {
+		c.setNodeLabelIfMissing(ConfidentialVMLabel, guest)
+	}
+}
+
+// UsesCRIO reports whether CRI-O runs the pods instead of containerd
+func (c *Config) UsesCRIO() bool {
+	return c.Runtime.ContainerRuntime == ContainerRuntimeCRIO
+}
+
+// ContainerRuntimeService returns the systemd service of the configured CRI runtime
+func (c *Config) ContainerRuntimeService() string {
+	if c.UsesCRIO() {
+		return "crio"
+	}
+	return "containerd"
+}
+
+// ContainerRuntimeEndpoint returns the CRI endpoint kubelet connects to
+func (c *Config) ContainerRuntimeEndpoint() string {
+	if c.UsesCRIO() {
+		return "unix://" + CRIOSocketPath
+	}
+	return "unix://" + ContainerdSocketPath
+}
+
+// setNodeLabelIfMissing adds a node label unless the configuration sets it
+func (c *Config) setNodeLabelIfMissing(key, value string) {
+	if c.Node.Labels == nil {
+		c.Node.Labels = make(map[string]string)
+	}
+	setIfMissing(c.Node.Labels, key, value)
+}
+
+// validateContainerRuntime validates runtime.containerRuntime and rejects the settings only containerd supports
+// when CRI-O runs the pods
+func validateContainerRuntime(c *Config) error {
+	switch c.Runtime.ContainerRuntime {
+	case "", ContainerRuntimeContainerd:
+		return nil
+	case ContainerRuntimeCRIO:
+	default:
+		return fmt.Errorf("invalid runtime.containerRuntime: %s. Valid values are: containerd, crio", c.Runtime.ContainerRuntime)
+	}
+	if c.Runtime.CRIO.Version != "" && !cniReleasePattern.MatchString(c.Runtime.CRIO.Version) {
+		return fmt.Errorf("invalid runtime.crio.version: %s. Expected a release such as 1.32.1", c.Runtime.CRIO.Version)
+	}
+
+	// Features built on containerd's plugins, ctr or its image store
+	unsupported := map[string]bool{
+		"containerd.snapshotter":         c.Containerd.Snapshotter != "" && c.Containerd.Snapshotter != SnapshotterOverlayfs,
+		"containerd.nri":                 c.Containerd.NRI.Enabled,
+		"containerd.configPatches":       len(c.Containerd.ConfigPatches) > 0,
+		"runtime.gvisor":                 c.Runtime.GVisor.Enabled,
+		"runtime.kata":                   c.Runtime.Kata.Enabled,
+		"imagePrePull":                   c.ImagePrePull.Enabled,
+		"sriov":                          c.SRIOV.Enabled,
+		"storage.dataDisk":               c.Storage.DataDisk.Enabled,
+		"cni.provider " + c.CNI.Provider: c.CNI.Provider == CNIProviderCilium || c.CNI.Provider == CNIProviderCalico,
+	}
+	fields := make([]string, 0, len(unsupported))
+	for field, set := range unsupported {
+		if set {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) > 0 {
+		sort.Strings(fields)
+		return fmt.Errorf("invalid runtime.containerRuntime: crio does not support %s. Use containerd", strings.Join(fields, ", "))
+	}
+	for _, mirror := range c.Containerd.Mirrors {
+		if mirror.Registry == RegistryMirrorDefault {
+			return fmt.Errorf("invalid containerd.mirrors registry: %s is not supported with crio. List each registry", RegistryMirrorDefault)
+		}
+	}
+	return nil
+}
+
+// validateOCIRuntime validates runtime.ociRuntime and runtime.crun
+func validateOCIRuntime(cfg *RuntimeConfig) error {
+	switch cfg.OCIRuntime {
+	case "", OCIRuntimeRunc, OCIRuntimeCrun:
+	default:
+		return fmt.Errorf("invalid runtime.ociRuntime: %s. Valid values are: runc, crun", cfg.OCIRuntime)
+	}
+	if cfg.Crun.Version != "" && !plainReleasePattern.MatchString(cfg.Crun.Version) {
+		return fmt.Errorf("invalid runtime.crun.version: %s. Expected a release such as 1.19.1", cfg.Crun.Version)
+	}
+	return nil
+}
+
+// validateGVisor validates runtime.gvisor
+func validateGVisor(cfg *GVisorConfig) error {
+	if cfg.Version != "" && !gvisorReleasePattern.MatchString(cfg.Version) {
+		return fmt.Errorf("invalid runtime.gvisor.version: %s. Expected a release such as 20250106.0", cfg.Version)
+	}
+	switch cfg.Platform {
+	case "", GVisorPlatformSystrap, GVisorPlatformKVM, GVisorPlatformPtrace:
+	default:
+		return fmt.Errorf("invalid runtime.gvisor.platform: %s. Valid values are: systrap, kvm, ptrace", cfg.Platform)
+	}
+	return nil
+}
+
+// validateKata validates runtime.kata
+func validateKata(cfg *KataConfig) error {
+	if cfg.Version != "" && !plainReleasePattern.MatchString(cfg.Version) {
+		return fmt.Errorf("invalid runtime.kata.version: %s. Expected a release such as 3.13.0", cfg.Version)
+	}
+	switch cfg.Hypervisor {
+	case "", KataHypervisorQEMU, KataHypervisorCloudHypervisor:
+	default:
+		return fmt.Errorf("invalid runtime.kata.hypervisor: %s. Valid values are: qemu, clh", cfg.Hypervisor)
+	}
+	return nil
+}
+
+// validateConfidential validates runtime.confidential. Confidential pods run in Kata VMs started by QEMU, the
+// only Kata hypervisor supporting both technologies.
+func validateConfidential(cfg *RuntimeConfig) error {
+	switch cfg.Confidential.Technology {
+	case "":
+		return nil
+	case ConfidentialSNP, ConfidentialTDX:
+	default:
+		return fmt.Errorf("invalid runtime.confidential.technology: %s. Valid values are: snp, tdx", cfg.Confidential.Technology)
+	}
+	if !cfg.Kata.Enabled {
+		return fmt.Errorf("invalid runtime.confidential: confidential pods run in Kata VMs. Expected runtime.kata.enabled")
+	}
+	if cfg.Kata.Hypervisor != "" && cfg.Kata.Hypervisor != KataHypervisorQEMU {
+		return fmt.Errorf("invalid runtime.confidential: confidential pods require runtime.kata.hypervisor qemu")
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package config
+
+import "testing"
+
+func TestValidateContainerRuntime(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "unset", cfg: Config{}},
+		{name: "containerd with gVisor", cfg: Config{Runtime: RuntimeConfig{ContainerRuntime: "containerd", GVisor: GVisorConfig{Enabled: true}}}},
+		{name: "crio", cfg: Config{Runtime: RuntimeConfig{ContainerRuntime: "crio", CRIO: CRIOConfig{Version: "1.32.1"}}}},
+		{name: "crio with mirrors", cfg: Config{
+			Runtime:    RuntimeConfig{ContainerRuntime: "crio"},
+			Containerd: ContainerdConfig{Snapshotter: "overlayfs", Mirrors: []RegistryMirrorConfig{{Registry: "docker.io", Endpoints: []string{"https://mirror.contoso.local"}}}},
+		}},
+		{name: "unknown runtime", cfg: Config{Runtime: RuntimeConfig{ContainerRuntime: "docker"}}, wantErr: true},
+		{name: "invalid version", cfg: Config{Runtime: RuntimeConfig{ContainerRuntime: "crio", CRIO: CRIOConfig{Version: "1.32"}}}, wantErr: true},
+		{name: "crio with gVisor", cfg: Config{Runtime: RuntimeConfig{ContainerRuntime: "crio", GVisor: GVisorConfig{Enabled: true}}}, wantErr: true},
+		{name: "crio with stargz", cfg: Config{Runtime: RuntimeConfig{ContainerRuntime: "crio"}, Containerd: ContainerdConfig{Snapshotter: "stargz"}}, wantErr: true},
+		{name: "crio with cilium", cfg: Config{Runtime: RuntimeConfig{ContainerRuntime: "crio"}, CNI: CNIConfig{Provider: "cilium"}}, wantErr: true},
+		{name: "crio with default mirror", cfg: Config{
+			Runtime:    RuntimeConfig{ContainerRuntime: "crio"},
+			Containerd: ContainerdConfig{Mirrors: []RegistryMirrorConfig{{Registry: "_default", Endpoints: []string{"https://mirror.contoso.local"}}}},
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateContainerRuntime(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateContainerRuntime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCRIOVersionDefault(t *testing.T) {
+	cfg := &Config{Kubernetes: KubernetesConfig{Version: "1.33.2"}, Runtime: RuntimeConfig{ContainerRuntime: "crio"}}
+	cfg.setRuntimeDefaults()
+	if cfg.Runtime.CRIO.Version != "1.33.0" {
+		t.Errorf("CRI-O version = %q, want 1.33.0", cfg.Runtime.CRIO.Version)
+	}
+	if cfg.ContainerRuntimeService() != "crio" || cfg.ContainerRuntimeEndpoint() != "unix:///var/run/crio/crio.sock" {
+		t.Errorf("CRI-O service = %q, endpoint %q", cfg.ContainerRuntimeService(), cfg.ContainerRuntimeEndpoint())
+	}
+}
+
+func TestValidateOCIRuntime(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RuntimeConfig
+		wantErr bool
+	}{
+		{name: "unset", cfg: RuntimeConfig{}},
+		{name: "runc", cfg: RuntimeConfig{OCIRuntime: "runc"}},
+		{name: "crun", cfg: RuntimeConfig{OCIRuntime: "crun", Crun: CrunConfig{Version: "1.19.1"}}},
+		{name: "unknown runtime", cfg: RuntimeConfig{OCIRuntime: "youki"}, wantErr: true},
+		{name: "v prefix", cfg: RuntimeConfig{OCIRuntime: "crun", Crun: CrunConfig{Version: "v1.19.1"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOCIRuntime(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOCIRuntime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGVisor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     GVisorConfig
+		wantErr bool
+	}{
+		{name: "defaults", cfg: GVisorConfig{Enabled: true}},
+		{name: "kvm platform", cfg: GVisorConfig{Enabled: true, Version: "20250106", Platform: "kvm"}},
+		{name: "point release", cfg: GVisorConfig{Enabled: true, Version: "20250106.1"}},
+		{name: "semantic version", cfg: GVisorConfig{Enabled: true, Version: "v1.0.0"}, wantErr: true},
+		{name: "unknown platform", cfg: GVisorConfig{Enabled: true, Platform: "gvisor"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGVisor(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGVisor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuntimeDefaultsLabelNode(t *testing.T) {
+	cfg := &Config{Runtime: RuntimeConfig{GVisor: GVisorConfig{Enabled: true}, Kata: KataConfig{Enabled: true}}}
+	cfg.setRuntimeDefaults()
+	for _, label := range []string{GVisorRuntimeLabel, KataRuntimeLabel} {
+		if cfg.Node.Labels[label] != "true" {
+			t.Errorf("node labels = %v, want %s=true", cfg.Node.Labels, label)
+		}
+	}
+	if cfg.Runtime.GVisor.Version != defaultGVisorVersion || cfg.Runtime.GVisor.Platform != GVisorPlatformSystrap {
+		t.Errorf("gVisor defaults = %+v", cfg.Runtime.GVisor)
+	}
+	if cfg.Runtime.ContainerRuntime != ContainerRuntimeContainerd || cfg.Runtime.CRIO.Version != "" {
+		t.Errorf("container runtime defaults = %q, CRI-O %q", cfg.Runtime.ContainerRuntime, cfg.Runtime.CRIO.Version)
+	}
+	if cfg.Runtime.OCIRuntime != OCIRuntimeRunc || cfg.Runtime.Crun.Version != "" {
+		t.Errorf("OCI runtime defaults = %q, crun %q", cfg.Runtime.OCIRuntime, cfg.Runtime.Crun.Version)
+	}
+	if cfg.Runtime.Kata.Version != defaultKataVersion || cfg.Runtime.Kata.Hypervisor != KataHypervisorQEMU {
+		t.Errorf("Kata defaults = %+v", cfg.Runtime.Kata)
+	}
+}
+
+func TestValidateKata(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     KataConfig
+		wantErr bool
+	}{
+		{name: "defaults", cfg: KataConfig{Enabled: true}},
+		{name: "cloud hypervisor", cfg: KataConfig{Enabled: true, Version: "3.13.0", Hypervisor: "clh"}},
+		{name: "v prefix", cfg: KataConfig{Enabled: true, Version: "v3.13.0"}, wantErr: true},
+		{name: "unknown hypervisor", cfg: KataConfig{Enabled: true, Hypervisor: "firecracker"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateKata(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateKata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfidential(t *testing.T) {
+	kata := KataConfig{Enabled: true, Hypervisor: "qemu"}
+	tests := []struct {
+		name    string
+		cfg     RuntimeConfig
+		wantErr bool
+	}{
+		{name: "not detected", cfg: RuntimeConfig{Confidential: ConfidentialConfig{Enabled: true}}},
+		{name: "snp", cfg: RuntimeConfig{Kata: kata, Confidential: ConfidentialConfig{Enabled: true, Technology: "snp"}}},
+		{name: "tdx", cfg: RuntimeConfig{Kata: kata, Confidential: ConfidentialConfig{Enabled: true, Technology: "tdx"}}},
+		{name: "unknown technology", cfg: RuntimeConfig{Kata: kata, Confidential: ConfidentialConfig{Enabled: true, Technology: "sgx"}}, wantErr: true},
+		{name: "without kata", cfg: RuntimeConfig{Confidential: ConfidentialConfig{Enabled: true, Technology: "snp"}}, wantErr: true},
+		{name: "cloud hypervisor", cfg: RuntimeConfig{Kata: KataConfig{Enabled: true, Hypervisor: "clh"}, Confidential: ConfidentialConfig{Enabled: true, Technology: "snp"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfidential(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfidential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyConfidential(t *testing.T) {
+	cfg := &Config{Runtime: RuntimeConfig{Confidential: ConfidentialConfig{Enabled: true}}}
+	cfg.applyConfidential(ConfidentialSNP, "")
+	if cfg.Runtime.Confidential.Technology != ConfidentialSNP || cfg.Node.Labels[ConfidentialTechnologyLabel] != "snp" || cfg.Node.Labels[KataCCRuntimeLabel] != "true" {
+		t.Errorf("SEV-SNP host: technology %q, labels %v", cfg.Runtime.Confidential.Technology, cfg.Node.Labels)
+	}
+
+	cfg = &Config{Runtime: RuntimeConfig{Confidential: ConfidentialConfig{Enabled: true}}}
+	cfg.applyConfidential("", ConfidentialTDX)
+	if cfg.Runtime.Confidential.Technology != "" || cfg.Node.Labels[ConfidentialVMLabel] != "tdx" {
+		t.Errorf("TDX guest: technology %q, labels %v", cfg.Runtime.Confidential.Technology, cfg.Node.Labels)
+	}
+	if _, ok := cfg.Node.Labels[KataCCRuntimeLabel]; ok {
+		t.Errorf("TDX guest labeled for kata-cc: %v", cfg.Node.Labels)
+	}
+
+	cfg = &Config{Runtime: RuntimeConfig{Confidential: ConfidentialConfig{Enabled: true, Technology: ConfidentialTDX}}}
+	cfg.applyConfidential(ConfidentialSNP, "")
+	if cfg.Runtime.Confidential.Technology != ConfidentialTDX {
+		t.Errorf("configured technology overridden with %q", cfg.Runtime.Confidential.Technology)
+	}
+}
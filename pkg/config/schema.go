@@ -0,0 +1,439 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schemaURI is the JSON Schema dialect of the generated schema
+const schemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// Schema returns the JSON Schema of the config file, generated from the Config struct. Durations are strings
+// such as "30s". Keys are listed as documented, although config loading matches them case-insensitively.
+func Schema() map[string]any {
+	schema := typeSchema(reflect.TypeFor[Config]())
+	schema["$schema"] = schemaURI
+	schema["title"] = "AKS Flex Node configuration"
+	return schema
+}
+
+// typeSchema returns the schema of values of type t
+func typeSchema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return map[string]any{"type": "string", "description": "Duration such as 300ms, 30s or 1h30m"}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]any{}
+		for _, field := range schemaFields(t) {
+			properties[field.key] = typeSchema(field.typ)
+		}
+		return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+// schemaField is a config file key and the type of its value
+type schemaField struct {
+	key string
+	typ reflect.Type
+}
+
+// schemaFields returns the keys of a config struct. Fields without a json tag are derived from other settings
+// and cannot be set in the file.
+func schemaFields(t reflect.Type) []schemaField {
+	var fields []schemaField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("json")
+		if !field.IsExported() || !ok {
+			continue
+		}
+		key, _, _ := strings.Cut(tag, ",")
+		if key == "-" {
+			continue
+		}
+		fields = append(fields, schemaField{key: key, typ: field.Type})
+	}
+	return fields
+}
+
+// SchemaError is a violation of the config schema at a position in a config file
+type SchemaError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Field   string `json:"field,omitempty"` // Dotted path of the field, e.g. azure.targetCluster.resourceId
+	Message string `json:"message"`
+}
+
+func (e SchemaError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, e.Field, e.Message)
+}
+
+// CheckSchema checks the config file, each of its drop-in and host layers and the overlays against the
+// schema. It only reads the files, and fetches the remote ones, so it runs before anything else looks at
+// the configuration.
+func CheckSchema(configPath string, overlays ...string) ([]SchemaError, error) {
+	layers, err := configLayers(configPath, overlays...)
+	if err != nil {
+		return nil, err
+	}
+	fetchCtx, cancel := context.WithTimeout(context.Background(), remoteConfigTimeout)
+	defer cancel()
+	remote, _, err := fetchRemoteLayers(fetchCtx, layers)
+	if err != nil {
+		return nil, err
+	}
+	var schemaErrors []SchemaError
+	for _, path := range layers {
+		data, err := readLayer(path, remote)
+		path = LayerName(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file at %s: %w", path, err)
+		}
+		schemaErrors = append(schemaErrors, checkDocument(path, data)...)
+	}
+	return schemaErrors, nil
+}
+
+// jsonNode is a parsed JSON value with the offset it starts at
+type jsonNode struct {
+	offset int
+	value  any // string, json.Number, bool or nil for scalars
+	fields []jsonField
+	items  []*jsonNode
+	object bool
+	array  bool
+}
+
+// jsonField is an object member with the offset of its key
+type jsonField struct {
+	key    string
+	offset int
+	value  *jsonNode
+}
+
+// schemaChecker collects the schema errors of one file
+type schemaChecker struct {
+	file   string
+	data   []byte
+	errors []SchemaError
+}
+
+// checkDocument parses a JSON, YAML or TOML config file and checks it against the Config struct
+func checkDocument(file string, data []byte) []SchemaError {
+	c := &schemaChecker{file: file, data: data}
+	var root *jsonNode
+	switch format := configFormat(file); format {
+	case formatYAML, formatTOML:
+		parse := parseYAML
+		if format == formatTOML {
+			parse = parseTOML
+		}
+		var err error
+		if root, err = parse(data); err != nil {
+			c.report(syntaxErrorOffset(data, err), "", "invalid %s: %s", strings.ToUpper(format),
+				yamlLinePattern.ReplaceAllString(err.Error(), "yaml:"))
+			return c.errors
+		}
+		if !root.object {
+			c.report(root.offset, "", "the config must be a %s", map[string]string{formatYAML: "YAML mapping", formatTOML: "TOML table"}[format])
+			return c.errors
+		}
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var err error
+		root, err = c.parse(decoder)
+		if err == nil {
+			if _, extra := decoder.Token(); !errors.Is(extra, io.EOF) {
+				err = fmt.Errorf("unexpected data after the top-level object")
+			}
+		}
+		if err != nil {
+			offset := int(decoder.InputOffset())
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				offset = int(syntaxErr.Offset)
+			}
+			c.report(offset, "", "invalid JSON: %v", err)
+			return c.errors
+		}
+		if !root.object {
+			c.report(root.offset, "", "the config must be a JSON object")
+			return c.errors
+		}
+	}
+	c.check(root, reflect.TypeFor[Config](), "")
+	return c.errors
+}
+
+// parse reads the next JSON value
+func (c *schemaChecker) parse(decoder *json.Decoder) (*jsonNode, error) {
+	node := &jsonNode{offset: c.skipSpace(int(decoder.InputOffset()))}
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		node.object = true
+		for decoder.More() {
+			offset := c.skipSpace(int(decoder.InputOffset()))
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("object key %v is not a string", keyToken)
+			}
+			value, err := c.parse(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.fields = append(node.fields, jsonField{key: key, offset: offset, value: value})
+		}
+	case json.Delim('['):
+		node.array = true
+		for decoder.More() {
+			item, err := c.parse(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, item)
+		}
+	default:
+		node.value = token
+		return node, nil
+	}
+	// Consume the closing delimiter
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// skipSpace moves an offset past whitespace and the separators between tokens
+func (c *schemaChecker) skipSpace(offset int) int {
+	for offset < len(c.data) && strings.IndexByte(" \t\r\n,:", c.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// check checks a value against the type of the field at path. Null is accepted everywhere, since it removes
+// a value set by an earlier layer.
+func (c *schemaChecker) check(node *jsonNode, t reflect.Type, path string) {
+	if !node.object && !node.array && node.value == nil {
+		return
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if s, ok := node.value.(string); ok && strings.Contains(s, "${") {
+		expanded, err := expandEnv(s)
+		if err != nil {
+			c.report(node.offset, path, "%v", err)
+			return
+		}
+		node = substitutedNode(node.offset, expanded, t)
+	}
+	if t == durationType {
+		s, ok := node.value.(string)
+		if !ok {
+			c.report(node.offset, path, "expected a duration string such as \"30s\", got %s", describe(node))
+		} else if _, err := time.ParseDuration(s); err != nil {
+			c.report(node.offset, path, "invalid duration %q, use a number with a unit such as 300ms, 30s or 1h30m", s)
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if !node.object {
+			c.report(node.offset, path, "expected an object, got %s", describe(node))
+			return
+		}
+		fields := schemaFields(t)
+		seen := map[string]string{}
+		for _, member := range node.fields {
+			memberPath := joinPath(path, member.key)
+			if previous, ok := seen[strings.ToLower(member.key)]; ok {
+				c.report(member.offset, memberPath, "duplicate key, %q is already set in this object", previous)
+				continue
+			}
+			seen[strings.ToLower(member.key)] = member.key
+			field, ok := lookupField(fields, member.key)
+			if !ok {
+				c.report(member.offset, memberPath, "unknown field%s", suggestField(fields, member.key))
+				continue
+			}
+			c.check(member.value, field.typ, joinPath(path, field.key))
+		}
+	case reflect.Map:
+		if !node.object {
+			c.report(node.offset, path, "expected an object, got %s", describe(node))
+			return
+		}
+		for _, member := range node.fields {
+			c.check(member.value, t.Elem(), joinPath(path, member.key))
+		}
+	case reflect.Slice, reflect.Array:
+		if !node.array {
+			c.report(node.offset, path, "expected an array, got %s", describe(node))
+			return
+		}
+		for i, item := range node.items {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.String:
+		if _, ok := node.value.(string); !ok {
+			c.report(node.offset, path, "expected a string, got %s", describe(node))
+		}
+	case reflect.Bool:
+		if _, ok := node.value.(bool); !ok {
+			c.report(node.offset, path, "expected true or false, got %s", describe(node))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, ok := node.value.(json.Number)
+		if _, err := strconv.ParseInt(number.String(), 10, t.Bits()); !ok || err != nil {
+			c.report(node.offset, path, "expected an integer, got %s", describe(node))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, ok := node.value.(json.Number)
+		if _, err := strconv.ParseUint(number.String(), 10, t.Bits()); !ok || err != nil {
+			c.report(node.offset, path, "expected a non-negative integer, got %s", describe(node))
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := node.value.(json.Number); !ok {
+			c.report(node.offset, path, "expected a number, got %s", describe(node))
+		}
+	}
+}
+
+// substitutedNode returns the value of an expanded environment variable reference. Config loading converts
+// strings to the type of the field, so for fields that are not strings the text is read as a JSON scalar.
+func substitutedNode(offset int, expanded string, t reflect.Type) *jsonNode {
+	node := &jsonNode{offset: offset, value: expanded}
+	if t.Kind() == reflect.String || t == durationType {
+		return node
+	}
+	decoder := json.NewDecoder(strings.NewReader(expanded))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err == nil && !decoder.More() {
+		switch value.(type) {
+		case bool, json.Number:
+			node.value = value
+		}
+	}
+	return node
+}
+
+// report records a schema error at a byte offset of the file
+func (c *schemaChecker) report(offset int, path, format string, args ...any) {
+	offset = min(offset, len(c.data))
+	line := bytes.Count(c.data[:offset], []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(c.data[:offset], '\n')
+	c.errors = append(c.errors, SchemaError{
+		File: c.file, Line: line, Column: column, Field: path, Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// lookupField finds the field of a key, ignoring case like config loading does
+func lookupField(fields []schemaField, key string) (schemaField, bool) {
+	for _, field := range fields {
+		if strings.EqualFold(field.key, key) {
+			return field, true
+		}
+	}
+	return schemaField{}, false
+}
+
+// suggestField names the closest known key of a misspelled one, if any is close enough
+func suggestField(fields []schemaField, key string) string {
+	best, bestDistance := "", 3
+	for _, field := range fields {
+		if distance := editDistance(strings.ToLower(field.key), strings.ToLower(key)); distance < bestDistance {
+			best, bestDistance = field.key, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// editDistance returns the Levenshtein distance of two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// describe names the JSON type of a value for error messages
+func describe(node *jsonNode) string {
+	switch value := node.value.(type) {
+	case string:
+		return fmt.Sprintf("string %q", value)
+	case json.Number:
+		return "number " + value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	switch {
+	case node.object:
+		return "an object"
+	case node.array:
+		return "an array"
+	default:
+		return "null"
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	if schema["$schema"] != schemaURI || schema["additionalProperties"] != false {
+		t.Fatalf("Schema() root = %v", schema)
+	}
+
+	// property follows a dotted path of properties through the schema
+	property := func(path string) map[string]any {
+		node := schema
+		for _, key := range strings.Split(path, ".") {
+			properties, ok := node["properties"].(map[string]any)
+			if !ok {
+				t.Fatalf("%s: no properties above %s", path, key)
+			}
+			if node, ok = properties[key].(map[string]any); !ok {
+				t.Fatalf("%s: no property %s", path, key)
+			}
+		}
+		return node
+	}
+
+	tests := map[string]string{
+		"azure.subscriptionId":         "string",
+		"azure.arc.enabled":            "boolean",
+		"azure.targetCluster":          "object",
+		"azure.armRetry.maxRetries":    "integer",
+		"azure.armRetry.retryDelay":    "string",
+		"node.labels":                  "object",
+		"hooks":                        "array",
+		"node.kubelet.configOverrides": "object",
+	}
+	for path, wantType := range tests {
+		if got := property(path)["type"]; got != wantType {
+			t.Errorf("%s type = %v, want %s", path, got, wantType)
+		}
+	}
+
+	// Fields derived from the resource ID cannot be set in the file
+	if _, ok := property("azure.targetCluster")["properties"].(map[string]any)["Name"]; ok {
+		t.Error("derived azure.targetCluster.Name is part of the schema")
+	}
+	if items := property("hooks")["items"].(map[string]any); items["type"] != "object" {
+		t.Errorf("hooks items = %v, want objects", items)
+	}
+}
+
+func TestCheckDocument(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "valid, with keys in another case and nulls",
+			data: `{
+  "Azure": {"SubscriptionID": "sub", "managedIdentity": null, "armRetry": {"retryDelay": "4s"}},
+  "node": {"labels": {"zone": "a"}, "kubelet": {"configOverrides": {"serializeImagePulls": false}}}
+}`,
+		},
+		{
+			name: "unknown field with a suggestion",
+			data: `{
+  "azure": {
+    "subscriptonId": "sub"
+  }
+}`,
+			want: []string{`f.json:3:5: azure.subscriptonId: unknown field, did you mean "subscriptionId"?`},
+		},
+		{
+			name: "wrong types",
+			data: `{
+  "azure": {"arc": {"enabled": "yes"}, "armRetry": {"maxRetries": 1.5, "retryDelay": 4}},
+  "node": {"labels": ["zone"]}
+}`,
+			want: []string{
+				`f.json:2:32: azure.arc.enabled: expected true or false, got string "yes"`,
+				`f.json:2:67: azure.armRetry.maxRetries: expected an integer, got number 1.5`,
+				`f.json:2:86: azure.armRetry.retryDelay: expected a duration string such as "30s", got number 4`,
+				`f.json:3:22: node.labels: expected an object, got an array`,
+			},
+		},
+		{
+			name: "invalid duration and array item",
+			data: `{"agent": {"timeouts": {"download": "5 minutes"}}, "hooks": [{"step": "KubeletInstaller"}, 3]}`,
+			want: []string{
+				`f.json:1:37: agent.timeouts.download: invalid duration "5 minutes", use a number with a unit such as 300ms, 30s or 1h30m`,
+				`f.json:1:92: hooks[1]: expected an object, got number 3`,
+			},
+		},
+		{
+			name: "environment variable references",
+			data: `{
+  "node": {"maxPods": "${FLEX_TEST_MAX_PODS:-110}", "labels": {"zone": "${FLEX_TEST_UNSET}"}},
+  "azure": {"arc": {"enabled": "${FLEX_TEST_MAX_PODS:-sometimes}"}}
+}`,
+			want: []string{
+				`f.json:2:72: node.labels.zone: environment variable FLEX_TEST_UNSET is not set; set it or give a default with ${FLEX_TEST_UNSET:-default}`,
+				`f.json:3:32: azure.arc.enabled: expected true or false, got string "sometimes"`,
+			},
+		},
+		{
+			name: "duplicate key",
+			data: "{\n  \"azure\": {},\n  \"Azure\": {}\n}",
+			want: []string{`f.json:3:3: Azure: duplicate key, "azure" is already set in this object`},
+		},
+		{
+			name: "syntax error",
+			data: "{\n  \"azure\": {\n    \"tenantId\": \"t\",\n  }\n}",
+			want: []string{"f.json:3:21: invalid JSON: invalid character ',' looking for beginning of value"},
+		},
+		{
+			name: "not an object",
+			data: `["azure"]`,
+			want: []string{"f.json:1:1: the config must be a JSON object"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range checkDocument("f.json", []byte(tt.data)) {
+				got = append(got, err.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("checkDocument() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckSchemaLayers(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.json")
+	dropIn := filepath.Join(dir, "config.d", "10-site.json")
+	if err := os.MkdirAll(filepath.Dir(dropIn), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base, []byte(`{"azure": {"tenantId": "t"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dropIn, []byte(`{"node": {"maxPods": "110"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	schemaErrors, err := CheckSchema(base)
+	if err != nil {
+		t.Fatalf("CheckSchema() error = %v", err)
+	}
+	if len(schemaErrors) != 1 || schemaErrors[0].File != dropIn || schemaErrors[0].Field != "node.maxPods" {
+		t.Errorf("CheckSchema() = %v, want the maxPods error of the drop-in", schemaErrors)
+	}
+}
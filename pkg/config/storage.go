@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Data disk settings
+const (
+	// DataDiskResource selects the Azure ephemeral resource disk as data disk
+	DataDiskResource = "resource"
+
+	DataDiskFilesystemExt4 = "ext4"
+	DataDiskFilesystemXFS  = "xfs"
+
+	defaultDataDiskMountPoint = "/mnt/aks-data"
+)
+
+func (c *Config) setStorageDefaults() {
+	// The mount point is also needed to unmount the disk after it was turned off
+	if c.Storage.DataDisk.MountPoint == "" {
+		c.Storage.DataDisk.MountPoint = defaultDataDiskMountPoint
+	}
+	if c.Storage.DataDisk.Enabled && c.Storage.DataDisk.Filesystem == "" {
+		c.Storage.DataDisk.Filesystem = DataDiskFilesystemExt4
+	}
+}
+
+// validateDataDisk validates storage.dataDisk. The mount point must not hold the directories the disk is
+// bind-mounted on, nor lie within them.
+func validateDataDisk(cfg *DataDiskConfig, kubeletDir string) error {
+	if cfg.Device != DataDiskResource && (!filepath.IsAbs(cfg.Device) || !strings.HasPrefix(filepath.Clean(cfg.Device), "/dev/")) {
+		return fmt.Errorf("invalid storage.dataDisk.device: %q. Expected a device path under /dev, e.g. /dev/sdc, or %q", cfg.Device, DataDiskResource)
+	}
+	switch cfg.Filesystem {
+	case "", DataDiskFilesystemExt4, DataDiskFilesystemXFS:
+	default:
+		return fmt.Errorf("invalid storage.dataDisk.filesystem: %s. Valid values are: ext4, xfs", cfg.Filesystem)
+	}
+	if cfg.MountPoint == "" {
+		return nil
+	}
+	mountPoint := filepath.Clean(cfg.MountPoint)
+	if !filepath.IsAbs(mountPoint) || mountPoint == "/" {
+		return fmt.Errorf("invalid storage.dataDisk.mountPoint: %s. Must be an absolute path other than /", cfg.MountPoint)
+	}
+	for _, dir := range []string{"/var/lib/containerd", kubeletDir} {
+		if dir == "" {
+			continue
+		}
+		if within(mountPoint, dir) || within(dir, mountPoint) {
+			return fmt.Errorf("invalid storage.dataDisk.mountPoint: %s. Must not overlap %s, which is mounted from the disk", cfg.MountPoint, dir)
+		}
+	}
+	return nil
+}
+
+// within reports whether path is dir or below it
+func within(path, dir string) bool {
+	dir = filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
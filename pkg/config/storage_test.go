@@ -0,0 +1,29 @@
+package config
+
+import "testing"
+
+func TestValidateDataDisk(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     DataDiskConfig
+		wantErr bool
+	}{
+		{name: "data disk", cfg: DataDiskConfig{Device: "/dev/disk/azure/scsi1/lun0", Filesystem: "xfs", MountPoint: "/mnt/aks-data"}},
+		{name: "resource disk", cfg: DataDiskConfig{Device: "resource"}},
+		{name: "missing device", cfg: DataDiskConfig{}, wantErr: true},
+		{name: "device outside /dev", cfg: DataDiskConfig{Device: "/tmp/disk.img"}, wantErr: true},
+		{name: "unsupported filesystem", cfg: DataDiskConfig{Device: "/dev/sdc", Filesystem: "btrfs"}, wantErr: true},
+		{name: "relative mount point", cfg: DataDiskConfig{Device: "/dev/sdc", MountPoint: "mnt/data"}, wantErr: true},
+		{name: "mount point below kubelet directory", cfg: DataDiskConfig{Device: "/dev/sdc", MountPoint: "/var/lib/kubelet/data"}, wantErr: true},
+		{name: "mount point above containerd directory", cfg: DataDiskConfig{Device: "/dev/sdc", MountPoint: "/var/lib"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDataDisk(&tt.cfg, "/var/lib/kubelet")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDataDisk() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,36 +1,98 @@
 package config
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
 
 // Config represents the complete agent configuration structure.
 // It contains Azure-specific settings and agent operational settings.
 type Config struct {
-	Azure      AzureConfig      `json:"azure"`
-	Agent      AgentConfig      `json:"agent"`
-	Containerd ContainerdConfig `json:"containerd"`
-	Kubernetes KubernetesConfig `json:"kubernetes"`
-	CNI        CNIConfig        `json:"cni"`
-	Runc       RuncConfig       `json:"runc"`
-	Node       NodeConfig       `json:"node"`
-	Paths      PathsConfig      `json:"paths"`
-	Npd        NPDConfig        `json:"npd"`
+	Azure        AzureConfig        `json:"azure"`
+	Agent        AgentConfig        `json:"agent"`
+	Containerd   ContainerdConfig   `json:"containerd"`
+	Kubernetes   KubernetesConfig   `json:"kubernetes"`
+	CNI          CNIConfig          `json:"cni"`
+	Runc         RuncConfig         `json:"runc"`
+	Node         NodeConfig         `json:"node"`
+	Paths        PathsConfig        `json:"paths"`
+	Npd          NPDConfig          `json:"npd"`
+	KubeProxy    KubeProxyConfig    `json:"kubeProxy"`
+	NodeLocalDNS NodeLocalDNSConfig `json:"nodeLocalDNS"`
+	System       SystemConfig       `json:"system"`
+	Preflight    PreflightConfig    `json:"preflight"`
+	Images       ImagesConfig       `json:"images"`
+	Network      NetworkConfig      `json:"network"`
+	SRIOV        SRIOVConfig        `json:"sriov"`
+	ImagePrePull ImagePrePullConfig `json:"imagePrePull"`
+	Storage      StorageConfig      `json:"storage"`
+	Runtime      RuntimeConfig      `json:"runtime"`
+	GPU          GPUConfig          `json:"gpu"`
+	RDMA         RDMAConfig         `json:"rdma"`
+
+	Hooks []HookConfig `json:"hooks"`
+
+	Plugins []PluginConfig `json:"plugins"`
+
+	CustomSteps []CustomStepConfig `json:"customSteps"`
 
 	// Internal field to track if ManagedIdentity was explicitly set in config
 	// This is necessary because viper unmarshals empty JSON objects {} as nil
 	isMIExplicitlySet bool `json:"-"`
+
+	// Whether system.firewall.manage was set, since its default depends on the distribution
+	isFirewallManageSet bool `json:"-"`
+
+	// Config files the configuration was merged from and the layer that set each value
+	layers     []string          `json:"-"`
+	provenance map[string]string `json:"-"`
+
+	// Layers fetched from URLs
+	remoteSources []RemoteSource `json:"-"`
 }
 
 // AzureConfig holds Azure-specific configuration required for connecting to Azure services.
 // All fields except Cloud are required for proper operation.
 type AzureConfig struct {
-	SubscriptionID   string                  `json:"subscriptionId"`             // Azure subscription ID
-	TenantID         string                  `json:"tenantId"`                   // Azure tenant ID
-	Cloud            string                  `json:"cloud"`                      // Azure cloud environment (defaults to AzurePublicCloud)
-	ServicePrincipal *ServicePrincipalConfig `json:"servicePrincipal,omitempty"` // Optional service principal authentication
-	ManagedIdentity  *ManagedIdentityConfig  `json:"managedIdentity,omitempty"`  // Optional managed identity authentication
-	BootstrapToken   *BootstrapTokenConfig   `json:"bootstrapToken,omitempty"`   // Optional bootstrap token authentication
-	Arc              *ArcConfig              `json:"arc"`                        // Azure Arc machine configuration
-	TargetCluster    *TargetClusterConfig    `json:"targetCluster"`              // Target AKS cluster configuration
+	SubscriptionID    string                   `json:"subscriptionId"`              // Azure subscription ID
+	TenantID          string                   `json:"tenantId"`                    // Azure tenant ID
+	Cloud             string                   `json:"cloud"`                       // Azure cloud environment (defaults to AzurePublicCloud)
+	ServicePrincipal  *ServicePrincipalConfig  `json:"servicePrincipal,omitempty"`  // Optional service principal authentication
+	ManagedIdentity   *ManagedIdentityConfig   `json:"managedIdentity,omitempty"`   // Optional managed identity authentication
+	BootstrapToken    *BootstrapTokenConfig    `json:"bootstrapToken,omitempty"`    // Optional bootstrap token authentication
+	FederatedIdentity *FederatedIdentityConfig `json:"federatedIdentity,omitempty"` // Optional OIDC-federated (client assertion) authentication
+	Arc               *ArcConfig               `json:"arc"`                         // Azure Arc machine configuration
+	TargetCluster     *TargetClusterConfig     `json:"targetCluster"`               // Target AKS cluster configuration
+	NoWrite           bool                     `json:"noWrite"`                     // Never create or delete role assignments or resources via ARM; an operator pre-creates them (see "aks-flex-node plan")
+	ARMRetry          ARMRetryConfig           `json:"armRetry"`                    // Retry and throttling policy of Azure Resource Manager clients
+
+	CloudProvider CloudProviderConfig `json:"cloudProvider"` // Network settings written to /etc/kubernetes/azure.json on Azure VMs
+}
+
+// CloudProviderConfig holds the network resources of an Azure VM that /etc/kubernetes/azure.json names for the
+// Azure cloud provider and CSI drivers. Leave them empty when the node's VM is not in the cluster's network.
+type CloudProviderConfig struct {
+	VnetName          string `json:"vnetName"`
+	VnetResourceGroup string `json:"vnetResourceGroup"` // Resource group of the virtual network (default: the VM's)
+	SubnetName        string `json:"subnetName"`
+	SecurityGroupName string `json:"securityGroupName"`
+	RouteTableName    string `json:"routeTableName"`
+}
+
+// ARMRetryConfig holds the retry policy of the Azure Resource Manager clients.
+// Throttled (429) and transient (408, 5xx) responses are retried, waiting for the Retry-After
+// interval returned by ARM when present and backing off exponentially otherwise.
+type ARMRetryConfig struct {
+	MaxRetries    int32         `json:"maxRetries"`    // Retries per request after the first attempt (default: 6)
+	RetryDelay    time.Duration `json:"retryDelay"`    // Initial backoff between retries, doubled on each retry (default: 4s)
+	MaxRetryDelay time.Duration `json:"maxRetryDelay"` // Longest wait between retries; a longer Retry-After fails the request (default: 5m)
+	TryTimeout    time.Duration `json:"tryTimeout"`    // Timeout of a single attempt (default: none)
 }
 
 // ServicePrincipalConfig holds Azure service principal authentication configuration.
@@ -44,19 +106,33 @@ type ServicePrincipalConfig struct {
 // ManagedIdentityConfig holds managed identity authentication configuration.
 // It can only be used when the agent is running on an Azure VM with a managed identity assigned.
 type ManagedIdentityConfig struct {
-	ClientID string `json:"clientId,omitempty"` // Client ID of the managed identity (optional, for VMs with multiple identities)
+	ClientID   string `json:"clientId,omitempty"`   // Client ID of the managed identity (optional, for VMs with multiple identities)
+	ResourceID string `json:"resourceId,omitempty"` // ARM resource ID of the user-assigned identity (alternative to clientId)
+}
+
+// FederatedIdentityConfig holds OIDC-federated identity authentication configuration.
+// An externally issued OIDC token is exchanged for an Azure AD token via a federated
+// credential on the app registration, so no client secret or Azure-assigned identity is needed.
+type FederatedIdentityConfig struct {
+	TenantID  string `json:"tenantId"`  // Azure AD tenant ID of the app registration
+	ClientID  string `json:"clientId"`  // Client ID of the app registration with the federated credential
+	TokenFile string `json:"tokenFile"` // Path to the OIDC token used as client assertion (re-read on every request so it can rotate)
 }
 
 // BootstrapTokenConfig holds Kubernetes bootstrap token authentication configuration.
 // Bootstrap tokens provide a lightweight authentication method for node joining.
 type BootstrapTokenConfig struct {
 	Token string `json:"token"` // Bootstrap token in format: <token-id>.<token-secret>
+
+	Kubeconfig string `json:"kubeconfig"` // Bootstrap kubeconfig holding the token, server and CA, e.g. a Key Vault reference
 }
 
 // TargetClusterConfig holds configuration for the target AKS cluster the ARC machine will connect to.
 type TargetClusterConfig struct {
 	ResourceID        string `json:"resourceId"` // Full resource ID of the target AKS cluster
 	Location          string `json:"location"`   // Azure region of the cluster (e.g., "eastus", "westus2")
+	TenantID          string `json:"tenantId"`   // Azure AD tenant of the cluster when it differs from azure.tenantId (cross-tenant join)
+	Private           bool   `json:"private"`    // Private cluster reached over ExpressRoute or VPN through its private FQDN
 	Name              string // will be populated from ResourceID
 	ResourceGroup     string // will be populated from ResourceID
 	SubscriptionID    string // will be populated from ResourceID
@@ -66,16 +142,172 @@ type TargetClusterConfig struct {
 // ArcConfig holds Azure Arc machine configuration for registering the machine with Azure Arc.
 type ArcConfig struct {
 	Enabled       bool              `json:"enabled"`       // Whether to enable Azure Arc registration
-	MachineName   string            `json:"machineName"`   // Name for the Arc machine resource
+	MachineName   string            `json:"machineName"`   // Name for the Arc machine resource, may contain {hostname} (default: hostname)
 	Tags          map[string]string `json:"tags"`          // Tags to apply to the Arc machine
 	ResourceGroup string            `json:"resourceGroup"` // Azure resource group for Arc machine
 	Location      string            `json:"location"`      // Azure region for Arc machine
+	CustomRole    CustomRoleConfig  `json:"customRole"`    // Least-privilege custom role assigned instead of the built-in roles
+	RoleScope     string            `json:"roleScope"`     // Scope of the role assignments: cluster (default), nodeResourceGroup or a resource ID
+	Proxy         ArcProxyConfig    `json:"proxy"`         // Outbound proxy used by the Arc agent installer, azcmagent connect and the Arc services
+
+	PrivateLinkScope string `json:"privateLinkScope"` // Resource ID of an Azure Arc Private Link Scope to onboard through private endpoints
+	AgentVersion     string `json:"agentVersion"`     // Exact azcmagent version to install, e.g. 1.45.02800.709 (default: latest)
+	InstallerPath    string `json:"installerPath"`    // Local azcmagent .deb package or install script used instead of downloading from Microsoft
+}
+
+// ArcProxyConfig holds the outbound proxy settings of the Azure Arc agent.
+type ArcProxyConfig struct {
+	URL    string   `json:"url"`    // HTTP(S) proxy URL, e.g. http://proxy.corp.example.com:3128
+	Bypass []string `json:"bypass"` // Arc traffic sent directly instead of through the proxy: AAD, ARM and/or Arc
+}
+
+// GetArcRoleScope returns the scope the Arc machine identity's roles are assigned on
+func (cfg *Config) GetArcRoleScope() string {
+	scope := RoleScopeCluster
+	if cfg.Azure.Arc != nil && cfg.Azure.Arc.RoleScope != "" {
+		scope = cfg.Azure.Arc.RoleScope
+	}
+	switch scope {
+	case RoleScopeCluster:
+		return cfg.GetTargetClusterID()
+	case RoleScopeNodeResourceGroup:
+		return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s",
+			cfg.GetTargetClusterSubscriptionID(), cfg.Azure.TargetCluster.NodeResourceGroup)
+	default:
+		return scope
+	}
+}
+
+// CustomRoleConfig holds settings for the least-privilege custom role granted to the Arc machine identity.
+type CustomRoleConfig struct {
+	Enabled bool   `json:"enabled"` // Create (or reuse) a custom role definition and assign it instead of the built-in roles
+	Name    string `json:"name"`    // Role name; the definition is reused by every node joining the same cluster resource group
 }
 
 // AgentConfig holds agent-specific operational configuration.
 type AgentConfig struct {
-	LogLevel string `json:"logLevel"` // Logging level: debug, info, warning, error
-	LogDir   string `json:"logDir"`   // Directory for log files
+	LogLevel    string            `json:"logLevel"`    // Logging level: debug, info, warning, error
+	LogDir      string            `json:"logDir"`      // Directory for log files
+	TokenBroker TokenBrokerConfig `json:"tokenBroker"` // Local token broker serving kubelet exec credentials
+	StateStore  StateStoreConfig  `json:"stateStore"`  // Persistence for checkpoints and audit metadata
+	Faults      []FaultConfig     `json:"faults"`      // Fault injection rules for testing failure handling (never use in production)
+	Webhook     WebhookConfig     `json:"webhook"`     // Callback notified when bootstrap finishes
+	Profile     string            `json:"profile"`     // Timing profile: production (default) or fastFail for CI and lab environments
+	Timeouts    TimeoutsConfig    `json:"timeouts"`    // Retry budgets and waits; unset values come from the profile
+	Retries     RetriesConfig     `json:"retries"`     // Retry policies of steps, role assignments and downloads
+	ClusterLoss ClusterLossConfig `json:"clusterLoss"` // What the agent does when the target cluster is deleted
+	// How often agent mode re-fetches a config given as a URL and applies its changes (default: 5m)
+	ConfigRefresh time.Duration `json:"configRefresh"`
+}
+
+// ClusterLossConfig holds the policy applied in agent mode when the target cluster is deleted.
+// A stopped cluster only pauses kubelet until the cluster runs again.
+type ClusterLossConfig struct {
+	Action      string        `json:"action"`      // none (default) keeps the node configured; unbootstrap removes it from the machine
+	GracePeriod time.Duration `json:"gracePeriod"` // How long the cluster must stay deleted before the action runs (default: 1h)
+}
+
+// TimeoutsConfig holds the retry budgets, propagation waits and download timeouts of bootstrap.
+// Every value defaults to the one of the selected profile.
+type TimeoutsConfig struct {
+	ArcRegistration       time.Duration `json:"arcRegistration"`       // How long to wait for the Arc machine and its identity to appear in Azure
+	IdentityPropagation   time.Duration `json:"identityPropagation"`   // Pause before assigning roles to a newly created Arc identity
+	RoleAssignmentRetries int           `json:"roleAssignmentRetries"` // Attempts to create a role assignment while the identity replicates
+	PermissionPropagation time.Duration `json:"permissionPropagation"` // How long to wait for role assignments to take effect
+	Download              time.Duration `json:"download"`              // Timeout of each binary download
+}
+
+// RetriesConfig holds the retry policies of bootstrap steps and of the operations inside them. Unset values
+// come from the profile.
+type RetriesConfig struct {
+	Steps          []StepRetryConfig `json:"steps"`          // Policies of individual steps; other steps run once
+	RoleAssignment RetryPolicy       `json:"roleAssignment"` // Creating a role assignment while the Arc identity replicates
+	Download       RetryPolicy       `json:"download"`       // Requesting a binary download
+}
+
+// StepRetryConfig retries a failed bootstrap, upgrade or unbootstrap step
+type StepRetryConfig struct {
+	Step   string      `json:"step"`   // Step name as reported in logs, or * for every step without its own policy
+	Policy RetryPolicy `json:"policy"` // Unset values default to 3 attempts, 10s initial delay and 2m maximum delay
+}
+
+// RetryPolicy bounds the attempts of an operation and the exponential, jittered backoff between them
+type RetryPolicy struct {
+	Attempts     int           `json:"attempts"`     // Attempts including the first one
+	InitialDelay time.Duration `json:"initialDelay"` // Delay before the first retry, doubled on each further retry
+	MaxDelay     time.Duration `json:"maxDelay"`     // Upper bound of the delay before jitter
+	Jitter       *float64      `json:"jitter"`       // Fraction of the delay added or removed at random, from 0 to 1 (default: 0.2); 0 turns it off
+}
+
+// FaultConfig describes a fault injected into a bootstrap or unbootstrap step for testing.
+type FaultConfig struct {
+	Step  string        `json:"step"`  // Step name as reported in logs (e.g. "ContainerdInstaller")
+	Mode  string        `json:"mode"`  // Fault mode: fail, timeout or transient
+	Count int           `json:"count"` // For transient faults: number of executions that fail before the step succeeds (default 1)
+	Delay time.Duration `json:"delay"` // For timeout faults: how long the step hangs before failing (default 30s)
+}
+
+// HookConfig runs a command or script before or after a bootstrap, upgrade or unbootstrap step, for site-specific
+// setup the agent does not cover.
+type HookConfig struct {
+	Step      string        `json:"step"`      // Step name as reported in logs (e.g. "ContainerdInstaller")
+	When      string        `json:"when"`      // pre or post
+	Command   []string      `json:"command"`   // Executable and arguments, run without a shell
+	Script    string        `json:"script"`    // Shell script run with /bin/sh, instead of command
+	Timeout   time.Duration `json:"timeout"`   // How long the hook may run (default 5m)
+	OnFailure string        `json:"onFailure"` // fail (default) fails the step, ignore only logs the failure
+}
+
+// PluginConfig adds a third-party component to bootstrap and unbootstrap. The plugin is an executable called with
+// validate, is-completed, execute and uninstall, like the agent's own components.
+type PluginConfig struct {
+	Name    string        `json:"name"`    // Step name in logs and results, unique among the steps
+	Path    string        `json:"path"`    // Absolute path of the plugin executable
+	Args    []string      `json:"args"`    // Arguments passed after the action
+	Env     []string      `json:"env"`     // Extra KEY=value environment variables
+	After   string        `json:"after"`   // Bootstrap step the plugin runs after (default: the last step)
+	Timeout time.Duration `json:"timeout"` // How long each call may run (default 10m)
+}
+
+// CustomStepConfig is a declarative bootstrap step for small site customizations. It installs its packages,
+// then writes its files, then enables and starts its units.
+type CustomStepConfig struct {
+	Name     string             `json:"name"`     // Step name in logs and results, unique among the steps
+	After    string             `json:"after"`    // Bootstrap step the custom step runs after (default: the last step)
+	Packages []string           `json:"packages"` // Packages to install with apt, or dnf on RHEL
+	Files    []CustomFileConfig `json:"files"`    // Files to write
+	Units    []string           `json:"units"`    // systemd units to enable and start, e.g. chrony.service
+}
+
+// CustomFileConfig is a file written by a custom step
+type CustomFileConfig struct {
+	Path    string `json:"path"`    // Absolute path of the file
+	Content string `json:"content"` // Content of the file
+	Mode    string `json:"mode"`    // Octal permissions (default 0644)
+}
+
+// StateStoreConfig selects where the agent persists its state (checkpoints, state bag, audit metadata).
+type StateStoreConfig struct {
+	Type             string `json:"type"`             // Store implementation: file or bolt
+	Path             string `json:"path"`             // Directory holding the state files or database
+	ConfigMapMirror  bool   `json:"configMapMirror"`  // Mirror state into a per-node ConfigMap in kube-system
+	MirrorKubeconfig string `json:"mirrorKubeconfig"` // Kubeconfig allowed to manage the mirror ConfigMaps; node credentials are not
+}
+
+// TokenBrokerConfig holds settings for the local token broker that caches Arc/MSI/SP tokens
+// and serves them to kubelet's exec credential plugin over a unix socket.
+type TokenBrokerConfig struct {
+	Enabled       bool          `json:"enabled"`       // Whether to run the token broker in agent mode
+	SocketPath    string        `json:"socketPath"`    // Unix socket the broker listens on
+	RefreshBefore time.Duration `json:"refreshBefore"` // How long before expiry cached tokens are refreshed
+}
+
+// WebhookConfig holds settings for the callback that reports bootstrap results to external
+// provisioning or inventory systems.
+type WebhookConfig struct {
+	URL     string        `json:"url"`     // HTTPS endpoint receiving the JSON payload; the webhook is disabled when empty
+	Secret  string        `json:"secret"`  // HMAC-SHA256 signing key (supports @keyvault references)
+	Timeout time.Duration `json:"timeout"` // Timeout of each delivery attempt (default: 10s)
 }
 
 // KubernetesConfig holds configuration settings for Kubernetes components.
@@ -90,30 +322,169 @@ type RuncConfig struct {
 	URL     string `json:"url"`
 }
 
+// NetworkConfig holds host network settings shared by all components.
+type NetworkConfig struct {
+	Proxy          ProxyConfig `json:"proxy"`
+	CACertificates []string    `json:"caCertificates"` // Extra trusted CA certificates, as inline PEM or absolute paths to PEM files
+	MTU            int         `json:"mtu"`            // MTU of pod interfaces; 0 detects the path MTU to the API server
+	IPFamilies     []string    `json:"ipFamilies"`     // IP families of the node, primary first: ["IPv4"] (default) or both IPv4 and IPv6 for dual-stack clusters
+	PodIPv6CIDR    string      `json:"podIPv6CIDR"`    // IPv6 pod range of the CNI bridge on dual-stack nodes; defaults to the IPv6 pod CIDR of the target cluster
+}
+
+// ProxyConfig holds the outbound HTTP(S) proxy used by artifact downloads, Azure SDK clients, containerd,
+// kubelet and the Arc agent.
+type ProxyConfig struct {
+	HTTPProxy  string   `json:"httpProxy"`  // Proxy for plain HTTP requests, e.g. http://proxy.corp.example.com:3128
+	HTTPSProxy string   `json:"httpsProxy"` // Proxy for HTTPS requests
+	NoProxy    []string `json:"noProxy"`    // Extra destinations reached directly, added to loopback, IMDS, the node subnets and the API server
+}
+
+// ImagesConfig holds settings shared by the built-in images the agent configures on the node.
+type ImagesConfig struct {
+	Registry string `json:"registry"` // Registry and optional path prefix replacing mcr.microsoft.com in built-in image references (e.g. for sovereign clouds or air-gapped mirrors)
+}
+
 // ContainerdConfig holds configuration settings for the containerd runtime.
 type ContainerdConfig struct {
-	Version        string `json:"version"`
-	PauseImage     string `json:"pauseImage"`
-	MetricsAddress string `json:"metricsAddress"`
+	Version               string                 `json:"version"`
+	PauseImage            string                 `json:"pauseImage"` // Full pause image reference, overrides images.registry for this image
+	MetricsAddress        string                 `json:"metricsAddress"`
+	Mirrors               []RegistryMirrorConfig `json:"mirrors"`       // Mirrors images are pulled through, rendered to hosts.toml files under /etc/containerd/certs.d
+	RegistryAuth          []RegistryAuthConfig   `json:"registryAuth"`  // Credentials containerd pulls private images with, without imagePullSecrets
+	ConfigPatches         []string               `json:"configPatches"` // TOML fragments deep-merged into the generated config.toml, after those of /etc/containerd/conf.d
+	Snapshotter           string                 `json:"snapshotter"`   // Snapshotter for image layers: overlayfs (default), native, zfs, erofs or stargz
+	Stargz                StargzConfig           `json:"stargz"`
+	GC                    ContainerdGCConfig     `json:"gc"`
+	NRI                   NRIConfig              `json:"nri"`
+	DiscardUnpackedLayers bool                   `json:"discardUnpackedLayers"` // Delete compressed layers once unpacked, roughly halving the disk images take; images can then not be exported from the node
+}
+
+// ContainerdGCConfig tunes containerd's garbage collector, which removes content and snapshots no longer
+// referenced by an image or container. Unset fields keep containerd's defaults.
+type ContainerdGCConfig struct {
+	PauseThreshold    float64 `json:"pauseThreshold"`    // Largest share of time collection may block the metadata store, up to 0.5 (default 0.02)
+	DeletionThreshold int     `json:"deletionThreshold"` // Deletions that trigger a collection, 0 to not trigger on deletions (default)
+	MutationThreshold int     `json:"mutationThreshold"` // Changes after which a collection runs once a deletion happened (default 100)
+	ScheduleDelay     string  `json:"scheduleDelay"`     // Delay before a triggered collection, e.g. 10ms (default 0ms)
+	StartupDelay      string  `json:"startupDelay"`      // Delay before the first collection after startup, e.g. 100ms (default)
+}
+
+// NRIConfig enables containerd's Node Resource Interface, through which plugins adjust containers as they are
+// created, e.g. resource managers pinning CPUs or injectors adding devices
+type NRIConfig struct {
+	Enabled             bool   `json:"enabled"`
+	PluginDir           string `json:"pluginDir"`           // Plugins containerd starts itself, named NN-<name> (default /opt/nri/plugins)
+	PluginConfigDir     string `json:"pluginConfigDir"`     // Configuration of those plugins, <name>.conf or NN-<name>.conf (default /etc/nri/conf.d)
+	SocketPath          string `json:"socketPath"`          // Socket plugins running as pods connect to (default /var/run/nri/nri.sock)
+	DisableConnections  bool   `json:"disableConnections"`  // Only run the plugins of pluginDir, refusing plugins that connect to the socket
+	RegistrationTimeout string `json:"registrationTimeout"` // Time a plugin has to register, e.g. 5s (containerd default)
+	RequestTimeout      string `json:"requestTimeout"`      // Time a plugin has to handle a request, e.g. 2s (containerd default)
+}
+
+// StargzConfig holds the settings of the stargz snapshotter, installed when containerd.snapshotter is stargz
+type StargzConfig struct {
+	Version string `json:"version"` // stargz-snapshotter release, e.g. 0.16.3
+}
+
+// RegistryAuthConfig holds the credentials of one registry. Set username and password, identityToken, or
+// managedIdentity; secrets may be Key Vault references.
+type RegistryAuthConfig struct {
+	Registry        string `json:"registry"` // Registry host, e.g. myregistry.azurecr.io
+	Username        string `json:"username"`
+	Password        string `json:"password"`
+	IdentityToken   string `json:"identityToken"`   // OAuth refresh token used instead of a username and password
+	ManagedIdentity bool   `json:"managedIdentity"` // Azure Container Registry: pull with the node identity, which needs AcrPull
+}
+
+// RegistryMirrorConfig declares the mirrors of one registry. containerd tries the endpoints in order and falls
+// back to the registry itself unless skipUpstream is set.
+type RegistryMirrorConfig struct {
+	Registry     string   `json:"registry"`     // Registry host, e.g. docker.io or mcr.microsoft.com, or _default for every registry
+	Endpoints    []string `json:"endpoints"`    // Mirror URLs, e.g. https://mirror.contoso.local:5000
+	SkipUpstream bool     `json:"skipUpstream"` // Never pull from the registry itself, for air-gapped sites
+	OverridePath bool     `json:"overridePath"` // Endpoints include the API path, e.g. https://harbor.contoso.local/v2/dockerhub-proxy
+	CAFile       string   `json:"caFile"`       // CA bundle the endpoints' certificates are verified with
+	SkipVerify   bool     `json:"skipVerify"`   // Skip TLS verification of the endpoints
 }
 
 // NodeConfig holds configuration settings for the Kubernetes node.
 type NodeConfig struct {
 	MaxPods int               `json:"maxPods"`
 	Labels  map[string]string `json:"labels"`
+	Taints  []string          `json:"taints"` // Taints the node registers with, as key[=value]:effect, e.g. "example.com/not-ready=true:NoSchedule"
 	Kubelet KubeletConfig     `json:"kubelet"`
+
+	AgentPool AgentPoolConfig `json:"agentPool"` // AKS agent pool the node presents as, so workloads selecting the pool schedule onto it
+
+	DetectCloudMetadata bool `json:"detectCloudMetadata"` // Record the AWS/GCP provider, instance type, region and zone as node labels and Arc tags
+
+	IP NodeIPConfig `json:"ip"` // Address kubelet advertises and the CNI uses on hosts with several network interfaces
+}
+
+// AgentPoolConfig associates the node with an AKS agent pool. The node carries the pool's agentpool and mode
+// labels; preflight checks that the pool exists and that the node has its labels and taints.
+type AgentPoolConfig struct {
+	Name string `json:"name"` // Name of the agent pool, e.g. nodepool1; an existing pool or a new logical one
+	Mode string `json:"mode"` // System or User (default)
+}
+
+// NodeIPConfig selects the node IP on multi-homed hosts. At most one field may be set; with none, kubelet
+// picks the address of the default route's interface. Dual-stack nodes take one CIDR or address per family,
+// comma-separated.
+type NodeIPConfig struct {
+	Interface string `json:"interface"` // Use the address of this interface, e.g. eth1
+	CIDR      string `json:"cidr"`      // Use the address within this range, e.g. 192.168.10.0/24 or 192.168.10.0/24,fd00:10::/64
+	Address   string `json:"address"`   // Use this address, which must be assigned to an interface
 }
 
 // KubeletConfig holds kubelet-specific configuration settings.
 type KubeletConfig struct {
-	KubeReserved         map[string]string `json:"kubeReserved"`
-	EvictionHard         map[string]string `json:"evictionHard"`
-	Verbosity            int               `json:"verbosity"`
-	ImageGCHighThreshold int               `json:"imageGCHighThreshold"`
-	ImageGCLowThreshold  int               `json:"imageGCLowThreshold"`
-	DNSServiceIP         string            `json:"dnsServiceIP"` // Cluster DNS service IP (default: 10.0.0.10 for AKS)
-	ServerURL            string            `json:"serverURL"`    // Kubernetes API server URL
-	CACertData           string            `json:"caCertData"`   // Base64-encoded CA certificate data
+	KubeReserved      map[string]string `json:"kubeReserved"`
+	SystemReserved    map[string]string `json:"systemReserved"`    // Resources kept for OS daemons outside kubelet and the container runtime, e.g. {"memory": "500Mi"}
+	ReservedResources string            `json:"reservedResources"` // "auto" (default) sizes the cpu and memory of kubeReserved from the host like AKS, "none" keeps only what is set
+	EvictionHard      map[string]string `json:"evictionHard"`
+
+	// Soft eviction gives pods their termination grace period once a threshold has been crossed for its grace period
+	EvictionSoft                     map[string]string `json:"evictionSoft"`                     // e.g. {"memory.available": "500Mi"}
+	EvictionSoftGracePeriod          map[string]string `json:"evictionSoftGracePeriod"`          // How long each soft threshold must be crossed, e.g. {"memory.available": "1m30s"}
+	EvictionMaxPodGracePeriod        int               `json:"evictionMaxPodGracePeriod"`        // Longest termination grace period in seconds soft eviction grants a pod
+	EvictionMinimumReclaim           map[string]string `json:"evictionMinimumReclaim"`           // Resources reclaimed beyond a threshold, e.g. {"nodefs.available": "1Gi"}
+	EvictionPressureTransitionPeriod string            `json:"evictionPressureTransitionPeriod"` // How long a pressure condition stays after the threshold clears (kubelet default: 5m)
+
+	Verbosity            int    `json:"verbosity"`
+	ImageGCHighThreshold int    `json:"imageGCHighThreshold"`
+	ImageGCLowThreshold  int    `json:"imageGCLowThreshold"`
+	ImageMinimumGCAge    string `json:"imageMinimumGCAge"`    // Age an unused image reaches before image GC may remove it, e.g. 10m (kubelet default: 2m)
+	ContainerLogMaxSize  string `json:"containerLogMaxSize"`  // Size at which a container log is rotated, e.g. 5Mi (kubelet default: 10Mi)
+	ContainerLogMaxFiles int    `json:"containerLogMaxFiles"` // Log files kept per container, at least 2 (kubelet default: 5)
+	DNSServiceIP         string `json:"dnsServiceIP"`         // Cluster DNS service IP (default: 10.0.0.10 for AKS)
+	ServerURL            string `json:"serverURL"`            // Kubernetes API server URL
+	CACertData           string `json:"caCertData"`           // Base64-encoded CA certificate data
+
+	ServerTLSBootstrap bool `json:"serverTLSBootstrap"` // Obtain kubelet's serving certificate from the cluster CA with a CertificateSigningRequest instead of self-signing it
+
+	SwapBehavior string `json:"swapBehavior"` // Keep swap on with kubelet's NodeSwap: "NoSwap" (only system daemons swap) or "LimitedSwap" (Burstable pods may too). Default: swap is turned off
+
+	AcrCredentialProvider AcrCredentialProviderConfig `json:"acrCredentialProvider"` // Pull from Azure Container Registry with the node identity through kubelet's image credential provider
+
+	// Resource managers pinning latency-sensitive pods to CPUs, NUMA nodes and memory
+	CPUManagerPolicy      string `json:"cpuManagerPolicy"`      // "none" (kubelet default) or "static", which gives Guaranteed pods with integer CPUs exclusive cores
+	TopologyManagerPolicy string `json:"topologyManagerPolicy"` // "none" (kubelet default), "best-effort", "restricted" or "single-numa-node"
+	MemoryManagerPolicy   string `json:"memoryManagerPolicy"`   // "None" (kubelet default) or "Static", which pins Guaranteed pods' memory to NUMA nodes
+	ReservedSystemCPUs    string `json:"reservedSystemCPUs"`    // CPUs kept for system daemons and kubelet, e.g. "0-1", overriding the CPU of kubeReserved
+
+	// ConfigOverrides is deep-merged into the generated KubeletConfiguration, e.g. {"maxPods": 250,
+	// "featureGates": {"InPlacePodVerticalScaling": true}}. Objects merge key by key; other values and null replace.
+	ConfigOverrides map[string]any `json:"configOverrides"`
+}
+
+// AcrCredentialProviderConfig holds the settings of the acr-credential-provider kubelet execs for images of Azure
+// Container Registry, so pods pull with the node identity instead of imagePullSecrets. The identity needs AcrPull.
+type AcrCredentialProviderConfig struct {
+	Enabled       bool     `json:"enabled"`
+	Version       string   `json:"version"`       // cloud-provider-azure release the provider is taken from, e.g. 1.32.5
+	MatchImages   []string `json:"matchImages"`   // Images the provider is asked for, e.g. myregistry.azurecr.io (default: every ACR login server)
+	CacheDuration string   `json:"cacheDuration"` // How long kubelet caches credentials the provider returns without a duration of their own (default: 10m)
 }
 
 // PathsConfig holds file system paths used by the agent for Kubernetes and CNI configurations.
@@ -132,7 +503,41 @@ type KubernetesPathsConfig struct {
 
 // CNIPathsConfig holds file system paths related to CNI plugins and configurations.
 type CNIConfig struct {
-	Version string `json:"version"`
+	Version            string        `json:"version"`            // Release of the standard CNI plugins, e.g. 1.5.1
+	Provider           string        `json:"provider"`           // CNI the cluster runs: "bridge" (default), "cilium", "calico" or "flannel"
+	ConfigTemplateFile string        `json:"configTemplateFile"` // Go template of the conflist bootstrap writes in place of the bridge configuration
+	Cilium             CiliumConfig  `json:"cilium"`
+	Calico             CalicoConfig  `json:"calico"`
+	Flannel            FlannelConfig `json:"flannel"`
+	Multus             MultusConfig  `json:"multus"`
+}
+
+// CiliumConfig holds the node-side settings for clusters running Cilium.
+type CiliumConfig struct {
+	Version string `json:"version"` // Cilium version the cluster runs, e.g. 1.16.6
+	Image   string `json:"image"`   // Agent image the cilium-cni binary is taken from, default <images.registry>/oss/cilium/cilium:<version>
+}
+
+// CalicoConfig holds the node-side settings for clusters running Calico.
+type CalicoConfig struct {
+	Version   string `json:"version"`   // Calico version the cluster runs, e.g. 3.29.1
+	Image     string `json:"image"`     // Image the calico and calico-ipam plugins are taken from, default <images.registry>/oss/calico/cni:v<version>
+	WireGuard bool   `json:"wireGuard"` // Load the wireguard kernel module for Calico's WireGuard encryption
+}
+
+// FlannelConfig holds the settings of flanneld and the flannel CNI plugin, which bootstrap runs on the node.
+type FlannelConfig struct {
+	Version       string `json:"version"`       // flanneld release, e.g. 0.26.2
+	PluginVersion string `json:"pluginVersion"` // flannel CNI plugin release, e.g. 1.6.2-flannel1
+	Backend       string `json:"backend"`       // "vxlan" (default) or "host-gw"
+	PodCIDR       string `json:"podCIDR"`       // Pod CIDR of the cluster, one per IP family, comma-separated; default the cluster's pod CIDRs
+}
+
+// MultusConfig enables the Multus meta-plugin, which attaches secondary networks to pods next to the network
+// of cni.provider.
+type MultusConfig struct {
+	Enabled bool   `json:"enabled"`
+	Version string `json:"version"` // Multus release, e.g. 4.1.4
 }
 
 // NPDConfig holds configuration settings for the Node Problem Detector (NPD).
@@ -140,6 +545,241 @@ type NPDConfig struct {
 	Version string `json:"version"`
 }
 
+// KubeProxyConfig holds settings for running kube-proxy on the node, for clusters that do not schedule
+// the kube-proxy DaemonSet onto flex nodes.
+type KubeProxyConfig struct {
+	Enabled     bool                     `json:"enabled"`
+	Mode        string                   `json:"mode"`        // Proxy mode: "iptables" (default) or "ipvs"
+	RunAs       string                   `json:"runAs"`       // "systemd" (default) or "staticPod"
+	Image       string                   `json:"image"`       // Image of the static pod, default <images.registry>/oss/kubernetes/kube-proxy:v<kubernetes.version>
+	ClusterCIDR string                   `json:"clusterCIDR"` // Pod CIDR of the cluster; traffic to services from outside it is masqueraded
+	Conntrack   KubeProxyConntrackConfig `json:"conntrack"`
+}
+
+// RuntimeConfig holds the settings of the container runtimes containerd runs pods with besides runc
+type RuntimeConfig struct {
+	ContainerRuntime string             `json:"containerRuntime"` // CRI runtime kubelet runs pods with: containerd (default) or crio
+	CRIO             CRIOConfig         `json:"crio"`
+	OCIRuntime       string             `json:"ociRuntime"` // Low-level runtime of containerd's default runc handler: runc (default) or crun
+	Crun             CrunConfig         `json:"crun"`
+	GVisor           GVisorConfig       `json:"gvisor"`
+	Kata             KataConfig         `json:"kata"`
+	Confidential     ConfidentialConfig `json:"confidential"`
+}
+
+// ConfidentialConfig runs confidential containers: pods in Kata VMs whose memory AMD SEV-SNP or Intel TDX
+// encrypts, selected with the kata-cc runtime handler. On a machine that is itself a confidential VM, the node
+// is labeled as one instead.
+type ConfidentialConfig struct {
+	Enabled      bool   `json:"enabled"`
+	Technology   string `json:"technology"`   // snp or tdx, detected from the CPU when unset
+	RuntimeClass bool   `json:"runtimeClass"` // Create the kata-cc RuntimeClass in the cluster, scheduling its pods to nodes that run them
+}
+
+// CRIOConfig holds the CRI-O release installed instead of containerd when runtime.containerRuntime is crio.
+// CRI-O pulls with the registry settings of containerd.mirrors and containerd.registryAuth.
+type CRIOConfig struct {
+	Version string `json:"version"` // CRI-O release, e.g. 1.32.1 (defaults to the first release of the Kubernetes minor version)
+}
+
+// CrunConfig holds the crun release installed when runtime.ociRuntime is crun. crun is a smaller and faster
+// alternative to runc, which stays installed.
+type CrunConfig struct {
+	Version string `json:"version"` // crun release, e.g. 1.19.1
+}
+
+// GVisorConfig installs gVisor, which runs pods in a user-space kernel so untrusted code does not reach the
+// host kernel. Pods select it with the runsc runtime handler.
+type GVisorConfig struct {
+	Enabled      bool   `json:"enabled"`
+	Version      string `json:"version"`      // gVisor release, e.g. 20250106.0
+	Platform     string `json:"platform"`     // How runsc intercepts system calls: systrap (default), kvm or ptrace
+	RuntimeClass bool   `json:"runtimeClass"` // Create the gvisor RuntimeClass in the cluster, scheduling its pods to nodes with gVisor
+}
+
+// KataConfig installs Kata Containers, which runs each pod in a lightweight VM with its own kernel. Pods select
+// it with the kata runtime handler. The node needs hardware virtualization: bare metal with VT-x or AMD-V, or a
+// VM with nested virtualization.
+type KataConfig struct {
+	Enabled      bool   `json:"enabled"`
+	Version      string `json:"version"`      // Kata Containers release, e.g. 3.13.0
+	Hypervisor   string `json:"hypervisor"`   // VMM running the pod VMs: qemu (default) or clh (Cloud Hypervisor)
+	RuntimeClass bool   `json:"runtimeClass"` // Create the kata RuntimeClass in the cluster, scheduling its pods to nodes with Kata
+}
+
+// GPUConfig holds the settings of the node's NVIDIA GPUs
+type GPUConfig struct {
+	MIG MIGConfig `json:"mig"`
+}
+
+// MIGConfig partitions A100, H100 and later GPUs into Multi-Instance GPU devices. The geometry is applied with
+// nvidia-smi at bootstrap and on every boot, since GPU instances do not survive a reboot.
+type MIGConfig struct {
+	Enabled  bool     `json:"enabled"`
+	Profiles []string `json:"profiles"` // GPU instance profiles created on each GPU, e.g. ["3g.40gb", "2g.20gb", "1g.10gb"]
+	GPUs     []int    `json:"gpus"`     // Indexes of the GPUs to partition; empty partitions all of them
+	Strategy string   `json:"strategy"` // How the device plugin exposes MIG devices: "single" or "mixed" (default)
+}
+
+// RDMAConfig prepares the host of RDMA-capable nodes, InfiniBand or RoCE, for the RDMA device plugins: the
+// rdma-core packages, the verbs and connection manager kernel modules and device permissions for pods.
+type RDMAConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Devices   []string `json:"devices"`   // RDMA devices that must each have an active port, e.g. mlx5_0; empty requires one active port on any device
+	Modules   []string `json:"modules"`   // Kernel modules loaded at boot in addition to ib_uverbs, rdma_ucm and ib_umad, e.g. ib_ipoib
+	NetNSMode string   `json:"netnsMode"` // RDMA network namespace mode: "shared" (default) or "exclusive", which SR-IOV VFs with RDMA require
+	SharedHCA bool     `json:"sharedHCA"` // Label the node for the RDMA shared device plugin, which shares each HCA among pods
+}
+
+// StorageConfig holds the storage settings of the node
+type StorageConfig struct {
+	DataDisk DataDiskConfig `json:"dataDisk"`
+}
+
+// DataDiskConfig selects a disk that holds the state of containerd and kubelet instead of the OS disk. Bootstrap
+// partitions and formats it unless it already holds the data partition, then mounts it and adds fstab entries.
+type DataDiskConfig struct {
+	Enabled    bool   `json:"enabled"`
+	Device     string `json:"device"`     // Disk device, e.g. /dev/sdc or /dev/disk/azure/scsi1/lun0, or "resource" for the Azure ephemeral resource disk
+	Filesystem string `json:"filesystem"` // Filesystem to format the disk with: ext4 (default) or xfs
+	MountPoint string `json:"mountPoint"` // Where the disk is mounted (default /mnt/aks-data)
+	Wipe       bool   `json:"wipe"`       // Format the disk even if it holds other partitions or a filesystem, destroying their data
+}
+
+// ImagePrePullConfig lists the images pulled before kubelet starts, so the pods scheduled as soon as the node is
+// Ready do not all wait on the same downloads. The built-in images the node runs are always included.
+type ImagePrePullConfig struct {
+	Enabled     bool     `json:"enabled"`
+	Images      []string `json:"images"`      // Extra image references, e.g. the images of critical DaemonSets
+	ConfigMap   string   `json:"configMap"`   // namespace/name of a ConfigMap in the cluster whose "images" key lists more images, one per line
+	Parallelism int      `json:"parallelism"` // Images pulled at the same time (default 3)
+}
+
+// NodeLocalDNSConfig holds settings for the node-local DNS cache, which answers pod DNS queries on a link-local
+// address and forwards cache misses to the cluster DNS service.
+type NodeLocalDNSConfig struct {
+	Enabled bool   `json:"enabled"`
+	LocalIP string `json:"localIP"` // Address the cache listens on and kubelet hands to pods (default 169.254.20.10)
+	Version string `json:"version"` // node-cache release, e.g. 1.23.1
+	Image   string `json:"image"`   // Image of the static pod, default <images.registry>/oss/kubernetes/k8s-dns-node-cache:<version>
+}
+
+// KubeProxyConntrackConfig tunes the conntrack table kube-proxy sizes on startup.
+type KubeProxyConntrackConfig struct {
+	MaxPerCore            int           `json:"maxPerCore"`            // Connections tracked per CPU core (default 32768)
+	Min                   int           `json:"min"`                   // Lower bound of nf_conntrack_max regardless of cores (default 131072)
+	TCPEstablishedTimeout time.Duration `json:"tcpEstablishedTimeout"` // Idle timeout of established TCP connections (default 24h)
+	TCPCloseWaitTimeout   time.Duration `json:"tcpCloseWaitTimeout"`   // Timeout of TCP connections in CLOSE_WAIT (default 1h)
+}
+
+// SRIOVConfig prepares the host for SR-IOV device plugin workloads, including DPDK applications that drive
+// virtual functions from user space.
+type SRIOVConfig struct {
+	Enabled   bool                 `json:"enabled"`
+	Devices   []SRIOVDeviceConfig  `json:"devices"`
+	Hugepages SRIOVHugepagesConfig `json:"hugepages"`
+	CNIImage  string               `json:"cniImage"` // Image the sriov CNI plugin is taken from, replacing the built-in ghcr.io/k8snetworkplumbingwg/sriov-cni:v2.8.1
+}
+
+// SRIOVDeviceConfig creates virtual functions on a physical function.
+type SRIOVDeviceConfig struct {
+	PF      string `json:"pf"`      // Interface of the physical function, e.g. ens1f0
+	NumVFs  int    `json:"numVFs"`  // Virtual functions to create
+	VFIOVFs []int  `json:"vfioVFs"` // Indexes of the VFs bound to vfio-pci for DPDK; the others keep the kernel driver
+}
+
+// SRIOVHugepagesConfig reserves hugepages for DPDK.
+type SRIOVHugepagesConfig struct {
+	Size  string `json:"size"`  // Page size: "2M" (default) or "1G"
+	Count int    `json:"count"` // Pages to reserve; 0 reserves none
+}
+
+// SystemConfig holds host-level settings applied by the system configuration step.
+type SystemConfig struct {
+	CoreDump  CoreDumpConfig  `json:"coreDump"`
+	Sockets   SocketsConfig   `json:"sockets"`
+	Firewall  FirewallConfig  `json:"firewall"`
+	Hugepages HugepagesConfig `json:"hugepages"`
+	Tuning    TuningConfig    `json:"tuning"`
+}
+
+// TuningConfig applies a host tuning profile for latency-sensitive pods. The realtime profile isolates CPUs
+// from kernel housekeeping and interrupts and runs every CPU at its highest frequency.
+type TuningConfig struct {
+	Profile      string `json:"profile"`      // none (default) or realtime
+	IsolatedCPUs string `json:"isolatedCPUs"` // CPUs isolated for pods (default: all CPUs but node.kubelet.reservedSystemCPUs)
+	Reboot       bool   `json:"reboot"`       // Reboot the node to apply the kernel arguments; the agent resumes bootstrap afterwards
+}
+
+// HugepagesConfig reserves hugepages for pods requesting hugepages-2Mi or hugepages-1Gi. 2Mi pages are reserved
+// with sysctl, 1Gi pages on the kernel command line, since they can only be reserved reliably at boot.
+type HugepagesConfig struct {
+	Pages2Mi int  `json:"pages2Mi"` // 2Mi pages to reserve
+	Pages1Gi int  `json:"pages1Gi"` // 1Gi pages to reserve
+	Reboot   bool `json:"reboot"`   // Reboot the node when the pages can only be reserved at boot; the agent resumes bootstrap afterwards
+}
+
+// FirewallConfig controls the host firewall rules opened for kubelet, NodePort services and CNI traffic.
+type FirewallConfig struct {
+	Manage     bool     `json:"manage"`     // Open the ports a node needs in the host firewall, and close them again on unbootstrap (default: true on RHEL, Rocky Linux and AlmaLinux)
+	Backend    string   `json:"backend"`    // ufw, firewalld or nftables (default: the active one)
+	ExtraPorts []string `json:"extraPorts"` // Additional inbound ports, e.g. "179/tcp" for BGP or "51820-51821/udp" for WireGuard
+}
+
+// SocketsConfig controls which local users can reach the container runtime and kubelet sockets.
+// Each socket is owned by root and a dedicated group with mode 0660, so only root and group members can connect.
+type SocketsConfig struct {
+	Containerd SocketAccessConfig `json:"containerd"` // containerd CRI socket (/run/containerd/containerd.sock)
+	Kubelet    SocketAccessConfig `json:"kubelet"`    // kubelet pod-resources socket, used by monitoring and device agents
+}
+
+// SocketAccessConfig holds the group owning a socket and the system users granted access to it.
+type SocketAccessConfig struct {
+	Group string   `json:"group"` // Group owning the socket, created if missing (defaults: containerd, kubelet)
+	Users []string `json:"users"` // Additional system users added to the group
+}
+
+// CoreDumpConfig holds kernel core pattern and systemd-coredump settings so that crashes of
+// node daemons (containerd, kubelet) leave core files behind for debugging.
+type CoreDumpConfig struct {
+	Enabled         bool   `json:"enabled"`         // Whether to manage core dump configuration
+	CorePattern     string `json:"corePattern"`     // kernel.core_pattern value (defaults to piping cores to systemd-coredump)
+	Storage         string `json:"storage"`         // systemd-coredump storage mode: external, journal or none
+	MaxUse          string `json:"maxUse"`          // Maximum disk space used by stored cores (e.g. "2G")
+	ProcessSizeMax  string `json:"processSizeMax"`  // Maximum size of a core that is processed
+	ExternalSizeMax string `json:"externalSizeMax"` // Maximum size of a core that is stored externally
+	SetuidDumps     bool   `json:"setuidDumps"`     // Also dump setuid and credential-changing processes (fs.suid_dumpable = 2), off by default
+}
+
+// PreflightConfig holds settings for the host and network checks that run before bootstrap.
+type PreflightConfig struct {
+	Skip []string        `json:"skip"` // Names of preflight checks to skip (e.g. "snat")
+	SNAT SNATCheckConfig `json:"snat"`
+
+	Connectivity ConnectivityCheckConfig `json:"connectivity"`
+	Latency      LatencyCheckConfig      `json:"latency"`
+}
+
+// LatencyCheckConfig holds the thresholds of the API server and registry latency and throughput check.
+type LatencyCheckConfig struct {
+	MaxRTT            time.Duration `json:"maxRTT"`            // Warn when the round-trip time to the API server or registry exceeds this (default: 150ms)
+	MinThroughputMbps int           `json:"minThroughputMbps"` // Warn when the measured download throughput is lower, in Mbit/s (default: 20)
+	ThroughputURL     string        `json:"throughputURL"`     // URL downloaded to measure throughput (default: the Kubernetes node binaries archive)
+}
+
+// ConnectivityCheckConfig holds the inputs of the outbound connectivity check.
+type ConnectivityCheckConfig struct {
+	Endpoints []string `json:"endpoints"` // Extra URLs probed besides the built-in ones, e.g. internal artifact mirrors
+}
+
+// SNATCheckConfig holds the inputs of the outbound SNAT capacity check.
+type SNATCheckConfig struct {
+	EndpointsPerPod  int    `json:"endpointsPerPod"`  // Estimated concurrent outbound connections per pod (default: 8)
+	AvailablePorts   int    `json:"availablePorts"`   // SNAT ports the site NAT device allocates to this node, 0 if unknown
+	ProbeConnections int    `json:"probeConnections"` // Concurrent connections opened through the NAT (default: 64)
+	ProbeTarget      string `json:"probeTarget"`      // host:port the probe connects to (default: the image registry on port 443)
+}
+
 // IsSPConfigured checks if service principal credentials are provided in the configuration
 func (cfg *Config) IsSPConfigured() bool {
 	return cfg.Azure.ServicePrincipal != nil &&
@@ -148,28 +788,52 @@ func (cfg *Config) IsSPConfigured() bool {
 		cfg.Azure.ServicePrincipal.TenantID != ""
 }
 
+// IsFederatedIdentityConfigured checks if OIDC-federated identity settings are provided in the configuration
+func (cfg *Config) IsFederatedIdentityConfigured() bool {
+	return cfg.Azure.FederatedIdentity != nil &&
+		cfg.Azure.FederatedIdentity.ClientID != "" &&
+		cfg.Azure.FederatedIdentity.TenantID != "" &&
+		cfg.Azure.FederatedIdentity.TokenFile != ""
+}
+
 // IsMIConfigured checks if managed identity configuration is provided in the configuration
 // Uses internal flag set during config loading to handle viper's empty object behavior
 func (cfg *Config) IsMIConfigured() bool {
 	return cfg.isMIExplicitlySet
 }
 
+// GetManagedIdentityID returns the user-assigned identity selected by client ID or resource ID,
+// or nil to use the machine's system-assigned (or only) identity
+func (cfg *Config) GetManagedIdentityID() azidentity.ManagedIDKind {
+	switch {
+	case cfg.Azure.ManagedIdentity == nil:
+		return nil
+	case cfg.Azure.ManagedIdentity.ClientID != "":
+		return azidentity.ClientID(cfg.Azure.ManagedIdentity.ClientID)
+	case cfg.Azure.ManagedIdentity.ResourceID != "":
+		return azidentity.ResourceID(cfg.Azure.ManagedIdentity.ResourceID)
+	}
+	return nil
+}
+
 // IsBootstrapTokenConfigured checks if bootstrap token credentials are provided in the configuration
 func (cfg *Config) IsBootstrapTokenConfigured() bool {
 	return cfg.Azure.BootstrapToken != nil &&
 		cfg.Azure.BootstrapToken.Token != ""
 }
 
-// GetArcMachineName returns the Arc machine name from configuration or defaults to the system hostname
+// GetArcMachineName returns the Arc machine name from configuration or defaults to the system hostname.
+// A {hostname} placeholder in the configured name is replaced with the hostname, so a shared config can
+// apply a naming convention such as "edge-{hostname}".
 func (cfg *Config) GetArcMachineName() string {
-	if cfg.Azure.Arc != nil && cfg.Azure.Arc.MachineName != "" {
-		return cfg.Azure.Arc.MachineName
-	}
 	hostname, err := os.Hostname()
-	if err == nil {
-		return hostname
+	if err != nil {
+		hostname = ""
 	}
-	return ""
+	if cfg.Azure.Arc != nil && cfg.Azure.Arc.MachineName != "" {
+		return strings.ReplaceAll(cfg.Azure.Arc.MachineName, ArcMachineNameHostnamePlaceholder, hostname)
+	}
+	return hostname
 }
 
 // GetTargetClusterName returns the target AKS cluster name from configuration
@@ -252,7 +916,48 @@ func (cfg *Config) GetKubernetesVersion() string {
 	return cfg.Kubernetes.Version
 }
 
+// KubernetesVersionAtLeast reports whether the configured Kubernetes version is major.minor or newer
+func (cfg *Config) KubernetesVersionAtLeast(major, minor int) bool {
+	return versionAtLeast(cfg.Kubernetes.Version, major, minor)
+}
+
+// IsCrossTenant checks if the target cluster lives in a different Azure AD tenant than the machine
+func (cfg *Config) IsCrossTenant() bool {
+	return cfg.Azure.TargetCluster != nil &&
+		cfg.Azure.TargetCluster.TenantID != "" &&
+		!strings.EqualFold(cfg.Azure.TargetCluster.TenantID, cfg.Azure.TenantID)
+}
+
+// ClusterTenantID returns the tenant a credential must request cluster tokens from:
+// the explicit target cluster tenant if set, otherwise the credential's own tenant
+func (cfg *Config) ClusterTenantID(credentialTenantID string) string {
+	if cfg.Azure.TargetCluster != nil && cfg.Azure.TargetCluster.TenantID != "" {
+		return cfg.Azure.TargetCluster.TenantID
+	}
+	return credentialTenantID
+}
+
+// IsPrivateCluster checks if the target cluster is a private cluster joined through its private endpoint
+func (cfg *Config) IsPrivateCluster() bool {
+	return cfg.Azure.TargetCluster != nil && cfg.Azure.TargetCluster.Private
+}
+
 // IsARCEnabled checks if Azure Arc registration is enabled in the configuration
 func (cfg *Config) IsARCEnabled() bool {
 	return cfg.Azure.Arc != nil && cfg.Azure.Arc.Enabled
 }
+
+// GetARMClientOptions returns the client options every Azure Resource Manager client is created with,
+// applying the configured retry policy
+func (cfg *Config) GetARMClientOptions() *arm.ClientOptions {
+	return &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Retry: policy.RetryOptions{
+				MaxRetries:    cfg.Azure.ARMRetry.MaxRetries,
+				RetryDelay:    cfg.Azure.ARMRetry.RetryDelay,
+				MaxRetryDelay: cfg.Azure.ARMRetry.MaxRetryDelay,
+				TryTimeout:    cfg.Azure.ARMRetry.TryTimeout,
+			},
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// Host tuning profiles
+const (
+	TuningProfileNone     = "none"
+	TuningProfileRealtime = "realtime"
+
+	// TuningProfileLabel carries the tuning profile of the node, so latency-sensitive pods can select tuned nodes
+	TuningProfileLabel = "kubernetes.azure.com/flex-tuning-profile"
+)
+
+func (c *Config) setTuningDefaults() {
+	if c.System.Tuning.Profile == "" {
+		c.System.Tuning.Profile = TuningProfileNone
+	}
+	if c.System.Tuning.Profile != TuningProfileNone {
+		c.setNodeLabelIfMissing(TuningProfileLabel, c.System.Tuning.Profile)
+	}
+}
+
+// validateTuning validates system.tuning. The realtime profile leaves node.kubelet.reservedSystemCPUs to system
+// daemons and interrupts and isolates the other CPUs, which only the static CPU manager hands out exclusively.
+func validateTuning(c *Config) error {
+	tuning := &c.System.Tuning
+	switch tuning.Profile {
+	case "", TuningProfileNone:
+		return nil
+	case TuningProfileRealtime:
+	default:
+		return fmt.Errorf("invalid system.tuning.profile: %s. Valid values are: none, realtime", tuning.Profile)
+	}
+
+	kubelet := &c.Node.Kubelet
+	if kubelet.ReservedSystemCPUs == "" {
+		return fmt.Errorf("invalid system.tuning.profile: realtime requires node.kubelet.reservedSystemCPUs for system daemons and interrupts")
+	}
+	if kubelet.CPUManagerPolicy != CPUManagerPolicyStatic {
+		return fmt.Errorf("invalid system.tuning.profile: realtime requires node.kubelet.cpuManagerPolicy static to give pods exclusive isolated CPUs")
+	}
+	if tuning.IsolatedCPUs == "" {
+		return nil
+	}
+	isolated, err := utilhost.ParseCPUList(tuning.IsolatedCPUs)
+	if err != nil {
+		return fmt.Errorf("invalid system.tuning.isolatedCPUs: %s. Expected a CPU list such as 2-15", tuning.IsolatedCPUs)
+	}
+	reserved, err := utilhost.ParseCPUList(kubelet.ReservedSystemCPUs)
+	if err != nil {
+		return fmt.Errorf("invalid node.kubelet.reservedSystemCPUs: %s. Expected a CPU list such as 0-1 or 0,2", kubelet.ReservedSystemCPUs)
+	}
+	for _, cpu := range isolated {
+		if slices.Contains(reserved, cpu) {
+			return fmt.Errorf("invalid system.tuning.isolatedCPUs: %s. CPU %d is also in node.kubelet.reservedSystemCPUs", tuning.IsolatedCPUs, cpu)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestValidateTuning(t *testing.T) {
+	static := KubeletConfig{CPUManagerPolicy: CPUManagerPolicyStatic, ReservedSystemCPUs: "0-1"}
+	tests := []struct {
+		name    string
+		tuning  TuningConfig
+		kubelet KubeletConfig
+		wantErr bool
+	}{
+		{name: "no profile"},
+		{name: "realtime", tuning: TuningConfig{Profile: "realtime"}, kubelet: static},
+		{name: "realtime with isolated CPUs", tuning: TuningConfig{Profile: "realtime", IsolatedCPUs: "4-15"}, kubelet: static},
+		{name: "unknown profile", tuning: TuningConfig{Profile: "throughput"}, wantErr: true},
+		{name: "realtime without reserved CPUs", tuning: TuningConfig{Profile: "realtime"}, kubelet: KubeletConfig{CPUManagerPolicy: CPUManagerPolicyStatic}, wantErr: true},
+		{name: "realtime without static CPU manager", tuning: TuningConfig{Profile: "realtime"}, kubelet: KubeletConfig{ReservedSystemCPUs: "0-1"}, wantErr: true},
+		{name: "invalid isolated CPUs", tuning: TuningConfig{Profile: "realtime", IsolatedCPUs: "4-2"}, kubelet: static, wantErr: true},
+		{name: "isolated CPUs overlap reserved", tuning: TuningConfig{Profile: "realtime", IsolatedCPUs: "1-15"}, kubelet: static, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{System: SystemConfig{Tuning: tt.tuning}, Node: NodeConfig{Kubelet: tt.kubelet}}
+			err := validateTuning(c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTuning() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
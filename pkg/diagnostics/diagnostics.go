@@ -0,0 +1,201 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/status"
+)
+
+const (
+	// coreDumpWindow bounds how far back core dumps are collected
+	coreDumpWindow = "-24h"
+	// commandTimeout bounds every diagnostic command run while collecting the bundle
+	commandTimeout = 30 * time.Second
+)
+
+// Variables so tests can replace them
+var (
+	// maxCoreDumpSize skips cores that would make the bundle impractically large
+	maxCoreDumpSize int64 = 512 * 1024 * 1024
+	// statusFilePath returns the agent status file included in bundles
+	statusFilePath = status.GetStatusFilePath
+	// runCommand runs a diagnostic command and returns its combined output
+	runCommand = func(ctx context.Context, command string, args ...string) ([]byte, error) {
+		// #nosec G204 -- commands come from fixed lists in this package
+		return exec.CommandContext(ctx, command, args...).CombinedOutput()
+	}
+)
+
+// coreDumpProcesses lists the node daemons whose core dumps are included in bundles
+var coreDumpProcesses = []string{"containerd", "containerd-shim-runc-v2", "kubelet"}
+
+// diagnosticCommands lists commands whose output is captured into the bundle, keyed by file name
+var diagnosticCommands = map[string][]string{
+	"journal-kubelet.log":    {"journalctl", "-u", "kubelet", "--since", "-2h", "--no-pager"},
+	"journal-containerd.log": {"journalctl", "-u", "containerd", "--since", "-2h", "--no-pager"},
+	"systemctl-status.txt":   {"systemctl", "status", "kubelet", "containerd", "--no-pager"},
+	"sysctl.txt":             {"sysctl", "-a"},
+	"ip-addr.txt":            {"ip", "addr"},
+	"ip-route.txt":           {"ip", "route"},
+	"disk-usage.txt":         {"df", "-h"},
+}
+
+// Collector gathers logs, status and crash dumps into a single diagnostics bundle
+type Collector struct {
+	config *config.Config
+	logger *logrus.Logger
+}
+
+// NewCollector creates a new diagnostics bundle collector
+func NewCollector(cfg *config.Config, logger *logrus.Logger) *Collector {
+	return &Collector{
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Collect writes a gzipped tarball with diagnostics to outputPath and returns the path written
+func (c *Collector) Collect(ctx context.Context, outputPath string) (string, error) {
+	if outputPath == "" {
+		outputPath = filepath.Join(os.TempDir(),
+			fmt.Sprintf("aks-flex-node-diagnostics-%s.tar.gz", time.Now().UTC().Format("20060102-150405")))
+	}
+
+	f, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create diagnostics bundle %s: %w", outputPath, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	c.addFile(tw, statusFilePath(), "status/status.json")
+	c.addDirectory(tw, c.config.Agent.LogDir, "logs")
+
+	for name, command := range diagnosticCommands {
+		c.addCommandOutput(ctx, tw, "commands/"+name, command[0], command[1:]...)
+	}
+
+	if c.config.System.CoreDump.Enabled {
+		c.addCoreDumps(ctx, tw)
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize diagnostics archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize diagnostics compression: %w", err)
+	}
+
+	c.logger.Infof("Diagnostics bundle written to %s", outputPath)
+	return outputPath, nil
+}
+
+// addCoreDumps includes the most recent core dump of each node daemon, if any
+func (c *Collector) addCoreDumps(ctx context.Context, tw *tar.Writer) {
+	c.addCommandOutput(ctx, tw, "coredumps/list.txt", "coredumpctl", "list", "--since", coreDumpWindow, "--no-pager")
+
+	tmpDir, err := os.MkdirTemp("", "aks-flex-node-cores-")
+	if err != nil {
+		c.logger.Warnf("Failed to create temporary directory for core dumps: %v", err)
+		return
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	for _, process := range coreDumpProcesses {
+		corePath := filepath.Join(tmpDir, process+".core")
+		cmdCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+		_, err := runCommand(cmdCtx, "coredumpctl", "dump", "--since", coreDumpWindow,
+			"--output", corePath, "COMM="+process)
+		cancel()
+		if err != nil {
+			c.logger.Debugf("No recent core dump found for %s: %v", process, err)
+			continue
+		}
+
+		info, err := os.Stat(corePath)
+		if err != nil {
+			continue
+		}
+		if info.Size() > maxCoreDumpSize {
+			c.logger.Warnf("Skipping %s core dump of %d bytes (limit %d)", process, info.Size(), maxCoreDumpSize)
+			continue
+		}
+		c.addFile(tw, corePath, "coredumps/"+process+".core")
+	}
+}
+
+// addCommandOutput runs a command and stores its combined output in the archive.
+// Failures are recorded in the output rather than aborting the bundle.
+func (c *Collector) addCommandOutput(ctx context.Context, tw *tar.Writer, name string, command string, args ...string) {
+	cmdCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	output, err := runCommand(cmdCtx, command, args...)
+	if err != nil {
+		output = append(output, []byte(fmt.Sprintf("\ncommand %s %s failed: %v\n", command, strings.Join(args, " "), err))...)
+	}
+	if err := writeEntry(tw, name, int64(len(output)), strings.NewReader(string(output))); err != nil {
+		c.logger.Warnf("Failed to add %s to diagnostics bundle: %v", name, err)
+	}
+}
+
+// addDirectory adds the regular files directly inside dir to the archive
+func (c *Collector) addDirectory(tw *tar.Writer, dir string, prefix string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		c.logger.Debugf("Skipping %s in diagnostics bundle: %v", dir, err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			c.addFile(tw, filepath.Join(dir, entry.Name()), prefix+"/"+entry.Name())
+		}
+	}
+}
+
+// addFile copies a local file into the archive, skipping files that do not exist
+func (c *Collector) addFile(tw *tar.Writer, path string, name string) {
+	f, err := os.Open(path) // #nosec G304 -- paths are controlled by the agent
+	if err != nil {
+		c.logger.Debugf("Skipping %s in diagnostics bundle: %v", path, err)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.logger.Debugf("Skipping %s in diagnostics bundle: %v", path, err)
+		return
+	}
+	if err := writeEntry(tw, name, info.Size(), f); err != nil {
+		c.logger.Warnf("Failed to add %s to diagnostics bundle: %v", path, err)
+	}
+}
+
+// writeEntry writes a single regular file entry to the archive
+func writeEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
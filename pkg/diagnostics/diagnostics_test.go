@@ -0,0 +1,200 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// stubDiagnostics points the status file at dir and replaces commands with run, restoring both when the test ends
+func stubDiagnostics(t *testing.T, dir string, run func(command string, args []string) ([]byte, error)) {
+	t.Helper()
+	origStatus, origRun := statusFilePath, runCommand
+	t.Cleanup(func() { statusFilePath, runCommand = origStatus, origRun })
+	statusFilePath = func() string { return filepath.Join(dir, "status.json") }
+	runCommand = func(_ context.Context, command string, args ...string) ([]byte, error) {
+		return run(command, args)
+	}
+}
+
+// readBundle returns the entries of a diagnostics bundle by name
+func readBundle(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[header.Name] = string(data)
+	}
+}
+
+func newTestCollector(logDir string, coreDumps bool) *Collector {
+	cfg := &config.Config{}
+	cfg.Agent.LogDir = logDir
+	cfg.System.CoreDump.Enabled = coreDumps
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewCollector(cfg, logger)
+}
+
+func TestCollectArchiveContents(t *testing.T) {
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "logs")
+	if err := os.MkdirAll(filepath.Join(logDir, "archive"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"status.json":                    `{"kubeletRunning": true}`,
+		"logs/aks-flex-node.log":         "agent log",
+		"logs/bootstrap.log":             "bootstrap log",
+		"logs/archive/aks-flex-node.log": "rotated log",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "status.json"), filepath.Join(logDir, "status-link.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	var commands []string
+	stubDiagnostics(t, dir, func(command string, args []string) ([]byte, error) {
+		commands = append(commands, command)
+		if command == "sysctl" {
+			return []byte("partial"), fmt.Errorf("exit status 255")
+		}
+		return []byte(command + " output"), nil
+	})
+
+	output := filepath.Join(dir, "bundle.tar.gz")
+	path, err := newTestCollector(logDir, false).Collect(context.Background(), output)
+	if err != nil || path != output {
+		t.Fatalf("Collect() = %s, %v, want %s", path, err, output)
+	}
+	entries := readBundle(t, output)
+
+	want := map[string]string{
+		"status/status.json":           `{"kubeletRunning": true}`,
+		"logs/aks-flex-node.log":       "agent log",
+		"logs/bootstrap.log":           "bootstrap log",
+		"commands/ip-route.txt":        "ip output",
+		"commands/journal-kubelet.log": "journalctl output",
+	}
+	for name, content := range want {
+		if entries[name] != content {
+			t.Errorf("bundle entry %s = %q, want %q", name, entries[name], content)
+		}
+	}
+	if sysctl := entries["commands/sysctl.txt"]; !strings.HasPrefix(sysctl, "partial") || !strings.Contains(sysctl, "command sysctl -a failed: exit status 255") {
+		t.Errorf("failed command output = %q, want its output followed by the failure", sysctl)
+	}
+	for name := range entries {
+		if strings.HasPrefix(name, "coredumps/") || name == "logs/archive/aks-flex-node.log" || name == "logs/status-link.json" {
+			t.Errorf("bundle includes %s", name)
+		}
+	}
+	if len(entries) != len(diagnosticCommands)+3 {
+		t.Errorf("bundle has %d entries, want %d: %v", len(entries), len(diagnosticCommands)+3, slices.Sorted(maps.Keys(entries)))
+	}
+	if slices.Contains(commands, "coredumpctl") {
+		t.Error("coredumpctl ran with core dumps disabled")
+	}
+	if info, err := os.Stat(output); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("bundle mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestCollectWithoutLogDirectory(t *testing.T) {
+	dir := t.TempDir()
+	stubDiagnostics(t, dir, func(command string, args []string) ([]byte, error) { return nil, nil })
+
+	output := filepath.Join(dir, "bundle.tar.gz")
+	if _, err := newTestCollector(filepath.Join(dir, "missing"), false).Collect(context.Background(), output); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	for name := range readBundle(t, output) {
+		if !strings.HasPrefix(name, "commands/") {
+			t.Errorf("bundle includes %s without a log directory or status file", name)
+		}
+	}
+}
+
+func TestCollectCoreDumps(t *testing.T) {
+	origLimit := maxCoreDumpSize
+	t.Cleanup(func() { maxCoreDumpSize = origLimit })
+	maxCoreDumpSize = 16
+
+	// kubelet has a small core, containerd one above the limit and the shim none
+	cores := map[string]string{
+		"COMM=kubelet":    "kubelet core",
+		"COMM=containerd": "containerd core larger than the limit",
+	}
+	dir := t.TempDir()
+	stubDiagnostics(t, dir, func(command string, args []string) ([]byte, error) {
+		if command != "coredumpctl" {
+			return nil, nil
+		}
+		if args[0] == "list" {
+			if !slices.Contains(args, coreDumpWindow) {
+				t.Errorf("coredumpctl list %v does not bound the window", args)
+			}
+			return []byte("core list"), nil
+		}
+		core, ok := cores[args[len(args)-1]]
+		if !ok {
+			return []byte("No coredumps found."), fmt.Errorf("exit status 1")
+		}
+		output := args[slices.Index(args, "--output")+1]
+		return nil, os.WriteFile(output, []byte(core), 0o600)
+	})
+
+	output := filepath.Join(dir, "bundle.tar.gz")
+	if _, err := newTestCollector(filepath.Join(dir, "logs"), true).Collect(context.Background(), output); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	entries := readBundle(t, output)
+
+	if entries["coredumps/list.txt"] != "core list" {
+		t.Errorf("core dump list = %q", entries["coredumps/list.txt"])
+	}
+	if entries["coredumps/kubelet.core"] != "kubelet core" {
+		t.Errorf("kubelet core = %q, want the dumped core", entries["coredumps/kubelet.core"])
+	}
+	for _, skipped := range []string{"coredumps/containerd.core", "coredumps/containerd-shim-runc-v2.core"} {
+		if _, ok := entries[skipped]; ok {
+			t.Errorf("bundle includes %s", skipped)
+		}
+	}
+}
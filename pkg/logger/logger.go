@@ -19,6 +19,15 @@ type contextKey string
 
 const loggerContextKey contextKey = "aks-flex-node-logger"
 
+// consoleOutput is where systemd-mode logs are written alongside the optional log file
+var consoleOutput io.Writer = os.Stdout
+
+// SetConsoleOutput changes the console writer used by loggers created afterwards,
+// e.g. to keep stdout free for machine-readable command output
+func SetConsoleOutput(w io.Writer) {
+	consoleOutput = w
+}
+
 // LogLevel represents supported logging levels
 type LogLevel string
 
@@ -100,7 +109,7 @@ func SetupLogger(ctx context.Context, level, logDir string) context.Context {
 		})
 
 		// Set up dual output: journal (stdout) and optional log file
-		writers := []io.Writer{os.Stdout}
+		writers := []io.Writer{consoleOutput}
 
 		if logDir != "" {
 			if fileWriter, err := setupLogFileWriter(logDir); err != nil {
@@ -0,0 +1,161 @@
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Events reported for a run of bootstrap, upgrade or unbootstrap
+const (
+	EventRunStarted   = "run_started"
+	EventStepStarted  = "step_started"
+	EventStepFinished = "step_finished"
+	EventRunFinished  = "run_finished"
+)
+
+// Step results reported with EventStepFinished
+const (
+	ResultDone    = "done"
+	ResultSkipped = "skipped"
+	ResultFailed  = "failed"
+)
+
+// writeTimeout bounds a single event write, so a reader that stopped reading cannot stall the run
+const writeTimeout = 5 * time.Second
+
+type contextKey string
+
+const reporterContextKey contextKey = "aks-flex-node-progress"
+
+// Event is a progress event, written as one JSON object per line
+type Event struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Event     string    `json:"event"`
+	Step      string    `json:"step,omitempty"`
+	Steps     []string  `json:"steps,omitempty"` // Names of all steps in order, set on run_started
+	Index     int       `json:"index,omitempty"` // Position of the step, counting from 1
+	Total     int       `json:"total"`           // Number of steps of the run
+	Percent   int       `json:"percent"`         // Share of the steps finished
+	Result    string    `json:"result,omitempty"`
+	Success   *bool     `json:"success,omitempty"` // Outcome of the run, set on run_finished
+	Aborted   bool      `json:"aborted,omitempty"`
+	Duration  float64   `json:"durationSeconds,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Reporter writes progress events for wrappers such as Packer, Ansible or VM extensions, and passes them to
+// in-process listeners such as the terminal UI. Reporting is best effort: after a failed write the reporter
+// stops writing instead of failing the run.
+type Reporter struct {
+	mu        sync.Mutex
+	out       io.WriteCloser
+	err       error
+	listeners []func(Event)
+}
+
+// New creates a reporter that only passes events to its listeners
+func New() *Reporter {
+	return &Reporter{}
+}
+
+// Listen calls fn with every event reported from now on
+func (r *Reporter) Listen(fn func(Event)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// OpenFD reports to an inherited file descriptor, such as the write end of a pipe set up by the wrapper.
+// Descriptors 0 to 2 are taken by stdin, stdout and stderr.
+func OpenFD(fd int) (*Reporter, error) {
+	if fd < 3 {
+		return nil, fmt.Errorf("invalid progress file descriptor %d: expected 3 or higher", fd)
+	}
+	file := os.NewFile(uintptr(fd), "progress")
+	if _, err := file.Stat(); err != nil {
+		return nil, fmt.Errorf("progress file descriptor %d is not open: %w", fd, err)
+	}
+	return &Reporter{out: file}, nil
+}
+
+// Dial reports to the unix socket a wrapper listens on
+func Dial(path string) (*Reporter, error) {
+	conn, err := net.DialTimeout("unix", path, writeTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to progress socket %s: %w", path, err)
+	}
+	return &Reporter{out: conn}, nil
+}
+
+// Report writes an event, stamping its time. A nil reporter discards it.
+func (r *Reporter) Report(event Event) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	event.Time = time.Now().UTC()
+	listeners := r.listeners
+	r.mu.Unlock()
+	// Listeners run outside the lock, so a slow listener does not hold up writes of other goroutines
+	for _, listener := range listeners {
+		listener(event)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.out == nil || r.err != nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if conn, ok := r.out.(net.Conn); ok {
+		_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	}
+	_, r.err = r.out.Write(append(data, '\n'))
+}
+
+// Err returns the write failure that silenced the reporter, if any
+func (r *Reporter) Err() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close closes the descriptor or socket
+func (r *Reporter) Close() error {
+	if r == nil || r.out == nil {
+		return nil
+	}
+	return r.out.Close()
+}
+
+// WithReporter returns a context carrying the reporter
+func WithReporter(ctx context.Context, r *Reporter) context.Context {
+	return context.WithValue(ctx, reporterContextKey, r)
+}
+
+// FromContext returns the reporter of the context, or nil when progress is not reported
+func FromContext(ctx context.Context) *Reporter {
+	r, _ := ctx.Value(reporterContextKey).(*Reporter)
+	return r
+}
+
+// Percent returns the share of total steps that finished
+func Percent(finished, total int) int {
+	if total == 0 {
+		return 100
+	}
+	return finished * 100 / total
+}
@@ -0,0 +1,92 @@
+package progress
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenFD(t *testing.T) {
+	if _, err := OpenFD(1); err == nil {
+		t.Error("OpenFD(1) succeeded, want stdout refused")
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = reader.Close() }()
+	reporter, err := OpenFD(int(writer.Fd()))
+	if err != nil {
+		t.Fatalf("OpenFD() error = %v", err)
+	}
+	reporter.Report(Event{Operation: "bootstrap", Event: EventStepStarted, Step: "KubeletInstaller", Index: 3, Total: 4, Percent: Percent(2, 4)})
+	if err := reporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var event Event
+	if err := json.NewDecoder(reader).Decode(&event); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if event.Step != "KubeletInstaller" || event.Percent != 50 || event.Time.IsZero() {
+		t.Errorf("event = %+v, want KubeletInstaller at 50%% with a time", event)
+	}
+}
+
+func TestDial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	reporter, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	reporter.Report(Event{Operation: "bootstrap", Event: EventRunStarted, Total: 4})
+	reporter.Report(Event{Operation: "bootstrap", Event: EventRunFinished, Total: 4, Percent: 100})
+	_ = reporter.Close()
+
+	var got []string
+	for line := range lines {
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event line %q: %v", line, err)
+		}
+		got = append(got, event.Event)
+	}
+	if len(got) != 2 || got[0] != EventRunStarted || got[1] != EventRunFinished {
+		t.Errorf("events = %v, want run_started and run_finished", got)
+	}
+
+	if _, err := Dial(filepath.Join(t.TempDir(), "missing.sock")); err == nil {
+		t.Error("Dial() of a missing socket succeeded")
+	}
+}
+
+func TestNilReporter(t *testing.T) {
+	var reporter *Reporter
+	reporter.Report(Event{Event: EventRunStarted})
+	if err := reporter.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
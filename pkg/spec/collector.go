@@ -3,11 +3,14 @@ package spec
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v5"
 	"github.com/sirupsen/logrus"
 
@@ -50,7 +53,7 @@ func NewManagedClusterSpecCollector(cfg *config.Config, logger *logrus.Logger) *
 		outputPath:   GetManagedClusterSpecFilePath(),
 	}
 	// Keep KubernetesVersion, fqdn required for now; more enrichers can be added over time.
-	c.enrichers = []ManagedClusterSpecEnricher{enrichKubernetesVersionRequired, enrichFQDNRequired}
+	c.enrichers = []ManagedClusterSpecEnricher{enrichKubernetesVersionRequired, enrichAPIServerAccess, enrichFQDNRequired, enrichLifecycleState, enrichNetworkProfile, enrichAgentPools}
 	return c
 }
 
@@ -100,12 +103,12 @@ func (c *ManagedClusterSpecCollector) Collect(ctx context.Context) (*ManagedClus
 			return nil, fmt.Errorf("subscription ID missing")
 		}
 
-		cred, err := c.authProvider.UserCredential(c.cfg)
+		cred, err := c.authProvider.ClusterCredential(c.cfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get credential: %w", err)
 		}
 
-		mcClient, err := armcontainerservice.NewManagedClustersClient(subscriptionID, cred, nil)
+		mcClient, err := armcontainerservice.NewManagedClustersClient(subscriptionID, cred, c.cfg.GetARMClientOptions())
 		if err != nil {
 			return nil, fmt.Errorf("failed to create managed clusters client: %w", err)
 		}
@@ -115,6 +118,13 @@ func (c *ManagedClusterSpecCollector) Collect(ctx context.Context) (*ManagedClus
 	c.logger.Infof("Collecting managed cluster spec for %s/%s", clusterRG, clusterName)
 	resp, err := c.client.Get(ctx, clusterRG, clusterName, nil)
 	if err != nil {
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrClusterNotFound, c.cfg.GetTargetClusterID())
+		}
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("%w: %s: %w", ErrClusterAccessDenied, c.cfg.GetTargetClusterID(), err)
+		}
 		return nil, fmt.Errorf("failed to get AKS managed cluster via SDK: %w", err)
 	}
 
@@ -174,8 +184,103 @@ func enrichFQDNRequired(spec *ManagedClusterSpec, resp armcontainerservice.Manag
 		return fmt.Errorf("spec is nil")
 	}
 	if resp.Properties == nil || resp.Properties.Fqdn == nil || *resp.Properties.Fqdn == "" {
+		// Private clusters without a public FQDN only have the private one
+		if spec.PrivateFqdn != "" {
+			return nil
+		}
 		return fmt.Errorf("managed cluster FQDN is empty")
 	}
 	spec.Fqdn = *resp.Properties.Fqdn
 	return nil
 }
+
+func enrichAPIServerAccess(spec *ManagedClusterSpec, resp armcontainerservice.ManagedClustersClientGetResponse) error {
+	if spec == nil {
+		return fmt.Errorf("spec is nil")
+	}
+	if resp.Properties == nil {
+		return nil
+	}
+	if resp.Properties.PrivateFQDN != nil {
+		spec.PrivateFqdn = *resp.Properties.PrivateFQDN
+	}
+	if profile := resp.Properties.APIServerAccessProfile; profile != nil {
+		if profile.EnablePrivateCluster != nil {
+			spec.PrivateCluster = *profile.EnablePrivateCluster
+		}
+		if profile.PrivateDNSZone != nil {
+			spec.PrivateDNSZone = *profile.PrivateDNSZone
+		}
+	}
+	return nil
+}
+
+func enrichLifecycleState(spec *ManagedClusterSpec, resp armcontainerservice.ManagedClustersClientGetResponse) error {
+	if spec == nil {
+		return fmt.Errorf("spec is nil")
+	}
+	if resp.Properties == nil {
+		return nil
+	}
+	if resp.Properties.PowerState != nil && resp.Properties.PowerState.Code != nil {
+		spec.PowerState = string(*resp.Properties.PowerState.Code)
+	}
+	if resp.Properties.ProvisioningState != nil {
+		spec.ProvisioningState = *resp.Properties.ProvisioningState
+	}
+	return nil
+}
+
+func enrichNetworkProfile(spec *ManagedClusterSpec, resp armcontainerservice.ManagedClustersClientGetResponse) error {
+	if spec == nil {
+		return fmt.Errorf("spec is nil")
+	}
+	if resp.Properties == nil || resp.Properties.NetworkProfile == nil {
+		return nil
+	}
+	profile := resp.Properties.NetworkProfile
+	for _, family := range profile.IPFamilies {
+		if family != nil {
+			spec.IPFamilies = append(spec.IPFamilies, string(*family))
+		}
+	}
+	for _, cidr := range profile.PodCidrs {
+		if cidr != nil {
+			spec.PodCIDRs = append(spec.PodCIDRs, *cidr)
+		}
+	}
+	return nil
+}
+
+func enrichAgentPools(spec *ManagedClusterSpec, resp armcontainerservice.ManagedClustersClientGetResponse) error {
+	if spec == nil {
+		return fmt.Errorf("spec is nil")
+	}
+	if resp.Properties == nil {
+		return nil
+	}
+	for _, profile := range resp.Properties.AgentPoolProfiles {
+		if profile == nil || profile.Name == nil {
+			continue
+		}
+		pool := AgentPoolSpec{Name: *profile.Name}
+		if profile.Mode != nil {
+			pool.Mode = string(*profile.Mode)
+		}
+		for key, value := range profile.NodeLabels {
+			if value != nil {
+				if pool.NodeLabels == nil {
+					pool.NodeLabels = make(map[string]string)
+				}
+				pool.NodeLabels[key] = *value
+			}
+		}
+		for _, taint := range profile.NodeTaints {
+			if taint != nil {
+				pool.NodeTaints = append(pool.NodeTaints, *taint)
+			}
+		}
+		spec.AgentPools = append(spec.AgentPools, pool)
+	}
+	return nil
+}
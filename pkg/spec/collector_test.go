@@ -3,10 +3,13 @@ package spec
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v5"
 	"github.com/sirupsen/logrus"
 
@@ -88,3 +91,164 @@ func TestManagedClusterSpecCollector_Collect_MissingClusterInfo(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestManagedClusterSpecCollector_Collect_ClusterState(t *testing.T) {
+	cfg := &config.Config{
+		Azure: config.AzureConfig{
+			SubscriptionID: "sub",
+			TargetCluster: &config.TargetClusterConfig{
+				Name:          "c1",
+				ResourceGroup: "rg1",
+				ResourceID:    "/subscriptions/sub/resourceGroups/rg1/providers/Microsoft.ContainerService/managedClusters/c1",
+			},
+		},
+	}
+	outPath := filepath.Join(t.TempDir(), "managedcluster.json")
+
+	stopped := armcontainerservice.CodeStopped
+	resp := armcontainerservice.ManagedClustersClientGetResponse{
+		ManagedCluster: armcontainerservice.ManagedCluster{
+			Properties: &armcontainerservice.ManagedClusterProperties{
+				KubernetesVersion:        ptr("1.30.1"),
+				CurrentKubernetesVersion: ptr("1.30.9"),
+				Fqdn:                     ptr("c1-12345.hcp.eastus.azmk8s.io"),
+				PowerState:               &armcontainerservice.PowerState{Code: &stopped},
+				ProvisioningState:        ptr("Succeeded"),
+			},
+		},
+	}
+	got, err := NewManagedClusterSpecCollectorWithClient(cfg, logrus.New(), &fakeManagedClusterClient{resp: resp}, outPath).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if state := got.ClusterState(); state != ClusterStateStopped {
+		t.Fatalf("expected cluster state %s, got %s", ClusterStateStopped, state)
+	}
+
+	notFound := &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "ResourceNotFound"}
+	_, err = NewManagedClusterSpecCollectorWithClient(cfg, logrus.New(), &fakeManagedClusterClient{err: notFound}, outPath).Collect(context.Background())
+	if !errors.Is(err, ErrClusterNotFound) {
+		t.Fatalf("expected ErrClusterNotFound, got %v", err)
+	}
+
+	forbidden := &azcore.ResponseError{StatusCode: http.StatusForbidden, ErrorCode: "AuthorizationFailed"}
+	_, err = NewManagedClusterSpecCollectorWithClient(cfg, logrus.New(), &fakeManagedClusterClient{err: forbidden}, outPath).Collect(context.Background())
+	if !errors.Is(err, ErrClusterAccessDenied) || errors.Is(err, ErrClusterNotFound) {
+		t.Fatalf("expected ErrClusterAccessDenied, got %v", err)
+	}
+}
+
+func TestManagedClusterSpecCollector_Collect_PrivateCluster(t *testing.T) {
+	cfg := &config.Config{
+		Azure: config.AzureConfig{
+			SubscriptionID: "sub",
+			TargetCluster: &config.TargetClusterConfig{
+				Name:          "c1",
+				ResourceGroup: "rg1",
+				ResourceID:    "/subscriptions/sub/resourceGroups/rg1/providers/Microsoft.ContainerService/managedClusters/c1",
+			},
+		},
+	}
+	outPath := filepath.Join(t.TempDir(), "managedcluster.json")
+
+	// Private clusters without a public FQDN only report the private one
+	resp := armcontainerservice.ManagedClustersClientGetResponse{
+		ManagedCluster: armcontainerservice.ManagedCluster{
+			Properties: &armcontainerservice.ManagedClusterProperties{
+				KubernetesVersion:        ptr("1.30.1"),
+				CurrentKubernetesVersion: ptr("1.30.9"),
+				PrivateFQDN:              ptr("c1-12345.privatelink.eastus.azmk8s.io"),
+				APIServerAccessProfile: &armcontainerservice.ManagedClusterAPIServerAccessProfile{
+					EnablePrivateCluster: ptr(true),
+					PrivateDNSZone:       ptr("system"),
+				},
+			},
+		},
+	}
+	got, err := NewManagedClusterSpecCollectorWithClient(cfg, logrus.New(), &fakeManagedClusterClient{resp: resp}, outPath).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if !got.PrivateCluster || got.PrivateDNSZone != "system" {
+		t.Fatalf("expected a private cluster with the system DNS zone, got %+v", got)
+	}
+	if fqdn := got.APIServerFQDN(); fqdn != "c1-12345.privatelink.eastus.azmk8s.io" {
+		t.Fatalf("expected private API server FQDN, got %q", fqdn)
+	}
+}
+
+func TestManagedClusterSpecCollector_Collect_DualStack(t *testing.T) {
+	cfg := &config.Config{
+		Azure: config.AzureConfig{
+			SubscriptionID: "sub",
+			TargetCluster: &config.TargetClusterConfig{
+				Name:          "c1",
+				ResourceGroup: "rg1",
+				ResourceID:    "/subscriptions/sub/resourceGroups/rg1/providers/Microsoft.ContainerService/managedClusters/c1",
+			},
+		},
+	}
+	outPath := filepath.Join(t.TempDir(), "managedcluster.json")
+
+	ipv4, ipv6 := armcontainerservice.IPFamilyIPv4, armcontainerservice.IPFamilyIPv6
+	resp := armcontainerservice.ManagedClustersClientGetResponse{
+		ManagedCluster: armcontainerservice.ManagedCluster{
+			Properties: &armcontainerservice.ManagedClusterProperties{
+				KubernetesVersion:        ptr("1.30.1"),
+				CurrentKubernetesVersion: ptr("1.30.9"),
+				Fqdn:                     ptr("c1-12345.hcp.eastus.azmk8s.io"),
+				NetworkProfile: &armcontainerservice.NetworkProfile{
+					IPFamilies: []*armcontainerservice.IPFamily{&ipv4, &ipv6},
+					PodCidrs:   []*string{ptr("10.244.0.0/16"), ptr("fd12:3456:789a::/64")},
+				},
+			},
+		},
+	}
+	got, err := NewManagedClusterSpecCollectorWithClient(cfg, logrus.New(), &fakeManagedClusterClient{resp: resp}, outPath).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if !got.IsDualStack() || len(got.PodCIDRs) != 2 {
+		t.Fatalf("expected a dual-stack cluster with two pod CIDRs, got %+v", got)
+	}
+}
+
+func TestManagedClusterSpecCollector_Collect_AgentPools(t *testing.T) {
+	cfg := &config.Config{
+		Azure: config.AzureConfig{
+			SubscriptionID: "sub",
+			TargetCluster: &config.TargetClusterConfig{
+				Name:          "c1",
+				ResourceGroup: "rg1",
+				ResourceID:    "/subscriptions/sub/resourceGroups/rg1/providers/Microsoft.ContainerService/managedClusters/c1",
+			},
+		},
+	}
+	outPath := filepath.Join(t.TempDir(), "managedcluster.json")
+
+	system, user := armcontainerservice.AgentPoolModeSystem, armcontainerservice.AgentPoolModeUser
+	resp := armcontainerservice.ManagedClustersClientGetResponse{
+		ManagedCluster: armcontainerservice.ManagedCluster{
+			Properties: &armcontainerservice.ManagedClusterProperties{
+				KubernetesVersion:        ptr("1.30.1"),
+				CurrentKubernetesVersion: ptr("1.30.9"),
+				Fqdn:                     ptr("c1-12345.hcp.eastus.azmk8s.io"),
+				AgentPoolProfiles: []*armcontainerservice.ManagedClusterAgentPoolProfile{
+					{Name: ptr("nodepool1"), Mode: &system},
+					{Name: ptr("gpu"), Mode: &user, NodeLabels: map[string]*string{"sku": ptr("gpu")}, NodeTaints: []*string{ptr("sku=gpu:NoSchedule")}},
+				},
+			},
+		},
+	}
+	got, err := NewManagedClusterSpecCollectorWithClient(cfg, logrus.New(), &fakeManagedClusterClient{resp: resp}, outPath).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	pool := got.AgentPool("gpu")
+	if pool == nil || pool.Mode != "User" || pool.NodeLabels["sku"] != "gpu" || len(pool.NodeTaints) != 1 {
+		t.Fatalf("expected the gpu agent pool with its labels and taints, got %+v", got.AgentPools)
+	}
+	if got.AgentPool("missing") != nil {
+		t.Fatalf("expected no agent pool named missing")
+	}
+}
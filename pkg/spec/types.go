@@ -1,12 +1,30 @@
 package spec
 
-import "time"
+import (
+	"errors"
+	"strings"
+	"time"
+)
 
 const (
 	// ManagedClusterSpecSchemaVersion is incremented when the persisted JSON schema changes.
 	ManagedClusterSpecSchemaVersion = 1
 )
 
+// Availability of the target cluster as seen from the node
+const (
+	ClusterStateAvailable = "Available" // The cluster exists and is running
+	ClusterStateStopped   = "Stopped"   // The cluster was stopped; it keeps its nodes' registrations and can be started again
+	ClusterStateDeleted   = "Deleted"   // The cluster resource is gone or being deleted
+)
+
+// ErrClusterNotFound is returned by Collect when the target cluster resource does not exist
+var ErrClusterNotFound = errors.New("managed cluster not found")
+
+// ErrClusterAccessDenied is returned by Collect when reading the target cluster is forbidden. Deleting a
+// cluster also deletes the role assignments scoped to it, so a deleted cluster often answers 403 rather than 404.
+var ErrClusterAccessDenied = errors.New("access to managed cluster denied")
+
 // ManagedClusterSpec is the persisted spec snapshot of the target AKS managed cluster.
 // It is intentionally extensible so we can add more fields over time without rewriting the collector.
 type ManagedClusterSpec struct {
@@ -21,6 +39,71 @@ type ManagedClusterSpec struct {
 	CurrentKubernetesVersion string `json:"currentKubernetesVersion,omitempty"` // "e.g., 1.32.7"
 	Fqdn                     string `json:"fqdn,omitempty"`
 
+	// API server access, used to resolve the API server of private clusters
+	PrivateCluster bool   `json:"privateCluster,omitempty"`
+	PrivateFqdn    string `json:"privateFqdn,omitempty"`
+	PrivateDNSZone string `json:"privateDNSZone,omitempty"` // "system", "none" or the resource ID of a custom private DNS zone
+
+	// Network profile, used to match the node's IP families to the cluster's
+	IPFamilies []string `json:"ipFamilies,omitempty"` // e.g. ["IPv4", "IPv6"] for dual-stack clusters
+	PodCIDRs   []string `json:"podCidrs,omitempty"`
+
+	// Cluster lifecycle, used to detect clusters that were stopped or are being deleted
+	PowerState        string `json:"powerState,omitempty"`        // "Running" or "Stopped"
+	ProvisioningState string `json:"provisioningState,omitempty"` // e.g. "Succeeded", "Deleting"
+
+	// Agent pools, used to associate the node with one
+	AgentPools []AgentPoolSpec `json:"agentPools,omitempty"`
+
 	// metadata
 	CollectedAt time.Time `json:"collectedAt"`
 }
+
+// AgentPoolSpec is an agent pool of the cluster with the labels and taints AKS puts on its nodes
+type AgentPoolSpec struct {
+	Name       string            `json:"name"`
+	Mode       string            `json:"mode,omitempty"` // "System" or "User"
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+	NodeTaints []string          `json:"nodeTaints,omitempty"` // e.g. "sku=gpu:NoSchedule"
+}
+
+// ClusterState derives the availability of the cluster from the spec snapshot
+func (s *ManagedClusterSpec) ClusterState() string {
+	switch {
+	case strings.EqualFold(s.ProvisioningState, "Deleting"):
+		return ClusterStateDeleted
+	case strings.EqualFold(s.PowerState, "Stopped"):
+		return ClusterStateStopped
+	default:
+		return ClusterStateAvailable
+	}
+}
+
+// IsDualStack reports whether the cluster assigns both IPv4 and IPv6 addresses
+func (s *ManagedClusterSpec) IsDualStack() bool {
+	var ipv4, ipv6 bool
+	for _, family := range s.IPFamilies {
+		ipv4 = ipv4 || strings.EqualFold(family, "IPv4")
+		ipv6 = ipv6 || strings.EqualFold(family, "IPv6")
+	}
+	return ipv4 && ipv6
+}
+
+// AgentPool returns the agent pool of the given name, or nil when the cluster has none
+func (s *ManagedClusterSpec) AgentPool(name string) *AgentPoolSpec {
+	for i := range s.AgentPools {
+		if s.AgentPools[i].Name == name {
+			return &s.AgentPools[i]
+		}
+	}
+	return nil
+}
+
+// APIServerFQDN returns the FQDN nodes reach the API server at: the private FQDN of private clusters,
+// the public FQDN otherwise
+func (s *ManagedClusterSpec) APIServerFQDN() string {
+	if s.PrivateCluster && s.PrivateFqdn != "" {
+		return s.PrivateFqdn
+	}
+	return s.Fqdn
+}
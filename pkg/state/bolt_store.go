@@ -0,0 +1,99 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// boltFileName is the database file created inside the configured state path
+	boltFileName = "state.db"
+	// boltBucket holds all agent state keys
+	boltBucket = "aks-flex-node"
+)
+
+// BoltStore stores state in an embedded bbolt database file
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (or creates) the bbolt database at path
+func NewBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory for %s: %w", path, err)
+	}
+
+	// A short lock timeout avoids hanging when another agent process holds the database
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database %s: %w", path, err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(boltBucket))
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize state database %s: %w", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Get returns the value stored under key
+func (s *BoltStore) Get(key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(boltBucket)).Get([]byte(key))
+		if v == nil {
+			return ErrNotFound
+		}
+		// Values are only valid inside the transaction, so copy them out
+		value = append([]byte(nil), v...)
+		return nil
+	})
+	return value, err
+}
+
+// Put stores value under key
+func (s *BoltStore) Put(key string, value []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(boltBucket)).Put([]byte(key), value)
+	})
+}
+
+// Delete removes key
+func (s *BoltStore) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(boltBucket)).Delete([]byte(key))
+	})
+}
+
+// Keys returns all stored keys in sorted order
+func (s *BoltStore) Keys() ([]string, error) {
+	var keys []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(boltBucket)).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+// Close closes the underlying database
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
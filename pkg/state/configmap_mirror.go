@@ -0,0 +1,209 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+const (
+	// mirrorNamespace is the namespace holding per-node state ConfigMaps
+	mirrorNamespace = "kube-system"
+	// mirrorNamePrefix prefixes the per-node ConfigMap name
+	mirrorNamePrefix = "aks-flex-node-state-"
+	// mirrorTimeout bounds each kubectl call made by the mirror
+	mirrorTimeout = 30 * time.Second
+)
+
+// restoreAttempted records whether a mirror of this process looked for a ConfigMap to restore. The state store
+// is opened for every checkpoint, and each lookup may take up to mirrorTimeout, so it is only done once.
+var restoreAttempted struct {
+	sync.Mutex
+	done bool
+}
+
+// ConfigMapMirror wraps a local store and mirrors its full contents into a per-node
+// ConfigMap in the cluster. The local store stays authoritative: mirror failures are
+// logged and never fail a write, and the ConfigMap is only used to repopulate an empty
+// local store (for example after the node's disk was replaced).
+type ConfigMapMirror struct {
+	Store
+	kubeconfig string
+	name       string
+	logger     *logrus.Logger
+}
+
+// NewConfigMapMirror wraps store with a ConfigMap mirror using the given kubeconfig, which must be allowed to
+// get, create and patch ConfigMaps in kube-system
+func NewConfigMapMirror(store Store, kubeconfig string, logger *logrus.Logger) *ConfigMapMirror {
+	nodeName, err := utilhost.NodeName()
+	if err != nil {
+		nodeName = "unknown"
+	}
+	m := &ConfigMapMirror{
+		Store:      store,
+		kubeconfig: kubeconfig,
+		name:       mirrorNamePrefix + nodeName,
+		logger:     logger,
+	}
+	m.restoreIfEmpty()
+	return m
+}
+
+// Put stores value locally and refreshes the cluster mirror
+func (m *ConfigMapMirror) Put(key string, value []byte) error {
+	if err := m.Store.Put(key, value); err != nil {
+		return err
+	}
+	m.sync()
+	return nil
+}
+
+// Delete removes key locally and refreshes the cluster mirror
+func (m *ConfigMapMirror) Delete(key string) error {
+	if err := m.Store.Delete(key); err != nil {
+		return err
+	}
+	m.sync()
+	return nil
+}
+
+// configMap is the minimal ConfigMap representation applied with kubectl
+type configMap struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   configMapMeta     `json:"metadata"`
+	Data       map[string]string `json:"data,omitempty"`
+	BinaryData map[string][]byte `json:"binaryData,omitempty"`
+}
+
+type configMapMeta struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// sync applies the full local store contents to the cluster ConfigMap
+func (m *ConfigMapMirror) sync() {
+	if _, err := os.Stat(m.kubeconfig); err != nil {
+		m.logger.Debugf("Skipping state mirror: kubeconfig %s not available yet", m.kubeconfig)
+		return
+	}
+
+	cm, err := m.buildConfigMap()
+	if err != nil {
+		m.logger.Warnf("Failed to build state mirror ConfigMap: %v", err)
+		return
+	}
+	manifest, err := json.Marshal(cm)
+	if err != nil {
+		m.logger.Warnf("Failed to encode state mirror ConfigMap: %v", err)
+		return
+	}
+	if _, err := m.kubectl(manifest, "apply", "-f", "-"); err != nil {
+		m.logger.Warnf("Failed to mirror state to ConfigMap %s/%s: %v", mirrorNamespace, m.name, err)
+	}
+}
+
+// buildConfigMap converts every key in the local store into ConfigMap data
+func (m *ConfigMapMirror) buildConfigMap() (*configMap, error) {
+	keys, err := m.Store.Keys()
+	if err != nil {
+		return nil, err
+	}
+	cm := &configMap{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata: configMapMeta{
+			Name:      m.name,
+			Namespace: mirrorNamespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "aks-flex-node"},
+		},
+		Data:       map[string]string{},
+		BinaryData: map[string][]byte{},
+	}
+	for _, key := range keys {
+		value, err := m.Store.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if utf8.Valid(value) {
+			cm.Data[key] = string(value)
+		} else {
+			cm.BinaryData[key] = value
+		}
+	}
+	return cm, nil
+}
+
+// restoreIfEmpty repopulates an empty local store from the cluster ConfigMap, once per process
+func (m *ConfigMapMirror) restoreIfEmpty() {
+	keys, err := m.Store.Keys()
+	if err != nil || len(keys) > 0 {
+		return
+	}
+	if _, err := os.Stat(m.kubeconfig); err != nil {
+		return
+	}
+	restoreAttempted.Lock()
+	defer restoreAttempted.Unlock()
+	if restoreAttempted.done {
+		return
+	}
+	restoreAttempted.done = true
+
+	output, err := m.kubectl(nil, "get", "configmap", m.name, "-n", mirrorNamespace, "-o", "json", "--ignore-not-found")
+	if err != nil || len(bytes.TrimSpace(output)) == 0 {
+		m.logger.Debugf("No state mirror to restore from: %v", err)
+		return
+	}
+	var cm configMap
+	if err := json.Unmarshal(output, &cm); err != nil {
+		m.logger.Warnf("Failed to parse state mirror ConfigMap: %v", err)
+		return
+	}
+	for key, value := range cm.Data {
+		if err := m.Store.Put(key, []byte(value)); err != nil {
+			m.logger.Warnf("Failed to restore state key %s: %v", key, err)
+		}
+	}
+	for key, value := range cm.BinaryData {
+		if err := m.Store.Put(key, value); err != nil {
+			m.logger.Warnf("Failed to restore state key %s: %v", key, err)
+		}
+	}
+	m.logger.Infof("Restored %d state keys from ConfigMap %s/%s", len(cm.Data)+len(cm.BinaryData), mirrorNamespace, m.name)
+}
+
+// kubectl runs kubectl against the cluster with the mirror kubeconfig
+func (m *ConfigMapMirror) kubectl(stdin []byte, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorTimeout)
+	defer cancel()
+	return runKubectl(ctx, stdin, append([]string{"--kubeconfig", m.kubeconfig}, args...)...)
+}
+
+// runKubectl runs kubectl with stdin and returns its output, a variable so tests can replace it
+var runKubectl = func(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("kubectl %s failed: %w: %s", args[2], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
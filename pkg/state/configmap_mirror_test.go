@@ -0,0 +1,151 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeKubectl records the kubectl calls of the mirror and answers get with a ConfigMap
+type fakeKubectl struct {
+	calls   [][]string
+	applied []configMap
+	stored  *configMap
+}
+
+func (f *fakeKubectl) run(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, args)
+	switch args[2] {
+	case "apply":
+		var cm configMap
+		if err := json.Unmarshal(stdin, &cm); err != nil {
+			return nil, err
+		}
+		f.applied = append(f.applied, cm)
+	case "get":
+		if f.stored != nil {
+			return json.Marshal(f.stored)
+		}
+	}
+	return nil, nil
+}
+
+func newTestMirror(t *testing.T, kubectl *fakeKubectl, kubeconfigExists bool) (*ConfigMapMirror, Store) {
+	t.Helper()
+	original := runKubectl
+	t.Cleanup(func() {
+		runKubectl = original
+		restoreAttempted.done = false
+	})
+	runKubectl = kubectl.run
+	restoreAttempted.done = false
+
+	dir := t.TempDir()
+	kubeconfig := filepath.Join(dir, "mirror.kubeconfig")
+	if kubeconfigExists {
+		if err := os.WriteFile(kubeconfig, []byte("apiVersion: v1\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	local, err := NewFileStore(filepath.Join(dir, "state"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewConfigMapMirror(local, kubeconfig, logger), local
+}
+
+func TestConfigMapMirrorSync(t *testing.T) {
+	kubectl := &fakeKubectl{}
+	mirror, _ := newTestMirror(t, kubectl, true)
+
+	if err := mirror.Put("last-bootstrap", []byte(`{"success":true}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mirror.Put("blob", []byte{0xff, 0xfe}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mirror.Delete("last-bootstrap"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(kubectl.applied) != 3 {
+		t.Fatalf("applied %d ConfigMaps, want one per write", len(kubectl.applied))
+	}
+	for _, call := range kubectl.calls {
+		if call[0] != "--kubeconfig" || call[1] != mirror.kubeconfig {
+			t.Errorf("kubectl %v does not use the mirror kubeconfig", call)
+		}
+	}
+	second := kubectl.applied[1]
+	if second.Metadata.Namespace != mirrorNamespace || !strings.HasPrefix(second.Metadata.Name, mirrorNamePrefix) {
+		t.Errorf("ConfigMap %s/%s, want %s/%s<node>", second.Metadata.Namespace, second.Metadata.Name, mirrorNamespace, mirrorNamePrefix)
+	}
+	if second.Data["last-bootstrap"] != `{"success":true}` || !reflect.DeepEqual(second.BinaryData["blob"], []byte{0xff, 0xfe}) {
+		t.Errorf("ConfigMap data = %v, binaryData = %v, want text in data and bytes in binaryData", second.Data, second.BinaryData)
+	}
+	if _, ok := kubectl.applied[2].Data["last-bootstrap"]; ok {
+		t.Error("deleted key is still mirrored")
+	}
+}
+
+func TestConfigMapMirrorSkipsWithoutKubeconfig(t *testing.T) {
+	kubectl := &fakeKubectl{}
+	mirror, local := newTestMirror(t, kubectl, false)
+
+	if err := mirror.Put("last-bootstrap", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(kubectl.calls) != 0 {
+		t.Errorf("kubectl called %v before the kubeconfig exists", kubectl.calls)
+	}
+	if _, err := local.Get("last-bootstrap"); err != nil {
+		t.Errorf("local store Get() error = %v, want the value written", err)
+	}
+}
+
+func TestConfigMapMirrorRestoresEmptyStore(t *testing.T) {
+	kubectl := &fakeKubectl{stored: &configMap{
+		Data:       map[string]string{"history": `[]`},
+		BinaryData: map[string][]byte{"blob": {0xff}},
+	}}
+	_, local := newTestMirror(t, kubectl, true)
+
+	keys, err := local.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"blob", "history"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("restored keys = %v, want %v", keys, want)
+	}
+	if len(kubectl.applied) != 0 {
+		t.Error("restoring applied the ConfigMap back")
+	}
+}
+
+func TestConfigMapMirrorRestoresOncePerProcess(t *testing.T) {
+	kubectl := &fakeKubectl{}
+	mirror, _ := newTestMirror(t, kubectl, true)
+
+	// The store is opened again for every checkpoint while it is still empty
+	NewConfigMapMirror(mirror.Store, mirror.kubeconfig, mirror.logger)
+	NewConfigMapMirror(mirror.Store, mirror.kubeconfig, mirror.logger)
+
+	gets := 0
+	for _, call := range kubectl.calls {
+		if call[2] == "get" {
+			gets++
+		}
+	}
+	if gets != 1 {
+		t.Errorf("looked for the state mirror %d times, want once per process", gets)
+	}
+}
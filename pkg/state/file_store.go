@@ -0,0 +1,91 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// fileSuffix is appended to keys to form file names in the state directory
+const fileSuffix = ".state"
+
+// FileStore stores each key as a separate file in a directory. Writes are atomic.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a file-backed store rooted at dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory %s: %w", dir, err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Get returns the value stored under key
+func (s *FileStore) Get(key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state key %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Put stores value under key
+func (s *FileStore) Put(key string, value []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	// Not through utilio.WriteFile: state is never recorded in the installed files manifest, which is kept here
+	if err := utilio.ReplaceFile(s.path(key), value, 0o600); err != nil {
+		return fmt.Errorf("failed to write state key %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes key
+func (s *FileStore) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete state key %s: %w", key, err)
+	}
+	return nil
+}
+
+// Keys returns all stored keys in sorted order
+func (s *FileStore) Keys() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list state directory %s: %w", s.dir, err)
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), fileSuffix) {
+			keys = append(keys, strings.TrimSuffix(entry.Name(), fileSuffix))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Close is a no-op for the file store
+func (s *FileStore) Close() error {
+	return nil
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, key+fileSuffix)
+}
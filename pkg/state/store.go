@@ -0,0 +1,71 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// ErrNotFound is returned when a key does not exist in the store
+var ErrNotFound = errors.New("state key not found")
+
+// keyPattern restricts keys to names that are safe as file names and ConfigMap data keys
+var keyPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Store persists agent state such as checkpoints, the state bag and audit metadata.
+// Values are opaque byte slices; callers typically store JSON documents.
+type Store interface {
+	// Get returns the value stored under key, or ErrNotFound
+	Get(key string) ([]byte, error)
+
+	// Put stores value under key, replacing any existing value
+	Put(key string, value []byte) error
+
+	// Delete removes key; deleting a missing key is not an error
+	Delete(key string) error
+
+	// Keys returns all keys currently stored
+	Keys() ([]string, error)
+
+	// Close releases resources held by the store
+	Close() error
+}
+
+// Open creates the state store selected by the agent configuration, wrapping it
+// with a cluster ConfigMap mirror when configured.
+func Open(cfg *config.Config, logger *logrus.Logger) (Store, error) {
+	store, err := OpenLocal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if storeCfg := cfg.Agent.StateStore; storeCfg.ConfigMapMirror {
+		store = NewConfigMapMirror(store, storeCfg.MirrorKubeconfig, logger)
+	}
+	return store, nil
+}
+
+// OpenLocal creates the state store selected by the agent configuration without the ConfigMap mirror, for
+// state written many times during a run. The next write through Open mirrors it along with everything else.
+func OpenLocal(cfg *config.Config) (Store, error) {
+	storeCfg := cfg.Agent.StateStore
+	switch storeCfg.Type {
+	case config.StateStoreTypeFile:
+		return NewFileStore(storeCfg.Path)
+	case config.StateStoreTypeBolt:
+		return NewBoltStore(filepath.Join(storeCfg.Path, boltFileName))
+	default:
+		return nil, fmt.Errorf("unsupported state store type: %s", storeCfg.Type)
+	}
+}
+
+// validateKey checks that a key can be stored by every implementation
+func validateKey(key string) error {
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("invalid state key %q: keys must match %s", key, keyPattern.String())
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package state
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStoreImplementations(t *testing.T) {
+	implementations := map[string]func(t *testing.T) Store{
+		"file": func(t *testing.T) Store {
+			s, err := NewFileStore(filepath.Join(t.TempDir(), "state"))
+			if err != nil {
+				t.Fatalf("NewFileStore() error = %v", err)
+			}
+			return s
+		},
+		"bolt": func(t *testing.T) Store {
+			s, err := NewBoltStore(filepath.Join(t.TempDir(), boltFileName))
+			if err != nil {
+				t.Fatalf("NewBoltStore() error = %v", err)
+			}
+			return s
+		},
+	}
+
+	for name, newStore := range implementations {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			defer func() { _ = store.Close() }()
+
+			if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() on missing key error = %v, want ErrNotFound", err)
+			}
+
+			if err := store.Put("last-bootstrap", []byte(`{"success":true}`)); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if err := store.Put("history", []byte(`[]`)); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			got, err := store.Get("last-bootstrap")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if string(got) != `{"success":true}` {
+				t.Errorf("Get() = %s, want stored value", got)
+			}
+
+			keys, err := store.Keys()
+			if err != nil {
+				t.Fatalf("Keys() error = %v", err)
+			}
+			if want := []string{"history", "last-bootstrap"}; !reflect.DeepEqual(keys, want) {
+				t.Errorf("Keys() = %v, want %v", keys, want)
+			}
+
+			if err := store.Delete("history"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := store.Delete("history"); err != nil {
+				t.Errorf("Delete() of missing key error = %v, want nil", err)
+			}
+			if _, err := store.Get("history"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "last-bootstrap", wantErr: false},
+		{key: "manifest.v1", wantErr: false},
+		{key: "", wantErr: true},
+		{key: "../escape", wantErr: true},
+		{key: "nested/key", wantErr: true},
+		{key: ".hidden", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if err := validateKey(tt.key); (err != nil) != tt.wantErr {
+				t.Errorf("validateKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
 	"go.goms.io/aks/AKSFlexNode/pkg/config"
 	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
 )
 
 // Collector collects system and node status information
@@ -49,6 +50,11 @@ func (c *Collector) CollectStatus(ctx context.Context) (*NodeStatus, error) {
 	// check if containerd is running, it will cause kubelet not ready
 	status.ContainerdRunning = utils.IsServiceActive("containerd")
 
+	// Watch that kubelet keeps renewing its serving certificate
+	if c.config != nil && c.config.Node.Kubelet.ServerTLSBootstrap {
+		status.KubeletServingCertExpiry = c.checkServingCertificate()
+	}
+
 	// Get runc version
 	status.RuncVersion = c.getRuncVersion(ctx)
 
@@ -62,6 +68,22 @@ func (c *Collector) CollectStatus(ctx context.Context) (*NodeStatus, error) {
 	return status, nil
 }
 
+// checkServingCertificate returns the expiry of kubelet's serving certificate, warning when kubelet has none or
+// has not renewed it in time, which usually means its renewal request waits for approval
+func (c *Collector) checkServingCertificate() *time.Time {
+	cert, err := kubelet.ServingCertificate()
+	if err != nil {
+		c.logger.Warnf("kubelet has no serving certificate from the cluster CA: %v", err)
+		return nil
+	}
+	if kubelet.RenewalOverdue(cert, time.Now()) {
+		c.logger.Warnf("kubelet has not renewed its serving certificate, which expires at %s; check for pending requests with kubectl get csr",
+			cert.NotAfter.Format(time.RFC3339))
+	}
+	expiry := cert.NotAfter
+	return &expiry
+}
+
 // getKubeletVersion gets the kubelet version
 func (c *Collector) getKubeletVersion(ctx context.Context) string {
 	output, err := c.runCommand(ctx, "/usr/local/bin/kubelet", "--version")
@@ -194,9 +216,9 @@ func (c *Collector) parseArcShowOutput(status *ArcStatus, output string) {
 
 // isKubeletReady checks if the kubelet reports the node as Ready
 func (c *Collector) isKubeletReady(ctx context.Context) string {
-	hostName, err := os.Hostname()
+	nodeName, err := utilhost.NodeName()
 	if err != nil {
-		c.logger.Warnf("Failed to get hostname: %v", err)
+		c.logger.Warnf("Failed to get node name: %v", err)
 		return "Unknown"
 	}
 
@@ -206,7 +228,7 @@ func (c *Collector) isKubeletReady(ctx context.Context) string {
 		kubelet.KubeletKubeconfigPath,
 		"get",
 		"node",
-		hostName,
+		nodeName,
 		"-o",
 		"jsonpath={.status.conditions[?(@.type==\"Ready\")].status}",
 	}
@@ -17,9 +17,15 @@ type NodeStatus struct {
 
 	ContainerdRunning bool `json:"containerdRunning"`
 
+	// Expiry of kubelet's serving certificate from the cluster CA, with node.kubelet.serverTLSBootstrap
+	KubeletServingCertExpiry *time.Time `json:"kubeletServingCertExpiry,omitempty"`
+
 	// Azure Arc status
 	ArcStatus ArcStatus `json:"arcStatus"`
 
+	// Availability of the target cluster (Available, Stopped or Deleted), empty until first checked
+	ClusterState string `json:"clusterState,omitempty"`
+
 	// Metadata
 	LastUpdated  time.Time `json:"lastUpdated"`
 	AgentVersion string    `json:"agentVersion"`
@@ -0,0 +1,228 @@
+package tokenbroker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/auth"
+	"go.goms.io/aks/AKSFlexNode/pkg/components/kubelet"
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+const (
+	// tokenPath is the HTTP path serving ExecCredential responses on the broker socket
+	tokenPath = "/token"
+
+	// Backoff bounds used when refreshing a token fails (e.g. during an IMDS outage)
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = 1 * time.Minute
+)
+
+// Broker caches AKS AAD tokens for the node identity and serves them to kubelet's exec
+// credential plugin. Concurrent callers share a single in-flight token request, and a
+// still-valid cached token keeps being served while IMDS or HIMDS is unavailable.
+type Broker struct {
+	credential    azcore.TokenCredential
+	scope         string
+	refreshBefore time.Duration
+	socketPath    string
+	logger        *logrus.Logger
+
+	// fetchMu serializes token requests, so concurrent callers share one, and mu guards token. Readers of a
+	// valid token only take mu, so a refresh stalled on IMDS or HIMDS never blocks them.
+	fetchMu sync.Mutex
+	mu      sync.Mutex
+	token   azcore.AccessToken
+}
+
+// New creates a token broker for the identity selected by the configuration.
+// Arc uses the HIMDS managed identity; managed identity, service principal and federated identity use their configured credentials.
+func New(cfg *config.Config, logger *logrus.Logger) (*Broker, error) {
+	authProvider := auth.NewAuthProvider()
+
+	var (
+		cred azcore.TokenCredential
+		err  error
+	)
+	switch {
+	case cfg.IsARCEnabled():
+		cred, err = authProvider.ArcCredential()
+	case cfg.IsMIConfigured() || cfg.IsSPConfigured() || cfg.IsFederatedIdentityConfigured():
+		// AKS tokens must come from the cluster tenant, which differs from the machine tenant for cross-tenant joins
+		cred, err = authProvider.ClusterCredential(cfg)
+	default:
+		return nil, fmt.Errorf("token broker requires Arc, managed identity, service principal or federated identity authentication")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newBroker(cred, cfg.Agent.TokenBroker, logger), nil
+}
+
+// newBroker creates a broker around an existing credential
+func newBroker(cred azcore.TokenCredential, cfg config.TokenBrokerConfig, logger *logrus.Logger) *Broker {
+	return &Broker{
+		credential:    cred,
+		scope:         kubelet.AKSServiceResourceID + "/.default",
+		refreshBefore: cfg.RefreshBefore,
+		socketPath:    cfg.SocketPath,
+		logger:        logger,
+	}
+}
+
+// Run serves tokens on the broker socket and refreshes the cached token ahead of expiry until ctx is cancelled
+func (b *Broker) Run(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(b.socketPath), 0o750); err != nil {
+		return fmt.Errorf("failed to create token broker socket directory: %w", err)
+	}
+	// Remove a stale socket left behind by a previous agent process
+	if err := os.Remove(b.socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale token broker socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", b.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on token broker socket %s: %w", b.socketPath, err)
+	}
+	// Tokens grant cluster access, so only root (kubelet) may talk to the broker
+	if err := os.Chmod(b.socketPath, 0o600); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("failed to restrict token broker socket permissions: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(tokenPath, b.handleToken)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go b.refreshLoop(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	b.logger.Infof("Token broker listening on %s", b.socketPath)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("token broker server failed: %w", err)
+	}
+	return nil
+}
+
+// Token returns a cached token, fetching a new one only when the cached token is missing or expired
+func (b *Broker) Token(ctx context.Context) (azcore.AccessToken, error) {
+	if token, ok := b.cached(time.Now()); ok {
+		return token, nil
+	}
+
+	b.fetchMu.Lock()
+	defer b.fetchMu.Unlock()
+	// Another caller may have fetched a token while this one waited
+	if token, ok := b.cached(time.Now()); ok {
+		return token, nil
+	}
+	return b.fetch(ctx)
+}
+
+// refreshLoop proactively refreshes the cached token before it expires, backing off on failures.
+// While refreshes fail, Token keeps serving the cached token until it actually expires.
+func (b *Broker) refreshLoop(ctx context.Context) {
+	retryDelay := minRetryDelay
+	for {
+		wait := b.nextRefresh(time.Now())
+		if wait == 0 {
+			b.fetchMu.Lock()
+			_, err := b.fetch(ctx)
+			b.fetchMu.Unlock()
+			if err != nil {
+				b.logger.Warnf("Token broker refresh failed, retrying in %v: %v", retryDelay, err)
+				wait = retryDelay
+				retryDelay = min(retryDelay*2, maxRetryDelay)
+			} else {
+				retryDelay = minRetryDelay
+				// Guard against tokens whose lifetime is shorter than the refresh window
+				wait = max(b.nextRefresh(time.Now()), minRetryDelay)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// nextRefresh returns how long to wait before the cached token should be refreshed
+func (b *Broker) nextRefresh(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token.Token == "" {
+		return 0
+	}
+	return max(b.token.ExpiresOn.Add(-b.refreshBefore).Sub(now), 0)
+}
+
+// cached returns the cached token if it can still be handed out
+func (b *Broker) cached(now time.Time) (azcore.AccessToken, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Keep a small margin so kubelet never receives a token that expires in flight
+	return b.token, b.token.Token != "" && now.Add(time.Minute).Before(b.token.ExpiresOn)
+}
+
+// fetch requests a new token from the credential and caches it. Caller must hold b.fetchMu, but not b.mu,
+// which is only taken to swap the cached token.
+func (b *Broker) fetch(ctx context.Context) (azcore.AccessToken, error) {
+	token, err := b.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{b.scope}})
+	if err != nil {
+		return azcore.AccessToken{}, fmt.Errorf("failed to get token: %w", err)
+	}
+	b.mu.Lock()
+	b.token = token
+	b.mu.Unlock()
+	b.logger.Debugf("Token broker cached new token expiring at %s", token.ExpiresOn.Format(time.RFC3339))
+	return token, nil
+}
+
+// handleToken serves the cached token as a kubelet ExecCredential
+func (b *Broker) handleToken(w http.ResponseWriter, r *http.Request) {
+	token, err := b.Token(r.Context())
+	if err != nil {
+		b.logger.Warnf("Token broker could not serve token: %v", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	expiration := metav1.NewTime(token.ExpiresOn)
+	credential := clientauthv1beta1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ExecCredential",
+			APIVersion: clientauthv1beta1.SchemeGroupVersion.String(),
+		},
+		Status: &clientauthv1beta1.ExecCredentialStatus{
+			ExpirationTimestamp: &expiration,
+			Token:               token.Token,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(credential); err != nil {
+		b.logger.Warnf("Failed to write token broker response: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package tokenbroker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+// fakeCredential returns tokens with a fixed lifetime and counts requests
+type fakeCredential struct {
+	calls    atomic.Int32
+	lifetime time.Duration
+	err      error
+}
+
+func (f *fakeCredential) GetToken(_ context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	f.calls.Add(1)
+	if f.err != nil {
+		return azcore.AccessToken{}, f.err
+	}
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(f.lifetime)}, nil
+}
+
+func newTestBroker(cred azcore.TokenCredential) *Broker {
+	return newBroker(cred, config.TokenBrokerConfig{RefreshBefore: 10 * time.Minute}, logrus.New())
+}
+
+func TestTokenIsCachedAcrossConcurrentCallers(t *testing.T) {
+	cred := &fakeCredential{lifetime: time.Hour}
+	broker := newTestBroker(cred)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := broker.Token(context.Background()); err != nil {
+				t.Errorf("Token() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := cred.calls.Load(); got != 1 {
+		t.Errorf("expected a single token request, got %d", got)
+	}
+}
+
+func TestTokenRefetchesExpiredToken(t *testing.T) {
+	cred := &fakeCredential{lifetime: 30 * time.Second} // inside the one-minute validity margin
+	broker := newTestBroker(cred)
+
+	for range 2 {
+		if _, err := broker.Token(context.Background()); err != nil {
+			t.Fatalf("Token() error = %v", err)
+		}
+	}
+	if got := cred.calls.Load(); got != 2 {
+		t.Errorf("expected expired token to be refetched, got %d requests", got)
+	}
+}
+
+func TestCachedTokenServedWhileRefreshFails(t *testing.T) {
+	cred := &fakeCredential{lifetime: 5 * time.Minute}
+	broker := newTestBroker(cred)
+
+	if _, err := broker.Token(context.Background()); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if wait := broker.nextRefresh(time.Now()); wait != 0 {
+		t.Errorf("token inside the refresh window should be refreshed now, got wait %v", wait)
+	}
+
+	// Simulate an identity endpoint outage: the cached token must still be served
+	cred.err = errors.New("imds unavailable")
+	token, err := broker.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v during outage", err)
+	}
+	if token.Token != "token" {
+		t.Errorf("expected cached token, got %q", token.Token)
+	}
+}
+
+// blockingCredential blocks every token request until release is closed
+type blockingCredential struct {
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingCredential) GetToken(ctx context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	b.once.Do(func() { close(b.started) })
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+	}
+	return azcore.AccessToken{}, errors.New("imds unavailable")
+}
+
+func TestCachedTokenServedWhileRefreshStalls(t *testing.T) {
+	cred := &blockingCredential{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(cred.release)
+	broker := newTestBroker(cred)
+	broker.token = azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(5 * time.Minute)}
+
+	// The cached token is inside the refresh window, so the refresh loop starts a request that hangs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go broker.refreshLoop(ctx)
+	<-cred.started
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if token, err := broker.Token(context.Background()); err != nil || token.Token != "token" {
+			t.Errorf("Token() = %q, %v, want the cached token", token.Token, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Token() blocked behind the stalled refresh")
+	}
+}
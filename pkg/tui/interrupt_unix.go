@@ -0,0 +1,13 @@
+//go:build !windows
+
+package tui
+
+import (
+	"os"
+	"syscall"
+)
+
+// interrupt sends the process the SIGINT Ctrl-C raises outside the view
+func interrupt() {
+	_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package tui
+
+import "golang.org/x/sys/windows"
+
+// interrupt raises the Ctrl-C event of the console, which Go delivers as os.Interrupt like outside the view
+func interrupt() {
+	_ = windows.GenerateConsoleCtrlEvent(windows.CTRL_C_EVENT, 0)
+}
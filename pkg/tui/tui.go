@@ -0,0 +1,381 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/progress"
+)
+
+const (
+	// maxLogLines bounds the log lines kept for scrolling; the log file keeps everything
+	maxLogLines = 5000
+	// refreshInterval redraws the view so the duration of the running step keeps counting
+	refreshInterval = time.Second
+)
+
+// Step states shown in the step list
+const (
+	statePending = "pending"
+	stateRunning = "running"
+)
+
+// stateSymbols marks each step state in the step list
+var stateSymbols = map[string]string{
+	statePending:           "·",
+	stateRunning:           "▶",
+	progress.ResultDone:    "✓",
+	progress.ResultSkipped: "–",
+	progress.ResultFailed:  "✗",
+}
+
+// ansiEscape matches the color and cursor sequences of command output, which would corrupt the log pane
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+type step struct {
+	name     string
+	state    string
+	started  time.Time
+	duration time.Duration
+}
+
+// View is a full-screen terminal view of a bootstrap, upgrade or unbootstrap run: the step list with live
+// status and durations above a scrollable pane with the log. It takes over the terminal when the run starts,
+// so prompts before the run still work, and gives it back when the run finishes.
+type View struct {
+	logger *logrus.Logger
+	out    *os.File // The terminal, kept aside while os.Stdout and os.Stderr feed the log pane
+	in     *os.File
+
+	mu        sync.Mutex
+	active    bool
+	operation string
+	started   time.Time
+	steps     []step
+	logs      []string
+	scroll    int // Log lines scrolled back from the end; 0 follows the log
+
+	restoreState  *term.State
+	restoreOutput io.Writer
+	restoreHooks  logrus.LevelHooks
+	restoreStdout *os.File
+	restoreStderr *os.File
+	pipe          *os.File
+	done          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// New creates a view of the runs reported to reporter. It fails unless stdin and stdout are a terminal.
+func New(logger *logrus.Logger, reporter *progress.Reporter) (*View, error) {
+	if !term.IsTerminal(int(os.Stdout.Fd())) || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("the terminal UI needs stdin and stdout to be a terminal")
+	}
+	v := &View{logger: logger, out: os.Stdout, in: os.Stdin}
+	reporter.Listen(v.handleEvent)
+	return v, nil
+}
+
+// handleEvent updates the step list, taking over the terminal on run_started and giving it back on
+// run_finished
+func (v *View) handleEvent(event progress.Event) {
+	switch event.Event {
+	case progress.EventRunStarted:
+		v.mu.Lock()
+		v.operation = event.Operation
+		v.started = event.Time
+		v.steps = make([]step, 0, len(event.Steps))
+		for _, name := range event.Steps {
+			v.steps = append(v.steps, step{name: name, state: statePending})
+		}
+		v.mu.Unlock()
+		if err := v.start(); err != nil {
+			v.logger.Warnf("Failed to start the terminal UI, logging to the console instead: %v", err)
+		}
+	case progress.EventStepStarted, progress.EventStepFinished:
+		v.mu.Lock()
+		if i := event.Index - 1; i >= 0 && i < len(v.steps) {
+			if event.Event == progress.EventStepStarted {
+				v.steps[i].state = stateRunning
+				v.steps[i].started = event.Time
+			} else {
+				v.steps[i].state = event.Result
+				v.steps[i].duration = time.Duration(event.Duration * float64(time.Second))
+			}
+		}
+		v.mu.Unlock()
+		v.draw()
+	case progress.EventRunFinished:
+		v.Stop()
+	}
+}
+
+// start switches the terminal to the view: raw input, the alternate screen, and the log and command output
+// redirected into the log pane
+func (v *View) start() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.active {
+		return nil
+	}
+	state, err := term.MakeRaw(int(v.in.Fd()))
+	if err != nil {
+		return err
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		_ = term.Restore(int(v.in.Fd()), state)
+		return err
+	}
+
+	v.restoreState = state
+	v.pipe = writer
+	v.done = make(chan struct{})
+	v.active = true
+
+	// Commands run by the steps write to os.Stdout and os.Stderr, which now lead to the log pane
+	v.restoreStdout, v.restoreStderr = os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = writer, writer
+	v.restoreOutput = v.logger.Out
+	if v.logger.Out == v.restoreStdout || v.logger.Out == v.restoreStderr {
+		v.logger.SetOutput(io.Discard)
+	}
+	v.restoreHooks = v.logger.ReplaceHooks(copyHooks(v.logger.Hooks))
+	v.logger.AddHook(&logHook{view: v})
+
+	_, _ = fmt.Fprint(v.out, "\x1b[?1049h\x1b[?25l")
+	v.wg.Add(2)
+	go v.readOutput(reader)
+	go v.refresh()
+	go v.readKeys(v.done)
+	return nil
+}
+
+// Stop gives the terminal back. It is safe to call more than once and before the run started.
+func (v *View) Stop() {
+	v.mu.Lock()
+	if !v.active {
+		v.mu.Unlock()
+		return
+	}
+	v.active = false
+	close(v.done)
+	os.Stdout, os.Stderr = v.restoreStdout, v.restoreStderr
+	v.logger.SetOutput(v.restoreOutput)
+	v.logger.ReplaceHooks(v.restoreHooks)
+	_ = v.pipe.Close()
+	v.mu.Unlock()
+
+	v.wg.Wait()
+	_, _ = fmt.Fprint(v.out, "\x1b[?25h\x1b[?1049l")
+	_ = term.Restore(int(v.in.Fd()), v.restoreState)
+}
+
+// readOutput moves the output of commands into the log pane
+func (v *View) readOutput(reader *os.File) {
+	defer v.wg.Done()
+	defer func() { _ = reader.Close() }()
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		v.appendLog(ansiEscape.ReplaceAllString(scanner.Text(), ""))
+	}
+}
+
+// readKeys scrolls the log pane. Ctrl-C interrupts the run like it does outside the view, where raw input
+// would otherwise swallow it. A terminal read cannot be interrupted, so the reader only notices that the view
+// stopped with the next key, which the process usually does not live to see.
+func (v *View) readKeys(done <-chan struct{}) {
+	buf := make([]byte, 16)
+	for {
+		n, err := v.in.Read(buf)
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if err != nil {
+			return
+		}
+		key := string(buf[:n])
+		if key == "\x03" {
+			interrupt()
+			continue
+		}
+		_, height := v.size()
+		page := max(height/2, 1)
+		v.mu.Lock()
+		switch key {
+		case "\x1b[A", "k":
+			v.scroll++
+		case "\x1b[B", "j":
+			v.scroll--
+		case "\x1b[5~":
+			v.scroll += page
+		case "\x1b[6~":
+			v.scroll -= page
+		case "\x1b[F", "\x1b[4~", "G":
+			v.scroll = 0
+		}
+		v.scroll = min(max(v.scroll, 0), max(len(v.logs)-1, 0))
+		v.mu.Unlock()
+		v.draw()
+	}
+}
+
+// refresh redraws the view periodically
+func (v *View) refresh() {
+	defer v.wg.Done()
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-v.done:
+			return
+		case <-ticker.C:
+			v.draw()
+		}
+	}
+}
+
+// appendLog adds a line to the log pane, keeping the view where it is when scrolled back
+func (v *View) appendLog(line string) {
+	v.mu.Lock()
+	v.logs = append(v.logs, line)
+	if len(v.logs) > maxLogLines {
+		v.logs = v.logs[len(v.logs)-maxLogLines:]
+	}
+	if v.scroll > 0 {
+		v.scroll = min(v.scroll+1, len(v.logs)-1)
+	}
+	v.mu.Unlock()
+	v.draw()
+}
+
+// size returns the terminal size, falling back to 80x24
+func (v *View) size() (int, int) {
+	width, height, err := term.GetSize(int(v.out.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// draw renders the view to the terminal
+func (v *View) draw() {
+	width, height := v.size()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.active {
+		return
+	}
+	lines := v.render(time.Now(), width, height)
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString("\x1b[2K")
+		sb.WriteString(line)
+	}
+	sb.WriteString("\x1b[J")
+	_, _ = v.out.WriteString(sb.String())
+}
+
+// render lays out the view: a header, the step list around the running step, a separator and the end of the
+// log, or the part scrolled to. It is called with the lock held.
+func (v *View) render(now time.Time, width, height int) []string {
+	finished, current := 0, -1
+	for i, s := range v.steps {
+		switch s.state {
+		case statePending:
+		case stateRunning:
+			current = i
+		default:
+			finished++
+			if current < 0 || v.steps[current].state != stateRunning {
+				current = i
+			}
+		}
+	}
+	header := fmt.Sprintf(" AKS Flex Node %s  %d/%d steps  %d%%  elapsed %s", v.operation, finished, len(v.steps),
+		progress.Percent(finished, len(v.steps)), now.Sub(v.started).Round(time.Second))
+	lines := []string{"\x1b[7m" + pad(header, width) + "\x1b[0m"}
+
+	// The step list takes up to half of the screen, scrolled so the current step stays visible
+	listHeight := min(len(v.steps), max((height-2)/2, 1))
+	first := min(max(current-listHeight/2, 0), len(v.steps)-listHeight)
+	for _, s := range v.steps[first : first+listHeight] {
+		duration := ""
+		switch s.state {
+		case stateRunning:
+			duration = now.Sub(s.started).Round(time.Second).String()
+		case statePending:
+		default:
+			duration = s.duration.Round(100 * time.Millisecond).String()
+		}
+		lines = append(lines, truncate(fmt.Sprintf(" %s %-40s %10s", stateSymbols[s.state], s.name, duration), width))
+	}
+
+	title := "─ Log (↑/↓ PgUp/PgDn scroll, End follows, Ctrl-C aborts) "
+	if v.scroll > 0 {
+		title = fmt.Sprintf("─ Log, %d lines back (End follows) ", v.scroll)
+	}
+	lines = append(lines, truncate(title+strings.Repeat("─", max(width-len([]rune(title)), 0)), width))
+
+	logHeight := max(height-len(lines), 0)
+	end := len(v.logs) - v.scroll
+	for _, line := range v.logs[max(end-logHeight, 0):end] {
+		lines = append(lines, truncate(line, width))
+	}
+	return lines
+}
+
+// truncate cuts a line to the terminal width
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) <= width {
+		return line
+	}
+	return string(runes[:width])
+}
+
+// pad fills a line to the terminal width
+func pad(line string, width int) string {
+	line = truncate(line, width)
+	return line + strings.Repeat(" ", max(width-len([]rune(line)), 0))
+}
+
+// logHook copies log entries into the log pane
+type logHook struct {
+	view *View
+}
+
+func (h *logHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logHook) Fire(entry *logrus.Entry) error {
+	line := fmt.Sprintf("%s %-5s %s", entry.Time.Format("15:04:05"), strings.ToUpper(entry.Level.String()), entry.Message)
+	for part := range strings.Lines(line) {
+		h.view.appendLog(strings.TrimRight(part, "\n"))
+	}
+	return nil
+}
+
+// copyHooks returns a copy of the logger hooks so adding a hook does not modify the original set
+func copyHooks(hooks logrus.LevelHooks) logrus.LevelHooks {
+	copied := make(logrus.LevelHooks, len(hooks))
+	for level, levelHooks := range hooks {
+		copied[level] = append([]logrus.Hook(nil), levelHooks...)
+	}
+	return copied
+}
@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/progress"
+)
+
+func TestHandleEventUpdatesSteps(t *testing.T) {
+	start := time.Now()
+	v := &View{logger: logrus.New()}
+	v.mu.Lock()
+	v.operation = "bootstrap"
+	v.started = start
+	v.steps = []step{{name: "a", state: statePending}, {name: "b", state: statePending}}
+	v.mu.Unlock()
+
+	v.handleEvent(progress.Event{Event: progress.EventStepStarted, Time: start, Step: "a", Index: 1})
+	if v.steps[0].state != stateRunning || !v.steps[0].started.Equal(start) {
+		t.Fatalf("step a = %+v, want running since %v", v.steps[0], start)
+	}
+	v.handleEvent(progress.Event{Event: progress.EventStepFinished, Step: "a", Index: 1, Result: progress.ResultDone, Duration: 1.5})
+	if v.steps[0].state != progress.ResultDone || v.steps[0].duration != 1500*time.Millisecond {
+		t.Fatalf("step a = %+v, want done after 1.5s", v.steps[0])
+	}
+	// Events for steps outside the list are ignored
+	v.handleEvent(progress.Event{Event: progress.EventStepStarted, Step: "c", Index: 3})
+	if v.steps[1].state != statePending {
+		t.Fatalf("step b = %+v, want pending", v.steps[1])
+	}
+	// Stopping a view that never took over the terminal does nothing
+	v.handleEvent(progress.Event{Event: progress.EventRunFinished})
+	v.Stop()
+}
+
+func TestRender(t *testing.T) {
+	now := time.Now()
+	v := &View{
+		operation: "bootstrap",
+		started:   now.Add(-90 * time.Second),
+		steps: []step{
+			{name: "first", state: progress.ResultDone, duration: 2 * time.Second},
+			{name: "second", state: progress.ResultSkipped},
+			{name: "third", state: stateRunning, started: now.Add(-5 * time.Second)},
+			{name: "fourth", state: statePending},
+		},
+	}
+	for i := range 30 {
+		v.logs = append(v.logs, fmt.Sprintf("line %d", i))
+	}
+
+	lines := v.render(now, 60, 12)
+	if len(lines) != 12 {
+		t.Fatalf("render returned %d lines, want 12:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[0], "bootstrap  2/4 steps  50%  elapsed 1m30s") {
+		t.Errorf("header = %q", lines[0])
+	}
+	// The step list takes half of the 10 lines below the header and separator
+	want := []struct {
+		symbol, name, duration string
+	}{
+		{"✓", "first", "2s"},
+		{"–", "second", "0s"},
+		{"▶", "third", "5s"},
+		{"·", "fourth", ""},
+	}
+	for i, w := range want {
+		fields := strings.Fields(lines[1+i])
+		if fields[0] != w.symbol || fields[1] != w.name || (w.duration != "" && fields[2] != w.duration) {
+			t.Errorf("step line %d = %q, want %s %s %s", i, lines[1+i], w.symbol, w.name, w.duration)
+		}
+	}
+	if !strings.HasPrefix(lines[5], "─ Log") || len([]rune(lines[5])) != 60 {
+		t.Errorf("separator = %q", lines[5])
+	}
+	if lines[6] != "line 24" || lines[11] != "line 29" {
+		t.Errorf("log pane = %q, want lines 24 to 29", lines[6:])
+	}
+
+	v.scroll = 10
+	lines = v.render(now, 60, 12)
+	if !strings.Contains(lines[5], "10 lines back") || lines[11] != "line 19" {
+		t.Errorf("scrolled log pane = %q, want to end at line 19", lines[5:])
+	}
+
+	// Narrow terminals truncate every line
+	for _, line := range v.render(now, 10, 12) {
+		if visible := strings.TrimSuffix(strings.TrimPrefix(line, "\x1b[7m"), "\x1b[0m"); len([]rune(visible)) > 10 {
+			t.Errorf("line %q is wider than 10 columns", line)
+		}
+	}
+}
+
+func TestRenderKeepsCurrentStepVisible(t *testing.T) {
+	now := time.Now()
+	v := &View{started: now}
+	for i := range 20 {
+		state := progress.ResultDone
+		if i == 15 {
+			state = stateRunning
+		} else if i > 15 {
+			state = statePending
+		}
+		v.steps = append(v.steps, step{name: fmt.Sprintf("step-%d", i), state: state, started: now})
+	}
+
+	lines := v.render(now, 80, 12)
+	found := false
+	for _, line := range lines[1:6] {
+		if strings.Contains(line, "step-15") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("running step not in the step list:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestTruncateAndPad(t *testing.T) {
+	if got := truncate("✓ abcdef", 4); got != "✓ ab" {
+		t.Errorf("truncate = %q, want %q", got, "✓ ab")
+	}
+	if got := pad("ab", 4); got != "ab  " {
+		t.Errorf("pad = %q, want %q", got, "ab  ")
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff is a retry policy with exponentially growing, jittered delays between attempts
+type Backoff struct {
+	Attempts     int           // Attempts including the first one
+	InitialDelay time.Duration // Delay before the first retry, doubled on each further retry
+	MaxDelay     time.Duration // Upper bound of the delay before jitter
+	Jitter       float64       // Fraction of the delay added or removed at random, from 0 to 1
+}
+
+// Delay returns the wait before the given retry, counting from 1 for the second attempt
+func (b Backoff) Delay(retry int) time.Duration {
+	delay := b.InitialDelay
+	for i := 1; i < retry && delay < b.MaxDelay; i++ {
+		delay *= 2
+	}
+	if b.MaxDelay > 0 {
+		delay = min(delay, b.MaxDelay)
+	}
+	if b.Jitter > 0 {
+		// Spread retries of many nodes failing at once over [delay*(1-jitter), delay*(1+jitter)]
+		delay += time.Duration(b.Jitter * (2*rand.Float64() - 1) * float64(delay)) // #nosec G404 -- jitter needs no cryptographic randomness
+	}
+	return max(delay, 0)
+}
+
+// Wait sleeps for the delay of the given retry, returning early with the context error when ctx is done
+func (b Backoff) Wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(b.Delay(retry))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// runtimeClassTimeout bounds the kubectl call creating a RuntimeClass
+const runtimeClassTimeout = 30 * time.Second
+
+// runtimeClass is the minimal RuntimeClass representation applied with kubectl
+type runtimeClass struct {
+	APIVersion string                 `json:"apiVersion"`
+	Kind       string                 `json:"kind"`
+	Metadata   runtimeClassMeta       `json:"metadata"`
+	Handler    string                 `json:"handler"`
+	Scheduling runtimeClassScheduling `json:"scheduling"`
+}
+
+type runtimeClassMeta struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+}
+
+type runtimeClassScheduling struct {
+	NodeSelector map[string]string `json:"nodeSelector"`
+}
+
+// ApplyRuntimeClass creates or updates a RuntimeClass selecting a containerd runtime handler, scheduling its pods
+// to the nodes labeled with nodeLabel=true. RuntimeClasses are cluster-wide, so this needs credentials allowed to
+// write them.
+func ApplyRuntimeClass(ctx context.Context, kubeconfig, name, handler, nodeLabel string) error {
+	manifest, err := json.Marshal(runtimeClass{
+		APIVersion: "node.k8s.io/v1",
+		Kind:       "RuntimeClass",
+		Metadata: runtimeClassMeta{
+			Name:   name,
+			Labels: map[string]string{"app.kubernetes.io/managed-by": "aks-flex-node"},
+		},
+		Handler:    handler,
+		Scheduling: runtimeClassScheduling{NodeSelector: map[string]string{nodeLabel: "true"}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode RuntimeClass %s: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, runtimeClassTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "kubectl", "--kubeconfig", kubeconfig, "apply", "-f", "-")
+	cmd.Stdin = bytes.NewReader(manifest)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to apply RuntimeClass %s: %w: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
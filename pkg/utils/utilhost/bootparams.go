@@ -0,0 +1,127 @@
+package utilhost
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Boot loader files, variables so tests can point them at temporary files
+var (
+	procCmdline    = "/proc/cmdline"
+	grubDropInDir  = "/etc/default/grub.d"
+	kernelArgsDir  = "/etc/aks-flex-node/kernel-args"
+	grubConfigPath = "/boot/grub/grub.cfg"
+)
+
+// KernelArgsActive reports whether the running kernel was booted with all of args
+func KernelArgsActive(args []string) (bool, error) {
+	data, err := os.ReadFile(procCmdline)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the kernel command line: %w", err)
+	}
+	booted := strings.Fields(string(data))
+	for _, arg := range args {
+		if !slices.Contains(booted, arg) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// KernelArgs returns the arguments set under name, or nil when none are
+func KernelArgs(name string) []string {
+	data, err := os.ReadFile(filepath.Join(kernelArgsDir, name))
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// SetKernelArgs adds args to the kernel command line of every boot entry, replacing the arguments set earlier
+// under the same name. It uses grubby on RHEL-family hosts and a drop-in under /etc/default/grub.d followed by
+// update-grub elsewhere. The arguments take effect on the next boot.
+func SetKernelArgs(name string, args []string) error {
+	previous := KernelArgs(name)
+	if slices.Equal(previous, args) {
+		return nil
+	}
+	if len(previous) > 0 {
+		if err := RemoveKernelArgs(name); err != nil {
+			return err
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	joined := strings.Join(args, " ")
+	if _, err := exec.LookPath("grubby"); err == nil {
+		if output, err := exec.Command("grubby", "--update-kernel=ALL", "--args="+joined).CombinedOutput(); err != nil { // #nosec - arguments come from the configuration
+			return fmt.Errorf("failed to add kernel arguments with grubby: %w, output: %s", err, string(output))
+		}
+	} else {
+		if err := os.MkdirAll(grubDropInDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", grubDropInDir, err)
+		}
+		dropIn := fmt.Sprintf("GRUB_CMDLINE_LINUX=\"${GRUB_CMDLINE_LINUX} %s\"\n", joined)
+		if err := os.WriteFile(grubDropIn(name), []byte(dropIn), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", grubDropIn(name), err)
+		}
+		if err := updateGrub(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(kernelArgsDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", kernelArgsDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(kernelArgsDir, name), []byte(joined+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to record kernel arguments: %w", err)
+	}
+	return nil
+}
+
+// RemoveKernelArgs removes the arguments set under name from the kernel command line of every boot entry
+func RemoveKernelArgs(name string) error {
+	args := KernelArgs(name)
+	if len(args) == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath("grubby"); err == nil {
+		if output, err := exec.Command("grubby", "--update-kernel=ALL", "--remove-args="+strings.Join(args, " ")).CombinedOutput(); err != nil { // #nosec - arguments were recorded by SetKernelArgs
+			return fmt.Errorf("failed to remove kernel arguments with grubby: %w, output: %s", err, string(output))
+		}
+	} else if _, err := os.Stat(grubDropIn(name)); err == nil {
+		if err := os.Remove(grubDropIn(name)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", grubDropIn(name), err)
+		}
+		if err := updateGrub(); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(filepath.Join(kernelArgsDir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove the kernel argument record: %w", err)
+	}
+	return nil
+}
+
+// grubDropIn returns the GRUB drop-in holding the arguments set under name
+func grubDropIn(name string) string {
+	return filepath.Join(grubDropInDir, "90-aks-flex-node-"+name+".cfg")
+}
+
+// updateGrub regenerates the GRUB configuration with update-grub, or grub-mkconfig where it is missing
+func updateGrub() error {
+	cmd := exec.Command("update-grub")
+	if _, err := exec.LookPath("update-grub"); err != nil {
+		cmd = exec.Command("grub-mkconfig", "-o", grubConfigPath) // #nosec - constant arguments
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to update the GRUB configuration: %w, output: %s", err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package utilhost
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKernelArgsActive(t *testing.T) {
+	original := procCmdline
+	defer func() { procCmdline = original }()
+	procCmdline = filepath.Join(t.TempDir(), "cmdline")
+	if err := os.WriteFile(procCmdline, []byte("BOOT_IMAGE=/vmlinuz-6.8.0 root=/dev/sda1 ro intel_iommu=on iommu=pt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{args: []string{"intel_iommu=on", "iommu=pt"}, want: true},
+		{args: []string{"intel_iommu=on", "hugepages=8"}, want: false},
+		{args: []string{"iommu"}, want: false},
+		{args: nil, want: true},
+	}
+	for _, tt := range tests {
+		got, err := KernelArgsActive(tt.args)
+		if err != nil || got != tt.want {
+			t.Errorf("KernelArgsActive(%v) = %v, %v, want %v", tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestKernelArgs(t *testing.T) {
+	original := kernelArgsDir
+	defer func() { kernelArgsDir = original }()
+	kernelArgsDir = t.TempDir()
+
+	if args := KernelArgs("sriov"); args != nil {
+		t.Errorf("KernelArgs() = %v without a record", args)
+	}
+	if err := os.WriteFile(filepath.Join(kernelArgsDir, "sriov"), []byte("intel_iommu=on iommu=pt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if args := KernelArgs("sriov"); !reflect.DeepEqual(args, []string{"intel_iommu=on", "iommu=pt"}) {
+		t.Errorf("KernelArgs() = %v", args)
+	}
+}
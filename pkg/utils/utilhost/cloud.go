@@ -0,0 +1,167 @@
+package utilhost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Cloud providers detected from instance metadata
+const (
+	CloudProviderAWS = "aws"
+	CloudProviderGCP = "gcp"
+)
+
+// CloudMetadata describes the non-Azure cloud instance the host runs on
+type CloudMetadata struct {
+	Provider     string `json:"provider"`     // aws or gcp
+	InstanceType string `json:"instanceType"` // e.g. m5.xlarge or n2-standard-4
+	Region       string `json:"region"`       // e.g. us-east-1 or us-central1
+	Zone         string `json:"zone"`         // e.g. us-east-1a or us-central1-a
+}
+
+// Instance metadata endpoints, variables so tests can point them at a local server
+var (
+	ec2MetadataEndpoint   = "http://169.254.169.254"
+	gceMetadataEndpoint   = "http://169.254.169.254"
+	azureMetadataEndpoint = "http://169.254.169.254"
+)
+
+// metadataRequestTimeout bounds each metadata request, so hosts without a metadata service are not held up
+const metadataRequestTimeout = 2 * time.Second
+
+// DetectCloud queries the EC2 and GCE instance metadata services and returns the metadata of the first
+// one that answers. It returns nil when the host is not an EC2 or GCE instance.
+func DetectCloud(ctx context.Context) *CloudMetadata {
+	client := &http.Client{Timeout: metadataRequestTimeout}
+	if metadata, err := detectEC2(ctx, client); err == nil {
+		return metadata
+	}
+	if metadata, err := detectGCE(ctx, client); err == nil {
+		return metadata
+	}
+	return nil
+}
+
+// AzureVMMetadata identifies the Azure virtual machine the host runs on
+type AzureVMMetadata struct {
+	ResourceID     string `json:"resourceId"`
+	SubscriptionID string `json:"subscriptionId"`
+	ResourceGroup  string `json:"resourceGroupName"`
+	Name           string `json:"name"`
+	Location       string `json:"location"`
+	VMScaleSetName string `json:"vmScaleSetName"` // Empty unless the VM is an instance of a scale set
+}
+
+// DetectAzureVM queries the Azure instance metadata service. It returns nil when the host is not an Azure VM.
+func DetectAzureVM(ctx context.Context) *AzureVMMetadata {
+	client := &http.Client{Timeout: metadataRequestTimeout}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, azureMetadataEndpoint+"/metadata/instance/compute?api-version=2021-02-01", nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Metadata", "true")
+	body, err := readMetadata(client, req)
+	if err != nil {
+		return nil
+	}
+	var metadata AzureVMMetadata
+	if err := json.Unmarshal([]byte(body), &metadata); err != nil || metadata.ResourceID == "" {
+		return nil
+	}
+	return &metadata
+}
+
+// detectEC2 reads the instance identity document through IMDSv2, which also works when IMDSv1 is disabled
+func detectEC2(ctx context.Context, client *http.Client) (*CloudMetadata, error) {
+	tokenReq, err := http.NewRequestWithContext(ctx, http.MethodPut, ec2MetadataEndpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := readMetadata(client, tokenReq)
+	if err != nil {
+		return nil, err
+	}
+
+	docReq, err := http.NewRequestWithContext(ctx, http.MethodGet, ec2MetadataEndpoint+"/latest/dynamic/instance-identity/document", nil)
+	if err != nil {
+		return nil, err
+	}
+	docReq.Header.Set("X-aws-ec2-metadata-token", token)
+	doc, err := readMetadata(client, docReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var identity struct {
+		InstanceType     string `json:"instanceType"`
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+	}
+	if err := json.Unmarshal([]byte(doc), &identity); err != nil {
+		return nil, fmt.Errorf("failed to parse EC2 instance identity document: %w", err)
+	}
+	return &CloudMetadata{
+		Provider:     CloudProviderAWS,
+		InstanceType: identity.InstanceType,
+		Region:       identity.Region,
+		Zone:         identity.AvailabilityZone,
+	}, nil
+}
+
+// detectGCE reads the machine type and zone, which GCE returns as resource paths such as
+// projects/123/zones/us-central1-a
+func detectGCE(ctx context.Context, client *http.Client) (*CloudMetadata, error) {
+	get := func(attribute string) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, gceMetadataEndpoint+"/computeMetadata/v1/instance/"+attribute, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		value, err := readMetadata(client, req)
+		return path.Base(value), err
+	}
+
+	machineType, err := get("machine-type")
+	if err != nil {
+		return nil, err
+	}
+	zone, err := get("zone")
+	if err != nil {
+		return nil, err
+	}
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	return &CloudMetadata{
+		Provider:     CloudProviderGCP,
+		InstanceType: machineType,
+		Region:       region,
+		Zone:         zone,
+	}, nil
+}
+
+// readMetadata sends a metadata request and returns the body of a successful response
+func readMetadata(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata request %s returned %s", req.URL.Path, resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,95 @@
+package utilhost
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectCloud(t *testing.T) {
+	originalEC2, originalGCE := ec2MetadataEndpoint, gceMetadataEndpoint
+	defer func() { ec2MetadataEndpoint, gceMetadataEndpoint = originalEC2, originalGCE }()
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    *CloudMetadata
+	}{
+		{
+			name: "ec2 instance",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+					_, _ = w.Write([]byte("token"))
+				case r.URL.Path == "/latest/dynamic/instance-identity/document" && r.Header.Get("X-aws-ec2-metadata-token") == "token":
+					_, _ = w.Write([]byte(`{"instanceType":"m5.xlarge","region":"us-east-1","availabilityZone":"us-east-1a"}`))
+				default:
+					http.NotFound(w, r)
+				}
+			},
+			want: &CloudMetadata{Provider: CloudProviderAWS, InstanceType: "m5.xlarge", Region: "us-east-1", Zone: "us-east-1a"},
+		},
+		{
+			name: "gce instance",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Metadata-Flavor") != "Google" {
+					http.NotFound(w, r)
+					return
+				}
+				switch r.URL.Path {
+				case "/computeMetadata/v1/instance/machine-type":
+					_, _ = w.Write([]byte("projects/123/machineTypes/n2-standard-4"))
+				case "/computeMetadata/v1/instance/zone":
+					_, _ = w.Write([]byte("projects/123/zones/us-central1-a"))
+				default:
+					http.NotFound(w, r)
+				}
+			},
+			want: &CloudMetadata{Provider: CloudProviderGCP, InstanceType: "n2-standard-4", Region: "us-central1", Zone: "us-central1-a"},
+		},
+		{
+			name:    "no metadata service",
+			handler: http.NotFound,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			ec2MetadataEndpoint, gceMetadataEndpoint = server.URL, server.URL
+
+			got := DetectCloud(context.Background())
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("DetectCloud() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectAzureVM(t *testing.T) {
+	original := azureMetadataEndpoint
+	defer func() { azureMetadataEndpoint = original }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Path != "/metadata/instance/compute" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"resourceId":"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm1","subscriptionId":"sub","resourceGroupName":"rg","name":"vm1","location":"eastus"}`))
+	}))
+	defer server.Close()
+
+	azureMetadataEndpoint = server.URL
+	got := DetectAzureVM(context.Background())
+	if got == nil || got.Name != "vm1" || got.ResourceGroup != "rg" || got.SubscriptionID != "sub" {
+		t.Fatalf("DetectAzureVM() = %+v", got)
+	}
+
+	azureMetadataEndpoint = "http://127.0.0.1:1"
+	if got := DetectAzureVM(context.Background()); got != nil {
+		t.Errorf("DetectAzureVM() without metadata service = %+v, want nil", got)
+	}
+}
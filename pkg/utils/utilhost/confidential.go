@@ -0,0 +1,74 @@
+package utilhost
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Confidential computing technologies
+const (
+	ConfidentialSNP = "snp" // AMD SEV-SNP
+	ConfidentialTDX = "tdx" // Intel TDX
+)
+
+// sysRoot and devRoot are variables so tests can point them at temporary directories
+var (
+	sysRoot = "/sys"
+	devRoot = "/dev"
+)
+
+// ConfidentialHostTechnology returns the technology the CPU offers for confidential VMs, or "" if it offers none.
+// It only reports CPU support; IsConfidentialHostEnabled tells whether KVM has it turned on.
+func ConfidentialHostTechnology(flags map[string]bool) string {
+	switch {
+	case flags["sev_snp"] && !IsVirtualMachine(flags):
+		return ConfidentialSNP
+	case flags["tdx_host_platform"]:
+		return ConfidentialTDX
+	}
+	return ""
+}
+
+// IsConfidentialHostEnabled reports whether KVM can launch confidential VMs of a technology, which the sev_snp
+// parameter of kvm_amd or the tdx parameter of kvm_intel turn on
+func IsConfidentialHostEnabled(technology string) bool {
+	parameter := map[string]string{
+		ConfidentialSNP: "kvm_amd/parameters/sev_snp",
+		ConfidentialTDX: "kvm_intel/parameters/tdx",
+	}[technology]
+	if parameter == "" {
+		return false
+	}
+	value, err := os.ReadFile(filepath.Join(sysRoot, "module", parameter))
+	return err == nil && (strings.TrimSpace(string(value)) == "Y" || strings.TrimSpace(string(value)) == "1")
+}
+
+// ConfidentialGuestTechnology returns the technology protecting the machine itself when it is a confidential VM,
+// or "" otherwise. The CPU flags tell even before the guest driver creates the attestation device.
+func ConfidentialGuestTechnology(flags map[string]bool) string {
+	switch {
+	case flags["sev_snp"] && IsVirtualMachine(flags), fileExists(filepath.Join(devRoot, "sev-guest")):
+		return ConfidentialSNP
+	case flags["tdx_guest"], fileExists(filepath.Join(devRoot, "tdx_guest")), fileExists(filepath.Join(devRoot, "tdx-guest")):
+		return ConfidentialTDX
+	}
+	return ""
+}
+
+// HasConfidentialGuestDevice reports whether the attestation device of a confidential VM guest exists
+func HasConfidentialGuestDevice(technology string) bool {
+	switch technology {
+	case ConfidentialSNP:
+		return fileExists(filepath.Join(devRoot, "sev-guest"))
+	case ConfidentialTDX:
+		return fileExists(filepath.Join(devRoot, "tdx_guest")) || fileExists(filepath.Join(devRoot, "tdx-guest"))
+	}
+	return false
+}
+
+// fileExists reports whether a path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
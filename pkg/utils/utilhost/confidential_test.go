@@ -0,0 +1,62 @@
+package utilhost
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfidentialHostTechnology(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags map[string]bool
+		want  string
+	}{
+		{name: "SEV-SNP host", flags: map[string]bool{"svm": true, "sev_snp": true}, want: ConfidentialSNP},
+		{name: "SEV-SNP guest", flags: map[string]bool{"sev_snp": true, "hypervisor": true}},
+		{name: "TDX host", flags: map[string]bool{"vmx": true, "tdx_host_platform": true}, want: ConfidentialTDX},
+		{name: "no support", flags: map[string]bool{"vmx": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConfidentialHostTechnology(tt.flags); got != tt.want {
+				t.Errorf("ConfidentialHostTechnology() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfidentialDetection(t *testing.T) {
+	root := t.TempDir()
+	originalSys, originalDev := sysRoot, devRoot
+	sysRoot, devRoot = filepath.Join(root, "sys"), filepath.Join(root, "dev")
+	t.Cleanup(func() { sysRoot, devRoot = originalSys, originalDev })
+
+	if IsConfidentialHostEnabled(ConfidentialSNP) || ConfidentialGuestTechnology(nil) != "" {
+		t.Fatalf("confidential computing detected on an empty host")
+	}
+
+	parameter := filepath.Join(sysRoot, "module", "kvm_amd", "parameters", "sev_snp")
+	if err := os.MkdirAll(filepath.Dir(parameter), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(parameter, []byte("Y\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !IsConfidentialHostEnabled(ConfidentialSNP) || IsConfidentialHostEnabled(ConfidentialTDX) {
+		t.Errorf("IsConfidentialHostEnabled() does not follow the kvm_amd sev_snp parameter")
+	}
+
+	if err := os.MkdirAll(devRoot, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(devRoot, "tdx_guest"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := ConfidentialGuestTechnology(nil); got != ConfidentialTDX || !HasConfidentialGuestDevice(ConfidentialTDX) {
+		t.Errorf("ConfidentialGuestTechnology() = %q, want %q", got, ConfidentialTDX)
+	}
+	if got := ConfidentialGuestTechnology(map[string]bool{"sev_snp": true, "hypervisor": true}); got != ConfidentialSNP {
+		t.Errorf("ConfidentialGuestTechnology() of a SEV-SNP guest = %q, want %q", got, ConfidentialSNP)
+	}
+}
@@ -0,0 +1,100 @@
+package utilhost
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CPU information files, variables so tests can point them at temporary files
+var (
+	cpuInfoPath   = "/proc/cpuinfo"
+	onlineCPUPath = "/sys/devices/system/cpu/online"
+)
+
+// CPUFlags returns the feature flags of the first CPU, e.g. vmx, svm or hypervisor on x86 and the features line
+// on arm64
+func CPUFlags() (map[string]bool, error) {
+	data, err := os.ReadFile(cpuInfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", cpuInfoPath, err)
+	}
+	flags := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if name = strings.TrimSpace(name); name != "flags" && name != "Features" {
+			continue
+		}
+		for _, flag := range strings.Fields(value) {
+			flags[flag] = true
+		}
+		break
+	}
+	return flags, nil
+}
+
+// HasHardwareVirtualization reports whether the CPU exposes Intel VT-x or AMD-V, which KVM needs. VMs only
+// expose them with nested virtualization. arm64 hosts report no flag, so only /dev/kvm tells there.
+func HasHardwareVirtualization(flags map[string]bool) bool {
+	return flags["vmx"] || flags["svm"]
+}
+
+// IsVirtualMachine reports whether the CPU flags show the host runs under a hypervisor
+func IsVirtualMachine(flags map[string]bool) bool {
+	return flags["hypervisor"]
+}
+
+// OnlineCPUs returns the CPUs the kernel runs tasks on
+func OnlineCPUs() ([]int, error) {
+	data, err := os.ReadFile(onlineCPUPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", onlineCPUPath, err)
+	}
+	return ParseCPUList(strings.TrimSpace(string(data)))
+}
+
+// ParseCPUList returns the sorted CPUs of a Linux CPU list such as 0-3,8
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid CPU list %q", list)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				return nil, fmt.Errorf("invalid CPU list %q", list)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	slices.Sort(cpus)
+	return slices.Compact(cpus), nil
+}
+
+// FormatCPUList renders sorted CPUs as a Linux CPU list, joining consecutive CPUs into ranges
+func FormatCPUList(cpus []int) string {
+	var parts []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if j == i {
+			parts = append(parts, strconv.Itoa(cpus[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,52 @@
+package utilhost
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCPUFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cpuinfo")
+	cpuInfo := "processor\t: 0\nvendor_id\t: GenuineIntel\nflags\t\t: fpu vme vmx hypervisor avx2\n\nprocessor\t: 1\nflags\t\t: fpu sse\n"
+	if err := os.WriteFile(path, []byte(cpuInfo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	original := cpuInfoPath
+	cpuInfoPath = path
+	t.Cleanup(func() { cpuInfoPath = original })
+
+	flags, err := CPUFlags()
+	if err != nil {
+		t.Fatalf("CPUFlags() error = %v", err)
+	}
+	if !flags["vmx"] || !flags["avx2"] || flags["sse"] {
+		t.Errorf("CPUFlags() = %v, want the flags of the first CPU", flags)
+	}
+	if !HasHardwareVirtualization(flags) || !IsVirtualMachine(flags) {
+		t.Errorf("flags %v report no nested virtualization", flags)
+	}
+	if HasHardwareVirtualization(map[string]bool{"hypervisor": true}) {
+		t.Errorf("HasHardwareVirtualization() without vmx or svm = true")
+	}
+}
+
+func TestCPUList(t *testing.T) {
+	cpus, err := ParseCPUList("8,0-3,2,10-11")
+	if err != nil {
+		t.Fatalf("ParseCPUList() error = %v", err)
+	}
+	want := []int{0, 1, 2, 3, 8, 10, 11}
+	if !reflect.DeepEqual(cpus, want) {
+		t.Errorf("ParseCPUList() = %v, want %v", cpus, want)
+	}
+	if got := FormatCPUList(cpus); got != "0-3,8,10-11" {
+		t.Errorf("FormatCPUList() = %q, want %q", got, "0-3,8,10-11")
+	}
+	for _, list := range []string{"", "3-1", "a", "1,,2"} {
+		if _, err := ParseCPUList(list); err == nil {
+			t.Errorf("ParseCPUList(%q) succeeded, want an error", list)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package utilhost
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// osReleasePath describes the distribution, a variable so tests can point it at a temporary file
+var osReleasePath = "/etc/os-release"
+
+// Distribution families, which share package managers, package names and service names
+const (
+	DistroFamilyDebian = "debian"
+	DistroFamilyRHEL   = "rhel"
+)
+
+// Distro is the distribution of the host, read from /etc/os-release
+type Distro struct {
+	ID         string   // e.g. ubuntu, rhel, rocky or almalinux
+	IDLike     []string // Distributions this one derives from, e.g. [rhel centos fedora]
+	VersionID  string   // e.g. 24.04 or 9.4
+	PrettyName string   // e.g. Rocky Linux 9.4 (Blue Onyx)
+}
+
+// supportedDistros are the distributions and major versions bootstrap is tested on
+var supportedDistros = map[string][]string{
+	"ubuntu":    {"22", "24"},
+	"rhel":      {"8", "9"},
+	"rocky":     {"8", "9"},
+	"almalinux": {"8", "9"},
+}
+
+// DetectDistro reads the distribution of the host from /etc/os-release
+func DetectDistro() (Distro, error) {
+	file, err := os.Open(osReleasePath)
+	if err != nil {
+		return Distro{}, fmt.Errorf("failed to read %s: %w", osReleasePath, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `'"`)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return Distro{}, fmt.Errorf("failed to read %s: %w", osReleasePath, err)
+	}
+	return Distro{
+		ID:         strings.ToLower(values["ID"]),
+		IDLike:     strings.Fields(strings.ToLower(values["ID_LIKE"])),
+		VersionID:  values["VERSION_ID"],
+		PrettyName: values["PRETTY_NAME"],
+	}, nil
+}
+
+// Family returns DistroFamilyDebian or DistroFamilyRHEL, or an empty string for other distributions
+func (d Distro) Family() string {
+	ids := append([]string{d.ID}, d.IDLike...)
+	switch {
+	case slices.Contains(ids, "debian") || slices.Contains(ids, "ubuntu"):
+		return DistroFamilyDebian
+	case slices.Contains(ids, "rhel") || slices.Contains(ids, "centos") || slices.Contains(ids, "fedora"):
+		return DistroFamilyRHEL
+	}
+	return ""
+}
+
+// MajorVersion returns the major version, e.g. 9 for 9.4 and 24 for 24.04
+func (d Distro) MajorVersion() string {
+	major, _, _ := strings.Cut(d.VersionID, ".")
+	return major
+}
+
+// String returns the name of the distribution
+func (d Distro) String() string {
+	if d.PrettyName != "" {
+		return d.PrettyName
+	}
+	return strings.TrimSpace(d.ID + " " + d.VersionID)
+}
+
+// Supported returns an error for a distribution or major version bootstrap is not tested on
+func (d Distro) Supported() error {
+	versions, ok := supportedDistros[d.ID]
+	if !ok {
+		return fmt.Errorf("%s is not supported, use Ubuntu 22.04 or 24.04, or RHEL, Rocky Linux or AlmaLinux 8 or 9", d)
+	}
+	if !slices.Contains(versions, d.MajorVersion()) {
+		return fmt.Errorf("%s is not supported, supported %s versions are %s", d, d.ID, strings.Join(versions, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package utilhost
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDetectDistro(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	original := osReleasePath
+	osReleasePath = path
+	t.Cleanup(func() { osReleasePath = original })
+
+	tests := []struct {
+		name       string
+		osRelease  string
+		want       Distro
+		wantFamily string
+		supported  bool
+	}{
+		{
+			name: "rocky",
+			osRelease: "NAME=\"Rocky Linux\"\nVERSION=\"9.4 (Blue Onyx)\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\n" +
+				"VERSION_ID=\"9.4\"\nPRETTY_NAME=\"Rocky Linux 9.4 (Blue Onyx)\"\n",
+			want:       Distro{ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}, VersionID: "9.4", PrettyName: "Rocky Linux 9.4 (Blue Onyx)"},
+			wantFamily: DistroFamilyRHEL,
+			supported:  true,
+		},
+		{
+			name:       "ubuntu",
+			osRelease:  "# comment\nID=ubuntu\nID_LIKE=debian\nVERSION_ID=\"24.04\"\nPRETTY_NAME=\"Ubuntu 24.04.1 LTS\"\n",
+			want:       Distro{ID: "ubuntu", IDLike: []string{"debian"}, VersionID: "24.04", PrettyName: "Ubuntu 24.04.1 LTS"},
+			wantFamily: DistroFamilyDebian,
+			supported:  true,
+		},
+		{
+			name:       "centos 7",
+			osRelease:  "ID=\"centos\"\nID_LIKE=\"rhel fedora\"\nVERSION_ID=\"7\"\n",
+			want:       Distro{ID: "centos", IDLike: []string{"rhel", "fedora"}, VersionID: "7"},
+			wantFamily: DistroFamilyRHEL,
+		},
+		{
+			name:      "unknown",
+			osRelease: "ID=arch\n",
+			want:      Distro{ID: "arch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.osRelease), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := DetectDistro()
+			if err != nil {
+				t.Fatalf("DetectDistro() error = %v", err)
+			}
+			if got.ID != tt.want.ID || !slices.Equal(got.IDLike, tt.want.IDLike) || got.VersionID != tt.want.VersionID || got.PrettyName != tt.want.PrettyName {
+				t.Errorf("DetectDistro() = %+v, want %+v", got, tt.want)
+			}
+			if family := got.Family(); family != tt.wantFamily {
+				t.Errorf("Family() = %q, want %q", family, tt.wantFamily)
+			}
+			if err := got.Supported(); (err == nil) != tt.supported {
+				t.Errorf("Supported() error = %v, want supported %v", err, tt.supported)
+			}
+		})
+	}
+}
+
+func TestSELinuxMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enforce")
+	original := selinuxEnforcePath
+	selinuxEnforcePath = path
+	t.Cleanup(func() { selinuxEnforcePath = original })
+
+	if mode := SELinuxMode(); mode != SELinuxDisabled {
+		t.Errorf("SELinuxMode() without selinuxfs = %q, want %q", mode, SELinuxDisabled)
+	}
+	for content, want := range map[string]string{"1\n": SELinuxEnforcing, "0\n": SELinuxPermissive} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if mode := SELinuxMode(); mode != want {
+			t.Errorf("SELinuxMode() with %q = %q, want %q", content, mode, want)
+		}
+	}
+}
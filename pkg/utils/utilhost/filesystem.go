@@ -0,0 +1,133 @@
+package utilhost
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Filesystem files, variables so tests can point them at temporary files
+var (
+	procFilesystemsPath = "/proc/filesystems"
+	mountInfoPath       = "/proc/self/mountinfo"
+)
+
+// FilesystemSupported reports whether the running kernel supports a filesystem type such as overlay or erofs.
+// Types provided by modules are only listed once the module is loaded.
+func FilesystemSupported(fsType string) (bool, error) {
+	data, err := os.ReadFile(procFilesystemsPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", procFilesystemsPath, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// Lines are "<type>" or "nodev\t<type>"
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[len(fields)-1] == fsType {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Mount is an entry of the mount table
+type Mount struct {
+	Point  string // Where it is mounted
+	Type   string // Filesystem type, e.g. ext4
+	Source string // Device or other source, e.g. /dev/sdb1
+}
+
+// FilesystemType returns the filesystem type of the mount holding path, e.g. ext4, xfs or zfs. The path does
+// not need to exist yet.
+func FilesystemType(path string) (string, error) {
+	mount, err := MountOf(path)
+	if err != nil {
+		return "", err
+	}
+	return mount.Type, nil
+}
+
+// FilesystemSize returns the size in bytes of the filesystem holding path. The path does not need to exist yet.
+func FilesystemSize(path string) (int64, error) {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil || path == filepath.Dir(path) {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	size, err := filesystemSize(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the filesystem of %s: %w", path, err)
+	}
+	return size, nil
+}
+
+// IsMountPoint reports whether a filesystem is mounted on path
+func IsMountPoint(path string) bool {
+	mount, err := MountOf(path)
+	return err == nil && mount.Point == filepath.Clean(path)
+}
+
+// MountOf returns the mount holding path. The path does not need to exist yet.
+func MountOf(path string) (Mount, error) {
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return Mount{}, fmt.Errorf("failed to read %s: %w", mountInfoPath, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	path = filepath.Clean(path)
+	var best Mount
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		mountFields, fsFields, ok := strings.Cut(scanner.Text(), " - ")
+		fields, types := strings.Fields(mountFields), strings.Fields(fsFields)
+		if !ok || len(fields) < 5 || len(types) < 2 {
+			continue
+		}
+		mountPoint := unescapeMountPath(fields[4])
+		if !pathWithin(path, mountPoint) || len(mountPoint) < len(best.Point) {
+			continue
+		}
+		// A later mount on the same point hides the earlier one
+		best = Mount{Point: mountPoint, Type: types[0], Source: unescapeMountPath(types[1])}
+	}
+	if err := scanner.Err(); err != nil {
+		return Mount{}, fmt.Errorf("failed to read %s: %w", mountInfoPath, err)
+	}
+	if best.Type == "" {
+		return Mount{}, fmt.Errorf("no mount found for %s", path)
+	}
+	return best, nil
+}
+
+// pathWithin reports whether path is dir or below it
+func pathWithin(path, dir string) bool {
+	if dir == "/" || path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, dir+"/")
+}
+
+// unescapeMountPath decodes the octal escapes mountinfo uses for spaces and other special characters
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+4 <= len(path) {
+			if value, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(path[i])
+	}
+	return sb.String()
+}
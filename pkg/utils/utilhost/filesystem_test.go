@@ -0,0 +1,85 @@
+package utilhost
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesystemSupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filesystems")
+	if err := os.WriteFile(path, []byte("nodev\tsysfs\nnodev\toverlay\n\text4\n\txfs\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	original := procFilesystemsPath
+	procFilesystemsPath = path
+	t.Cleanup(func() { procFilesystemsPath = original })
+
+	for fsType, want := range map[string]bool{"overlay": true, "ext4": true, "erofs": false, "nodev": false} {
+		got, err := FilesystemSupported(fsType)
+		if err != nil {
+			t.Fatalf("FilesystemSupported(%q) error = %v", fsType, err)
+		}
+		if got != want {
+			t.Errorf("FilesystemSupported(%q) = %v, want %v", fsType, got, want)
+		}
+	}
+}
+
+func TestFilesystemType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mountinfo")
+	mountInfo := `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+30 22 0:26 / /var/lib rw,relatime shared:2 - zfs rpool/var/lib rw,xattr
+31 30 0:27 / /var/lib/containerd rw,relatime shared:3 - xfs /dev/sdb1 rw
+32 22 0:28 / /mnt/data\040disk rw,relatime shared:4 - btrfs /dev/sdc1 rw
+33 22 0:29 / /var/lib/container rw,relatime shared:5 - tmpfs tmpfs rw
+`
+	if err := os.WriteFile(path, []byte(mountInfo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	original := mountInfoPath
+	mountInfoPath = path
+	t.Cleanup(func() { mountInfoPath = original })
+
+	tests := map[string]string{
+		"/etc/containerd":                 "ext4",
+		"/var/lib/kubelet":                "zfs",
+		"/var/lib/containerd":             "xfs",
+		"/var/lib/containerd/io.snapshot": "xfs",
+		"/var/lib/containerd-stargz-grpc": "zfs",
+		"/mnt/data disk/images":           "btrfs",
+	}
+	for path, want := range tests {
+		got, err := FilesystemType(path)
+		if err != nil {
+			t.Fatalf("FilesystemType(%q) error = %v", path, err)
+		}
+		if got != want {
+			t.Errorf("FilesystemType(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	if mount, err := MountOf("/var/lib/containerd/io.snapshot"); err != nil || mount != (Mount{Point: "/var/lib/containerd", Type: "xfs", Source: "/dev/sdb1"}) {
+		t.Errorf("MountOf() = %+v, %v", mount, err)
+	}
+	for path, want := range map[string]bool{"/var/lib/containerd": true, "/var/lib/containerd/": true, "/var/lib/kubelet": false, "/mnt/data disk": true} {
+		if got := IsMountPoint(path); got != want {
+			t.Errorf("IsMountPoint(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestFilesystemSize(t *testing.T) {
+	dir := t.TempDir()
+	size, err := FilesystemSize(dir)
+	if err != nil {
+		t.Fatalf("FilesystemSize() error = %v", err)
+	}
+	if size <= 0 {
+		t.Errorf("FilesystemSize() = %d, want a positive size", size)
+	}
+	missing, err := FilesystemSize(filepath.Join(dir, "not", "created"))
+	if err != nil || missing != size {
+		t.Errorf("FilesystemSize() of a missing path = %d, %v, want the size %d of its parent's filesystem", missing, err, size)
+	}
+}
@@ -0,0 +1,53 @@
+package utilhost
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"os/user"
+	"slices"
+	"strconv"
+)
+
+// EnsureGroup creates the system group if it does not exist and returns its GID.
+func EnsureGroup(name string) (int, error) {
+	group, err := user.LookupGroup(name)
+	var unknown user.UnknownGroupError
+	if errors.As(err, &unknown) {
+		if output, err := exec.Command("groupadd", "--system", name).CombinedOutput(); err != nil {
+			return 0, fmt.Errorf("failed to create group %s: %w (%s)", name, err, output)
+		}
+		group, err = user.LookupGroup(name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up group %s: %w", name, err)
+	}
+	gid, err := strconv.Atoi(group.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("invalid GID %q for group %s: %w", group.Gid, name, err)
+	}
+	return gid, nil
+}
+
+// EnsureGroupMember adds an existing user to the group unless it is already a member.
+func EnsureGroupMember(username, groupName string) error {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+	group, err := user.LookupGroup(groupName)
+	if err != nil {
+		return fmt.Errorf("failed to look up group %s: %w", groupName, err)
+	}
+	groupIDs, err := u.GroupIds()
+	if err != nil {
+		return fmt.Errorf("failed to list groups of user %s: %w", username, err)
+	}
+	if slices.Contains(groupIDs, group.Gid) {
+		return nil
+	}
+	if output, err := exec.Command("usermod", "--append", "--groups", groupName, username).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to add user %s to group %s: %w (%s)", username, groupName, err, output)
+	}
+	return nil
+}
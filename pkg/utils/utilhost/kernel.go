@@ -0,0 +1,123 @@
+package utilhost
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilio"
+)
+
+// Kernel files, variables so tests can point them at temporary files
+var (
+	kernelReleasePath = "/proc/sys/kernel/osrelease"
+	bootDir           = "/boot"
+	procConfigGz      = "/proc/config.gz"
+	sysModuleDir      = "/sys/module"
+)
+
+// LoadKernelModule loads a kernel module with modprobe. A module that was not loaded before is listed in the
+// installed files manifest, so unbootstrap unloads it again and leaves modules the host loaded itself alone.
+func LoadKernelModule(module string) error {
+	loaded := kernelModuleLoaded(module)
+	if err := utils.RunSystemCommand("modprobe", module); err != nil {
+		return err
+	}
+	if !loaded {
+		utilio.RecordLoadedModule(module)
+	}
+	return nil
+}
+
+// kernelModuleLoaded reports whether a module is loaded or built into the kernel, which sysfs lists with
+// underscores in place of dashes
+func kernelModuleLoaded(module string) bool {
+	_, err := os.Stat(filepath.Join(sysModuleDir, strings.ReplaceAll(module, "-", "_")))
+	return err == nil
+}
+
+// KernelRelease returns the release of the running kernel, e.g. 5.15.0-1064-azure
+func KernelRelease() (string, error) {
+	data, err := os.ReadFile(kernelReleasePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the kernel release: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// KernelVersionAtLeast reports whether a kernel release such as 5.15.0-1064-azure is major.minor or newer
+func KernelVersionAtLeast(release string, major, minor int) (bool, error) {
+	version, _, _ := strings.Cut(release, "-")
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false, fmt.Errorf("unrecognized kernel release %q", release)
+	}
+	releaseMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false, fmt.Errorf("unrecognized kernel release %q", release)
+	}
+	releaseMinor, err := strconv.Atoi(strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err != nil {
+		return false, fmt.Errorf("unrecognized kernel release %q", release)
+	}
+	if releaseMajor != major {
+		return releaseMajor > major, nil
+	}
+	return releaseMinor >= minor, nil
+}
+
+// KernelConfig returns the build options of the running kernel, e.g. CONFIG_BPF_SYSCALL=y, read from
+// /boot/config-<release> or, when the distribution does not ship it, /proc/config.gz
+func KernelConfig(release string) (map[string]string, error) {
+	if file, err := os.Open(filepath.Join(bootDir, "config-"+release)); err == nil {
+		defer func() { _ = file.Close() }()
+		return parseKernelConfig(file)
+	}
+
+	file, err := os.Open(procConfigGz)
+	if err != nil {
+		return nil, fmt.Errorf("kernel configuration not found in %s or %s", filepath.Join(bootDir, "config-"+release), procConfigGz)
+	}
+	defer func() { _ = file.Close() }()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", procConfigGz, err)
+	}
+	return parseKernelConfig(reader)
+}
+
+// parseKernelConfig reads "CONFIG_X=value" lines, skipping comments such as "# CONFIG_X is not set"
+func parseKernelConfig(reader io.Reader) (map[string]string, error) {
+	options := map[string]string{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if name, value, ok := strings.Cut(line, "="); ok {
+			options[name] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the kernel configuration: %w", err)
+	}
+	return options, nil
+}
+
+// MissingKernelOptions returns the options that are neither built in nor built as modules
+func MissingKernelOptions(options map[string]string, required []string) []string {
+	var missing []string
+	for _, name := range required {
+		if value := options[name]; value != "y" && value != "m" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
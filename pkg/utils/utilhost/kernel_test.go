@@ -0,0 +1,91 @@
+package utilhost
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKernelVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		release string
+		major   int
+		minor   int
+		want    bool
+		wantErr bool
+	}{
+		{release: "5.15.0-1064-azure", major: 5, minor: 10, want: true},
+		{release: "5.4.0-1110-azure", major: 5, minor: 10, want: false},
+		{release: "5.4.0-1110-azure", major: 5, minor: 4, want: true},
+		{release: "6.1.0", major: 5, minor: 10, want: true},
+		{release: "4.18.0-553.el8_10.x86_64", major: 5, minor: 4, want: false},
+		{release: "6.8.0+", major: 6, minor: 8, want: true},
+		{release: "unknown", major: 5, minor: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.release, func(t *testing.T) {
+			got, err := KernelVersionAtLeast(tt.release, tt.major, tt.minor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("KernelVersionAtLeast() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("KernelVersionAtLeast(%q, %d, %d) = %v, want %v", tt.release, tt.major, tt.minor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKernelConfig(t *testing.T) {
+	originalBoot, originalProc := bootDir, procConfigGz
+	defer func() { bootDir, procConfigGz = originalBoot, originalProc }()
+	bootDir = t.TempDir()
+	procConfigGz = filepath.Join(t.TempDir(), "config.gz")
+
+	if _, err := KernelConfig("6.8.0"); err == nil {
+		t.Error("KernelConfig() succeeded without a kernel configuration")
+	}
+
+	// /proc/config.gz is the fallback
+	file, err := os.Create(procConfigGz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := gzip.NewWriter(file)
+	if _, err := writer.Write([]byte("CONFIG_BPF=y\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	options, err := KernelConfig("6.8.0")
+	if err != nil || options["CONFIG_BPF"] != "y" {
+		t.Fatalf("KernelConfig() = %v, %v, want CONFIG_BPF from /proc/config.gz", options, err)
+	}
+
+	config := strings.Join([]string{
+		"# Automatically generated file; DO NOT EDIT.",
+		"CONFIG_BPF=y",
+		"CONFIG_BPF_JIT=y",
+		"CONFIG_NET_CLS_BPF=m",
+		"# CONFIG_CGROUP_BPF is not set",
+		`CONFIG_LOCALVERSION=""`,
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(bootDir, "config-6.8.0"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options, err = KernelConfig("6.8.0")
+	if err != nil {
+		t.Fatalf("KernelConfig() error = %v", err)
+	}
+	missing := MissingKernelOptions(options, []string{"CONFIG_BPF", "CONFIG_BPF_JIT", "CONFIG_NET_CLS_BPF", "CONFIG_CGROUP_BPF", "CONFIG_PERF_EVENTS"})
+	if want := []string{"CONFIG_CGROUP_BPF", "CONFIG_PERF_EVENTS"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("MissingKernelOptions() = %v, want %v", missing, want)
+	}
+}
@@ -0,0 +1,31 @@
+package utilhost
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// meminfoPath is a variable so tests can point it at a temporary file
+var meminfoPath = "/proc/meminfo"
+
+// MemoryTotal returns the memory of the host in bytes, as the kernel reports it in MemTotal
+func MemoryTotal() (int64, error) {
+	data, err := os.ReadFile(meminfoPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", meminfoPath, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		value, ok := strings.CutPrefix(line, "MemTotal:")
+		if !ok {
+			continue
+		}
+		kib, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal in %s: %q", meminfoPath, strings.TrimSpace(value))
+		}
+		return kib * 1024, nil
+	}
+	return 0, fmt.Errorf("no MemTotal in %s", meminfoPath)
+}
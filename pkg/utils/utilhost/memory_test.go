@@ -0,0 +1,32 @@
+package utilhost
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryTotal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meminfo")
+	if err := os.WriteFile(path, []byte("MemTotal:        8039636 kB\nMemFree:         1203456 kB\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	original := meminfoPath
+	meminfoPath = path
+	t.Cleanup(func() { meminfoPath = original })
+
+	total, err := MemoryTotal()
+	if err != nil {
+		t.Fatalf("MemoryTotal() error = %v", err)
+	}
+	if total != 8039636*1024 {
+		t.Errorf("MemoryTotal() = %d, want %d", total, 8039636*1024)
+	}
+
+	if err := os.WriteFile(path, []byte("MemFree: 1 kB\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MemoryTotal(); err == nil {
+		t.Error("MemoryTotal() without MemTotal succeeded")
+	}
+}
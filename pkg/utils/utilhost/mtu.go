@@ -0,0 +1,146 @@
+package utilhost
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// minPathMTU is the smallest MTU probed, the IPv6 minimum that every link in practice supports
+	minPathMTU = 1280
+	// ipv4HeaderOverhead and ipv6HeaderOverhead are the IP and ICMP header bytes ping adds to its payload
+	ipv4HeaderOverhead = 28
+	ipv6HeaderOverhead = 48
+)
+
+// sysClassNetDir holds the per-interface attributes, a variable so tests can point it at a temporary directory
+var sysClassNetDir = "/sys/class/net"
+
+// PathMTU returns the largest packet that reaches host without fragmentation. It starts from the MTU of the
+// route and interface traffic to host leaves through, which already reflects WireGuard and IPsec tunnels, and
+// narrows it down with don't-fragment pings to catch smaller MTUs further along the path, such as ExpressRoute
+// or site-to-site VPN gateways. When ICMP is blocked it returns the route MTU. A non-nil source measures the
+// path taken from that local address.
+func PathMTU(ctx context.Context, host string, source net.IP) (int, error) {
+	ip, err := resolveHost(ctx, host)
+	if err != nil {
+		return 0, err
+	}
+
+	routeArgs := []string{"route", "get", ip.String()}
+	if source != nil {
+		routeArgs = append(routeArgs, "from", source.String())
+	}
+	output, err := exec.CommandContext(ctx, "ip", routeArgs...).CombinedOutput() // #nosec - arguments are parsed addresses
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up the route to %s: %w, output: %s", ip, err, string(output))
+	}
+	dev, routeMTU := parseRouteGet(string(output))
+	if dev == "" {
+		return 0, fmt.Errorf("no route to %s", ip)
+	}
+	linkMTU, err := interfaceMTU(dev)
+	if err != nil {
+		return 0, err
+	}
+	if routeMTU == 0 || routeMTU > linkMTU {
+		routeMTU = linkMTU
+	}
+
+	fits := func(mtu int) bool { return pingDontFragment(ctx, ip, source, mtu) }
+	if routeMTU <= minPathMTU || fits(routeMTU) || !fits(minPathMTU) {
+		return routeMTU, nil
+	}
+	return searchMTU(minPathMTU, routeMTU, fits), nil
+}
+
+// resolveHost returns the address of host, preferring IPv4 since pod traffic leaves the node over IPv4 by default
+func resolveHost(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s has no addresses", host)
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return addr.IP, nil
+		}
+	}
+	return addrs[0].IP, nil
+}
+
+// parseRouteGet returns the interface and the cached path MTU, if any, from the output of "ip route get", e.g.
+// "10.0.0.4 via 192.168.1.1 dev wg0 src 192.168.1.5 uid 0 \n    cache expires 590sec mtu 1400"
+func parseRouteGet(output string) (string, int) {
+	var dev string
+	var mtu int
+	fields := strings.Fields(output)
+	for index := 0; index+1 < len(fields); index++ {
+		switch fields[index] {
+		case "dev":
+			dev = fields[index+1]
+		case "mtu":
+			// "mtu lock 1400" pins the route MTU
+			value := fields[index+1]
+			if value == "lock" && index+2 < len(fields) {
+				value = fields[index+2]
+			}
+			if parsed, err := strconv.Atoi(value); err == nil {
+				mtu = parsed
+			}
+		}
+	}
+	return dev, mtu
+}
+
+// interfaceMTU reads the MTU of a network interface
+func interfaceMTU(dev string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(sysClassNetDir, dev, "mtu"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the MTU of %s: %w", dev, err)
+	}
+	mtu, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid MTU %q for %s", strings.TrimSpace(string(data)), dev)
+	}
+	return mtu, nil
+}
+
+// pingDontFragment reports whether a packet of mtu bytes with the don't-fragment bit set gets a reply from ip
+func pingDontFragment(ctx context.Context, ip, source net.IP, mtu int) bool {
+	args := []string{"-c", "1", "-W", "1", "-M", "do"}
+	if source != nil {
+		args = append(args, "-I", source.String())
+	}
+	payload := mtu - ipv4HeaderOverhead
+	if ip.To4() == nil {
+		args = append(args, "-6")
+		payload = mtu - ipv6HeaderOverhead
+	}
+	args = append(args, "-s", strconv.Itoa(payload), ip.String())
+	return exec.CommandContext(ctx, "ping", args...).Run() == nil // #nosec - arguments are numbers and a parsed address
+}
+
+// searchMTU returns the largest MTU in [low, high] that fits, given that low fits and high does not
+func searchMTU(low, high int, fits func(int) bool) int {
+	for high-low > 1 {
+		mid := (low + high) / 2
+		if fits(mid) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low
+}
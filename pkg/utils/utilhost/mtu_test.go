@@ -0,0 +1,77 @@
+package utilhost
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRouteGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		wantDev string
+		wantMTU int
+	}{
+		{
+			name:    "direct route",
+			output:  "10.0.0.4 dev eth0 src 10.0.0.5 uid 0 \n    cache ",
+			wantDev: "eth0",
+		},
+		{
+			name:    "tunnel with cached path mtu",
+			output:  "10.0.0.4 via 192.168.1.1 dev wg0 src 192.168.1.5 uid 0 \n    cache expires 590sec mtu 1400 ",
+			wantDev: "wg0",
+			wantMTU: 1400,
+		},
+		{
+			name:    "locked route mtu",
+			output:  "10.0.0.4 via 172.16.0.1 dev vti0 src 172.16.0.5 uid 0 \n    cache mtu lock 1350 ",
+			wantDev: "vti0",
+			wantMTU: 1350,
+		},
+		{
+			name:   "unreachable",
+			output: "RTNETLINK answers: Network is unreachable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, mtu := parseRouteGet(tt.output)
+			if dev != tt.wantDev || mtu != tt.wantMTU {
+				t.Errorf("parseRouteGet() = %q, %d, want %q, %d", dev, mtu, tt.wantDev, tt.wantMTU)
+			}
+		})
+	}
+}
+
+func TestInterfaceMTU(t *testing.T) {
+	original := sysClassNetDir
+	defer func() { sysClassNetDir = original }()
+	sysClassNetDir = t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(sysClassNetDir, "wg0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sysClassNetDir, "wg0", "mtu"), []byte("1420\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mtu, err := interfaceMTU("wg0")
+	if err != nil || mtu != 1420 {
+		t.Errorf("interfaceMTU(wg0) = %d, %v, want 1420", mtu, err)
+	}
+	if _, err := interfaceMTU("eth9"); err == nil {
+		t.Error("interfaceMTU(eth9) expected an error for a missing interface")
+	}
+}
+
+func TestSearchMTU(t *testing.T) {
+	for _, pathMTU := range []int{1280, 1350, 1400, 1438, 1499} {
+		fits := func(mtu int) bool { return mtu <= pathMTU }
+		if got := searchMTU(minPathMTU, 1500, fits); got != pathMTU {
+			t.Errorf("searchMTU() = %d, want %d", got, pathMTU)
+		}
+	}
+}
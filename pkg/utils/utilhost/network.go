@@ -0,0 +1,56 @@
+package utilhost
+
+import (
+	"net"
+	"slices"
+)
+
+// interfaceNetworks lists the addresses of an interface with their masks, a variable so tests can replace it
+var interfaceNetworks = func(name string) ([]*net.IPNet, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var ipNets []*net.IPNet
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ipNets = append(ipNets, ipNet)
+		}
+	}
+	return ipNets, nil
+}
+
+// NodeNetworkSubnets returns the subnets of the global unicast addresses on the given interfaces in CIDR
+// notation, e.g. 10.0.1.0/24, so traffic to other machines on the node network can bypass a proxy. Without
+// interfaces it uses the interface of the IPv4 default route. Other interfaces are left out, so CNI bridges,
+// veths and tunnels, which come and go with pods, do not change the result.
+func NodeNetworkSubnets(interfaces ...string) []string {
+	if len(interfaces) == 0 {
+		iface, err := defaultRouteInterface()
+		if err != nil {
+			return nil
+		}
+		interfaces = []string{iface}
+	}
+	var subnets []string
+	for _, name := range interfaces {
+		ipNets, err := interfaceNetworks(name)
+		if err != nil {
+			continue
+		}
+		for _, ipNet := range ipNets {
+			if !ipNet.IP.IsGlobalUnicast() {
+				continue
+			}
+			subnet := (&net.IPNet{IP: ipNet.IP.Mask(ipNet.Mask), Mask: ipNet.Mask}).String()
+			if !slices.Contains(subnets, subnet) {
+				subnets = append(subnets, subnet)
+			}
+		}
+	}
+	return subnets
+}
@@ -0,0 +1,55 @@
+package utilhost
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNodeNetworkSubnets(t *testing.T) {
+	originalNetworks, originalRoute := interfaceNetworks, procNetRoute
+	t.Cleanup(func() { interfaceNetworks, procNetRoute = originalNetworks, originalRoute })
+	networks := map[string][]string{
+		"eth0": {"10.0.1.5/24", "fe80::1/64", "fd00:10::5/64"},
+		"eth1": {"192.168.10.5/24"},
+		"cni0": {"10.244.0.1/24"},
+	}
+	interfaceNetworks = func(name string) ([]*net.IPNet, error) {
+		cidrs, ok := networks[name]
+		if !ok {
+			return nil, fmt.Errorf("no such interface %s", name)
+		}
+		var ipNets []*net.IPNet
+		for _, cidr := range cidrs {
+			ip, ipNet, _ := net.ParseCIDR(cidr)
+			ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: ipNet.Mask})
+		}
+		return ipNets, nil
+	}
+	procNetRoute = filepath.Join(t.TempDir(), "route")
+	route := "Iface\tDestination\tGateway\tFlags\tRefCnt\tUse\tMetric\tMask\tMTU\tWindow\tIRTT\n" +
+		"eth0\t00000000\t0101000A\t0003\t0\t0\t100\t00000000\t0\t0\t0\n"
+	if err := os.WriteFile(procNetRoute, []byte(route), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		interfaces []string
+		want       []string
+	}{
+		{name: "default route interface", want: []string{"10.0.1.0/24", "fd00:10::/64"}},
+		{name: "node ip interfaces", interfaces: []string{"eth1", "eth1"}, want: []string{"192.168.10.0/24"}},
+		{name: "missing interface", interfaces: []string{"eth9"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NodeNetworkSubnets(tt.interfaces...); !slices.Equal(got, tt.want) {
+				t.Errorf("NodeNetworkSubnets(%v) = %v, want %v", tt.interfaces, got, tt.want)
+			}
+		})
+	}
+}
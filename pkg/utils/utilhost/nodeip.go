@@ -0,0 +1,202 @@
+package utilhost
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// InterfaceAddress is a unicast address assigned to a network interface
+type InterfaceAddress struct {
+	Interface string
+	IP        net.IP
+}
+
+// interfaceAddresses lists the global unicast addresses of the host, a variable so tests can replace it
+var interfaceAddresses = func() ([]InterfaceAddress, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var addresses []InterfaceAddress
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the addresses of %s: %w", iface.Name, err)
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || !ipNet.IP.IsGlobalUnicast() {
+				continue
+			}
+			addresses = append(addresses, InterfaceAddress{Interface: iface.Name, IP: ipNet.IP})
+		}
+	}
+	return addresses, nil
+}
+
+// procNetRoute holds the IPv4 routing table, a variable so tests can point it at a temporary file
+var procNetRoute = "/proc/net/route"
+
+// NodeIPSelector chooses the node IP on hosts with several network interfaces. At most one field is set.
+// CIDR and Address take a comma-separated pair, one per IP family, on dual-stack nodes.
+type NodeIPSelector struct {
+	Interface string // Use the address of this interface
+	CIDR      string // Use the address within this range
+	Address   string // Use this address, which must be assigned to an interface
+}
+
+// SelectNodeIP returns the address the selector picks. Candidates are ordered by interface name, IPv4 before
+// IPv6 and then by address, so the same host always yields the same address regardless of the order the kernel
+// lists interfaces in.
+func SelectNodeIP(selector NodeIPSelector) (InterfaceAddress, error) {
+	addresses, match, err := nodeIPCandidates(selector)
+	if err != nil {
+		return InterfaceAddress{}, err
+	}
+	for _, address := range addresses {
+		if match.matches(address) {
+			return address, nil
+		}
+	}
+	return InterfaceAddress{}, errors.New(match.description)
+}
+
+// SelectDualStackNodeIPs returns the IPv4 and the IPv6 address the selector picks, in that order. Without a
+// selector, both come from the interface of the IPv4 default route.
+func SelectDualStackNodeIPs(selector NodeIPSelector) ([]InterfaceAddress, error) {
+	if selector == (NodeIPSelector{}) {
+		iface, err := defaultRouteInterface()
+		if err != nil {
+			return nil, err
+		}
+		selector.Interface = iface
+	}
+	addresses, match, err := nodeIPCandidates(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var ipv4, ipv6 *InterfaceAddress
+	for index, address := range addresses {
+		if !match.matches(address) {
+			continue
+		}
+		if address.IP.To4() != nil && ipv4 == nil {
+			ipv4 = &addresses[index]
+		}
+		if address.IP.To4() == nil && ipv6 == nil {
+			ipv6 = &addresses[index]
+		}
+	}
+	switch {
+	case ipv4 == nil && ipv6 == nil:
+		return nil, errors.New(match.description)
+	case ipv4 == nil:
+		return nil, fmt.Errorf("%s: no IPv4 address matches", selectorDescription(selector))
+	case ipv6 == nil:
+		return nil, fmt.Errorf("%s: no IPv6 address matches", selectorDescription(selector))
+	}
+	return []InterfaceAddress{*ipv4, *ipv6}, nil
+}
+
+// addressMatcher matches candidate node IPs, with the error reported when none match
+type addressMatcher struct {
+	matches     func(InterfaceAddress) bool
+	description string
+}
+
+// nodeIPCandidates lists the host addresses in selection order and builds the matcher of the selector
+func nodeIPCandidates(selector NodeIPSelector) ([]InterfaceAddress, addressMatcher, error) {
+	var match addressMatcher
+	switch {
+	case selector.Address != "":
+		var ips []net.IP
+		for _, value := range strings.Split(selector.Address, ",") {
+			ip := net.ParseIP(strings.TrimSpace(value))
+			if ip == nil {
+				return nil, match, fmt.Errorf("invalid address %s", value)
+			}
+			ips = append(ips, ip)
+		}
+		match.matches = func(address InterfaceAddress) bool {
+			return slices.ContainsFunc(ips, address.IP.Equal)
+		}
+		match.description = fmt.Sprintf("address %s is not assigned to any interface", selector.Address)
+	case selector.Interface != "":
+		match.matches = func(address InterfaceAddress) bool { return address.Interface == selector.Interface }
+		match.description = fmt.Sprintf("interface %s does not exist, is down or has no global unicast address", selector.Interface)
+	case selector.CIDR != "":
+		var ipNets []*net.IPNet
+		for _, value := range strings.Split(selector.CIDR, ",") {
+			_, ipNet, err := net.ParseCIDR(strings.TrimSpace(value))
+			if err != nil {
+				return nil, match, fmt.Errorf("invalid CIDR %s: %w", value, err)
+			}
+			ipNets = append(ipNets, ipNet)
+		}
+		match.matches = func(address InterfaceAddress) bool {
+			return slices.ContainsFunc(ipNets, func(ipNet *net.IPNet) bool { return ipNet.Contains(address.IP) })
+		}
+		match.description = fmt.Sprintf("no interface has an address in %s", selector.CIDR)
+	default:
+		return nil, match, fmt.Errorf("no interface, CIDR or address selects the node IP")
+	}
+
+	addresses, err := interfaceAddresses()
+	if err != nil {
+		return nil, match, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+	sortInterfaceAddresses(addresses)
+	return addresses, match, nil
+}
+
+// selectorDescription names the selector in errors, e.g. "interface eth1"
+func selectorDescription(selector NodeIPSelector) string {
+	switch {
+	case selector.Address != "":
+		return "address " + selector.Address
+	case selector.Interface != "":
+		return "interface " + selector.Interface
+	default:
+		return "CIDR " + selector.CIDR
+	}
+}
+
+// defaultRouteInterface returns the interface of the IPv4 default route, read from lines such as
+// "eth0	00000000	0100000A	0003	0	0	100	00000000	0	0	0"
+func defaultRouteInterface() (string, error) {
+	data, err := os.ReadFile(procNetRoute)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the routing table: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) >= 8 && fields[1] == "00000000" && fields[7] == "00000000" {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("the host has no IPv4 default route")
+}
+
+// sortInterfaceAddresses orders addresses by interface name, IPv4 before IPv6, then by address
+func sortInterfaceAddresses(addresses []InterfaceAddress) {
+	sort.SliceStable(addresses, func(a, b int) bool {
+		if addresses[a].Interface != addresses[b].Interface {
+			return addresses[a].Interface < addresses[b].Interface
+		}
+		aIPv4, bIPv4 := addresses[a].IP.To4() != nil, addresses[b].IP.To4() != nil
+		if aIPv4 != bIPv4 {
+			return aIPv4
+		}
+		return bytes.Compare(addresses[a].IP.To16(), addresses[b].IP.To16()) < 0
+	})
+}
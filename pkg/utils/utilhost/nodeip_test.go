@@ -0,0 +1,120 @@
+package utilhost
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectNodeIP(t *testing.T) {
+	original := interfaceAddresses
+	defer func() { interfaceAddresses = original }()
+	interfaceAddresses = func() ([]InterfaceAddress, error) {
+		return []InterfaceAddress{
+			{Interface: "eth1", IP: net.ParseIP("fd00:10::5")},
+			{Interface: "eth1", IP: net.ParseIP("192.168.10.5")},
+			{Interface: "eth0", IP: net.ParseIP("10.0.0.10")},
+			{Interface: "eth0", IP: net.ParseIP("10.0.0.9")},
+		}, nil
+	}
+
+	tests := []struct {
+		name     string
+		selector NodeIPSelector
+		want     InterfaceAddress
+		wantErr  bool
+	}{
+		{
+			name:     "interface prefers ipv4",
+			selector: NodeIPSelector{Interface: "eth1"},
+			want:     InterfaceAddress{Interface: "eth1", IP: net.ParseIP("192.168.10.5")},
+		},
+		{
+			name:     "interface picks lowest address",
+			selector: NodeIPSelector{Interface: "eth0"},
+			want:     InterfaceAddress{Interface: "eth0", IP: net.ParseIP("10.0.0.9")},
+		},
+		{
+			name:     "cidr",
+			selector: NodeIPSelector{CIDR: "fd00:10::/64"},
+			want:     InterfaceAddress{Interface: "eth1", IP: net.ParseIP("fd00:10::5")},
+		},
+		{
+			name:     "address",
+			selector: NodeIPSelector{Address: "10.0.0.10"},
+			want:     InterfaceAddress{Interface: "eth0", IP: net.ParseIP("10.0.0.10")},
+		},
+		{name: "missing interface", selector: NodeIPSelector{Interface: "eth2"}, wantErr: true},
+		{name: "cidr without match", selector: NodeIPSelector{CIDR: "172.16.0.0/12"}, wantErr: true},
+		{name: "unassigned address", selector: NodeIPSelector{Address: "10.0.0.11"}, wantErr: true},
+		{name: "no selector", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectNodeIP(tt.selector)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SelectNodeIP() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectNodeIP() error = %v", err)
+			}
+			if got.Interface != tt.want.Interface || !got.IP.Equal(tt.want.IP) {
+				t.Errorf("SelectNodeIP() = %s %s, want %s %s", got.Interface, got.IP, tt.want.Interface, tt.want.IP)
+			}
+		})
+	}
+}
+
+func TestSelectDualStackNodeIPs(t *testing.T) {
+	originalAddresses, originalRoute := interfaceAddresses, procNetRoute
+	defer func() { interfaceAddresses, procNetRoute = originalAddresses, originalRoute }()
+	interfaceAddresses = func() ([]InterfaceAddress, error) {
+		return []InterfaceAddress{
+			{Interface: "eth0", IP: net.ParseIP("10.0.0.9")},
+			{Interface: "eth1", IP: net.ParseIP("fd00:10::5")},
+			{Interface: "eth1", IP: net.ParseIP("192.168.10.5")},
+		}, nil
+	}
+	procNetRoute = filepath.Join(t.TempDir(), "route")
+	routes := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth1\t000AA8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n" +
+		"eth1\t00000000\t010AA8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n"
+	if err := os.WriteFile(procNetRoute, []byte(routes), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		selector NodeIPSelector
+		want     []string
+		wantErr  bool
+	}{
+		{name: "default route interface", want: []string{"192.168.10.5", "fd00:10::5"}},
+		{name: "cidr pair", selector: NodeIPSelector{CIDR: "10.0.0.0/24,fd00:10::/64"}, want: []string{"10.0.0.9", "fd00:10::5"}},
+		{name: "address pair", selector: NodeIPSelector{Address: "fd00:10::5,192.168.10.5"}, want: []string{"192.168.10.5", "fd00:10::5"}},
+		{name: "interface without ipv6", selector: NodeIPSelector{Interface: "eth0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectDualStackNodeIPs(tt.selector)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SelectDualStackNodeIPs() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectDualStackNodeIPs() error = %v", err)
+			}
+			if len(got) != 2 || got[0].IP.String() != tt.want[0] || got[1].IP.String() != tt.want[1] {
+				t.Errorf("SelectDualStackNodeIPs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
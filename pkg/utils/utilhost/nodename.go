@@ -0,0 +1,20 @@
+package utilhost
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// hostname returns the kernel hostname, a variable so tests can replace it
+var hostname = os.Hostname
+
+// NodeName returns the name kubelet registers the node under: the hostname, lowercased because Node names
+// must be valid DNS subdomains
+func NodeName() (string, error) {
+	name, err := hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname: %w", err)
+	}
+	return strings.ToLower(name), nil
+}
@@ -0,0 +1,21 @@
+package utilhost
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNodeName(t *testing.T) {
+	original := hostname
+	t.Cleanup(func() { hostname = original })
+
+	hostname = func() (string, error) { return "Edge-Node-01", nil }
+	if name, err := NodeName(); err != nil || name != "edge-node-01" {
+		t.Errorf("NodeName() = %q, %v, want edge-node-01", name, err)
+	}
+
+	hostname = func() (string, error) { return "", errors.New("uname failed") }
+	if _, err := NodeName(); err == nil {
+		t.Error("NodeName() succeeded without a hostname")
+	}
+}
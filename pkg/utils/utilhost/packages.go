@@ -0,0 +1,105 @@
+package utilhost
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Package managers
+const (
+	PackageManagerApt = "apt"
+	PackageManagerDnf = "dnf"
+	PackageManagerYum = "yum"
+)
+
+// PackageManager returns the package manager of the host: apt on Debian and Ubuntu, and dnf on RHEL and its
+// rebuilds, or yum where dnf is missing
+func PackageManager() (string, error) {
+	distro, err := DetectDistro()
+	if err != nil {
+		return "", err
+	}
+	switch distro.Family() {
+	case DistroFamilyDebian:
+		return PackageManagerApt, nil
+	case DistroFamilyRHEL:
+		if _, err := exec.LookPath(PackageManagerDnf); err == nil {
+			return PackageManagerDnf, nil
+		}
+		return PackageManagerYum, nil
+	}
+	return "", fmt.Errorf("no known package manager on %s", distro)
+}
+
+// InstallPackages installs packages, or local package files, with the package manager of the host
+func InstallPackages(names ...string) error {
+	manager, err := PackageManager()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(manager, append([]string{"install", "-y"}, names...)...) // #nosec G204 -- packages come from the agent and its config
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if manager == PackageManagerApt {
+		cmd.Env = append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s install %s failed: %w", manager, strings.Join(names, " "), err)
+	}
+	return nil
+}
+
+// InstallPackageFile installs a local .deb or .rpm package file
+func InstallPackageFile(path string) error {
+	switch filepath.Ext(path) {
+	case ".deb", ".rpm":
+	default:
+		return fmt.Errorf("%s is not a .deb or .rpm package", path)
+	}
+	manager, err := PackageManager()
+	if err != nil {
+		return err
+	}
+	if (manager == PackageManagerApt) != (filepath.Ext(path) == ".deb") {
+		return fmt.Errorf("%s cannot be installed with %s", path, manager)
+	}
+	// apt and dnf only take a file for a path, not a bare file name
+	if !strings.Contains(path, "/") {
+		path = "./" + path
+	}
+	return InstallPackages(path)
+}
+
+// PackageInstalled reports whether a package is installed
+func PackageInstalled(name string) bool {
+	manager, err := PackageManager()
+	if err != nil {
+		return false
+	}
+	if manager == PackageManagerApt {
+		output, err := exec.Command("dpkg-query", "--show", "--showformat=${Status}", name).Output() // #nosec G204 -- package names come from the agent and its config
+		return err == nil && strings.Contains(string(output), "install ok installed")
+	}
+	return exec.Command("rpm", "-q", name).Run() == nil // #nosec G204 -- package names come from the agent and its config
+}
+
+// RemovePackage removes a package and its configuration files
+func RemovePackage(name string) error {
+	manager, err := PackageManager()
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	if manager == PackageManagerApt {
+		cmd = exec.Command("dpkg", "--purge", name) // #nosec G204 -- package names come from the agent
+	} else {
+		cmd = exec.Command("rpm", "-e", name) // #nosec G204 -- package names come from the agent
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove %s: %w, output: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
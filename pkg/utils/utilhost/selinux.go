@@ -0,0 +1,54 @@
+package utilhost
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// selinuxEnforcePath holds 1 in enforcing and 0 in permissive mode, and is missing when SELinux is disabled; a
+// variable so tests can point it at a temporary file
+var selinuxEnforcePath = "/sys/fs/selinux/enforce"
+
+// SELinux modes
+const (
+	SELinuxEnforcing  = "enforcing"
+	SELinuxPermissive = "permissive"
+	SELinuxDisabled   = "disabled"
+)
+
+// SELinuxMode returns the current SELinux mode
+func SELinuxMode() string {
+	data, err := os.ReadFile(selinuxEnforcePath)
+	if err != nil {
+		return SELinuxDisabled
+	}
+	if strings.TrimSpace(string(data)) == "1" {
+		return SELinuxEnforcing
+	}
+	return SELinuxPermissive
+}
+
+// RestoreSELinuxContexts resets the SELinux labels of the existing paths to the policy's defaults, so binaries the
+// agent installed get the types container-selinux gives them, such as container_runtime_exec_t for containerd.
+// It does nothing when SELinux is disabled.
+func RestoreSELinuxContexts(paths ...string) error {
+	if SELinuxMode() == SELinuxDisabled {
+		return nil
+	}
+	var existing []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			existing = append(existing, path)
+		}
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	output, err := exec.Command("restorecon", append([]string{"-R"}, existing...)...).CombinedOutput() // #nosec G204 -- paths are the agent's own files
+	if err != nil {
+		return fmt.Errorf("restorecon failed: %w, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
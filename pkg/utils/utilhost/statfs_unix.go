@@ -0,0 +1,14 @@
+//go:build !windows
+
+package utilhost
+
+import "syscall"
+
+// filesystemSize returns the size in bytes of the filesystem holding an existing path
+func filesystemSize(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Blocks) * stat.Bsize, nil
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package utilhost
+
+import "golang.org/x/sys/windows"
+
+// filesystemSize returns the size in bytes of the volume holding an existing path
+func filesystemSize(path string) (int64, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(name, &available, &total, &free); err != nil {
+		return 0, err
+	}
+	return int64(total), nil
+}
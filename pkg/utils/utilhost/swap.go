@@ -0,0 +1,43 @@
+package utilhost
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Swap and cgroup files, variables so tests can point them at temporary files
+var (
+	procSwapsPath = "/proc/swaps"
+	cgroupRoot    = "/sys/fs/cgroup"
+)
+
+// SwapDevices returns the swap files and partitions the kernel uses
+func SwapDevices() ([]string, error) {
+	data, err := os.ReadFile(procSwapsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", procSwapsPath, err)
+	}
+	var devices []string
+	// The first line is the header: Filename Type Size Used Priority
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			devices = append(devices, fields[0])
+		}
+	}
+	return devices, nil
+}
+
+// CgroupV2 reports whether the unified cgroup v2 hierarchy is mounted
+func CgroupV2() bool {
+	_, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// SwapAccounting reports whether the kernel accounts swap per cgroup, which kubelet needs to limit the swap of
+// pods. system.slice is checked since the root cgroup has no memory controls.
+func SwapAccounting() bool {
+	_, err := os.Stat(filepath.Join(cgroupRoot, "system.slice", "memory.swap.max"))
+	return err == nil
+}
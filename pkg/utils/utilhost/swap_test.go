@@ -0,0 +1,58 @@
+package utilhost
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSwapDevices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swaps")
+	original := procSwapsPath
+	procSwapsPath = path
+	t.Cleanup(func() { procSwapsPath = original })
+
+	swaps := "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n" +
+		"/swap.img                               file\t\t2097148\t\t0\t\t-2\n" +
+		"/dev/sdb2                               partition\t4194300\t\t0\t\t-3\n"
+	if err := os.WriteFile(path, []byte(swaps), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	devices, err := SwapDevices()
+	if err != nil {
+		t.Fatalf("SwapDevices() error = %v", err)
+	}
+	if want := []string{"/swap.img", "/dev/sdb2"}; !slices.Equal(devices, want) {
+		t.Errorf("SwapDevices() = %v, want %v", devices, want)
+	}
+
+	if err := os.WriteFile(path, []byte("Filename\tType\tSize\tUsed\tPriority\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if devices, err := SwapDevices(); err != nil || len(devices) != 0 {
+		t.Errorf("SwapDevices() without swap = %v, %v", devices, err)
+	}
+}
+
+func TestCgroupV2AndSwapAccounting(t *testing.T) {
+	root := t.TempDir()
+	original := cgroupRoot
+	cgroupRoot = root
+	t.Cleanup(func() { cgroupRoot = original })
+
+	if CgroupV2() || SwapAccounting() {
+		t.Fatal("CgroupV2() or SwapAccounting() true for an empty hierarchy")
+	}
+	if err := os.MkdirAll(filepath.Join(root, "system.slice"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"cgroup.controllers", "system.slice/memory.swap.max"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !CgroupV2() || !SwapAccounting() {
+		t.Error("CgroupV2() or SwapAccounting() false for a cgroup v2 hierarchy with swap accounting")
+	}
+}
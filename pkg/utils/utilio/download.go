@@ -13,29 +13,76 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
 )
 
 var remoteHTTPClient = &http.Client{
-	Timeout: 10 * time.Minute, // FIXME: proper configuration
+	Timeout: 10 * time.Minute,
+}
+
+// downloadRetry is the retry policy of download requests
+var downloadRetry = utils.Backoff{Attempts: 3, InitialDelay: 2 * time.Second, MaxDelay: 30 * time.Second, Jitter: 0.2}
+
+// SetDownloadTimeout changes the timeout of each remote download
+func SetDownloadTimeout(timeout time.Duration) {
+	remoteHTTPClient.Timeout = timeout
+}
+
+// DownloadError is returned when the server answers a download request with a status other than 200 OK
+type DownloadError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("download %q failed with status code %d", e.URL, e.StatusCode)
 }
 
+// SetDownloadRetry changes the retry policy of remote download requests
+func SetDownloadRetry(backoff utils.Backoff) {
+	downloadRetry = backoff
+}
+
+// downloadFromRemote requests url, retrying connection failures and throttled or transient responses. Once the
+// body is streaming, a failure is returned to the caller.
 func downloadFromRemote(ctx context.Context, url string) (io.ReadCloser, error) {
+	var lastErr error
+	for attempt := range max(downloadRetry.Attempts, 1) {
+		if attempt > 0 {
+			if err := downloadRetry.Wait(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
+		body, retriable, err := requestRemote(ctx, url)
+		if err == nil || !retriable {
+			return body, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// requestRemote performs a single download request and reports whether a failure is worth retrying
+func requestRemote(ctx context.Context, url string) (io.ReadCloser, bool, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, false, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	resp, err := remoteHTTPClient.Do(req) // #nosec - FIXME: harden to mitigate SSRF in the following PRs
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform HTTP request: %w", err)
+		return nil, ctx.Err() == nil, fmt.Errorf("failed to perform HTTP request: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		_ = resp.Body.Close() //nolint:errcheck // body close
-		return nil, fmt.Errorf("download %q failed with status code %d", url, resp.StatusCode)
+		retriable := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode >= http.StatusInternalServerError
+		return nil, retriable, &DownloadError{URL: url, StatusCode: resp.StatusCode}
 	}
 
-	return resp.Body, nil
+	return resp.Body, false, nil
 }
 
 type TarFile struct {
@@ -13,9 +13,17 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/utils"
 )
 
+func init() {
+	// Retry failed requests without waiting between attempts
+	downloadRetry = utils.Backoff{Attempts: 3}
+}
+
 func TestDownloadFromRemote(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -80,6 +88,46 @@ func TestDownloadFromRemote(t *testing.T) {
 	}
 }
 
+func TestDownloadFromRemote_retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		wantErr      bool
+		wantRequests int32
+	}{
+		{name: "transient failures are retried", failures: 2, status: http.StatusServiceUnavailable, wantRequests: 3},
+		{name: "throttling is retried", failures: 1, status: http.StatusTooManyRequests, wantRequests: 2},
+		{name: "attempts run out", failures: 3, status: http.StatusBadGateway, wantErr: true, wantRequests: 3},
+		{name: "client errors are not retried", failures: 1, status: http.StatusNotFound, wantErr: true, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(requests.Add(1)) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				fmt.Fprint(w, "binary")
+			}))
+			defer srv.Close()
+
+			body, err := downloadFromRemote(context.Background(), srv.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadFromRemote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if body != nil {
+				_ = body.Close()
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
 func TestDownloadFromRemote_cancelledContext(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, "should not reach here")
@@ -6,8 +6,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-
-	"github.com/google/renameio/v2"
 )
 
 var ErrFileTooLarge = errors.New("file exceeds maximum allowed size")
@@ -30,28 +28,25 @@ func InstallFileWithLimitedSize(filename string, r io.Reader, perm os.FileMode,
 	if maxBytes <= 0 {
 		return fmt.Errorf("invalid maxBytes: %d", maxBytes)
 	}
+	recordWrite := trackWrite(filename)
 	if err := os.MkdirAll(filepath.Dir(filename), 0750); err != nil {
 		return err
 	}
 
-	pf, err := renameio.NewPendingFile(filename, renameio.WithPermissions(perm))
-	if err != nil {
-		return err
-	}
-	defer pf.Cleanup() // nolint:errcheck // pending file cleanup
-
-	lr := io.LimitReader(r, maxBytes+1)
-	n, err := io.Copy(pf, lr)
+	err := replaceFile(filename, perm, func(w io.Writer) error {
+		n, err := io.Copy(w, io.LimitReader(r, maxBytes+1))
+		if err != nil {
+			return err
+		}
+		if n > maxBytes {
+			return fmt.Errorf("%w: %d bytes exceeds limit %d", ErrFileTooLarge, n, maxBytes)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if n > maxBytes {
-		return fmt.Errorf("%w: %d bytes exceeds limit %d", ErrFileTooLarge, n, maxBytes)
-	}
-
-	if err := pf.CloseAtomicallyReplace(); err != nil {
-		return err
-	}
+	recordWrite()
 	return nil
 }
 
@@ -60,9 +55,23 @@ func InstallFileWithLimitedSize(filename string, r io.Reader, perm os.FileMode,
 //
 // NOTE: we assume the filename is trusted and cleaned without path traversal characters.
 func WriteFile(filename string, content []byte, perm os.FileMode) error {
+	recordWrite := trackWrite(filename)
 	if err := os.MkdirAll(filepath.Dir(filename), 0750); err != nil {
 		return err
 	}
 
-	return renameio.WriteFile(filename, content, perm)
+	if err := ReplaceFile(filename, content, perm); err != nil {
+		return err
+	}
+	recordWrite()
+	return nil
+}
+
+// ReplaceFile atomically replaces the content of filename like WriteFile, without creating its directory or
+// recording it in the installed files manifest. It is meant for the agent's own state and caches.
+func ReplaceFile(filename string, content []byte, perm os.FileMode) error {
+	return replaceFile(filename, perm, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
 }
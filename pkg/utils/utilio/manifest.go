@@ -0,0 +1,285 @@
+package utilio
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Kinds of manifest entries. Units and sysctl files are files that need systemd or the kernel to be told
+// about their removal. Replaced files existed before and were overwritten; their originals are kept as backups.
+// Modules are kernel modules the agent loaded, listed by name rather than path.
+const (
+	ManifestKindFile      = "file"
+	ManifestKindDirectory = "directory"
+	ManifestKindUnit      = "unit"
+	ManifestKindSysctl    = "sysctl"
+	ManifestKindReplaced  = "replaced"
+	ManifestKindModule    = "module"
+)
+
+const (
+	// systemdUnitDir holds the units and drop-ins installed by the agent
+	systemdUnitDir = "/etc/systemd/system"
+	// sysctlConfigDir holds the sysctl files installed by the agent
+	sysctlConfigDir = "/etc/sysctl.d"
+)
+
+// ManifestEntry is a path created on the host
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Backup string `json:"backup,omitempty"` // Copy of the original of a replaced file
+}
+
+// Manifest lists the paths created through WriteFile, InstallFile, MkdirAll and TrackCreated while recording
+// was on, in creation order. Files that already existed are listed as replaced with a backup of their original,
+// so removing the created entries never touches files the agent only modified.
+type Manifest struct {
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ManifestStore keeps the manifest between runs, such as in the agent's state store
+type ManifestStore interface {
+	// LoadManifest returns the recorded manifest, or an error wrapping os.ErrNotExist when nothing was recorded
+	LoadManifest() (*Manifest, error)
+	// SaveManifest replaces the recorded manifest
+	SaveManifest(manifest *Manifest) error
+}
+
+// recorder is the manifest created paths are recorded into, if any
+var recorder struct {
+	sync.Mutex
+	store     ManifestStore
+	originals string
+	exclude   []string
+}
+
+// RecordCreatedPaths records the files and directories created by WriteFile, InstallFile, MkdirAll and
+// TrackCreated into the manifest in store, merging with what earlier runs recorded, until the returned function
+// is called. The originals of replaced files are copied below the originals directory under their own paths.
+// Paths under exclude are not recorded.
+func RecordCreatedPaths(store ManifestStore, originals string, exclude ...string) (stop func()) {
+	recorder.Lock()
+	defer recorder.Unlock()
+	recorder.store = store
+	recorder.originals = originals
+	recorder.exclude = append(exclude, originals)
+	return func() {
+		recorder.Lock()
+		defer recorder.Unlock()
+		recorder.store = nil
+		recorder.originals = ""
+		recorder.exclude = nil
+	}
+}
+
+// missingPaths returns the parent directories of filename that do not exist yet, outermost first, and
+// whether filename itself does not exist
+func missingPaths(filename string) (dirs []string, fileMissing bool) {
+	if _, err := os.Lstat(filename); err == nil {
+		return nil, false
+	}
+	for dir := filepath.Dir(filename); ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil || dir == filepath.Dir(dir) {
+			break
+		}
+		dirs = append(dirs, dir)
+	}
+	slices.Reverse(dirs)
+	return dirs, true
+}
+
+// ClearManifest removes the originals kept below the originals directory and leaves the manifest in store
+// empty, so uninstallers run again later know that nothing bootstrap created is left
+func ClearManifest(store ManifestStore, originals string) error {
+	if err := os.RemoveAll(originals); err != nil {
+		return err
+	}
+	return store.SaveManifest(&Manifest{Entries: []ManifestEntry{}})
+}
+
+// trackWrite prepares the manifest for a write of filename and returns the function recording the write once
+// it succeeded. An existing file is backed up before its first overwrite.
+func trackWrite(filename string) (done func()) {
+	createdDirs, created := missingPaths(filename)
+	if !created {
+		backupOriginal(filename)
+		return func() {}
+	}
+	return func() {
+		recordCreated(filename, createdDirs, ManifestEntry{Path: filepath.Clean(filename), Kind: manifestKind(filename)})
+	}
+}
+
+// backupOriginal copies a file to the originals directory before the agent overwrites it for the first time
+// and lists it as replaced. Files the agent created, or backed up before, are not copied again.
+func backupOriginal(filename string) {
+	recorder.Lock()
+	defer recorder.Unlock()
+	manifest := recordedManifest(filename)
+	if manifest == nil {
+		return
+	}
+	filename = filepath.Clean(filename)
+	if manifest.lists(filename) {
+		return
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+	original, err := os.ReadFile(filename) // #nosec G304 -- the agent writes this file next
+	if err != nil {
+		return
+	}
+	// Not through WriteFile, which would record the backup itself
+	backup := filepath.Join(recorder.originals, filename)
+	if err := os.MkdirAll(filepath.Dir(backup), 0o700); err != nil {
+		return
+	}
+	if err := ReplaceFile(backup, original, info.Mode().Perm()); err != nil {
+		return
+	}
+	manifest.add(ManifestEntry{Path: filename, Kind: ManifestKindReplaced, Backup: backup})
+	_ = recorder.store.SaveManifest(manifest)
+}
+
+// MkdirAll creates a directory and its missing parents like os.MkdirAll, and records the ones it created
+func MkdirAll(path string, perm os.FileMode) error {
+	recordCreate := TrackCreated(path)
+	if err := os.MkdirAll(path, perm); err != nil {
+		return err
+	}
+	recordCreate()
+	return nil
+}
+
+// TrackCreated prepares the manifest for a path created other than through WriteFile, InstallFile or MkdirAll,
+// such as a tree extracted from an archive or a symlink, and returns the function recording it once it was
+// created. The path, its missing parents and everything below it are recorded. A path that already exists is
+// left to its owner.
+func TrackCreated(path string) (done func()) {
+	createdDirs, created := missingPaths(path)
+	if !created {
+		return func() {}
+	}
+	return func() {
+		var entries []ManifestEntry
+		_ = filepath.WalkDir(path, func(walked string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			kind := manifestKind(walked)
+			if d.IsDir() {
+				kind = ManifestKindDirectory
+			}
+			entries = append(entries, ManifestEntry{Path: filepath.Clean(walked), Kind: kind})
+			return nil
+		})
+		recordCreated(path, createdDirs, entries...)
+	}
+}
+
+// recordCreated adds the directories and the paths created by a write to the manifest. Recording is best
+// effort: a path it misses is still removed by the uninstaller of its component.
+func recordCreated(filename string, dirs []string, created ...ManifestEntry) {
+	recorder.Lock()
+	defer recorder.Unlock()
+	manifest := recordedManifest(filename)
+	if manifest == nil {
+		return
+	}
+	for _, dir := range dirs {
+		manifest.add(ManifestEntry{Path: dir, Kind: ManifestKindDirectory})
+	}
+	for _, entry := range created {
+		manifest.add(entry)
+	}
+	_ = recorder.store.SaveManifest(manifest)
+}
+
+// recordedManifest returns the manifest to record filename into, or nil when recording is off, filename is
+// excluded or the manifest cannot be read. The caller holds the recorder lock.
+func recordedManifest(filename string) *Manifest {
+	if recorder.store == nil || isExcluded(filename, recorder.exclude) {
+		return nil
+	}
+	manifest, err := recorder.store.LoadManifest()
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{}
+	}
+	if err != nil {
+		return nil
+	}
+	return manifest
+}
+
+// RecordLoadedModule lists a kernel module the agent loaded, which was not loaded before, in the manifest
+func RecordLoadedModule(module string) {
+	recorder.Lock()
+	defer recorder.Unlock()
+	manifest := recordedManifest(module)
+	if manifest == nil {
+		return
+	}
+	manifest.add(ManifestEntry{Path: module, Kind: ManifestKindModule})
+	_ = recorder.store.SaveManifest(manifest)
+}
+
+// LoadedModules returns the kernel modules the manifest lists as loaded by the agent
+func (m *Manifest) LoadedModules() []string {
+	var modules []string
+	for _, entry := range m.Entries {
+		if entry.Kind == ManifestKindModule {
+			modules = append(modules, entry.Path)
+		}
+	}
+	return modules
+}
+
+// Created reports whether the manifest lists path as created by the agent, rather than as a replaced file
+func (m *Manifest) Created(path string) bool {
+	return slices.ContainsFunc(m.Entries, func(entry ManifestEntry) bool {
+		return entry.Path == filepath.Clean(path) && entry.Kind != ManifestKindReplaced && entry.Kind != ManifestKindModule
+	})
+}
+
+// lists reports whether the manifest has an entry for path
+func (m *Manifest) lists(path string) bool {
+	return slices.ContainsFunc(m.Entries, func(entry ManifestEntry) bool { return entry.Path == path })
+}
+
+// add appends entry unless the manifest already lists its path
+func (m *Manifest) add(entry ManifestEntry) {
+	if !m.lists(entry.Path) {
+		m.Entries = append(m.Entries, entry)
+	}
+}
+
+// manifestKind classifies a created file: units directly in the systemd unit directory and sysctl files
+func manifestKind(filename string) string {
+	switch dir := filepath.Dir(filename); {
+	case dir == systemdUnitDir && slices.Contains([]string{".service", ".mount", ".socket", ".timer"}, filepath.Ext(filename)):
+		return ManifestKindUnit
+	case dir == sysctlConfigDir && filepath.Ext(filename) == ".conf":
+		return ManifestKindSysctl
+	default:
+		return ManifestKindFile
+	}
+}
+
+// isExcluded reports whether filename is one of the excluded paths or under one of them
+func isExcluded(filename string, exclude []string) bool {
+	for _, excluded := range exclude {
+		if excluded != "" && (filename == excluded || strings.HasPrefix(filename, strings.TrimSuffix(excluded, "/")+"/")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package utilio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// memoryManifestStore keeps the manifest in memory
+type memoryManifestStore struct {
+	manifest *Manifest
+}
+
+func (s *memoryManifestStore) LoadManifest() (*Manifest, error) {
+	if s.manifest == nil {
+		return nil, fmt.Errorf("no manifest: %w", os.ErrNotExist)
+	}
+	return &Manifest{Entries: append([]ManifestEntry{}, s.manifest.Entries...)}, nil
+}
+
+func (s *memoryManifestStore) SaveManifest(manifest *Manifest) error {
+	s.manifest = manifest
+	return nil
+}
+
+func TestRecordCreatedPaths(t *testing.T) {
+	root := t.TempDir()
+	store := &memoryManifestStore{}
+	stop := RecordCreatedPaths(store, filepath.Join(root, "state", "originals"), filepath.Join(root, "logs"))
+	for _, path := range []string{
+		filepath.Join(root, "opt", "bin", "tool"),
+		filepath.Join(root, "opt", "bin", "tool"),
+		filepath.Join(root, "logs", "agent.log"),
+	} {
+		if err := WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop()
+	if err := WriteFile(filepath.Join(root, "after-stop"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := store.LoadManifest()
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	want := []ManifestEntry{
+		{Path: filepath.Join(root, "opt"), Kind: ManifestKindDirectory},
+		{Path: filepath.Join(root, "opt", "bin"), Kind: ManifestKindDirectory},
+		{Path: filepath.Join(root, "opt", "bin", "tool"), Kind: ManifestKindFile},
+	}
+	if !reflect.DeepEqual(manifest.Entries, want) {
+		t.Errorf("manifest = %+v, want %+v", manifest.Entries, want)
+	}
+}
+
+func TestManifestKind(t *testing.T) {
+	tests := map[string]string{
+		"/etc/systemd/system/kubelet.service":                 ManifestKindUnit,
+		"/etc/systemd/system/kubelet.service.d/10-proxy.conf": ManifestKindFile,
+		"/etc/sysctl.d/999-sysctl-aks.conf":                   ManifestKindSysctl,
+		"/usr/local/bin/kubelet":                              ManifestKindFile,
+	}
+	for path, want := range tests {
+		if got := manifestKind(path); got != want {
+			t.Errorf("manifestKind(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRecordReplacedFile(t *testing.T) {
+	root := t.TempDir()
+	store := &memoryManifestStore{}
+	originals := filepath.Join(root, "state", "originals")
+	existing := filepath.Join(root, "etc", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(existing), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("original"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := RecordCreatedPaths(store, originals)
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFile(existing, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop()
+
+	manifest, err := store.LoadManifest()
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if len(manifest.Entries) != 1 || manifest.Entries[0].Kind != ManifestKindReplaced {
+		t.Fatalf("manifest = %+v, want one replaced entry", manifest.Entries)
+	}
+	backup, err := os.ReadFile(manifest.Entries[0].Backup)
+	if err != nil || string(backup) != "original" {
+		t.Errorf("backup = %q, %v, want the original content", backup, err)
+	}
+
+	if manifest.Created(existing) {
+		t.Errorf("Created(%s) = true for a replaced file", existing)
+	}
+
+	if err := ClearManifest(store, originals); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Dir(manifest.Entries[0].Backup)); !os.IsNotExist(err) {
+		t.Errorf("originals were not removed with the manifest: %v", err)
+	}
+	if cleared, err := store.LoadManifest(); err != nil || len(cleared.Entries) != 0 {
+		t.Errorf("cleared manifest = %+v, %v, want no entries", cleared, err)
+	}
+}
+
+func TestRecordLoadedModule(t *testing.T) {
+	RecordLoadedModule("overlay")
+
+	store := &memoryManifestStore{}
+	stop := RecordCreatedPaths(store, filepath.Join(t.TempDir(), "originals"))
+	for _, module := range []string{"br_netfilter", "ip_vs", "br_netfilter"} {
+		RecordLoadedModule(module)
+	}
+	stop()
+
+	manifest, err := store.LoadManifest()
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if modules := manifest.LoadedModules(); !reflect.DeepEqual(modules, []string{"br_netfilter", "ip_vs"}) {
+		t.Errorf("LoadedModules() = %v, want the modules loaded while recording", modules)
+	}
+	if manifest.Created("ip_vs") {
+		t.Error("Created() = true for a module")
+	}
+}
+
+func TestRecordCreatedDirectoriesAndTrees(t *testing.T) {
+	root := t.TempDir()
+	store := &memoryManifestStore{}
+	originals := filepath.Join(root, "state", "originals")
+	existing := filepath.Join(root, "etc")
+	if err := os.MkdirAll(existing, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stop := RecordCreatedPaths(store, originals)
+	defer stop()
+
+	// Directories made by MkdirAll, an existing one left out
+	for _, dir := range []string{filepath.Join(root, "etc", "kubernetes", "manifests"), existing} {
+		if err := MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A tree made by an external command, such as tar
+	tree := filepath.Join(root, "opt", "kata")
+	recordTree := TrackCreated(tree)
+	if err := os.MkdirAll(filepath.Join(tree, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tree, "bin", "kata-runtime"), []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	recordTree()
+
+	manifest, err := store.LoadManifest()
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	want := []ManifestEntry{
+		{Path: filepath.Join(root, "etc", "kubernetes"), Kind: ManifestKindDirectory},
+		{Path: filepath.Join(root, "etc", "kubernetes", "manifests"), Kind: ManifestKindDirectory},
+		{Path: filepath.Join(root, "opt"), Kind: ManifestKindDirectory},
+		{Path: tree, Kind: ManifestKindDirectory},
+		{Path: filepath.Join(tree, "bin"), Kind: ManifestKindDirectory},
+		{Path: filepath.Join(tree, "bin", "kata-runtime"), Kind: ManifestKindFile},
+	}
+	if !reflect.DeepEqual(manifest.Entries, want) {
+		t.Errorf("manifest = %+v, want %+v", manifest.Entries, want)
+	}
+	if !manifest.Created(filepath.Join(root, "etc", "kubernetes")+"/") || manifest.Created(existing) {
+		t.Errorf("Created() does not match the recorded directories")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package utilio
+
+import (
+	"io"
+	"os"
+
+	"github.com/google/renameio/v2"
+)
+
+// replaceFile writes filename through a temporary file in the same directory that replaces it atomically once
+// write succeeded, so readers see either the old or the new content
+func replaceFile(filename string, perm os.FileMode, write func(io.Writer) error) error {
+	pf, err := renameio.NewPendingFile(filename, renameio.WithPermissions(perm))
+	if err != nil {
+		return err
+	}
+	defer pf.Cleanup() // nolint:errcheck // pending file cleanup
+
+	if err := write(pf); err != nil {
+		return err
+	}
+	return pf.CloseAtomicallyReplace()
+}
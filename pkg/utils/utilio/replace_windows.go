@@ -0,0 +1,35 @@
+//go:build windows
+
+package utilio
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// replaceFile writes filename through a temporary file in the same directory that replaces it once write
+// succeeded. os.Rename replaces an existing file on Windows as well, through MoveFileEx.
+func replaceFile(filename string, perm os.FileMode, write func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
@@ -32,12 +32,28 @@ func RunCommandWithOutput(name string, args ...string) (string, error) {
 	return string(output), err
 }
 
+// SystemdEnvironmentDropIn renders a systemd drop-in that sets the given NAME=value environment variables
+func SystemdEnvironmentDropIn(env []string) string {
+	var sb strings.Builder
+	sb.WriteString("[Service]\n")
+	for _, variable := range env {
+		sb.WriteString(fmt.Sprintf("Environment=\"%s\"\n", variable))
+	}
+	return sb.String()
+}
+
 // FileExists checks if a file exists
 func FileExists(path string) bool {
 	_, err := os.Stat(path)
 	return !os.IsNotExist(err)
 }
 
+// FileHasContent reports whether the file at path holds exactly content
+func FileHasContent(path, content string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && string(data) == content
+}
+
 // FileExistsAndValid checks if a file exists and is not empty (useful for binaries)
 func FileExistsAndValid(path string) bool {
 	stat, err := os.Stat(path)
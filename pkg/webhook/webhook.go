@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+const (
+	// SignatureHeader carries the payload signature in the form t=<unix timestamp>,v1=<hex HMAC-SHA256>.
+	// The HMAC covers "<timestamp>.<body>" so receivers can reject replayed deliveries.
+	SignatureHeader = "X-AKS-Flex-Node-Signature"
+
+	// EventBootstrapCompleted is sent when a bootstrap run finishes, successfully or not
+	EventBootstrapCompleted = "bootstrap.completed"
+
+	maxAttempts  = 3
+	initialDelay = 2 * time.Second
+)
+
+// Payload is the JSON document POSTed to the webhook
+type Payload struct {
+	Event           string        `json:"event"`
+	Timestamp       time.Time     `json:"timestamp"`
+	NodeName        string        `json:"nodeName"`
+	ProviderID      string        `json:"providerId,omitempty"`
+	ClusterID       string        `json:"clusterResourceId"`
+	Success         bool          `json:"success"`
+	Error           string        `json:"error,omitempty"`
+	DurationSeconds float64       `json:"durationSeconds"`
+	Steps           []StepSummary `json:"steps"`
+}
+
+// StepSummary is the outcome of a single bootstrap step
+type StepSummary struct {
+	Name            string  `json:"name"`
+	Success         bool    `json:"success"`
+	Skipped         bool    `json:"skipped,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Notifier delivers signed payloads to the configured webhook
+type Notifier struct {
+	cfg    config.WebhookConfig
+	client *http.Client
+	logger *logrus.Logger
+}
+
+// New creates a notifier for the configured webhook, or returns nil when no webhook is configured
+func New(cfg config.WebhookConfig, logger *logrus.Logger) *Notifier {
+	if cfg.URL == "" {
+		return nil
+	}
+	return &Notifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger,
+	}
+}
+
+// Send POSTs the payload, retrying transient failures. Delivery is best effort: the error is returned
+// for logging, but a failed delivery never changes the bootstrap outcome.
+func (n *Notifier) Send(ctx context.Context, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	var lastErr error
+	for attempt := range maxAttempts {
+		if attempt > 0 {
+			select {
+			case <-time.After(initialDelay * time.Duration(1<<(attempt-1))):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		retry, err := n.deliver(ctx, body)
+		if err == nil {
+			n.logger.Infof("Bootstrap result sent to webhook %s", n.cfg.URL)
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+		n.logger.Warnf("Webhook delivery attempt %d/%d failed: %v", attempt+1, maxAttempts, err)
+	}
+	return fmt.Errorf("failed to deliver webhook to %s: %w", n.cfg.URL, lastErr)
+}
+
+// deliver performs a single signed POST and reports whether a failure is worth retrying
+func (n *Notifier) deliver(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign([]byte(n.cfg.Secret), time.Now(), body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+}
+
+// Sign returns the signature header value for body sent at timestamp
+func Sign(secret []byte, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
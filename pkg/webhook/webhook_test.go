@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+)
+
+func TestSendSignsPayload(t *testing.T) {
+	secret := "test-secret"
+	var received Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		// Recompute the signature the way a receiver would
+		signature := r.Header.Get(SignatureHeader)
+		ts, _, _ := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			t.Errorf("invalid signature timestamp in %q", signature)
+		}
+		if want := Sign([]byte(secret), time.Unix(unix, 0), body); !hmac.Equal([]byte(signature), []byte(want)) {
+			t.Errorf("signature = %q, want %q", signature, want)
+		}
+
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := New(config.WebhookConfig{URL: server.URL, Secret: secret, Timeout: time.Second}, logrus.New())
+	payload := Payload{Event: EventBootstrapCompleted, NodeName: "node-1", Success: true}
+	if err := notifier.Send(context.Background(), payload); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if received.NodeName != "node-1" || !received.Success {
+		t.Errorf("received payload = %+v", received)
+	}
+}
+
+func TestSendDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	notifier := New(config.WebhookConfig{URL: server.URL, Secret: "s", Timeout: time.Second}, logrus.New())
+	if err := notifier.Send(context.Background(), Payload{}); err == nil {
+		t.Fatal("expected an error for a 400 response")
+	}
+	if calls != 1 {
+		t.Errorf("expected a single delivery attempt, got %d", calls)
+	}
+}
+
+func TestNewWithoutURLDisablesWebhook(t *testing.T) {
+	if New(config.WebhookConfig{}, logrus.New()) != nil {
+		t.Error("expected no notifier without a URL")
+	}
+}
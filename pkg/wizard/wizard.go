@@ -0,0 +1,495 @@
+package wizard
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v5"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+// Authentication methods the wizard configures. Federated identities and bootstrap tokens take settings from
+// outside Azure, so they are added to the written file by hand.
+const (
+	AuthArc              = "arc"
+	AuthServicePrincipal = "service-principal"
+	AuthManagedIdentity  = "managed-identity"
+)
+
+// authMethods lists the authentication methods in the order they are offered
+var authMethods = []string{AuthArc, AuthServicePrincipal, AuthManagedIdentity}
+
+// guidPattern matches subscription, tenant and client IDs
+var guidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// kubernetesVersionPattern matches the kubernetes.version setting, e.g. 1.32.7
+var kubernetesVersionPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+
+// resourceNamePattern matches resource group and cluster names, like the AKS cluster resource ID pattern
+var resourceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_\-\.]+$`)
+
+// locationPattern matches Azure region names such as eastus2
+var locationPattern = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// Answers are the settings collected by the wizard
+type Answers struct {
+	SubscriptionID    string
+	TenantID          string
+	ClusterResourceID string
+	Location          string
+	KubernetesVersion string
+	Auth              string
+	ClientID          string // Client ID of the service principal, or of a user-assigned managed identity
+	ClientSecret      string
+	ArcResourceGroup  string
+	ArcMachineName    string
+}
+
+// Cluster is an AKS cluster found in the subscription, offered as the target cluster
+type Cluster struct {
+	ResourceID        string
+	Name              string
+	Location          string
+	KubernetesVersion string
+}
+
+// CLIAccount is the subscription the Azure CLI is logged in to
+type CLIAccount struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenantId"`
+}
+
+// Wizard asks for the settings of a new config file. Defaults come from the Azure instance metadata service
+// and the Azure CLI context, and the answers are checked against Azure before the file is written.
+type Wizard struct {
+	logger     *logrus.Logger
+	in         *bufio.Reader
+	out        io.Writer
+	readSecret func() (string, error)
+
+	// Discovery and the live check, replaced in tests
+	detectAzureVM func(ctx context.Context) *utilhost.AzureVMMetadata
+	cliAccount    func(ctx context.Context) (*CLIAccount, error)
+	listClusters  func(ctx context.Context, subscriptionID string) ([]Cluster, error)
+	checkCluster  func(ctx context.Context, cfg *config.Config) (*spec.ManagedClusterSpec, error)
+	hostname      func() (string, error)
+}
+
+// New creates a wizard that reads answers from in and writes questions to out. Secrets are read without echo
+// when in is a terminal.
+func New(logger *logrus.Logger, in *os.File, out io.Writer) *Wizard {
+	w := &Wizard{
+		logger:        logger,
+		in:            bufio.NewReader(in),
+		out:           out,
+		detectAzureVM: utilhost.DetectAzureVM,
+		cliAccount:    azureCLIAccount,
+		listClusters:  listClusters,
+		hostname:      os.Hostname,
+	}
+	w.readSecret = w.readLine
+	if term.IsTerminal(int(in.Fd())) {
+		w.readSecret = func() (string, error) {
+			secret, err := term.ReadPassword(int(in.Fd()))
+			_, _ = fmt.Fprintln(out)
+			return string(secret), err
+		}
+	}
+	w.checkCluster = func(ctx context.Context, cfg *config.Config) (*spec.ManagedClusterSpec, error) {
+		dir, err := os.MkdirTemp("", "aks-flex-node-init-")
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		return spec.NewManagedClusterSpecCollectorWithClient(cfg, logger, nil, filepath.Join(dir, "spec.json")).Collect(ctx)
+	}
+	return w
+}
+
+// Run asks for the settings, discovering defaults first
+func (w *Wizard) Run(ctx context.Context) (*Answers, error) {
+	vm := w.detectAzureVM(ctx)
+	account, err := w.cliAccount(ctx)
+	if err != nil {
+		w.logger.Debugf("No Azure CLI context to take defaults from: %v", err)
+		account = &CLIAccount{}
+	}
+
+	w.say("This wizard writes an AKS Flex Node config file. Press Enter to accept the value in brackets.\n\n")
+	a := &Answers{}
+
+	subscriptionDefault := account.ID
+	if subscriptionDefault == "" && vm != nil {
+		subscriptionDefault = vm.SubscriptionID
+	}
+	if a.SubscriptionID, err = w.ask("Azure subscription ID", subscriptionDefault, validGUID); err != nil {
+		return nil, err
+	}
+	if a.TenantID, err = w.ask("Azure tenant ID", account.TenantID, validGUID); err != nil {
+		return nil, err
+	}
+
+	cluster, err := w.askCluster(ctx, a.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	a.ClusterResourceID = cluster.ResourceID
+	locationDefault := cluster.Location
+	if locationDefault == "" && vm != nil {
+		locationDefault = vm.Location
+	}
+	if a.Location, err = w.ask("Cluster location", locationDefault, validLocation); err != nil {
+		return nil, err
+	}
+	if a.KubernetesVersion, err = w.ask("Kubernetes version of the node", cluster.KubernetesVersion, validKubernetesVersion); err != nil {
+		return nil, err
+	}
+
+	// Azure VMs join with their managed identity, other machines through Arc
+	authDefault := AuthArc
+	if vm != nil {
+		authDefault = AuthManagedIdentity
+	}
+	if a.Auth, err = w.ask("Authentication ("+strings.Join(authMethods, ", ")+")", authDefault, validAuth); err != nil {
+		return nil, err
+	}
+	if err := w.askAuth(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// askCluster offers the clusters of the subscription, or asks for the resource group and name of the cluster
+// when none can be listed
+func (w *Wizard) askCluster(ctx context.Context, subscriptionID string) (Cluster, error) {
+	clusters, err := w.listClusters(ctx, subscriptionID)
+	if err != nil {
+		w.logger.Debugf("Failed to list AKS clusters in subscription %s: %v", subscriptionID, err)
+	}
+	if len(clusters) > 0 {
+		w.say("\nAKS clusters in subscription %s:\n", subscriptionID)
+		for i, cluster := range clusters {
+			w.say("  %d) %s (%s, Kubernetes %s)\n", i+1, cluster.Name, cluster.Location, cluster.KubernetesVersion)
+		}
+		answer, err := w.ask("AKS cluster (number or resource ID)", "1", func(answer string) error {
+			n, err := strconv.Atoi(answer)
+			if err != nil {
+				return validClusterID(answer)
+			}
+			if n < 1 || n > len(clusters) {
+				return fmt.Errorf("choose a number from 1 to %d", len(clusters))
+			}
+			return nil
+		})
+		if err != nil {
+			return Cluster{}, err
+		}
+		if n, err := strconv.Atoi(answer); err == nil {
+			return clusters[n-1], nil
+		}
+		return Cluster{ResourceID: answer}, nil
+	}
+
+	resourceGroup, err := w.ask("Resource group of the AKS cluster", "", validResourceName)
+	if err != nil {
+		return Cluster{}, err
+	}
+	name, err := w.ask("AKS cluster name", "", validResourceName)
+	if err != nil {
+		return Cluster{}, err
+	}
+	return Cluster{ResourceID: fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s",
+		subscriptionID, resourceGroup, name)}, nil
+}
+
+// askAuth asks for the settings of the chosen authentication method
+func (w *Wizard) askAuth(a *Answers) error {
+	var err error
+	switch a.Auth {
+	case AuthArc:
+		clusterResourceGroup := config.AKSClusterResourceIDPattern.FindStringSubmatch(a.ClusterResourceID)[2]
+		if a.ArcResourceGroup, err = w.ask("Resource group of the Arc machine", clusterResourceGroup, validResourceName); err != nil {
+			return err
+		}
+		hostname, _ := w.hostname()
+		hostname, _, _ = strings.Cut(hostname, ".")
+		a.ArcMachineName, err = w.ask("Arc machine name", hostname, required)
+		return err
+	case AuthServicePrincipal:
+		if a.ClientID, err = w.ask("Client ID of the service principal", "", validGUID); err != nil {
+			return err
+		}
+		for a.ClientSecret == "" {
+			w.say("Client secret of the service principal: ")
+			if a.ClientSecret, err = w.readSecret(); err != nil {
+				return fmt.Errorf("failed to read the client secret: %w", err)
+			}
+			a.ClientSecret = strings.TrimSpace(a.ClientSecret)
+		}
+		return nil
+	default:
+		a.ClientID, err = w.ask("Client ID of a user-assigned identity (empty for the VM's identity)", "", optional(validGUID))
+		return err
+	}
+}
+
+// Document returns the config file for the answers
+func (a *Answers) Document() map[string]any {
+	azure := map[string]any{
+		"subscriptionId": a.SubscriptionID,
+		"tenantId":       a.TenantID,
+		"cloud":          "AzurePublicCloud",
+		"arc":            map[string]any{"enabled": false},
+		"targetCluster": map[string]any{
+			"resourceId": a.ClusterResourceID,
+			"location":   a.Location,
+		},
+	}
+	switch a.Auth {
+	case AuthArc:
+		azure["arc"] = map[string]any{
+			"enabled":       true,
+			"machineName":   a.ArcMachineName,
+			"resourceGroup": a.ArcResourceGroup,
+			"location":      a.Location,
+		}
+	case AuthServicePrincipal:
+		azure["servicePrincipal"] = map[string]any{
+			"tenantId":     a.TenantID,
+			"clientId":     a.ClientID,
+			"clientSecret": a.ClientSecret,
+		}
+	case AuthManagedIdentity:
+		identity := map[string]any{}
+		if a.ClientID != "" {
+			identity["clientId"] = a.ClientID
+		}
+		azure["managedIdentity"] = identity
+	}
+	return map[string]any{
+		"azure":      azure,
+		"kubernetes": map[string]any{"version": strings.TrimPrefix(a.KubernetesVersion, "v")},
+		"agent": map[string]any{
+			"logLevel": "info",
+			"logDir":   "/var/log/aks-flex-node",
+		},
+	}
+}
+
+// Check validates the config file for the answers like bootstrap loads it, and reads the target cluster with
+// the configured identity
+func (w *Wizard) Check(ctx context.Context, a *Answers) error {
+	dir, err := os.MkdirTemp("", "aks-flex-node-init-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "config.json")
+	if err := Write(path, a); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	w.say("\nChecking that the configured identity can read cluster %s...\n", cfg.GetTargetClusterName())
+	cluster, err := w.checkCluster(ctx, cfg)
+	if err != nil {
+		if a.Auth == AuthArc {
+			// Until the machine is connected to Arc, bootstrap reads the cluster with the Azure CLI login
+			return fmt.Errorf("failed to read the cluster with the Azure CLI login, run 'az login' first: %w", err)
+		}
+		return fmt.Errorf("failed to read the cluster: %w", err)
+	}
+	w.say("Found cluster %s running Kubernetes %s\n", cluster.ClusterName, cluster.CurrentKubernetesVersion)
+	return nil
+}
+
+// Confirm asks a yes/no question; anything but "y" or "yes" counts as no
+func (w *Wizard) Confirm(question string) bool {
+	answer, err := w.ask(question+" [y/N]", "", nil)
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// Write writes the config file for the answers, as JSON, YAML or TOML according to the extension of path.
+// It holds secrets, so only root can read it.
+func Write(path string, a *Answers) error {
+	data, err := config.EncodeDocument(path, a.Document())
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// ask asks a question until the answer passes validate, using the default for an empty answer
+func (w *Wizard) ask(question, defaultValue string, validate func(string) error) (string, error) {
+	for {
+		if defaultValue != "" {
+			w.say("%s [%s]: ", question, defaultValue)
+		} else {
+			w.say("%s: ", question)
+		}
+		answer, err := w.readLine()
+		if err != nil {
+			return "", fmt.Errorf("no answer to %q: %w", question, err)
+		}
+		if answer == "" {
+			answer = defaultValue
+		}
+		if validate == nil {
+			return answer, nil
+		}
+		if err := validate(answer); err != nil {
+			w.say("  %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// readLine reads a trimmed line of input; input ending without a newline still counts as a line
+func (w *Wizard) readLine() (string, error) {
+	line, err := w.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func (w *Wizard) say(format string, args ...any) {
+	_, _ = fmt.Fprintf(w.out, format, args...)
+}
+
+func required(answer string) error {
+	if answer == "" {
+		return fmt.Errorf("a value is required")
+	}
+	return nil
+}
+
+// optional accepts an empty answer and validates any other
+func optional(validate func(string) error) func(string) error {
+	return func(answer string) error {
+		if answer == "" {
+			return nil
+		}
+		return validate(answer)
+	}
+}
+
+func validGUID(answer string) error {
+	if !guidPattern.MatchString(answer) {
+		return fmt.Errorf("%q is not a GUID such as 00000000-0000-0000-0000-000000000000", answer)
+	}
+	return nil
+}
+
+func validResourceName(answer string) error {
+	if !resourceNamePattern.MatchString(answer) {
+		return fmt.Errorf("%q is not a valid name, use letters, digits, hyphens, underscores and periods", answer)
+	}
+	return nil
+}
+
+func validLocation(answer string) error {
+	if !locationPattern.MatchString(answer) {
+		return fmt.Errorf("%q is not an Azure region name such as eastus2", answer)
+	}
+	return nil
+}
+
+func validKubernetesVersion(answer string) error {
+	if !kubernetesVersionPattern.MatchString(answer) {
+		return fmt.Errorf("%q is not a Kubernetes version such as 1.32.7", answer)
+	}
+	return nil
+}
+
+func validAuth(answer string) error {
+	if !slices.Contains(authMethods, answer) {
+		return fmt.Errorf("choose one of: %s", strings.Join(authMethods, ", "))
+	}
+	return nil
+}
+
+func validClusterID(answer string) error {
+	if !config.AKSClusterResourceIDPattern.MatchString(answer) {
+		return fmt.Errorf("%q is not an AKS cluster resource ID", answer)
+	}
+	return nil
+}
+
+// azureCLIAccount reads the subscription the Azure CLI is logged in to
+func azureCLIAccount(ctx context.Context) (*CLIAccount, error) {
+	output, err := exec.CommandContext(ctx, "az", "account", "show", "--output", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("az account show failed: %w", err)
+	}
+	var account CLIAccount
+	if err := json.Unmarshal(output, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse az account show output: %w", err)
+	}
+	return &account, nil
+}
+
+// listClusters lists the AKS clusters of the subscription with the Azure CLI login
+func listClusters(ctx context.Context, subscriptionID string) ([]Cluster, error) {
+	cred, err := azidentity.NewAzureCLICredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	client, err := armcontainerservice.NewManagedClustersClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+	var clusters []Cluster
+	pager := client.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, mc := range page.Value {
+			if mc.ID == nil || mc.Name == nil {
+				continue
+			}
+			cluster := Cluster{ResourceID: *mc.ID, Name: *mc.Name}
+			if mc.Location != nil {
+				cluster.Location = *mc.Location
+			}
+			if mc.Properties != nil && mc.Properties.CurrentKubernetesVersion != nil {
+				cluster.KubernetesVersion = *mc.Properties.CurrentKubernetesVersion
+			}
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters, nil
+}
@@ -0,0 +1,220 @@
+package wizard
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.goms.io/aks/AKSFlexNode/pkg/config"
+	"go.goms.io/aks/AKSFlexNode/pkg/spec"
+	"go.goms.io/aks/AKSFlexNode/pkg/utils/utilhost"
+)
+
+const (
+	testSubscription = "00000000-0000-0000-0000-000000000001"
+	testTenant       = "00000000-0000-0000-0000-000000000002"
+	testClient       = "00000000-0000-0000-0000-000000000003"
+	testClusterID    = "/subscriptions/" + testSubscription + "/resourceGroups/rg1/providers/Microsoft.ContainerService/managedClusters/cluster1"
+)
+
+// newTestWizard creates a wizard answering from input, with the given discovery results
+func newTestWizard(input string, vm *utilhost.AzureVMMetadata, account *CLIAccount, clusters []Cluster) (*Wizard, *strings.Builder) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	out := &strings.Builder{}
+	w := &Wizard{
+		logger:        logger,
+		in:            bufio.NewReader(strings.NewReader(input)),
+		out:           out,
+		detectAzureVM: func(ctx context.Context) *utilhost.AzureVMMetadata { return vm },
+		cliAccount: func(ctx context.Context) (*CLIAccount, error) {
+			if account == nil {
+				return nil, errors.New("az not found")
+			}
+			return account, nil
+		},
+		listClusters: func(ctx context.Context, subscriptionID string) ([]Cluster, error) { return clusters, nil },
+		checkCluster: func(ctx context.Context, cfg *config.Config) (*spec.ManagedClusterSpec, error) {
+			return &spec.ManagedClusterSpec{ClusterName: cfg.GetTargetClusterName(), CurrentKubernetesVersion: "1.32.7"}, nil
+		},
+		hostname: func() (string, error) { return "edge-01.example.com", nil },
+	}
+	w.readSecret = w.readLine
+	return w, out
+}
+
+func TestRunWithDiscoveredDefaults(t *testing.T) {
+	clusters := []Cluster{
+		{ResourceID: "/subscriptions/" + testSubscription + "/resourceGroups/rg0/providers/Microsoft.ContainerService/managedClusters/other", Name: "other", Location: "westus", KubernetesVersion: "1.31.4"},
+		{ResourceID: testClusterID, Name: "cluster1", Location: "eastus2", KubernetesVersion: "1.32.7"},
+	}
+	// Accept the subscription and tenant, pick the second cluster and accept every other default
+	w, out := newTestWizard("\n\n2\n\n\n\n\n\n", nil, &CLIAccount{ID: testSubscription, TenantID: testTenant}, clusters)
+
+	answers, err := w.Run(t.Context())
+	if err != nil {
+		t.Fatalf("Run() error = %v\n%s", err, out)
+	}
+	want := &Answers{
+		SubscriptionID:    testSubscription,
+		TenantID:          testTenant,
+		ClusterResourceID: testClusterID,
+		Location:          "eastus2",
+		KubernetesVersion: "1.32.7",
+		Auth:              AuthArc,
+		ArcResourceGroup:  "rg1",
+		ArcMachineName:    "edge-01",
+	}
+	if *answers != *want {
+		t.Errorf("Run() = %+v, want %+v", answers, want)
+	}
+	if !strings.Contains(out.String(), "2) cluster1 (eastus2, Kubernetes 1.32.7)") {
+		t.Errorf("clusters were not offered:\n%s", out)
+	}
+}
+
+func TestRunOnAzureVM(t *testing.T) {
+	vm := &utilhost.AzureVMMetadata{ResourceID: "/subscriptions/x/vm", SubscriptionID: testSubscription, Location: "westeurope"}
+	// Without an Azure CLI login the tenant, cluster and version are typed in; the identity defaults to the VM's
+	input := strings.Join([]string{"", testTenant, "rg1", "cluster1", "", "1.32.7", "", testClient}, "\n") + "\n"
+	w, out := newTestWizard(input, vm, nil, nil)
+
+	answers, err := w.Run(t.Context())
+	if err != nil {
+		t.Fatalf("Run() error = %v\n%s", err, out)
+	}
+	if answers.SubscriptionID != testSubscription || answers.ClusterResourceID != testClusterID || answers.Location != "westeurope" {
+		t.Errorf("Run() = %+v, want the subscription and location of the VM", answers)
+	}
+	if answers.Auth != AuthManagedIdentity || answers.ClientID != testClient {
+		t.Errorf("Run() auth = %s with client %q, want the managed identity %s", answers.Auth, answers.ClientID, testClient)
+	}
+}
+
+func TestRunRepeatsInvalidAnswers(t *testing.T) {
+	input := strings.Join([]string{
+		"not-a-guid", testSubscription, testTenant, "rg 1", "rg1", "cluster1", "East US", "eastus", "latest", "1.32.7",
+		"password", AuthServicePrincipal, testClient, "", "s3cret",
+	}, "\n") + "\n"
+	w, out := newTestWizard(input, nil, nil, nil)
+
+	answers, err := w.Run(t.Context())
+	if err != nil {
+		t.Fatalf("Run() error = %v\n%s", err, out)
+	}
+	if answers.ClusterResourceID != testClusterID || answers.Location != "eastus" || answers.ClientSecret != "s3cret" {
+		t.Errorf("Run() = %+v", answers)
+	}
+	for _, message := range []string{`"not-a-guid" is not a GUID`, `"rg 1" is not a valid name`, "not an Azure region", "not a Kubernetes version", "choose one of"} {
+		if !strings.Contains(out.String(), message) {
+			t.Errorf("output does not explain %q:\n%s", message, out)
+		}
+	}
+}
+
+func TestRunFailsWhenInputEnds(t *testing.T) {
+	w, _ := newTestWizard(testSubscription+"\n", nil, nil, nil)
+	if _, err := w.Run(t.Context()); !errors.Is(err, io.EOF) {
+		t.Errorf("Run() error = %v, want io.EOF", err)
+	}
+}
+
+func TestCheckAndWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		answers Answers
+		check   func(t *testing.T, cfg *config.Config)
+	}{
+		{
+			name:    "arc",
+			file:    "config.json",
+			answers: Answers{Auth: AuthArc, ArcResourceGroup: "arc-rg", ArcMachineName: "edge-01"},
+			check: func(t *testing.T, cfg *config.Config) {
+				if !cfg.IsARCEnabled() || cfg.GetArcMachineName() != "edge-01" || cfg.GetArcResourceGroup() != "arc-rg" {
+					t.Errorf("arc config = %+v", cfg.Azure.Arc)
+				}
+			},
+		},
+		{
+			name:    "service principal",
+			file:    "config.yaml",
+			answers: Answers{Auth: AuthServicePrincipal, ClientID: testClient, ClientSecret: "s3cret"},
+			check: func(t *testing.T, cfg *config.Config) {
+				if !cfg.IsSPConfigured() || cfg.IsARCEnabled() {
+					t.Errorf("service principal config = %+v", cfg.Azure.ServicePrincipal)
+				}
+			},
+		},
+		{
+			name:    "system-assigned managed identity",
+			file:    "config.toml",
+			answers: Answers{Auth: AuthManagedIdentity},
+			check: func(t *testing.T, cfg *config.Config) {
+				if !cfg.IsMIConfigured() || cfg.GetManagedIdentityID() != nil {
+					t.Errorf("managed identity config = %+v", cfg.Azure.ManagedIdentity)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answers := tt.answers
+			answers.SubscriptionID = testSubscription
+			answers.TenantID = testTenant
+			answers.ClusterResourceID = testClusterID
+			answers.Location = "eastus"
+			answers.KubernetesVersion = "v1.32.7"
+
+			w, out := newTestWizard("", nil, nil, nil)
+			if err := w.Check(t.Context(), &answers); err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if !strings.Contains(out.String(), "Found cluster cluster1") {
+				t.Errorf("Check() output = %q", out)
+			}
+
+			path := filepath.Join(t.TempDir(), "etc", tt.file)
+			if err := Write(path, &answers); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0o600 {
+				t.Errorf("config file mode = %v, want 0600", info.Mode().Perm())
+			}
+			cfg, err := config.LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.Kubernetes.Version != "1.32.7" {
+				t.Errorf("kubernetes.version = %q, want 1.32.7", cfg.Kubernetes.Version)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestCheckReportsClusterFailure(t *testing.T) {
+	w, _ := newTestWizard("", nil, nil, nil)
+	w.checkCluster = func(ctx context.Context, cfg *config.Config) (*spec.ManagedClusterSpec, error) {
+		return nil, spec.ErrClusterNotFound
+	}
+	answers := &Answers{
+		SubscriptionID: testSubscription, TenantID: testTenant, ClusterResourceID: testClusterID, Location: "eastus",
+		KubernetesVersion: "1.32.7", Auth: AuthArc, ArcResourceGroup: "rg1", ArcMachineName: "edge-01",
+	}
+	err := w.Check(t.Context(), answers)
+	if !errors.Is(err, spec.ErrClusterNotFound) || !strings.Contains(err.Error(), "az login") {
+		t.Errorf("Check() error = %v, want the cluster lookup failure with a hint to log in", err)
+	}
+}